@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/advise"
+	"github.com/deanhigh/bury-it/internal/age"
+	"github.com/deanhigh/bury-it/internal/size"
+	"github.com/spf13/cobra"
+)
+
+var (
+	adviseMaxHistoryFlag string
+	adviseIdleFlag       string
+	adviseJSONFlag       bool
+)
+
+var adviseCmd = &cobra.Command{
+	Use:   "advise",
+	Short: "Suggest ways to reclaim space in the graveyard",
+	Long: `Analyze the graveyard and print suggestions for shrinking it, largest
+estimated savings first, each with the command that carries it out:
+
+  - pack: git gc the loose objects and garbage in the object store
+  - compact: drop a preserved history larger than --max-history
+  - purge: remove a project whose review date has passed, or most of whose
+    files are also in another project
+  - cold-tier: move a project untouched in the graveyard for --idle into a
+    compressed archive in the current directory, and remove it; with reads
+    recorded by --track-access, a project read within --idle is kept
+
+Nothing is changed; review each command before running it. Savings are
+estimates: purging or cold-tiering a project frees its checked-out files,
+while its history stays in the object store until it is compacted.`,
+	Example: `  bury-it advise -g ~/graveyard
+  bury-it advise -g ~/graveyard --max-history 200MB --idle 3y --json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+		opts := advise.Options{Now: time.Now()}
+		if adviseMaxHistoryFlag != "" {
+			if opts.MaxHistory, err = size.Parse(adviseMaxHistoryFlag); err != nil {
+				exitWithError(fmt.Errorf("invalid --max-history: %w", err))
+			}
+		}
+		if adviseIdleFlag != "" {
+			if opts.Idle, err = age.Parse(adviseIdleFlag); err != nil {
+				exitWithError(fmt.Errorf("invalid --idle: %w", err))
+			}
+		}
+
+		accesses, err := gy.Accesses()
+		if err != nil {
+			exitWithError(err)
+		}
+		if len(accesses) > 0 {
+			opts.Accesses = accesses
+		}
+
+		suggestions, err := advise.Run(gy, opts)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		if adviseJSONFlag {
+			if suggestions == nil {
+				suggestions = []advise.Suggestion{}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.SetEscapeHTML(false)
+			if err := enc.Encode(suggestions); err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+
+		if len(suggestions) == 0 {
+			fmt.Println("Nothing to suggest; the graveyard is as small as it can easily be.")
+			return
+		}
+		var total int64
+		for i, s := range suggestions {
+			target := s.Project
+			if target == "" {
+				target = "the graveyard"
+			}
+			fmt.Printf("%d. %s %s, saving about %s: %s\n", i+1, s.Action, target, size.Format(s.Savings), s.Reason)
+			fmt.Printf("   %s\n", s.Command)
+			total += s.Savings
+		}
+		fmt.Println("")
+		fmt.Printf("Estimated savings: %s\n", size.Format(total))
+	},
+}
+
+func init() {
+	adviseCmd.Flags().StringVar(&adviseMaxHistoryFlag, "max-history", "500MB", "preserved history size above which to suggest compacting, or empty to skip")
+	adviseCmd.Flags().StringVar(&adviseIdleFlag, "idle", "2y", "how long a project must go untouched to suggest cold storage, or empty to skip")
+	adviseCmd.Flags().BoolVar(&adviseJSONFlag, "json", false, "output the suggestions as JSON")
+	rootCmd.AddCommand(adviseCmd)
+}
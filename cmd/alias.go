@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/deanhigh/bury-it/internal/index"
+	"github.com/spf13/cobra"
+)
+
+var aliasCmd = &cobra.Command{
+	Use:   "alias",
+	Short: "Give buried projects alternative names",
+	Long: `Give a buried project alternative names, such as the name it had before it
+was buried, a codename, or a ticket number, without moving its directory or
+rewriting the graveyard's history.
+
+Aliases are stored beside the search index in .bury-it/aliases/, one file per
+alias holding its project's name, and committed, so clones adding different
+aliases merge without conflict. bury-it info, expand, history, blame, and
+checklist show accept an alias wherever they take a project, and bury-it
+search matches aliases as it does project names. A project's own name takes
+precedence over an alias.`,
+	Args: cobra.NoArgs,
+}
+
+var aliasAddCmd = &cobra.Command{
+	Use:   "add <project> <alias>",
+	Short: "Add an alias for a buried project",
+	Example: `  # Find a project by the codename it had while it was alive
+  bury-it alias add old-experiment bluebird -g ~/graveyard
+  bury-it info bluebird -g ~/graveyard`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeProject,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkReadOnly("adding an alias"); err != nil {
+			exitWithError(err)
+		}
+		project, alias := args[0], args[1]
+		gy, err := openProjectGraveyard(project)
+		if err != nil {
+			exitWithError(err)
+		}
+		if !gy.ProjectExists(project) {
+			exitWithError(fmt.Errorf("project not found in graveyard: %s", project))
+		}
+		if gy.ProjectExists(alias) {
+			exitWithError(fmt.Errorf("%s is already the name of a project", alias))
+		}
+
+		aliases, err := index.LoadAliases(gy.Path)
+		if err != nil {
+			exitWithError(err)
+		}
+		if err := aliases.Add(project, alias); err != nil {
+			exitWithError(err)
+		}
+		if err := aliases.Save(gy.Path); err != nil {
+			exitWithError(err)
+		}
+		if err := commitMetadata(gy, project, fmt.Sprintf("docs: bury-it - aliased %s as %s", project, alias)); err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("%s: %s\n", project, strings.Join(aliases.Of(project), " "))
+	},
+}
+
+var aliasRemoveCmd = &cobra.Command{
+	Use:     "remove <alias>",
+	Short:   "Remove an alias",
+	Example: `  bury-it alias remove bluebird -g ~/graveyard`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkReadOnly("removing an alias"); err != nil {
+			exitWithError(err)
+		}
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		alias := args[0]
+		aliases, err := index.LoadAliases(gy.Path)
+		if err != nil {
+			exitWithError(err)
+		}
+		project, ok := aliases[alias]
+		if !ok {
+			exitWithError(fmt.Errorf("alias not found: %s", alias))
+		}
+		delete(aliases, alias)
+		if err := aliases.Save(gy.Path); err != nil {
+			exitWithError(err)
+		}
+		if err := commitMetadata(gy, project, fmt.Sprintf("docs: bury-it - removed alias %s of %s", alias, project)); err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("Removed alias %s of %s.\n", alias, project)
+	},
+}
+
+var aliasListCmd = &cobra.Command{
+	Use:   "list [project]",
+	Short: "List aliases and the projects they stand for",
+	Example: `  bury-it alias list -g ~/graveyard
+  bury-it alias list old-experiment -g ~/graveyard`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeProject,
+	Run: func(cmd *cobra.Command, args []string) {
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+		aliases, err := index.LoadAliases(gy.Path)
+		if err != nil {
+			exitWithError(err)
+		}
+		names := make([]string, 0, len(aliases))
+		for alias, project := range aliases {
+			if len(args) == 0 || project == args[0] {
+				names = append(names, alias)
+			}
+		}
+		sort.Strings(names)
+		for _, alias := range names {
+			fmt.Printf("%s\t%s\n", alias, aliases[alias])
+		}
+	},
+}
+
+func init() {
+	aliasCmd.AddCommand(aliasAddCmd, aliasRemoveCmd, aliasListCmd)
+	rootCmd.AddCommand(aliasCmd)
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/archive"
+	"github.com/deanhigh/bury-it/internal/display"
+	"github.com/spf13/cobra"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply <plan-file>",
+	Short: "Carry out a burial plan made by bury-it plan",
+	Long: `Carry out a burial saved by bury-it plan --out, with the options recorded in
+the plan. The burial is refused if the source is no longer at the planned
+commit or the project name has been taken since.`,
+	Example: `  bury-it apply my-experiment.plan.json`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkReadOnly("applying a plan"); err != nil {
+			exitWithError(err)
+		}
+		plan, err := archive.LoadPlan(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+
+		fmt.Printf("Applying plan to bury %s into %s...\n", display.Path(plan.Options.Source), display.Path(plan.Options.Graveyard))
+		started := time.Now()
+		result, err := archive.Apply(plan)
+		if err != nil {
+			exitWithError(err)
+		}
+		recordPersonalBurial(plan.Options.Graveyard, result.ProjectName, started)
+		printBurial(result)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/deanhigh/bury-it/internal/approval"
+	"github.com/deanhigh/bury-it/internal/archive"
+	"github.com/deanhigh/bury-it/internal/capability"
+	"github.com/deanhigh/bury-it/internal/display"
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/spf13/cobra"
+)
+
+var approveListFlag bool
+
+var approveCmd = &cobra.Command{
+	Use:   "approve <request-id>",
+	Short: "Carry out a destructive action requested by someone else",
+	Long: `Approve and carry out an action recorded with --require-approval, such as
+bury-it undo --require-approval, or one the administrator requires approval
+for: undo, compact, or a burial with --new-version, when the system
+configuration sets capability.undo, capability.purge-history, or
+capability.replace to approve (see bury-it config --help).
+
+Requests are kept in the local state directory ($BURY_IT_HOME, or bury-it in
+the user config directory); point BURY_IT_HOME at a shared directory for a team
+to approve each other's requests. A request can only be approved by an
+operating system account other than the one that made it; git's user.email
+can be set to anything, so it does not count. The action is refused if the
+graveyard has changed since the request was made.
+
+This is a courtesy check against acting alone by mistake, not a security
+boundary: whoever can write to the state directory, as the requester can, can
+edit a request to approve it themselves. Enforce the rule with access controls
+on the graveyard's remote, such as protected branches, where it matters.`,
+	Example: `  # Show pending requests
+  bury-it approve --list
+
+  # Approve a request
+  bury-it approve 3f9a1c2e`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if approveListFlag {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		pending, err := approval.Pending()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		if approveListFlag {
+			if len(pending) == 0 {
+				fmt.Println("No pending requests.")
+				return
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tACTION\tREQUESTED BY\tREQUESTED AT\tGRAVEYARD")
+			for _, req := range pending {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", req.ID, req.Summary, req.RequestedBy, req.RequestedAt.Format("2006-01-02 15:04"), display.Path(req.Graveyard))
+			}
+			_ = w.Flush()
+			return
+		}
+
+		if err := checkReadOnly("approving a request"); err != nil {
+			exitWithError(err)
+		}
+
+		var req *approval.Request
+		for _, p := range pending {
+			if p.ID == args[0] {
+				req = p
+			}
+		}
+		if req == nil {
+			exitWithError(fmt.Errorf("no pending request with id %s", args[0]))
+		}
+
+		gy, err := graveyard.New(req.Graveyard)
+		if err != nil {
+			exitWithError(fmt.Errorf("invalid graveyard: %w", err))
+		}
+		approvedBy, err := approval.User()
+		if err != nil {
+			exitWithError(err)
+		}
+		take := func() {
+			if _, err := approval.Take(req.ID, approvedBy); err != nil {
+				exitWithError(err)
+			}
+			fmt.Printf("Approved request %s from %s: %s\n", req.ID, req.RequestedBy, req.Summary)
+		}
+
+		switch req.Action {
+		case "undo":
+			if err := capability.Check(capability.Undo); err != nil {
+				exitWithError(err)
+			}
+			burial, err := gy.LastBurial()
+			if err != nil {
+				exitWithError(err)
+			}
+			if burial.Commits[0] != req.Args["commit"] {
+				exitWithError(fmt.Errorf("the graveyard has changed since the request was made; the last burial is now %s", burial.Project))
+			}
+			take()
+			if err := undoBurial(gy, burial, req.Args["revert"] == "true"); err != nil {
+				exitWithError(err)
+			}
+		case "compact":
+			if err := capability.Check(capability.PurgeHistory); err != nil {
+				exitWithError(err)
+			}
+			if err := checkUnchanged(gy, req); err != nil {
+				exitWithError(err)
+			}
+			take()
+			if err := compactProject(gy, req.Args["project"], req.Args["force"] == "true", req.Args["prune"] == "true"); err != nil {
+				exitWithError(err)
+			}
+		case "replace":
+			var opts archive.Options
+			if err := json.Unmarshal([]byte(req.Args["options"]), &opts); err != nil {
+				exitWithError(fmt.Errorf("invalid options in request %s: %w", req.ID, err))
+			}
+			if err := checkUnchanged(gy, req); err != nil {
+				exitWithError(err)
+			}
+			take()
+			opts.Approved = true
+			result, err := bury(opts)
+			if err != nil {
+				exitWithError(err)
+			}
+			printBurial(result)
+		default:
+			exitWithError(fmt.Errorf("unknown action %q in request %s", req.Action, req.ID))
+		}
+	},
+}
+
+// requestApproval records a request for a second person to approve an action
+// on the graveyard, instead of carrying it out. The graveyard's current commit
+// is recorded with args, so that the action is refused if it changes first.
+func requestApproval(action string, gy *graveyard.Graveyard, summary string, args map[string]string) error {
+	requestedBy, err := approval.User()
+	if err != nil {
+		return err
+	}
+	if _, ok := args["head"]; !ok {
+		head, err := git.Head(gy.Path)
+		if err != nil {
+			return err
+		}
+		args["head"] = head
+	}
+	req, err := approval.Submit(action, gy.Path, summary, requestedBy, args)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Requested approval to %s.\n", summary)
+	fmt.Println("")
+	fmt.Printf("Next step: Ask someone else to run: bury-it approve %s\n", req.ID)
+	return nil
+}
+
+// checkUnchanged checks that the graveyard is still at the commit it was at
+// when req was made.
+func checkUnchanged(gy *graveyard.Graveyard, req *approval.Request) error {
+	head, err := git.Head(gy.Path)
+	if err != nil {
+		return err
+	}
+	if head != req.Args["head"] {
+		return fmt.Errorf("the graveyard has changed since the request was made")
+	}
+	return nil
+}
+
+func init() {
+	approveCmd.Flags().BoolVar(&approveListFlag, "list", false, "list pending requests")
+	rootCmd.AddCommand(approveCmd)
+}
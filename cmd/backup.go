@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/deanhigh/bury-it/internal/backup"
+	"github.com/deanhigh/bury-it/internal/display"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/size"
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupToFlag      string
+	restoreFromFlag   string
+	restoreNumberFlag int
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Take an incremental offsite backup of the graveyard",
+	Long: `Take an incremental backup of the graveyard. Each backup uploads a git bundle
+of the commits made since the previous one, the files of the graveyard that
+git does not track whose content changed, and a catalog listing every backup,
+so that backups stay small however large the graveyard grows.
+
+--to is a local directory (such as a mounted drive), an rsync destination
+(host:path or rsync://host/path, copied with rsync), or an S3 prefix
+(s3://bucket/prefix, copied with the aws CLI). Once given, it is remembered
+for the graveyard and may be left out.
+
+Only committed changes are backed up. Restore a backup with bury-it restore.`,
+	Example: `  # Back up to a mounted drive
+  bury-it backup --to /mnt/offsite/graveyard -g ~/graveyard
+
+  # Back up to S3, then again to the same bucket later
+  bury-it backup --to s3://archives/graveyard -g ~/graveyard
+  bury-it backup -g ~/graveyard`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkReadOnly("backing up a graveyard"); err != nil {
+			exitWithError(err)
+		}
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		to := backupToFlag
+		if to == "" {
+			last, err := backup.LastBackup(gy.Path)
+			if err != nil {
+				exitWithError(err)
+			}
+			if last == nil {
+				exitWithError(fmt.Errorf("--to is required for the first backup of a graveyard"))
+			}
+			to = last.Target
+		}
+		target, err := backup.OpenTarget(to)
+		if err != nil {
+			exitWithError(err)
+		}
+		if !backup.IsLocal(target) {
+			if err := checkOffline("backing up to " + target.String()); err != nil {
+				exitWithError(err)
+			}
+		}
+
+		inc, err := backup.Backup(gy, target, Version)
+		if err != nil {
+			exitWithError(err)
+		}
+		if inc == nil {
+			fmt.Printf("Nothing changed since the last backup to %s\n", target)
+			return
+		}
+		if err := backup.RecordBackup(gy.Path, target, inc); err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("Backed up %s to %s as backup %d (%s uploaded)\n",
+			inc.Head[:12], target, inc.Number, size.Format(inc.Uploaded))
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore a graveyard from an incremental backup",
+	Long: `Restore a graveyard backed up with bury-it backup into the path given by
+--graveyard, which must not exist or be empty. The latest backup is restored
+unless --backup picks an earlier one.
+
+Every downloaded file is checked against the digest in the backup's catalog
+and the restored repository against the commit it was backed up at. If
+anything does not match, nothing is restored.`,
+	Example: `  bury-it restore --from s3://archives/graveyard -g ~/restored-graveyard
+
+  # Restore the graveyard as of its third backup
+  bury-it restore --from /mnt/offsite/graveyard --backup 3 -g ~/restored-graveyard`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkReadOnly("restoring a graveyard"); err != nil {
+			exitWithError(err)
+		}
+		if restoreFromFlag == "" {
+			exitWithError(fmt.Errorf("--from is required"))
+		}
+		if graveyardFlag == "" {
+			exitWithError(fmt.Errorf("--graveyard is required"))
+		}
+		if restoreNumberFlag < 0 {
+			exitWithError(fmt.Errorf("--backup must be a positive number"))
+		}
+		target, err := backup.OpenTarget(restoreFromFlag)
+		if err != nil {
+			exitWithError(err)
+		}
+		if !backup.IsLocal(target) {
+			if err := checkOffline("restoring from " + target.String()); err != nil {
+				exitWithError(err)
+			}
+		}
+
+		inc, err := backup.Restore(target, graveyardFlag, restoreNumberFlag)
+		if err != nil {
+			exitWithError(err)
+		}
+		if gy, err := graveyard.New(graveyardFlag); err == nil {
+			_ = gy.Remember()
+		}
+		fmt.Printf("Restored backup %d of %s (%s, taken %s) to %s\n",
+			inc.Number, target, inc.Head[:12], inc.CreatedAt.Format("2006-01-02 15:04"), display.Path(graveyardFlag))
+	},
+}
+
+func init() {
+	backupCmd.Flags().StringVar(&backupToFlag, "to", "", "directory, host:path, rsync:// or s3:// URL to back up to (default: the last one used)")
+	restoreCmd.Flags().StringVar(&restoreFromFlag, "from", "", "directory, host:path, rsync:// or s3:// URL to restore from")
+	restoreCmd.Flags().IntVar(&restoreNumberFlag, "backup", 0, "number of the backup to restore (default: the latest)")
+	rootCmd.AddCommand(backupCmd, restoreCmd)
+}
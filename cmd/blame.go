@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var blameCmd = &cobra.Command{
+	Use:   "blame <project> <file> [-- <git blame args>]",
+	Short: "Run git blame on a file of a buried project",
+	Long: `Run git blame on a file of a buried project, given its path within the
+project rather than in the graveyard.
+
+For projects buried with their history, lines are traced back to the
+original commits that wrote them. Arguments after -- are passed to git blame,
+for example a revision such as a version tag, or -L to blame some lines. The
+output is not paged.`,
+	Example: `  bury-it blame old-tool src/config.go
+  bury-it blame old-tool src/config.go -- -L 10,20 --date=short`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if dash := cmd.ArgsLenAtDash(); (dash >= 0 && dash != 2) || (dash < 0 && len(args) != 2) {
+			return fmt.Errorf("requires a project and a file, with git blame arguments after --")
+		}
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return completeProject(cmd, args, toComplete)
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		gy, project, err := openAliasedProject(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+		file, err := gy.ProjectFile(project, args[1])
+		if err != nil {
+			exitWithError(err)
+		}
+		trackAccess(gy, project)
+
+		gitArgs := append([]string{"blame"}, args[2:]...)
+		gitArgs = append(gitArgs, "--", file)
+		if err := git.Passthrough(gy.Path, os.Stdout, os.Stderr, gitArgs...); err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(blameCmd)
+}
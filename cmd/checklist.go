@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/checklist"
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/metadata"
+	"github.com/spf13/cobra"
+)
+
+var checklistNoteFlag string
+
+var checklistCmd = &cobra.Command{
+	Use:   "checklist",
+	Short: "Show and tick a buried project's decommissioning checklist",
+	Long: `Every burial writes a DECOMMISSION.md checklist next to the project's
+metadata: archive the original repository, deprecate published artifacts, tear
+down the infrastructure and DNS records found in the source, and revoke access.
+These commands show the checklist and tick its items, committing an audit
+entry with each tick.`,
+	Args: cobra.NoArgs,
+}
+
+var checklistShowCmd = &cobra.Command{
+	Use:               "show <project>",
+	Short:             "Show a project's decommissioning checklist",
+	Example:           `  bury-it checklist show old-experiment -g ~/graveyard`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProject,
+	Run: func(cmd *cobra.Command, args []string) {
+		gy, project, err := openAliasedProject(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+		if !gy.ProjectExists(project) {
+			exitWithError(fmt.Errorf("project not found in graveyard: %s", project))
+		}
+
+		items, err := checklist.Read(gy.ProjectPath(project))
+		if err != nil {
+			exitWithError(err)
+		}
+		trackAccess(gy, project)
+		done := 0
+		for _, item := range items {
+			mark := " "
+			if item.Done {
+				mark = "x"
+				done++
+			}
+			fmt.Printf("[%s] %2d. %s\n", mark, item.Number, item.Text)
+		}
+		fmt.Printf("\n%d of %d done.\n", done, len(items))
+	},
+}
+
+var checklistTickCmd = &cobra.Command{
+	Use:   "tick <project> <item>",
+	Short: "Tick an item of a project's decommissioning checklist",
+	Long: `Mark an item of a project's decommissioning checklist as done. The item is
+given by its number, or by part of its text that matches no other item. An
+audit entry with the git identity (user.email) of the person ticking it, the
+time, and any --note is appended to the checklist, and the change is
+committed.`,
+	Example: `  # Tick an item by number
+  bury-it checklist tick old-experiment 3 -g ~/graveyard
+
+  # Tick an item by its text, noting how it was done
+  bury-it checklist tick old-experiment "api.example.io" -g ~/graveyard --note "CNAME removed in INFRA-212"`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeChecklistItem,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkReadOnly("ticking a checklist item"); err != nil {
+			exitWithError(err)
+		}
+		gy, err := openProjectGraveyard(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+		project := args[0]
+		if !gy.ProjectExists(project) {
+			exitWithError(fmt.Errorf("project not found in graveyard: %s", project))
+		}
+
+		by, err := git.Identity(gy.Path)
+		if err != nil {
+			exitWithError(err)
+		}
+		item, err := checklist.TickFile(gy.ProjectPath(project), args[1], by, checklistNoteFlag, time.Now())
+		if err != nil {
+			exitWithError(err)
+		}
+		if err := git.StageFile(gy.Path, filepath.Join(project, metadata.DecommissionFileName)); err != nil {
+			exitWithError(fmt.Errorf("failed to stage checklist: %w", err))
+		}
+		message := fmt.Sprintf("docs: bury-it - ticked checklist item %d of %s", item.Number, project)
+		if err := commitMetadata(gy, project, message); err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("Ticked %d. %s\n", item.Number, item.Text)
+	},
+}
+
+func init() {
+	checklistTickCmd.Flags().StringVar(&checklistNoteFlag, "note", "", "note to record with the tick, e.g. a ticket reference")
+	checklistCmd.AddCommand(checklistShowCmd, checklistTickCmd)
+	rootCmd.AddCommand(checklistCmd)
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/deanhigh/bury-it/internal/capability"
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/spf13/cobra"
+)
+
+var (
+	compactForceFlag           bool
+	compactPruneFlag           bool
+	compactRequireApprovalFlag bool
+)
+
+var compactCmd = &cobra.Command{
+	Use:   "compact <project>",
+	Short: "Drop the preserved history of a buried project",
+	Long: `Convert a project buried with its git history into a snapshot-only burial to
+shrink the graveyard. The project's files are kept as they are; its original
+commits are dropped from the graveyard's history and the metadata's History
+Preserved field is set to No.
+
+This rewrites every graveyard commit made since the project was buried. If
+those commits have been pushed, --force is required and the graveyard must be
+force-pushed afterwards. The dropped commits stay in the object store until
+they are pruned, which --prune does straight away, dropping them from the
+reflogs of HEAD and the current branch only, so other reflogs keep their
+recovery points. If the graveyard was pushed, its remote-tracking branches
+still hold the dropped commits, so their space is only reclaimed after the
+force-push, once git gc prunes them.
+
+With --require-approval, nothing is changed. The compaction is recorded as a
+pending request instead, and only carried out when someone logged in as a
+different user runs bury-it approve with the printed request ID. An
+administrator can require this for every compaction by setting
+capability.purge-history to approve in the system configuration.`,
+	Example: `  # Drop the history of a project and reclaim the space
+  bury-it compact old-experiment -g ~/graveyard --prune
+
+  # Ask a second person to approve dropping the history
+  bury-it compact old-experiment -g ~/graveyard --require-approval`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProject,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := capability.Check(capability.PurgeHistory); err != nil {
+			exitWithError(err)
+		}
+		if err := checkReadOnly("compacting a project"); err != nil {
+			exitWithError(err)
+		}
+		gy, err := openProjectGraveyard(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+
+		project := args[0]
+		if compactRequireApprovalFlag || capability.RequiresApproval(capability.PurgeHistory) {
+			if !gy.ProjectExists(project) {
+				exitWithError(fmt.Errorf("project not found in graveyard: %s", project))
+			}
+			err := requestApproval("compact", gy, "drop the history of "+project, map[string]string{
+				"project": project,
+				"force":   strconv.FormatBool(compactForceFlag),
+				"prune":   strconv.FormatBool(compactPruneFlag),
+			})
+			if err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+		if err := compactProject(gy, project, compactForceFlag, compactPruneFlag); err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+// compactProject drops the history of a project and reports what was
+// rewritten.
+func compactProject(gy *graveyard.Graveyard, project string, force, prune bool) error {
+	result, err := gy.Compact(project, force)
+	if err != nil {
+		return err
+	}
+	if err := commitMetadata(gy, project, "docs: bury-it - compacted "+project); err != nil {
+		return err
+	}
+	fmt.Printf("Dropped the history of %s; rewrote %d graveyard commit(s).\n", project, result.Rewritten)
+
+	if prune {
+		fmt.Printf("Pruning unreachable objects...\n")
+		if err := git.PruneUnreachable(gy.Path); err != nil {
+			return err
+		}
+		if result.Pushed {
+			fmt.Println("The remote-tracking branches still hold the dropped commits, so no space is reclaimed until the graveyard is force-pushed.")
+		}
+	}
+
+	if result.Pushed {
+		fmt.Println("")
+		fmt.Println("Next step: Force-push the graveyard, e.g. git push --force-with-lease, after which git gc reclaims the space")
+	}
+	return nil
+}
+
+func init() {
+	compactCmd.Flags().BoolVar(&compactForceFlag, "force", false, "rewrite history even if it has been pushed")
+	compactCmd.Flags().BoolVar(&compactPruneFlag, "prune", false, "delete the dropped commits from the object store right away")
+	compactCmd.Flags().BoolVar(&compactRequireApprovalFlag, "require-approval", false, "record a request for a second person to approve instead of compacting")
+	rootCmd.AddCommand(compactCmd)
+}
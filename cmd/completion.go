@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/deanhigh/bury-it/internal/checklist"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/spf13/cobra"
+)
+
+// completeGraveyards completes --graveyard with the graveyards used before on
+// this machine, falling back to the shell's path completion.
+func completeGraveyards(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	known, err := graveyard.Known()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	var matches []string
+	for _, path := range known {
+		if strings.HasPrefix(path, toComplete) {
+			matches = append(matches, path)
+		}
+	}
+	return matches, cobra.ShellCompDirectiveDefault
+}
+
+// completeProjectNames returns the buried projects starting with toComplete,
+// in the graveyard given by --graveyard.
+func completeProjectNames(toComplete string) ([]string, cobra.ShellCompDirective) {
+	if graveyardFlag == "" {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	gy, err := graveyard.New(graveyardFlag)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	projects, err := gy.Projects()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var matches []string
+	for _, name := range projects {
+		if strings.HasPrefix(name, toComplete) {
+			matches = append(matches, name)
+		}
+	}
+	return matches, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProject completes the project argument of commands that take a
+// single project as their first argument.
+func completeProject(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeProjectNames(toComplete)
+}
+
+// completeProjectFlag completes a --project flag.
+func completeProjectFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeProjectNames(toComplete)
+}
+
+// completeChecklistItem completes the project and then the item number of
+// checklist tick, describing each open item.
+func completeChecklistItem(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		return completeProjectNames(toComplete)
+	case 1:
+		gy, err := graveyard.New(graveyardFlag)
+		if err != nil || graveyardFlag == "" {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		items, err := checklist.Read(gy.ProjectPath(args[0]))
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var completions []string
+		for _, item := range items {
+			if !item.Done {
+				completions = append(completions, fmt.Sprintf("%d\t%s", item.Number, item.Text))
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
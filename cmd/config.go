@@ -0,0 +1,466 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/deanhigh/bury-it/internal/capability"
+	"github.com/deanhigh/bury-it/internal/config"
+	"github.com/deanhigh/bury-it/internal/gitea"
+	"github.com/deanhigh/bury-it/internal/source"
+	"github.com/deanhigh/bury-it/internal/state"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.yaml.in/yaml/v3"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage default options",
+	Long: `Manage bury-it's user configuration, so that common options do not have to be
+repeated on every invocation.
+
+A key of the form default.<flag> sets the default value of that flag for every
+command that has it, e.g. default.graveyard for --graveyard or
+default.drop-history for --drop-history. A flag given on the command line
+always takes precedence.
+
+A key of the form transport.<host> sets whether shorthand sources on that host,
+such as owner/repo for github.com, are cloned over ssh or https, unless
+--prefer-transport is given.
+
+A key of the form forge.<host> marks a self-hosted instance as running github
+(GitHub Enterprise Server), gitea, or forgejo. The GitHub options, such as
+--link-original-issues, work with sources on a github host, authenticating
+with GH_ENTERPRISE_TOKEN. The description and topics of repositories buried
+from a gitea or forgejo host are fetched from its API and recorded in the
+metadata; codeberg.org and gitea.com are recognized without configuration.
+
+The shorthand.host key sets the host owner/repo shorthand expands to instead
+of github.com, such as github.example.com, which then counts as a github host.
+
+A key of the form prefix.<name> defines a shorthand prefix and the host it
+expands to, so that prefix.corp set to git.example.com makes corp:team/repo
+expand to https://git.example.com/team/repo. gh:, gl:, and bb: (and github:,
+gitlab:, and bitbucket:) are built in, and may be redefined.
+
+The configuration is stored in config.json in the bury-it state directory,
+which is $BURY_IT_HOME if set, or bury-it in the user's configuration
+directory.
+
+An administrator can disable capabilities for everyone on a machine, such as
+a shared CI runner, with keys of the form capability.<name> set to deny in
+the system configuration, /etc/bury-it/config.json unless the package moved
+it. It has the same format, and cannot be changed with config set. The
+capabilities are:
+  push            pushing to a remote, as --registry-pr does
+  github-write    writing issues and pull requests on GitHub, as
+                  --tombstone-issue, --registry-pr, and sweep --notify-owners do
+  purge-history   dropping preserved history with compact
+  replace         burying a project again with --new-version
+  serve           serving the graveyard with serve
+  undo            undoing a burial with undo
+Using a disabled capability fails with "disabled by administrator". Set to
+approve instead, a capability is only used once someone logged in as a
+different user approves it with bury-it approve; this applies to
+purge-history, replace, and undo. Approval guards against mistakes rather
+than determined users, who can edit the pending requests in their state
+directory (see bury-it approve --help).`,
+	Example: `  # Use the same graveyard everywhere
+  bury-it config set default.graveyard ~/graveyard
+
+  # Show all settings
+  bury-it config list`,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:               "set <key> <value>",
+	Short:             "Set a configuration value",
+	Example:           `  bury-it config set default.graveyard ~/graveyard`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeConfigKey,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkReadOnly("setting configuration"); err != nil {
+			exitWithError(err)
+		}
+		key, value := args[0], args[1]
+		if err := checkConfigKey(key, value); err != nil {
+			exitWithError(err)
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			exitWithError(err)
+		}
+		cfg[key] = value
+		if err := cfg.Save(); err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:               "get <key>",
+	Short:             "Print a configuration value",
+	Long:              `Print a configuration value. Exits with status 1 if the key is not set.`,
+	Example:           `  bury-it config get default.graveyard`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeConfigKey,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.Load()
+		if err != nil {
+			exitWithError(err)
+		}
+		value, ok := cfg[args[0]]
+		if !ok {
+			os.Exit(1)
+		}
+		fmt.Println(value)
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:               "unset <key>",
+	Short:             "Remove a configuration value",
+	Example:           `  bury-it config unset default.graveyard`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeConfigKey,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkReadOnly("unsetting configuration"); err != nil {
+			exitWithError(err)
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			exitWithError(err)
+		}
+		if _, ok := cfg[args[0]]; !ok {
+			exitWithError(fmt.Errorf("key not set: %s", args[0]))
+		}
+		delete(cfg, args[0])
+		if err := cfg.Save(); err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List configuration values",
+	Example: `  bury-it config list`,
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.Load()
+		if err != nil {
+			exitWithError(err)
+		}
+		for _, key := range cfg.Keys() {
+			fmt.Printf("%s=%s\n", key, cfg[key])
+		}
+	},
+}
+
+var configEffectiveJSONFlag bool
+
+// secretEnv are the environment variables holding credentials, whose values
+// are never printed.
+var secretEnv = []string{
+	"GITHUB_TOKEN", "GH_TOKEN",
+	"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN",
+	"GITEA_TOKEN", "FORGEJO_TOKEN",
+	source.AzureDevOpsPATEnv,
+}
+
+// redacted replaces the value of a secret.
+const redacted = "<redacted>"
+
+// effectiveFlag is the value a flag takes and where it comes from.
+type effectiveFlag struct {
+	Value string `json:"value" yaml:"value"`
+	// Source is "flag" if given on the command line, "config" if set by a
+	// default.<flag> key, or "default".
+	Source string `json:"source" yaml:"source"`
+}
+
+// effectiveConfig is the configuration a command runs with, as printed by
+// config effective.
+type effectiveConfig struct {
+	Command       string                   `json:"command" yaml:"command"`
+	StateDir      string                   `json:"state_dir" yaml:"state_dir"`
+	ConfigFile    string                   `json:"config_file" yaml:"config_file"`
+	SystemConfig  string                   `json:"system_config_file" yaml:"system_config_file"`
+	Capabilities  map[string]string        `json:"capabilities" yaml:"capabilities"`
+	Flags         map[string]effectiveFlag `json:"flags" yaml:"flags"`
+	ShorthandHost string                   `json:"shorthand_host" yaml:"shorthand_host"`
+	Prefixes      map[string]string        `json:"prefixes" yaml:"prefixes"`
+	Transports    map[string]string        `json:"transports" yaml:"transports"`
+	Forges        map[string]string        `json:"forges" yaml:"forges"`
+	// Environment holds the variables bury-it reads that are set, with
+	// secrets redacted.
+	Environment map[string]string `json:"environment" yaml:"environment"`
+}
+
+var configEffectiveCmd = &cobra.Command{
+	Use:   "effective [command]",
+	Short: "Print the configuration a command runs with",
+	Long: `Print the fully resolved configuration a command runs with, as YAML or with
+--json as JSON, so that declarative setups such as Nix or asdf can check that
+bury-it behaves as intended.
+
+The output holds the value of every flag of the command, the burial itself
+unless another command is named, and whether it comes from the command line,
+a default.<flag> key, or the built-in default; flags given before "config",
+such as --graveyard, count as given on the command line. It also holds the
+state directory and configuration file in use, the shorthand host, prefixes,
+transports, and forges, built-in ones included, the capabilities allowed by
+the system configuration, and the environment variables bury-it reads that
+are set. Tokens are printed as ` + redacted + `.`,
+	Example: `  # The configuration of a burial
+  bury-it config effective
+
+  # The configuration of sweep, as JSON
+  bury-it config effective sweep --json`,
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		target, _, err := rootCmd.Find(args)
+		if err != nil {
+			exitWithError(err)
+		}
+		effective, err := resolveConfig(target)
+		if err != nil {
+			exitWithError(err)
+		}
+		if configEffectiveJSONFlag {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(effective); err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(effective); err != nil {
+			exitWithError(err)
+		}
+		if err := enc.Close(); err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+func init() {
+	configEffectiveCmd.Flags().BoolVar(&configEffectiveJSONFlag, "json", false, "output the configuration as JSON")
+	configCmd.AddCommand(configSetCmd, configGetCmd, configUnsetCmd, configListCmd, configEffectiveCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// resolveConfig returns the configuration target runs with.
+func resolveConfig(target *cobra.Command) (*effectiveConfig, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("%w (fix or remove %s)", err, config.Path())
+	}
+	stateDir, err := state.Dir()
+	if err != nil {
+		return nil, err
+	}
+	effective := &effectiveConfig{
+		Command:       target.CommandPath(),
+		StateDir:      stateDir,
+		ConfigFile:    config.Path(),
+		SystemConfig:  config.SystemPath,
+		Capabilities:  capability.States(),
+		Flags:         map[string]effectiveFlag{},
+		ShorthandHost: "github.com",
+		Prefixes:      map[string]string{},
+		Transports:    cfg.Transports(),
+		Forges:        map[string]string{"github.com": "github"},
+		Environment:   map[string]string{},
+	}
+
+	defaults := cfg.Defaults()
+	add := func(f *pflag.Flag) {
+		if f.Name == "help" || f.Name == "version" {
+			return
+		}
+		// Persistent flags are shared with this command, so they have
+		// already been given their configured defaults
+		switch value, ok := defaults[f.Name]; {
+		case f.Changed:
+			effective.Flags[f.Name] = effectiveFlag{Value: f.Value.String(), Source: "flag"}
+		case ok:
+			effective.Flags[f.Name] = effectiveFlag{Value: value, Source: "config"}
+		default:
+			effective.Flags[f.Name] = effectiveFlag{Value: f.DefValue, Source: "default"}
+		}
+	}
+	target.Flags().VisitAll(add)
+	target.InheritedFlags().VisitAll(add)
+
+	if host := cfg[config.ShorthandHostKey]; host != "" {
+		effective.ShorthandHost = host
+	}
+	maps.Copy(effective.Prefixes, source.DefaultPrefixes)
+	maps.Copy(effective.Prefixes, cfg.Prefixes())
+	maps.Copy(effective.Forges, gitea.DefaultHosts)
+	maps.Copy(effective.Forges, cfg.Forges())
+
+	if dir := os.Getenv(state.HomeEnv); dir != "" {
+		effective.Environment[state.HomeEnv] = dir
+	}
+	for _, name := range secretEnv {
+		if os.Getenv(name) != "" {
+			effective.Environment[name] = redacted
+		}
+	}
+	return effective, nil
+}
+
+// hostPattern matches a host name, optionally with a port.
+var hostPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*(:[0-9]+)?$`)
+
+// checkConfigKey checks that key is a default.<flag> key naming a flag of
+// some command, and that value suits the flag's type, a transport.<host>
+// key set to a transport, a forge.<host> key set to a forge kind, or a
+// prefix.<name> or the shorthand host key set to a host name.
+func checkConfigKey(key, value string) error {
+	if err := config.CheckKey(key); err != nil {
+		return err
+	}
+	if _, ok := strings.CutPrefix(key, config.TransportPrefix); ok {
+		_, err := source.ParseTransport(value)
+		return err
+	}
+	if _, ok := strings.CutPrefix(key, config.ForgePrefix); ok {
+		if value == "github" {
+			return nil
+		}
+		if _, err := gitea.ParseKind(value); err != nil {
+			return fmt.Errorf("unknown forge %q: must be github, %s", value, strings.Join(gitea.Kinds, ", or "))
+		}
+		return nil
+	}
+	if prefix, ok := strings.CutPrefix(key, config.SourcePrefix); ok {
+		if err := source.CheckPrefix(prefix); err != nil {
+			return err
+		}
+		if !hostPattern.MatchString(value) {
+			return fmt.Errorf("invalid host %q: want a host name such as git.example.com", value)
+		}
+		return nil
+	}
+	if key == config.ShorthandHostKey {
+		if !hostPattern.MatchString(value) {
+			return fmt.Errorf("invalid host %q: want a host name such as github.example.com", value)
+		}
+		return nil
+	}
+	name, ok := strings.CutPrefix(key, config.DefaultPrefix)
+	if !ok {
+		return fmt.Errorf("unknown key %q: only %s<flag>, %s<host>, %s<host>, %s<name>, and %s keys are supported", key, config.DefaultPrefix, config.TransportPrefix, config.ForgePrefix, config.SourcePrefix, config.ShorthandHostKey)
+	}
+	flag, ok := flagNames()[name]
+	if !ok {
+		return fmt.Errorf("unknown key %q: no command has a --%s flag", key, name)
+	}
+	switch flag.Value.Type() {
+	case "bool":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("invalid value %q for --%s: must be true or false", value, name)
+		}
+	case "int", "int64":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("invalid value %q for --%s: must be a whole number", value, name)
+		}
+	}
+	return nil
+}
+
+// flagNames returns the flags of every command that can be given a default,
+// keyed by name.
+func flagNames() map[string]*pflag.Flag {
+	names := map[string]*pflag.Flag{}
+	var visit func(c *cobra.Command)
+	visit = func(c *cobra.Command) {
+		add := func(f *pflag.Flag) {
+			if f.Name != "help" && f.Name != "version" {
+				names[f.Name] = f
+			}
+		}
+		c.Flags().VisitAll(add)
+		c.PersistentFlags().VisitAll(add)
+		for _, child := range c.Commands() {
+			visit(child)
+		}
+	}
+	visit(rootCmd)
+	return names
+}
+
+// applyConfigDefaults sets the flags of cmd that were not given on the
+// command line to their configured defaults. Flags set this way are not
+// marked as changed, so they still count as not given.
+func applyConfigDefaults(cmd *cobra.Command) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("%w (fix or remove %s)", err, config.Path())
+	}
+	defaults := cfg.Defaults()
+	names := make([]string, 0, len(defaults))
+	for name := range defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || flag.Changed {
+			continue
+		}
+		if err := flag.Value.Set(defaults[name]); err != nil {
+			return fmt.Errorf("invalid %s%s in %s: %w", config.DefaultPrefix, name, config.Path(), err)
+		}
+	}
+	return nil
+}
+
+// applyCapabilities disables the capabilities the system configuration
+// denies.
+func applyCapabilities() error {
+	cfg, err := config.LoadSystem()
+	if err != nil {
+		return err
+	}
+	if err := capability.Configure(cfg); err != nil {
+		return fmt.Errorf("invalid system configuration %s: %w", config.SystemPath, err)
+	}
+	return nil
+}
+
+// completeConfigKey completes the keys of config subcommands.
+func completeConfigKey(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	if cmd.Name() != "set" {
+		cfg, err := config.Load()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return cfg.Keys(), cobra.ShellCompDirectiveNoFileComp
+	}
+	var keys []string
+	for name := range flagNames() {
+		keys = append(keys, config.DefaultPrefix+name)
+	}
+	for _, host := range []string{"github.com", "gitlab.com"} {
+		keys = append(keys, config.TransportPrefix+host)
+	}
+	keys = append(keys, config.ShorthandHostKey)
+	sort.Strings(keys)
+	return keys, cobra.ShellCompDirectiveNoFileComp
+}
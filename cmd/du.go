@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/size"
+	"github.com/spf13/cobra"
+)
+
+var duJSONFlag bool
+
+var duCmd = &cobra.Command{
+	Use:   "du",
+	Short: "Report disk usage per buried project",
+	Long: `Report the working-tree size and approximate packfile contribution of each
+buried project, largest first.
+
+The packed size is the on-disk size of the git objects that belong to the
+project: its files throughout the graveyard's history and, for projects buried
+with history, the objects of the original repository.`,
+	Example: `  bury-it du -g ~/graveyard`,
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		projects, err := gy.Projects()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		usages := make([]*graveyard.Usage, 0, len(projects))
+		for _, name := range projects {
+			usage, err := gy.DiskUsage(name)
+			if err != nil {
+				exitWithError(err)
+			}
+			usages = append(usages, usage)
+		}
+		sort.SliceStable(usages, func(i, j int) bool {
+			return usages[i].Packed > usages[j].Packed
+		})
+
+		if duJSONFlag {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(usages); err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+
+		var totalWorkTree, totalPacked int64
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PROJECT\tWORKTREE\tPACKED")
+		for _, u := range usages {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", u.Project, size.Format(u.WorkTree), size.Format(u.Packed))
+			totalWorkTree += u.WorkTree
+			totalPacked += u.Packed
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", "total", size.Format(totalWorkTree), size.Format(totalPacked))
+		_ = w.Flush()
+	},
+}
+
+func init() {
+	duCmd.Flags().BoolVar(&duJSONFlag, "json", false, "output usage as JSON")
+	rootCmd.AddCommand(duCmd)
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/deanhigh/bury-it/internal/source"
+	"github.com/spf13/cobra"
+)
+
+var (
+	expandSourceFlag string
+	expandRefFlag    string
+)
+
+var expandCmd = &cobra.Command{
+	Use:   "expand <project>",
+	Short: "Attach the git history of a snapshot-only burial",
+	Long: `Attach the original git history to a project buried with --drop-history,
+for when keeping it would have been the better choice. The project's original
+source, or --source if it has moved, is fetched and its history grafted under
+the project's directory with a subtree merge, as if it had been buried with
+history. The metadata's History Preserved field is set to Yes.
+
+The project's files are kept as they were buried. If the source has changed
+since, the files that differ are listed; use --ref to attach the history at an
+earlier branch, tag, or commit instead.`,
+	Example: `  # Attach the history of a project whose origin still exists
+  bury-it expand old-experiment -g ~/graveyard
+
+  # Attach the history of the tag the burial was made from
+  bury-it expand old-experiment -g ~/graveyard --ref v1.2.0`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProject,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkReadOnly("expanding a project"); err != nil {
+			exitWithError(err)
+		}
+		gy, project, err := openAliasedProject(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+
+		origin := expandSourceFlag
+		if origin == "" {
+			meta, err := gy.Metadata(project)
+			if err != nil {
+				exitWithError(err)
+			}
+			origin = meta.OriginalSource
+		}
+		url, err := fetchURL(origin)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		fmt.Printf("Fetching %s from %s...\n", expandRefFlag, url)
+		result, err := gy.Expand(project, url, expandRefFlag)
+		if err != nil {
+			exitWithError(err)
+		}
+		if err := commitMetadata(gy, project, "docs: bury-it - expanded "+project); err != nil {
+			exitWithError(err)
+		}
+		trackAccess(gy, project)
+		fmt.Printf("Attached the history of %s up to %s.\n", project, result.Commit)
+
+		if len(result.Changed) > 0 {
+			fmt.Println("")
+			fmt.Printf("Warning: %d file(s) differ between the buried snapshot and %s:\n", len(result.Changed), result.Commit)
+			for _, path := range result.Changed {
+				fmt.Printf("  %s\n", path)
+			}
+			fmt.Println("The buried files were kept. Undo with bury-it compact and retry with --ref to attach an earlier commit.")
+		}
+	},
+}
+
+func init() {
+	expandCmd.Flags().StringVarP(&expandSourceFlag, "source", "s", "", "repository to fetch the history from (default: the project's original source)")
+	expandCmd.Flags().StringVar(&expandRefFlag, "ref", "HEAD", "branch, tag, or commit of the source to attach")
+	rootCmd.AddCommand(expandCmd)
+}
+
+// fetchURL returns the URL or path to fetch a source from. Sources recorded
+// in metadata may be URLs of any host, while sources given by the user may
+// also use shorthand such as owner/repo.
+func fetchURL(input string) (string, error) {
+	if strings.Contains(input, "://") || source.SCPURLPattern.MatchString(input) {
+		if err := checkOffline("fetching " + input); err != nil {
+			return "", err
+		}
+		return input, nil
+	}
+	expanded, err := expandSource(input, "")
+	if err != nil {
+		return "", err
+	}
+	src, err := source.Parse(expanded)
+	if err != nil {
+		return "", fmt.Errorf("invalid source: %w", err)
+	}
+	if _, local := src.FilePath(); (src.Type == source.TypeRemote && !local) || src.IsDownload() {
+		if err := checkOffline("fetching " + input); err != nil {
+			return "", err
+		}
+	}
+	if err := src.Validate(); err != nil {
+		return "", err
+	}
+	return expanded, nil
+}
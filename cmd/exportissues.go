@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/archive"
+	"github.com/deanhigh/bury-it/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var exportIssuesMaxWaitFlag time.Duration
+
+var exportIssuesCmd = &cobra.Command{
+	Use:   "export-issues <project>",
+	Short: "Export or resume exporting the issues of a buried project",
+	Long: `Export every issue and pull request of a buried project's GitHub source into
+the project directory as .bury-it-issues.jsonl, one JSON object per line.
+
+The export proceeds one page at a time and records a checkpoint in the project
+directory after each page, so an export interrupted by a network failure or a
+rate limit continues where it stopped when the command is run again. Rate
+limits that reset within --max-wait are waited out; longer ones stop the
+export. Whatever was exported is committed, along with the checkpoint of an
+export that stopped, so the graveyard is left clean either way.
+
+Burials made with --with-issues run this automatically. GITHUB_TOKEN is used
+if set, which raises the rate limit considerably.`,
+	Example: `  # Resume an interrupted export
+  bury-it export-issues huge-project -g ~/graveyard
+
+  # Wait up to an hour for rate limits to reset
+  bury-it export-issues huge-project -g ~/graveyard --max-wait 1h`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProject,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkReadOnly("exporting issues"); err != nil {
+			exitWithError(err)
+		}
+		if err := checkOffline("exporting issues from GitHub"); err != nil {
+			exitWithError(err)
+		}
+		gy, err := openProjectGraveyard(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		project := args[0]
+		cp, err := archive.ExportIssues(gy, project, exportIssuesMaxWaitFlag, cfg.Forges())
+		if err != nil {
+			if cp != nil {
+				fmt.Printf("Run the command again to resume from issue %d.\n", cp.Exported+1)
+			}
+			exitWithError(err)
+		}
+		fmt.Printf("Exported %d issues and pull requests of %s.\n", cp.Exported, cp.Repository)
+	},
+}
+
+func init() {
+	exportIssuesCmd.Flags().DurationVar(&exportIssuesMaxWaitFlag, "max-wait", archive.DefaultIssueExportWait, "longest time to wait for a rate limit to reset before stopping")
+	rootCmd.AddCommand(exportIssuesCmd)
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/deanhigh/bury-it/internal/github"
+)
+
+// buryGitHubQuery lists the repositories matching a GitHub search query and
+// buries those chosen with --select or, on a terminal, at a prompt.
+func buryGitHubQuery(query string) {
+	if err := checkOffline("searching GitHub"); err != nil {
+		exitWithError(err)
+	}
+	repos, err := github.NewClient(github.TokenFromEnv()).SearchRepos(query)
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to search GitHub: %w", err))
+	}
+	if len(repos) == 0 {
+		fmt.Printf("No repositories match %q.\n", query)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tREPOSITORY\tARCHIVED\tLAST PUSH")
+	sources := make([]string, len(repos))
+	for i, repo := range repos {
+		archived := "no"
+		if repo.Archived {
+			archived = "yes"
+		}
+		pushed := "never"
+		if !repo.PushedAt.IsZero() {
+			pushed = repo.PushedAt.Format("2006-01-02")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", i+1, repo.FullName, archived, pushed)
+		sources[i] = repo.FullName
+	}
+	_ = w.Flush()
+	buryChosen(sources)
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/deanhigh/bury-it/internal/github"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+)
+
+// buryGitHubRepos buries the GitHub repositories matched out of the listed
+// ones, each as <prefix><repo>, and prints a table and a summary of the run;
+// whose tells whose repositories they are, as in "of acme". Repositories
+// already in the graveyard, under that name or buried from the same
+// repository under another, are skipped.
+func buryGitHubRepos(gy *graveyard.Graveyard, matched []github.Repository, listed int, whose, prefix string, dryRun bool) {
+	if len(matched) == 0 {
+		fmt.Printf("No repositories %s matched (%d listed).\n", whose, listed)
+		return
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].FullName < matched[j].FullName })
+	buriedFrom, err := buriedGitHubRepos(gy)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	statuses := make([]string, len(matched))
+	projects := make([]string, len(matched))
+	var buried, toBury int
+	var failures []string
+	for i, repo := range matched {
+		projects[i] = prefix + repo.Name
+		if existing, ok := buriedFrom[strings.ToLower(repo.FullName)]; ok {
+			statuses[i], projects[i] = "already buried", existing
+			continue
+		}
+		if gy.ProjectExists(projects[i]) {
+			statuses[i] = "already buried"
+			continue
+		}
+		toBury++
+		if dryRun {
+			statuses[i] = "would bury"
+			continue
+		}
+
+		fmt.Printf("Burying %s as %s...\n", repo.FullName, projects[i])
+		opts, err := burialOptions(repo.FullName)
+		if err == nil {
+			opts.Name = projects[i]
+			_, err = bury(opts)
+		}
+		if err != nil {
+			statuses[i] = "failed"
+			failures = append(failures, fmt.Sprintf("%s: %v", repo.FullName, err))
+			fmt.Printf("Failed to bury %s: %v\n", repo.FullName, err)
+			continue
+		}
+		statuses[i] = "buried"
+		buried++
+	}
+
+	if !dryRun && toBury > 0 {
+		fmt.Println("")
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tREPOSITORY\tLAST PUSH\tPROJECT")
+	for i, repo := range matched {
+		pushed := "never"
+		if !repo.PushedAt.IsZero() {
+			pushed = repo.PushedAt.Format("2006-01-02")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", statuses[i], repo.FullName, pushed, projects[i])
+	}
+	_ = w.Flush()
+
+	if dryRun {
+		fmt.Printf("\n%d of %d repositories %s would be buried.\n", toBury, listed, whose)
+		return
+	}
+	fmt.Printf("\nBuried %d of %d matching repositories %s.\n", buried, len(matched), whose)
+	if len(failures) > 0 {
+		exitWithError(fmt.Errorf("%d burials failed:\n  %s", len(failures), strings.Join(failures, "\n  ")))
+	}
+}
+
+// buriedGitHubRepos returns the projects of the graveyard buried from GitHub
+// repositories, keyed by the lowercase owner/repo they were buried from,
+// whichever URL they were cloned by.
+func buriedGitHubRepos(gy *graveyard.Graveyard) (map[string]string, error) {
+	names, err := gy.Projects()
+	if err != nil {
+		return nil, err
+	}
+	buried := make(map[string]string)
+	for _, name := range names {
+		meta, err := gy.Metadata(name)
+		if err != nil {
+			continue
+		}
+		if owner, repo, ok := github.ParseRepoURL(meta.OriginalSource); ok {
+			buried[strings.ToLower(owner+"/"+repo)] = name
+		}
+	}
+	return buried, nil
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/deanhigh/bury-it/internal/backup"
+	"github.com/deanhigh/bury-it/internal/display"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/size"
+	"github.com/spf13/cobra"
+)
+
+var graveyardOutFlag string
+
+var graveyardCmd = &cobra.Command{
+	Use:   "graveyard",
+	Short: "Back up and restore a whole graveyard",
+	Long: `Back up an entire graveyard to a single portable archive, or restore one.
+
+The archive holds every file of the graveyard, its git repository, index, and
+audit log included, together with a manifest of each file's SHA-256 digest, so
+that it can be kept offline or offsite and verified when restored.`,
+}
+
+var graveyardExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Bundle the graveyard into a portable archive",
+	Long: `Bundle the graveyard into a single archive with an integrity manifest. The
+archive is compressed according to the extension of --out: .tar.zst (or
+.tzst) for zstd, .tar.gz (or .tgz) for gzip, or .tar for none.
+
+The graveyard must have no uncommitted changes, so that the archive matches
+its latest commit.`,
+	Example: `  bury-it graveyard export --out graveyard-2025.tar.zst -g ~/graveyard`,
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkReadOnly("exporting a graveyard"); err != nil {
+			exitWithError(err)
+		}
+		if graveyardOutFlag == "" {
+			exitWithError(fmt.Errorf("--out is required"))
+		}
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		manifest, err := backup.ExportFile(gy, graveyardOutFlag, Version)
+		if err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("Exported %d projects (%d files, %s) at %s to %s\n",
+			len(manifest.Projects), len(manifest.Files), size.Format(manifest.Size()), manifest.Head[:12], display.Path(graveyardOutFlag))
+	},
+}
+
+var graveyardImportCmd = &cobra.Command{
+	Use:   "import <archive>",
+	Short: "Restore a graveyard from an archive",
+	Long: `Restore a graveyard exported with bury-it graveyard export into the path
+given by --graveyard, which must not exist or be empty.
+
+Every file is checked against the archive's manifest and the restored
+repository against the commit it was exported at. If anything does not match,
+nothing is restored.`,
+	Example: `  bury-it graveyard import graveyard-2025.tar.zst -g ~/restored-graveyard`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkReadOnly("importing a graveyard"); err != nil {
+			exitWithError(err)
+		}
+		if graveyardFlag == "" {
+			exitWithError(fmt.Errorf("--graveyard is required"))
+		}
+
+		manifest, err := backup.ImportFile(args[0], graveyardFlag)
+		if err != nil {
+			exitWithError(err)
+		}
+		if gy, err := graveyard.New(graveyardFlag); err == nil {
+			_ = gy.Remember()
+		}
+		fmt.Printf("Restored %d projects (%d files, %s) at %s to %s\n",
+			len(manifest.Projects), len(manifest.Files), size.Format(manifest.Size()), manifest.Head[:12], display.Path(graveyardFlag))
+	},
+}
+
+func init() {
+	graveyardExportCmd.Flags().StringVarP(&graveyardOutFlag, "out", "o", "", "archive to write (.tar.zst, .tar.gz, or .tar)")
+	graveyardCmd.AddCommand(graveyardExportCmd, graveyardImportCmd)
+	rootCmd.AddCommand(graveyardCmd)
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/spf13/cobra"
+)
+
+var (
+	grepProjectFlag    string
+	grepIgnoreCaseFlag bool
+	grepJSONFlag       bool
+)
+
+var grepCmd = &cobra.Command{
+	Use:   "grep <pattern>",
+	Short: "Search the contents of buried projects",
+	Long: `Search the file contents of all buried projects in the graveyard.
+
+The search is delegated to git grep, so only files committed to the graveyard
+are searched and the pattern uses git grep's basic regular expression syntax.`,
+	Example: `  # Find which buried project defined a function
+  bury-it grep "func parseConfig" -g ~/graveyard
+
+  # Limit the search to a single project and emit JSON
+  bury-it grep TODO -g ~/graveyard --project old-project --json`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		matches, err := gy.Grep(args[0], grepProjectFlag, grepIgnoreCaseFlag)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		if grepJSONFlag {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if matches == nil {
+				matches = []graveyard.Match{}
+			}
+			if err := enc.Encode(matches); err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+
+		for _, m := range matches {
+			fmt.Printf("%s/%s:%d:%s\n", m.Project, m.Path, m.Line, m.Text)
+		}
+	},
+}
+
+func init() {
+	grepCmd.Flags().StringVarP(&grepProjectFlag, "project", "p", "", "limit the search to a single buried project")
+	_ = grepCmd.RegisterFlagCompletionFunc("project", completeProjectFlag)
+	grepCmd.Flags().BoolVarP(&grepIgnoreCaseFlag, "ignore-case", "i", false, "match case-insensitively")
+	grepCmd.Flags().BoolVar(&grepJSONFlag, "json", false, "output matches as JSON")
+	rootCmd.AddCommand(grepCmd)
+}
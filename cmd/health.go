@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/health"
+	"github.com/deanhigh/bury-it/internal/size"
+	"github.com/spf13/cobra"
+)
+
+var (
+	healthMaxSizeFlag string
+	healthIgnoreFlags []string
+	healthJSONFlag    bool
+)
+
+var healthCmd = &cobra.Command{
+	Use:   "health",
+	Short: "Check the graveyard for problems",
+	Long: `Run every graveyard health check in one pass and report which passed:
+
+  - top-level directories without a .bury-it.md metadata file
+  - tracked metadata, or search index entries, of projects that are missing
+  - metadata that cannot be parsed
+  - uncommitted changes in the graveyard
+  - projects larger than --max-size, in files or packed objects
+  - projects whose review date has passed
+
+The command exits with status 1 if any check fails, for use in automation.
+Directories that belong in the graveyard but are not projects, such as a
+generated site, can be skipped with --ignore.`,
+	Example: `  bury-it health -g ~/graveyard
+  bury-it health -g ~/graveyard --max-size 1GB --ignore docs --json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+		opts := health.Options{Ignore: healthIgnoreFlags, Now: time.Now()}
+		if healthMaxSizeFlag != "" {
+			if opts.MaxProjectSize, err = size.Parse(healthMaxSizeFlag); err != nil {
+				exitWithError(fmt.Errorf("invalid --max-size: %w", err))
+			}
+		}
+
+		report, err := health.Run(gy, opts)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		if healthJSONFlag {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				exitWithError(err)
+			}
+		} else {
+			for _, check := range report.Checks {
+				status := "PASS"
+				if !check.Passed {
+					status = "FAIL"
+				}
+				fmt.Printf("%s  %s\n", status, check.Name)
+				for _, problem := range check.Problems {
+					fmt.Printf("        %s\n", problem)
+				}
+			}
+			fmt.Println("")
+			if report.Healthy {
+				fmt.Println("The graveyard is healthy.")
+			} else {
+				fmt.Println("The graveyard has problems.")
+			}
+		}
+
+		if !report.Healthy {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	healthCmd.Flags().StringVar(&healthMaxSizeFlag, "max-size", "500MB", "size above which a project is oversized, or empty to skip the check")
+	healthCmd.Flags().StringArrayVar(&healthIgnoreFlags, "ignore", nil, "top-level directory that is not a project (repeatable, glob)")
+	healthCmd.Flags().BoolVar(&healthJSONFlag, "json", false, "output the report as JSON")
+	rootCmd.AddCommand(healthCmd)
+}
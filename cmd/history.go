@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history <project> [-- <git log args> [-- <paths>]]",
+	Short: "Run git log over a buried project's history",
+	Long: `Run git log over the history of a buried project, without needing to know how
+it is laid out in the graveyard.
+
+The graveyard commits that touched the project come first, including those
+of its earlier versions. For projects buried with their history, the
+original commits follow, in which the project's files are at the root.
+
+Arguments after -- are passed to git log. Paths after a second -- are
+relative to the project and narrow both parts of the history. The output is
+not paged; pipe it to a pager if needed.`,
+	Example: `  bury-it history old-tool
+  bury-it history old-tool -- --oneline --since=2020-01-01
+  bury-it history old-tool -- --stat -- src/config.go`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if dash := cmd.ArgsLenAtDash(); dash > 1 || (dash < 0 && len(args) > 1) {
+			return fmt.Errorf("git log arguments must follow --")
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
+	ValidArgsFunction: completeProject,
+	Run: func(cmd *cobra.Command, args []string) {
+		gy, project, err := openAliasedProject(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+
+		logArgs, paths := args[1:], []string(nil)
+		if i := slices.Index(logArgs, "--"); i >= 0 {
+			logArgs, paths = logArgs[:i], logArgs[i+1:]
+		}
+		scopes, err := gy.HistoryScopes(project, paths)
+		if err != nil {
+			exitWithError(err)
+		}
+		trackAccess(gy, project)
+
+		for _, scope := range scopes {
+			gitArgs := append([]string{"log"}, logArgs...)
+			gitArgs = append(gitArgs, scope.Revs...)
+			gitArgs = append(gitArgs, "--")
+			gitArgs = append(gitArgs, scope.Paths...)
+			if err := git.Passthrough(gy.Path, os.Stdout, os.Stderr, gitArgs...); err != nil {
+				exitWithError(err)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/deanhigh/bury-it/internal/audit"
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/index"
+	"github.com/spf13/cobra"
+)
+
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Build the full-text search index for the graveyard",
+	Long: `Build an on-disk search index covering the file contents, metadata, and
+commit messages of every buried project. The index is stored in the graveyard
+under .bury-it/index/, a file for each project, committed, and kept up to date
+by later burials. As burials of different projects change different files,
+graveyards cloned on several machines merge without conflicts in the index.
+Rebuilding it converts an index in the old single-file format.
+
+Query the index with bury-it search --content.`,
+	Example: `  bury-it index -g ~/graveyard`,
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkReadOnly("building the search index"); err != nil {
+			exitWithError(err)
+		}
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		fmt.Println("Indexing graveyard...")
+		idx, err := index.Build(gy)
+		if err != nil {
+			exitWithError(err)
+		}
+		if err := idx.Save(gy.Path); err != nil {
+			exitWithError(err)
+		}
+
+		if err := index.Stage(gy.Path); err != nil {
+			exitWithError(err)
+		}
+		changed, err := git.HasStagedChanges(gy.Path)
+		if err != nil {
+			exitWithError(err)
+		}
+		if changed {
+			if err := audit.Record(gy.Path, ""); err != nil {
+				exitWithError(err)
+			}
+			if err := git.Commit(gy.Path, "docs: bury-it - rebuilt search index"); err != nil {
+				exitWithError(fmt.Errorf("failed to commit: %w", err))
+			}
+		}
+
+		fmt.Printf("Indexed %d documents.\n", len(idx.Documents))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(indexCmd)
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/deanhigh/bury-it/internal/display"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/metadata"
+	"github.com/spf13/cobra"
+)
+
+var infoJSONFlag bool
+
+var infoCmd = &cobra.Command{
+	Use:   "info <project>",
+	Short: "Show the details of a buried project",
+	Long: `Show the details recorded about a buried project: where it came from, when
+it was buried, its tags, its review date, and the projects it superseded or
+was superseded by.`,
+	Example:           `  bury-it info old-experiment -g ~/graveyard`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProject,
+	Run: func(cmd *cobra.Command, args []string) {
+		gy, project, err := openAliasedProject(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+
+		meta, err := gy.Metadata(project)
+		if err != nil {
+			exitWithError(err)
+		}
+		trackAccess(gy, project)
+
+		entry := newListEntry(project, meta)
+		entry.Checklist = checklistProgress(gy.ProjectPath(project))
+
+		if infoJSONFlag {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(entry); err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+
+		printProjectDetails(gy, project, meta, entry)
+	},
+}
+
+// printProjectDetails prints the details recorded about a buried project.
+func printProjectDetails(gy *graveyard.Graveyard, project string, meta *metadata.Metadata, entry listEntry) {
+	history := "no"
+	if meta.HistoryPreserved {
+		history = "yes"
+	}
+	fmt.Printf("Project:        %s\n", project)
+	fmt.Printf("Path:           %s\n", display.Path(gy.ProjectPath(project)))
+	fmt.Printf("Original:       %s\n", meta.OriginalSource)
+	fmt.Printf("Buried on:      %s\n", meta.BuriedAt.Format("2006-01-02"))
+	fmt.Printf("History:        %s\n", history)
+	if meta.Version > 0 {
+		fmt.Printf("Version:        %d\n", meta.Version)
+	}
+	if meta.Description != "" {
+		fmt.Printf("Description:    %s\n", meta.Description)
+	}
+	if len(meta.Topics) > 0 {
+		fmt.Printf("Topics:         %s\n", strings.Join(meta.Topics, " "))
+	}
+	if meta.Owner != "" {
+		fmt.Printf("Owner:          %s\n", meta.Owner)
+	}
+	if len(meta.Tags) > 0 {
+		fmt.Printf("Tags:           %s\n", strings.Join(meta.Tags, " "))
+	}
+	if !meta.ReviewAfter.IsZero() {
+		fmt.Printf("Review after:   %s\n", meta.ReviewAfter.Format("2006-01-02"))
+	} else if meta.InvalidReviewAfter != "" {
+		fmt.Printf("Review after:   %s (not a date, want YYYY-MM-DD)\n", meta.InvalidReviewAfter)
+	}
+	if meta.MonthlyCostBefore != nil {
+		fmt.Printf("Cost before:    %s/month\n", metadata.FormatCost(*meta.MonthlyCostBefore))
+	}
+	if meta.MonthlyCostAfter != nil {
+		fmt.Printf("Cost after:     %s/month\n", metadata.FormatCost(*meta.MonthlyCostAfter))
+	}
+	if entry.Checklist != nil {
+		fmt.Printf("Checklist:      %d/%d done\n", entry.Checklist.Done, entry.Checklist.Total)
+	}
+	if meta.Supersedes != "" || meta.SupersededBy != "" {
+		printLinks(meta)
+	}
+}
+
+func init() {
+	infoCmd.Flags().BoolVar(&infoJSONFlag, "json", false, "output the project as JSON")
+	rootCmd.AddCommand(infoCmd)
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/deanhigh/bury-it/internal/size"
+	"github.com/spf13/cobra"
+)
+
+var (
+	largestProjectFlag string
+	largestLimitFlag   int
+	largestJSONFlag    bool
+)
+
+var largestCmd = &cobra.Command{
+	Use:   "largest",
+	Short: "List the largest files in the history of buried projects",
+	Long: `List the biggest blobs stored across the git history of buried projects,
+including file versions that no longer exist in the latest snapshot. Use this
+to decide what to strip from the graveyard or move to Git LFS.`,
+	Example: `  # Top 20 blobs across the whole graveyard
+  bury-it largest -g ~/graveyard
+
+  # Top 5 blobs in a single project
+  bury-it largest -g ~/graveyard --project old-project --limit 5`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		blobs, err := gy.LargestBlobs(largestProjectFlag, largestLimitFlag)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		if largestJSONFlag {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(blobs); err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SIZE\tPROJECT\tPATH\tBLOB")
+		for _, b := range blobs {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", size.Format(b.Size), b.Project, b.Path, b.Hash[:12])
+		}
+		_ = w.Flush()
+	},
+}
+
+func init() {
+	largestCmd.Flags().StringVarP(&largestProjectFlag, "project", "p", "", "limit the report to a single buried project")
+	_ = largestCmd.RegisterFlagCompletionFunc("project", completeProjectFlag)
+	largestCmd.Flags().IntVar(&largestLimitFlag, "limit", 20, "maximum number of blobs to list (0 for all)")
+	largestCmd.Flags().BoolVar(&largestJSONFlag, "json", false, "output blobs as JSON")
+	rootCmd.AddCommand(largestCmd)
+}
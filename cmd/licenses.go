@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/deanhigh/bury-it/internal/licenses"
+	"github.com/spf13/cobra"
+)
+
+var licensesJSONFlag bool
+
+var licensesCmd = &cobra.Command{
+	Use:   "licenses",
+	Short: "Report the licenses of buried projects and their dependencies",
+	Long: `Report the licenses found across the graveyard, for legal teams that need to
+know what obligations the archive still carries. Each project's license files,
+such as LICENSE and COPYING, are recognized from their text, and the licenses
+package.json files declare are read. Files under node_modules, vendor, or
+third_party are reported as the licenses of those vendored dependencies.
+
+Licenses are grouped by kind: strong copyleft first, then licenses that were
+not recognized and need reading, weak copyleft, permissive, and public domain.
+Projects with no license of their own, which leaves all rights with their
+authors, are listed last. Recognition covers the common open source licenses
+and is no substitute for legal review.`,
+	Example: `  bury-it licenses -g ~/graveyard
+  bury-it licenses -g ~/graveyard --json > licenses.json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+		projects, err := gy.Projects()
+		if err != nil {
+			exitWithError(err)
+		}
+		findings, err := licenses.Scan(gy)
+		if err != nil {
+			exitWithError(err)
+		}
+		report := licenses.Aggregate(projects, findings)
+
+		if licensesJSONFlag {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+
+		if len(report.Licenses) == 0 {
+			fmt.Println("No licenses found.")
+		}
+		for _, s := range report.Licenses {
+			fmt.Printf("%s (%s)\n", s.License, s.Kind)
+			for _, f := range s.Findings {
+				if f.Dependency != "" {
+					fmt.Printf("  %s: %s\n", f.Project, f.Dependency)
+				} else {
+					fmt.Printf("  %s\n", f.Project)
+				}
+			}
+			fmt.Println("")
+		}
+		if len(report.Unlicensed) > 0 {
+			fmt.Printf("No license of their own (%d): %s\n", len(report.Unlicensed), strings.Join(report.Unlicensed, ", "))
+		}
+	},
+}
+
+func init() {
+	licensesCmd.Flags().BoolVar(&licensesJSONFlag, "json", false, "output the report as JSON, with the file each license was found in")
+	rootCmd.AddCommand(licensesCmd)
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/deanhigh/bury-it/internal/metadata"
+	"github.com/spf13/cobra"
+)
+
+var (
+	linkSupersededByFlag string
+	linkSupersedesFlag   string
+)
+
+var linkCmd = &cobra.Command{
+	Use:   "link <project>",
+	Short: "Record which projects replaced or were replaced by a buried project",
+	Long: `Record the relationship between a buried project and the project that
+replaced it, or the project it replaced, to keep a trail from experiments to
+their successors. Either side may be a URL or the name of another project in
+the graveyard.
+
+Links are stored in the project's metadata file and committed, and shown by
+bury-it list and bury-it info. Pass an empty value to remove a link.`,
+	Example: `  # Record the repository that replaced an experiment
+  bury-it link old-experiment --superseded-by https://github.com/org/new-service -g ~/graveyard
+
+  # Record that one buried project replaced another
+  bury-it link prototype-v2 --supersedes prototype-v1 -g ~/graveyard
+
+  # Remove a link
+  bury-it link old-experiment --superseded-by "" -g ~/graveyard`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProject,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkReadOnly("linking projects"); err != nil {
+			exitWithError(err)
+		}
+		flags := cmd.Flags()
+		if !flags.Changed("superseded-by") && !flags.Changed("supersedes") {
+			exitWithError(fmt.Errorf("--superseded-by or --supersedes is required"))
+		}
+
+		gy, err := openProjectGraveyard(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+
+		project := args[0]
+		links := []struct {
+			flag  string
+			key   string
+			value string
+		}{
+			{"superseded-by", metadata.SupersededByField, linkSupersededByFlag},
+			{"supersedes", metadata.SupersedesField, linkSupersedesFlag},
+		}
+		for _, link := range links {
+			if !flags.Changed(link.flag) {
+				continue
+			}
+			if link.value == project {
+				exitWithError(fmt.Errorf("a project cannot be linked to itself"))
+			}
+			if err := metadata.CheckValue(link.value); err != nil {
+				exitWithError(err)
+			}
+			if err := gy.SetField(project, link.key, link.value); err != nil {
+				exitWithError(err)
+			}
+		}
+		if err := commitMetadata(gy, project, "docs: bury-it - linked "+project); err != nil {
+			exitWithError(err)
+		}
+
+		meta, err := gy.Metadata(project)
+		if err != nil {
+			exitWithError(err)
+		}
+		printLinks(meta)
+	},
+}
+
+func init() {
+	linkCmd.Flags().StringVar(&linkSupersededByFlag, "superseded-by", "", "URL or project name of the project that replaced this one")
+	linkCmd.Flags().StringVar(&linkSupersedesFlag, "supersedes", "", "URL or project name of the project this one replaced")
+	rootCmd.AddCommand(linkCmd)
+}
+
+// printLinks prints the relationship links of a project.
+func printLinks(meta *metadata.Metadata) {
+	if meta.Supersedes == "" && meta.SupersededBy == "" {
+		fmt.Println("No links recorded.")
+		return
+	}
+	if meta.Supersedes != "" {
+		fmt.Printf("Supersedes:     %s\n", meta.Supersedes)
+	}
+	if meta.SupersededBy != "" {
+		fmt.Printf("Superseded by:  %s\n", meta.SupersededBy)
+	}
+}
@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/checklist"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/metadata"
+	"github.com/spf13/cobra"
+)
+
+var (
+	listTagFlags       []string
+	listJSONFlag       bool
+	listIncompleteFlag bool
+	listVersionsFlag   bool
+)
+
+// listEntry is a buried project as printed by the list command.
+type listEntry struct {
+	Project          string          `json:"project"`
+	OriginalSource   string          `json:"original_source"`
+	BuriedAt         time.Time       `json:"buried_at"`
+	HistoryPreserved bool            `json:"history_preserved"`
+	Description      string          `json:"description,omitempty"`
+	Topics           []string        `json:"topics,omitempty"`
+	Owner            string          `json:"owner,omitempty"`
+	Tags             []string        `json:"tags"`
+	Supersedes       string          `json:"supersedes,omitempty"`
+	SupersededBy     string          `json:"superseded_by,omitempty"`
+	Checklist        *checklistCount `json:"checklist,omitempty"`
+	MonthlyCost      *costs          `json:"monthly_cost,omitempty"`
+	Versions         []version       `json:"versions,omitempty"`
+}
+
+// version is one burial of a project buried more than once.
+type version struct {
+	Version          int       `json:"version"`
+	Tag              string    `json:"tag"`
+	Commit           string    `json:"commit"`
+	OriginalSource   string    `json:"original_source,omitempty"`
+	BuriedAt         time.Time `json:"buried_at,omitempty"`
+	HistoryPreserved bool      `json:"history_preserved"`
+}
+
+// projectVersions returns the lineage of a project buried more than once, or
+// nil for a project buried once.
+func projectVersions(gy *graveyard.Graveyard, name string) ([]version, error) {
+	tagged, err := gy.Versions(name)
+	if err != nil {
+		return nil, err
+	}
+	var versions []version
+	for _, v := range tagged {
+		entry := version{Version: v.Number, Tag: v.Tag, Commit: v.Commit}
+		if v.Metadata != nil {
+			entry.OriginalSource = v.Metadata.OriginalSource
+			entry.BuriedAt = v.Metadata.BuriedAt
+			entry.HistoryPreserved = v.Metadata.HistoryPreserved
+		}
+		versions = append(versions, entry)
+	}
+	return versions, nil
+}
+
+// costs are the monthly running costs recorded for a project.
+type costs struct {
+	Before *float64 `json:"before,omitempty"`
+	After  *float64 `json:"after,omitempty"`
+}
+
+// checklistCount counts the ticked items of a decommissioning checklist.
+type checklistCount struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// checklistProgress returns the progress of a project's decommissioning
+// checklist, or nil if it has none.
+func checklistProgress(dir string) *checklistCount {
+	items, err := checklist.Read(dir)
+	if err != nil || len(items) == 0 {
+		return nil
+	}
+	p := &checklistCount{Total: len(items)}
+	for _, item := range items {
+		if item.Done {
+			p.Done++
+		}
+	}
+	return p
+}
+
+// newListEntry returns the list entry of a project.
+func newListEntry(name string, meta *metadata.Metadata) listEntry {
+	tags := meta.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	entry := listEntry{
+		Project:          name,
+		OriginalSource:   meta.OriginalSource,
+		BuriedAt:         meta.BuriedAt,
+		HistoryPreserved: meta.HistoryPreserved,
+		Description:      meta.Description,
+		Topics:           meta.Topics,
+		Owner:            meta.Owner,
+		Tags:             tags,
+		Supersedes:       meta.Supersedes,
+		SupersededBy:     meta.SupersededBy,
+	}
+	if meta.MonthlyCostBefore != nil || meta.MonthlyCostAfter != nil {
+		entry.MonthlyCost = &costs{Before: meta.MonthlyCostBefore, After: meta.MonthlyCostAfter}
+	}
+	return entry
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List buried projects",
+	Long: `List the projects buried in the graveyard with their burial date, tags,
+and the project that superseded them, if recorded with bury-it link.
+
+With --tag, only projects carrying every given tag are listed. With
+--incomplete, only projects whose decommissioning checklist still has open
+items are listed, oldest burial first, so unfinished sunsets stand out. With
+--versions, only projects buried more than once with --new-version are listed,
+one line per version, to show their lineage.`,
+	Example: `  # List everything
+  bury-it list -g ~/graveyard
+
+  # List machine learning projects buried in 2023
+  bury-it list -g ~/graveyard --tag ml --tag 2023
+
+  # List burials whose decommissioning is unfinished
+  bury-it list -g ~/graveyard --incomplete
+
+  # Show every version of projects that were buried more than once
+  bury-it list -g ~/graveyard --versions`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		projects, err := taggedProjects(gy, listTagFlags)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		entries := make([]listEntry, 0, len(projects))
+		for _, name := range projects {
+			meta, err := gy.Metadata(name)
+			if err != nil {
+				exitWithError(err)
+			}
+			entry := newListEntry(name, meta)
+			entry.Checklist = checklistProgress(gy.ProjectPath(name))
+			if listIncompleteFlag && (entry.Checklist == nil || entry.Checklist.Done == entry.Checklist.Total) {
+				continue
+			}
+			if listVersionsFlag {
+				entry.Versions, err = projectVersions(gy, name)
+				if err != nil {
+					exitWithError(err)
+				}
+				if len(entry.Versions) == 0 {
+					continue
+				}
+			}
+			entries = append(entries, entry)
+		}
+		if listIncompleteFlag {
+			sort.SliceStable(entries, func(i, j int) bool {
+				return entries[i].BuriedAt.Before(entries[j].BuriedAt)
+			})
+		}
+
+		if listJSONFlag {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(entries); err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if listVersionsFlag {
+			fmt.Fprintln(w, "PROJECT\tVERSION\tBURIED ON\tHISTORY\tORIGINAL SOURCE\tTAG")
+			for _, e := range entries {
+				for _, v := range e.Versions {
+					history := "no"
+					if v.HistoryPreserved {
+						history = "yes"
+					}
+					fmt.Fprintf(w, "%s\tv%d\t%s\t%s\t%s\t%s\n", e.Project, v.Version, v.BuriedAt.Format("2006-01-02"), history, v.OriginalSource, v.Tag)
+				}
+			}
+			_ = w.Flush()
+			return
+		}
+		if listIncompleteFlag {
+			fmt.Fprintln(w, "PROJECT\tBURIED ON\tAGE\tCHECKLIST\tTAGS")
+			for _, e := range entries {
+				days := int(time.Since(e.BuriedAt).Hours() / 24)
+				fmt.Fprintf(w, "%s\t%s\t%dd\t%d/%d done\t%s\n", e.Project, e.BuriedAt.Format("2006-01-02"), days,
+					e.Checklist.Done, e.Checklist.Total, strings.Join(e.Tags, " "))
+			}
+			_ = w.Flush()
+			return
+		}
+		fmt.Fprintln(w, "PROJECT\tBURIED ON\tHISTORY\tTAGS\tSUPERSEDED BY")
+		for _, e := range entries {
+			history := "no"
+			if e.HistoryPreserved {
+				history = "yes"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.Project, e.BuriedAt.Format("2006-01-02"), history, strings.Join(e.Tags, " "), e.SupersededBy)
+		}
+		_ = w.Flush()
+	},
+}
+
+func init() {
+	listCmd.Flags().StringArrayVar(&listTagFlags, "tag", nil, "only list projects with this tag (repeatable)")
+	listCmd.Flags().BoolVar(&listJSONFlag, "json", false, "output projects as JSON")
+	listCmd.Flags().BoolVar(&listIncompleteFlag, "incomplete", false, "only list projects with open decommissioning checklist items, oldest first")
+	listCmd.Flags().BoolVar(&listVersionsFlag, "versions", false, "only list projects buried more than once, with every version")
+	listCmd.MarkFlagsMutuallyExclusive("incomplete", "versions")
+	rootCmd.AddCommand(listCmd)
+}
+
+// taggedProjects returns the buried projects that carry every one of tags.
+func taggedProjects(gy *graveyard.Graveyard, tags []string) ([]string, error) {
+	projects, err := gy.Projects()
+	if err != nil || len(tags) == 0 {
+		return projects, err
+	}
+
+	var matched []string
+	for _, name := range projects {
+		meta, err := gy.Metadata(name)
+		if err != nil {
+			return nil, err
+		}
+		if meta.HasTags(tags...) {
+			matched = append(matched, name)
+		}
+	}
+	return matched, nil
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/deanhigh/bury-it/internal/github"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mineArchivedFlag     bool
+	mineIncludeForksFlag bool
+	minePrefixFlag       string
+	mineDryRunFlag       bool
+)
+
+var mineCmd = &cobra.Command{
+	Use:   "mine",
+	Short: "Bury your own archived GitHub repositories",
+	Long: `List the repositories you own on GitHub, as the user GITHUB_TOKEN or
+GH_TOKEN authenticates, and bury those that are archived, with --archived,
+into the graveyard in one run. Forks are left out unless --include-forks is
+given.
+
+Each repository is buried under its own name, or with a prefix set by
+--prefix. Repositories already in the graveyard under that name are skipped,
+as are those buried from the same repository under another name, so running
+mine again only buries what was archived since.
+
+The burial flags, such as --drop-history, --owner, or --with-issues, apply to
+every repository. A failed burial is reported and the run continues with the
+next repository.`,
+	Example: `  # See which of your archived repositories would be buried
+  bury-it mine --archived -g ~/graveyard --dry-run
+
+  # Bury them
+  bury-it mine --archived -g ~/graveyard`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !mineArchivedFlag {
+			exitWithError(fmt.Errorf("choose the repositories to bury with --archived"))
+		}
+		if len(sourceFlags) > 0 || nameFlag != "" {
+			exitWithError(fmt.Errorf("--source and --name cannot be used with mine, which buries each repository as <prefix><repo>"))
+		}
+		token := github.TokenFromEnv()
+		if token == "" {
+			exitWithError(fmt.Errorf("mine requires GITHUB_TOKEN or GH_TOKEN to be set, to list your repositories"))
+		}
+		if err := checkOffline("listing your repositories"); err != nil {
+			exitWithError(err)
+		}
+		if !mineDryRunFlag {
+			if err := checkReadOnly("burying your repositories"); err != nil {
+				exitWithError(err)
+			}
+		}
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		repos, err := github.NewClient(token).ListUserRepos()
+		if err != nil {
+			exitWithError(fmt.Errorf("failed to list your repositories: %w", err))
+		}
+		var matched []github.Repository
+		for _, repo := range repos {
+			if repo.Fork && !mineIncludeForksFlag {
+				continue
+			}
+			if mineArchivedFlag && !repo.Archived {
+				continue
+			}
+			matched = append(matched, repo)
+		}
+		buryGitHubRepos(gy, matched, len(repos), "you own", minePrefixFlag, mineDryRunFlag)
+	},
+}
+
+func init() {
+	mineCmd.Flags().BoolVar(&mineArchivedFlag, "archived", false, "bury the repositories archived on GitHub")
+	mineCmd.Flags().BoolVar(&mineIncludeForksFlag, "include-forks", false, "bury forks too")
+	mineCmd.Flags().StringVar(&minePrefixFlag, "prefix", "", "prefix of the project names")
+	mineCmd.Flags().BoolVar(&mineDryRunFlag, "dry-run", false, "list the repositories that would be buried without burying them")
+	addBurialFlags(mineCmd.Flags())
+	_ = mineCmd.Flags().MarkHidden("source")
+	_ = mineCmd.Flags().MarkHidden("name")
+	rootCmd.AddCommand(mineCmd)
+}
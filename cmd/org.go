@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/age"
+	"github.com/deanhigh/bury-it/internal/github"
+	"github.com/spf13/cobra"
+)
+
+var (
+	orgArchivedOnlyFlag bool
+	orgNotPushedForFlag string
+	orgIncludeForksFlag bool
+	orgPrefixFlag       string
+	orgDryRunFlag       bool
+)
+
+var orgCmd = &cobra.Command{
+	Use:   "org <org>",
+	Short: "Bury the archived or stale repositories of a GitHub organization",
+	Long: `List the repositories of a GitHub organization and bury those that are
+archived, with --archived-only, or have had nothing pushed for a given time,
+with --not-pushed-for, into the graveyard in one run. With both, only archived
+repositories that are also stale are buried. Forks are left out unless
+--include-forks is given.
+
+Each repository is buried as <org>-<repo>, so that repositories of several
+organizations can share a graveyard; --prefix sets another prefix, or none
+with --prefix "". Repositories already in the graveyard under that name are
+skipped, as are those buried from the same repository under another name, so
+an interrupted run can simply be started again.
+
+GITHUB_TOKEN or GH_TOKEN is used to list and clone private repositories. The
+burial flags, such as --drop-history, --owner, or --with-issues, apply to
+every repository. A failed burial is reported and the run continues with the
+next repository.`,
+	Example: `  # See which archived repositories would be buried
+  bury-it org acme --archived-only -g ~/graveyard --dry-run
+
+  # Bury them, as acme-<repo>
+  bury-it org acme --archived-only -g ~/graveyard
+
+  # Bury repositories nothing was pushed to for two years, archived or not
+  bury-it org acme --not-pushed-for 2y -g ~/graveyard --drop-history`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		org := args[0]
+		if !orgArchivedOnlyFlag && orgNotPushedForFlag == "" {
+			exitWithError(fmt.Errorf("choose the repositories to bury with --archived-only, --not-pushed-for, or both"))
+		}
+		var stale *age.Span
+		if orgNotPushedForFlag != "" {
+			span, err := age.Parse(orgNotPushedForFlag)
+			if err != nil {
+				exitWithError(fmt.Errorf("invalid --not-pushed-for: %w", err))
+			}
+			stale = &span
+		}
+		if len(sourceFlags) > 0 || nameFlag != "" {
+			exitWithError(fmt.Errorf("--source and --name cannot be used with org, which buries each repository as <prefix><repo>"))
+		}
+		prefix := org + "-"
+		if cmd.Flags().Changed("prefix") {
+			prefix = orgPrefixFlag
+		}
+		if err := checkOffline("listing an organization's repositories"); err != nil {
+			exitWithError(err)
+		}
+		if !orgDryRunFlag {
+			if err := checkReadOnly("burying an organization's repositories"); err != nil {
+				exitWithError(err)
+			}
+		}
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		repos, err := github.NewClient(github.TokenFromEnv()).ListOrgRepos(org)
+		if err != nil {
+			exitWithError(fmt.Errorf("failed to list the repositories of %s: %w", org, err))
+		}
+		var matched []github.Repository
+		for _, repo := range repos {
+			if repo.Fork && !orgIncludeForksFlag {
+				continue
+			}
+			if orgArchivedOnlyFlag && !repo.Archived {
+				continue
+			}
+			if stale != nil && !repo.PushedAt.Before(stale.Before(time.Now())) {
+				continue
+			}
+			matched = append(matched, repo)
+		}
+		buryGitHubRepos(gy, matched, len(repos), "of "+org, prefix, orgDryRunFlag)
+	},
+}
+
+func init() {
+	orgCmd.Flags().BoolVar(&orgArchivedOnlyFlag, "archived-only", false, "bury only repositories archived on GitHub")
+	orgCmd.Flags().StringVar(&orgNotPushedForFlag, "not-pushed-for", "", "bury only repositories nothing was pushed to for this long (e.g. 2y or 18mo)")
+	orgCmd.Flags().BoolVar(&orgIncludeForksFlag, "include-forks", false, "bury forks too")
+	orgCmd.Flags().StringVar(&orgPrefixFlag, "prefix", "", "prefix of the project names (default <org>-)")
+	orgCmd.Flags().BoolVar(&orgDryRunFlag, "dry-run", false, "list the repositories that would be buried without burying them")
+	addBurialFlags(orgCmd.Flags())
+	_ = orgCmd.Flags().MarkHidden("source")
+	_ = orgCmd.Flags().MarkHidden("name")
+	rootCmd.AddCommand(orgCmd)
+}
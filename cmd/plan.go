@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/deanhigh/bury-it/internal/archive"
+	"github.com/deanhigh/bury-it/internal/display"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/metadata"
+	"github.com/deanhigh/bury-it/internal/size"
+	"github.com/spf13/cobra"
+)
+
+var (
+	planOutFlag  string
+	planJSONFlag bool
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Show what a burial would do without changing anything",
+	Long: `Work out what burying a source would do, without changing the graveyard:
+whether the source is cloned, the project directory, the commit message, the
+files added, and an estimate of the size added to the graveyard.
+
+Takes the same flags as a burial. With --out, the plan is saved to a file that
+bury-it apply carries out later. The plan pins the source's current commit, so
+applying it fails if the source has changed in the meantime.`,
+	Example: `  # Review a burial, then carry it out
+  bury-it plan -s ./my-experiment -g ~/graveyard --out my-experiment.plan.json
+  bury-it apply my-experiment.plan.json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(sourceFlags) == 0 {
+			exitWithError(fmt.Errorf("--source is required"))
+		}
+		if len(sourceFlags) > 1 {
+			exitWithError(fmt.Errorf("plan takes a single --source"))
+		}
+		if graveyardFlag == "" {
+			exitWithError(fmt.Errorf("--graveyard is required"))
+		}
+
+		if planOutFlag != "" {
+			if err := checkReadOnly("saving a plan"); err != nil {
+				exitWithError(err)
+			}
+		}
+
+		// Keep stdout for the JSON plan; progress, such as cloning the
+		// source, goes to stderr
+		out := os.Stdout
+		if planJSONFlag {
+			os.Stdout = os.Stderr
+		}
+
+		opts, err := burialOptions(sourceFlags[0])
+		if err != nil {
+			exitWithError(err)
+		}
+		plan, err := archive.NewPlan(opts)
+		if err != nil {
+			exitWithError(err)
+		}
+		if planOutFlag != "" {
+			if err := plan.Save(planOutFlag); err != nil {
+				exitWithError(err)
+			}
+		}
+
+		if planJSONFlag {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(plan); err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+
+		printPlan(plan)
+		if planOutFlag != "" {
+			fmt.Printf("\nSaved plan to %s. Run bury-it apply %s to carry it out.\n", planOutFlag, planOutFlag)
+		}
+	},
+}
+
+// printPlan prints a plan for review.
+func printPlan(plan *archive.Plan) {
+	opts := plan.Options
+	history := fmt.Sprintf("preserved (%d commits)", plan.Commits)
+	if opts.DropHistory {
+		history = "dropped"
+	}
+
+	fmt.Printf("Source:         %s\n", display.Path(opts.Source))
+	if plan.Clone {
+		fmt.Printf("Clone:          yes\n")
+	}
+	if opts.Branch != "" {
+		fmt.Printf("Branch:         %s\n", opts.Branch)
+	}
+	if opts.Ref != "" {
+		fmt.Printf("Ref:            %s\n", opts.Ref)
+	}
+	if opts.RecurseSubmodules {
+		fmt.Printf("Submodules:     cloned and buried with the project\n")
+	}
+	fmt.Printf("Source commit:  %s\n", opts.ExpectCommit)
+	fmt.Printf("Graveyard:      %s\n", display.Path(opts.Graveyard))
+	fmt.Printf("Prefix:         %s/\n", plan.Prefix)
+	if plan.BurialVersion > 0 {
+		fmt.Printf("Version:        %d (version %d is kept under %s)\n", plan.BurialVersion, plan.BurialVersion-1, graveyard.VersionTag(plan.Prefix, plan.BurialVersion-1))
+	}
+	fmt.Printf("History:        %s\n", history)
+	if opts.ReviewAfter != nil {
+		fmt.Printf("Review after:   %s\n", opts.ReviewAfter)
+	}
+	if opts.Owner != "" {
+		fmt.Printf("Owner:          %s\n", opts.Owner)
+	}
+	if opts.MonthlyCostBefore != nil {
+		fmt.Printf("Cost before:    %s/month\n", metadata.FormatCost(*opts.MonthlyCostBefore))
+	}
+	if opts.MonthlyCostAfter != nil {
+		fmt.Printf("Cost after:     %s/month\n", metadata.FormatCost(*opts.MonthlyCostAfter))
+	}
+	fmt.Printf("Commit message: %s\n", plan.CommitMessage)
+	fmt.Printf("Estimated size: %s\n", size.Format(plan.EstimatedSize))
+	fmt.Printf("\nFiles added (%d):\n", len(plan.Files))
+	for _, file := range plan.Files {
+		fmt.Printf("  %s\n", file)
+	}
+}
+
+func init() {
+	addBurialFlags(planCmd.Flags())
+	planCmd.Flags().StringVarP(&planOutFlag, "out", "o", "", "save the plan to a file for bury-it apply")
+	planCmd.Flags().BoolVar(&planJSONFlag, "json", false, "output the plan as JSON")
+	rootCmd.AddCommand(planCmd)
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/spf13/cobra"
+)
+
+var (
+	recoverDryRunFlag bool
+	recoverForceFlag  bool
+)
+
+var recoverCmd = &cobra.Command{
+	Use:   "recover",
+	Short: "Finish or roll back a burial that was interrupted",
+	Long: `Recover from a burial that was interrupted, by a crash, a killed process, or
+an error, after it started changing the graveyard. Each burial records where
+the graveyard was before it changed anything until it is committed, so an
+interrupted one is found when the graveyard is next used.
+
+A burial that was committed is completed: the version tag of a re-burial is
+created if it is missing. Steps after the commit, such as --tombstone-issue,
+--registry, and --with-issues, are not redone. Any other burial is rolled
+back: the graveyard is reset to the commit it started from, discarding its
+uncommitted changes, and whatever the burial left in the project directory is
+removed.
+
+Either way, git's index lock, left behind when git is killed, and the
+burial's temporary directories are removed.
+
+A burial whose process is still running is left alone. Where that cannot be
+told, as for a burial started on another machine sharing the graveyard,
+--force recovers it anyway; make sure it is no longer running first.`,
+	Example: `  bury-it recover -g ~/graveyard
+
+  # Show what would be done without changing anything
+  bury-it recover -g ~/graveyard --dry-run`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Opened without openGraveyard, which warns of the very burial about
+		// to be recovered
+		if graveyardFlag == "" {
+			exitWithError(fmt.Errorf("--graveyard is required"))
+		}
+		gy, err := graveyard.New(graveyardFlag)
+		if err != nil {
+			exitWithError(fmt.Errorf("invalid graveyard: %w", err))
+		}
+		if err := gy.Validate(); err != nil {
+			exitWithError(err)
+		}
+		op, err := gy.PendingOperation()
+		if err != nil {
+			exitWithError(err)
+		}
+		if op == nil {
+			fmt.Println("No interrupted burial found.")
+			return
+		}
+		if !recoverDryRunFlag {
+			if err := checkReadOnly("recovering a burial"); err != nil {
+				exitWithError(err)
+			}
+		}
+
+		recovery, err := gy.Recover(op, recoverForceFlag, recoverDryRunFlag)
+		if err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("Interrupted burial: %s (started %s)\n", op.Project, op.StartedAt.Local().Format("2006-01-02 15:04"))
+		if recoverDryRunFlag {
+			if recovery.RemovedLock {
+				fmt.Println("  would remove git's stale index lock")
+			}
+			if recovery.Completed {
+				fmt.Println("  would complete the burial, which was committed")
+			} else {
+				fmt.Printf("  would reset the graveyard to %s\n", recovery.Head[:12])
+			}
+			return
+		}
+		if recovery.RemovedLock {
+			fmt.Println("Removed git's stale index lock.")
+		}
+		if recovery.Completed {
+			fmt.Printf("Completed burial of %s, which had been committed.\n", op.Project)
+		} else {
+			fmt.Printf("Rolled back burial of %s; graveyard reset to %s.\n", op.Project, recovery.Head[:12])
+		}
+	},
+}
+
+func init() {
+	recoverCmd.Flags().BoolVar(&recoverDryRunFlag, "dry-run", false, "show what would be done without changing anything")
+	recoverCmd.Flags().BoolVar(&recoverForceFlag, "force", false, "recover a burial even if its process seems to be running")
+	rootCmd.AddCommand(recoverCmd)
+}
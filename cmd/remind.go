@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/age"
+	"github.com/deanhigh/bury-it/internal/metadata"
+	"github.com/spf13/cobra"
+)
+
+var (
+	remindWithinFlag string
+	remindJSONFlag   bool
+)
+
+// reminder is a project due for review as printed by the remind command.
+type reminder struct {
+	Project        string    `json:"project"`
+	OriginalSource string    `json:"original_source"`
+	BuriedAt       time.Time `json:"buried_at"`
+	ReviewAfter    time.Time `json:"review_after"`
+}
+
+var remindCmd = &cobra.Command{
+	Use:   "remind",
+	Short: "List buried projects due for review",
+	Long: `List the projects whose review date, set with --review-after when they were
+buried, has passed, so that they can be purged or resurrected. Projects buried
+without a review date are never listed.
+
+With --within, projects coming up for review within the given time are listed
+too.`,
+	Example: `  # Projects past their review date
+  bury-it remind -g ~/graveyard
+
+  # Include projects due within the next month
+  bury-it remind -g ~/graveyard --within 1mo`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		now := time.Now()
+		if remindWithinFlag != "" {
+			within, err := age.Parse(remindWithinFlag)
+			if err != nil {
+				exitWithError(fmt.Errorf("invalid --within: %w", err))
+			}
+			now = within.After(now)
+		}
+
+		projects, err := gy.Projects()
+		if err != nil {
+			exitWithError(err)
+		}
+		reminders := []reminder{}
+		for _, name := range projects {
+			meta, err := gy.Metadata(name)
+			if err != nil {
+				exitWithError(err)
+			}
+			if meta.InvalidReviewAfter != "" {
+				fmt.Fprintf(os.Stderr, "Warning: %s has a review date that is not a date, %q; set it as YYYY-MM-DD in its %s\n", name, meta.InvalidReviewAfter, metadata.FileName)
+				continue
+			}
+			if !meta.ReviewDue(now) {
+				continue
+			}
+			reminders = append(reminders, reminder{
+				Project:        name,
+				OriginalSource: meta.OriginalSource,
+				BuriedAt:       meta.BuriedAt,
+				ReviewAfter:    meta.ReviewAfter,
+			})
+		}
+		sort.SliceStable(reminders, func(i, j int) bool {
+			return reminders[i].ReviewAfter.Before(reminders[j].ReviewAfter)
+		})
+
+		if remindJSONFlag {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(reminders); err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+
+		if len(reminders) == 0 {
+			fmt.Println("No projects are due for review.")
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PROJECT\tREVIEW AFTER\tBURIED ON\tORIGINAL SOURCE")
+		for _, r := range reminders {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Project, r.ReviewAfter.Format("2006-01-02"), r.BuriedAt.Format("2006-01-02"), r.OriginalSource)
+		}
+		_ = w.Flush()
+	},
+}
+
+func init() {
+	remindCmd.Flags().StringVar(&remindWithinFlag, "within", "", "also list projects due for review within this time (e.g. 30d)")
+	remindCmd.Flags().BoolVar(&remindJSONFlag, "json", false, "output projects as JSON")
+	rootCmd.AddCommand(remindCmd)
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/audit"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/metadata"
+	"github.com/deanhigh/bury-it/internal/report"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportYearFlag   int
+	reportFormatFlag string
+	reportOutFlag    string
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Write an annual report of the year's burials",
+	Long: `Write a report of the projects buried in a year, for posting in an
+end-of-year engineering retrospective: how many were buried and how large they
+were, the most notable of them (the largest, longest lived, and those with the
+most commits and contributors), who buried the most, and a table of every
+project.
+
+Who buried each project is taken from the graveyard's audit log, so burials
+made before it was kept are not attributed. Measuring sizes reads every
+project buried in the year.`,
+	Example: `  # Last year's report, as Markdown
+  bury-it report -g ~/graveyard --year 2025
+
+  # As an HTML page
+  bury-it report -g ~/graveyard --year 2025 --format html --out report-2025.html`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !slices.Contains(report.Formats, reportFormatFlag) {
+			exitWithError(fmt.Errorf("unknown --format %q: must be %s", reportFormatFlag, strings.Join(report.Formats, " or ")))
+		}
+		if reportOutFlag != "" {
+			if err := checkReadOnly("writing a report to a file"); err != nil {
+				exitWithError(err)
+			}
+		}
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		names, err := gy.Projects()
+		if err != nil {
+			exitWithError(err)
+		}
+		projects := map[string]*metadata.Metadata{}
+		usages := map[string]*graveyard.Usage{}
+		for _, name := range names {
+			meta, err := gy.Metadata(name)
+			if err != nil {
+				exitWithError(err)
+			}
+			projects[name] = meta
+			if meta.BuriedAt.Year() != reportYearFlag {
+				continue
+			}
+			usage, err := gy.DiskUsage(name)
+			if err != nil {
+				exitWithError(err)
+			}
+			usages[name] = usage
+		}
+		entries, err := audit.Read(gy.Path)
+		if err != nil {
+			exitWithError(err)
+		}
+		r := report.Build(reportYearFlag, graveyardLocation(gy), projects, usages, entries)
+
+		out := os.Stdout
+		if reportOutFlag != "" {
+			out, err = os.Create(reportOutFlag)
+			if err != nil {
+				exitWithError(fmt.Errorf("failed to create report: %w", err))
+			}
+		}
+		if err := report.Write(out, r, reportFormatFlag); err != nil {
+			exitWithError(err)
+		}
+		if reportOutFlag != "" {
+			if err := out.Close(); err != nil {
+				exitWithError(fmt.Errorf("failed to write report: %w", err))
+			}
+			fmt.Printf("Wrote report of %d projects buried in %d to %s\n", len(r.Projects), r.Year, reportOutFlag)
+		}
+	},
+}
+
+func init() {
+	reportCmd.Flags().IntVar(&reportYearFlag, "year", time.Now().Year(), "year to report on")
+	reportCmd.Flags().StringVar(&reportFormatFlag, "format", "md", "report format: "+strings.Join(report.Formats, " or "))
+	reportCmd.Flags().StringVarP(&reportOutFlag, "out", "o", "", "file to write the report to instead of standard output")
+	rootCmd.AddCommand(reportCmd)
+}
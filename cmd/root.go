@@ -2,21 +2,86 @@
 package cmd
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/deanhigh/bury-it/internal/age"
 	"github.com/deanhigh/bury-it/internal/archive"
+	"github.com/deanhigh/bury-it/internal/atomicfile"
+	"github.com/deanhigh/bury-it/internal/audit"
+	"github.com/deanhigh/bury-it/internal/capability"
+	"github.com/deanhigh/bury-it/internal/config"
+	"github.com/deanhigh/bury-it/internal/display"
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/index"
+	"github.com/deanhigh/bury-it/internal/metadata"
+	"github.com/deanhigh/bury-it/internal/progress"
+	"github.com/deanhigh/bury-it/internal/registry"
+	"github.com/deanhigh/bury-it/internal/size"
+	"github.com/deanhigh/bury-it/internal/snapshot"
+	"github.com/deanhigh/bury-it/internal/source"
+	"github.com/deanhigh/bury-it/internal/state"
+	"github.com/deanhigh/bury-it/internal/stats"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // Version is set at build time.
 var Version = "dev"
 
 var (
-	sourceFlag      string
-	graveyardFlag   string
-	nameFlag        string
-	dropHistoryFlag bool
+	sourceFlags            []string
+	sourcesFileFlag        string
+	sourceTypeFlag         string
+	ignoreFileFlag         string
+	graveyardFlag          string
+	nameFlag               string
+	dropHistoryFlag        bool
+	captureUncommittedFlag bool
+	includeUntrackedFlag   bool
+	unshallowFlag          bool
+	allowEmptyFlag         bool
+	sparklineFlag          bool
+	linkIssuesFlag         bool
+	withIssuesFlag         bool
+	ciHistoryFlag          bool
+	releaseFlag            string
+	sizeLimitFlag          string
+	autoDowngradeFlag      bool
+	tombstoneIssueFlag     bool
+	registryFlag           string
+	registryFileFlag       string
+	registryPRFlag         bool
+	reviewAfterFlag        string
+	ownerFlag              string
+	costBeforeFlag         string
+	costAfterFlag          string
+	newVersionFlag         bool
+	preferTransportFlag    string
+	offlineFlag            bool
+	readOnlyFlag           bool
+	explainFlag            bool
+	copyEngineFlag         string
+	lowPriorityFlag        bool
+	trackAccessFlag        bool
+	absolutePathsFlag      bool
+	progressFlag           string
+	nicenessFlag           int
+	branchFlag             string
+	refFlag                string
+	recurseSubmodulesFlag  bool
+	scanFlag               string
+	githubQueryFlag        string
+	selectFlag             string
+	emitScriptFlag         string
 )
 
 var rootCmd = &cobra.Command{
@@ -25,7 +90,11 @@ var rootCmd = &cobra.Command{
 	Long: `bury-it is a CLI tool to sunset experimental projects by archiving them
 into a local "graveyard" repository while optionally preserving their full git history.
 
-It supports both remote GitHub repositories and local git repositories as sources.`,
+It supports local git repositories and remote repositories on GitHub, GitLab,
+Bitbucket, Azure DevOps, AWS CodeCommit, or any other host git can reach as
+sources. Private Azure DevOps repositories are cloned with the personal access
+token in AZURE_DEVOPS_EXT_PAT, and CodeCommit repositories with the AWS CLI's
+credential helper, or git-remote-codecommit for codecommit:: URLs.`,
 	Example: `  # Bury a GitHub repository
   bury-it --source deanhigh/old-project --graveyard ~/graveyard
 
@@ -33,16 +102,53 @@ It supports both remote GitHub repositories and local git repositories as source
   bury-it --source ./my-experiment --graveyard ~/graveyard --drop-history
 
   # Full GitHub URL with custom name
-  bury-it -s https://github.com/deanhigh/experiment -g /path/to/graveyard --name my-old-experiment`,
+  bury-it -s https://github.com/deanhigh/experiment -g /path/to/graveyard --name my-old-experiment
+
+  # Several repositories in one run
+  bury-it -g ~/graveyard -s ./prototype -s ./spike -s deanhigh/old-project
+
+  # Every archived repository of an organization, one source per line
+  gh repo list my-org --archived --limit 200 | bury-it -g ~/graveyard -s -
+  bury-it -g ~/graveyard --sources-file retired.txt
+
+  # Choose which repositories under a directory to bury
+  bury-it -g ~/graveyard --scan ~/src/experiments
+
+  # Choose among your archived GitHub repositories not pushed to since 2022
+  bury-it -g ~/graveyard --github-query "user:alice archived:true pushed:<2022-01-01"`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		operation := strings.TrimPrefix(strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()), " ")
+		if operation == "" {
+			operation = "bury"
+		}
+		audit.SetInvocation(operation, Version, os.Args[1:])
+		if err := applyConfigDefaults(cmd); err != nil {
+			exitWithError(err)
+		}
+		if err := applyCapabilities(); err != nil {
+			exitWithError(err)
+		}
+		if err := applyPriority(); err != nil {
+			exitWithError(err)
+		}
+		state.SetReadOnly(readOnlyFlag)
+		if explainFlag {
+			git.SetExplain(os.Stderr)
+		}
+		display.SetAbsolute(absolutePathsFlag)
+		if err := progress.SetFormat(progressFlag, os.Stderr); err != nil {
+			exitWithError(err)
+		}
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		// If no flags provided, show help (FR-5.1)
-		if sourceFlag == "" && graveyardFlag == "" {
+		if cmd.Flags().NFlag() == 0 {
 			_ = cmd.Help()
 			return
 		}
 
 		// Validate required flags (FR-5.3)
-		if sourceFlag == "" {
+		if len(sourceFlags) == 0 && sourcesFileFlag == "" && scanFlag == "" && githubQueryFlag == "" {
 			fmt.Fprintln(os.Stderr, "Error: --source is required")
 			fmt.Fprintln(os.Stderr, "")
 			_ = cmd.Help()
@@ -56,32 +162,89 @@ It supports both remote GitHub repositories and local git repositories as source
 			os.Exit(1)
 		}
 
-		// Execute archive
-		result, err := archive.Archive(archive.Options{
-			Source:      sourceFlag,
-			Graveyard:   graveyardFlag,
-			Name:        nameFlag,
-			DropHistory: dropHistoryFlag,
-		})
+		if scanFlag != "" && githubQueryFlag != "" {
+			exitWithError(fmt.Errorf("--scan and --github-query cannot be used together"))
+		}
+		if scanFlag != "" || githubQueryFlag != "" {
+			flag := "--scan"
+			if githubQueryFlag != "" {
+				flag = "--github-query"
+			}
+			if len(sourceFlags) > 0 || sourcesFileFlag != "" {
+				exitWithError(fmt.Errorf("%s cannot be used with --source or --sources-file", flag))
+			}
+			if nameFlag != "" {
+				exitWithError(fmt.Errorf("--name cannot be used with %s", flag))
+			}
+			if emitScriptFlag != "" {
+				exitWithError(fmt.Errorf("--emit-script cannot be used with %s", flag))
+			}
+			if githubQueryFlag != "" {
+				buryGitHubQuery(githubQueryFlag)
+			} else {
+				buryScanned(scanFlag)
+			}
+			return
+		}
+		if selectFlag != "" {
+			exitWithError(fmt.Errorf("--select can only be used with --scan or --github-query"))
+		}
+
+		if emitScriptFlag != "" {
+			emitScript()
+			return
+		}
+		if err := checkReadOnly("burying a repository"); err != nil {
+			exitWithError(err)
+		}
+		sources, err := readSources()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			exitWithError(err)
+		}
+		if len(sources) > 1 {
+			if nameFlag != "" {
+				exitWithError(fmt.Errorf("--name cannot be used with several sources"))
+			}
+			burySources(sources)
+			return
 		}
 
-		// Success message
-		fmt.Println("")
-		fmt.Printf("Successfully buried %s!\n", result.ProjectName)
-		fmt.Printf("  Archived to: %s\n", result.ProjectPath)
-		fmt.Println("")
-		fmt.Println("Next step: Archive or delete the original repository")
+		// Execute archive
+		opts, err := burialOptions(sources[0])
+		if err != nil {
+			exitWithError(err)
+		}
+		if opts.NewVersion && capability.RequiresApproval(capability.Replace) {
+			if err := requestReplacement(opts); err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+		result, err := bury(opts)
+		if err != nil {
+			exitWithError(err)
+		}
+		printBurial(result)
 	},
 }
 
 func init() {
-	rootCmd.Flags().StringVarP(&sourceFlag, "source", "s", "", "source repository (GitHub URL, owner/repo, or local path)")
-	rootCmd.Flags().StringVarP(&graveyardFlag, "graveyard", "g", "", "local path to the graveyard repository")
-	rootCmd.Flags().StringVarP(&nameFlag, "name", "n", "", "override the project name in the graveyard")
-	rootCmd.Flags().BoolVar(&dropHistoryFlag, "drop-history", false, "archive only the latest state, discard git history")
+	rootCmd.PersistentFlags().StringVarP(&graveyardFlag, "graveyard", "g", "", "local path to the graveyard repository")
+	rootCmd.PersistentFlags().BoolVar(&offlineFlag, "offline", false, "forbid network access, failing fast when a command would need it")
+	rootCmd.PersistentFlags().BoolVar(&readOnlyFlag, "read-only", false, "forbid any change to graveyards, sources, local state, or remote services")
+	rootCmd.PersistentFlags().BoolVar(&explainFlag, "explain", false, "print each git command, with the reason for it, to stderr before running it")
+	rootCmd.PersistentFlags().BoolVar(&lowPriorityFlag, "low-priority", false, "run git and other child processes at a low CPU and I/O priority")
+	rootCmd.PersistentFlags().BoolVar(&trackAccessFlag, "track-access", false, "record on this machine when a command reads a buried project, for advise and stats")
+	rootCmd.PersistentFlags().IntVar(&nicenessFlag, "niceness", 10, "niceness of child processes under --low-priority, from 1 to 19")
+	rootCmd.PersistentFlags().BoolVar(&absolutePathsFlag, "absolute-paths", false, "print paths in full instead of relative to the working directory or ~")
+	rootCmd.PersistentFlags().StringVar(&progressFlag, "progress", progress.FormatText, "how to report progress: text, or json to also write JSON events to stderr")
+	addBurialFlags(rootCmd.Flags())
+	rootCmd.Flags().StringVar(&sourcesFileFlag, "sources-file", "", "file listing sources to bury, one per line, or - for stdin")
+	rootCmd.Flags().StringVar(&emitScriptFlag, "emit-script", "", "write a shell script that makes the burial to this file instead of making it")
+	rootCmd.Flags().StringVar(&scanFlag, "scan", "", "list the git repositories under a directory and bury those selected")
+	rootCmd.Flags().StringVar(&githubQueryFlag, "github-query", "", "list the GitHub repositories matching a search query and bury those selected")
+	rootCmd.Flags().StringVar(&selectFlag, "select", "", "repositories of --scan or --github-query to bury, by number, such as 1,3-5, or all")
+	_ = rootCmd.RegisterFlagCompletionFunc("graveyard", completeGraveyards)
 
 	rootCmd.Version = Version
 	rootCmd.SetVersionTemplate("bury-it version {{.Version}}\n")
@@ -91,3 +254,574 @@ func init() {
 func Execute() error {
 	return rootCmd.Execute()
 }
+
+// openGraveyard resolves and validates the graveyard given by --graveyard.
+func openGraveyard() (*graveyard.Graveyard, error) {
+	if graveyardFlag == "" {
+		return nil, fmt.Errorf("--graveyard is required")
+	}
+	gy, err := graveyard.New(graveyardFlag)
+	if err != nil {
+		return nil, fmt.Errorf("invalid graveyard: %w", err)
+	}
+	if err := gy.Validate(); err != nil {
+		return nil, err
+	}
+	// Remembered only to complete --graveyard and find projects, so failures
+	// do not matter
+	_ = gy.Remember()
+	_ = gy.RememberProjects()
+	if op, err := gy.PendingOperation(); err == nil && op != nil && !op.Running() {
+		fmt.Fprintf(os.Stderr, "Warning: a burial of %s into this graveyard was interrupted; run bury-it recover -g %s\n", op.Project, display.Path(gy.Path))
+	}
+	return gy, nil
+}
+
+// openProjectGraveyard opens the graveyard given by --graveyard or, if it is
+// not given, the one graveyard used on this machine that holds project.
+func openProjectGraveyard(project string) (*graveyard.Graveyard, error) {
+	if graveyardFlag != "" {
+		return openGraveyard()
+	}
+	found, err := graveyard.FindKnownProjects(project, "")
+	if err != nil {
+		return nil, err
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("--graveyard is required (%s is not in any graveyard used on this machine)", project)
+	case 1:
+		graveyardFlag = found[0].Graveyard
+		return openGraveyard()
+	}
+	var paths []string
+	for _, p := range found {
+		paths = append(paths, p.Graveyard)
+	}
+	return nil, fmt.Errorf("%s is buried in several graveyards, choose one with --graveyard: %s", project, strings.Join(paths, ", "))
+}
+
+// openAliasedProject opens the graveyard holding the project called name,
+// as openProjectGraveyard does, and returns the project's name, resolving
+// name if it is an alias added with bury-it alias add. Without --graveyard,
+// an alias is looked for in the graveyards used on this machine.
+func openAliasedProject(name string) (*graveyard.Graveyard, string, error) {
+	if graveyardFlag == "" {
+		if found, err := graveyard.FindKnownProjects(name, ""); err == nil && len(found) == 0 {
+			graveyardFlag = aliasGraveyard(name)
+		}
+	}
+	gy, err := openProjectGraveyard(name)
+	if err != nil {
+		return nil, "", err
+	}
+	if gy.ProjectExists(name) {
+		return gy, name, nil
+	}
+	aliases, err := index.LoadAliases(gy.Path)
+	if err != nil {
+		return nil, "", err
+	}
+	return gy, aliases.Resolve(name), nil
+}
+
+// aliasGraveyard returns the only graveyard used on this machine in which
+// alias names a project, or "" if there is no such graveyard or several.
+func aliasGraveyard(alias string) string {
+	known, err := graveyard.Known()
+	if err != nil {
+		return ""
+	}
+	var found []string
+	for _, path := range known {
+		aliases, err := index.LoadAliases(path)
+		if err != nil {
+			continue
+		}
+		if _, ok := aliases[alias]; ok {
+			found = append(found, path)
+		}
+	}
+	if len(found) != 1 {
+		return ""
+	}
+	return found[0]
+}
+
+// warnBuriedElsewhere warns if the project about to be buried is already
+// buried in another graveyard used on this machine, by name or by source.
+func warnBuriedElsewhere(opts archive.Options) {
+	src, err := source.Parse(opts.Source)
+	if err != nil {
+		// Reported by the burial itself
+		return
+	}
+	gy, err := graveyard.New(opts.Graveyard)
+	if err != nil {
+		return
+	}
+	name := opts.Name
+	if name == "" {
+		name = src.Name
+	}
+	found, err := graveyard.FindKnownProjects(name, src.DisplayPath())
+	if err != nil {
+		return
+	}
+	for _, p := range found {
+		if p.Graveyard == gy.Path {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s was already buried in %s on %s (from %s)\n",
+			p.Name, p.Graveyard, p.BuriedAt.Format("2006-01-02"), p.Source)
+	}
+}
+
+// recordPersonalBurial adds a burial that started at started to the
+// personal statistics kept on this machine, shown by stats --personal.
+// They are only a keepsake, so failures are ignored.
+func recordPersonalBurial(graveyardPath, project string, started time.Time) {
+	b := stats.Burial{Time: time.Now(), Project: project, Seconds: time.Since(started).Seconds()}
+	if gy, err := graveyard.New(graveyardPath); err == nil {
+		if usage, err := gy.DiskUsage(project); err == nil {
+			b.Bytes = usage.WorkTree + usage.Packed
+		}
+	}
+	_ = stats.RecordBurial(b)
+}
+
+// bury carries out a burial, recording it in the personal statistics and
+// remembering the graveyard.
+func bury(opts archive.Options) (*archive.Result, error) {
+	warnBuriedElsewhere(opts)
+	started := time.Now()
+	result, err := archive.Archive(opts)
+	if err != nil {
+		return nil, err
+	}
+	recordPersonalBurial(opts.Graveyard, result.ProjectName, started)
+
+	if gy, err := graveyard.New(opts.Graveyard); err == nil {
+		_ = gy.Remember()
+		_ = gy.RememberProjects()
+	}
+	return result, nil
+}
+
+// requestReplacement records a request for a second person to approve
+// burying a project again as a new version with opts.
+func requestReplacement(opts archive.Options) error {
+	if err := capability.Check(capability.Replace); err != nil {
+		return err
+	}
+	gy, err := graveyard.New(opts.Graveyard)
+	if err != nil {
+		return fmt.Errorf("invalid graveyard: %w", err)
+	}
+	// The approver may run bury-it from another directory
+	opts.Graveyard = gy.Path
+	if _, err := os.Stat(opts.Source); err == nil {
+		if opts.Source, err = filepath.Abs(opts.Source); err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
+	return requestApproval("replace", gy, "bury "+opts.Source+" as a new version", map[string]string{"options": string(data)})
+}
+
+// emitScript writes a script making the burial of the single source given
+// to the file named by --emit-script, leaving the graveyard alone.
+func emitScript() {
+	if err := checkReadOnly("writing a burial script"); err != nil {
+		exitWithError(err)
+	}
+	sources, err := readSources()
+	if err != nil {
+		exitWithError(err)
+	}
+	if len(sources) > 1 {
+		exitWithError(fmt.Errorf("--emit-script takes a single source"))
+	}
+	opts, err := burialOptions(sources[0])
+	if err != nil {
+		exitWithError(err)
+	}
+	script, err := archive.Script(opts)
+	if err != nil {
+		exitWithError(err)
+	}
+	if err := atomicfile.Write(emitScriptFlag, []byte(script), 0755); err != nil {
+		exitWithError(fmt.Errorf("failed to write script: %w", err))
+	}
+	fmt.Printf("\nWrote the burial script to %s; the graveyard was not changed.\n", display.Path(emitScriptFlag))
+}
+
+// readSources returns the sources given by --source and --sources-file,
+// reading the list from stdin for a --source of - or a --sources-file of -.
+func readSources() ([]string, error) {
+	var sources []string
+	readStdin := false
+	readList := func(path string) error {
+		name := path
+		var r io.Reader = os.Stdin
+		if path == "-" {
+			if readStdin {
+				return fmt.Errorf("sources can only be read from stdin once")
+			}
+			readStdin = true
+			name = "stdin"
+		} else {
+			f, err := os.Open(path)
+			if err != nil {
+				return fmt.Errorf("failed to open sources file: %w", err)
+			}
+			defer f.Close()
+			r = f
+		}
+		list, err := source.ReadList(r)
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			return fmt.Errorf("no sources listed in %s", name)
+		}
+		sources = append(sources, list...)
+		return nil
+	}
+
+	for _, input := range sourceFlags {
+		if input != "-" {
+			sources = append(sources, input)
+			continue
+		}
+		if err := readList(input); err != nil {
+			return nil, err
+		}
+	}
+	if sourcesFileFlag != "" {
+		if err := readList(sourcesFileFlag); err != nil {
+			return nil, err
+		}
+	}
+	return sources, nil
+}
+
+// burySources buries each of several sources in turn, continuing past
+// failures, then prints a summary of every burial.
+func burySources(sources []string) {
+	statuses := make([]string, len(sources))
+	projects := make([]string, len(sources))
+	var failures []string
+	buried := 0
+	for i, input := range sources {
+		fmt.Printf("Burying %s (%d of %d)...\n", input, i+1, len(sources))
+		opts, err := burialOptions(input)
+		var result *archive.Result
+		if err == nil {
+			result, err = bury(opts)
+		}
+		if err != nil {
+			statuses[i] = "failed"
+			failures = append(failures, fmt.Sprintf("%s: %v", input, err))
+			fmt.Printf("Failed to bury %s: %v\n\n", input, err)
+			continue
+		}
+		statuses[i] = "buried"
+		projects[i] = result.ProjectName
+		buried++
+		printBurial(result)
+		fmt.Println("")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tSOURCE\tPROJECT")
+	for i, input := range sources {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", statuses[i], input, projects[i])
+	}
+	_ = w.Flush()
+	fmt.Printf("\nBuried %d of %d sources.\n", buried, len(sources))
+	if len(failures) > 0 {
+		exitWithError(fmt.Errorf("%d burials failed:\n  %s", len(failures), strings.Join(failures, "\n  ")))
+	}
+}
+
+// printBurial prints the success message for a burial.
+func printBurial(result *archive.Result) {
+	fmt.Println("")
+	fmt.Printf("Successfully buried %s!\n", result.ProjectName)
+	fmt.Printf("  Archived to: %s\n", display.Path(result.ProjectPath))
+	if result.Version > 0 {
+		fmt.Printf("  Version: %d (see bury-it list --versions)\n", result.Version)
+	}
+	if result.TombstoneURL != "" {
+		fmt.Printf("  Tombstone issue: %s\n", result.TombstoneURL)
+	}
+	if result.RegistryURL != "" {
+		fmt.Printf("  Registry pull request: %s\n", result.RegistryURL)
+	}
+	if result.IssueExportComplete {
+		fmt.Printf("  Issues exported: %d\n", result.IssuesExported)
+	}
+	fmt.Println("")
+	fmt.Println("Next step: Archive or delete the original repository")
+}
+
+// addBurialFlags registers the flags that control a single burial.
+func addBurialFlags(flags *pflag.FlagSet) {
+	flags.StringArrayVarP(&sourceFlags, "source", "s", nil, "source repository, repeatable to bury several in one run (git URL of any host, owner/repo, prefixed shorthand such as gl:group/repo, a GitHub gist URL or ID, local path to a repository, bare or not, or a linked worktree, or a bundle, or a tar or zip archive, as a path or URL, or a Mercurial or Subversion repository, as a path or an hg:: or svn:: URL)")
+	flags.StringVar(&sourceTypeFlag, "source-type", "", "bury sources as this type instead of telling it from the source: plain, for a directory never under version control")
+	flags.StringVar(&ignoreFileFlag, "ignore-file", "", "file of gitignore patterns matching files of a plain directory, or untracked files, to leave out")
+	flags.StringVarP(&nameFlag, "name", "n", "", "override the project name in the graveyard")
+	flags.StringVar(&branchFlag, "branch", "", "bury this branch instead of the checked-out or default one")
+	flags.StringVar(&refFlag, "ref", "", "bury the state at this tag or commit, such as the last release")
+	flags.StringVar(&releaseFlag, "release", "", "bury the published state of this GitHub release tag, with its name and notes")
+	flags.BoolVar(&dropHistoryFlag, "drop-history", false, "archive only the latest state, discard git history")
+	flags.StringVar(&sizeLimitFlag, "size-limit", "1GiB", "history size above which to offer burying without history, or empty to skip the check")
+	flags.BoolVar(&autoDowngradeFlag, "auto-downgrade", false, "bury without history, without asking, when the history is over --size-limit")
+	flags.BoolVar(&unshallowFlag, "unshallow", false, "fetch the full history of a local source that is a shallow clone before burying it")
+	flags.BoolVar(&allowEmptyFlag, "allow-empty", false, "bury the files of a local source repository without commits as a snapshot, instead of failing")
+	flags.BoolVar(&recurseSubmodulesFlag, "recurse-submodules", false, "clone submodules and bury their content instead of empty directories")
+	flags.BoolVar(&sparklineFlag, "activity-sparkline", false, "include an SVG sparkline of commit activity with --drop-history")
+	flags.BoolVar(&linkIssuesFlag, "link-original-issues", false, "record issue and pull request counts and links to open ones (GitHub sources)")
+	flags.BoolVar(&ciHistoryFlag, "ci-history", false, "record a summary of GitHub Actions workflow runs (GitHub sources)")
+	flags.BoolVar(&withIssuesFlag, "with-issues", false, "export every issue and pull request of a GitHub source into the project, resumably")
+	flags.BoolVar(&tombstoneIssueFlag, "tombstone-issue", false, "open or update a pinned archive notice issue on the GitHub source")
+	flags.BoolVar(&captureUncommittedFlag, "capture-uncommitted", false, "save uncommitted changes and stashes of a local source as a patch")
+	flags.BoolVar(&includeUntrackedFlag, "include-untracked", false, "bury the untracked files of a local source too, except those ignored")
+	flags.StringVar(&reviewAfterFlag, "review-after", "", "record a date to review the burial, this long from now (e.g. 2y or 18mo)")
+	flags.StringVar(&copyEngineFlag, "copy-engine", snapshot.DefaultEngine, "how --drop-history copies tracked files: "+strings.Join(snapshot.Names(), ", "))
+	flags.StringVar(&preferTransportFlag, "prefer-transport", "", "clone shorthand sources such as owner/repo over ssh or https (default: per host, else https)")
+	flags.BoolVar(&newVersionFlag, "new-version", false, "bury the source again as a new version of a project already in the graveyard")
+	flags.StringVar(&ownerFlag, "owner", "", "record the team or person responsible for the project")
+	flags.StringVar(&costBeforeFlag, "monthly-cost-before", "", "record what the project cost to run each month before the burial (e.g. 420)")
+	flags.StringVar(&costAfterFlag, "monthly-cost-after", "", "record what the project still costs each month after the burial")
+	addRegistryFlags(flags)
+}
+
+// burialOptions returns the archive options given by the burial flags for a
+// source.
+func burialOptions(input string) (archive.Options, error) {
+	var reviewAfter *age.Span
+	if reviewAfterFlag != "" {
+		span, err := age.Parse(reviewAfterFlag)
+		if err != nil {
+			return archive.Options{}, fmt.Errorf("invalid --review-after: %w", err)
+		}
+		reviewAfter = &span
+	}
+	costBefore, err := parseCostFlag("monthly-cost-before", costBeforeFlag)
+	if err != nil {
+		return archive.Options{}, err
+	}
+	costAfter, err := parseCostFlag("monthly-cost-after", costAfterFlag)
+	if err != nil {
+		return archive.Options{}, err
+	}
+	var sizeLimit int64
+	if sizeLimitFlag != "" {
+		if sizeLimit, err = size.Parse(sizeLimitFlag); err != nil {
+			return archive.Options{}, fmt.Errorf("invalid --size-limit: %w", err)
+		}
+	}
+	var confirm func(historyBytes, limit int64) bool
+	if isTerminal(os.Stdin) {
+		confirm = confirmDowngrade
+	}
+	// A plain directory is a path, never shorthand for a repository
+	sourceURL := input
+	if sourceTypeFlag == "" {
+		if sourceURL, err = expandSource(input, preferTransportFlag); err != nil {
+			return archive.Options{}, err
+		}
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return archive.Options{}, err
+	}
+	opts := archive.Options{
+		Source:             sourceURL,
+		SourceType:         sourceTypeFlag,
+		IgnoreFile:         ignoreFileFlag,
+		Graveyard:          graveyardFlag,
+		Name:               nameFlag,
+		Branch:             branchFlag,
+		Ref:                refFlag,
+		Release:            releaseFlag,
+		DropHistory:        dropHistoryFlag,
+		RecurseSubmodules:  recurseSubmodulesFlag,
+		CaptureUncommitted: captureUncommittedFlag,
+		IncludeUntracked:   includeUntrackedFlag,
+		Unshallow:          unshallowFlag,
+		AllowEmpty:         allowEmptyFlag,
+		ActivitySparkline:  sparklineFlag,
+		LinkOriginalIssues: linkIssuesFlag,
+		WithIssues:         withIssuesFlag,
+		CIHistory:          ciHistoryFlag,
+		TombstoneIssue:     tombstoneIssueFlag,
+		Registry:           registryFlag,
+		RegistryFile:       registryFileFlag,
+		RegistryPR:         registryPRFlag,
+		ReviewAfter:        reviewAfter,
+		NewVersion:         newVersionFlag,
+		Owner:              ownerFlag,
+		MonthlyCostBefore:  costBefore,
+		MonthlyCostAfter:   costAfter,
+		Forges:             cfg.Forges(),
+		CopyEngine:         copyEngineFlag,
+		SizeLimit:          sizeLimit,
+		AutoDowngrade:      autoDowngradeFlag,
+		ConfirmDowngrade:   confirm,
+		Offline:            offlineFlag,
+	}
+	if err := checkBurialOptions(opts); err != nil {
+		return archive.Options{}, err
+	}
+	return opts, nil
+}
+
+// checkBurialOptions returns an error if a burial's options hold values the
+// burial flags would reject, wherever they came from.
+func checkBurialOptions(opts archive.Options) error {
+	if err := metadata.CheckValue(opts.Owner); err != nil {
+		return fmt.Errorf("invalid owner: %w", err)
+	}
+	for _, cost := range []struct {
+		name  string
+		value *float64
+	}{
+		{"monthly cost before", opts.MonthlyCostBefore},
+		{"monthly cost after", opts.MonthlyCostAfter},
+	} {
+		if cost.value != nil && *cost.value < 0 {
+			return fmt.Errorf("invalid %s %s: cannot be negative", cost.name, metadata.FormatCost(*cost.value))
+		}
+	}
+	if opts.SizeLimit < 0 {
+		return fmt.Errorf("invalid size limit %d: cannot be negative", opts.SizeLimit)
+	}
+	return nil
+}
+
+// confirmDowngrade asks at the terminal whether to bury a history over the
+// size limit without it. Anything but yes keeps the history.
+func confirmDowngrade(historyBytes, limit int64) bool {
+	fmt.Fprintf(os.Stderr, "The history is %s, over the %s size limit. Bury only the latest state instead? [y/N] ", size.Format(historyBytes), size.Format(limit))
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		// Input ended without an answer, as from /dev/null
+		fmt.Fprintln(os.Stderr)
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// applyPriority lowers the priority of child processes if --low-priority is
+// set.
+func applyPriority() error {
+	if !lowPriorityFlag {
+		return nil
+	}
+	if nicenessFlag < 1 || nicenessFlag > 19 {
+		return fmt.Errorf("invalid --niceness %d: must be from 1 to 19", nicenessFlag)
+	}
+	git.SetLowPriority(nicenessFlag)
+	return nil
+}
+
+// checkOffline returns an error if --offline is set, naming the operation
+// that needs network access.
+func checkOffline(operation string) error {
+	if offlineFlag {
+		return fmt.Errorf("%s needs network access, which --offline forbids", operation)
+	}
+	return nil
+}
+
+// trackAccess records that a command read project, with --track-access.
+// The record is local state, so it is skipped in read-only mode, and failing
+// to write it is only a warning.
+func trackAccess(gy *graveyard.Graveyard, project string) {
+	if !trackAccessFlag || readOnlyFlag {
+		return
+	}
+	if err := gy.RecordAccess(project, time.Now()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record reading %s: %v\n", project, err)
+	}
+}
+
+// checkReadOnly returns an error if --read-only is set, naming the operation
+// that would make changes.
+func checkReadOnly(operation string) error {
+	if readOnlyFlag {
+		return fmt.Errorf("--read-only forbids %s", operation)
+	}
+	return nil
+}
+
+// expandSource expands a shorthand source such as owner/repo or
+// gl:group/repo, including prefixes defined in the configuration, to a URL
+// over the given transport, or if none is given, the transport configured
+// for its host, or HTTPS. owner/repo expands to the configured shorthand
+// host, if any, instead of github.com. Other sources are returned as they
+// are.
+func expandSource(input, transport string) (string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return "", err
+	}
+	src, err := source.ParseWithPrefixes(input, cfg.Prefixes())
+	if err != nil || src.ShorthandHost() == "" {
+		// Errors are reported by the burial itself
+		return input, nil
+	}
+	// An explicit prefix such as gh: always names its own host
+	if host := cfg[config.ShorthandHostKey]; host != "" && src.ShorthandHost() == "github.com" && !strings.Contains(input, ":") {
+		src = src.OnHost(host)
+	}
+	if transport == "" {
+		transport = cfg.Transports()[src.ShorthandHost()]
+	}
+	if transport == "" {
+		return src.ShorthandURL(source.TransportHTTPS), nil
+	}
+	t, err := source.ParseTransport(transport)
+	if err != nil {
+		return "", fmt.Errorf("invalid --prefer-transport: %w", err)
+	}
+	return src.ShorthandURL(t), nil
+}
+
+// parseCostFlag parses the monthly cost given by a flag, returning nil if the
+// flag is empty.
+func parseCostFlag(name, value string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	cost, err := metadata.ParseCost(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --%s: %w", name, err)
+	}
+	return &cost, nil
+}
+
+// addRegistryFlags registers the burial registry flags on a command.
+func addRegistryFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&registryFlag, "registry", "", "local clone of a registry repository to record the burial in")
+	flags.StringVar(&registryFileFlag, "registry-file", registry.DefaultFile, "ledger file in the registry (.jsonl or .csv)")
+	flags.BoolVar(&registryPRFlag, "registry-pr", false, "propose the registry entry as a GitHub pull request instead of committing it")
+}
+
+// exitWithError prints err to stderr and exits with a non-zero status.
+func exitWithError(err error) {
+	// A wrapper reading JSON events gets the error as one
+	if !progress.Fail(err) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/deanhigh/bury-it/internal/archive"
+	"github.com/deanhigh/bury-it/internal/config"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/progress"
+	"github.com/deanhigh/bury-it/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+// buryResult is the result of the bury method.
+type buryResult struct {
+	Project             string `json:"project"`
+	Path                string `json:"path"`
+	HistoryPreserved    bool   `json:"history_preserved"`
+	Version             int    `json:"version,omitempty"`
+	TombstoneURL        string `json:"tombstone_url,omitempty"`
+	RegistryURL         string `json:"registry_url,omitempty"`
+	IssuesExported      int    `json:"issues_exported,omitempty"`
+	IssueExportComplete bool   `json:"issue_export_complete,omitempty"`
+}
+
+// showResult is the result of the show method: a project as printed by
+// info --json, with where it lies.
+type showResult struct {
+	listEntry
+	Graveyard string `json:"graveyard"`
+	Path      string `json:"path"`
+}
+
+var rpcCmd = &cobra.Command{
+	Use:   "rpc",
+	Short: "Serve JSON-RPC on stdin and stdout for editor integrations",
+	Long: `Serve JSON-RPC 2.0 on stdin and stdout, one message per line, so that an
+editor extension or other tool can bury repositories and browse graveyards
+without parsing bury-it's output. Requests are handled one at a time until
+stdin is closed.
+
+Methods:
+  graveyards  the graveyards used on this machine
+  list        {"graveyard", "tags"}: the projects of a graveyard, as list --json
+  show        {"project", "graveyard"}: a project, as info --json, with its path
+  search      {"query", "graveyard", "content", "tags"}: as search --json;
+              without a graveyard, every graveyard is searched
+  bury        the options of a burial, as in a plan file, such as {"source",
+              "graveyard", "name", "drop_history"}
+
+While a burial runs, its progress is sent as "progress" notifications
+carrying the events of --progress json. The lines bury-it would print go to
+stderr.`,
+	Example: `  echo '{"jsonrpc":"2.0","id":1,"method":"list","params":{"graveyard":"/home/me/graveyard"}}' | bury-it rpc`,
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Keep stdout for messages; everything printed along the way goes to
+		// stderr
+		out := os.Stdout
+		os.Stdout = os.Stderr
+
+		server := rpc.NewServer(out)
+		if err := progress.SetFormat(progress.FormatJSON, server.Notifier("progress")); err != nil {
+			exitWithError(err)
+		}
+		server.Handle("graveyards", rpcGraveyards)
+		server.Handle("list", rpcList)
+		server.Handle("show", rpcShow)
+		server.Handle("search", rpcSearch)
+		server.Handle("bury", rpcBury)
+		if err := server.Serve(os.Stdin); err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+// Requests are handled one at a time, so the handlers below set the flags
+// they share with the commands for each request.
+
+// rpcGraveyards returns the graveyards used on this machine.
+func rpcGraveyards(params json.RawMessage) (any, error) {
+	known, err := graveyard.Known()
+	if err != nil {
+		return nil, err
+	}
+	if known == nil {
+		known = []string{}
+	}
+	return known, nil
+}
+
+// rpcList returns the projects of a graveyard, optionally only those with
+// every tag given.
+func rpcList(params json.RawMessage) (any, error) {
+	var p struct {
+		Graveyard string   `json:"graveyard"`
+		Tags      []string `json:"tags"`
+	}
+	if err := rpc.DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	graveyardFlag = p.Graveyard
+	gy, err := openGraveyard()
+	if err != nil {
+		return nil, err
+	}
+	projects, err := taggedProjects(gy, p.Tags)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]listEntry, 0, len(projects))
+	for _, name := range projects {
+		meta, err := gy.Metadata(name)
+		if err != nil {
+			return nil, err
+		}
+		entry := newListEntry(name, meta)
+		entry.Checklist = checklistProgress(gy.ProjectPath(name))
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// rpcShow returns a project, from the one graveyard holding it if none is
+// given.
+func rpcShow(params json.RawMessage) (any, error) {
+	var p struct {
+		Project   string `json:"project"`
+		Graveyard string `json:"graveyard"`
+	}
+	if err := rpc.DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Project == "" {
+		return nil, rpc.InvalidParams(fmt.Errorf("project is required"))
+	}
+	graveyardFlag = p.Graveyard
+	gy, err := openProjectGraveyard(p.Project)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := gy.Metadata(p.Project)
+	if err != nil {
+		return nil, err
+	}
+	entry := newListEntry(p.Project, meta)
+	entry.Checklist = checklistProgress(gy.ProjectPath(p.Project))
+	return showResult{listEntry: entry, Graveyard: gy.Path, Path: gy.ProjectPath(p.Project)}, nil
+}
+
+// rpcSearch searches a graveyard, or every graveyard if none is given.
+func rpcSearch(params json.RawMessage) (any, error) {
+	var p struct {
+		Query     string   `json:"query"`
+		Graveyard string   `json:"graveyard"`
+		Content   bool     `json:"content"`
+		Tags      []string `json:"tags"`
+	}
+	if err := rpc.DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Query == "" {
+		return nil, rpc.InvalidParams(fmt.Errorf("query is required"))
+	}
+	graveyardFlag = p.Graveyard
+	searchContentFlag = p.Content
+	searchTagFlags = p.Tags
+
+	var results []searchResult
+	var err error
+	if p.Graveyard == "" {
+		results, err = searchAllGraveyards(p.Query)
+	} else {
+		var gy *graveyard.Graveyard
+		gy, err = openGraveyard()
+		if err == nil {
+			results, err = searchGraveyard(gy, p.Query, false)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if results == nil {
+		results = []searchResult{}
+	}
+	return results, nil
+}
+
+// rpcBury buries a repository, given the options of a burial as in a plan
+// file.
+func rpcBury(params json.RawMessage) (any, error) {
+	var opts archive.Options
+	if err := rpc.DecodeParams(params, &opts); err != nil {
+		return nil, err
+	}
+	if opts.Source == "" {
+		return nil, rpc.InvalidParams(fmt.Errorf("source is required"))
+	}
+	if opts.Graveyard == "" {
+		return nil, rpc.InvalidParams(fmt.Errorf("graveyard is required"))
+	}
+	if err := checkReadOnly("burying a repository"); err != nil {
+		return nil, err
+	}
+	if err := checkBurialOptions(opts); err != nil {
+		return nil, rpc.InvalidParams(err)
+	}
+	// A plain directory is a path, never shorthand for a repository
+	if opts.SourceType == "" {
+		expanded, err := expandSource(opts.Source, "")
+		if err != nil {
+			return nil, err
+		}
+		opts.Source = expanded
+	}
+	if opts.Forges == nil {
+		cfg, err := config.Load()
+		if err != nil {
+			return nil, err
+		}
+		opts.Forges = cfg.Forges()
+	}
+	opts.Offline = opts.Offline || offlineFlag
+
+	result, err := bury(opts)
+	if err != nil {
+		return nil, err
+	}
+	return buryResult{
+		Project:             result.ProjectName,
+		Path:                result.ProjectPath,
+		HistoryPreserved:    result.HistoryPreserved,
+		Version:             result.Version,
+		TombstoneURL:        result.TombstoneURL,
+		RegistryURL:         result.RegistryURL,
+		IssuesExported:      result.IssuesExported,
+		IssueExportComplete: result.IssueExportComplete,
+	}, nil
+}
+
+func init() {
+	rootCmd.AddCommand(rpcCmd)
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/deanhigh/bury-it/internal/display"
+	"github.com/deanhigh/bury-it/internal/scan"
+)
+
+// buryScanned lists the git repositories under dir, other than the
+// graveyard, and buries those chosen with --select or, on a terminal, at a
+// prompt.
+func buryScanned(dir string) {
+	found, err := scan.Repos(dir)
+	if err != nil {
+		exitWithError(err)
+	}
+	graveyardPath, err := filepath.Abs(graveyardFlag)
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to resolve path: %w", err))
+	}
+	var repos []scan.Repo
+	for _, repo := range found {
+		if repo.Path != graveyardPath {
+			repos = append(repos, repo)
+		}
+	}
+	if len(repos) == 0 {
+		fmt.Printf("No git repositories found under %s.\n", display.Path(dir))
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tREPOSITORY\tLAST COMMIT")
+	for i, repo := range repos {
+		last := "never"
+		if !repo.LastCommit.IsZero() {
+			last = repo.LastCommit.Format("2006-01-02")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", i+1, display.Path(repo.Path), last)
+	}
+	_ = w.Flush()
+
+	sources := make([]string, len(repos))
+	for i, repo := range repos {
+		sources[i] = repo.Path
+	}
+	buryChosen(sources)
+}
+
+// buryChosen buries the sources of a numbered list just printed that are
+// chosen with --select or, on a terminal, at a prompt.
+func buryChosen(listed []string) {
+	selection := selectFlag
+	if selection == "" {
+		if !isTerminal(os.Stdin) {
+			fmt.Println("\nChoose the repositories to bury with --select, such as --select 1,3-5 or --select all.")
+			return
+		}
+		fmt.Print("\nBury which repositories? (numbers such as 1,3-5, all, or none): ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			exitWithError(fmt.Errorf("failed to read selection: %w", err))
+		}
+		if err == io.EOF {
+			// Input ended without an answer, as from /dev/null
+			fmt.Println("")
+		}
+		selection = line
+	}
+	indexes, err := scan.Select(selection, len(listed))
+	if err != nil {
+		exitWithError(err)
+	}
+	if len(indexes) == 0 {
+		fmt.Println("Nothing selected.")
+		return
+	}
+	if err := checkReadOnly("burying a repository"); err != nil {
+		exitWithError(err)
+	}
+
+	sources := make([]string, len(indexes))
+	for i, index := range indexes {
+		sources[i] = listed[index]
+	}
+	fmt.Println("")
+	if len(sources) == 1 {
+		opts, err := burialOptions(sources[0])
+		if err != nil {
+			exitWithError(err)
+		}
+		result, err := bury(opts)
+		if err != nil {
+			exitWithError(err)
+		}
+		printBurial(result)
+		return
+	}
+	burySources(sources)
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
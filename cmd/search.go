@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/deanhigh/bury-it/internal/display"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/index"
+	"github.com/deanhigh/bury-it/internal/metadata"
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchContentFlag bool
+	searchJSONFlag    bool
+	searchTagFlags    []string
+	searchAllFlag     bool
+)
+
+// searchResult is a single search hit as printed by the search command.
+type searchResult struct {
+	Graveyard string     `json:"graveyard,omitempty"`
+	Project   string     `json:"project"`
+	Kind      index.Kind `json:"kind"`
+	Ref       string     `json:"ref"`
+	Title     string     `json:"title,omitempty"`
+}
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search buried projects by name, metadata, or content",
+	Long: `Search buried projects in the graveyard.
+
+By default, project names, their aliases, and metadata are matched against
+the query.
+With --content, the full-text search index built by bury-it index is queried
+instead, covering file contents, metadata, and commit messages. Every word in
+the query must appear in a document for it to match.
+
+With --tag, only projects carrying every given tag are searched.
+
+With --all-graveyards, or without --graveyard, every graveyard used on this
+machine is searched, and each result is prefixed with the graveyard holding
+it. A graveyard without a search index is indexed in memory
+for the search, without changing it.`,
+	Example: `  # Find projects by name or metadata
+  bury-it search experiment -g ~/graveyard
+
+  # Search file contents and commit messages using the index
+  bury-it search --content "websocket reconnect" -g ~/graveyard
+
+  # Search only projects tagged ml
+  bury-it search --content tensor --tag ml -g ~/graveyard
+
+  # Find which graveyard holds a project
+  bury-it search old-experiment --all-graveyards`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var results []searchResult
+		if searchAllFlag || graveyardFlag == "" {
+			var err error
+			results, err = searchAllGraveyards(args[0])
+			if err != nil {
+				exitWithError(err)
+			}
+		} else {
+			gy, err := openGraveyard()
+			if err != nil {
+				exitWithError(err)
+			}
+			results, err = searchGraveyard(gy, args[0], false)
+			if err != nil {
+				exitWithError(err)
+			}
+		}
+
+		if searchJSONFlag {
+			if results == nil {
+				results = []searchResult{}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(results); err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+
+		for _, r := range results {
+			if r.Graveyard != "" {
+				fmt.Printf("%s\t", display.Path(r.Graveyard))
+			}
+			if r.Title != "" {
+				fmt.Printf("%s\t%s\t%s\t%s\n", r.Project, r.Kind, r.Ref, r.Title)
+			} else {
+				fmt.Printf("%s\t%s\t%s\n", r.Project, r.Kind, r.Ref)
+			}
+		}
+	},
+}
+
+// searchGraveyard runs the search given by the flags in one graveyard. If
+// buildIndex is set, a missing search index is built in memory rather than
+// reported.
+func searchGraveyard(gy *graveyard.Graveyard, query string, buildIndex bool) ([]searchResult, error) {
+	var results []searchResult
+	var err error
+	if searchContentFlag {
+		results, err = searchContent(gy, query, buildIndex)
+	} else {
+		results, err = searchMetadata(gy, query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(searchTagFlags) > 0 {
+		return filterByTags(gy, results, searchTagFlags)
+	}
+	return results, nil
+}
+
+// searchAllGraveyards searches every graveyard used on this machine and
+// merges the results, in the order the graveyards were last used. Graveyards
+// that have gone missing are reported and skipped.
+func searchAllGraveyards(query string) ([]searchResult, error) {
+	known, err := graveyard.Known()
+	if err != nil {
+		return nil, err
+	}
+	if len(known) == 0 {
+		return nil, fmt.Errorf("no graveyards have been used on this machine yet")
+	}
+
+	var results []searchResult
+	for _, path := range known {
+		gy, err := graveyard.New(path)
+		if err == nil {
+			err = gy.Validate()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping graveyard %s: %v\n", path, err)
+			continue
+		}
+		found, err := searchGraveyard(gy, query, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search %s: %w", path, err)
+		}
+		for _, r := range found {
+			r.Graveyard = path
+			results = append(results, r)
+		}
+	}
+	return results, nil
+}
+
+// searchMetadata matches the query against project names, their aliases,
+// and metadata files.
+func searchMetadata(gy *graveyard.Graveyard, query string) ([]searchResult, error) {
+	projects, err := gy.Projects()
+	if err != nil {
+		return nil, err
+	}
+	aliases, err := index.LoadAliases(gy.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(query)
+	var results []searchResult
+	for _, name := range projects {
+		content, err := os.ReadFile(filepath.Join(gy.ProjectPath(name), metadata.FileName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read metadata for %s: %w", name, err)
+		}
+		names := strings.ToLower(strings.Join(append([]string{name}, aliases.Of(name)...), "\n"))
+		if strings.Contains(names, query) || strings.Contains(strings.ToLower(string(content)), query) {
+			results = append(results, searchResult{Project: name, Kind: index.KindMetadata, Ref: metadata.FileName})
+		}
+	}
+	return results, nil
+}
+
+// searchContent queries the full-text search index. If buildIndex is set, a
+// graveyard without an index is indexed in memory.
+func searchContent(gy *graveyard.Graveyard, query string, buildIndex bool) ([]searchResult, error) {
+	var idx *index.Index
+	var err error
+	if _, statErr := os.Stat(index.Path(gy.Path)); buildIndex && os.IsNotExist(statErr) {
+		idx, err = index.Build(gy)
+	} else {
+		idx, err = index.Load(gy.Path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var results []searchResult
+	for _, doc := range idx.Search(query) {
+		results = append(results, searchResult{Project: doc.Project, Kind: doc.Kind, Ref: doc.Ref, Title: doc.Title})
+	}
+	return results, nil
+}
+
+// filterByTags keeps the results from projects that carry every one of tags.
+func filterByTags(gy *graveyard.Graveyard, results []searchResult, tags []string) ([]searchResult, error) {
+	projects, err := taggedProjects(gy, tags)
+	if err != nil {
+		return nil, err
+	}
+	keep := make(map[string]bool, len(projects))
+	for _, name := range projects {
+		keep[name] = true
+	}
+
+	var filtered []searchResult
+	for _, r := range results {
+		if keep[r.Project] {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered, nil
+}
+
+func init() {
+	searchCmd.Flags().BoolVar(&searchContentFlag, "content", false, "search file contents and commit messages using the index")
+	searchCmd.Flags().BoolVar(&searchJSONFlag, "json", false, "output results as JSON")
+	searchCmd.Flags().StringArrayVar(&searchTagFlags, "tag", nil, "only search projects with this tag (repeatable)")
+	searchCmd.Flags().BoolVar(&searchAllFlag, "all-graveyards", false, "search every graveyard used on this machine")
+	rootCmd.AddCommand(searchCmd)
+}
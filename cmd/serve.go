@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/capability"
+	"github.com/deanhigh/bury-it/internal/display"
+	"github.com/deanhigh/bury-it/internal/web"
+	"github.com/spf13/cobra"
+)
+
+var serveAddrFlag string
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Browse the graveyard in a web browser",
+	Long: `Start a local, read-only web server for browsing the graveyard: the project
+list, each project's metadata and README, and its files.
+
+The server listens on localhost only unless --addr says otherwise.`,
+	Example: `  bury-it serve -g ~/graveyard
+
+  # Listen on a different port
+  bury-it serve -g ~/graveyard --addr 127.0.0.1:9000`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := capability.Check(capability.Serve); err != nil {
+			exitWithError(err)
+		}
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		server := &http.Server{
+			Addr:              serveAddrFlag,
+			Handler:           web.New(gy),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		fmt.Printf("Serving %s at http://%s/\n", display.Path(gy.Path), serveAddrFlag)
+		if err := server.ListenAndServe(); err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddrFlag, "addr", "127.0.0.1:8080", "address to listen on")
+	rootCmd.AddCommand(serveCmd)
+}
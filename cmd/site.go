@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/deanhigh/bury-it/internal/web"
+	"github.com/spf13/cobra"
+)
+
+var siteOutFlag string
+
+var siteCmd = &cobra.Command{
+	Use:   "site",
+	Short: "Generate a static HTML catalog of the graveyard",
+	Long: `Generate a static HTML catalog of the graveyard: an index page listing every
+buried project and a page per project with its metadata and README.
+
+Pages use relative links, so the output can be published as-is, for example
+from the docs/ directory of a repository with GitHub Pages. Pages for projects
+that are no longer in the graveyard are removed; other files in the output
+directory are left alone.`,
+	Example: `  bury-it site -g ~/graveyard --out docs/`,
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkReadOnly("writing a site"); err != nil {
+			exitWithError(err)
+		}
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		n, err := web.WriteSite(gy, siteOutFlag)
+		if err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("Wrote catalog of %d projects to %s\n", n, siteOutFlag)
+	},
+}
+
+func init() {
+	siteCmd.Flags().StringVarP(&siteOutFlag, "out", "o", "site", "directory to write the site to")
+	rootCmd.AddCommand(siteCmd)
+}
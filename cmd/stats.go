@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/metadata"
+	"github.com/deanhigh/bury-it/internal/size"
+	"github.com/deanhigh/bury-it/internal/stats"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsTagFlags     []string
+	statsJSONFlag     bool
+	statsByOwnerFlag  bool
+	statsPersonalFlag bool
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Summarize the graveyard and the savings of its sunset projects",
+	Long: `Summarize the projects in the graveyard: how many were buried each year, how
+many kept their history, and how many are due for review. If reads of the
+projects are recorded with --track-access, how many were read in the last
+year, and how many never, is shown too.
+
+For projects buried with --monthly-cost-before, the estimated savings are
+totalled: the monthly cost before the sunset less the cost after it, per
+month, per year, and accumulated since each burial. Projects without a cost
+before their sunset are not included in the savings.
+
+With --by-owner, burial counts, sizes, and savings are also broken down by the
+owner recorded with --owner, for reporting on each team's decommissioning
+progress. Measuring sizes reads every project, so this is slower.
+
+With --personal, the burials made on this machine are summarized instead, in
+any graveyard: how many, how large, and roughly how much time they saved over
+burying by hand, in all and per year. They are recorded in a file in the
+bury-it state directory, which is never sent anywhere.`,
+	Example: `  # Summarize the whole graveyard
+  bury-it stats -g ~/graveyard
+
+  # Savings of the machine learning experiments, as JSON
+  bury-it stats -g ~/graveyard --tag ml --json
+
+  # Break burials down per team
+  bury-it stats -g ~/graveyard --by-owner
+
+  # Recap your own burials
+  bury-it stats --personal`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if statsPersonalFlag {
+			burials, err := stats.LoadBurials()
+			if err != nil {
+				exitWithError(err)
+			}
+			p := stats.ComputePersonal(burials)
+			if statsJSONFlag {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(p); err != nil {
+					exitWithError(err)
+				}
+				return
+			}
+			printPersonalStats(p)
+			return
+		}
+
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		names, err := gy.Projects()
+		if err != nil {
+			exitWithError(err)
+		}
+		projects := map[string]*metadata.Metadata{}
+		for _, name := range names {
+			meta, err := gy.Metadata(name)
+			if err != nil {
+				exitWithError(err)
+			}
+			if meta.HasTags(statsTagFlags...) {
+				projects[name] = meta
+			}
+		}
+		s := stats.Compute(projects, time.Now())
+		accesses, err := gy.Accesses()
+		if err != nil {
+			exitWithError(err)
+		}
+		if len(accesses) > 0 {
+			s.CountConsulted(projects, accesses, time.Now())
+		}
+		if statsByOwnerFlag {
+			usages := map[string]*graveyard.Usage{}
+			for name := range projects {
+				usage, err := gy.DiskUsage(name)
+				if err != nil {
+					exitWithError(err)
+				}
+				usages[name] = usage
+			}
+			s.ByOwner = stats.ByOwner(projects, usages)
+		}
+
+		if statsJSONFlag {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(s); err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+		printStats(s)
+	},
+}
+
+func init() {
+	statsCmd.Flags().StringArrayVar(&statsTagFlags, "tag", nil, "only include projects with this tag (repeatable)")
+	statsCmd.Flags().BoolVar(&statsByOwnerFlag, "by-owner", false, "break projects, sizes, and savings down by owner")
+	statsCmd.Flags().BoolVar(&statsPersonalFlag, "personal", false, "summarize the burials made on this machine, from local records only")
+	statsCmd.Flags().BoolVar(&statsJSONFlag, "json", false, "output the statistics as JSON")
+	statsCmd.MarkFlagsMutuallyExclusive("personal", "tag")
+	statsCmd.MarkFlagsMutuallyExclusive("personal", "by-owner")
+	rootCmd.AddCommand(statsCmd)
+}
+
+// printStats prints graveyard statistics as text.
+func printStats(s *stats.Stats) {
+	fmt.Printf("Projects:           %d\n", s.Projects)
+	fmt.Printf("History preserved:  %d\n", s.HistoryPreserved)
+	fmt.Printf("Reviews due:        %d\n", s.ReviewsDue)
+	if c := s.Consulted; c != nil {
+		fmt.Printf("Read last year:     %d (%d never read)\n", c.LastYear, c.Never)
+	}
+
+	if len(s.BuriedByYear) > 0 {
+		years := make([]int, 0, len(s.BuriedByYear))
+		for year := range s.BuriedByYear {
+			years = append(years, year)
+		}
+		sort.Ints(years)
+		fmt.Println("")
+		fmt.Println("Buried by year:")
+		for _, year := range years {
+			fmt.Printf("  %d  %d\n", year, s.BuriedByYear[year])
+		}
+	}
+
+	if len(s.ByOwner) > 0 {
+		fmt.Println("")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "OWNER\tPROJECTS\tWORKTREE\tPACKED\tSAVED PER MONTH")
+		for _, o := range s.ByOwner {
+			owner := o.Owner
+			if owner == "" {
+				owner = "(none)"
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", owner, o.Projects, size.Format(o.WorkTree), size.Format(o.Packed), formatAmount(o.MonthlySavings))
+		}
+		_ = w.Flush()
+	}
+
+	fmt.Println("")
+	if s.Costs.Projects == 0 {
+		fmt.Println("No costs recorded. Bury projects with --monthly-cost-before to estimate savings.")
+		return
+	}
+	c := s.Costs
+	fmt.Printf("Estimated savings (%d of %d projects with costs):\n", c.Projects, s.Projects)
+	fmt.Printf("  Monthly cost before:  %s\n", formatAmount(c.MonthlyBefore))
+	fmt.Printf("  Monthly cost after:   %s\n", formatAmount(c.MonthlyAfter))
+	fmt.Printf("  Saved per month:      %s\n", formatAmount(c.MonthlySavings))
+	fmt.Printf("  Saved per year:       %s\n", formatAmount(c.AnnualSavings))
+	fmt.Printf("  Saved to date:        %s\n", formatAmount(c.SavedToDate))
+
+	fmt.Println("")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROJECT\tPER MONTH\tTO DATE")
+	for _, saving := range c.Savings {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", saving.Project, formatAmount(saving.Monthly), formatAmount(saving.ToDate))
+	}
+	_ = w.Flush()
+}
+
+// printPersonalStats prints the statistics of the burials made on this
+// machine as text.
+func printPersonalStats(p *stats.Personal) {
+	if p.Burials == 0 {
+		fmt.Println("No burials recorded on this machine yet.")
+		return
+	}
+	fmt.Printf("Burials:      %d\n", p.Burials)
+	fmt.Printf("Archived:     %s\n", size.Format(p.Bytes))
+	fmt.Printf("Time saved:   %s (estimated)\n", formatMinutes(p.MinutesSaved))
+
+	fmt.Println("")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "YEAR\tBURIALS\tARCHIVED\tTIME SAVED")
+	for _, y := range p.Years {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\n", y.Year, y.Burials, size.Format(y.Bytes), formatMinutes(y.MinutesSaved))
+	}
+	_ = w.Flush()
+	fmt.Println("")
+	fmt.Println("These figures are kept on this machine only and are never sent anywhere.")
+}
+
+// formatMinutes formats a number of minutes as hours and minutes.
+func formatMinutes(minutes float64) string {
+	d := time.Duration(minutes * float64(time.Minute)).Round(time.Minute)
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// formatAmount formats an amount of money to two decimal places.
+func formatAmount(v float64) string {
+	return fmt.Sprintf("%.2f", v)
+}
@@ -0,0 +1,422 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/age"
+	"github.com/deanhigh/bury-it/internal/archive"
+	"github.com/deanhigh/bury-it/internal/capability"
+	"github.com/deanhigh/bury-it/internal/display"
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/github"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/sweep"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sweepRulesFlag    string
+	sweepPolicyFlag   string
+	sweepPathFlags    []string
+	sweepDryRunFlag   bool
+	sweepNotifyFlag   bool
+	sweepGraceFlag    string
+	sweepSimulateFlag bool
+	sweepAsOfFlag     string
+)
+
+var sweepCmd = &cobra.Command{
+	Use:   "sweep",
+	Short: "Bury every repository matched by a rules file",
+	Long: `Scan the directories named in a rules file for git repositories that have not
+been touched for a given time, and bury each match in one run.
+
+A rules file looks like:
+
+  graveyard: ~/graveyard
+  rules:
+    - name: stale experiments
+      path: ~/src/experiments
+      not_touched_for: 18mo
+      drop_history: false
+      exclude: ["keep-*"]
+
+Ages are written as a number and unit: d (days), w (weeks), mo (months), or
+y (years), e.g. 30d, 18mo, or 1y6mo. A repository's age is taken from its most
+recent commit on any branch. Rules are applied in order and a repository is
+only buried once. The --graveyard flag overrides the graveyard in the file.
+
+Instead of a rules file, the criteria can come from an organization's sunset
+policy with --policy, given as a sunset-policy.yaml file or a clone of the
+governance repository that holds one. The policy is applied to each --path:
+
+  staleness: 18mo
+  topics: [experiment, prototype]
+  owners: ["@org/platform"]
+  exclude: ["infra-*"]
+  drop_history: false
+
+Rules and policies that list topics only match repositories whose GitHub
+origin carries one of them; GITHUB_TOKEN is used if set. The commit of the
+policy file is printed with the report so that a sweep can be traced back to
+the reviewed policy.
+
+With --notify-owners, a sweep does not bury a match straight away. It opens
+an issue on the repository's GitHub origin, mentioning the rule's owners, and
+records the notice locally. Later sweeps bury the repository once the grace
+period has passed, unless one of the rule's owners or a collaborator on the
+repository commented /keep on the issue, in which case it is kept for good.
+Repositories not hosted on GitHub are skipped.
+
+With --simulate, nothing is buried or notified. The rules are evaluated as of
+the --as-of date, judging each repository only by the commits it had then, to
+show what a sweep on that date would have buried. Repositories that have had
+commits since are flagged as revived, which helps tune thresholds before
+turning on automated sweeps.
+
+A failed burial is reported and the sweep continues with the next repository.`,
+	Example: `  # Preview what would be buried
+  bury-it sweep --rules rules.yaml --dry-run
+
+  # Bury every match into the graveyard
+  bury-it sweep --rules rules.yaml -g ~/graveyard
+
+  # Apply the organization's sunset policy to ~/src
+  bury-it sweep --policy ~/governance --path ~/src -g ~/graveyard
+
+  # Warn owners first; run again after two weeks to bury
+  bury-it sweep --rules rules.yaml --notify-owners --grace 14d
+
+  # See what a sweep at the start of 2024 would have buried
+  bury-it sweep --rules rules.yaml --simulate --as-of 2024-01-01`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		rules, err := loadSweepRules()
+		if err != nil {
+			exitWithError(err)
+		}
+		if !sweepDryRunFlag && !sweepSimulateFlag {
+			if err := checkReadOnly("sweeping without --dry-run"); err != nil {
+				exitWithError(err)
+			}
+		}
+		if sweepNotifyFlag && !sweepDryRunFlag {
+			if err := checkOffline("--notify-owners"); err != nil {
+				exitWithError(err)
+			}
+		}
+		if registryPRFlag {
+			if err := checkOffline("--registry-pr"); err != nil {
+				exitWithError(err)
+			}
+		}
+		if graveyardFlag == "" {
+			graveyardFlag = rules.Graveyard
+		}
+		if sweepSimulateFlag {
+			if err := simulateSweep(rules); err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		candidates, err := rules.Candidates(time.Now())
+		if err != nil {
+			exitWithError(err)
+		}
+		candidates = excludeGraveyard(candidates, gy)
+		client := github.NewClient(github.TokenFromEnv())
+		candidates, err = sweep.FilterTopics(candidates, func(repoPath string) ([]string, error) {
+			return repoTopics(client, repoPath)
+		})
+		if err != nil {
+			exitWithError(err)
+		}
+		if len(candidates) == 0 {
+			fmt.Println("No repositories matched the rules.")
+			return
+		}
+
+		var notices sweep.Notices
+		var notifier sweep.Notifier
+		var grace age.Span
+		if sweepNotifyFlag {
+			if !sweepDryRunFlag {
+				if err := capability.Check(capability.GitHubWrite); err != nil {
+					exitWithError(fmt.Errorf("--notify-owners cannot be used: %w", err))
+				}
+			}
+			if client.Token == "" && !sweepDryRunFlag {
+				exitWithError(fmt.Errorf("--notify-owners requires GITHUB_TOKEN to be set"))
+			}
+			if grace, err = age.Parse(sweepGraceFlag); err != nil {
+				exitWithError(fmt.Errorf("invalid --grace: %w", err))
+			}
+			if notices, err = sweep.LoadNotices(); err != nil {
+				exitWithError(err)
+			}
+			notifier = &sweep.GitHubNotifier{Client: client}
+		}
+
+		now := time.Now()
+		statuses := make([]string, len(candidates))
+		var buried, wouldBury int
+		var failures []string
+		for i, c := range candidates {
+			var notice *sweep.Notice
+			if notices != nil {
+				decision, err := notices.Review(c, now, grace, notifier, sweepDryRunFlag)
+				if err != nil {
+					statuses[i] = "failed"
+					failures = append(failures, fmt.Sprintf("%s: %v", c.Repo.Path, err))
+					continue
+				}
+				// Save straight away, so that a later failure cannot lose
+				// the record of an issue that was just opened
+				if (decision == sweep.DecisionNotify || decision == sweep.DecisionKeep) && !sweepDryRunFlag {
+					if err := notices.Save(); err != nil {
+						exitWithError(err)
+					}
+				}
+				notice = notices[c.Repo.Path]
+				switch {
+				case decision == sweep.DecisionBury:
+				case notice == nil && decision == sweep.DecisionNotify:
+					statuses[i] = "would notify"
+					continue
+				case decision == sweep.DecisionNotify || decision == sweep.DecisionWait:
+					statuses[i] = fmt.Sprintf("%s until %s", decision, notice.Deadline.Format("2006-01-02"))
+					continue
+				case decision == sweep.DecisionSkip:
+					statuses[i] = "skipped (owners unreachable)"
+					continue
+				default:
+					statuses[i] = string(decision)
+					continue
+				}
+			}
+
+			if sweepDryRunFlag {
+				statuses[i] = "would bury"
+				wouldBury++
+				continue
+			}
+
+			fmt.Printf("Burying %s (%s)...\n", display.Path(c.Repo.Path), c.Rule.Name)
+			started := time.Now()
+			result, err := archive.Archive(archive.Options{
+				Source:       c.Repo.Path,
+				Graveyard:    gy.Path,
+				DropHistory:  c.Rule.DropHistory,
+				Registry:     registryFlag,
+				RegistryFile: registryFileFlag,
+				RegistryPR:   registryPRFlag,
+				Offline:      offlineFlag,
+			})
+			if err != nil {
+				statuses[i] = "failed"
+				failures = append(failures, fmt.Sprintf("%s: %v", c.Repo.Path, err))
+				continue
+			}
+			statuses[i] = "buried"
+			buried++
+			recordPersonalBurial(gy.Path, result.ProjectName, started)
+
+			if notice != nil {
+				if err := notifier.Resolve(notice, graveyardLocation(gy)); err != nil {
+					fmt.Printf("Warning: failed to close notice %s: %v\n", notice.URL, err)
+				}
+				delete(notices, c.Repo.Path)
+				if err := notices.Save(); err != nil {
+					exitWithError(err)
+				}
+			}
+		}
+
+		if !sweepDryRunFlag {
+			fmt.Println("")
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "STATUS\tREPOSITORY\tLAST COMMIT\tRULE")
+		for i, c := range candidates {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", statuses[i], display.Path(c.Repo.Path), c.Repo.LastCommit.Format("2006-01-02"), c.Rule.Name)
+		}
+		_ = w.Flush()
+		printOwners(candidates)
+
+		if sweepDryRunFlag {
+			fmt.Printf("\n%d repositories would be buried.\n", wouldBury)
+			return
+		}
+		fmt.Printf("\nBuried %d of %d repositories.\n", buried, len(candidates))
+		if len(failures) > 0 {
+			exitWithError(fmt.Errorf("%d burials failed:\n  %s", len(failures), strings.Join(failures, "\n  ")))
+		}
+	},
+}
+
+func init() {
+	sweepCmd.Flags().StringVar(&sweepRulesFlag, "rules", "", "path to the sweep rules file (YAML)")
+	sweepCmd.Flags().StringVar(&sweepPolicyFlag, "policy", "", "sunset policy file, or governance repository containing "+sweep.PolicyFileName)
+	sweepCmd.Flags().StringArrayVar(&sweepPathFlags, "path", nil, "directory to apply --policy to (repeatable)")
+	sweepCmd.MarkFlagsMutuallyExclusive("rules", "policy")
+	sweepCmd.Flags().BoolVar(&sweepNotifyFlag, "notify-owners", false, "open a notice issue on each match and only bury once the grace period passes without objection")
+	sweepCmd.Flags().StringVar(&sweepGraceFlag, "grace", "30d", "grace period between notice and burial with --notify-owners")
+	sweepCmd.Flags().BoolVar(&sweepDryRunFlag, "dry-run", false, "list matching repositories without burying them")
+	sweepCmd.Flags().BoolVar(&sweepSimulateFlag, "simulate", false, "show what a sweep on the --as-of date would have buried")
+	sweepCmd.Flags().StringVar(&sweepAsOfFlag, "as-of", "", "date to simulate the sweep at (YYYY-MM-DD), with --simulate")
+	sweepCmd.MarkFlagsMutuallyExclusive("simulate", "notify-owners")
+	addRegistryFlags(sweepCmd.Flags())
+	rootCmd.AddCommand(sweepCmd)
+}
+
+// loadSweepRules loads the rules file, or builds rules from the sunset policy.
+func loadSweepRules() (*sweep.Rules, error) {
+	switch {
+	case sweepRulesFlag != "":
+		if len(sweepPathFlags) > 0 {
+			return nil, fmt.Errorf("--path can only be used with --policy")
+		}
+		return sweep.Load(sweepRulesFlag)
+	case sweepPolicyFlag != "":
+		policy, err := sweep.LoadPolicy(sweepPolicyFlag)
+		if err != nil {
+			return nil, err
+		}
+		version := policy.Version
+		if version == "" {
+			version = "not under version control"
+		}
+		fmt.Printf("Policy: %s (%s)\n", display.Path(policy.Path), version)
+		return policy.Rules(sweepPathFlags)
+	default:
+		return nil, fmt.Errorf("--rules or --policy is required")
+	}
+}
+
+// simulateSweep reports what the rules would have selected on the --as-of
+// date, flagging repositories with commits after that date as revived.
+func simulateSweep(rules *sweep.Rules) error {
+	if sweepAsOfFlag == "" {
+		return fmt.Errorf("--simulate requires --as-of")
+	}
+	asOf, err := time.Parse("2006-01-02", sweepAsOfFlag)
+	if err != nil {
+		return fmt.Errorf("invalid --as-of %q: use YYYY-MM-DD", sweepAsOfFlag)
+	}
+	if !asOf.Before(time.Now()) {
+		return fmt.Errorf("--as-of must be in the past")
+	}
+
+	candidates, err := rules.CandidatesAsOf(asOf)
+	if err != nil {
+		return err
+	}
+	if graveyardFlag != "" {
+		if gy, err := openGraveyard(); err == nil {
+			candidates = excludeGraveyard(candidates, gy)
+		}
+	}
+	client := github.NewClient(github.TokenFromEnv())
+	candidates, err = sweep.FilterTopics(candidates, func(repoPath string) ([]string, error) {
+		return repoTopics(client, repoPath)
+	})
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		fmt.Printf("No repositories would have matched the rules on %s.\n", sweepAsOfFlag)
+		return nil
+	}
+
+	var revived int
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tREPOSITORY\tLAST COMMIT\tRULE")
+	for _, c := range candidates {
+		status := "would bury"
+		latest, err := git.LastCommitDate(c.Repo.Path)
+		if err != nil {
+			return err
+		}
+		if latest.After(asOf) {
+			status = "would bury, revived " + latest.Format("2006-01-02")
+			revived++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status, display.Path(c.Repo.Path), c.Repo.LastCommit.Format("2006-01-02"), c.Rule.Name)
+	}
+	_ = w.Flush()
+
+	fmt.Printf("\n%d repositories would have been buried on %s; %d have had commits since.\n", len(candidates), sweepAsOfFlag, revived)
+	return nil
+}
+
+// repoTopics returns the GitHub topics of a repository's origin. Repositories
+// not hosted on GitHub have no topics.
+func repoTopics(client *github.Client, repoPath string) ([]string, error) {
+	remote, err := git.GetRemoteURL(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	owner, repo, ok := github.ParseRepoURL(remote)
+	if !ok {
+		return nil, nil
+	}
+	if err := checkOffline("reading topics from GitHub for a rule with topics"); err != nil {
+		return nil, err
+	}
+	return client.Topics(owner, repo)
+}
+
+// printOwners lists the owners to notify about the candidates, grouped by
+// owner.
+func printOwners(candidates []sweep.Candidate) {
+	repos := make(map[string][]string)
+	var owners []string
+	for _, c := range candidates {
+		for _, owner := range c.Rule.Owners {
+			if _, ok := repos[owner]; !ok {
+				owners = append(owners, owner)
+			}
+			repos[owner] = append(repos[owner], filepath.Base(c.Repo.Path))
+		}
+	}
+	if len(owners) == 0 {
+		return
+	}
+	fmt.Println("\nOwners to notify:")
+	for _, owner := range owners {
+		fmt.Printf("  %s: %s\n", owner, strings.Join(repos[owner], ", "))
+	}
+}
+
+// graveyardLocation returns the graveyard's remote URL, without any
+// credentials in it, or its path if it has no remote.
+func graveyardLocation(gy *graveyard.Graveyard) string {
+	if remote, err := git.GetRemoteURL(gy.Path); err == nil && remote != "" {
+		return display.URL(remote)
+	}
+	return gy.Path
+}
+
+// excludeGraveyard drops the graveyard itself, and anything inside it, from
+// the candidates.
+func excludeGraveyard(candidates []sweep.Candidate, gy *graveyard.Graveyard) []sweep.Candidate {
+	var kept []sweep.Candidate
+	for _, c := range candidates {
+		rel, err := filepath.Rel(gy.Path, c.Repo.Path)
+		if err == nil && (rel == "." || !strings.HasPrefix(rel, "..")) {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/deanhigh/bury-it/internal/audit"
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/index"
+	"github.com/deanhigh/bury-it/internal/metadata"
+	"github.com/spf13/cobra"
+)
+
+var tagCmd = &cobra.Command{
+	Use:   "tag <project> [+tag | -tag]...",
+	Short: "Add or remove labels on a buried project",
+	Long: `Attach labels to a buried project, such as a theme or year, to organize the
+graveyard. Tags are stored in the project's metadata file and committed.
+
+Prefix a tag with + (or nothing) to add it and with - to remove it. Put
+removals after -- so they are not read as flags. Without any tags, the
+project's current tags are printed.
+
+Filter by tag with bury-it list --tag and bury-it search --tag.`,
+	Example: `  # Label a project by theme and year
+  bury-it tag old-experiment +ml +2023 -g ~/graveyard
+
+  # Remove a label
+  bury-it tag old-experiment -g ~/graveyard -- -2023
+
+  # Show a project's tags
+  bury-it tag old-experiment -g ~/graveyard`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeProject,
+	Run: func(cmd *cobra.Command, args []string) {
+		gy, err := openProjectGraveyard(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+
+		project := args[0]
+		meta, err := gy.Metadata(project)
+		if err != nil {
+			exitWithError(err)
+		}
+		if len(args) == 1 {
+			fmt.Println(strings.Join(meta.Tags, " "))
+			return
+		}
+
+		if err := checkReadOnly("changing tags"); err != nil {
+			exitWithError(err)
+		}
+
+		tags := make(map[string]bool)
+		for _, tag := range meta.Tags {
+			tags[strings.ToLower(tag)] = true
+		}
+		for _, arg := range args[1:] {
+			remove := strings.HasPrefix(arg, "-")
+			tag, err := metadata.NormalizeTag(strings.TrimLeft(arg, "+-"))
+			if err != nil {
+				exitWithError(err)
+			}
+			if remove {
+				delete(tags, tag)
+			} else {
+				tags[tag] = true
+			}
+		}
+
+		updated := make([]string, 0, len(tags))
+		for tag := range tags {
+			updated = append(updated, tag)
+		}
+		sort.Strings(updated)
+
+		if err := gy.SetTags(project, updated); err != nil {
+			exitWithError(err)
+		}
+		if err := commitMetadata(gy, project, "docs: bury-it - tagged "+project); err != nil {
+			exitWithError(err)
+		}
+
+		fmt.Printf("%s: %s\n", project, strings.Join(updated, " "))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+}
+
+// commitMetadata refreshes the search index entry of a project whose staged
+// metadata changed, if the graveyard has an index, and commits the change.
+func commitMetadata(gy *graveyard.Graveyard, project, message string) error {
+	if _, err := os.Stat(index.Path(gy.Path)); err == nil {
+		if err := index.Refresh(gy, project); err != nil {
+			return err
+		}
+	}
+	changed, err := git.HasStagedChanges(gy.Path)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+	if err := audit.Record(gy.Path, project); err != nil {
+		return err
+	}
+	if err := git.Commit(gy.Path, message); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/deanhigh/bury-it/internal/audit"
+	"github.com/deanhigh/bury-it/internal/capability"
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/spf13/cobra"
+)
+
+var (
+	undoRevertFlag          bool
+	undoDryRunFlag          bool
+	undoRequireApprovalFlag bool
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Revert the most recent burial",
+	Long: `Undo the most recent burial in the graveyard, including the subtree merge
+created when history was preserved and the issue export of a burial made with
+--with-issues, and remove the project directory. Undoing a burial made with
+--new-version puts the previous version back.
+
+Burials that have not been pushed are removed by resetting the graveyard to
+the commit before the burial. Burials that have already been pushed are
+removed with revert commits so that shared history is not rewritten, as are
+burials with earlier undos recorded on top of them.
+
+With --require-approval, nothing is undone. The undo is recorded as a pending
+request instead, and only carried out when someone logged in as a different
+user runs bury-it approve with the printed request ID. An administrator can
+require this for every undo by setting capability.undo to approve in the
+system configuration.`,
+	Example: `  # Undo the last burial
+  bury-it undo -g ~/graveyard
+
+  # Show what would be undone without changing anything
+  bury-it undo -g ~/graveyard --dry-run
+
+  # Ask a second person to approve the undo
+  bury-it undo -g ~/graveyard --require-approval`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		burial, err := gy.LastBurial()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		fmt.Printf("Last burial: %s (%d commit(s))\n", burial.Project, len(burial.Commits))
+		if undoDryRunFlag {
+			for _, commit := range burial.Commits {
+				fmt.Printf("  would undo %s\n", commit)
+			}
+			return
+		}
+		if err := checkReadOnly("undoing a burial"); err != nil {
+			exitWithError(err)
+		}
+		if err := capability.Check(capability.Undo); err != nil {
+			exitWithError(err)
+		}
+
+		if undoRequireApprovalFlag || capability.RequiresApproval(capability.Undo) {
+			err := requestApproval("undo", gy, "undo the burial of "+burial.Project, map[string]string{
+				"project": burial.Project,
+				"commit":  burial.Commits[0],
+				"revert":  strconv.FormatBool(undoRevertFlag),
+			})
+			if err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+
+		if err := undoBurial(gy, burial, undoRevertFlag); err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+func init() {
+	undoCmd.Flags().BoolVar(&undoRevertFlag, "revert", false, "always undo with revert commits instead of resetting")
+	undoCmd.Flags().BoolVar(&undoDryRunFlag, "dry-run", false, "show what would be undone without changing anything")
+	undoCmd.Flags().BoolVar(&undoRequireApprovalFlag, "require-approval", false, "record a request for a second person to approve instead of undoing")
+	rootCmd.AddCommand(undoCmd)
+}
+
+// undoBurial undoes a burial and reports how it was removed.
+func undoBurial(gy *graveyard.Graveyard, burial *graveyard.Burial, revert bool) error {
+	reverted, err := gy.Undo(burial, revert)
+	if err != nil {
+		return err
+	}
+
+	// The burial's own log entry may be gone with it, so log the undo on top
+	if err := audit.Record(gy.Path, burial.Project); err != nil {
+		return err
+	}
+	if changed, err := git.HasStagedChanges(gy.Path); err == nil && changed {
+		if err := git.Commit(gy.Path, graveyard.UndoLogMessage(burial.Project)); err != nil {
+			return fmt.Errorf("failed to commit: %w", err)
+		}
+	}
+
+	if reverted {
+		fmt.Printf("Reverted burial of %s.\n", burial.Project)
+		fmt.Println("")
+		fmt.Println("Next step: Push the revert commits to share the change")
+	} else {
+		fmt.Printf("Removed burial of %s; graveyard reset to %s.\n", burial.Project, burial.Parent[:12])
+	}
+	if burial.Retired > 0 {
+		fmt.Printf("Version %d of %s is back in place.\n", burial.Retired, burial.Project)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/deanhigh/bury-it/internal/archive"
+	"github.com/spf13/cobra"
+)
+
+var vendorSubmodulesCmd = &cobra.Command{
+	Use:   "vendor-submodules <project>",
+	Short: "Copy the content of a buried project's submodules into it",
+	Long: `Copy the content of a buried project's submodules into it as plain files,
+for projects buried without --recurse-submodules. Once the original repository
+is deleted, the submodules' own repositories may follow, and the links the
+burial kept would point nowhere.
+
+Each submodule is cloned from the URL recorded for it by the burial, resolved
+against the project's original source if relative, and checked out at the
+commit the project pinned. Submodules of submodules are copied too. The
+metadata's Submodules section is updated and the result committed.`,
+	Example:           `  bury-it vendor-submodules old-experiment -g ~/graveyard`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProject,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkReadOnly("vendoring submodules"); err != nil {
+			exitWithError(err)
+		}
+		gy, err := openProjectGraveyard(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+
+		project := args[0]
+		vendored, err := archive.VendorSubmodules(gy, project, offlineFlag)
+		if err != nil {
+			exitWithError(err)
+		}
+		if err := commitMetadata(gy, project, "docs: bury-it - vendored submodules of "+project); err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("Copied %d submodule(s) into %s:\n", len(vendored), project)
+		for _, sub := range vendored {
+			fmt.Printf("  %s at %s\n", sub.Path, sub.Commit[:12])
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(vendorSubmodulesCmd)
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var whyJSONFlag bool
+
+// whyEntry is a graveyard path traced back to the project it was buried
+// with.
+type whyEntry struct {
+	Path    string      `json:"path"`
+	Within  string      `json:"path_in_project"`
+	Project listEntry   `json:"project"`
+	Commits []whyCommit `json:"commits"`
+}
+
+// whyCommit is a commit that changed a traced path.
+type whyCommit struct {
+	Hash    string    `json:"hash"`
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+	Subject string    `json:"subject"`
+}
+
+var whyCmd = &cobra.Command{
+	Use:   "why <path>",
+	Short: "Show how a file ended up in the graveyard",
+	Long: `Trace a file or directory in the graveyard back to the project it was buried
+with: show the project's burial details and the commits that changed the path.
+
+For projects buried with their history, the commits include the original
+ones from before the burial, following the file across renames. For
+projects buried without history, only the graveyard's commits are shown.
+
+The path may be absolute, relative to the working directory, or relative to
+the graveyard. Without --graveyard, the graveyard is the git repository the
+path is in.`,
+	Example: `  bury-it why ~/graveyard/old-tool/src/main.go
+  bury-it why old-tool/src/main.go -g ~/graveyard`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := args[0]
+		if graveyardFlag == "" {
+			dir := path
+			if info, err := os.Stat(path); err != nil || !info.IsDir() {
+				dir = filepath.Dir(path)
+			}
+			top, err := git.TopLevel(dir)
+			if err != nil {
+				exitWithError(fmt.Errorf("--graveyard is required (%s is not in a git repository)", path))
+			}
+			graveyardFlag = top
+		}
+		gy, err := openGraveyard()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		project, within, err := gy.Locate(path)
+		if err != nil {
+			exitWithError(err)
+		}
+		meta, err := gy.Metadata(project)
+		if err != nil {
+			exitWithError(err)
+		}
+		commits, err := gy.FileHistory(project, within)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		entry := whyEntry{Path: path, Within: within, Project: newListEntry(project, meta), Commits: []whyCommit{}}
+		entry.Project.Checklist = checklistProgress(gy.ProjectPath(project))
+		for _, c := range commits {
+			entry.Commits = append(entry.Commits, whyCommit{Hash: c.Hash, Author: c.Author, Date: c.Date, Subject: c.Subject})
+		}
+
+		if whyJSONFlag {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(entry); err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+
+		printProjectDetails(gy, project, meta, entry.Project)
+		if within == "" {
+			within = "the project"
+		}
+		fmt.Println()
+		if !meta.HistoryPreserved {
+			fmt.Printf("%s was buried without its history; only graveyard commits are shown.\n", project)
+		}
+		fmt.Printf("Commits that changed %s:\n", within)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, c := range entry.Commits {
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", c.Hash[:12], c.Date.Format("2006-01-02"), c.Author, c.Subject)
+		}
+		_ = w.Flush()
+	},
+}
+
+func init() {
+	whyCmd.Flags().BoolVar(&whyJSONFlag, "json", false, "output the project and commits as JSON")
+	rootCmd.AddCommand(whyCmd)
+}
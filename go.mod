@@ -2,9 +2,11 @@ module github.com/deanhigh/bury-it
 
 go 1.25.5
 
-require github.com/spf13/cobra v1.10.2
-
 require (
-	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/pflag v1.0.9 // indirect
+	github.com/klauspost/compress v1.18.0
+	github.com/spf13/cobra v1.10.2
+	github.com/spf13/pflag v1.0.9
+	go.yaml.in/yaml/v3 v3.0.4
 )
+
+require github.com/inconshreveable/mousetrap v1.1.0 // indirect
@@ -0,0 +1,277 @@
+// Package advise looks for ways to reclaim space in a graveyard, such as
+// histories worth dropping or projects nobody has touched in years, and
+// ranks them by what they would save.
+package advise
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/age"
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/metadata"
+	"github.com/deanhigh/bury-it/internal/size"
+)
+
+// Actions a suggestion can make.
+const (
+	// ActionPack packs loose objects and deletes garbage with git gc.
+	ActionPack = "pack"
+	// ActionCompact drops the preserved history of a project.
+	ActionCompact = "compact"
+	// ActionColdTier moves a project's files to a compressed archive
+	// outside the graveyard.
+	ActionColdTier = "cold-tier"
+	// ActionPurge removes a project's files from the graveyard.
+	ActionPurge = "purge"
+)
+
+// minPackable is the number of bytes of loose objects and garbage below
+// which packing is not worth suggesting.
+const minPackable = 1 << 20
+
+// minDuplicateShare is the share of a project's files that must be found,
+// unchanged, in another project for it to count as a duplicate.
+const minDuplicateShare = 0.9
+
+// Suggestion is a way to reclaim space in the graveyard.
+type Suggestion struct {
+	// Action is what the suggestion does, one of the Action constants.
+	Action string `json:"action"`
+	// Project is the project it applies to, or "" for the whole graveyard.
+	Project string `json:"project,omitempty"`
+	// Reason explains why it is suggested.
+	Reason string `json:"reason"`
+	// Savings is the estimated number of bytes it reclaims.
+	Savings int64 `json:"savings_bytes"`
+	// Command is the shell command that carries it out.
+	Command string `json:"command"`
+}
+
+// Options configures which suggestions are made.
+type Options struct {
+	// MaxHistory is the size in bytes of preserved history above which
+	// dropping it is suggested. Zero disables the suggestion.
+	MaxHistory int64
+	// Idle is how long a project must have gone untouched in the graveyard
+	// for moving it to cold storage to be suggested. A zero span disables
+	// the suggestion.
+	Idle age.Span
+	// Accesses are the reads of the projects recorded on this machine,
+	// keyed by project, or nil if reads are not tracked. A project read
+	// within Idle is still consulted, so it is not moved to cold storage.
+	Accesses map[string]graveyard.Access
+	// Now is the time review dates and idleness are judged against.
+	Now time.Time
+}
+
+// Run analyzes the graveyard and returns its suggestions, largest savings
+// first.
+func Run(gy *graveyard.Graveyard, opts Options) ([]Suggestion, error) {
+	projects, err := gy.Projects()
+	if err != nil {
+		return nil, err
+	}
+	tree, err := git.TrackedTree(gy.Path)
+	if err != nil {
+		return nil, err
+	}
+	files := projectFiles(tree)
+	blobs := make([]string, 0, len(tree))
+	for _, entry := range tree {
+		blobs = append(blobs, entry.Object)
+	}
+	infos, err := git.ObjectInfos(gy.Path, blobs)
+	if err != nil {
+		return nil, err
+	}
+
+	graveyardPath := git.ShellQuote(gy.Path)
+	var suggestions []Suggestion
+	loose, garbage, err := git.LooseObjects(gy.Path)
+	if err != nil {
+		return nil, err
+	}
+	if loose+garbage >= minPackable {
+		suggestions = append(suggestions, Suggestion{
+			Action:  ActionPack,
+			Reason:  fmt.Sprintf("%s of loose objects and %s of garbage in the object store", size.Format(loose), size.Format(garbage)),
+			Savings: loose + garbage,
+			Command: fmt.Sprintf("git -C %s gc --prune=now", graveyardPath),
+		})
+	}
+
+	suggested := make(map[string]bool)
+	for _, name := range projects {
+		meta, err := gy.Metadata(name)
+		if err != nil {
+			// Reported by bury-it health
+			continue
+		}
+		usage, err := gy.DiskUsage(name)
+		if err != nil {
+			return nil, err
+		}
+		quoted := git.ShellQuote(name)
+		remove := purgeCommand(graveyardPath, name)
+
+		if opts.MaxHistory > 0 && meta.HistoryPreserved {
+			var current int64
+			for _, entry := range files[name] {
+				current += infos[entry.Object].DiskSize
+			}
+			if history := usage.Packed - current; history > opts.MaxHistory {
+				suggestions = append(suggestions, Suggestion{
+					Action:  ActionCompact,
+					Project: name,
+					Reason:  fmt.Sprintf("its preserved history takes %s, over %s", size.Format(history), size.Format(opts.MaxHistory)),
+					Savings: history,
+					Command: fmt.Sprintf("bury-it compact %s -g %s --prune", quoted, graveyardPath),
+				})
+			}
+		}
+
+		if meta.ReviewDue(opts.Now) {
+			suggested[name] = true
+			suggestions = append(suggestions, Suggestion{
+				Action:  ActionPurge,
+				Project: name,
+				Reason:  fmt.Sprintf("it was due for review on %s", meta.ReviewAfter.Format("2006-01-02")),
+				Savings: usage.WorkTree,
+				Command: remove,
+			})
+			continue
+		}
+
+		if opts.Idle != (age.Span{}) {
+			commits, err := git.Log(gy.Path, []string{"HEAD"}, name+"/")
+			if err != nil {
+				return nil, err
+			}
+			var last time.Time
+			if len(commits) > 0 {
+				last = commits[0].Date
+			}
+			untouched := "touched"
+			if opts.Accesses != nil {
+				untouched = "changed or read"
+				if access := opts.Accesses[name]; access.Last.After(last) {
+					last = access.Last
+				}
+			}
+			if !last.IsZero() && last.Before(opts.Idle.Before(opts.Now)) {
+				suggested[name] = true
+				suggestions = append(suggestions, Suggestion{
+					Action:  ActionColdTier,
+					Project: name,
+					Reason:  fmt.Sprintf("it has not been %s since %s", untouched, last.Format("2006-01-02")),
+					Savings: usage.WorkTree,
+					Command: fmt.Sprintf("tar -czf %s -C %s %s && %s", git.ShellQuote(name+".tar.gz"), graveyardPath, quoted, remove),
+				})
+			}
+		}
+	}
+
+	for _, dup := range duplicates(files, projects) {
+		if suggested[dup.project] {
+			continue
+		}
+		suggested[dup.project] = true
+		usage, err := gy.DiskUsage(dup.project)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, Suggestion{
+			Action:  ActionPurge,
+			Project: dup.project,
+			Reason:  fmt.Sprintf("%.0f%% of its files are also in %s", dup.share*100, dup.of),
+			Savings: usage.WorkTree,
+			Command: purgeCommand(graveyardPath, dup.project),
+		})
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Savings > suggestions[j].Savings
+	})
+	return suggestions, nil
+}
+
+// projectFiles groups the files of the graveyard's tree by project, leaving
+// out the files bury-it generates, which every project has.
+func projectFiles(tree []git.TreeEntry) map[string][]git.TreeEntry {
+	files := make(map[string][]git.TreeEntry)
+	for _, entry := range tree {
+		project, rel, ok := strings.Cut(entry.Path, "/")
+		if !ok || entry.Mode == git.ModeSubmodule || generated(rel) {
+			continue
+		}
+		files[project] = append(files[project], entry)
+	}
+	return files
+}
+
+// generated reports whether rel, relative to a project, is a file bury-it
+// writes.
+func generated(rel string) bool {
+	return rel == metadata.DecommissionFileName || (!strings.Contains(rel, "/") && strings.HasPrefix(rel, ".bury-it"))
+}
+
+// purgeCommand returns the command removing project from the graveyard at
+// graveyardPath, which is already quoted.
+func purgeCommand(graveyardPath, project string) string {
+	return fmt.Sprintf("git -C %s rm -r -q %s && git -C %s commit -q -m %s",
+		graveyardPath, git.ShellQuote(project), graveyardPath, git.ShellQuote("docs: bury-it - purged "+project))
+}
+
+// duplicate is a project most of whose files are also in another.
+type duplicate struct {
+	project string
+	of      string
+	share   float64
+}
+
+// duplicates finds projects at least minDuplicateShare of whose files are
+// found unchanged in another project. Of two projects duplicating each
+// other, only the one with fewer files, or later in order, is reported.
+func duplicates(files map[string][]git.TreeEntry, projects []string) []duplicate {
+	contents := make(map[string]map[string]bool, len(projects))
+	for _, name := range projects {
+		set := make(map[string]bool)
+		for _, entry := range files[name] {
+			set[entry.Object] = true
+		}
+		contents[name] = set
+	}
+
+	var found []duplicate
+	for i, name := range projects {
+		set := contents[name]
+		if len(set) == 0 {
+			continue
+		}
+		best := duplicate{project: name}
+		for j, other := range projects {
+			otherSet := contents[other]
+			// Keep the larger project, or the earlier of two of a size
+			if i == j || len(otherSet) < len(set) || (len(otherSet) == len(set) && j > i) {
+				continue
+			}
+			shared := 0
+			for object := range set {
+				if otherSet[object] {
+					shared++
+				}
+			}
+			if share := float64(shared) / float64(len(set)); share > best.share {
+				best.of, best.share = other, share
+			}
+		}
+		if best.share >= minDuplicateShare {
+			found = append(found, best)
+		}
+	}
+	return found
+}
@@ -0,0 +1,129 @@
+package advise
+
+import (
+	"crypto/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/age"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/metadata"
+)
+
+func TestRun(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	meta := func(history bool, review time.Time) string {
+		m := &metadata.Metadata{OriginalSource: "/src/project", BuriedAt: now.AddDate(-1, 0, 0), HistoryPreserved: history, ReviewAfter: review}
+		return m.Generate()
+	}
+	random := make([]byte, 2<<20)
+	if _, err := rand.Read(random); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	runGit(t, dir, nil, "init", "-q")
+	commit := func(date string, files map[string]string) {
+		t.Helper()
+		for name, content := range files {
+			writeFile(t, filepath.Join(dir, name), content)
+		}
+		runGit(t, dir, nil, "add", "-A")
+		runGit(t, dir, []string{"GIT_AUTHOR_DATE=" + date, "GIT_COMMITTER_DATE=" + date}, "commit", "-qm", "bury")
+	}
+	commit("2020-01-01T00:00:00Z", map[string]string{
+		"idle/" + metadata.FileName: meta(false, time.Time{}),
+		"idle/main.go":              "package main\n",
+	})
+	commit("2026-01-01T00:00:00Z", map[string]string{
+		"overdue/" + metadata.FileName:  meta(false, now.AddDate(0, -1, 0)),
+		"overdue/main.go":               "package overdue\n",
+		"original/" + metadata.FileName: meta(false, time.Time{}),
+		"original/a.go":                 "package a\n",
+		"original/b.go":                 "package b\n",
+		"original/c.go":                 "package c\n",
+		"copy/" + metadata.FileName:     meta(false, time.Time{}),
+		"copy/a.go":                     "package a\n",
+		"copy/b.go":                     "package b\n",
+		"history/" + metadata.FileName:  meta(true, time.Time{}),
+		"history/data.bin":              string(random),
+	})
+	if err := os.Remove(filepath.Join(dir, "history", "data.bin")); err != nil {
+		t.Fatal(err)
+	}
+	commit("2026-01-02T00:00:00Z", map[string]string{"history/README": "gone\n"})
+
+	suggestions, err := Run(&graveyard.Graveyard{Path: dir}, Options{MaxHistory: 1 << 20, Idle: age.Span{Years: 2}, Now: now})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	got := make(map[string]Suggestion)
+	for i, s := range suggestions {
+		got[s.Action+" "+s.Project] = s
+		if i > 0 && s.Savings > suggestions[i-1].Savings {
+			t.Errorf("Run() suggestion %d saves more than the one before it: %+v", i, suggestions)
+		}
+	}
+	if len(got) != len(suggestions) || len(got) != 5 {
+		t.Fatalf("Run() = %+v, want pack, compact history, purge overdue, cold-tier idle, and purge copy", suggestions)
+	}
+	for key, want := range map[string]string{
+		"pack ":           "gc --prune=now",
+		"compact history": "bury-it compact history -g ",
+		"purge overdue":   "rm -r -q overdue",
+		"cold-tier idle":  "tar -czf idle.tar.gz -C ",
+		"purge copy":      "rm -r -q copy",
+	} {
+		s, ok := got[key]
+		if !ok {
+			t.Errorf("Run() missing %q: %+v", key, suggestions)
+			continue
+		}
+		if !strings.Contains(s.Command, want) || s.Reason == "" {
+			t.Errorf("Run() %q = %+v, want a command containing %q", key, s, want)
+		}
+	}
+	if s := got["compact history"]; s.Savings < 2<<20 {
+		t.Errorf("compact history saves %d, want at least the deleted file", s.Savings)
+	}
+	if s := got["purge copy"]; s.Reason != "100% of its files are also in original" {
+		t.Errorf("purge copy reason = %q", s.Reason)
+	}
+
+	// A project still being read is not dead weight
+	accesses := map[string]graveyard.Access{"idle": {Last: now.AddDate(0, -1, 0), Count: 1}}
+	suggestions, err = Run(&graveyard.Graveyard{Path: dir}, Options{Idle: age.Span{Years: 2}, Accesses: accesses, Now: now})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	for _, s := range suggestions {
+		if s.Action == ActionColdTier {
+			t.Errorf("Run() with a recent read suggested %+v", s)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func runGit(t *testing.T, dir string, env []string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.email=t@t", "-c", "user.name=T"}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
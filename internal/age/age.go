@@ -0,0 +1,97 @@
+// Package age parses calendar spans such as "18mo" or "2y".
+package age
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Span is a calendar span of years, months, and days.
+type Span struct {
+	// Years is the number of years.
+	Years int
+	// Months is the number of months.
+	Months int
+	// Days is the number of days.
+	Days int
+}
+
+// partPattern matches a single number and unit, such as "18mo".
+var partPattern = regexp.MustCompile(`^(\d+)(y|mo|w|d)`)
+
+// Parse parses a span made of one or more number and unit pairs, where the
+// unit is y (years), mo (months), w (weeks), or d (days), e.g. "1y6mo".
+func Parse(s string) (Span, error) {
+	input := strings.ToLower(strings.TrimSpace(s))
+	if input == "" {
+		return Span{}, fmt.Errorf("span cannot be empty")
+	}
+
+	var span Span
+	for input != "" {
+		m := partPattern.FindStringSubmatch(input)
+		if m == nil {
+			return Span{}, fmt.Errorf("invalid span %q (use e.g. 30d, 6w, 18mo, 2y)", s)
+		}
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return Span{}, fmt.Errorf("invalid span %q: %w", s, err)
+		}
+		switch m[2] {
+		case "y":
+			span.Years += n
+		case "mo":
+			span.Months += n
+		case "w":
+			span.Days += 7 * n
+		case "d":
+			span.Days += n
+		}
+		input = input[len(m[0]):]
+	}
+	return span, nil
+}
+
+// Before returns the time span before t.
+func (s Span) Before(t time.Time) time.Time {
+	return t.AddDate(-s.Years, -s.Months, -s.Days)
+}
+
+// After returns the time span after t.
+func (s Span) After(t time.Time) time.Time {
+	return t.AddDate(s.Years, s.Months, s.Days)
+}
+
+// String returns the span in the format accepted by Parse.
+func (s Span) String() string {
+	var b strings.Builder
+	if s.Years > 0 {
+		fmt.Fprintf(&b, "%dy", s.Years)
+	}
+	if s.Months > 0 {
+		fmt.Fprintf(&b, "%dmo", s.Months)
+	}
+	if s.Days > 0 || b.Len() == 0 {
+		fmt.Fprintf(&b, "%dd", s.Days)
+	}
+	return b.String()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler so spans can be read
+// directly from configuration files.
+func (s *Span) UnmarshalText(text []byte) error {
+	span, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*s = span
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Span) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
@@ -0,0 +1,86 @@
+package age
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Span
+		wantErr bool
+	}{
+		{name: "years", input: "2y", want: Span{Years: 2}},
+		{name: "months", input: "18mo", want: Span{Months: 18}},
+		{name: "weeks", input: "6w", want: Span{Days: 42}},
+		{name: "days", input: "30d", want: Span{Days: 30}},
+		{name: "combined", input: "1y6mo", want: Span{Years: 1, Months: 6}},
+		{name: "uppercase with spaces", input: " 2Y ", want: Span{Years: 2}},
+		{name: "empty", input: "", wantErr: true},
+		{name: "ambiguous minutes or months", input: "18m", wantErr: true},
+		{name: "missing unit", input: "18", wantErr: true},
+		{name: "trailing garbage", input: "2yx", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpan_BeforeAfterString(t *testing.T) {
+	base := time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		span       Span
+		wantBefore time.Time
+		wantAfter  time.Time
+		wantString string
+	}{
+		{
+			name:       "eighteen months",
+			span:       Span{Months: 18},
+			wantBefore: time.Date(2023, 12, 15, 0, 0, 0, 0, time.UTC),
+			wantAfter:  time.Date(2026, 12, 15, 0, 0, 0, 0, time.UTC),
+			wantString: "18mo",
+		},
+		{
+			name:       "mixed",
+			span:       Span{Years: 1, Days: 10},
+			wantBefore: time.Date(2024, 6, 5, 0, 0, 0, 0, time.UTC),
+			wantAfter:  time.Date(2026, 6, 25, 0, 0, 0, 0, time.UTC),
+			wantString: "1y10d",
+		},
+		{
+			name:       "zero",
+			span:       Span{},
+			wantBefore: base,
+			wantAfter:  base,
+			wantString: "0d",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.span.Before(base); !got.Equal(tt.wantBefore) {
+				t.Errorf("Before() = %v, want %v", got, tt.wantBefore)
+			}
+			if got := tt.span.After(base); !got.Equal(tt.wantAfter) {
+				t.Errorf("After() = %v, want %v", got, tt.wantAfter)
+			}
+			if got := tt.span.String(); got != tt.wantString {
+				t.Errorf("String() = %q, want %q", got, tt.wantString)
+			}
+		})
+	}
+}
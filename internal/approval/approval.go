@@ -0,0 +1,113 @@
+// Package approval holds destructive actions until a second person approves
+// them.
+//
+// The check is a courtesy that keeps people from acting alone by mistake,
+// not an enforcement boundary: requests are kept in the state directory,
+// which whoever made them can write to, so someone set on it can approve
+// their own request by editing it. Actions that must not be taken alone
+// need access controls outside bury-it, such as protected branches on the
+// graveyard's remote.
+package approval
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/state"
+)
+
+// requestsFile is the state file holding pending requests. Pointing
+// BURY_IT_HOME at a shared directory lets a team approve each other's
+// requests.
+const requestsFile = "approvals.json"
+
+// Request is a destructive action waiting for approval.
+type Request struct {
+	// ID identifies the request for bury-it approve.
+	ID string `json:"id"`
+	// Action names the action, such as "undo".
+	Action string `json:"action"`
+	// Graveyard is the path of the graveyard the action applies to.
+	Graveyard string `json:"graveyard"`
+	// Args are action-specific arguments.
+	Args map[string]string `json:"args,omitempty"`
+	// Summary describes the action for the approver.
+	Summary string `json:"summary"`
+	// RequestedBy is the account of the person who asked for the action.
+	RequestedBy string `json:"requested_by"`
+	// RequestedAt is when the action was requested.
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// User returns the operating system account running bury-it, which requests
+// and approvals are made by. Unlike a git identity, which anyone can set to
+// anything, it takes a second account to approve a request as someone else.
+func User() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine the current user: %w", err)
+	}
+	return u.Username, nil
+}
+
+// Pending returns the requests waiting for approval, oldest first.
+func Pending() ([]*Request, error) {
+	var requests []*Request
+	if err := state.Load(requestsFile, &requests); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// Submit records a new request and returns it.
+func Submit(action, graveyardPath, summary, requestedBy string, args map[string]string) (*Request, error) {
+	requests, err := Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate request id: %w", err)
+	}
+	req := &Request{
+		ID:          hex.EncodeToString(id),
+		Action:      action,
+		Graveyard:   graveyardPath,
+		Args:        args,
+		Summary:     summary,
+		RequestedBy: requestedBy,
+		RequestedAt: time.Now(),
+	}
+	if err := state.Save(requestsFile, append(requests, req)); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Take removes a request so that approvedBy can carry it out. Requesters
+// cannot approve their own requests, as far as the recorded requester can be
+// trusted; see the package documentation.
+func Take(id, approvedBy string) (*Request, error) {
+	requests, err := Pending()
+	if err != nil {
+		return nil, err
+	}
+	for i, req := range requests {
+		if req.ID != id {
+			continue
+		}
+		if strings.EqualFold(req.RequestedBy, approvedBy) {
+			return nil, fmt.Errorf("request %s was made by %s and must be approved by someone else", id, req.RequestedBy)
+		}
+		if err := state.Save(requestsFile, append(requests[:i], requests[i+1:]...)); err != nil {
+			return nil, err
+		}
+		return req, nil
+	}
+	return nil, fmt.Errorf("no pending request with id %s", id)
+}
@@ -0,0 +1,72 @@
+package approval
+
+import (
+	"os/user"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSubmitAndTake(t *testing.T) {
+	t.Setenv("BURY_IT_HOME", filepath.Join(t.TempDir(), "state"))
+
+	req, err := Submit("undo", "/graveyard", "undo burial of old-app", "alice@example.com", map[string]string{"commit": "abc123"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if len(req.ID) != 8 {
+		t.Errorf("Submit() id = %q, want 8 hex characters", req.ID)
+	}
+
+	pending, err := Pending()
+	if err != nil {
+		t.Fatalf("Pending() error = %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != req.ID || pending[0].Args["commit"] != "abc123" {
+		t.Fatalf("Pending() = %+v, want the submitted request", pending)
+	}
+
+	tests := []struct {
+		name       string
+		id         string
+		approvedBy string
+		wantErr    string
+	}{
+		{name: "requester cannot approve", id: req.ID, approvedBy: "Alice@Example.com", wantErr: "someone else"},
+		{name: "unknown request", id: "ffffffff", approvedBy: "bob@example.com", wantErr: "no pending request"},
+		{name: "second person approves", id: req.ID, approvedBy: "bob@example.com"},
+		{name: "request is gone once approved", id: req.ID, approvedBy: "bob@example.com", wantErr: "no pending request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Take(tt.id, tt.approvedBy)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Take() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Take() error = %v", err)
+			}
+			if got.Action != "undo" || got.Graveyard != "/graveyard" {
+				t.Errorf("Take() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestUser(t *testing.T) {
+	// The account cannot be changed through the environment like git's
+	// identity can
+	t.Setenv("GIT_AUTHOR_EMAIL", "someone-else@example.com")
+	t.Setenv("USER", "someone-else")
+	want, err := user.Current()
+	if err != nil {
+		t.Skip(err)
+	}
+	if got, err := User(); err != nil || got != want.Username {
+		t.Errorf("User() = %q, %v, want %q", got, err, want.Username)
+	}
+}
@@ -2,29 +2,161 @@
 package archive
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"time"
 
+	"github.com/deanhigh/bury-it/internal/age"
+	"github.com/deanhigh/bury-it/internal/artifacts"
+	"github.com/deanhigh/bury-it/internal/atomicfile"
+	"github.com/deanhigh/bury-it/internal/audit"
+	"github.com/deanhigh/bury-it/internal/capability"
+	"github.com/deanhigh/bury-it/internal/checklist"
+	"github.com/deanhigh/bury-it/internal/display"
+	"github.com/deanhigh/bury-it/internal/endpoints"
 	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/gitea"
+	"github.com/deanhigh/bury-it/internal/github"
 	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/history"
+	"github.com/deanhigh/bury-it/internal/index"
+	"github.com/deanhigh/bury-it/internal/lfs"
 	"github.com/deanhigh/bury-it/internal/metadata"
+	"github.com/deanhigh/bury-it/internal/progress"
+	"github.com/deanhigh/bury-it/internal/registry"
+	"github.com/deanhigh/bury-it/internal/snapshot"
 	"github.com/deanhigh/bury-it/internal/source"
 )
 
 // Options contains the options for the archive operation.
 type Options struct {
-	// Source is the source repository string (URL, owner/repo, or path).
-	Source string
+	// Source is the source repository string (URL, owner/repo, or path),
+	// a tar or zip archive, as a path or URL, or a Mercurial or Subversion
+	// repository, whose history is converted.
+	Source string `json:"source"`
+	// SourceType is SourceTypePlain to bury Source as a plain directory that
+	// was never under version control, or "" to tell its type from Source.
+	SourceType string `json:"source_type,omitempty"`
+	// IgnoreFile is a file of gitignore patterns matching the files of a
+	// plain directory, or the untracked files of IncludeUntracked, to leave
+	// out.
+	IgnoreFile string `json:"ignore_file,omitempty"`
 	// Graveyard is the path to the graveyard repository.
-	Graveyard string
+	Graveyard string `json:"graveyard"`
 	// Name is an optional override for the project name in the graveyard.
-	Name string
+	Name string `json:"name,omitempty"`
 	// DropHistory indicates whether to drop git history.
-	DropHistory bool
+	DropHistory bool `json:"drop_history,omitempty"`
+	// CaptureUncommitted saves uncommitted changes and stashes of a local
+	// source as a patch alongside the metadata.
+	CaptureUncommitted bool `json:"capture_uncommitted,omitempty"`
+	// IncludeUntracked buries the untracked files of a local source's
+	// working tree with the project, except those its .gitignore files or
+	// IgnoreFile match.
+	IncludeUntracked bool `json:"include_untracked,omitempty"`
+	// ActivitySparkline writes an SVG sparkline of commit activity for
+	// drop-history burials.
+	ActivitySparkline bool `json:"activity_sparkline,omitempty"`
+	// LinkOriginalIssues records issue and pull request counts and links to
+	// open ones from the source's GitHub repository.
+	LinkOriginalIssues bool `json:"link_original_issues,omitempty"`
+	// WithIssues exports every issue and pull request of the source's GitHub
+	// repository into the project after the burial. Large exports resume
+	// from a checkpoint with ExportIssues if interrupted.
+	WithIssues bool `json:"with_issues,omitempty"`
+	// CIHistory records a summary of the source's GitHub Actions workflow
+	// runs in the metadata.
+	CIHistory bool `json:"ci_history,omitempty"`
+	// TombstoneIssue opens or updates a pinned issue on the source's GitHub
+	// repository pointing at the graveyard.
+	TombstoneIssue bool `json:"tombstone_issue,omitempty"`
+	// Registry is the path to a local clone of a burial registry repository.
+	// When set, an entry for the burial is appended to its ledger.
+	Registry string `json:"registry,omitempty"`
+	// RegistryFile is the ledger file within the registry; its extension
+	// selects the format. Defaults to registry.DefaultFile.
+	RegistryFile string `json:"registry_file,omitempty"`
+	// RegistryPR proposes the registry entry as a GitHub pull request instead
+	// of committing it directly.
+	RegistryPR bool `json:"registry_pr,omitempty"`
+	// ReviewAfter, if set, is how long after the burial the project should be
+	// reviewed for purging or resurrection.
+	ReviewAfter *age.Span `json:"review_after,omitempty"`
+	// Owner, if set, is the team or person responsible for the project.
+	Owner string `json:"owner,omitempty"`
+	// MonthlyCostBefore and MonthlyCostAfter, if set, record what the
+	// project cost to run each month before and after it was sunset.
+	MonthlyCostBefore *float64 `json:"monthly_cost_before,omitempty"`
+	MonthlyCostAfter  *float64 `json:"monthly_cost_after,omitempty"`
+	// ExpectCommit, if set, is the commit the source's HEAD must be at. The
+	// burial is refused if the source has moved on.
+	ExpectCommit string `json:"expect_commit,omitempty"`
+	// NewVersion buries the source again under the name of a project already
+	// in the graveyard. The previous version is kept under a version tag.
+	NewVersion bool `json:"new_version,omitempty"`
+	// Forges are the kinds of forge ("github", "gitea", or "forgejo") run by
+	// hosts beyond github.com and gitea.DefaultHosts, keyed by host. The
+	// GitHub options work with sources on GitHub Enterprise Server hosts,
+	// and the description and topics of a source on a Gitea or Forgejo host
+	// are recorded in the metadata.
+	Forges map[string]string `json:"forges,omitempty"`
+	// CopyEngine names the snapshot.Engine that copies the tracked files of
+	// a DropHistory burial, or "" for snapshot.DefaultEngine.
+	CopyEngine string `json:"copy_engine,omitempty"`
+	// Branch is the branch to bury instead of the source's checked-out or
+	// default branch. Local sources are cloned to check it out, leaving
+	// their working tree alone.
+	Branch string `json:"branch,omitempty"`
+	// Ref is a tag, commit, or other ref to bury the state of instead of
+	// the head of a branch, such as the last release. Local sources are
+	// cloned to check it out unless it is already checked out.
+	Ref string `json:"ref,omitempty"`
+	// Release is the tag of a GitHub release to bury the published state
+	// of, without history. Its name and notes are recorded with the project.
+	Release string `json:"release,omitempty"`
+	// Unshallow fetches the full history of a local source that is a
+	// shallow clone before burying it, instead of only warning that its
+	// history is cut short.
+	Unshallow bool `json:"unshallow,omitempty"`
+	// AllowEmpty buries a local source repository that has no commits yet
+	// as a snapshot of the files in its working tree, as a plain directory
+	// is, instead of failing.
+	AllowEmpty bool `json:"allow_empty,omitempty"`
+	// RecurseSubmodules clones the source's submodules, recursively, and
+	// copies their content into the project instead of leaving their
+	// directories empty. Local sources are cloned to fetch them, leaving
+	// their working tree alone.
+	RecurseSubmodules bool `json:"recurse_submodules,omitempty"`
+	// SizeLimit is the number of bytes of history above which a burial
+	// with history is downgraded to one without, or warned about; zero
+	// disables the check.
+	SizeLimit int64 `json:"size_limit,omitempty"`
+	// AutoDowngrade buries without history whenever the history is over
+	// SizeLimit, without asking.
+	AutoDowngrade bool `json:"auto_downgrade,omitempty"`
+	// ConfirmDowngrade, if set, is asked whether to bury without history
+	// when the history of historyBytes is over the limit and AutoDowngrade
+	// is unset, such as at a prompt.
+	ConfirmDowngrade func(historyBytes, limit int64) bool `json:"-"`
+	// Offline forbids any network access. Burials that would need it, such
+	// as of remote sources or with GitHub enrichment, are refused up front,
+	// and optional lookups, such as of Gitea descriptions, are skipped.
+	Offline bool `json:"offline,omitempty"`
+	// Approved records that a second person approved the burial, which
+	// replacing a project with NewVersion needs if the administrator
+	// requires approval for it. It is set by bury-it approve only, and is
+	// never read from a plan.
+	Approved bool `json:"-"`
 }
 
+// ErrApprovalRequired is returned for a burial replacing a project with
+// NewVersion when the administrator requires approval for it and the burial
+// was not approved.
+var ErrApprovalRequired = errors.New("replacing a project requires approval by a second person")
+
 // Result contains the result of the archive operation.
 type Result struct {
 	// ProjectName is the name of the archived project.
@@ -33,14 +165,55 @@ type Result struct {
 	ProjectPath string
 	// HistoryPreserved indicates whether git history was preserved.
 	HistoryPreserved bool
+	// Version is the number of the burial when the project was buried again
+	// with NewVersion, or zero.
+	Version int
+	// TombstoneURL is the URL of the tombstone issue, if one was opened.
+	TombstoneURL string
+	// RegistryURL is the URL of the registry pull request, if one was opened.
+	RegistryURL string
+	// IssuesExported is the number of issues and pull requests exported with
+	// WithIssues.
+	IssuesExported int
+	// IssueExportComplete reports whether the issue export finished.
+	IssueExportComplete bool
 }
 
 // Archive archives a source repository into a graveyard.
 func Archive(opts Options) (*Result, error) {
 	// Parse source
-	src, err := source.Parse(opts.Source)
+	src, err := parseSource(opts)
 	if err != nil {
-		return nil, fmt.Errorf("invalid source: %w", err)
+		return nil, err
+	}
+	if err := checkOffline(opts, src); err != nil {
+		return nil, err
+	}
+	if _, err := snapshot.Get(opts.CopyEngine); err != nil {
+		return nil, err
+	}
+	if err := applyRelease(&opts); err != nil {
+		return nil, err
+	}
+	if opts.Branch != "" && opts.Ref != "" {
+		return nil, errBranchAndRef
+	}
+	if src.Type == source.TypeArchive && opts.Branch != "" {
+		return nil, errArchiveBranch
+	}
+	if src.Type == source.TypePlain && (opts.Branch != "" || opts.Ref != "") {
+		return nil, errPlainRevision
+	}
+	if err := checkIncludeUntracked(opts, src); err != nil {
+		return nil, err
+	}
+	if opts.NewVersion {
+		if err := capability.Check(capability.Replace); err != nil {
+			return nil, err
+		}
+		if capability.RequiresApproval(capability.Replace) && !opts.Approved {
+			return nil, ErrApprovalRequired
+		}
 	}
 
 	// Parse graveyard
@@ -53,97 +226,1110 @@ func Archive(opts Options) (*Result, error) {
 	if err := gy.Validate(); err != nil {
 		return nil, err
 	}
+	if err := gy.CheckNoOperation(); err != nil {
+		return nil, err
+	}
 
-	// Determine project name
+	// Determine project name, naming a gist after its description
 	projectName := src.Name
+	gist := lookupGist(src)
+	if gist != nil && gistName(gist.Description) != "" {
+		projectName = gistName(gist.Description)
+	}
 	if opts.Name != "" {
 		projectName = opts.Name
 	}
 
 	// Validate project name
-	if err := gy.ValidateProjectName(projectName); err != nil {
+	validateName := gy.ValidateProjectName
+	if opts.NewVersion {
+		validateName = gy.ValidateNewVersion
+	}
+	if err := validateName(projectName); err != nil {
+		return nil, err
+	}
+
+	// Unpack archives, convert Mercurial and Subversion repositories, and
+	// clone remote repositories, bare ones, and bundles, and local ones to
+	// bury a branch other than the one checked out or their submodules
+	// without touching their working tree
+	if err := src.Validate(); err != nil {
+		return nil, err
+	}
+	if err := checkShallow(opts, src, true); err != nil {
+		return nil, err
+	}
+	if src, err = checkEmpty(opts, src); err != nil {
 		return nil, err
 	}
+	localSourcePath := src.Path
+	snapshotOnly := false
+	var tempDirs []string
+	if src.Type == source.TypeArchive {
+		tempDir, err := os.MkdirTemp("", "bury-it-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(tempDir) }()
+		tempDirs = append(tempDirs, tempDir)
 
-	// Handle remote repositories
-	var localSourcePath string
-	var tempDir string
-	if src.Type == source.TypeRemote {
-		// Clone to temp directory
-		tempDir, err = os.MkdirTemp("", "bury-it-*")
+		localSourcePath, snapshotOnly, err = unpackSource(src, tempDir)
+		if err != nil {
+			return nil, err
+		}
+		if snapshotOnly && opts.Ref != "" {
+			return nil, errArchiveRef
+		}
+		// A commit made of the archive's files is not history worth keeping
+		opts.DropHistory = opts.DropHistory || snapshotOnly
+	} else if src.Type == source.TypePlain {
+		tempDir, err := os.MkdirTemp("", "bury-it-*")
 		if err != nil {
 			return nil, fmt.Errorf("failed to create temp directory: %w", err)
 		}
 		defer func() { _ = os.RemoveAll(tempDir) }()
+		tempDirs = append(tempDirs, tempDir)
+
+		localSourcePath, err = snapshotPlain(src, tempDir, opts.IgnoreFile)
+		if err != nil {
+			return nil, err
+		}
+		snapshotOnly = true
+		opts.DropHistory = true
+	} else if src.ConvertedFrom() != "" {
+		tempDir, err := os.MkdirTemp("", "bury-it-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(tempDir) }()
+		tempDirs = append(tempDirs, tempDir)
+
+		localSourcePath, err = convertSource(src, tempDir, projectName, opts.Branch)
+		if err != nil {
+			return nil, err
+		}
+	} else if needsClone(src, opts) || opts.RecurseSubmodules {
+		tempDir, err := os.MkdirTemp("", "bury-it-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(tempDir) }()
+		tempDirs = append(tempDirs, tempDir)
 
 		clonePath := filepath.Join(tempDir, projectName)
-		fmt.Printf("Cloning %s...\n", src.Path)
-		if err := git.Clone(src.Path, clonePath); err != nil {
+		if opts.Branch != "" {
+			progress.Phase("clone", "Cloning branch %s of %s...", opts.Branch, display.Path(src.Path))
+		} else {
+			progress.Phase("clone", "Cloning %s...", display.Path(src.Path))
+		}
+		if err := git.CloneBranch(src.Path, clonePath, opts.Branch, src.CloneEnv()...); err != nil {
 			return nil, fmt.Errorf("failed to clone repository: %w", err)
 		}
 		localSourcePath = clonePath
-	} else {
-		// Validate local source
-		if err := src.Validate(); err != nil {
+	}
+	refCommit, err := checkoutRef(src, localSourcePath, opts.Ref)
+	if err != nil {
+		return nil, err
+	}
+	if opts.RecurseSubmodules {
+		// The source's credentials are for its own host, so they are not
+		// passed on. Submodules on the local filesystem are only cloned for
+		// a local source, where they are the user's own.
+		var env []string
+		if src.Type == source.TypeLocal {
+			env = git.ConfigEnv("protocol.file.allow", "always")
+		}
+		progress.Phase("submodules", "Cloning submodules...")
+		if err := git.UpdateSubmodules(localSourcePath, env...); err != nil {
+			return nil, fmt.Errorf("failed to clone submodules: %w", err)
+		}
+	}
+
+	// Refuse to bury a different snapshot than the one that was planned
+	if opts.ExpectCommit != "" {
+		head, err := git.Head(localSourcePath)
+		if err != nil {
 			return nil, err
 		}
-		localSourcePath = src.Path
+		if head != opts.ExpectCommit {
+			return nil, fmt.Errorf("source is at %s but %s was expected; the source changed since it was planned", head, opts.ExpectCommit)
+		}
+	}
+
+	// Offer to leave out a history too large to bury whole
+	if !opts.DropHistory && opts.SizeLimit > 0 {
+		historyBytes, _, err := historySize(localSourcePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure history: %w", err)
+		}
+		opts.DropHistory = downgradeHistory(opts, historyBytes)
+	}
+
+	// Inventory work that exists outside the committed snapshot, which a
+	// bare repository or bundle has no working tree for
+	var uncommitted *metadata.Uncommitted
+	if src.Type == source.TypeLocal && !src.IsBare() && !src.IsBundle() {
+		uncommitted, err = inventoryUncommitted(src.Path)
+		if err != nil {
+			return nil, err
+		}
+		if opts.IncludeUntracked {
+			if err := includeUntracked(uncommitted, src.Path, opts.IgnoreFile); err != nil {
+				return nil, err
+			}
+		}
+		if uncommitted.LeftOut() {
+			progress.Warn("source has uncommitted work that will not be archived (see %s)", metadata.FileName)
+		}
 	}
 
 	// Get display path for metadata before any operations
 	displayPath := src.DisplayPath()
 
+	// Check GitHub requirements before changing anything
+	owner, repo, client, isGitHub := githubRepo(displayPath, opts.Forges)
+	if opts.LinkOriginalIssues && !isGitHub {
+		return nil, fmt.Errorf("--link-original-issues requires a GitHub source, got %s", displayPath)
+	}
+	if opts.Release != "" && !isGitHub {
+		return nil, fmt.Errorf("--release requires a GitHub source, got %s", displayPath)
+	}
+	if opts.CIHistory && !isGitHub {
+		return nil, fmt.Errorf("--ci-history requires a GitHub source, got %s", displayPath)
+	}
+	if opts.WithIssues && !isGitHub {
+		return nil, fmt.Errorf("--with-issues requires a GitHub source, got %s", displayPath)
+	}
+	if opts.TombstoneIssue {
+		if err := capability.Check(capability.GitHubWrite); err != nil {
+			return nil, fmt.Errorf("--tombstone-issue cannot be used: %w", err)
+		}
+		if !isGitHub {
+			return nil, fmt.Errorf("--tombstone-issue requires a GitHub source, got %s", displayPath)
+		}
+		if client.Token == "" {
+			return nil, fmt.Errorf("--tombstone-issue requires %s to be set", tokenEnv(client))
+		}
+	}
+
+	var reg *registry.Registry
+	registryClient := github.NewClient(github.TokenFromEnv())
+	if opts.Registry != "" {
+		reg, err = registry.New(opts.Registry, opts.RegistryFile)
+		if err != nil {
+			return nil, err
+		}
+		if opts.RegistryPR {
+			for _, name := range []string{capability.Push, capability.GitHubWrite} {
+				if err := capability.Check(name); err != nil {
+					return nil, fmt.Errorf("--registry-pr cannot be used: %w", err)
+				}
+			}
+			if _, _, ok := reg.GitHubRepo(); !ok {
+				return nil, fmt.Errorf("--registry-pr requires the registry's origin to be on GitHub")
+			}
+			if registryClient.Token == "" {
+				return nil, fmt.Errorf("--registry-pr requires GITHUB_TOKEN to be set")
+			}
+		}
+	} else if opts.RegistryPR {
+		return nil, fmt.Errorf("--registry-pr requires --registry")
+	}
+
+	// Cross-reference unfinished business on the original host
+	var issues *metadata.Issues
+	if opts.LinkOriginalIssues {
+		progress.Phase("issues", "Recording issues and pull requests of %s/%s...", owner, repo)
+		issues, err = fetchIssues(client, owner, repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch issues: %w", err)
+		}
+	}
+	var release *metadata.Release
+	if opts.Release != "" {
+		progress.Phase("release", "Recording release %s of %s/%s...", opts.Release, owner, repo)
+		release, err = fetchRelease(client, owner, repo, opts.Release)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch release %s: %w", opts.Release, err)
+		}
+	}
+	var workflows []metadata.Workflow
+	if opts.CIHistory {
+		progress.Phase("ci-history", "Recording CI workflow runs of %s/%s...", owner, repo)
+		workflows, err = fetchWorkflows(client, owner, repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch CI workflows: %w", err)
+		}
+	}
+
+	// Describe the project as its Gitea or Forgejo host did
+	var hosted *gitea.Repository
+	if host, owner, repo, ok := src.HostedRepo(); ok && !opts.Offline && gitea.Kind(host, opts.Forges) != "" {
+		progress.Phase("description", "Recording description and topics of %s/%s from %s...", owner, repo, host)
+		hosted, err = gitea.NewClient(host, gitea.TokenFromEnv()).Repo(owner, repo)
+		if err != nil {
+			progress.Warn("failed to fetch repository details: %v", err)
+		}
+	}
+
+	// Record the branches, tags, and activity of the history about to be
+	// discarded, from the source itself if it is local, unless the only
+	// history is the snapshot made of an archive
+	var refs []metadata.Ref
+	var summary *history.Summary
+	if opts.DropHistory && !snapshotOnly {
+		refsPath := localSourcePath
+		if src.Type == source.TypeLocal && !src.IsBundle() {
+			refsPath = src.Path
+		}
+		refs, err = inventoryRefs(refsPath)
+		if err != nil {
+			return nil, err
+		}
+		commits, err := git.Log(refsPath, []string{"--all"})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read history: %w", err)
+		}
+		summary = history.Summarize(commits)
+	}
+
+	// Record where published images and packages may still live
+	artifacts, err := inventoryArtifacts(localSourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// List the infrastructure that may outlive the project
+	decommission, err := inventoryEndpoints(localSourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the submodules, whose content is only buried if requested
+	submodules, err := inventorySubmodules(localSourcePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(submodules) > 0 && !opts.RecurseSubmodules {
+		progress.Warn("source has submodules whose content will not be archived; use --recurse-submodules to include it (see %s)", metadata.FileName)
+	}
+
+	// Fetch the content of files tracked with Git LFS, which git itself only
+	// holds pointers to
+	lfsFiles, err := git.LFSFiles(localSourcePath)
+	if err != nil {
+		return nil, err
+	}
+	var pointers map[string]lfs.Pointer
+	var lfsStore string
+	if len(lfsFiles) > 0 {
+		switch {
+		case !git.HasLFS():
+			progress.Warn("source uses Git LFS but git-lfs is not installed; only LFS objects already fetched will be archived")
+		case opts.Offline:
+			progress.Warn("source uses Git LFS; with --offline, only LFS objects already fetched will be archived")
+		default:
+			progress.Phase("lfs-fetch", "Fetching Git LFS objects...")
+			if err := git.LFSFetchAll(localSourcePath, src.CloneEnv()...); err != nil {
+				progress.Warn("%v; only LFS objects already fetched will be archived", err)
+			}
+		}
+		pointers, err = lfs.ReadPointers(localSourcePath, "HEAD", lfsFiles)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Git LFS pointers: %w", err)
+		}
+		lfsStore, err = lfs.Store(localSourcePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var lfsInfo *metadata.LFS
+	if len(pointers) > 0 {
+		lfsInfo = &metadata.LFS{}
+		for _, file := range lfsFiles {
+			if _, ok := pointers[file]; ok {
+				lfsInfo.Files = append(lfsInfo.Files, file)
+			}
+		}
+	}
+
+	// From here on the graveyard is changed, so record the burial until it
+	// is committed, for bury-it recover to finish or roll back if it is
+	// interrupted
+	op, err := gy.BeginBurial(projectName, tempDirs)
+	if err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			progress.Warn("the burial of %s was left incomplete; run bury-it recover -g %s to roll it back", projectName, display.Path(gy.Path))
+		}
+	}()
+
+	// Make way for a new version, keeping the current one under a tag
+	version := 0
+	if opts.NewVersion {
+		progress.Phase("retire", "Retiring the current version of %s...", projectName)
+		retired, err := gy.Retire(projectName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retire previous version: %w", err)
+		}
+		version = retired + 1
+	}
+
 	// Archive the project
 	projectPath := gy.ProjectPath(projectName)
 	historyPreserved := !opts.DropHistory
 
 	if opts.DropHistory {
-		// Copy only tracked files (respects .gitignore)
-		fmt.Printf("Copying tracked files (without history) to %s...\n", projectName)
-		if err := git.CopyTrackedFiles(localSourcePath, projectPath); err != nil {
+		// Copy only tracked files (respects .gitignore), cloning them where
+		// the filesystem allows unless another engine was chosen
+		engineName := opts.CopyEngine
+		if engineName == "" || engineName == snapshot.AutoEngine {
+			engineName = snapshot.Choose(localSourcePath, projectPath)
+		}
+		engine, err := snapshot.Get(engineName)
+		if err != nil {
+			return nil, err
+		}
+		if engineName == "reflink" {
+			progress.Phase("copy", "Cloning tracked files (without history, copy-on-write) to %s...", projectName)
+		} else {
+			progress.Phase("copy", "Copying tracked files (without history) to %s...", projectName)
+		}
+		if err := engine.Copy(localSourcePath, projectPath); err != nil {
 			return nil, fmt.Errorf("failed to copy files: %w", err)
 		}
+		if len(pointers) > 0 {
+			progress.Phase("lfs-materialize", "Replacing Git LFS pointer files with their content...")
+			lfsInfo.Missing, err = lfs.Materialize(lfsStore, projectPath, pointers)
+			if err != nil {
+				return nil, err
+			}
+			lfsInfo.Materialized = true
+		}
 	} else {
+		// Bring the LFS objects of the whole history along first, so that
+		// they are there to check out
+		if len(pointers) > 0 {
+			gyStore, err := lfs.Store(gy.Path)
+			if err != nil {
+				return nil, err
+			}
+			progress.Phase("lfs-objects", "Copying Git LFS objects into the graveyard...")
+			lfsInfo.Objects, err = lfs.CopyObjects(lfsStore, gyStore)
+			if err != nil {
+				return nil, err
+			}
+			lfsInfo.Missing = lfs.Missing(lfsStore, pointers)
+		}
+
 		// Use subtree to preserve history
-		fmt.Printf("Adding %s with full history...\n", projectName)
-		if err := git.SubtreeAdd(gy.Path, localSourcePath, projectName); err != nil {
+		progress.Phase("subtree", "Adding %s with full history...", projectName)
+		if err := git.SubtreeAddRev(gy.Path, localSourcePath, projectName, refCommit); err != nil {
 			return nil, fmt.Errorf("failed to add subtree: %w", err)
 		}
 	}
+	vendored := opts.RecurseSubmodules && len(submodules) > 0
+	if vendored {
+		progress.Phase("submodules", "Copying submodules into %s...", projectName)
+		if err := snapshot.CopySubmodules(localSourcePath, projectPath); err != nil {
+			return nil, fmt.Errorf("failed to copy submodules: %w", err)
+		}
+	}
+
+	if opts.IncludeUntracked && len(uncommitted.Included) > 0 {
+		progress.Phase("untracked", "Copying %d untracked files to %s...", len(uncommitted.Included), projectName)
+		if err := snapshot.CopyFiles(src.Path, projectPath, uncommitted.Included); err != nil {
+			return nil, fmt.Errorf("failed to copy untracked files: %w", err)
+		}
+	}
 
 	// Generate and write metadata
 	meta := &metadata.Metadata{
 		OriginalSource:   displayPath,
 		BuriedAt:         time.Now(),
 		HistoryPreserved: historyPreserved,
+		ConvertedFrom:    src.ConvertedFrom(),
+		Unversioned:      src.Type == source.TypePlain,
+		Uncommitted:      uncommitted,
+		Refs:             refs,
+		History:          summary,
+		Issues:           issues,
+		Workflows:        workflows,
+		Artifacts:        artifacts,
+		Decommission:     decommission,
+		LFS:              lfsInfo,
+	}
+	for _, sub := range submodules {
+		meta.Submodules = append(meta.Submodules, metadata.Submodule{Path: sub.Path, URL: sub.URL, Commit: sub.Commit})
+	}
+	meta.SubmodulesVendored = vendored
+	if opts.ReviewAfter != nil {
+		meta.ReviewAfter = opts.ReviewAfter.After(meta.BuriedAt)
+	}
+	meta.Version = version
+	meta.Branch = opts.Branch
+	meta.Ref = opts.Ref
+	meta.RefCommit = refCommit
+	meta.Owner = opts.Owner
+	meta.MonthlyCostBefore = opts.MonthlyCostBefore
+	meta.MonthlyCostAfter = opts.MonthlyCostAfter
+	if hosted != nil {
+		meta.Description = hosted.Description
+		meta.Topics = hosted.Topics
+	}
+	meta.Release = release
+	if gist != nil {
+		meta.Description = gist.Description
+	}
+	stageFiles := []string{metadata.FileName}
+	if vendored && !opts.DropHistory {
+		// The subtree brought the submodules in as links to their commits,
+		// which their content replaces
+		for _, sub := range submodules {
+			if err := git.Unstage(gy.Path, path.Join(projectName, sub.Path)); err != nil {
+				return nil, err
+			}
+			stageFiles = append(stageFiles, sub.Path)
+		}
+	}
+
+	if issues != nil {
+		issuesPath := filepath.Join(projectPath, metadata.IssuesFileName)
+		if err := atomicfile.Write(issuesPath, []byte(issues.Generate(meta.BuriedAt)), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write issues file: %w", err)
+		}
+		stageFiles = append(stageFiles, metadata.IssuesFileName)
 	}
+
+	if release != nil {
+		notesPath := filepath.Join(projectPath, metadata.ReleaseNotesFileName)
+		if err := atomicfile.Write(notesPath, []byte(release.Generate()), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write release notes: %w", err)
+		}
+		stageFiles = append(stageFiles, metadata.ReleaseNotesFileName)
+	}
+
+	checklistPath := filepath.Join(projectPath, metadata.DecommissionFileName)
+	if _, err := os.Stat(checklistPath); err == nil {
+		// Never overwrite a file of the project's own
+		progress.Warn("source has its own %s; no decommissioning checklist was written", metadata.DecommissionFileName)
+		meta.Decommission = nil
+	} else {
+		if err := atomicfile.Write(checklistPath, []byte(checklist.Generate(projectName, meta)), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write decommissioning checklist: %w", err)
+		}
+		stageFiles = append(stageFiles, metadata.DecommissionFileName)
+	}
+
+	if opts.ActivitySparkline && summary != nil {
+		imagePath := filepath.Join(projectPath, metadata.ActivityImageFileName)
+		if err := atomicfile.Write(imagePath, []byte(summary.Sparkline()), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write activity sparkline: %w", err)
+		}
+		meta.ActivityImage = metadata.ActivityImageFileName
+		stageFiles = append(stageFiles, metadata.ActivityImageFileName)
+	}
+
+	if opts.IncludeUntracked && uncommitted != nil {
+		stageFiles = append(stageFiles, uncommitted.Included...)
+	}
+
+	// Save the uncommitted changes themselves if requested
+	if opts.CaptureUncommitted && !uncommitted.IsEmpty() {
+		patch, err := git.UncommittedPatch(src.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to capture uncommitted changes: %w", err)
+		}
+		if patch != "" {
+			patchPath := filepath.Join(projectPath, metadata.UncommittedPatchFileName)
+			if err := atomicfile.Write(patchPath, []byte(patch), 0644); err != nil {
+				return nil, fmt.Errorf("failed to write uncommitted patch: %w", err)
+			}
+			uncommitted.PatchFile = metadata.UncommittedPatchFileName
+			stageFiles = append(stageFiles, metadata.UncommittedPatchFileName)
+		}
+	}
+
 	if err := meta.Write(projectPath); err != nil {
 		return nil, err
 	}
 
 	// Stage the metadata file (and all files if drop-history was used)
 	if opts.DropHistory {
-		if err := git.StageAll(gy.Path); err != nil {
+		// The content of LFS files is stored in git as it is, even though
+		// the project's .gitattributes still hands them to Git LFS
+		var env []string
+		if lfsInfo != nil {
+			env = lfs.FilterlessEnv()
+		}
+		if err := git.StageAll(gy.Path, env...); err != nil {
 			return nil, fmt.Errorf("failed to stage files: %w", err)
 		}
 	} else {
-		// For subtree, only stage the metadata file
-		metaPath := filepath.Join(projectName, metadata.FileName)
-		if err := git.StageFile(gy.Path, metaPath); err != nil {
-			return nil, fmt.Errorf("failed to stage metadata: %w", err)
+		// For subtree, only stage the files bury-it added
+		for _, name := range stageFiles {
+			if err := git.StageFile(gy.Path, filepath.Join(projectName, name)); err != nil {
+				return nil, fmt.Errorf("failed to stage metadata: %w", err)
+			}
+		}
+	}
+
+	// Keep the search index up to date if the graveyard has one
+	if _, err := os.Stat(index.Path(gy.Path)); err == nil {
+		progress.Phase("index", "Updating search index...")
+		if err := index.Refresh(gy, projectName); err != nil {
+			return nil, err
 		}
 	}
 
+	if err := audit.Record(gy.Path, projectName); err != nil {
+		return nil, err
+	}
+
 	// Auto-commit the archived project
-	commitMsg := fmt.Sprintf("docs: bury-it - archived %s", projectName)
-	fmt.Printf("Committing to graveyard...\n")
+	commitMsg := graveyard.BurialMessage(projectName)
+	progress.Phase("commit", "Committing to graveyard...")
 	if err := git.Commit(gy.Path, commitMsg); err != nil {
 		return nil, fmt.Errorf("failed to commit: %w", err)
 	}
+	if version > 0 {
+		if err := git.CreateTag(gy.Path, graveyard.VersionTag(projectName, version), "HEAD"); err != nil {
+			return nil, err
+		}
+	}
+	if err := op.End(); err != nil {
+		return nil, err
+	}
+	committed = true
 
-	return &Result{
+	result := &Result{
 		ProjectName:      projectName,
 		ProjectPath:      projectPath,
 		HistoryPreserved: historyPreserved,
+		Version:          version,
+	}
+
+	// The location is published in the tombstone issue and the registry, so
+	// any credentials in the remote URL are left out
+	location := gy.Path
+	if remote, err := git.GetRemoteURL(gy.Path); err == nil && remote != "" {
+		location = display.URL(remote)
+	}
+
+	// Point visitors of the original repository at the graveyard. The burial
+	// is already committed, so a failure here is only a warning.
+	if opts.TombstoneIssue {
+		progress.Phase("tombstone", "Opening tombstone issue on %s/%s...", owner, repo)
+		url, err := openTombstone(client, owner, repo, meta.TombstoneBody(projectName, location))
+		if err != nil {
+			progress.Warn("failed to open tombstone issue: %v", err)
+		}
+		result.TombstoneURL = url
+	}
+
+	// Record the burial in the shared registry, again only warning on failure
+	if reg != nil {
+		progress.Phase("registry", "Recording burial in registry %s...", display.Path(reg.Path))
+		url, err := publishRegistry(reg, registryClient, opts.RegistryPR, registry.Entry{
+			Project:          projectName,
+			Graveyard:        location,
+			OriginalSource:   displayPath,
+			BuriedAt:         meta.BuriedAt,
+			HistoryPreserved: historyPreserved,
+		}, gy.Path)
+		if err != nil {
+			progress.Warn("failed to record burial in registry: %v", err)
+		}
+		result.RegistryURL = url
+	}
+
+	// Export the full issue history last, since it can take a long time and
+	// can be resumed on its own
+	if opts.WithIssues {
+		progress.Phase("export-issues", "Exporting issues and pull requests of %s/%s...", owner, repo)
+		cp, err := ExportIssues(gy, projectName, DefaultIssueExportWait, opts.Forges)
+		if err != nil {
+			progress.Warn("%v", err)
+			fmt.Printf("Resume with: bury-it export-issues %s -g %s\n", projectName, display.Path(gy.Path))
+		}
+		if cp != nil {
+			result.IssuesExported = cp.Exported
+			result.IssueExportComplete = cp.Complete
+		}
+	}
+
+	progress.Done("Buried " + projectName)
+	return result, nil
+}
+
+// checkIncludeUntracked checks that the untracked files of src can be buried
+// if opts asks for them: src must be a local repository with a working tree,
+// buried as it is checked out.
+func checkIncludeUntracked(opts Options, src *source.Source) error {
+	if !opts.IncludeUntracked {
+		return nil
+	}
+	if src.Type != source.TypeLocal || src.IsBare() || src.IsBundle() {
+		return fmt.Errorf("--include-untracked requires a local repository with a working tree")
+	}
+	if opts.Branch != "" || opts.Ref != "" {
+		return fmt.Errorf("--include-untracked cannot be used with --branch or --ref: the untracked files belong to the checked-out branch")
+	}
+	return nil
+}
+
+// includeUntracked moves the untracked files of the local repository at
+// repoPath that are to be buried, those neither its .gitignore files nor
+// ignoreFile match, from the untracked files of u to its included ones.
+func includeUntracked(u *metadata.Uncommitted, repoPath, ignoreFile string) error {
+	included, err := git.UntrackedFiles(repoPath, ignoreFile)
+	if err != nil {
+		return err
+	}
+	buried := make(map[string]bool, len(included))
+	for _, file := range included {
+		buried[file] = true
+	}
+	var left []string
+	for _, file := range u.Untracked {
+		if !buried[file] {
+			left = append(left, file)
+		}
+	}
+	u.Untracked, u.Included = left, included
+	return nil
+}
+
+// inventoryUncommitted lists stashes and uncommitted files in a local source.
+func inventoryUncommitted(repoPath string) (*metadata.Uncommitted, error) {
+	stashes, err := git.Stashes(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stashes: %w", err)
+	}
+	modified, untracked, err := git.UncommittedFiles(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list uncommitted files: %w", err)
+	}
+	return &metadata.Uncommitted{
+		Stashes:   stashes,
+		Modified:  modified,
+		Untracked: untracked,
+	}, nil
+}
+
+// errArchiveBranch is returned for burials of an archive given a branch,
+// since an archive holds a single checkout.
+var errArchiveBranch = errors.New("--branch cannot be used with an archive source: it holds a single checkout")
+
+// errArchiveRef is returned for burials given a ref of an archive that
+// holds no repository.
+var errArchiveRef = errors.New("--ref cannot be used with an archive that holds no git repository")
+
+// errPlainRevision is returned for burials of a plain directory given a
+// branch or ref, since it has no history.
+var errPlainRevision = errors.New("--branch and --ref cannot be used with a plain directory: it has no history")
+
+// applyRelease makes opts bury the state tagged by the release it names, if
+// any, without history, as the release was published.
+func applyRelease(opts *Options) error {
+	if opts.Release == "" {
+		return nil
+	}
+	// Options replayed from a plan already carry the release as their ref
+	if opts.Branch != "" || (opts.Ref != "" && opts.Ref != opts.Release) {
+		return fmt.Errorf("--release cannot be combined with --branch or --ref: the release names the commit to bury")
+	}
+	opts.Ref = opts.Release
+	opts.DropHistory = true
+	return nil
+}
+
+// errBranchAndRef is returned for burials given both a branch and a ref.
+var errBranchAndRef = errors.New("--branch and --ref cannot be combined: --ref already names the commit to bury")
+
+// SourceTypePlain is the Options.SourceType of a plain directory.
+const SourceTypePlain = "plain"
+
+// parseSource parses the source of opts as the type it names.
+func parseSource(opts Options) (*source.Source, error) {
+	var src *source.Source
+	var err error
+	switch opts.SourceType {
+	case "":
+		if opts.IgnoreFile != "" && !opts.IncludeUntracked {
+			return nil, fmt.Errorf("an ignore file can only be used with a plain directory source or --include-untracked")
+		}
+		src, err = source.Parse(opts.Source)
+	case SourceTypePlain:
+		src, err = source.ParsePlain(opts.Source)
+	default:
+		return nil, fmt.Errorf("unknown source type %q: want %s", opts.SourceType, SourceTypePlain)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid source: %w", err)
+	}
+	return src, nil
+}
+
+// needsClone reports whether src must be cloned to bury the branch or ref
+// of opts: always for remote sources, bare repositories, and bundles, and
+// for other local ones when the branch or ref given is not the one checked
+// out.
+func needsClone(src *source.Source, opts Options) bool {
+	switch {
+	case src.Type == source.TypeRemote, src.IsBare(), src.IsBundle():
+		return true
+	case opts.Branch != "":
+		current, err := git.CurrentBranch(src.Path)
+		return err != nil || current != opts.Branch
+	case opts.Ref != "":
+		commit, err := git.ResolveCommit(src.Path, opts.Ref)
+		if err != nil {
+			return true
+		}
+		head, err := git.Head(src.Path)
+		return err != nil || head != commit
+	}
+	return false
+}
+
+// inventorySubmodules lists the submodules of a source repository.
+func inventorySubmodules(repoPath string) ([]git.Submodule, error) {
+	submodules, err := git.Submodules(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list submodules: %w", err)
+	}
+	return submodules, nil
+}
+
+// checkoutRef checks out the commit ref names in repoPath, the source or a
+// clone of it, and returns the commit, or "" if no ref is given. Refs of a
+// local source other than a bundle are resolved in the source itself, where
+// its local branches are.
+func checkoutRef(src *source.Source, repoPath, ref string) (string, error) {
+	if ref == "" {
+		return "", nil
+	}
+	resolveIn := repoPath
+	if src.Type == source.TypeLocal && !src.IsBundle() {
+		resolveIn = src.Path
+	}
+	commit, err := git.ResolveCommit(resolveIn, ref)
+	if err != nil {
+		return "", err
+	}
+	if repoPath != src.Path {
+		if err := git.Detach(repoPath, commit); err != nil {
+			return "", err
+		}
+	}
+	return commit, nil
+}
+
+// inventoryRefs lists the branches and tags of a source repository.
+func inventoryRefs(repoPath string) ([]metadata.Ref, error) {
+	gitRefs, err := git.Refs(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches and tags: %w", err)
+	}
+	refs := make([]metadata.Ref, len(gitRefs))
+	for i, r := range gitRefs {
+		refs[i] = metadata.Ref{Name: r.Name, IsTag: r.IsTag, Commit: r.Hash, Date: r.Date}
+	}
+	return refs, nil
+}
+
+// inventoryArtifacts lists the images and packages a source repository
+// publishes.
+func inventoryArtifacts(repoPath string) ([]metadata.Artifact, error) {
+	found, err := artifacts.Scan(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan for published artifacts: %w", err)
+	}
+	refs := make([]metadata.Artifact, len(found))
+	for i, a := range found {
+		refs[i] = metadata.Artifact{Registry: a.Registry, Name: a.Name, File: a.File}
+	}
+	return refs, nil
+}
+
+// inventoryEndpoints lists the hostnames, URLs, and cloud resources a source
+// repository refers to.
+func inventoryEndpoints(repoPath string) (*metadata.Decommission, error) {
+	found, err := endpoints.Scan(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan for infrastructure: %w", err)
+	}
+	d := &metadata.Decommission{Endpoints: make([]metadata.Endpoint, len(found))}
+	for i, e := range found {
+		d.Endpoints[i] = metadata.Endpoint{Kind: string(e.Kind), Value: e.Value, File: e.File, Line: e.Line}
+	}
+	return d, nil
+}
+
+// checkOffline returns an error naming the first network access a burial
+// with opts needs, if opts.Offline forbids it.
+func checkOffline(opts Options, src *source.Source) error {
+	if !opts.Offline {
+		return nil
+	}
+	if _, local := src.FilePath(); src.Type == source.TypeRemote && !local {
+		return fmt.Errorf("cannot clone remote source %s with --offline: clone it first and bury the local copy", src.Path)
+	}
+	if src.IsDownload() {
+		return fmt.Errorf("cannot download archive %s with --offline: download it first and bury the local copy", src.Path)
+	}
+	if src.ConvertedFrom() != "" && src.IsURL() {
+		return fmt.Errorf("cannot convert %s repository %s with --offline: clone it first and bury the local copy", src.ConvertedFrom(), src.Path)
+	}
+	for _, f := range []struct {
+		set  bool
+		flag string
+	}{
+		{opts.LinkOriginalIssues, "--link-original-issues"},
+		{opts.WithIssues, "--with-issues"},
+		{opts.CIHistory, "--ci-history"},
+		{opts.Release != "", "--release"},
+		{opts.TombstoneIssue, "--tombstone-issue"},
+		{opts.RegistryPR, "--registry-pr"},
+	} {
+		if f.set {
+			return fmt.Errorf("%s needs the GitHub API, which --offline forbids", f.flag)
+		}
+	}
+	if opts.RecurseSubmodules {
+		return fmt.Errorf("--recurse-submodules clones the source's submodules, which --offline forbids")
+	}
+	if opts.Unshallow {
+		return fmt.Errorf("--unshallow fetches the source's history from its remote, which --offline forbids")
+	}
+	return nil
+}
+
+// checkShallow warns that a local source that is a shallow clone will be
+// buried with only the history it has, unless the history is dropped
+// anyway. With opts.Unshallow, the rest of the history is fetched instead,
+// if fetch is set; a plan leaves the source as it is.
+func checkShallow(opts Options, src *source.Source, fetch bool) error {
+	if src.Type != source.TypeLocal || src.IsBundle() || opts.DropHistory || !git.IsShallow(src.Path) {
+		return nil
+	}
+	if !opts.Unshallow {
+		progress.Warn("source is a shallow clone, so its history before the commits it has fetched will be missing; use --unshallow to fetch all of it first, or --drop-history")
+		return nil
+	}
+	if !fetch {
+		return nil
+	}
+	progress.Phase("unshallow", "Fetching the full history of %s...", display.Path(src.Path))
+	return git.Unshallow(src.Path)
+}
+
+// checkEmpty explains that a local source repository without commits, whose
+// HEAD names no commit yet, has nothing git can bury. With opts.AllowEmpty,
+// it returns the source as a plain directory instead, so that the files of
+// its working tree are buried as a snapshot; a source given a branch or ref
+// is left to fail if that names no commit either.
+func checkEmpty(opts Options, src *source.Source) (*source.Source, error) {
+	if src.Type != source.TypeLocal || src.IsBundle() || opts.Branch != "" || opts.Ref != "" || git.HasCommits(src.Path) {
+		return src, nil
+	}
+	if src.IsBare() {
+		return nil, fmt.Errorf("source repository %s has no commits, so there is nothing to bury", src.Path)
+	}
+	if !opts.AllowEmpty {
+		return nil, fmt.Errorf("source repository %s has no commits yet, so it has no history or tracked files to bury; commit its files first, or use --allow-empty to bury them as a snapshot", src.Path)
+	}
+	progress.Warn("source repository has no commits; burying the files in its working tree as a snapshot, as for a plain directory")
+	plain := *src
+	plain.Type = source.TypePlain
+	return &plain, nil
+}
+
+// githubRepo returns the owner and name of a repository on github.com, or on
+// a GitHub Enterprise Server host marked as github in forges, given its
+// remote URL, and a client for its host. The client is for github.com when
+// the repository is on neither.
+func githubRepo(remoteURL string, forges map[string]string) (owner, repo string, client *github.Client, ok bool) {
+	if owner, repo, ok := github.ParseRepoURL(remoteURL); ok {
+		return owner, repo, github.NewClient(github.TokenFromEnv()), true
+	}
+	if src, err := source.Parse(remoteURL); err == nil {
+		if host, owner, repo, ok := src.HostedRepo(); ok && forges[host] == "github" {
+			return owner, repo, github.NewEnterpriseClient(host, github.EnterpriseTokenFromEnv()), true
+		}
+	}
+	return "", "", github.NewClient(github.TokenFromEnv()), false
+}
+
+// tokenEnv names the environment variable holding the token for client's
+// host.
+func tokenEnv(client *github.Client) string {
+	if client.BaseURL != github.DefaultBaseURL {
+		return "GH_ENTERPRISE_TOKEN"
+	}
+	return "GITHUB_TOKEN"
+}
+
+// fetchIssues counts a GitHub repository's issues and pull requests and
+// collects links to the open ones.
+func fetchIssues(client *github.Client, owner, repo string) (*metadata.Issues, error) {
+	issues := &metadata.Issues{Repository: owner + "/" + repo}
+	for _, count := range []struct {
+		pullRequests bool
+		state        string
+		dest         *int
+	}{
+		{false, "open", &issues.OpenIssues},
+		{false, "closed", &issues.ClosedIssues},
+		{true, "open", &issues.OpenPullRequests},
+		{true, "closed", &issues.ClosedPullRequests},
+	} {
+		n, err := client.CountIssues(owner, repo, count.pullRequests, count.state)
+		if err != nil {
+			return nil, err
+		}
+		*count.dest = n
+	}
+
+	open, err := client.ListOpenIssues(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, issue := range open {
+		issues.Open = append(issues.Open, metadata.IssueLink{
+			Number:      issue.Number,
+			Title:       issue.Title,
+			URL:         issue.HTMLURL,
+			PullRequest: issue.IsPullRequest(),
+		})
+	}
+	return issues, nil
+}
+
+// fetchRelease returns the GitHub release of a repository with the given tag,
+// whose notes are kept with the burial.
+func fetchRelease(client *github.Client, owner, repo, tag string) (*metadata.Release, error) {
+	r, err := client.ReleaseByTag(owner, repo, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &metadata.Release{
+		Repository:  owner + "/" + repo,
+		Tag:         r.TagName,
+		Name:        r.Name,
+		URL:         r.HTMLURL,
+		PublishedAt: r.PublishedAt,
+		Notes:       r.Body,
 	}, nil
 }
+
+// fetchWorkflows summarizes the runs of each GitHub Actions workflow of a
+// repository: how often it ran, when it last succeeded and failed, and the
+// status its badge showed for the default branch.
+func fetchWorkflows(client *github.Client, owner, repo string) ([]metadata.Workflow, error) {
+	ghWorkflows, err := client.ListWorkflows(owner, repo)
+	if err != nil || len(ghWorkflows) == 0 {
+		return nil, err
+	}
+	defaultBranch, err := client.DefaultBranch(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	workflows := make([]metadata.Workflow, 0, len(ghWorkflows))
+	for _, gw := range ghWorkflows {
+		w := metadata.Workflow{Name: gw.Name, Path: gw.Path, Disabled: gw.State != "active", Status: "no status"}
+		if _, w.Runs, err = client.LatestWorkflowRun(owner, repo, gw.ID, "", ""); err != nil {
+			return nil, err
+		}
+		if w.Runs > 0 {
+			for _, last := range []struct {
+				status string
+				dest   *time.Time
+			}{
+				{"success", &w.LastSuccess},
+				{"failure", &w.LastFailure},
+			} {
+				run, _, err := client.LatestWorkflowRun(owner, repo, gw.ID, last.status, "")
+				if err != nil {
+					return nil, err
+				}
+				if run != nil {
+					*last.dest = run.CreatedAt
+				}
+			}
+			run, _, err := client.LatestWorkflowRun(owner, repo, gw.ID, "completed", defaultBranch)
+			if err != nil {
+				return nil, err
+			}
+			if run != nil {
+				w.Status = badgeStatus(run.Conclusion)
+			}
+		}
+		workflows = append(workflows, w)
+	}
+	return workflows, nil
+}
+
+// badgeStatus returns the text a workflow status badge shows for a run
+// conclusion.
+func badgeStatus(conclusion string) string {
+	switch conclusion {
+	case "success":
+		return "passing"
+	case "failure":
+		return "failing"
+	default:
+		return conclusion
+	}
+}
+
+// openTombstone opens or updates the pinned tombstone issue on a GitHub
+// repository and returns its URL.
+func openTombstone(client *github.Client, owner, repo, body string) (string, error) {
+	open, err := client.ListOpenIssues(owner, repo)
+	if err != nil {
+		return "", err
+	}
+
+	var issue *github.Issue
+	for i := range open {
+		if open[i].Title == metadata.TombstoneTitle && !open[i].IsPullRequest() {
+			issue, err = client.UpdateIssueBody(owner, repo, open[i].Number, body)
+			if err != nil {
+				return "", err
+			}
+			break
+		}
+	}
+	if issue == nil {
+		issue, err = client.CreateIssue(owner, repo, metadata.TombstoneTitle, body)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if err := client.PinIssue(issue.NodeID); err != nil {
+		return issue.HTMLURL, err
+	}
+	return issue.HTMLURL, nil
+}
+
+// publishRegistry appends the burial to the registry ledger, either as a direct
+// commit or as a pull request, and returns the pull request URL if one was
+// opened. The entry's commit is taken from the graveyard's HEAD.
+func publishRegistry(reg *registry.Registry, client *github.Client, pr bool, entry registry.Entry, graveyardPath string) (string, error) {
+	commit, err := git.Head(graveyardPath)
+	if err != nil {
+		return "", err
+	}
+	entry.Commit = commit
+	if pr {
+		return reg.Propose(client, entry)
+	}
+	return "", reg.Commit(entry)
+}
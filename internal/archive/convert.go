@@ -0,0 +1,39 @@
+package archive
+
+import (
+	"path/filepath"
+
+	"github.com/deanhigh/bury-it/internal/convert"
+	"github.com/deanhigh/bury-it/internal/display"
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/progress"
+	"github.com/deanhigh/bury-it/internal/source"
+)
+
+// convertSource converts the history of a Mercurial or Subversion source to
+// git in a new repository named name in dir, checked out at branch if one is
+// given, and returns its path.
+func convertSource(src *source.Source, dir, name, branch string) (string, error) {
+	repoPath := filepath.Join(dir, name)
+	progress.Phase("convert", "Converting the %s history of %s...", src.ConvertedFrom(), display.Path(src.Path))
+	var err error
+	switch src.Type {
+	case source.TypeMercurial:
+		err = convert.Mercurial(src.Path, repoPath)
+	case source.TypeSubversion:
+		url := src.Path
+		if !src.IsURL() {
+			url = "file://" + filepath.ToSlash(src.Path)
+		}
+		err = convert.Subversion(url, repoPath)
+	}
+	if err != nil {
+		return "", err
+	}
+	if branch != "" {
+		if err := git.Checkout(repoPath, branch, false); err != nil {
+			return "", err
+		}
+	}
+	return repoPath, nil
+}
@@ -0,0 +1,49 @@
+package archive
+
+import (
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/progress"
+	"github.com/deanhigh/bury-it/internal/size"
+)
+
+// historySize returns the approximate number of bytes the history reachable
+// from HEAD in repoPath adds to the graveyard, and its number of commits.
+func historySize(repoPath string) (int64, int, error) {
+	commits, err := git.Log(repoPath, []string{"HEAD"})
+	if err != nil {
+		return 0, 0, err
+	}
+	objects, err := git.ListObjects(repoPath, []string{"HEAD"})
+	if err != nil {
+		return 0, 0, err
+	}
+	hashes := make([]string, len(objects))
+	for i, obj := range objects {
+		hashes[i] = obj.Hash
+	}
+	infos, err := git.ObjectInfos(repoPath, hashes)
+	if err != nil {
+		return 0, 0, err
+	}
+	var total int64
+	for _, info := range infos {
+		total += info.DiskSize
+	}
+	return total, len(commits), nil
+}
+
+// downgradeHistory decides whether a full-history burial of historyBytes
+// over opts.SizeLimit is made without history instead: always with
+// AutoDowngrade, else if ConfirmDowngrade agrees. A burial kept whole is
+// warned about.
+func downgradeHistory(opts Options, historyBytes int64) bool {
+	if opts.SizeLimit <= 0 || historyBytes <= opts.SizeLimit {
+		return false
+	}
+	if opts.AutoDowngrade || (opts.ConfirmDowngrade != nil && opts.ConfirmDowngrade(historyBytes, opts.SizeLimit)) {
+		progress.Warn("history is %s, over the %s size limit; burying without history", size.Format(historyBytes), size.Format(opts.SizeLimit))
+		return true
+	}
+	progress.Warn("history is %s, over the %s size limit; use --drop-history or --auto-downgrade to bury without it", size.Format(historyBytes), size.Format(opts.SizeLimit))
+	return false
+}
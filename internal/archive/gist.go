@@ -0,0 +1,58 @@
+package archive
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/deanhigh/bury-it/internal/github"
+	"github.com/deanhigh/bury-it/internal/progress"
+	"github.com/deanhigh/bury-it/internal/source"
+)
+
+// maxGistNameLength is the longest project name made from a gist's
+// description, which can run to a paragraph.
+const maxGistNameLength = 40
+
+// lookupGist returns the gist a source is, or nil if it is not one or its
+// details could not be fetched, which only costs it a better name.
+func lookupGist(src *source.Source) *github.Gist {
+	id, ok := src.GistID()
+	if !ok {
+		return nil
+	}
+	progress.Phase("description", "Looking up gist %s...", id)
+	gist, err := github.NewClient(github.TokenFromEnv()).Gist(id)
+	if err != nil {
+		progress.Warn("failed to fetch gist details, naming it after its ID: %v", err)
+		return nil
+	}
+	return gist
+}
+
+// gistName returns a project name made from a gist's description, such as
+// "plot-sensor-data" for "Plot sensor data!", or "" if it has none.
+func gistName(description string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(description) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	name := b.String()
+	if len(name) <= maxGistNameLength {
+		return name
+	}
+	// Cut at a word, or mid-word if the first one is too long
+	name = name[:maxGistNameLength+1]
+	if i := strings.LastIndexByte(name, '-'); i > 0 {
+		return name[:i]
+	}
+	return strings.ToValidUTF8(name[:maxGistNameLength], "")
+}
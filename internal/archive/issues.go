@@ -0,0 +1,85 @@
+package archive
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/audit"
+	"github.com/deanhigh/bury-it/internal/export"
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/github"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/metadata"
+	"github.com/deanhigh/bury-it/internal/progress"
+)
+
+// DefaultIssueExportWait is the longest an issue export waits for the GitHub
+// rate limit to reset before stopping.
+const DefaultIssueExportWait = 15 * time.Minute
+
+// ExportIssues exports every issue and pull request of a buried project's
+// GitHub source into the project directory, resuming an earlier export if one
+// was interrupted. Whatever was exported is committed, together with the
+// checkpoint of an incomplete export so that it can be resumed later, which
+// leaves the graveyard clean either way. forges marks GitHub Enterprise Server
+// hosts as in Options.
+func ExportIssues(gy *graveyard.Graveyard, project string, maxWait time.Duration, forges map[string]string) (*export.Checkpoint, error) {
+	meta, err := gy.Metadata(project)
+	if err != nil {
+		return nil, err
+	}
+	owner, repo, client, ok := githubRepo(meta.OriginalSource, forges)
+	if !ok {
+		return nil, fmt.Errorf("%s was not buried from a GitHub repository: %s", project, meta.OriginalSource)
+	}
+
+	x := &export.Issues{
+		Dir:        gy.ProjectPath(project),
+		Repository: owner + "/" + repo,
+		Fetch: func(next string) ([]github.Issue, string, error) {
+			return client.ListIssuesPage(owner, repo, next)
+		},
+		MaxWait: maxWait,
+		Progress: func(exported int) {
+			progress.Count(int64(exported), 0, "issues", "  %d issues and pull requests exported", exported)
+		},
+	}
+	cp, runErr := x.Run()
+	if err := commitIssueExport(gy, project); err != nil {
+		return cp, err
+	}
+	if runErr != nil {
+		if cp != nil {
+			return cp, fmt.Errorf("issue export stopped after %d issues: %w", cp.Exported, runErr)
+		}
+		return nil, runErr
+	}
+	return cp, nil
+}
+
+// commitIssueExport commits the issue export files of project, if they have
+// changed.
+func commitIssueExport(gy *graveyard.Graveyard, project string) error {
+	for _, name := range []string{metadata.IssueExportFileName, metadata.IssueExportCheckpointFileName} {
+		if _, err := os.Stat(filepath.Join(gy.ProjectPath(project), name)); err != nil {
+			// An export that stopped before its first page has no files
+			continue
+		}
+		if err := git.StageFile(gy.Path, filepath.Join(project, name)); err != nil {
+			return fmt.Errorf("failed to stage issue export: %w", err)
+		}
+	}
+	changed, err := git.HasStagedChanges(gy.Path)
+	if err != nil || !changed {
+		return err
+	}
+	if err := audit.Record(gy.Path, project); err != nil {
+		return err
+	}
+	if err := git.Commit(gy.Path, graveyard.IssueExportMessage(project)); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
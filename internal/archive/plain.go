@@ -0,0 +1,69 @@
+package archive
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/display"
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/progress"
+	"github.com/deanhigh/bury-it/internal/source"
+)
+
+// snapshotPlain commits the files of a plain directory source as a single
+// snapshot in a repository created in dir, and returns the repository. The
+// commit is dated by the newest of the files, and the directory itself is
+// left untouched. Files matching the .gitignore files in the directory, or the
+// patterns of ignoreFile if it is set, are left out.
+func snapshotPlain(src *source.Source, dir, ignoreFile string) (string, error) {
+	progress.Phase("snapshot", "Committing the files of %s...", display.Path(src.Path))
+	repoPath := filepath.Join(dir, "snapshot")
+	if err := os.Mkdir(repoPath, 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := git.Init(repoPath); err != nil {
+		return "", err
+	}
+	gitDir := filepath.Join(repoPath, ".git")
+	if ignoreFile != "" {
+		patterns, err := os.ReadFile(ignoreFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read ignore file: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(gitDir, "info", "exclude"), patterns, 0644); err != nil {
+			return "", fmt.Errorf("failed to write ignore patterns: %w", err)
+		}
+	}
+
+	// Stage the files where they are, then check them out into the snapshot
+	// repository, so that nothing is written to the source
+	if err := git.StageAll(src.Path, "GIT_DIR="+gitDir, "GIT_WORK_TREE="+src.Path); err != nil {
+		return "", err
+	}
+	files, err := git.ListFiles(repoPath)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("directory %s holds no files to bury", src.Path)
+	}
+	var latest time.Time
+	for _, file := range files {
+		if info, err := os.Lstat(filepath.Join(src.Path, file)); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	if latest.IsZero() {
+		latest = time.Unix(0, 0)
+	}
+	message := "Snapshot of " + filepath.Base(src.Path)
+	if err := git.CommitAs(repoPath, snapshotAuthorName, snapshotAuthorEmail, message, latest); err != nil {
+		return "", err
+	}
+	if err := git.ResetHard(repoPath, "HEAD"); err != nil {
+		return "", err
+	}
+	return repoPath, nil
+}
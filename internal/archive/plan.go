@@ -0,0 +1,313 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/atomicfile"
+	"github.com/deanhigh/bury-it/internal/display"
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/metadata"
+	"github.com/deanhigh/bury-it/internal/progress"
+	"github.com/deanhigh/bury-it/internal/snapshot"
+	"github.com/deanhigh/bury-it/internal/source"
+)
+
+// planVersion is the current plan file format version.
+const planVersion = 1
+
+// Plan describes what a burial will do, computed without changing the
+// graveyard. A saved plan can be applied later.
+type Plan struct {
+	// Version is the plan file format version.
+	Version int `json:"version"`
+	// CreatedAt is when the plan was made.
+	CreatedAt time.Time `json:"created_at"`
+	// Options are the options the burial will run with. Paths are absolute
+	// and ExpectCommit pins the planned source commit.
+	Options Options `json:"options"`
+	// Clone indicates that the source will be cloned from a remote.
+	Clone bool `json:"clone"`
+	// Prefix is the project directory in the graveyard.
+	Prefix string `json:"prefix"`
+	// CommitMessage is the message of the burial commit.
+	CommitMessage string `json:"commit_message"`
+	// BurialVersion is the version the burial will create when the project
+	// is buried again with NewVersion, or zero.
+	BurialVersion int `json:"burial_version,omitempty"`
+	// Commits is the number of commits brought in with the history, or zero
+	// when history is dropped.
+	Commits int `json:"commits"`
+	// Files are the paths added to the graveyard, relative to its root.
+	Files []string `json:"files"`
+	// EstimatedSize is the approximate number of bytes added to the
+	// graveyard: the packed objects of the history, or the tracked files
+	// when history is dropped.
+	EstimatedSize int64 `json:"estimated_size"`
+}
+
+// NewPlan works out what Archive would do with opts without changing the
+// graveyard. Remote sources are cloned to a temporary directory to inspect
+// them.
+func NewPlan(opts Options) (*Plan, error) {
+	src, err := parseSource(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOffline(opts, src); err != nil {
+		return nil, err
+	}
+	if _, err := snapshot.Get(opts.CopyEngine); err != nil {
+		return nil, err
+	}
+	if err := applyRelease(&opts); err != nil {
+		return nil, err
+	}
+	if opts.Branch != "" && opts.Ref != "" {
+		return nil, errBranchAndRef
+	}
+	if src.Type == source.TypeArchive && opts.Branch != "" {
+		return nil, errArchiveBranch
+	}
+	if src.Type == source.TypePlain && (opts.Branch != "" || opts.Ref != "") {
+		return nil, errPlainRevision
+	}
+	if err := checkIncludeUntracked(opts, src); err != nil {
+		return nil, err
+	}
+	gy, err := graveyard.New(opts.Graveyard)
+	if err != nil {
+		return nil, fmt.Errorf("invalid graveyard: %w", err)
+	}
+	if err := gy.Validate(); err != nil {
+		return nil, err
+	}
+
+	projectName := src.Name
+	if gist := lookupGist(src); gist != nil && opts.Name == "" && gistName(gist.Description) != "" {
+		// Pin the name, which the gist's description may not keep
+		projectName = gistName(gist.Description)
+		opts.Name = projectName
+	}
+	if opts.Name != "" {
+		projectName = opts.Name
+	}
+	validateName := gy.ValidateProjectName
+	if opts.NewVersion {
+		validateName = gy.ValidateNewVersion
+	}
+	if err := validateName(projectName); err != nil {
+		return nil, err
+	}
+	burialVersion := 0
+	if opts.NewVersion {
+		current, err := gy.CurrentVersion(projectName)
+		if err != nil {
+			return nil, err
+		}
+		burialVersion = current + 1
+	}
+
+	localSourcePath := src.Path
+	if src.Type != source.TypeRemote && !src.IsURL() {
+		if err := src.Validate(); err != nil {
+			return nil, err
+		}
+		if err := checkShallow(opts, src, false); err != nil {
+			return nil, err
+		}
+		if src, err = checkEmpty(opts, src); err != nil {
+			return nil, err
+		}
+		// Plans may be applied from another directory
+		opts.Source = src.Path
+		if opts.IgnoreFile != "" {
+			if opts.IgnoreFile, err = filepath.Abs(opts.IgnoreFile); err != nil {
+				return nil, fmt.Errorf("failed to resolve path: %w", err)
+			}
+		}
+	}
+	if src.Type == source.TypeArchive {
+		tempDir, err := os.MkdirTemp("", "bury-it-plan-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(tempDir) }()
+
+		// The snapshot commit of an archive without a repository is the
+		// same each time it is unpacked, so the burial can be pinned to it
+		var snapshotOnly bool
+		localSourcePath, snapshotOnly, err = unpackSource(src, tempDir)
+		if err != nil {
+			return nil, err
+		}
+		if snapshotOnly && opts.Ref != "" {
+			return nil, errArchiveRef
+		}
+		opts.DropHistory = opts.DropHistory || snapshotOnly
+	} else if src.Type == source.TypePlain {
+		tempDir, err := os.MkdirTemp("", "bury-it-plan-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(tempDir) }()
+
+		// Like that of an archive, the snapshot commit of a directory is the
+		// same each time it is made while the files stay the same
+		localSourcePath, err = snapshotPlain(src, tempDir, opts.IgnoreFile)
+		if err != nil {
+			return nil, err
+		}
+		opts.DropHistory = true
+	} else if src.ConvertedFrom() != "" {
+		tempDir, err := os.MkdirTemp("", "bury-it-plan-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(tempDir) }()
+
+		localSourcePath, err = convertSource(src, tempDir, projectName, opts.Branch)
+		if err != nil {
+			return nil, err
+		}
+	} else if needsClone(src, opts) {
+		tempDir, err := os.MkdirTemp("", "bury-it-plan-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(tempDir) }()
+
+		localSourcePath = filepath.Join(tempDir, projectName)
+		progress.Phase("clone", "Cloning %s to inspect it...", display.Path(src.Path))
+		if err := git.CloneBranch(src.Path, localSourcePath, opts.Branch, src.CloneEnv()...); err != nil {
+			return nil, fmt.Errorf("failed to clone repository: %w", err)
+		}
+	}
+	if _, err := checkoutRef(src, localSourcePath, opts.Ref); err != nil {
+		return nil, err
+	}
+	opts.Graveyard = gy.Path
+
+	head, err := git.Head(localSourcePath)
+	if err != nil {
+		return nil, err
+	}
+	opts.ExpectCommit = head
+
+	plan := &Plan{
+		Version:       planVersion,
+		CreatedAt:     time.Now(),
+		Options:       opts,
+		Clone:         src.Type == source.TypeRemote,
+		Prefix:        projectName,
+		CommitMessage: graveyard.BurialMessage(projectName),
+		BurialVersion: burialVersion,
+	}
+
+	tracked, err := git.ListFiles(localSourcePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range tracked {
+		plan.Files = append(plan.Files, path.Join(projectName, file))
+	}
+	if opts.IncludeUntracked {
+		untracked, err := git.UntrackedFiles(src.Path, opts.IgnoreFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range untracked {
+			plan.Files = append(plan.Files, path.Join(projectName, file))
+		}
+	}
+	if !opts.DropHistory {
+		historyBytes, commits, err := historySize(localSourcePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read history: %w", err)
+		}
+		// Nobody is asked while planning, so only an automatic downgrade
+		// applies
+		unasked := opts
+		unasked.ConfirmDowngrade = nil
+		if downgradeHistory(unasked, historyBytes) {
+			opts.DropHistory = true
+			plan.Options.DropHistory = true
+		} else {
+			plan.Commits = commits
+			plan.EstimatedSize = historyBytes
+		}
+	}
+	if opts.DropHistory {
+		for _, file := range tracked {
+			if info, err := os.Stat(filepath.Join(localSourcePath, file)); err == nil {
+				plan.EstimatedSize += info.Size()
+			}
+		}
+	}
+	for _, file := range plannedExtraFiles(opts) {
+		plan.Files = append(plan.Files, path.Join(projectName, file))
+	}
+
+	return plan, nil
+}
+
+// plannedExtraFiles lists the files bury-it itself may add next to the
+// project's own files. Some are only written if there is something to record.
+func plannedExtraFiles(opts Options) []string {
+	files := []string{metadata.FileName, metadata.DecommissionFileName}
+	if opts.LinkOriginalIssues {
+		files = append(files, metadata.IssuesFileName)
+	}
+	if opts.WithIssues {
+		files = append(files, metadata.IssueExportFileName, metadata.IssueExportCheckpointFileName)
+	}
+	if opts.ActivitySparkline && opts.DropHistory {
+		files = append(files, metadata.ActivityImageFileName)
+	}
+	if opts.CaptureUncommitted {
+		files = append(files, metadata.UncommittedPatchFileName)
+	}
+	if opts.Release != "" {
+		files = append(files, metadata.ReleaseNotesFileName)
+	}
+	return files
+}
+
+// Save writes the plan as JSON to path.
+func (p *Plan) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	if err := atomicfile.Write(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	return nil
+}
+
+// LoadPlan reads a plan saved by Save.
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan %s: %w", path, err)
+	}
+	if plan.Version != planVersion {
+		return nil, fmt.Errorf("unsupported plan version %d in %s", plan.Version, path)
+	}
+	return &plan, nil
+}
+
+// Apply carries out a plan. It fails without changing anything if the source
+// no longer matches the planned commit or the project name has been taken.
+func Apply(p *Plan) (*Result, error) {
+	return Archive(p.Options)
+}
@@ -0,0 +1,314 @@
+package archive
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/source"
+)
+
+// scriptDelimiter ends the here-documents a script writes files with.
+const scriptDelimiter = "BURY_IT_EOF"
+
+// Script returns a shell script that carries out the burial opts describe,
+// for a separate runner to review and execute, without changing the
+// graveyard. The burial is made in a scratch clone of the graveyard, and the
+// script repeats its git and tar commands with the source commit pinned, then
+// writes the files bury-it generated with the content they had there. The
+// script refuses to run if the graveyard has moved on from its current
+// commit.
+//
+// Only git repositories can be scripted, and not with options that reach
+// beyond the graveyard after the burial or bring in content the runner could
+// not fetch the same way: --tombstone-issue, --registry, --with-issues, and
+// --recurse-submodules.
+func Script(opts Options) (string, error) {
+	switch {
+	case opts.TombstoneIssue:
+		return "", fmt.Errorf("a script cannot open a tombstone issue; open it with a burial run")
+	case opts.Registry != "":
+		return "", fmt.Errorf("a script cannot record the burial in a registry; record it with a burial run")
+	case opts.WithIssues:
+		return "", fmt.Errorf("a script cannot export issues; export them afterwards with bury-it export-issues")
+	case opts.RecurseSubmodules:
+		return "", fmt.Errorf("a script cannot bury the content of submodules")
+	case opts.IncludeUntracked:
+		return "", fmt.Errorf("a script cannot bury untracked files")
+	case opts.Unshallow:
+		return "", fmt.Errorf("a script cannot fetch the history of a shallow source; run git fetch --unshallow in it first")
+	case opts.AllowEmpty:
+		return "", fmt.Errorf("a script cannot bury a repository without commits; commit its files first")
+	}
+	src, err := parseSource(opts)
+	if err != nil {
+		return "", err
+	}
+	if src.Type == source.TypeArchive || src.Type == source.TypePlain || src.ConvertedFrom() != "" {
+		return "", fmt.Errorf("only git repositories can be buried with a script, not %s", opts.Source)
+	}
+
+	// Pin the source commit, which the script buries whatever the source's
+	// branches point at by the time it runs
+	plan, err := NewPlan(opts)
+	if err != nil {
+		return "", err
+	}
+	opts = plan.Options
+	gy, err := graveyard.New(opts.Graveyard)
+	if err != nil {
+		return "", fmt.Errorf("invalid graveyard: %w", err)
+	}
+	base, err := git.Head(gy.Path)
+	if err != nil {
+		return "", fmt.Errorf("the graveyard needs a commit to write a script against: %w", err)
+	}
+
+	// Bury into a scratch clone, copying with git archive as the script does
+	tempDir, err := os.MkdirTemp("", "bury-it-script-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+	scratch := filepath.Join(tempDir, "graveyard")
+	if err := git.Clone(gy.Path, scratch); err != nil {
+		return "", fmt.Errorf("failed to clone the graveyard: %w", err)
+	}
+	if head, err := git.Head(scratch); err != nil || head != base {
+		return "", fmt.Errorf("the graveyard must have a branch checked out to write a script")
+	}
+	scratchOpts := opts
+	scratchOpts.Graveyard = scratch
+	scratchOpts.CopyEngine = "archive"
+	if _, err := Archive(scratchOpts); err != nil {
+		return "", err
+	}
+	if lfsFiles, err := git.LFSFiles(scratch); err != nil {
+		return "", err
+	} else if len(lfsFiles) > 0 {
+		return "", fmt.Errorf("a script cannot bury the content of files stored with Git LFS")
+	}
+
+	// The files bury-it wrote itself are those the burial commit changed,
+	// apart from the project's own files copied without history
+	prefix := plan.Prefix
+	changed, err := git.DiffNames(scratch, "HEAD^", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	tree, err := git.TrackedTree(scratch)
+	if err != nil {
+		return "", err
+	}
+	entries := make(map[string]git.TreeEntry, len(tree))
+	for _, e := range tree {
+		entries[e.Path] = e
+	}
+	generated := make(map[string]bool)
+	for _, file := range plannedExtraFiles(opts) {
+		generated[path.Join(prefix, file)] = true
+	}
+
+	s := &scriptWriter{}
+	s.line("#!/bin/sh")
+	s.line("# Burial of %s into %s, written by bury-it --emit-script on %s.", prefix, gy.Path, time.Now().Format("2006-01-02 15:04"))
+	s.line("# It makes the same burial bury-it would have made, burying commit %s", opts.ExpectCommit)
+	s.line("# of %s. It refuses to run once the graveyard has moved on.", src.DisplayPath())
+	s.line("set -eu")
+	s.line("")
+	s.line("cd %s", git.ShellQuote(gy.Path))
+	s.line("if [ \"$(git rev-parse HEAD)\" != %s ]; then", base)
+	s.line("\techo \"the graveyard is no longer at %s; write the script again\" >&2", base[:12])
+	s.line("\texit 1")
+	s.line("fi")
+	s.line("if [ -n \"$(git status --porcelain)\" ]; then")
+	s.line("\techo \"the graveyard has uncommitted changes\" >&2")
+	s.line("\texit 1")
+	s.line("fi")
+	s.line("work=$(mktemp -d)")
+	s.line("trap 'rm -rf \"$work\"' EXIT")
+	s.line("")
+
+	if src.Type == source.TypeRemote || src.IsBundle() {
+		s.line("# Copy the source repository into a temporary directory")
+		clone := "git clone -q"
+		if opts.Branch != "" {
+			clone += " --branch " + git.ShellQuote(opts.Branch)
+		}
+		s.line("%s %s \"$work/source\"", clone, git.ShellQuote(src.Path))
+		s.line("source=\"$work/source\"")
+	} else {
+		s.line("source=%s", git.ShellQuote(src.Path))
+	}
+	s.line("")
+
+	// The commits before the burial commit are made as they were in the
+	// scratch clone, so that they get the same hashes, which the search
+	// index records
+	if plan.BurialVersion > 0 {
+		retirement := "HEAD^"
+		if !opts.DropHistory {
+			retirement = "HEAD^^"
+		}
+		env, err := commitEnv(scratch, retirement)
+		if err != nil {
+			return "", err
+		}
+		retired := plan.BurialVersion - 1
+		tag := graveyard.VersionTag(prefix, retired)
+		s.line("# Retire the current version of %s, keeping it under a tag", prefix)
+		s.line("git rev-parse -q --verify %s >/dev/null || git tag %s HEAD", git.ShellQuote("refs/tags/"+tag), git.ShellQuote(tag))
+		s.line("git rm -r -q -- %s", git.ShellQuote(prefix))
+		s.line("rm -rf %s", git.ShellQuote(prefix))
+		s.line("%s git commit -m %s", env, git.ShellQuote(graveyard.RetirementMessage(prefix, retired)))
+		s.line("")
+	}
+
+	if opts.DropHistory {
+		s.line("# Copy the tracked files of the source, without history")
+		s.line("git -C \"$source\" archive --format=tar -o \"$work/source.tar\" %s", opts.ExpectCommit)
+		s.line("mkdir -p %s", git.ShellQuote(prefix))
+		s.line("tar -xf \"$work/source.tar\" -C %s", git.ShellQuote(prefix))
+	} else {
+		env, err := commitEnv(scratch, "HEAD^")
+		if err != nil {
+			return "", err
+		}
+		s.line("# Graft the source's history into the graveyard under %s", prefix)
+		s.line("%s git subtree add --prefix=%s \"$source\" %s", env, git.ShellQuote(prefix), opts.ExpectCommit)
+	}
+	s.line("")
+
+	s.line("# Write the files bury-it generates")
+	dirs := map[string]bool{prefix: true}
+	stage := []string{prefix}
+	for _, p := range changed {
+		inProject := strings.HasPrefix(p, prefix+"/")
+		if opts.DropHistory && inProject && !generated[p] {
+			continue
+		}
+		e, ok := entries[p]
+		if !ok {
+			s.line("git rm -q -- %s", git.ShellQuote(p))
+			continue
+		}
+		if !inProject {
+			stage = append(stage, p)
+		}
+		if dir := path.Dir(p); dir != "." && !dirs[dir] {
+			dirs[dir] = true
+			s.line("mkdir -p %s", git.ShellQuote(dir))
+		}
+		content, err := git.ShowFile(scratch, "HEAD", p)
+		if err != nil {
+			return "", err
+		}
+		s.file(p, e.Mode, content)
+	}
+	s.line("")
+
+	s.line("# Commit the burial")
+	add := make([]string, len(stage))
+	for i, p := range stage {
+		add[i] = git.ShellQuote(p)
+	}
+	s.line("git add -A -- %s", strings.Join(add, " "))
+	s.line("git commit -m %s", git.ShellQuote(plan.CommitMessage))
+	if plan.BurialVersion > 0 {
+		s.line("git tag %s HEAD", git.ShellQuote(graveyard.VersionTag(prefix, plan.BurialVersion)))
+	}
+	s.line("echo %s", git.ShellQuote("Buried "+prefix+"."))
+	return s.String(), nil
+}
+
+// commitEnv returns the environment variable assignments that make git
+// commit as the author and committer of rev, at the same times.
+func commitEnv(repoPath, rev string) (string, error) {
+	content, err := git.CatCommit(repoPath, rev)
+	if err != nil {
+		return "", err
+	}
+	var env []string
+	for _, line := range strings.Split(content, "\n") {
+		role, ident, ok := strings.Cut(line, " ")
+		if !ok || (role != "author" && role != "committer") {
+			continue
+		}
+		name, rest, ok1 := strings.Cut(ident, " <")
+		email, date, ok2 := strings.Cut(rest, "> ")
+		if !ok1 || !ok2 {
+			return "", fmt.Errorf("failed to parse the %s of commit %s", role, rev)
+		}
+		role = "GIT_" + strings.ToUpper(role)
+		env = append(env,
+			role+"_NAME="+git.ShellQuote(name),
+			role+"_EMAIL="+git.ShellQuote(email),
+			role+"_DATE="+git.ShellQuote("@"+date))
+	}
+	if len(env) != 6 {
+		return "", fmt.Errorf("failed to read the author and committer of commit %s", rev)
+	}
+	return strings.Join(env, " "), nil
+}
+
+// scriptWriter builds a shell script.
+type scriptWriter struct {
+	strings.Builder
+}
+
+// line adds a formatted line to the script.
+func (s *scriptWriter) line(format string, args ...any) {
+	fmt.Fprintf(s, format, args...)
+	s.WriteByte('\n')
+}
+
+// file adds commands writing content to the file at p with the given tree
+// mode: a here-document for text, or base64 for anything else.
+func (s *scriptWriter) file(p, mode, content string) {
+	quoted := git.ShellQuote(p)
+	switch {
+	case mode == git.ModeSymlink:
+		s.line("ln -sfn %s %s", git.ShellQuote(content), quoted)
+		return
+	case content == "":
+		s.line(": > %s", quoted)
+	case isHereDocText(content):
+		s.line("cat > %s <<'%s'", quoted, scriptDelimiter)
+		s.WriteString(content)
+		s.line(scriptDelimiter)
+	default:
+		s.line("base64 -d > %s <<'%s'", quoted, scriptDelimiter)
+		encoded := base64.StdEncoding.EncodeToString([]byte(content))
+		for len(encoded) > 76 {
+			s.line("%s", encoded[:76])
+			encoded = encoded[76:]
+		}
+		s.line("%s", encoded)
+		s.line(scriptDelimiter)
+	}
+	if mode == "100755" {
+		s.line("chmod +x %s", quoted)
+	}
+}
+
+// isHereDocText reports whether content can be written as it is in a quoted
+// here-document: text ending in a newline, with no line that would end the
+// here-document early.
+func isHereDocText(content string) bool {
+	if !utf8.ValidString(content) || strings.ContainsRune(content, 0) || !strings.HasSuffix(content, "\n") {
+		return false
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if line == scriptDelimiter {
+			return false
+		}
+	}
+	return true
+}
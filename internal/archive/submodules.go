@@ -0,0 +1,133 @@
+package archive
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/deanhigh/bury-it/internal/display"
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/metadata"
+	"github.com/deanhigh/bury-it/internal/progress"
+	"github.com/deanhigh/bury-it/internal/snapshot"
+	"github.com/deanhigh/bury-it/internal/source"
+)
+
+// VendorSubmodules copies the content of a buried project's submodules into
+// it as plain files, replacing the links to their commits, so that the
+// project survives the submodules' repositories being deleted. Each
+// submodule is cloned from its URL, resolved against the project's original
+// source if relative, and checked out at the commit the project pinned; its
+// own submodules are copied too. The files and the updated metadata are
+// staged for the caller to commit. offline refuses submodules that would be
+// cloned over the network.
+func VendorSubmodules(gy *graveyard.Graveyard, project string, offline bool) ([]metadata.Submodule, error) {
+	meta, err := gy.Metadata(project)
+	if err != nil {
+		return nil, err
+	}
+	clean, err := git.IsClean(gy.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !clean {
+		return nil, fmt.Errorf("graveyard has uncommitted changes; commit or stash them first")
+	}
+
+	submodules, err := git.SubmodulesIn(gy.Path, project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list submodules: %w", err)
+	}
+	linked := len(submodules) > 0
+	if !linked && !meta.SubmodulesVendored {
+		// Burials without history keep no links, only the metadata's list
+		for _, sub := range meta.Submodules {
+			submodules = append(submodules, git.Submodule{Path: sub.Path, URL: sub.URL, Commit: sub.Commit})
+		}
+	}
+	if len(submodules) == 0 {
+		return nil, fmt.Errorf("%s has no submodules left to vendor", project)
+	}
+	for i, sub := range submodules {
+		if sub.URL == "" {
+			return nil, fmt.Errorf("submodule %s of %s has no URL", sub.Path, project)
+		}
+		submodules[i].URL = source.ResolveSubmoduleURL(meta.OriginalSource, sub.URL)
+		if offline && !clonesLocally(submodules[i].URL) {
+			return nil, fmt.Errorf("cannot clone submodule %s from %s with --offline", sub.Path, submodules[i].URL)
+		}
+	}
+
+	tempDir, err := os.MkdirTemp("", "bury-it-submodules-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	// As for a burial, nested submodules on the local filesystem are only
+	// cloned for a project buried from a local source
+	var env []string
+	if filepath.IsAbs(meta.OriginalSource) {
+		env = git.ConfigEnv("protocol.file.allow", "always")
+	}
+	engine, err := snapshot.Get("tree")
+	if err != nil {
+		return nil, err
+	}
+	var vendored []metadata.Submodule
+	for i, sub := range submodules {
+		progress.Phase("clone", "Cloning %s at %s...", display.Path(sub.URL), sub.Commit[:12])
+		clone := filepath.Join(tempDir, strconv.Itoa(i))
+		if err := git.Clone(sub.URL, clone); err != nil {
+			return nil, fmt.Errorf("failed to clone submodule %s: %w", sub.Path, err)
+		}
+		if err := git.Detach(clone, sub.Commit); err != nil {
+			return nil, fmt.Errorf("submodule %s: %w", sub.Path, err)
+		}
+		if err := git.UpdateSubmodules(clone, env...); err != nil {
+			return nil, fmt.Errorf("submodule %s: %w", sub.Path, err)
+		}
+
+		dest := filepath.Join(gy.ProjectPath(project), filepath.FromSlash(sub.Path))
+		if err := os.RemoveAll(dest); err != nil {
+			return nil, err
+		}
+		if err := engine.Copy(clone, dest); err != nil {
+			return nil, fmt.Errorf("failed to copy submodule %s: %w", sub.Path, err)
+		}
+		if err := snapshot.CopySubmodules(clone, dest); err != nil {
+			return nil, fmt.Errorf("failed to copy submodule %s: %w", sub.Path, err)
+		}
+		if linked {
+			if err := git.Unstage(gy.Path, path.Join(project, sub.Path)); err != nil {
+				return nil, err
+			}
+		}
+		if err := git.StageFile(gy.Path, path.Join(project, sub.Path)); err != nil {
+			return nil, fmt.Errorf("failed to stage submodule %s: %w", sub.Path, err)
+		}
+		vendored = append(vendored, metadata.Submodule{Path: sub.Path, URL: sub.URL, Commit: sub.Commit})
+	}
+
+	if err := metadata.SetSubmodulesVendored(gy.ProjectPath(project), vendored); err != nil {
+		return nil, fmt.Errorf("%s: %w", project, err)
+	}
+	if err := git.StageFile(gy.Path, filepath.Join(project, metadata.FileName)); err != nil {
+		return nil, fmt.Errorf("failed to stage metadata: %w", err)
+	}
+	return vendored, nil
+}
+
+// clonesLocally reports whether the submodule at url is cloned from the
+// local filesystem, as a path or a file:// URL.
+func clonesLocally(url string) bool {
+	src, err := source.Parse(url)
+	if err != nil {
+		return false
+	}
+	_, local := src.FilePath()
+	return src.Type != source.TypeRemote || local
+}
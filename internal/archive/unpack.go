@@ -0,0 +1,75 @@
+package archive
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/display"
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/progress"
+	"github.com/deanhigh/bury-it/internal/source"
+	"github.com/deanhigh/bury-it/internal/unpack"
+)
+
+// The author of the commit made of the files of an archive that holds no
+// repository.
+const (
+	snapshotAuthorName  = "bury-it"
+	snapshotAuthorEmail = "bury-it@localhost"
+)
+
+// unpackSource extracts an archive source into dir, downloading it first if
+// it is a URL, and returns the repository to bury. An archive holding a
+// repository, .git included, is buried as that repository. Otherwise its
+// files are committed as a single snapshot, dated by the newest of them,
+// and snapshot is true: there is no history to keep.
+func unpackSource(src *source.Source, dir string) (repoPath string, snapshot bool, err error) {
+	archivePath := src.Path
+	if src.IsDownload() {
+		progress.Phase("download", "Downloading %s...", src.Path)
+		if archivePath, err = unpack.Download(src.Path, dir); err != nil {
+			return "", false, err
+		}
+	}
+
+	progress.Phase("unpack", "Unpacking %s...", display.Path(src.Path))
+	extracted := filepath.Join(dir, "unpacked")
+	if err := os.Mkdir(extracted, 0755); err != nil {
+		return "", false, fmt.Errorf("failed to create directory: %w", err)
+	}
+	latest, err := unpack.Extract(archivePath, extracted)
+	if err != nil {
+		return "", false, err
+	}
+	root, err := unpack.Root(extracted)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read archive content: %w", err)
+	}
+	if git.IsValidRepo(root) {
+		return root, false, nil
+	}
+
+	if err := git.Init(root); err != nil {
+		return "", false, err
+	}
+	if err := git.StageAll(root); err != nil {
+		return "", false, err
+	}
+	files, err := git.ListFiles(root)
+	if err != nil {
+		return "", false, err
+	}
+	if len(files) == 0 {
+		return "", false, fmt.Errorf("archive %s holds no files", filepath.Base(archivePath))
+	}
+	if latest.IsZero() {
+		latest = time.Unix(0, 0)
+	}
+	message := "Snapshot of " + filepath.Base(archivePath)
+	if err := git.CommitAs(root, snapshotAuthorName, snapshotAuthorEmail, message, latest); err != nil {
+		return "", false, err
+	}
+	return root, true, nil
+}
@@ -0,0 +1,277 @@
+// Package artifacts finds references to artifacts that a repository publishes
+// to container and package registries.
+package artifacts
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/deanhigh/bury-it/internal/git"
+	"go.yaml.in/yaml/v3"
+)
+
+// Registry names.
+const (
+	Docker   = "Docker"
+	NPM      = "npm"
+	PyPI     = "PyPI"
+	Crates   = "crates.io"
+	Homebrew = "Homebrew"
+)
+
+// Reference is an artifact that a registry may host.
+type Reference struct {
+	// Registry is the kind of registry, e.g. Docker or npm.
+	Registry string
+	// Name is the package or image name, or empty for an image built from a
+	// Dockerfile that does not name it.
+	Name string
+	// File is the path of the file the reference was found in.
+	File string
+}
+
+// Scan looks through the tracked files of a repository for published
+// artifacts: images named in goreleaser and compose files or built from
+// Dockerfiles, and npm, PyPI, crates.io, and Homebrew packages. Vendored
+// dependencies are skipped.
+func Scan(repoPath string) ([]Reference, error) {
+	files, err := git.ListFiles(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []Reference
+	seen := make(map[Reference]bool)
+	for _, file := range files {
+		if isVendored(file) {
+			continue
+		}
+		parse := parser(path.Base(file))
+		if parse == nil {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(repoPath, file))
+		if err != nil {
+			// Tracked files can be missing from a dirty working tree
+			continue
+		}
+		for _, ref := range parse(content) {
+			ref.File = file
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+
+	sort.SliceStable(refs, func(i, j int) bool {
+		return strings.ToLower(refs[i].Registry) < strings.ToLower(refs[j].Registry)
+	})
+	return refs, nil
+}
+
+// isVendored reports whether a file belongs to a vendored dependency.
+func isVendored(file string) bool {
+	for _, dir := range strings.Split(path.Dir(file), "/") {
+		if dir == "node_modules" || dir == "vendor" || dir == "third_party" {
+			return true
+		}
+	}
+	return false
+}
+
+// parser returns the function that extracts references from a file with the
+// given base name, or nil if the file is not of interest.
+func parser(base string) func([]byte) []Reference {
+	switch {
+	case base == "package.json":
+		return parsePackageJSON
+	case base == "pyproject.toml":
+		return parsePyproject
+	case base == "setup.cfg":
+		return parseSetupCfg
+	case base == "setup.py":
+		return parseSetupPy
+	case base == "Cargo.toml":
+		return parseCargo
+	case base == ".goreleaser.yml" || base == ".goreleaser.yaml" || base == "goreleaser.yml" || base == "goreleaser.yaml":
+		return parseGoreleaser
+	case base == "Dockerfile" || strings.HasPrefix(base, "Dockerfile.") || strings.HasSuffix(base, ".Dockerfile"):
+		return parseDockerfile
+	case strings.HasPrefix(base, "docker-compose") || strings.HasPrefix(base, "compose."):
+		if strings.HasSuffix(base, ".yml") || strings.HasSuffix(base, ".yaml") {
+			return parseCompose
+		}
+	}
+	return nil
+}
+
+func parsePackageJSON(content []byte) []Reference {
+	var pkg struct {
+		Name    string `json:"name"`
+		Private bool   `json:"private"`
+	}
+	if json.Unmarshal(content, &pkg) != nil || pkg.Name == "" || pkg.Private {
+		return nil
+	}
+	return []Reference{{Registry: NPM, Name: pkg.Name}}
+}
+
+func parsePyproject(content []byte) []Reference {
+	for _, section := range []string{"project", "tool.poetry"} {
+		if name := iniValue(content, section, "name"); name != "" {
+			return []Reference{{Registry: PyPI, Name: name}}
+		}
+	}
+	return nil
+}
+
+func parseSetupCfg(content []byte) []Reference {
+	if name := iniValue(content, "metadata", "name"); name != "" {
+		return []Reference{{Registry: PyPI, Name: name}}
+	}
+	return nil
+}
+
+// setupNamePattern matches the name argument of a setup() call.
+var setupNamePattern = regexp.MustCompile(`\bname\s*=\s*["']([^"']+)["']`)
+
+func parseSetupPy(content []byte) []Reference {
+	if m := setupNamePattern.FindSubmatch(content); m != nil {
+		return []Reference{{Registry: PyPI, Name: string(m[1])}}
+	}
+	return nil
+}
+
+func parseCargo(content []byte) []Reference {
+	name := iniValue(content, "package", "name")
+	if name == "" || iniValue(content, "package", "publish") == "false" {
+		return nil
+	}
+	return []Reference{{Registry: Crates, Name: name}}
+}
+
+func parseGoreleaser(content []byte) []Reference {
+	var config struct {
+		Dockers []struct {
+			ImageTemplates []string `yaml:"image_templates"`
+		} `yaml:"dockers"`
+		DockerManifests []struct {
+			NameTemplate string `yaml:"name_template"`
+		} `yaml:"docker_manifests"`
+		Kos []struct {
+			Repository string `yaml:"repository"`
+		} `yaml:"kos"`
+		Brews []struct {
+			Name string `yaml:"name"`
+		} `yaml:"brews"`
+	}
+	if yaml.Unmarshal(content, &config) != nil {
+		return nil
+	}
+
+	var refs []Reference
+	for _, d := range config.Dockers {
+		for _, image := range d.ImageTemplates {
+			refs = append(refs, Reference{Registry: Docker, Name: imageName(image)})
+		}
+	}
+	for _, m := range config.DockerManifests {
+		refs = append(refs, Reference{Registry: Docker, Name: imageName(m.NameTemplate)})
+	}
+	for _, k := range config.Kos {
+		refs = append(refs, Reference{Registry: Docker, Name: imageName(k.Repository)})
+	}
+	for _, b := range config.Brews {
+		refs = append(refs, Reference{Registry: Homebrew, Name: b.Name})
+	}
+	return refs
+}
+
+// imageLabels are the Dockerfile labels that name the image, in order of
+// preference.
+var imageLabels = []string{"org.opencontainers.image.ref.name", "org.opencontainers.image.title", "org.opencontainers.image.source"}
+
+// labelPattern matches a key=value pair of a LABEL instruction.
+var labelPattern = regexp.MustCompile(`([\w.-]+)=("[^"]*"|\S+)`)
+
+func parseDockerfile(content []byte) []Reference {
+	labels := make(map[string]string)
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "LABEL") {
+			continue
+		}
+		for _, m := range labelPattern.FindAllStringSubmatch(line, -1) {
+			labels[m[1]] = strings.Trim(m[2], `"`)
+		}
+	}
+	for _, label := range imageLabels {
+		if name := labels[label]; name != "" {
+			return []Reference{{Registry: Docker, Name: imageName(name)}}
+		}
+	}
+	return []Reference{{Registry: Docker}}
+}
+
+func parseCompose(content []byte) []Reference {
+	var compose struct {
+		Services map[string]struct {
+			Image string `yaml:"image"`
+			Build any    `yaml:"build"`
+		} `yaml:"services"`
+	}
+	if yaml.Unmarshal(content, &compose) != nil {
+		return nil
+	}
+
+	var refs []Reference
+	for _, service := range compose.Services {
+		// Only images the project builds itself are its own artifacts
+		if service.Image != "" && service.Build != nil {
+			refs = append(refs, Reference{Registry: Docker, Name: imageName(service.Image)})
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
+	return refs
+}
+
+// imageName strips the tag or template suffix from an image reference, e.g.
+// "ghcr.io/org/app:{{ .Version }}" becomes "ghcr.io/org/app".
+func imageName(image string) string {
+	image = strings.TrimPrefix(strings.TrimPrefix(image, "https://"), "http://")
+	if i := strings.Index(image, "{{"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return strings.TrimRight(image, ":-/")
+}
+
+// iniValue returns the value of key in a section of an INI or TOML file, with
+// surrounding quotes removed, or an empty string if it is not set.
+func iniValue(content []byte, section, key string) string {
+	current := ""
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = strings.TrimSpace(strings.Trim(line, "[]"))
+			continue
+		}
+		if current != section {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(k) != key {
+			continue
+		}
+		return strings.Trim(strings.TrimSpace(v), `"'`)
+	}
+	return ""
+}
@@ -0,0 +1,186 @@
+package artifacts
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParsers(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []Reference
+	}{
+		{
+			name:    "npm package",
+			file:    "package.json",
+			content: `{"name": "@org/widget", "version": "1.0.0"}`,
+			want:    []Reference{{Registry: NPM, Name: "@org/widget"}},
+		},
+		{
+			name:    "private npm package",
+			file:    "package.json",
+			content: `{"name": "internal-app", "private": true}`,
+		},
+		{
+			name:    "pyproject",
+			file:    "pyproject.toml",
+			content: "[build-system]\nrequires = [\"hatchling\"]\n\n[project]\nname = \"widget\"\nversion = \"1.0\"\n",
+			want:    []Reference{{Registry: PyPI, Name: "widget"}},
+		},
+		{
+			name:    "poetry",
+			file:    "pyproject.toml",
+			content: "[tool.poetry]\nname = 'widget-cli'\n",
+			want:    []Reference{{Registry: PyPI, Name: "widget-cli"}},
+		},
+		{
+			name:    "setup.py",
+			file:    "setup.py",
+			content: "from setuptools import setup\n\nsetup(\n    name=\"widget\",\n    version=\"1.0\",\n)\n",
+			want:    []Reference{{Registry: PyPI, Name: "widget"}},
+		},
+		{
+			name:    "setup.cfg",
+			file:    "setup.cfg",
+			content: "[metadata]\nname = widget\n\n[options]\nname = other\n",
+			want:    []Reference{{Registry: PyPI, Name: "widget"}},
+		},
+		{
+			name:    "crate",
+			file:    "Cargo.toml",
+			content: "[package]\nname = \"widget\"\n\n[dependencies]\nserde = \"1\"\n",
+			want:    []Reference{{Registry: Crates, Name: "widget"}},
+		},
+		{
+			name:    "unpublished crate",
+			file:    "Cargo.toml",
+			content: "[package]\nname = \"widget\"\npublish = false\n",
+		},
+		{
+			name: "goreleaser",
+			file: ".goreleaser.yaml",
+			content: `dockers:
+  - image_templates:
+      - "ghcr.io/org/widget:{{ .Version }}"
+      - "org/widget:latest"
+docker_manifests:
+  - name_template: "ghcr.io/org/widget:{{ .Version }}"
+brews:
+  - name: widget
+`,
+			want: []Reference{
+				{Registry: Docker, Name: "ghcr.io/org/widget"},
+				{Registry: Docker, Name: "org/widget"},
+				{Registry: Docker, Name: "ghcr.io/org/widget"},
+				{Registry: Homebrew, Name: "widget"},
+			},
+		},
+		{
+			name:    "labelled Dockerfile",
+			file:    "Dockerfile",
+			content: "FROM alpine\nLABEL org.opencontainers.image.source=\"https://github.com/org/widget\" maintainer=me\n",
+			want:    []Reference{{Registry: Docker, Name: "github.com/org/widget"}},
+		},
+		{
+			name:    "unlabelled Dockerfile",
+			file:    "Dockerfile.dev",
+			content: "FROM alpine\n",
+			want:    []Reference{{Registry: Docker}},
+		},
+		{
+			name: "compose file",
+			file: "docker-compose.yml",
+			content: `services:
+  api:
+    build: .
+    image: org/widget-api:dev
+  db:
+    image: postgres:16
+`,
+			want: []Reference{{Registry: Docker, Name: "org/widget-api"}},
+		},
+		{
+			name:    "unrelated file",
+			file:    "main.go",
+			content: "package main\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Reference
+			if parse := parser(tt.file); parse != nil {
+				got = parse([]byte(tt.content))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parse(%s) = %+v, want %+v", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageName(t *testing.T) {
+	tests := map[string]string{
+		"ghcr.io/org/app:{{ .Version }}":   "ghcr.io/org/app",
+		"ghcr.io/org/app:v{{ .Version }}":  "ghcr.io/org/app",
+		"org/app-{{ .Arch }}":              "org/app",
+		"localhost:5000/app:1.0":           "localhost:5000/app",
+		"localhost:5000/app":               "localhost:5000/app",
+		"https://github.com/org/app":       "github.com/org/app",
+		"registry.example.com/team/app:v1": "registry.example.com/team/app",
+	}
+	for in, want := range tests {
+		if got := imageName(in); got != want {
+			t.Errorf("imageName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"package.json":                  `{"name": "widget"}`,
+		"node_modules/dep/package.json": `{"name": "dep"}`,
+		"services/api/Dockerfile":       "FROM alpine\n",
+		"services/api/pyproject.toml":   "[project]\nname = \"widget-api\"\n",
+		"untracked/Cargo.toml":          "[package]\nname = \"scratch\"\n",
+		"docs/README.md":                "# widget\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-f", "package.json", "node_modules", "services", "docs"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	got, err := Scan(dir)
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	want := []Reference{
+		{Registry: Docker, File: "services/api/Dockerfile"},
+		{Registry: NPM, Name: "widget", File: "package.json"},
+		{Registry: PyPI, Name: "widget-api", File: "services/api/pyproject.toml"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Scan() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,47 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.json")
+
+	for _, content := range []string{`{"version":1}`, `{"version":2,"documents":[]}`, "{}"} {
+		if err := Write(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("content = %q, want %q", data, content)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("mode = %v, want 0644", info.Mode().Perm())
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want only the file and no temporary ones", len(entries))
+	}
+}
+
+func TestWrite_MissingDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "index.json")
+	if err := Write(path, []byte("{}"), 0644); err == nil {
+		t.Error("Write() error = nil, want an error for a missing directory")
+	}
+}
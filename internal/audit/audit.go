@@ -0,0 +1,258 @@
+// Package audit keeps an append-only log of the bury-it operations made on a
+// graveyard, committed alongside the changes they made.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/atomicfile"
+	"github.com/deanhigh/bury-it/internal/display"
+	"github.com/deanhigh/bury-it/internal/git"
+)
+
+// Dir is the graveyard directory holding the log. It is shared with the
+// search index.
+const Dir = ".bury-it"
+
+// FileName is the name of the log file within Dir.
+const FileName = "audit.log"
+
+// attributesFile is the graveyard's git attributes file.
+const attributesFile = ".gitattributes"
+
+// mergeAttribute hands the log to git's union merge, so that clones that
+// each logged operations merge without conflict, keeping the lines of both.
+const mergeAttribute = "/" + Dir + "/" + FileName + " merge=union"
+
+// Entry is a single logged operation.
+type Entry struct {
+	// Time is when the operation was recorded.
+	Time time.Time `json:"time"`
+	// User is the git identity (user.email) of whoever ran the operation.
+	User string `json:"user"`
+	// Version is the bury-it version that ran it.
+	Version string `json:"version"`
+	// Operation is the bury-it command, e.g. "bury" or "checklist tick".
+	Operation string `json:"operation"`
+	// Project is the buried project the operation changed, if any.
+	Project string `json:"project,omitempty"`
+	// Args are the command-line arguments the command was run with.
+	Args []string `json:"args"`
+}
+
+// invocation describes the running command, set by SetInvocation.
+var invocation *Entry
+
+// SetInvocation records the command being run, so that Record can log it.
+// Until it is called, Record does nothing, which keeps library callers and
+// tests from writing to the log. As the log is committed and pushed with the
+// graveyard, the arguments are recorded with secrets removed.
+func SetInvocation(operation, version string, args []string) {
+	invocation = &Entry{Operation: operation, Version: version, Args: redactArgs(args)}
+}
+
+// secretFlagWords mark flags whose values are secrets, such as --token or
+// --api-password, wherever they appear in the flag's name.
+var secretFlagWords = []string{"token", "password", "secret", "credential"}
+
+// redacted replaces a secret in the logged arguments.
+const redacted = "[redacted]"
+
+// redactArgs returns args with the values of secret-carrying flags replaced,
+// and credentials removed from URLs.
+func redactArgs(args []string) []string {
+	out := make([]string, len(args))
+	secretNext := false
+	for i, arg := range args {
+		switch {
+		case secretNext:
+			out[i] = redacted
+			secretNext = false
+		case strings.HasPrefix(arg, "-"):
+			flag, value, hasValue := strings.Cut(arg, "=")
+			secret := isSecretFlag(flag)
+			switch {
+			case secret && hasValue:
+				out[i] = flag + "=" + redacted
+			case secret:
+				out[i] = arg
+				secretNext = true
+			case hasValue:
+				out[i] = flag + "=" + display.URL(value)
+			default:
+				out[i] = arg
+			}
+		default:
+			out[i] = display.URL(arg)
+		}
+	}
+	return out
+}
+
+// isSecretFlag reports whether the flag, with its dashes, carries a secret.
+func isSecretFlag(flag string) bool {
+	name := strings.ToLower(strings.TrimLeft(flag, "-"))
+	for _, word := range secretFlagWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// Path returns the location of the log in the given graveyard.
+func Path(graveyardPath string) string {
+	return filepath.Join(graveyardPath, Dir, FileName)
+}
+
+// Record appends an entry for the running command and project to the
+// graveyard's log and stages it, to be committed with the operation's
+// changes.
+func Record(graveyardPath, project string) error {
+	if invocation == nil {
+		return nil
+	}
+	e := *invocation
+	e.Time = time.Now().UTC()
+	e.User = identity(graveyardPath)
+	e.Project = project
+	if err := ensureUnionMerge(graveyardPath); err != nil {
+		return err
+	}
+	if err := Append(graveyardPath, e); err != nil {
+		return err
+	}
+	if err := git.StageFile(graveyardPath, filepath.Join(Dir, FileName)); err != nil {
+		return fmt.Errorf("failed to stage audit log: %w", err)
+	}
+	return nil
+}
+
+// ensureUnionMerge adds mergeAttribute to the graveyard's .gitattributes,
+// and stages it, unless it is already there.
+func ensureUnionMerge(graveyardPath string) error {
+	path := filepath.Join(graveyardPath, attributesFile)
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", attributesFile, err)
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.TrimSpace(line) == mergeAttribute {
+			return nil
+		}
+	}
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		content = append(content, '\n')
+	}
+	content = append(content, mergeAttribute+"\n"...)
+	if err := atomicfile.Write(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", attributesFile, err)
+	}
+	if err := git.StageFile(graveyardPath, attributesFile); err != nil {
+		return fmt.Errorf("failed to stage %s: %w", attributesFile, err)
+	}
+	return nil
+}
+
+// identity returns the git identity of the user, or their login name if git
+// has none configured.
+func identity(repoPath string) string {
+	if id, err := git.Identity(repoPath); err == nil {
+		return id
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "unknown"
+}
+
+// Append writes an entry to the end of the graveyard's log.
+func Append(graveyardPath string, e Entry) error {
+	path := Path(graveyardPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// Read returns the entries of the graveyard's log, oldest first. A graveyard
+// without a log has no entries.
+func Read(graveyardPath string) ([]Entry, error) {
+	f, err := os.Open(Path(graveyardPath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("audit log line %d: %w", n, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	// A merge of clones keeps each side's lines together, not in time order
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	return entries, nil
+}
+
+// Filter selects log entries. Empty fields match everything.
+type Filter struct {
+	// Project matches entries for this project.
+	Project string
+	// User matches entries whose user contains this text, ignoring case.
+	User string
+	// Operation matches entries whose operation starts with this text.
+	Operation string
+	// Since matches entries recorded at or after this time.
+	Since time.Time
+}
+
+// Match reports whether an entry is selected by the filter.
+func (f Filter) Match(e Entry) bool {
+	if f.Project != "" && e.Project != f.Project {
+		return false
+	}
+	if f.User != "" && !strings.Contains(strings.ToLower(e.User), strings.ToLower(f.User)) {
+		return false
+	}
+	if f.Operation != "" && !strings.HasPrefix(e.Operation, f.Operation) {
+		return false
+	}
+	return f.Since.IsZero() || !e.Time.Before(f.Since)
+}
@@ -0,0 +1,468 @@
+// Package backup bundles an entire graveyard, its git repository included,
+// into a single portable archive with an integrity manifest, and restores
+// such archives. It also takes incremental backups of a graveyard to a
+// directory, rsync destination, or S3 bucket, and restores them.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ManifestName is the name of the manifest entry in an archive.
+const ManifestName = "manifest.json"
+
+// rootDir is the directory in an archive holding the graveyard's files.
+const rootDir = "graveyard"
+
+// FormatVersion is the version of the archive layout.
+const FormatVersion = 1
+
+// Manifest describes the contents of an archive.
+type Manifest struct {
+	// Format is the version of the archive layout.
+	Format int `json:"format"`
+	// CreatedAt is when the archive was written.
+	CreatedAt time.Time `json:"created_at"`
+	// BuryItVersion is the version of bury-it that wrote the archive.
+	BuryItVersion string `json:"bury_it_version"`
+	// Head is the commit the graveyard was at.
+	Head string `json:"head"`
+	// Projects are the buried projects in the graveyard.
+	Projects []string `json:"projects"`
+	// Files lists every file and symlink of the graveyard, .git included.
+	Files []File `json:"files"`
+}
+
+// File is a single entry of the manifest.
+type File struct {
+	// Path is the slash-separated path relative to the graveyard.
+	Path string `json:"path"`
+	// Size is the size of a regular file in bytes.
+	Size int64 `json:"size"`
+	// SHA256 is the hex digest of a regular file's content.
+	SHA256 string `json:"sha256,omitempty"`
+	// Link is the target of a symlink.
+	Link string `json:"link,omitempty"`
+}
+
+// Size returns the total size of the files in the manifest in bytes.
+func (m *Manifest) Size() int64 {
+	var total int64
+	for _, f := range m.Files {
+		total += f.Size
+	}
+	return total
+}
+
+// ExportFile writes the graveyard to an archive at path, compressed according
+// to its extension: .tar.zst or .tzst for zstd, .tar.gz or .tgz for gzip, and
+// .tar for none.
+func ExportFile(gy *graveyard.Graveyard, path, version string) (*Manifest, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if rel, err := filepath.Rel(gy.Path, abs); err == nil && filepath.IsLocal(rel) {
+		return nil, fmt.Errorf("archive cannot be written inside the graveyard: %s", path)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("archive already exists: %s", path)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive: %w", err)
+	}
+	manifest, err := exportCompressed(gy, f, path, version)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write archive: %w", closeErr)
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// exportCompressed writes the archive to w through the compressor chosen by
+// name.
+func exportCompressed(gy *graveyard.Graveyard, w io.Writer, name, version string) (*Manifest, error) {
+	var cw io.WriteCloser
+	switch compression(name) {
+	case "zstd":
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, err
+		}
+		cw = zw
+	case "gzip":
+		cw = gzip.NewWriter(w)
+	case "none":
+		return Export(gy, w, version)
+	default:
+		return nil, fmt.Errorf("unsupported archive extension %q: use .tar.zst, .tar.gz, or .tar", filepath.Ext(name))
+	}
+	manifest, err := Export(gy, cw, version)
+	if closeErr := cw.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to compress archive: %w", closeErr)
+	}
+	return manifest, err
+}
+
+// compression returns the compression implied by an archive name.
+func compression(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".tar.zst"), strings.HasSuffix(name, ".tzst"):
+		return "zstd"
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return "gzip"
+	case strings.HasSuffix(name, ".tar"):
+		return "none"
+	}
+	return ""
+}
+
+// Export writes the graveyard to w as an uncompressed tar archive. The
+// graveyard must have no uncommitted changes, so that the archive matches
+// its HEAD.
+func Export(gy *graveyard.Graveyard, w io.Writer, version string) (*Manifest, error) {
+	clean, err := git.IsClean(gy.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !clean {
+		return nil, fmt.Errorf("graveyard has uncommitted changes; commit or discard them first")
+	}
+	head, err := git.Head(gy.Path)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := gy.Projects()
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{
+		Format:        FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		BuryItVersion: version,
+		Head:          head,
+		Projects:      projects,
+		Files:         []File{},
+	}
+	tw := tar.NewWriter(w)
+	err = filepath.WalkDir(gy.Path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(gy.Path, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		file, err := writeEntry(tw, p, filepath.ToSlash(rel), info)
+		if err != nil {
+			return fmt.Errorf("failed to archive %s: %w", rel, err)
+		}
+		if file != nil {
+			manifest.Files = append(manifest.Files, *file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	hdr := &tar.Header{Name: ManifestName, Mode: 0644, Size: int64(len(data)), ModTime: manifest.CreatedAt}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// writeEntry writes a single graveyard path to the archive and returns its
+// manifest entry, or nil for directories and other entries without content.
+func writeEntry(tw *tar.Writer, p, rel string, info fs.FileInfo) (*File, error) {
+	name := path.Join(rootDir, rel)
+	switch mode := info.Mode(); {
+	case mode.IsDir():
+		return nil, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: name + "/", Mode: int64(mode.Perm()), ModTime: info.ModTime()})
+	case mode&fs.ModeSymlink != 0:
+		target, err := os.Readlink(p)
+		if err != nil {
+			return nil, err
+		}
+		hdr := &tar.Header{Typeflag: tar.TypeSymlink, Name: name, Linkname: target, Mode: 0777, ModTime: info.ModTime()}
+		return &File{Path: rel, Link: target}, tw.WriteHeader(hdr)
+	case mode.IsRegular():
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = f.Close() }()
+		hdr := &tar.Header{Typeflag: tar.TypeReg, Name: name, Size: info.Size(), Mode: int64(mode.Perm()), ModTime: info.ModTime()}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		h := sha256.New()
+		if _, err := io.Copy(io.MultiWriter(tw, h), f); err != nil {
+			return nil, err
+		}
+		return &File{Path: rel, Size: info.Size(), SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+	}
+	// Sockets, devices, and the like cannot be restored; leave them out
+	return nil, nil
+}
+
+// ImportFile restores the archive at path into dest, which must not exist
+// or be empty. The compression is chosen by the archive's extension.
+func ImportFile(path, dest string) (*Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var r io.Reader = f
+	switch compression(path) {
+	case "zstd":
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	case "gzip":
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		defer func() { _ = gr.Close() }()
+		r = gr
+	case "none":
+	default:
+		return nil, fmt.Errorf("unsupported archive extension %q: use .tar.zst, .tar.gz, or .tar", filepath.Ext(path))
+	}
+	return Import(r, dest)
+}
+
+// Import restores an uncompressed archive from r into dest, which must not
+// exist or be empty. Every file is checked against the manifest and the
+// restored repository against its recorded HEAD; on any mismatch nothing is
+// left behind.
+func Import(r io.Reader, dest string) (*Manifest, error) {
+	dest, err := filepath.Abs(dest)
+	if err != nil {
+		return nil, err
+	}
+	if entries, err := os.ReadDir(dest); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("destination is not empty: %s", dest)
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	// Restore next to the destination and move into place once verified
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dest), "."+filepath.Base(dest)+"-import-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create restore directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	manifest, got, err := extract(r, tmp)
+	if err != nil {
+		return nil, err
+	}
+	if err := verify(manifest, got); err != nil {
+		return nil, err
+	}
+	head, err := git.Head(tmp)
+	if err != nil {
+		return nil, err
+	}
+	if head != manifest.Head {
+		return nil, fmt.Errorf("restored HEAD %s does not match the manifest's %s", head, manifest.Head)
+	}
+
+	if err := os.Chmod(tmp, 0755); err != nil {
+		return nil, err
+	}
+	_ = os.Remove(dest)
+	if err := os.Rename(tmp, dest); err != nil {
+		return nil, fmt.Errorf("failed to move restored graveyard into place: %w", err)
+	}
+	return manifest, nil
+}
+
+// extract unpacks the archive into dir and returns its manifest and the
+// manifest entries of what was actually unpacked. Everything is written
+// through a root on dir, which refuses to follow links out of it, and
+// symlinks are created last, so no file is written through one. A symlink
+// whose own path goes through another is refused, as a graveyard never holds
+// one.
+func extract(r io.Reader, dir string) (*Manifest, map[string]File, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = root.Close() }()
+
+	var manifest *Manifest
+	got := map[string]File{}
+	var links []File
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if hdr.Name == ManifestName {
+			manifest = &Manifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, nil, fmt.Errorf("invalid manifest: %w", err)
+			}
+			continue
+		}
+		rel, ok := strings.CutPrefix(path.Clean(hdr.Name), rootDir+"/")
+		if !ok || !fs.ValidPath(rel) {
+			return nil, nil, fmt.Errorf("unexpected archive entry %q", hdr.Name)
+		}
+		target := filepath.FromSlash(rel)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := root.MkdirAll(target, 0755); err != nil {
+				return nil, nil, err
+			}
+		case tar.TypeSymlink:
+			links = append(links, File{Path: rel, Link: hdr.Linkname})
+		case tar.TypeReg:
+			file, err := extractFile(root, tr, target, rel, hdr)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to restore %s: %w", rel, err)
+			}
+			got[rel] = *file
+		default:
+			return nil, nil, fmt.Errorf("unsupported archive entry %q", hdr.Name)
+		}
+	}
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("archive has no %s; it was not written by bury-it graveyard export", ManifestName)
+	}
+	if manifest.Format > FormatVersion {
+		return nil, nil, fmt.Errorf("archive format %d is newer than this bury-it supports (%d)", manifest.Format, FormatVersion)
+	}
+	for _, link := range links {
+		target := filepath.FromSlash(link.Path)
+		if err := checkNoLinkIn(root, filepath.Dir(target)); err != nil {
+			return nil, nil, fmt.Errorf("unexpected archive entry %q: %w", link.Path, err)
+		}
+		if err := root.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, nil, err
+		}
+		if err := root.Symlink(link.Link, target); err != nil {
+			return nil, nil, fmt.Errorf("failed to restore %s: %w", link.Path, err)
+		}
+		got[link.Path] = link
+	}
+	return manifest, got, nil
+}
+
+// checkNoLinkIn returns an error if any directory on the path dir, relative
+// to root, is a symlink.
+func checkNoLinkIn(root *os.Root, dir string) error {
+	for d := dir; d != "."; d = filepath.Dir(d) {
+		info, err := root.Lstat(d)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a symlink", filepath.ToSlash(d))
+		}
+	}
+	return nil
+}
+
+// extractFile writes a regular file from the archive into root and hashes it.
+func extractFile(root *os.Root, r io.Reader, target, rel string, hdr *tar.Header) (*File, error) {
+	if err := root.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return nil, err
+	}
+	f, err := root.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fs.FileMode(hdr.Mode).Perm())
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &File{Path: rel, Size: n, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// verify checks that the unpacked files are exactly those of the manifest.
+func verify(manifest *Manifest, got map[string]File) error {
+	var problems []string
+	for _, want := range manifest.Files {
+		file, ok := got[want.Path]
+		switch {
+		case !ok:
+			problems = append(problems, "missing "+want.Path)
+		case file != want:
+			problems = append(problems, "corrupt "+want.Path)
+		}
+		delete(got, want.Path)
+	}
+	for rel := range got {
+		problems = append(problems, "unexpected "+rel)
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	if len(problems) > 10 {
+		problems = append(problems[:10], fmt.Sprintf("and %d more", len(problems)-10))
+	}
+	return fmt.Errorf("archive does not match its manifest: %s", strings.Join(problems, ", "))
+}
@@ -0,0 +1,263 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/metadata"
+)
+
+func TestExportImportFile(t *testing.T) {
+	gy := newGraveyard(t)
+
+	for _, name := range []string{"graveyard.tar.zst", "graveyard.tgz", "graveyard.tar"} {
+		t.Run(name, func(t *testing.T) {
+			archive := filepath.Join(t.TempDir(), name)
+			manifest, err := ExportFile(gy, archive, "1.2.3")
+			if err != nil {
+				t.Fatalf("ExportFile() error = %v", err)
+			}
+			if len(manifest.Projects) != 1 || manifest.Projects[0] != "old-experiment" {
+				t.Errorf("Projects = %v, want [old-experiment]", manifest.Projects)
+			}
+			if manifest.BuryItVersion != "1.2.3" || manifest.Head == "" {
+				t.Errorf("manifest = %+v, want version and head", manifest)
+			}
+
+			dest := filepath.Join(t.TempDir(), "restored")
+			restored, err := ImportFile(archive, dest)
+			if err != nil {
+				t.Fatalf("ImportFile() error = %v", err)
+			}
+			if restored.Head != manifest.Head || len(restored.Files) != len(manifest.Files) {
+				t.Errorf("ImportFile() manifest = %+v, want %+v", restored, manifest)
+			}
+			for _, rel := range []string{"old-experiment/main.go", "old-experiment/" + metadata.FileName, ".bury-it/index.json"} {
+				want, _ := os.ReadFile(filepath.Join(gy.Path, rel))
+				got, err := os.ReadFile(filepath.Join(dest, rel))
+				if err != nil || !bytes.Equal(got, want) {
+					t.Errorf("restored %s = %q, %v, want %q", rel, got, err, want)
+				}
+			}
+			if info, err := os.Stat(filepath.Join(dest, "old-experiment", "run.sh")); err != nil || info.Mode().Perm()&0100 == 0 {
+				t.Errorf("restored run.sh lost its executable bit: %v, %v", info, err)
+			}
+			if link, err := os.Readlink(filepath.Join(dest, "old-experiment", "latest")); err != nil || link != "main.go" {
+				t.Errorf("restored symlink = %q, %v, want main.go", link, err)
+			}
+			restoredGy, err := graveyard.New(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := restoredGy.Validate(); err != nil {
+				t.Errorf("restored graveyard is invalid: %v", err)
+			}
+
+			if _, err := ImportFile(archive, dest); err == nil {
+				t.Errorf("ImportFile() into a non-empty destination expected error")
+			}
+			if _, err := ExportFile(gy, archive, "1.2.3"); err == nil {
+				t.Errorf("ExportFile() over an existing archive expected error")
+			}
+		})
+	}
+
+	if _, err := ExportFile(gy, filepath.Join(t.TempDir(), "graveyard.zip"), "dev"); err == nil {
+		t.Errorf("ExportFile() with an unknown extension expected error")
+	}
+	if _, err := ExportFile(gy, filepath.Join(gy.Path, "backup.tar"), "dev"); err == nil {
+		t.Errorf("ExportFile() inside the graveyard expected error")
+	}
+}
+
+func TestExport_Dirty(t *testing.T) {
+	gy := newGraveyard(t)
+	writeFile(t, filepath.Join(gy.Path, "scratch.txt"), "scratch")
+	if _, err := Export(gy, io.Discard, "dev"); err == nil || !strings.Contains(err.Error(), "uncommitted") {
+		t.Errorf("Export() error = %v, want uncommitted changes error", err)
+	}
+}
+
+func TestImport_Tampered(t *testing.T) {
+	gy := newGraveyard(t)
+	var buf bytes.Buffer
+	if _, err := Export(gy, &buf, "dev"); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		rewrite func(hdr *tar.Header, content []byte) (*tar.Header, []byte)
+		wantErr string
+	}{
+		{
+			name: "corrupt file",
+			rewrite: func(hdr *tar.Header, content []byte) (*tar.Header, []byte) {
+				if hdr.Name == "graveyard/old-experiment/main.go" {
+					content = []byte("package tampered\n")
+					hdr.Size = int64(len(content))
+				}
+				return hdr, content
+			},
+			wantErr: "corrupt old-experiment/main.go",
+		},
+		{
+			name: "missing file",
+			rewrite: func(hdr *tar.Header, content []byte) (*tar.Header, []byte) {
+				if hdr.Name == "graveyard/old-experiment/main.go" {
+					return nil, nil
+				}
+				return hdr, content
+			},
+			wantErr: "missing old-experiment/main.go",
+		},
+		{
+			name: "no manifest",
+			rewrite: func(hdr *tar.Header, content []byte) (*tar.Header, []byte) {
+				if hdr.Name == ManifestName {
+					return nil, nil
+				}
+				return hdr, content
+			},
+			wantErr: "no manifest.json",
+		},
+		{
+			name: "path outside the graveyard",
+			rewrite: func(hdr *tar.Header, content []byte) (*tar.Header, []byte) {
+				if hdr.Name == "graveyard/old-experiment/main.go" {
+					hdr.Name = "graveyard/../../escaped.go"
+				}
+				return hdr, content
+			},
+			wantErr: "unexpected archive entry",
+		},
+		{
+			// Each link stays inside the graveyard, but a link made through
+			// both would land next to it
+			name: "link through other links",
+			rewrite: func(hdr *tar.Header, content []byte) (*tar.Header, []byte) {
+				link := func(name, target string) (*tar.Header, []byte) {
+					return &tar.Header{Typeflag: tar.TypeSymlink, Name: "graveyard/old-experiment/" + name, Linkname: target, Mode: 0777}, nil
+				}
+				switch hdr.Name {
+				case "graveyard/old-experiment/latest":
+					return link("up", "..")
+				case "graveyard/old-experiment/main.go":
+					return link("up/up", "..")
+				case "graveyard/old-experiment/run.sh":
+					return link("up/up/escaped", "/etc/passwd")
+				}
+				return hdr, content
+			},
+			wantErr: "is a symlink",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := rewriteArchive(t, buf.Bytes(), tt.rewrite)
+			dest := filepath.Join(t.TempDir(), "restored")
+			_, err := Import(bytes.NewReader(archive), dest)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Import() error = %v, want %q", err, tt.wantErr)
+			}
+			if _, err := os.Stat(dest); !os.IsNotExist(err) {
+				t.Errorf("Import() left %s behind after failing", dest)
+			}
+			entries, _ := os.ReadDir(filepath.Dir(dest))
+			if len(entries) != 0 {
+				t.Errorf("Import() left %d entries behind after failing", len(entries))
+			}
+		})
+	}
+}
+
+// newGraveyard creates a committed graveyard with one buried project holding
+// an executable and a symlink.
+func newGraveyard(t *testing.T) *graveyard.Graveyard {
+	t.Helper()
+	dir := t.TempDir()
+	meta := &metadata.Metadata{OriginalSource: "/src/old-experiment", BuriedAt: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)}
+	writeFile(t, filepath.Join(dir, "README.md"), "graveyard\n")
+	writeFile(t, filepath.Join(dir, "old-experiment", metadata.FileName), meta.Generate())
+	writeFile(t, filepath.Join(dir, "old-experiment", "main.go"), "package main\n")
+	writeFile(t, filepath.Join(dir, "old-experiment", "run.sh"), "#!/bin/sh\n")
+	writeFile(t, filepath.Join(dir, ".bury-it", "index.json"), "{}\n")
+	if err := os.Chmod(filepath.Join(dir, "old-experiment", "run.sh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("main.go", filepath.Join(dir, "old-experiment", "latest")); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "init", "-q")
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "-c", "user.email=t@t", "-c", "user.name=T", "commit", "-qm", "init")
+
+	gy, err := graveyard.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gy
+}
+
+// rewriteArchive copies a tar archive, passing each entry through rewrite,
+// which may drop it by returning a nil header.
+func rewriteArchive(t *testing.T, data []byte, rewrite func(*tar.Header, []byte) (*tar.Header, []byte)) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	tr := tar.NewReader(bytes.NewReader(data))
+	tw := tar.NewWriter(&out)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hdr, content = rewrite(hdr, content)
+		if hdr == nil {
+			continue
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
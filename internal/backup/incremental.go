@@ -0,0 +1,500 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/atomicfile"
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/state"
+)
+
+// CatalogName is the name of the catalog file in a backup target.
+const CatalogName = "catalog.json"
+
+// CatalogFormat is the version of the backup target layout.
+const CatalogFormat = 1
+
+// stateFile is the local state file recording the latest backup of each
+// graveyard.
+const stateFile = "backups.json"
+
+// Catalog lists the incremental backups kept in a target. It is written
+// after everything it refers to, so it only ever lists complete backups.
+type Catalog struct {
+	// Format is the version of the target layout.
+	Format int `json:"format"`
+	// Backups are the backups in the order they were taken.
+	Backups []Increment `json:"backups"`
+}
+
+// Increment is a single incremental backup.
+type Increment struct {
+	// Number counts the backups of the target, starting at 1.
+	Number int `json:"number"`
+	// CreatedAt is when the backup was taken.
+	CreatedAt time.Time `json:"created_at"`
+	// BuryItVersion is the version of bury-it that took the backup.
+	BuryItVersion string `json:"bury_it_version"`
+	// Head is the commit the graveyard was at.
+	Head string `json:"head"`
+	// Branch is the full name of the checked-out branch.
+	Branch string `json:"branch"`
+	// Refs are the objects every branch and tag pointed to.
+	Refs map[string]string `json:"refs"`
+	// Bundle is the git bundle holding the objects new since the previous
+	// backup, or empty if there were none.
+	Bundle string `json:"bundle,omitempty"`
+	// BundleSHA256 is the hex digest of the bundle.
+	BundleSHA256 string `json:"bundle_sha256,omitempty"`
+	// Files are the files of the graveyard that git does not track, each
+	// stored once under its digest.
+	Files []File `json:"files"`
+	// Uploaded is how many bytes this backup added to the target.
+	Uploaded int64 `json:"uploaded"`
+}
+
+// Latest returns the most recent backup, or nil if there are none.
+func (c *Catalog) Latest() *Increment {
+	if len(c.Backups) == 0 {
+		return nil
+	}
+	return &c.Backups[len(c.Backups)-1]
+}
+
+// bundleName returns the name of a backup's bundle in the target.
+func bundleName(number int) string {
+	return fmt.Sprintf("bundles/%04d.bundle", number)
+}
+
+// blobName returns the name a file with the given digest is stored under.
+func blobName(sha string) string {
+	return path.Join("files", sha)
+}
+
+// ReadCatalog reads the catalog of a target. A target without one has an
+// empty catalog.
+func ReadCatalog(target Target) (*Catalog, error) {
+	tmp, err := os.MkdirTemp("", "bury-it-backup-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	local := filepath.Join(tmp, CatalogName)
+	if err := target.Get(CatalogName, local); errors.Is(err, fs.ErrNotExist) {
+		return &Catalog{Format: CatalogFormat, Backups: []Increment{}}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read backup catalog: %w", err)
+	}
+	data, err := os.ReadFile(local)
+	if err != nil {
+		return nil, err
+	}
+	var catalog Catalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("invalid backup catalog: %w", err)
+	}
+	if catalog.Format > CatalogFormat {
+		return nil, fmt.Errorf("backup format %d is newer than this bury-it supports (%d)", catalog.Format, CatalogFormat)
+	}
+	return &catalog, nil
+}
+
+// Backup takes an incremental backup of the graveyard into target: a git
+// bundle of the objects new since the previous backup, the untracked files
+// whose content the target does not hold yet, and a new catalog entry. It
+// returns nil if nothing changed since the previous backup.
+//
+// Only committed changes are backed up; uncommitted changes to tracked files
+// are left out.
+func Backup(gy *graveyard.Graveyard, target Target, version string) (*Increment, error) {
+	catalog, err := ReadCatalog(target)
+	if err != nil {
+		return nil, err
+	}
+	prev := catalog.Latest()
+
+	head, err := git.Head(gy.Path)
+	if err != nil {
+		return nil, err
+	}
+	branch, err := git.CurrentBranch(gy.Path)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := git.RefTips(gy.Path)
+	if err != nil {
+		return nil, err
+	}
+	files, err := otherFiles(gy.Path)
+	if err != nil {
+		return nil, err
+	}
+	inc := &Increment{
+		Number:        len(catalog.Backups) + 1,
+		CreatedAt:     time.Now().UTC(),
+		BuryItVersion: version,
+		Head:          head,
+		Branch:        "refs/heads/" + branch,
+		Refs:          refs,
+		Files:         files,
+	}
+	if prev != nil && prev.Head == inc.Head && prev.Branch == inc.Branch &&
+		sameRefs(prev.Refs, inc.Refs) && sameFiles(prev.Files, inc.Files) {
+		return nil, nil
+	}
+
+	tmp, err := os.MkdirTemp("", "bury-it-backup-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	if err := backupObjects(gy.Path, target, prev, inc, tmp); err != nil {
+		return nil, err
+	}
+	if err := backupFiles(gy.Path, target, catalog, inc); err != nil {
+		return nil, err
+	}
+
+	catalog.Format = CatalogFormat
+	catalog.Backups = append(catalog.Backups, *inc)
+	data, err := json.MarshalIndent(catalog, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	local := filepath.Join(tmp, CatalogName)
+	if err := atomicfile.Write(local, append(data, '\n'), 0644); err != nil {
+		return nil, err
+	}
+	if err := target.Put(local, CatalogName); err != nil {
+		return nil, fmt.Errorf("failed to write backup catalog: %w", err)
+	}
+	return inc, nil
+}
+
+// backupObjects bundles the objects that are new since the previous backup,
+// if any. When the previous backup's objects are gone, as after a compaction
+// rewrote history, everything is bundled again.
+func backupObjects(repo string, target Target, prev *Increment, inc *Increment, tmp string) error {
+	var exclude []string
+	if prev != nil {
+		for _, hash := range prev.Refs {
+			if !git.HasObject(repo, hash) {
+				exclude = nil
+				break
+			}
+			exclude = append(exclude, hash)
+		}
+		sort.Strings(exclude)
+	}
+	if len(exclude) > 0 {
+		changed, err := git.HasNewObjects(repo, exclude)
+		if err != nil || !changed {
+			return err
+		}
+	}
+
+	local := filepath.Join(tmp, "backup.bundle")
+	if err := git.CreateBundle(repo, local, exclude); err != nil {
+		return err
+	}
+	sha, size, err := hashFile(local)
+	if err != nil {
+		return err
+	}
+	inc.Bundle = bundleName(inc.Number)
+	inc.BundleSHA256 = sha
+	if err := target.Put(local, inc.Bundle); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", inc.Bundle, err)
+	}
+	inc.Uploaded += size
+	return nil
+}
+
+// backupFiles uploads the untracked files whose content no earlier backup
+// stored.
+func backupFiles(repo string, target Target, catalog *Catalog, inc *Increment) error {
+	stored := make(map[string]bool)
+	for _, b := range catalog.Backups {
+		for _, f := range b.Files {
+			stored[f.SHA256] = true
+		}
+	}
+	for _, f := range inc.Files {
+		if stored[f.SHA256] {
+			continue
+		}
+		if err := target.Put(filepath.Join(repo, filepath.FromSlash(f.Path)), blobName(f.SHA256)); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", f.Path, err)
+		}
+		stored[f.SHA256] = true
+		inc.Uploaded += f.Size
+	}
+	return nil
+}
+
+// otherFiles returns the manifest entries of the regular files git does not
+// track.
+func otherFiles(repo string) ([]File, error) {
+	names, err := git.OtherFiles(repo)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	files := []File{}
+	for _, name := range names {
+		p := filepath.Join(repo, filepath.FromSlash(name))
+		if info, err := os.Lstat(p); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		sha, size, err := hashFile(p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Path: name, Size: size, SHA256: sha})
+	}
+	return files, nil
+}
+
+// hashFile returns the hex SHA-256 digest and size of a file.
+func hashFile(p string) (string, int64, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+func sameRefs(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for ref, hash := range a {
+		if b[ref] != hash {
+			return false
+		}
+	}
+	return true
+}
+
+func sameFiles(a, b []File) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Record is the local record of a graveyard's latest backup.
+type Record struct {
+	// Target is the backup target as it was given.
+	Target string `json:"target"`
+	// Number is the number of the latest backup in the target.
+	Number int `json:"number"`
+	// Head is the commit the graveyard was at.
+	Head string `json:"head"`
+	// CreatedAt is when the backup was taken.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LastBackup returns the local record of the graveyard's latest backup, or
+// nil if it has never been backed up.
+func LastBackup(graveyardPath string) (*Record, error) {
+	records := map[string]*Record{}
+	if err := state.Load(stateFile, &records); err != nil {
+		return nil, err
+	}
+	return records[graveyardPath], nil
+}
+
+// RecordBackup records a backup of the graveyard as its latest.
+func RecordBackup(graveyardPath string, target Target, inc *Increment) error {
+	records := map[string]*Record{}
+	if err := state.Load(stateFile, &records); err != nil {
+		return err
+	}
+	records[graveyardPath] = &Record{
+		Target:    target.String(),
+		Number:    inc.Number,
+		Head:      inc.Head,
+		CreatedAt: inc.CreatedAt,
+	}
+	return state.Save(stateFile, records)
+}
+
+// Restore restores the graveyard as of a backup in target into dest, which
+// must not exist or be empty. A number of 0 restores the latest backup. The
+// bundles of every backup up to it are applied in turn and each downloaded
+// file is checked against its digest; on any failure nothing is left behind.
+func Restore(target Target, dest string, number int) (*Increment, error) {
+	catalog, err := ReadCatalog(target)
+	if err != nil {
+		return nil, err
+	}
+	if len(catalog.Backups) == 0 {
+		return nil, fmt.Errorf("no backups found in %s", target)
+	}
+	if number == 0 {
+		number = catalog.Latest().Number
+	}
+	if number < 1 || number > len(catalog.Backups) {
+		return nil, fmt.Errorf("no backup %d in %s (it has 1 to %d)", number, target, len(catalog.Backups))
+	}
+	inc := &catalog.Backups[number-1]
+
+	dest, err = filepath.Abs(dest)
+	if err != nil {
+		return nil, err
+	}
+	if entries, err := os.ReadDir(dest); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("destination is not empty: %s", dest)
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	// Restore next to the destination and move into place once verified
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dest), "."+filepath.Base(dest)+"-restore-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create restore directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+	download, err := os.MkdirTemp("", "bury-it-restore-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(download) }()
+
+	repo := filepath.Join(tmp, "graveyard")
+	if err := os.Mkdir(repo, 0755); err != nil {
+		return nil, err
+	}
+	if err := git.Init(repo); err != nil {
+		return nil, err
+	}
+	for _, b := range catalog.Backups[:number] {
+		if b.Bundle == "" {
+			continue
+		}
+		local := filepath.Join(download, path.Base(b.Bundle))
+		if err := fetch(target, b.Bundle, b.BundleSHA256, local); err != nil {
+			return nil, err
+		}
+		if err := git.Unbundle(repo, local); err != nil {
+			return nil, fmt.Errorf("failed to apply %s: %w", b.Bundle, err)
+		}
+	}
+	refs := make([]string, 0, len(inc.Refs))
+	for ref := range inc.Refs {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	for _, ref := range refs {
+		if err := git.UpdateRef(repo, ref, inc.Refs[ref], ""); err != nil {
+			return nil, err
+		}
+	}
+	if err := git.SetHead(repo, inc.Branch); err != nil {
+		return nil, err
+	}
+	if err := git.ResetHard(repo, "HEAD"); err != nil {
+		return nil, err
+	}
+	head, err := git.Head(repo)
+	if err != nil {
+		return nil, err
+	}
+	if head != inc.Head {
+		return nil, fmt.Errorf("restored HEAD %s does not match backup %d's %s", head, inc.Number, inc.Head)
+	}
+
+	// Untracked files are written through a root on the repository, so that
+	// a tracked symlink cannot lead them out of it
+	root, err := os.OpenRoot(repo)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = root.Close() }()
+	for _, f := range inc.Files {
+		first, _, _ := strings.Cut(f.Path, "/")
+		if !fs.ValidPath(f.Path) || strings.EqualFold(first, ".git") {
+			return nil, fmt.Errorf("unexpected file %q in backup %d", f.Path, inc.Number)
+		}
+		local := filepath.Join(download, path.Base(blobName(f.SHA256)))
+		if err := fetch(target, blobName(f.SHA256), f.SHA256, local); err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", f.Path, err)
+		}
+		if err := copyInto(root, local, filepath.FromSlash(f.Path)); err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", f.Path, err)
+		}
+	}
+
+	_ = os.Remove(dest)
+	if err := os.Rename(repo, dest); err != nil {
+		return nil, fmt.Errorf("failed to move restored graveyard into place: %w", err)
+	}
+	return inc, nil
+}
+
+// copyInto copies the file at src to name in root. An existing file, which
+// for an untracked file can only be a tracked one or a symlink, is refused.
+func copyInto(root *os.Root, src, name string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	if err := root.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	out, err := root.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// fetch downloads the named file from target to local and checks its digest.
+func fetch(target Target, name, sha, local string) error {
+	if err := target.Get(name, local); err != nil {
+		return fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	got, _, err := hashFile(local)
+	if err != nil {
+		return err
+	}
+	if got != sha {
+		return fmt.Errorf("%s is corrupt: its digest does not match the catalog", name)
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/state"
+)
+
+func TestOpenTarget(t *testing.T) {
+	tests := []struct {
+		spec     string
+		wantType string
+		wantErr  bool
+	}{
+		{spec: "/mnt/offsite", wantType: "dir"},
+		{spec: "./backups", wantType: "dir"},
+		{spec: "C:/backups", wantType: "dir"},
+		{spec: "backup-host:/srv/graveyard", wantType: "rsync"},
+		{spec: "me@backup-host:graveyard", wantType: "rsync"},
+		{spec: "rsync://backup-host/graveyard", wantType: "rsync"},
+		{spec: "s3://archives/graveyard", wantType: "aws"},
+		{spec: "s3://", wantErr: true},
+		{spec: "https://example.com/graveyard", wantErr: true},
+		{spec: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			target, err := OpenTarget(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("OpenTarget(%q) expected error", tt.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("OpenTarget(%q) error = %v", tt.spec, err)
+			}
+			got := "dir"
+			if ct, ok := target.(*commandTarget); ok {
+				got = ct.tool
+			}
+			if got != tt.wantType || target.String() != tt.spec {
+				t.Errorf("OpenTarget(%q) = %s %q, want %s", tt.spec, got, target, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestCommandTarget_GetAWS(t *testing.T) {
+	// A fake aws CLI lists and copies according to the test case
+	bin := t.TempDir()
+	script := `#!/bin/sh
+if [ "$2" = ls ]; then
+	[ -n "$FAKE_AWS_LS" ] && printf '%s\n' "$FAKE_AWS_LS"
+	[ -n "$FAKE_AWS_ERR" ] && echo "$FAKE_AWS_ERR" >&2
+	exit "$FAKE_AWS_EXIT"
+fi
+echo copied > "$5"
+`
+	if err := os.WriteFile(filepath.Join(bin, "aws"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	tests := []struct {
+		name        string
+		listing     string
+		stderr      string
+		exit        int
+		wantMissing bool
+		wantErr     bool
+	}{
+		{name: "listed", listing: "2025-12-01 10:00:00        123 manifest.json"},
+		{name: "nothing listed", exit: 1, wantMissing: true, wantErr: true},
+		{name: "only longer names listed", listing: "2025-12-01 10:00:00        123 manifest.json.bak", wantMissing: true, wantErr: true},
+		{name: "credentials missing", stderr: "Unable to locate credentials", exit: 255, wantErr: true},
+		{name: "access denied", stderr: "An error occurred (AccessDenied)", exit: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("FAKE_AWS_LS", tt.listing)
+			t.Setenv("FAKE_AWS_ERR", tt.stderr)
+			t.Setenv("FAKE_AWS_EXIT", strconv.Itoa(tt.exit))
+			target, err := OpenTarget("s3://archives/graveyard")
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = target.Get("manifest.json", filepath.Join(t.TempDir(), "manifest.json"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if missing := errors.Is(err, fs.ErrNotExist); missing != tt.wantMissing {
+				t.Errorf("Get() error = %v, want missing %v", err, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	gy := newGraveyard(t)
+	target, err := OpenTarget(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(gy.Path, "notes.txt"), "untracked notes\n")
+
+	first, err := Backup(gy, target, "1.2.3")
+	if err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	if first == nil || first.Number != 1 || first.Bundle == "" || len(first.Files) != 1 {
+		t.Fatalf("Backup() = %+v, want backup 1 with a bundle and one file", first)
+	}
+
+	if again, err := Backup(gy, target, "1.2.3"); err != nil || again != nil {
+		t.Errorf("Backup() of an unchanged graveyard = %+v, %v, want nil", again, err)
+	}
+
+	// A new commit is bundled on its own; unchanged files are not uploaded
+	writeFile(t, filepath.Join(gy.Path, "another", "main.go"), "package another\n")
+	runGit(t, gy.Path, "add", "another")
+	runGit(t, gy.Path, "-c", "user.email=t@t", "-c", "user.name=T", "commit", "-qm", "another")
+	runGit(t, gy.Path, "tag", "buried/another/v1")
+	second, err := Backup(gy, target, "1.2.3")
+	if err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	if second == nil || second.Number != 2 || second.Bundle == "" {
+		t.Fatalf("Backup() = %+v, want backup 2 with a bundle", second)
+	}
+	if second.Uploaded >= first.Uploaded {
+		t.Errorf("incremental backup uploaded %d bytes, want less than the first's %d", second.Uploaded, first.Uploaded)
+	}
+
+	// A tag on an existing commit needs no bundle
+	runGit(t, gy.Path, "tag", "reviewed")
+	third, err := Backup(gy, target, "1.2.3")
+	if err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	if third == nil || third.Bundle != "" || third.Uploaded != 0 {
+		t.Errorf("Backup() = %+v, want backup 3 without a bundle", third)
+	}
+
+	dest := filepath.Join(t.TempDir(), "restored")
+	restored, err := Restore(target, dest, 0)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if restored.Number != 3 {
+		t.Errorf("Restore() restored backup %d, want 3", restored.Number)
+	}
+	for _, rel := range []string{"another/main.go", "old-experiment/main.go", "notes.txt"} {
+		want, _ := os.ReadFile(filepath.Join(gy.Path, rel))
+		got, err := os.ReadFile(filepath.Join(dest, rel))
+		if err != nil || string(got) != string(want) {
+			t.Errorf("restored %s = %q, %v, want %q", rel, got, err, want)
+		}
+	}
+	wantRefs, _ := git.RefTips(gy.Path)
+	if gotRefs, err := git.RefTips(dest); err != nil || !sameRefs(gotRefs, wantRefs) {
+		t.Errorf("restored refs = %v, %v, want %v", gotRefs, err, wantRefs)
+	}
+	if clean, err := git.IsClean(dest); err != nil || clean {
+		t.Errorf("restored graveyard should hold the untracked notes.txt: clean = %v, %v", clean, err)
+	}
+
+	// An earlier backup restores the graveyard as it was then
+	earlier := filepath.Join(t.TempDir(), "restored")
+	if _, err := Restore(target, earlier, 1); err != nil {
+		t.Fatalf("Restore(1) error = %v", err)
+	}
+	if head, _ := git.Head(earlier); head != first.Head {
+		t.Errorf("Restore(1) HEAD = %s, want %s", head, first.Head)
+	}
+	if _, err := os.Stat(filepath.Join(earlier, "another")); !os.IsNotExist(err) {
+		t.Errorf("Restore(1) restored a project buried after backup 1")
+	}
+
+	if _, err := Restore(target, dest, 0); err == nil {
+		t.Errorf("Restore() into a non-empty destination expected error")
+	}
+	if _, err := Restore(target, filepath.Join(t.TempDir(), "restored"), 4); err == nil {
+		t.Errorf("Restore() of a missing backup expected error")
+	}
+}
+
+func TestRestore_Corrupt(t *testing.T) {
+	gy := newGraveyard(t)
+	dir := t.TempDir()
+	target, err := OpenTarget(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inc, err := Backup(gy, target, "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, filepath.FromSlash(inc.Bundle)), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("tampered")
+	_ = f.Close()
+
+	dest := filepath.Join(t.TempDir(), "restored")
+	if _, err := Restore(target, dest, 0); err == nil || !strings.Contains(err.Error(), "corrupt") {
+		t.Fatalf("Restore() error = %v, want corrupt bundle error", err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(dest))
+	if len(entries) != 0 {
+		t.Errorf("Restore() left %d entries behind after failing", len(entries))
+	}
+}
+
+func TestRestore_UnexpectedFiles(t *testing.T) {
+	gy := newGraveyard(t)
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(gy.Path, "old-experiment", "escape")); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, gy.Path, "add", "-A")
+	runGit(t, gy.Path, "-c", "user.email=t@t", "-c", "user.name=T", "commit", "-qm", "escape")
+	writeFile(t, filepath.Join(gy.Path, "notes.txt"), "untracked notes\n")
+	dir := t.TempDir()
+	target, err := OpenTarget(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Backup(gy, target, "dev"); err != nil {
+		t.Fatal(err)
+	}
+	original, err := os.ReadFile(filepath.Join(dir, CatalogName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{
+		"old-experiment/escape/notes.txt",
+		"old-experiment/latest",
+		".git",
+		".GIT/hooks/pre-commit",
+	} {
+		t.Run(name, func(t *testing.T) {
+			var catalog Catalog
+			if err := json.Unmarshal(original, &catalog); err != nil {
+				t.Fatal(err)
+			}
+			catalog.Backups[0].Files[0].Path = name
+			data, _ := json.Marshal(catalog)
+			if err := os.WriteFile(filepath.Join(dir, CatalogName), data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := Restore(target, filepath.Join(t.TempDir(), "restored"), 0); err == nil {
+				t.Errorf("Restore() of an untracked %s expected error", name)
+			}
+			if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+				t.Errorf("Restore() wrote %d entries outside the graveyard", len(entries))
+			}
+		})
+	}
+}
+
+func TestRestore_NoBackups(t *testing.T) {
+	target, err := OpenTarget(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Restore(target, filepath.Join(t.TempDir(), "restored"), 0); err == nil {
+		t.Errorf("Restore() from an empty target expected error")
+	}
+}
+
+func TestRecordBackup(t *testing.T) {
+	t.Setenv(state.HomeEnv, t.TempDir())
+	if last, err := LastBackup("/graveyard"); err != nil || last != nil {
+		t.Fatalf("LastBackup() = %+v, %v, want nil", last, err)
+	}
+	target, err := OpenTarget("s3://archives/graveyard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordBackup("/graveyard", target, &Increment{Number: 4, Head: "abc"}); err != nil {
+		t.Fatal(err)
+	}
+	last, err := LastBackup("/graveyard")
+	if err != nil || last == nil || last.Target != "s3://archives/graveyard" || last.Number != 4 {
+		t.Errorf("LastBackup() = %+v, %v, want backup 4 to s3://archives/graveyard", last, err)
+	}
+}
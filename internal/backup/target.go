@@ -0,0 +1,200 @@
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/deanhigh/bury-it/internal/git"
+)
+
+// Target is a place incremental backups are kept. Files are named by
+// slash-separated paths relative to the target.
+type Target interface {
+	// Get copies the named file to dst, returning an error matching
+	// fs.ErrNotExist if the target has no such file.
+	Get(name, dst string) error
+	// Put copies the local file src to the named file, replacing it.
+	Put(src, name string) error
+	// String describes the target as it was given.
+	String() string
+}
+
+// rsyncPattern matches rsync destinations such as host:path or
+// user@host:path. A single letter before the colon is left to be a Windows
+// drive.
+var rsyncPattern = regexp.MustCompile(`^(?:[\w.-]+@)?[\w.-]{2,}:`)
+
+// OpenTarget returns the target given by spec: an s3://bucket/prefix URL,
+// copied with the aws CLI; an rsync://host/path URL or host:path
+// destination, copied with rsync; or a local directory, which may be a
+// mounted drive or synced folder.
+func OpenTarget(spec string) (Target, error) {
+	switch {
+	case spec == "":
+		return nil, fmt.Errorf("no backup target given")
+	case strings.HasPrefix(spec, "s3://"):
+		if strings.TrimSuffix(strings.TrimPrefix(spec, "s3://"), "/") == "" {
+			return nil, fmt.Errorf("invalid S3 target %q: want s3://bucket/prefix", spec)
+		}
+		return &commandTarget{spec: spec, base: strings.TrimSuffix(spec, "/"), tool: "aws"}, nil
+	case strings.HasPrefix(spec, "rsync://"):
+		return &commandTarget{spec: spec, base: strings.TrimSuffix(spec, "/"), tool: "rsync"}, nil
+	case strings.Contains(spec, "://"):
+		return nil, fmt.Errorf("unsupported backup target %q: use a directory, host:path, rsync://, or s3://", spec)
+	case rsyncPattern.MatchString(spec):
+		return &commandTarget{spec: spec, base: strings.TrimSuffix(spec, "/"), tool: "rsync"}, nil
+	}
+	dir, err := filepath.Abs(spec)
+	if err != nil {
+		return nil, err
+	}
+	return &dirTarget{spec: spec, dir: dir}, nil
+}
+
+// IsLocal reports whether target is a local directory, which can be backed
+// up to without network access.
+func IsLocal(target Target) bool {
+	_, ok := target.(*dirTarget)
+	return ok
+}
+
+// dirTarget keeps backups in a local directory.
+type dirTarget struct {
+	spec string
+	dir  string
+}
+
+func (t *dirTarget) String() string { return t.spec }
+
+func (t *dirTarget) Get(name, dst string) error {
+	return copyFile(filepath.Join(t.dir, filepath.FromSlash(name)), dst)
+}
+
+func (t *dirTarget) Put(src, name string) error {
+	target := filepath.Join(t.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	// Write beside the file and rename, so an interrupted copy never
+	// replaces a good one
+	tmp := target + ".tmp"
+	if err := copyFile(src, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, target)
+}
+
+// copyFile copies the regular file src to dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// commandTarget copies files with the aws or rsync command, which handle
+// their own credentials and connections.
+type commandTarget struct {
+	spec string
+	base string
+	tool string
+}
+
+func (t *commandTarget) String() string { return t.spec }
+
+func (t *commandTarget) Get(name, dst string) error {
+	remote := t.base + "/" + name
+	if t.tool == "aws" {
+		// aws s3 cp does not tell a missing file from other failures, so
+		// the file is looked up first. aws s3 ls lists every file the name
+		// is a prefix of, and fails without a message if there are none.
+		listing, msg, err := runOutput("aws", "s3", "ls", remote)
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && msg == "" {
+			return fmt.Errorf("%s: %w", remote, fs.ErrNotExist)
+		}
+		if err != nil {
+			return err
+		}
+		if !listsFile(listing, path.Base(name)) {
+			return fmt.Errorf("%s: %w", remote, fs.ErrNotExist)
+		}
+		_, err = run("aws", "s3", "cp", "--quiet", remote, dst)
+		return err
+	}
+	out, err := run("rsync", "--quiet", remote, dst)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 23 && strings.Contains(out, "No such file") {
+		return fmt.Errorf("%s: %w", remote, fs.ErrNotExist)
+	}
+	return err
+}
+
+func (t *commandTarget) Put(src, name string) error {
+	remote := t.base + "/" + name
+	if t.tool == "aws" {
+		_, err := run("aws", "s3", "cp", "--quiet", src, remote)
+		return err
+	}
+	// --mkpath creates the remote directories, like a local target does
+	_, err := run("rsync", "--quiet", "--mkpath", src, remote)
+	return err
+}
+
+// listsFile reports whether the output of aws s3 ls lists the file base, on
+// a line of its date, time, size, and name.
+func listsFile(listing, base string) bool {
+	for _, line := range strings.Split(listing, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 4 && fields[2] != "PRE" && strings.HasSuffix(line, " "+base) {
+			return true
+		}
+	}
+	return false
+}
+
+// run runs a backup tool and returns its standard error with the error.
+func run(name string, args ...string) (string, error) {
+	_, msg, err := runOutput(name, args...)
+	return msg, err
+}
+
+// runOutput runs a backup tool and returns its standard output, and its
+// standard error with the error.
+func runOutput(name string, args ...string) (string, string, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return "", "", fmt.Errorf("%s is required for this backup target but was not found", name)
+	}
+	cmd := git.Command(name, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return stdout.String(), msg, fmt.Errorf("%s failed: %w", name, err)
+		}
+		return stdout.String(), msg, fmt.Errorf("%s failed: %s: %w", name, msg, err)
+	}
+	return stdout.String(), "", nil
+}
@@ -0,0 +1,145 @@
+// Package capability lets an administrator disable operations of bury-it
+// that reach beyond the local machine or destroy data, or require a second
+// person to approve them, so that it can be installed broadly, such as on
+// shared CI runners.
+package capability
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/deanhigh/bury-it/internal/config"
+)
+
+// Capabilities that can be disabled.
+const (
+	// Push pushes to a remote, as --registry-pr does.
+	Push = "push"
+	// GitHubWrite opens, edits, or closes issues and pull requests on
+	// GitHub, as --tombstone-issue, --registry-pr, and sweep --notify-owners
+	// do.
+	GitHubWrite = "github-write"
+	// PurgeHistory drops the preserved history of buried projects, as
+	// compact does.
+	PurgeHistory = "purge-history"
+	// Replace buries a project again as a new version, retiring the
+	// current one, as --new-version does.
+	Replace = "replace"
+	// Serve listens for connections, as serve does.
+	Serve = "serve"
+	// Undo removes the most recent burial, as undo does.
+	Undo = "undo"
+)
+
+// Names are the capabilities that can be disabled, in order.
+var Names = []string{GitHubWrite, PurgeHistory, Push, Replace, Serve, Undo}
+
+// Values a capability key may be set to. Approve lets a capability be used
+// only once a second person has approved it with bury-it approve.
+const (
+	Allow   = "allow"
+	Deny    = "deny"
+	Approve = "approve"
+)
+
+// approvable are the capabilities that can be made to require approval:
+// those that act on a graveyard, which bury-it approve can carry out later.
+var approvable = map[string]bool{PurgeHistory: true, Replace: true, Undo: true}
+
+// KeyPrefix is the prefix of the keys in the system configuration that allow
+// or deny a capability, e.g. capability.push.
+const KeyPrefix = "capability."
+
+// DisabledError is returned when a disabled capability is used.
+type DisabledError struct {
+	Capability string
+}
+
+func (e *DisabledError) Error() string {
+	return e.Capability + " is disabled by administrator"
+}
+
+var (
+	mu       sync.Mutex
+	disabled = map[string]bool{}
+	approved = map[string]bool{}
+)
+
+// Configure disables the capabilities denied by the capability keys of cfg,
+// requires approval for those it sets to approve, and allows the rest. Other
+// keys are ignored.
+func Configure(cfg config.Config) error {
+	denied := map[string]bool{}
+	approval := map[string]bool{}
+	for _, key := range cfg.Keys() {
+		name, ok := strings.CutPrefix(key, KeyPrefix)
+		if !ok {
+			continue
+		}
+		if err := checkName(name); err != nil {
+			return err
+		}
+		switch value := cfg[key]; value {
+		case Allow:
+		case Deny:
+			denied[name] = true
+		case Approve:
+			if !approvable[name] {
+				return fmt.Errorf("invalid value %q for %s: %s cannot require approval", value, key, name)
+			}
+			approval[name] = true
+		default:
+			return fmt.Errorf("invalid value %q for %s: must be %s, %s, or %s", value, key, Allow, Deny, Approve)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	disabled, approved = denied, approval
+	return nil
+}
+
+// checkName checks that name is a capability that can be disabled.
+func checkName(name string) error {
+	i := sort.SearchStrings(Names, name)
+	if i == len(Names) || Names[i] != name {
+		return fmt.Errorf("unknown capability %q: must be %s", name, strings.Join(Names, ", "))
+	}
+	return nil
+}
+
+// Check returns a *DisabledError if the capability has been disabled.
+func Check(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if disabled[name] {
+		return &DisabledError{Capability: name}
+	}
+	return nil
+}
+
+// RequiresApproval reports whether the capability may only be used once a
+// second person has approved it.
+func RequiresApproval(name string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return approved[name]
+}
+
+// States returns whether each capability is allowed, denied, or requires
+// approval, keyed by name.
+func States() map[string]string {
+	mu.Lock()
+	defer mu.Unlock()
+	states := make(map[string]string, len(Names))
+	for _, name := range Names {
+		states[name] = Allow
+		if disabled[name] {
+			states[name] = Deny
+		} else if approved[name] {
+			states[name] = Approve
+		}
+	}
+	return states
+}
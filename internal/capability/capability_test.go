@@ -0,0 +1,101 @@
+package capability
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/deanhigh/bury-it/internal/config"
+)
+
+func TestConfigure(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			cfg:  config.Config{},
+			want: map[string]string{GitHubWrite: Allow, PurgeHistory: Allow, Push: Allow, Replace: Allow, Serve: Allow, Undo: Allow},
+		},
+		{
+			name: "denied",
+			cfg:  config.Config{"capability.push": "deny", "capability.serve": "deny", "capability.github-write": "allow", "default.graveyard": "/srv/graveyard"},
+			want: map[string]string{GitHubWrite: Allow, PurgeHistory: Allow, Push: Deny, Replace: Allow, Serve: Deny, Undo: Allow},
+		},
+		{
+			name: "approval required",
+			cfg:  config.Config{"capability.undo": "approve", "capability.purge-history": "approve", "capability.replace": "deny"},
+			want: map[string]string{GitHubWrite: Allow, PurgeHistory: Approve, Push: Allow, Replace: Deny, Serve: Allow, Undo: Approve},
+		},
+		{
+			name:    "unknown capability",
+			cfg:     config.Config{"capability.delete": "deny"},
+			wantErr: true,
+		},
+		{
+			name:    "approval of a capability that cannot be approved",
+			cfg:     config.Config{"capability.serve": "approve"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid value",
+			cfg:     config.Config{"capability.push": "no"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() { _ = Configure(config.Config{}) }()
+			err := Configure(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Configure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := States(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("States() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	defer func() { _ = Configure(config.Config{}) }()
+	if err := Configure(config.Config{"capability.purge-history": "deny"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Check(Push); err != nil {
+		t.Errorf("Check(%q) error = %v, want nil", Push, err)
+	}
+	err := Check(PurgeHistory)
+	var disabledErr *DisabledError
+	if !errors.As(err, &disabledErr) || disabledErr.Capability != PurgeHistory {
+		t.Fatalf("Check(%q) error = %v, want a *DisabledError", PurgeHistory, err)
+	}
+	if want := "purge-history is disabled by administrator"; err.Error() != want {
+		t.Errorf("Check() error = %q, want %q", err, want)
+	}
+}
+
+func TestRequiresApproval(t *testing.T) {
+	defer func() { _ = Configure(config.Config{}) }()
+	if err := Configure(config.Config{"capability.undo": "approve"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !RequiresApproval(Undo) {
+		t.Errorf("RequiresApproval(%q) = false, want true", Undo)
+	}
+	if err := Check(Undo); err != nil {
+		t.Errorf("Check(%q) error = %v, want nil for a capability requiring approval", Undo, err)
+	}
+	if RequiresApproval(Replace) {
+		t.Errorf("RequiresApproval(%q) = true, want false", Replace)
+	}
+}
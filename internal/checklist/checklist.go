@@ -0,0 +1,209 @@
+// Package checklist generates and tracks the decommissioning checklist of a
+// buried project: the steps, beyond the burial itself, that take a project
+// out of service.
+package checklist
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/atomicfile"
+	"github.com/deanhigh/bury-it/internal/metadata"
+)
+
+// Item is a step of a checklist.
+type Item struct {
+	// Number identifies the item within its checklist, starting at 1.
+	Number int
+	// Text describes the step.
+	Text string
+	// Done is true once the item has been ticked.
+	Done bool
+}
+
+// auditHeading starts the audit log at the end of a checklist.
+const auditHeading = "## Audit Log"
+
+// itemPattern matches a checklist item line.
+var itemPattern = regexp.MustCompile(`^- \[([ xX])\] (\d+)\. (.*)$`)
+
+// endpointSteps describes how each kind of endpoint is taken down.
+var endpointSteps = []struct {
+	kind    string
+	section string
+	step    string
+}{
+	{"Terraform resource", "Infrastructure", "Destroy Terraform resource"},
+	{"Cloud resource", "Infrastructure", "Delete cloud resource"},
+	{"Hostname", "DNS", "Remove DNS records for"},
+	{"URL", "Services", "Shut down the service at"},
+}
+
+// Generate returns the decommissioning checklist of a project, seeded from
+// the inventories recorded in its metadata.
+func Generate(project string, meta *metadata.Metadata) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `# Decommissioning Checklist
+
+Steps to take %s out of service, generated when it was buried on %s.
+Tick items with bury-it checklist tick; each tick is recorded in the audit log
+below.
+`, project, meta.BuriedAt.Format("2006-01-02"))
+
+	n := 0
+	section := ""
+	add := func(heading, text string) {
+		if heading != section {
+			section = heading
+			fmt.Fprintf(&b, "\n## %s\n\n", heading)
+		}
+		n++
+		fmt.Fprintf(&b, "- [ ] %d. %s\n", n, text)
+	}
+
+	add("Repository", fmt.Sprintf("Archive or delete the original repository (%s)", meta.OriginalSource))
+	if meta.Issues != nil && meta.Issues.OpenIssues+meta.Issues.OpenPullRequests > 0 {
+		add("Repository", fmt.Sprintf("Close or transfer the %d open issues and %d open pull requests",
+			meta.Issues.OpenIssues, meta.Issues.OpenPullRequests))
+	}
+	if len(meta.Workflows) > 0 {
+		add("Repository", fmt.Sprintf("Disable the %d CI workflows and their schedules", len(meta.Workflows)))
+	}
+
+	for _, a := range meta.Artifacts {
+		name := "the images built from"
+		if a.Name != "" {
+			name = "`" + a.Name + "`, built from"
+		}
+		add("Published Artifacts", fmt.Sprintf("Deprecate or delete %s `%s`, on %s", name, a.File, a.Registry))
+	}
+
+	if meta.Decommission != nil {
+		for _, step := range endpointSteps {
+			for _, e := range meta.Decommission.Endpoints {
+				if e.Kind == step.kind {
+					add(step.section, fmt.Sprintf("%s `%s` (`%s:%d`)", step.step, e.Value, e.File, e.Line))
+				}
+			}
+		}
+	}
+
+	add("Access", "Revoke the deploy keys, API tokens, and CI secrets issued to the project")
+	add("Access", "Remove the project's webhooks and third-party integrations")
+
+	fmt.Fprintf(&b, "\n%s\n\n", auditHeading)
+	return b.String()
+}
+
+// Parse returns the items of a checklist.
+func Parse(content string) []Item {
+	var items []Item
+	for _, line := range strings.Split(content, "\n") {
+		if item, ok := parseItem(line); ok {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseItem(line string) (Item, bool) {
+	m := itemPattern.FindStringSubmatch(line)
+	if m == nil {
+		return Item{}, false
+	}
+	n, _ := strconv.Atoi(m[2])
+	return Item{Number: n, Text: m[3], Done: m[1] != " "}, true
+}
+
+// Read returns the items of the checklist in a project directory.
+func Read(dir string) ([]Item, error) {
+	content, err := os.ReadFile(filepath.Join(dir, metadata.DecommissionFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checklist: %w", err)
+	}
+	return Parse(string(content)), nil
+}
+
+// Find returns the item selected by number, or by a case-insensitive part of
+// its text that matches no other item.
+func Find(items []Item, selector string) (Item, error) {
+	if n, err := strconv.Atoi(selector); err == nil {
+		for _, item := range items {
+			if item.Number == n {
+				return item, nil
+			}
+		}
+		return Item{}, fmt.Errorf("checklist has no item %d", n)
+	}
+
+	var matches []Item
+	for _, item := range items {
+		if strings.Contains(strings.ToLower(item.Text), strings.ToLower(selector)) {
+			matches = append(matches, item)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Item{}, fmt.Errorf("no checklist item matches %q", selector)
+	case 1:
+		return matches[0], nil
+	default:
+		return Item{}, fmt.Errorf("%q matches %d checklist items; use the item number", selector, len(matches))
+	}
+}
+
+// Tick marks the selected item of a checklist as done and appends an audit
+// entry recording who ticked it, when, and why. It returns the updated
+// content and the item as it was before the tick.
+func Tick(content, selector, by, note string, at time.Time) (string, Item, error) {
+	item, err := Find(Parse(content), selector)
+	if err != nil {
+		return "", Item{}, err
+	}
+	if item.Done {
+		return "", item, fmt.Errorf("item %d is already ticked: %s", item.Number, item.Text)
+	}
+
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	for i, line := range lines {
+		if parsed, ok := parseItem(line); ok && parsed.Number == item.Number {
+			lines[i] = fmt.Sprintf("- [x] %d. %s", item.Number, item.Text)
+		}
+	}
+	if !strings.Contains(content, "\n"+auditHeading+"\n") {
+		lines = append(lines, "", auditHeading, "")
+	}
+
+	entry := fmt.Sprintf("- %s: %s ticked %d. %s", at.UTC().Format(time.RFC3339), by, item.Number, item.Text)
+	if note = strings.TrimSpace(note); note != "" {
+		entry += " (" + strings.Join(strings.Fields(note), " ") + ")"
+	}
+	// Trailing blank lines of an empty log are dropped by the trim above
+	if lines[len(lines)-1] == auditHeading {
+		lines = append(lines, "")
+	}
+	lines = append(lines, entry)
+	return strings.Join(lines, "\n") + "\n", item, nil
+}
+
+// TickFile ticks an item of the checklist in a project directory.
+func TickFile(dir, selector, by, note string, at time.Time) (Item, error) {
+	path := filepath.Join(dir, metadata.DecommissionFileName)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Item{}, fmt.Errorf("failed to read checklist: %w", err)
+	}
+	updated, item, err := Tick(string(content), selector, by, note, at)
+	if err != nil {
+		return item, err
+	}
+	if err := atomicfile.Write(path, []byte(updated), 0644); err != nil {
+		return item, fmt.Errorf("failed to write checklist: %w", err)
+	}
+	return item, nil
+}
@@ -0,0 +1,124 @@
+package checklist
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/metadata"
+)
+
+func testMetadata() *metadata.Metadata {
+	return &metadata.Metadata{
+		OriginalSource: "https://github.com/owner/widget",
+		BuriedAt:       time.Date(2025, 12, 26, 10, 30, 0, 0, time.UTC),
+		Issues:         &metadata.Issues{OpenIssues: 2, OpenPullRequests: 1},
+		Artifacts: []metadata.Artifact{
+			{Registry: "npm", Name: "widget", File: "package.json"},
+			{Registry: "Docker", File: "Dockerfile"},
+		},
+		Decommission: &metadata.Decommission{Endpoints: []metadata.Endpoint{
+			{Kind: "Hostname", Value: "api.widget.io", File: "prod.env", Line: 2},
+			{Kind: "Terraform resource", Value: "aws_s3_bucket.assets", File: "main.tf", Line: 1},
+		}},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	got := Generate("widget", testMetadata())
+	for _, want := range []string{
+		"# Decommissioning Checklist",
+		"buried on 2025-12-26",
+		"## Repository\n\n- [ ] 1. Archive or delete the original repository (https://github.com/owner/widget)\n- [ ] 2. Close or transfer the 2 open issues and 1 open pull requests\n",
+		"## Published Artifacts\n\n- [ ] 3. Deprecate or delete `widget`, built from `package.json`, on npm\n- [ ] 4. Deprecate or delete the images built from `Dockerfile`, on Docker\n",
+		"## Infrastructure\n\n- [ ] 5. Destroy Terraform resource `aws_s3_bucket.assets` (`main.tf:1`)\n",
+		"## DNS\n\n- [ ] 6. Remove DNS records for `api.widget.io` (`prod.env:2`)\n",
+		"## Access\n\n- [ ] 7. Revoke",
+		"## Audit Log\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Generate() missing %q\n\nGot:\n%s", want, got)
+		}
+	}
+	if items := Parse(got); len(items) != 8 {
+		t.Errorf("Parse() = %d items, want 8", len(items))
+	}
+}
+
+func TestFind(t *testing.T) {
+	items := Parse(Generate("widget", testMetadata()))
+	tests := []struct {
+		selector string
+		want     int
+		wantErr  string
+	}{
+		{selector: "6", want: 6},
+		{selector: "API.WIDGET.IO", want: 6},
+		{selector: "99", wantErr: "no item 99"},
+		{selector: "deprecate", wantErr: "matches 2 checklist items"},
+		{selector: "kubernetes", wantErr: "no checklist item matches"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			item, err := Find(items, tt.selector)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Find() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || item.Number != tt.want {
+				t.Errorf("Find() = %+v, %v, want item %d", item, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTickFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, metadata.DecommissionFileName)
+	if err := os.WriteFile(path, []byte(Generate("widget", testMetadata())), 0644); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+
+	item, err := TickFile(dir, "6", "ops@example.com", "CNAME removed\nin INFRA-212", at)
+	if err != nil {
+		t.Fatalf("TickFile() error = %v", err)
+	}
+	if item.Number != 6 {
+		t.Errorf("TickFile() = %+v, want item 6", item)
+	}
+	if _, err := TickFile(dir, "s3_bucket", "dev@example.com", "", at.Add(time.Hour)); err != nil {
+		t.Fatalf("TickFile() error = %v", err)
+	}
+	if _, err := TickFile(dir, "6", "ops@example.com", "", at); err == nil || !strings.Contains(err.Error(), "already ticked") {
+		t.Errorf("TickFile() error = %v, want already ticked", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(content)
+	for _, want := range []string{
+		"- [x] 6. Remove DNS records for `api.widget.io` (`prod.env:2`)\n",
+		"- [x] 5. Destroy Terraform resource",
+		"## Audit Log\n\n- 2026-01-05T09:00:00Z: ops@example.com ticked 6. Remove DNS records for `api.widget.io` (`prod.env:2`) (CNAME removed in INFRA-212)\n- 2026-01-05T10:00:00Z: dev@example.com ticked 5. Destroy Terraform resource `aws_s3_bucket.assets` (`main.tf:1`)\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("checklist missing %q\n\nGot:\n%s", want, got)
+		}
+	}
+	done := 0
+	for _, item := range Parse(got) {
+		if item.Done {
+			done++
+		}
+	}
+	if done != 2 {
+		t.Errorf("Parse() = %d items done, want 2", done)
+	}
+}
@@ -0,0 +1,158 @@
+// Package config stores user configuration, such as default flag values, in
+// the bury-it state directory, and reads the system configuration an
+// administrator may install.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/deanhigh/bury-it/internal/state"
+)
+
+// FileName is the name of the configuration file in the state directory.
+const FileName = "config.json"
+
+// DefaultPrefix is the prefix of keys that set the default value of a flag,
+// e.g. default.graveyard.
+const DefaultPrefix = "default."
+
+// TransportPrefix is the prefix of keys that set the transport shorthand
+// sources on a host are cloned over, e.g. transport.github.com.
+const TransportPrefix = "transport."
+
+// ForgePrefix is the prefix of keys that name the kind of forge a host
+// runs, e.g. forge.git.example.com.
+const ForgePrefix = "forge."
+
+// SourcePrefix is the prefix of keys that define a shorthand prefix and the
+// host it expands to, e.g. prefix.corp for sources such as corp:team/repo.
+const SourcePrefix = "prefix."
+
+// ShorthandHostKey is the key holding the host owner/repo shorthand sources
+// expand to instead of github.com, such as a GitHub Enterprise Server host.
+const ShorthandHostKey = "shorthand.host"
+
+// keyPattern matches a valid key: dot-separated lowercase words.
+var keyPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*(\.[a-z0-9]+(-[a-z0-9]+)*)+$`)
+
+// Config is the user configuration, a set of key-value pairs.
+type Config map[string]string
+
+// Path returns the path of the configuration file, or just its name if the
+// state directory cannot be located.
+func Path() string {
+	dir, err := state.Dir()
+	if err != nil {
+		return FileName
+	}
+	return filepath.Join(dir, FileName)
+}
+
+// SystemPath is the path of the system configuration, which is managed by
+// an administrator rather than the user, e.g. to disable capabilities.
+// Packagers may move it at build time with
+// -ldflags "-X github.com/deanhigh/bury-it/internal/config.SystemPath=...".
+var SystemPath = "/etc/bury-it/config.json"
+
+// LoadSystem reads the system configuration. A missing file gives an empty
+// configuration.
+func LoadSystem() (Config, error) {
+	cfg := Config{}
+	data, err := os.ReadFile(SystemPath)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read system configuration: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse system configuration %s: %w", SystemPath, err)
+	}
+	return cfg, nil
+}
+
+// Load reads the configuration. A missing file gives an empty configuration.
+func Load() (Config, error) {
+	cfg := Config{}
+	if err := state.Load(FileName, &cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Save writes the configuration.
+func (c Config) Save() error {
+	return state.Save(FileName, c)
+}
+
+// CheckKey checks that key is well formed, e.g. default.graveyard.
+func CheckKey(key string) error {
+	if !keyPattern.MatchString(key) {
+		return fmt.Errorf("invalid key %q: keys are lowercase words separated by dots, e.g. default.graveyard", key)
+	}
+	return nil
+}
+
+// Keys returns the configured keys in order.
+func (c Config) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Defaults returns the default flag values, keyed by flag name.
+func (c Config) Defaults() map[string]string {
+	defaults := map[string]string{}
+	for key, value := range c {
+		if name, ok := strings.CutPrefix(key, DefaultPrefix); ok {
+			defaults[name] = value
+		}
+	}
+	return defaults
+}
+
+// Transports returns the transports set for hosts, keyed by host.
+func (c Config) Transports() map[string]string {
+	transports := map[string]string{}
+	for key, value := range c {
+		if host, ok := strings.CutPrefix(key, TransportPrefix); ok {
+			transports[host] = value
+		}
+	}
+	return transports
+}
+
+// Forges returns the forge kinds set for hosts, keyed by host. The
+// shorthand host runs GitHub unless a forge key says otherwise.
+func (c Config) Forges() map[string]string {
+	forges := map[string]string{}
+	if host := c[ShorthandHostKey]; host != "" {
+		forges[host] = "github"
+	}
+	for key, value := range c {
+		if host, ok := strings.CutPrefix(key, ForgePrefix); ok {
+			forges[host] = value
+		}
+	}
+	return forges
+}
+
+// Prefixes returns the hosts shorthand prefixes expand to, keyed by prefix.
+func (c Config) Prefixes() map[string]string {
+	prefixes := map[string]string{}
+	for key, value := range c {
+		if prefix, ok := strings.CutPrefix(key, SourcePrefix); ok {
+			prefixes[prefix] = value
+		}
+	}
+	return prefixes
+}
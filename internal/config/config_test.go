@@ -0,0 +1,110 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/deanhigh/bury-it/internal/state"
+)
+
+func TestLoadSave(t *testing.T) {
+	t.Setenv(state.HomeEnv, filepath.Join(t.TempDir(), "state"))
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() of missing file error = %v", err)
+	}
+	if len(cfg) != 0 {
+		t.Errorf("Load() of missing file = %v, want empty", cfg)
+	}
+
+	cfg["default.graveyard"] = "/srv/graveyard"
+	cfg["default.drop-history"] = "true"
+	cfg["report.currency"] = "EUR"
+	cfg["transport.github.com"] = "ssh"
+	cfg["forge.git.example.com"] = "forgejo"
+	cfg["shorthand.host"] = "github.example.com"
+	cfg["prefix.corp"] = "git.example.com"
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	got, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if want := []string{"default.drop-history", "default.graveyard", "forge.git.example.com", "prefix.corp", "report.currency", "shorthand.host", "transport.github.com"}; !reflect.DeepEqual(got.Keys(), want) {
+		t.Errorf("Keys() = %v, want %v", got.Keys(), want)
+	}
+	wantDefaults := map[string]string{"graveyard": "/srv/graveyard", "drop-history": "true"}
+	if !reflect.DeepEqual(got.Defaults(), wantDefaults) {
+		t.Errorf("Defaults() = %v, want %v", got.Defaults(), wantDefaults)
+	}
+	if want := map[string]string{"github.com": "ssh"}; !reflect.DeepEqual(got.Transports(), want) {
+		t.Errorf("Transports() = %v, want %v", got.Transports(), want)
+	}
+	if want := map[string]string{"git.example.com": "forgejo", "github.example.com": "github"}; !reflect.DeepEqual(got.Forges(), want) {
+		t.Errorf("Forges() = %v, want %v", got.Forges(), want)
+	}
+	if want := map[string]string{"corp": "git.example.com"}; !reflect.DeepEqual(got.Prefixes(), want) {
+		t.Errorf("Prefixes() = %v, want %v", got.Prefixes(), want)
+	}
+}
+
+func TestCheckKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		wantErr bool
+	}{
+		{key: "default.graveyard"},
+		{key: "default.monthly-cost-before"},
+		{key: "a.b.c"},
+		{key: "graveyard", wantErr: true},
+		{key: "default.", wantErr: true},
+		{key: "Default.Graveyard", wantErr: true},
+		{key: "default.-x", wantErr: true},
+		{key: "default.a b", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if err := CheckKey(tt.key); (err != nil) != tt.wantErr {
+				t.Errorf("CheckKey(%q) error = %v, wantErr %v", tt.key, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadSystem(t *testing.T) {
+	dir := t.TempDir()
+	defer func(path string) { SystemPath = path }(SystemPath)
+
+	SystemPath = filepath.Join(dir, "missing.json")
+	cfg, err := LoadSystem()
+	if err != nil {
+		t.Fatalf("LoadSystem() of missing file error = %v", err)
+	}
+	if len(cfg) != 0 {
+		t.Errorf("LoadSystem() of missing file = %v, want empty", cfg)
+	}
+
+	SystemPath = filepath.Join(dir, "config.json")
+	if err := os.WriteFile(SystemPath, []byte(`{"capability.push": "deny"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = LoadSystem()
+	if err != nil {
+		t.Fatalf("LoadSystem() error = %v", err)
+	}
+	if want := (Config{"capability.push": "deny"}); !reflect.DeepEqual(cfg, want) {
+		t.Errorf("LoadSystem() = %v, want %v", cfg, want)
+	}
+
+	if err := os.WriteFile(SystemPath, []byte(`{`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSystem(); err == nil {
+		t.Error("LoadSystem() of invalid file error = nil, want error")
+	}
+}
@@ -0,0 +1,194 @@
+// Package convert converts the history of Mercurial and Subversion
+// repositories to git, with hg-fast-export or git-remote-hg and git svn, so
+// that legacy projects can be buried with their history rather than as
+// snapshots.
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/deanhigh/bury-it/internal/git"
+)
+
+// fastExportCommands are the names hg-fast-export is installed under.
+var fastExportCommands = []string{"hg-fast-export.sh", "hg-fast-export"}
+
+// remoteHgCommand is the git remote helper that lets git clone Mercurial
+// repositories given as hg:: URLs.
+const remoteHgCommand = "git-remote-hg"
+
+// originPrefix is the prefix of the remote-tracking refs of a clone, and of
+// those git svn creates for the branches and tags of a repository with the
+// standard layout.
+const originPrefix = "refs/remotes/origin/"
+
+// Mercurial converts the Mercurial repository at source, a local path or a
+// URL, into a new git repository at dest, with hg-fast-export if it is
+// installed and git-remote-hg otherwise. Remote repositories are cloned
+// first. Mercurial branches become git branches, with default as master,
+// and its tags become git tags.
+func Mercurial(source, dest string) error {
+	if _, err := exec.LookPath("hg"); err != nil {
+		return fmt.Errorf("converting a Mercurial repository requires hg; install Mercurial")
+	}
+	fastExport := ""
+	for _, name := range fastExportCommands {
+		if _, err := exec.LookPath(name); err == nil {
+			fastExport = name
+			break
+		}
+	}
+	if fastExport == "" {
+		if _, err := exec.LookPath(remoteHgCommand); err == nil {
+			return mercurialRemoteHg(source, dest)
+		}
+		return fmt.Errorf("converting a Mercurial repository requires hg-fast-export or git-remote-hg; install fast-export (https://github.com/frej/fast-export) or git-remote-hg (https://github.com/felipec/git-remote-hg)")
+	}
+
+	hgPath := source
+	if strings.Contains(source, "://") {
+		work, err := os.MkdirTemp("", "bury-it-hg-*")
+		if err != nil {
+			return fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		defer func() { _ = os.RemoveAll(work) }()
+		hgPath = filepath.Join(work, "hg")
+		if err := run("", "hg", "clone", "--noupdate", "--quiet", source, hgPath); err != nil {
+			return fmt.Errorf("failed to clone Mercurial repository: %w", err)
+		}
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := git.Init(dest); err != nil {
+		return err
+	}
+	if err := run(dest, fastExport, "-r", hgPath); err != nil {
+		return fmt.Errorf("failed to convert Mercurial history: %w", err)
+	}
+	// hg-fast-export writes the history but leaves the working tree empty
+	return git.ResetHard(dest, "HEAD")
+}
+
+// mercurialRemoteHg converts a Mercurial repository by cloning it with git
+// through git-remote-hg, then turning the remote-tracking branches of the
+// clone into branches, as hg-fast-export leaves them.
+func mercurialRemoteHg(source, dest string) error {
+	if !strings.Contains(source, "://") {
+		abs, err := filepath.Abs(source)
+		if err != nil {
+			return fmt.Errorf("failed to resolve path: %w", err)
+		}
+		source = abs
+	}
+	if err := run("", "git", "clone", "--quiet", "hg::"+source, dest); err != nil {
+		return fmt.Errorf("failed to convert Mercurial history: %w", err)
+	}
+	current, err := git.CurrentBranch(dest)
+	if err != nil {
+		return err
+	}
+	out, err := output(dest, "git", "for-each-ref", "--format=%(refname)", originPrefix)
+	if err != nil {
+		return fmt.Errorf("git for-each-ref failed: %w", err)
+	}
+	for _, ref := range strings.Fields(out) {
+		name := strings.TrimPrefix(ref, originPrefix)
+		if name == "HEAD" || name == current {
+			continue
+		}
+		if err := run(dest, "git", "branch", "--quiet", "--no-track", name, ref); err != nil {
+			return fmt.Errorf("git branch failed: %w", err)
+		}
+	}
+	// The converted repository stands on its own, without the hg:: remote
+	if err := run(dest, "git", "remote", "remove", "origin"); err != nil {
+		return fmt.Errorf("git remote remove failed: %w", err)
+	}
+	return nil
+}
+
+// Subversion converts the Subversion repository at url into a new git
+// repository at dest with git svn. A repository with the standard
+// trunk, branches, and tags layout has its branches converted to git
+// branches and its tags to git tags; any other is converted as a single
+// line of history.
+func Subversion(url, dest string) error {
+	if err := exec.Command("git", "svn", "--version").Run(); err != nil {
+		return fmt.Errorf("converting a Subversion repository requires git svn; install it, e.g. the git-svn package")
+	}
+
+	if err := run("", "git", "svn", "clone", "--quiet", "--stdlayout", url, dest); err != nil {
+		return fmt.Errorf("failed to convert Subversion history: %w", err)
+	}
+	if _, err := git.Head(dest); err == nil {
+		return convertSubversionRefs(dest)
+	}
+
+	// Without a trunk, the standard layout finds nothing to convert
+	if err := os.RemoveAll(dest); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", dest, err)
+	}
+	if err := run("", "git", "svn", "clone", "--quiet", url, dest); err != nil {
+		return fmt.Errorf("failed to convert Subversion history: %w", err)
+	}
+	if _, err := git.Head(dest); err != nil {
+		return fmt.Errorf("no commits found in Subversion repository %s", url)
+	}
+	return nil
+}
+
+// convertSubversionRefs turns the remote-tracking refs git svn leaves for
+// Subversion branches and tags into git branches and tags.
+func convertSubversionRefs(repoPath string) error {
+	out, err := output(repoPath, "git", "for-each-ref", "--format=%(refname)", originPrefix)
+	if err != nil {
+		return fmt.Errorf("git for-each-ref failed: %w", err)
+	}
+	for _, ref := range strings.Fields(out) {
+		name := strings.TrimPrefix(ref, originPrefix)
+		switch {
+		case name == "trunk":
+			// Already checked out as master
+		case strings.HasPrefix(name, "tags/"):
+			// Subversion tags are copies, with a commit of their own
+			if err := git.CreateTag(repoPath, strings.TrimPrefix(name, "tags/"), ref); err != nil {
+				return err
+			}
+		default:
+			if err := run(repoPath, "git", "branch", "--quiet", "--no-track", name, ref); err != nil {
+				return fmt.Errorf("git branch failed: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// run runs a command in dir, or the current directory if dir is "".
+func run(dir, name string, args ...string) error {
+	_, err := output(dir, name, args...)
+	return err
+}
+
+// output runs a command in dir, or the current directory if dir is "", and
+// returns its standard output.
+func output(dir, name string, args ...string) (string, error) {
+	cmd := git.Command(name, args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("%s", msg)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,133 @@
+package convert
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deanhigh/bury-it/internal/git"
+)
+
+// remoteHgScript stands in for git-remote-hg, serving a fixed history with
+// a default and a stable branch over git's remote helper protocol.
+const remoteHgScript = `#!/bin/sh
+# Stand-in for git-remote-hg serving a fixed history with a default and a
+# stable branch.
+while read -r line; do
+	case "$line" in
+	capabilities)
+		printf 'import\nrefspec refs/heads/*:refs/hg/origin/*\n\n' ;;
+	list)
+		printf '? refs/heads/master\n? refs/heads/stable\n@refs/heads/master HEAD\n\n' ;;
+	import*)
+		while read -r more && [ -n "$more" ]; do :; done
+		cat <<'STREAM'
+feature done
+commit refs/hg/origin/master
+mark :1
+committer Ann <ann@example.com> 1500000000 +0000
+data 6
+first
+M 644 inline README
+data 6
+hello
+commit refs/hg/origin/stable
+mark :2
+committer Ann <ann@example.com> 1500000100 +0000
+data 7
+stable
+from :1
+M 644 inline STABLE
+data 3
+ok
+commit refs/hg/origin/master
+mark :3
+committer Ann <ann@example.com> 1500000200 +0000
+data 7
+second
+from :1
+M 644 inline README
+data 6
+world
+done
+STREAM
+		;;
+	"") exit 0 ;;
+	esac
+done
+`
+
+func TestMissingTools(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		convert func() error
+		want    string
+	}{
+		{
+			name:    "mercurial",
+			convert: func() error { return Mercurial("https://hg.example.com/repo", filepath.Join(dir, "hg")) },
+			want:    "requires hg",
+		},
+		{
+			name:    "subversion",
+			convert: func() error { return Subversion("svn://svn.example.com/repo", filepath.Join(dir, "svn")) },
+			want:    "requires git svn",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.convert()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want one saying it %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMercurial_RemoteHg(t *testing.T) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+	bin := t.TempDir()
+	for name, script := range map[string]string{"hg": "#!/bin/sh\nexit 0\n", "git-remote-hg": remoteHgScript} {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", strings.Join([]string{bin, filepath.Dir(gitPath), "/bin"}, string(os.PathListSeparator)))
+	for _, name := range fastExportCommands {
+		if _, err := exec.LookPath(name); err == nil {
+			t.Skipf("%s is installed, so git-remote-hg would not be used", name)
+		}
+	}
+
+	dest := filepath.Join(t.TempDir(), "repo")
+	if err := Mercurial("https://hg.example.com/repo", dest); err != nil {
+		t.Fatalf("Mercurial() error = %v", err)
+	}
+	commits, err := git.Log(dest, []string{"--all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 3 {
+		t.Errorf("converted %d commits, want 3", len(commits))
+	}
+	for _, branch := range []string{"master", "stable"} {
+		if _, err := git.ResolveCommit(dest, "refs/heads/"+branch); err != nil {
+			t.Errorf("branch %s missing after conversion: %v", branch, err)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(dest, "README")); err != nil || string(data) != "world\n" {
+		t.Errorf("README = %q, %v; want the default branch checked out", data, err)
+	}
+	if remote, _ := git.GetRemoteURL(dest); remote != "" {
+		t.Errorf("converted repository still has remote %s", remote)
+	}
+}
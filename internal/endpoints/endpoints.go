@@ -0,0 +1,217 @@
+// Package endpoints finds the infrastructure a repository refers to:
+// hostnames, service URLs, Terraform resources, and cloud resource
+// identifiers that may need to be torn down when the project is buried.
+package endpoints
+
+import (
+	"bytes"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/deanhigh/bury-it/internal/git"
+)
+
+// Kind is the kind of an endpoint.
+type Kind string
+
+// Endpoint kinds, in the order they are listed.
+const (
+	TerraformResource Kind = "Terraform resource"
+	CloudResource     Kind = "Cloud resource"
+	Hostname          Kind = "Hostname"
+	URL               Kind = "URL"
+)
+
+var kindOrder = map[Kind]int{TerraformResource: 0, CloudResource: 1, Hostname: 2, URL: 3}
+
+// Endpoint is a piece of infrastructure referenced by the source.
+type Endpoint struct {
+	// Kind is what the endpoint is.
+	Kind Kind
+	// Value is the hostname, URL, resource address, or identifier.
+	Value string
+	// File is the path of the first file the endpoint was found in.
+	File string
+	// Line is the line number within File.
+	Line int
+}
+
+// maxFileSize is the size above which files are not scanned.
+const maxFileSize = 1 << 20
+
+// Scan looks through the tracked text files of a repository for
+// infrastructure references. Documentation, lock files, and vendored
+// dependencies are skipped, as are hosts that belong to well-known public
+// services rather than the project. Each endpoint is reported once.
+func Scan(repoPath string) ([]Endpoint, error) {
+	files, err := git.ListFiles(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []Endpoint
+	seen := make(map[string]bool)
+	for _, file := range files {
+		if skipFile(file) {
+			continue
+		}
+		full := filepath.Join(repoPath, file)
+		info, err := os.Stat(full)
+		if err != nil || !info.Mode().IsRegular() || info.Size() > maxFileSize {
+			continue
+		}
+		content, err := os.ReadFile(full)
+		if err != nil || isBinary(content) {
+			continue
+		}
+		for _, e := range scanFile(file, content) {
+			key := string(e.Kind) + "\x00" + e.Value
+			if !seen[key] {
+				seen[key] = true
+				found = append(found, e)
+			}
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].Kind != found[j].Kind {
+			return kindOrder[found[i].Kind] < kindOrder[found[j].Kind]
+		}
+		return found[i].Value < found[j].Value
+	})
+	return found, nil
+}
+
+// skipFile reports whether a file is documentation, a lock file, or part of
+// a vendored dependency.
+func skipFile(file string) bool {
+	for _, dir := range strings.Split(path.Dir(file), "/") {
+		if dir == "node_modules" || dir == "vendor" || dir == "third_party" || dir == "testdata" {
+			return true
+		}
+	}
+	base := strings.ToLower(path.Base(file))
+	switch path.Ext(base) {
+	case ".md", ".markdown", ".rst", ".adoc", ".txt", ".svg", ".lock", ".sum":
+		return true
+	}
+	for _, prefix := range []string{"license", "licence", "changelog", "notice", "authors", "package-lock.", "pnpm-lock.", ".bury-it"} {
+		if strings.HasPrefix(base, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBinary reports whether content looks like a binary file.
+func isBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+var (
+	// terraformResourcePattern matches a Terraform resource block.
+	terraformResourcePattern = regexp.MustCompile(`^\s*resource\s+"([\w-]+)"\s+"([\w-]+)"`)
+	// arnPattern matches an AWS ARN.
+	arnPattern = regexp.MustCompile(`\barn:aws[\w-]*:[\w-]+:[\w-]*:\d*:[\w/:.+=@-]+`)
+	// azureResourcePattern matches an Azure resource ID.
+	azureResourcePattern = regexp.MustCompile(`(?i)/subscriptions/[0-9a-f-]{36}/resourceGroups/[\w./-]+`)
+	// gcpResourcePattern matches a Google Cloud resource name.
+	gcpResourcePattern = regexp.MustCompile(`\bprojects/[a-z][a-z0-9-]{4,28}[a-z0-9]/(?:locations|zones|regions|topics|subscriptions|instances|databases|secrets|buckets|datasets|services)/[\w.-]+(?:/[\w.-]+)*`)
+	// urlPattern matches an http(s) URL.
+	urlPattern = regexp.MustCompile(`https?://[^\s"'<>()\[\]{}\x60,;\\]+`)
+	// hostSettingPattern matches a configuration setting naming a host.
+	hostSettingPattern = regexp.MustCompile(`(?i)\b(?:[\w-]*host(?:name)?|endpoint|domain|fqdn|server_name)["']?\s*(?:[:=]|\s)\s*["']?([a-z0-9][a-z0-9-]*(?:\.[a-z0-9-]+)*\.[a-z]{2,})\b`)
+)
+
+// scanFile returns the endpoints referenced by a file.
+func scanFile(file string, content []byte) []Endpoint {
+	var found []Endpoint
+	add := func(kind Kind, value string, line int) {
+		found = append(found, Endpoint{Kind: kind, Value: value, File: file, Line: line})
+	}
+
+	terraform := path.Ext(file) == ".tf"
+	for i, line := range strings.Split(string(content), "\n") {
+		n := i + 1
+		if terraform {
+			if m := terraformResourcePattern.FindStringSubmatch(line); m != nil {
+				add(TerraformResource, m[1]+"."+m[2], n)
+			}
+		}
+		for _, pattern := range []*regexp.Regexp{arnPattern, azureResourcePattern, gcpResourcePattern} {
+			for _, id := range pattern.FindAllString(line, -1) {
+				add(CloudResource, strings.TrimRight(id, ".:/"), n)
+			}
+		}
+		for _, raw := range urlPattern.FindAllString(line, -1) {
+			raw = strings.TrimRight(raw, ".:/")
+			u, err := url.Parse(raw)
+			// Templated URLs stop at the template, leaving no usable host
+			if err != nil || !strings.Contains(u.Hostname(), ".") || isPublicHost(u.Hostname()) {
+				continue
+			}
+			u.RawQuery, u.Fragment = "", ""
+			add(URL, u.String(), n)
+		}
+		for _, m := range hostSettingPattern.FindAllStringSubmatch(line, -1) {
+			host := strings.ToLower(m[1])
+			if !isPublicHost(host) && !looksLikeFileName(host) {
+				add(Hostname, host, n)
+			}
+		}
+	}
+	return found
+}
+
+// publicHosts are domains of public services that projects commonly link to
+// but do not own.
+var publicHosts = []string{
+	"localhost", "example.com", "example.org", "example.net",
+	"github.com", "githubusercontent.com", "github.io", "gitlab.com", "bitbucket.org",
+	"golang.org", "go.dev", "gopkg.in", "npmjs.com", "npmjs.org", "yarnpkg.com", "pypi.org", "python.org",
+	"crates.io", "rubygems.org", "maven.org", "apache.org", "w3.org", "json-schema.org", "schema.org",
+	"opensource.org", "shields.io", "docker.com", "docker.io", "mozilla.org", "wikipedia.org",
+	"googleapis.com", "microsoft.com", "stackoverflow.com", "creativecommons.org", "semver.org",
+}
+
+// isPublicHost reports whether host is empty, a loopback address, or belongs
+// to a well-known public service.
+func isPublicHost(host string) bool {
+	host = strings.ToLower(host)
+	if host == "" {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback() || ip.IsUnspecified()
+	}
+	for _, public := range publicHosts {
+		if host == public || strings.HasSuffix(host, "."+public) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileExtensions are suffixes that make a dotted name a file rather than a
+// host, e.g. "config.json".
+var fileExtensions = []string{".json", ".yaml", ".yml", ".toml", ".conf", ".cfg", ".ini", ".py", ".js", ".ts", ".go", ".rb", ".sh", ".tf", ".html", ".css", ".xml", ".pem", ".key", ".crt", ".log"}
+
+// looksLikeFileName reports whether a dotted name is more likely a file name
+// than a hostname.
+func looksLikeFileName(name string) bool {
+	for _, ext := range fileExtensions {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package endpoints
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestScanFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []Endpoint
+	}{
+		{
+			name:    "terraform resources and ARNs",
+			file:    "infra/main.tf",
+			content: "resource \"aws_s3_bucket\" \"assets\" {\n  bucket = \"widget-assets\"\n}\n\ndata \"aws_iam_policy\" \"read\" {\n  arn = \"arn:aws:iam::123456789012:policy/widget-read\"\n}\n",
+			want: []Endpoint{
+				{Kind: TerraformResource, Value: "aws_s3_bucket.assets", File: "infra/main.tf", Line: 1},
+				{Kind: CloudResource, Value: "arn:aws:iam::123456789012:policy/widget-read", File: "infra/main.tf", Line: 6},
+			},
+		},
+		{
+			name:    "resource blocks outside terraform files",
+			file:    "notes.hcl",
+			content: "resource \"aws_s3_bucket\" \"assets\" {}\n",
+		},
+		{
+			name:    "config hosts and URLs",
+			file:    "config/prod.yaml",
+			content: "db_host: db.widget.internal\napi:\n  endpoint: \"https://api.widget.io/v1?key=secret\"\nschema: https://json-schema.org/draft-07/schema\nlocal: http://localhost:8080/\nconfig_file: settings.yaml\n",
+			want: []Endpoint{
+				{Kind: Hostname, Value: "db.widget.internal", File: "config/prod.yaml", Line: 1},
+				{Kind: URL, Value: "https://api.widget.io/v1", File: "config/prod.yaml", Line: 3},
+			},
+		},
+		{
+			name:    "nginx server name",
+			file:    "deploy/nginx.conf",
+			content: "server {\n    server_name www.widget.app widget.app;\n}\n",
+			want: []Endpoint{
+				{Kind: Hostname, Value: "www.widget.app", File: "deploy/nginx.conf", Line: 2},
+			},
+		},
+		{
+			name:    "cloud resource names",
+			file:    "deploy.sh",
+			content: "gcloud pubsub topics publish projects/widget-prod/topics/events\naz resource show --ids /subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/widget-rg/providers/Microsoft.Web/sites/widget\n",
+			want: []Endpoint{
+				{Kind: CloudResource, Value: "projects/widget-prod/topics/events", File: "deploy.sh", Line: 1},
+				{Kind: CloudResource, Value: "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/widget-rg/providers/Microsoft.Web/sites/widget", File: "deploy.sh", Line: 2},
+			},
+		},
+		{
+			name:    "templated URLs",
+			file:    "app.js",
+			content: "fetch(`https://${host}/api`)\nconst u = \"https://{{ .Host }}/x\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scanFile(tt.file, []byte(tt.content))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("scanFile() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkipFile(t *testing.T) {
+	tests := map[string]bool{
+		"main.go":                       false,
+		"config/prod.yaml":              false,
+		"README.md":                     true,
+		"docs/guide.rst":                true,
+		"LICENSE":                       true,
+		"package-lock.json":             true,
+		"go.sum":                        true,
+		"vendor/github.com/x/y/main.go": true,
+		"web/node_modules/a/index.js":   true,
+		".bury-it.md":                   true,
+	}
+	for file, want := range tests {
+		if got := skipFile(file); got != want {
+			t.Errorf("skipFile(%q) = %v, want %v", file, got, want)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"infra/main.tf": "resource \"aws_sqs_queue\" \"jobs\" {}\n",
+		"app.env":       "API_HOST=api.widget.io\n",
+		"worker.env":    "API_HOST=api.widget.io\nCALLBACK=https://hooks.widget.io/done\n",
+		"README.md":     "Deployed at https://widget.io\n",
+		"logo.png":      "\x89PNG\x00\x00https://binary.widget.io",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{{"init", "-q"}, {"add", "-A"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	got, err := Scan(dir)
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	want := []Endpoint{
+		{Kind: TerraformResource, Value: "aws_sqs_queue.jobs", File: "infra/main.tf", Line: 1},
+		{Kind: Hostname, Value: "api.widget.io", File: "app.env", Line: 1},
+		{Kind: URL, Value: "https://hooks.widget.io/done", File: "worker.env", Line: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Scan() = %+v, want %+v", got, want)
+	}
+}
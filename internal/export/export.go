@@ -0,0 +1,161 @@
+// Package export copies the issues and pull requests of a GitHub repository
+// into a buried project in resumable steps, so that repositories with tens of
+// thousands of issues survive interruptions and rate limits.
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/atomicfile"
+	"github.com/deanhigh/bury-it/internal/github"
+	"github.com/deanhigh/bury-it/internal/metadata"
+)
+
+// Checkpoint records how far an issue export has got.
+type Checkpoint struct {
+	// Repository is the owner/name of the exported repository.
+	Repository string `json:"repository"`
+	// Next is the URL of the next page to fetch; empty before the first page.
+	Next string `json:"next,omitempty"`
+	// Exported is the number of issues and pull requests written so far.
+	Exported int `json:"exported"`
+	// Offset is the size of the export file once the exported pages were
+	// written. Anything after it is a partially written page and is dropped
+	// on resume.
+	Offset int64 `json:"offset"`
+	// Complete is true once every page has been exported.
+	Complete bool `json:"complete"`
+	// UpdatedAt is when the checkpoint was last written.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// LoadCheckpoint reads the export checkpoint in a project directory. It
+// returns nil if no export was started.
+func LoadCheckpoint(dir string) (*Checkpoint, error) {
+	data, err := os.ReadFile(filepath.Join(dir, metadata.IssueExportCheckpointFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export checkpoint: %w", err)
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("invalid export checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+// save writes the checkpoint to a project directory.
+func (cp *Checkpoint) save(dir string) error {
+	cp.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode export checkpoint: %w", err)
+	}
+	path := filepath.Join(dir, metadata.IssueExportCheckpointFileName)
+	if err := atomicfile.Write(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write export checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Issues exports the issues and pull requests of a repository into a project
+// directory, one page at a time.
+type Issues struct {
+	// Dir is the project directory to write the export to.
+	Dir string
+	// Repository is the owner/name of the repository to export.
+	Repository string
+	// Fetch returns the page of issues at next, or the first page if next is
+	// empty, and the URL of the following page.
+	Fetch func(next string) ([]github.Issue, string, error)
+	// MaxWait is the longest the export waits for a rate limit to reset
+	// before stopping. Stopped exports resume from their checkpoint.
+	MaxWait time.Duration
+	// Progress, if set, is called after each page with the number of issues
+	// exported so far.
+	Progress func(exported int)
+	// Sleep waits for the given duration; time.Sleep if nil.
+	Sleep func(time.Duration)
+}
+
+// Run exports the remaining pages, continuing from the checkpoint in the
+// project directory if there is one. It returns the checkpoint reached, which
+// is saved after every page, together with any error that stopped the export.
+func (x *Issues) Run() (*Checkpoint, error) {
+	cp, err := LoadCheckpoint(x.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if cp == nil {
+		cp = &Checkpoint{Repository: x.Repository}
+	} else if cp.Repository != x.Repository {
+		return cp, fmt.Errorf("an export of %s was started in %s; remove %s to export %s instead",
+			cp.Repository, x.Dir, metadata.IssueExportCheckpointFileName, x.Repository)
+	}
+	if cp.Complete {
+		return cp, nil
+	}
+
+	file, err := os.OpenFile(filepath.Join(x.Dir, metadata.IssueExportFileName), os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return cp, fmt.Errorf("failed to open export file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	if err := file.Truncate(cp.Offset); err != nil {
+		return cp, fmt.Errorf("failed to truncate export file: %w", err)
+	}
+	if _, err := file.Seek(cp.Offset, 0); err != nil {
+		return cp, fmt.Errorf("failed to seek export file: %w", err)
+	}
+
+	sleep := x.Sleep
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+	for !cp.Complete {
+		page, next, err := x.Fetch(cp.Next)
+		var rateErr *github.RateLimitError
+		if errors.As(err, &rateErr) {
+			wait := time.Until(rateErr.Reset) + time.Second
+			if wait > x.MaxWait {
+				return cp, err
+			}
+			sleep(wait)
+			continue
+		}
+		if err != nil {
+			return cp, err
+		}
+
+		var written int64
+		for _, issue := range page {
+			line, err := json.Marshal(issue)
+			if err != nil {
+				return cp, fmt.Errorf("failed to encode issue #%d: %w", issue.Number, err)
+			}
+			n, err := file.Write(append(line, '\n'))
+			if err != nil {
+				return cp, fmt.Errorf("failed to write export file: %w", err)
+			}
+			written += int64(n)
+		}
+		cp.Offset += written
+		cp.Exported += len(page)
+		cp.Next = next
+		cp.Complete = next == ""
+		if err := cp.save(x.Dir); err != nil {
+			return cp, err
+		}
+		if x.Progress != nil {
+			x.Progress(cp.Exported)
+		}
+	}
+	return cp, nil
+}
@@ -0,0 +1,126 @@
+package export
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/github"
+	"github.com/deanhigh/bury-it/internal/metadata"
+)
+
+// pages is a fake paginated issue list of three pages.
+var pages = map[string]struct {
+	issues []github.Issue
+	next   string
+}{
+	"":   {issues: []github.Issue{{Number: 1}, {Number: 2}}, next: "p2"},
+	"p2": {issues: []github.Issue{{Number: 3}, {Number: 4}}, next: "p3"},
+	"p3": {issues: []github.Issue{{Number: 5}}},
+}
+
+func fetchPages(failAt string, failErr error) func(string) ([]github.Issue, string, error) {
+	return func(next string) ([]github.Issue, string, error) {
+		if next == failAt && failErr != nil {
+			err := failErr
+			failErr = nil
+			return nil, "", err
+		}
+		p := pages[next]
+		return p.issues, p.next, nil
+	}
+}
+
+func TestIssues_Run(t *testing.T) {
+	tests := []struct {
+		name      string
+		failAt    string
+		failErr   error
+		maxWait   time.Duration
+		wantStop  bool
+		wantSlept bool
+	}{
+		{name: "complete export"},
+		{name: "interrupted export resumes", failAt: "p2", failErr: errors.New("connection reset"), wantStop: true},
+		{name: "short rate limit is waited out", failAt: "p3", failErr: &github.RateLimitError{Reset: time.Now().Add(time.Minute)}, maxWait: time.Hour, wantSlept: true},
+		{name: "long rate limit stops the export", failAt: "p3", failErr: &github.RateLimitError{Reset: time.Now().Add(2 * time.Hour)}, maxWait: time.Hour, wantStop: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			slept := false
+			x := &Issues{
+				Dir:        dir,
+				Repository: "owner/repo",
+				Fetch:      fetchPages(tt.failAt, tt.failErr),
+				MaxWait:    tt.maxWait,
+				Sleep:      func(time.Duration) { slept = true },
+			}
+
+			cp, err := x.Run()
+			if tt.wantStop {
+				if err == nil || cp.Complete {
+					t.Fatalf("Run() = %+v, %v, want an incomplete export and an error", cp, err)
+				}
+				// A page written without its checkpoint is dropped on resume.
+				f, openErr := os.OpenFile(filepath.Join(dir, metadata.IssueExportFileName), os.O_APPEND|os.O_WRONLY, 0644)
+				if openErr != nil {
+					t.Fatalf("Failed to open export: %v", openErr)
+				}
+				_, _ = fmt.Fprintln(f, `{"number": 99}`)
+				_ = f.Close()
+
+				cp, err = x.Run()
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if !cp.Complete || cp.Exported != 5 {
+				t.Errorf("Run() = %+v, want a complete export of 5 issues", cp)
+			}
+			if slept != tt.wantSlept {
+				t.Errorf("Run() slept = %v, want %v", slept, tt.wantSlept)
+			}
+
+			if got := countLines(t, filepath.Join(dir, metadata.IssueExportFileName)); got != 5 {
+				t.Errorf("export has %d lines, want 5", got)
+			}
+			saved, err := LoadCheckpoint(dir)
+			if err != nil || saved == nil || !saved.Complete {
+				t.Errorf("LoadCheckpoint() = %+v, %v, want a complete checkpoint", saved, err)
+			}
+		})
+	}
+}
+
+func TestIssues_RunOtherRepository(t *testing.T) {
+	dir := t.TempDir()
+	x := &Issues{Dir: dir, Repository: "owner/repo", Fetch: fetchPages("", nil)}
+	if _, err := x.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	x.Repository = "owner/other"
+	if _, err := x.Run(); err == nil {
+		t.Errorf("Run() expected error when resuming an export of another repository")
+	}
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer func() { _ = f.Close() }()
+	n := 0
+	for s := bufio.NewScanner(f); s.Scan(); {
+		n++
+	}
+	return n
+}
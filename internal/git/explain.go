@@ -0,0 +1,120 @@
+package git
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// explainTo is where commands are explained before they run, or nil to run
+// them quietly.
+var explainTo io.Writer
+
+// SetExplain makes commands run from now on be printed to w, with the reason
+// for git commands, before they run, so that users can see and repeat what
+// bury-it does. nil stops explaining them.
+func SetExplain(w io.Writer) {
+	explainTo = w
+}
+
+// reasons are the reasons for git commands, keyed by subcommand, or by
+// subcommand and action for those that take one, such as "subtree add".
+var reasons = map[string]string{
+	"add":               "stage the files the burial changed",
+	"archive":           "export the tracked files of the latest commit, without history",
+	"blame":             "show which commit last changed each line",
+	"branch":            "create or find a branch",
+	"bundle create":     "write history to a bundle file",
+	"bundle list-heads": "check that the file is a git bundle",
+	"bundle unbundle":   "read history from a bundle file",
+	"cat-file":          "read objects from the repository",
+	"check-attr":        "find the files stored with Git LFS",
+	"checkout":          "switch branches",
+	"clean":             "remove untracked files left in the project directory",
+	"clone":             "copy the source repository into a temporary directory",
+	"commit":            "record the change in the graveyard's history",
+	"commit-tree":       "write a commit without touching the working tree",
+	"config":            "read git configuration",
+	"diff":              "compare the index, working tree, or commits",
+	"fetch":             "fetch commits from another repository",
+	"for-each-ref":      "list branches and tags",
+	"gc":                "remove objects no longer reachable from any commit",
+	"grep":              "search the files of the graveyard",
+	"hash-object":       "store content as an object",
+	"init":              "create a repository",
+	"lfs fetch":         "download the Git LFS objects of every commit",
+	"lfs version":       "check that Git LFS is installed",
+	"log":               "read commit history",
+	"ls-files":          "list tracked files",
+	"ls-remote":         "check that the remote repository can be reached",
+	"ls-tree":           "list the files of a commit",
+	"push":              "publish commits to a remote",
+	"reflog":            "expire reflog entries so old objects can be removed",
+	"remote get-url":    "find where the repository was cloned from",
+	"reset":             "move the graveyard back to an earlier commit",
+	"rev-parse":         "resolve a commit, branch, or repository path",
+	"rev-list":          "list the commits or objects of history",
+	"revert":            "undo a burial with a new commit",
+	"rm":                "remove files from the graveyard",
+	"show":              "read a file as of a commit",
+	"stash":             "list stashes",
+	"status":            "check for uncommitted changes",
+	"submodule update":  "clone the source's submodules",
+	"subtree add":       "graft the source's history into the graveyard under the project directory",
+	"subtree split":     "find the history of a project directory",
+	"svn":               "convert Subversion history to git",
+	"symbolic-ref":      "find the current or default branch",
+	"tag":               "create, list, or delete tags",
+	"update-ref":        "move a branch to a new commit",
+	"version":           "check the installed version of git",
+}
+
+// explain prints a command and, for git commands, the reason for it.
+func explain(name string, args []string) {
+	if explainTo == nil {
+		return
+	}
+	if name == "git" {
+		if reason := reason(args); reason != "" {
+			fmt.Fprintf(explainTo, "# %s\n", reason)
+		}
+	}
+	words := make([]string, 0, len(args)+1)
+	for _, arg := range append([]string{name}, args...) {
+		words = append(words, ShellQuote(arg))
+	}
+	fmt.Fprintf(explainTo, "$ %s\n", strings.Join(words, " "))
+}
+
+// reason returns the reason for the git command given by args, or "" if
+// there is none.
+func reason(args []string) string {
+	// Skip options to git itself, such as -C dir, to reach the subcommand
+	i := 0
+	for i < len(args) && strings.HasPrefix(args[i], "-") {
+		if args[i] == "-C" || args[i] == "-c" {
+			i++
+		}
+		i++
+	}
+	if i >= len(args) {
+		return reasons["version"]
+	}
+	sub := args[i]
+	if i+1 < len(args) {
+		if reason, ok := reasons[sub+" "+args[i+1]]; ok {
+			return reason
+		}
+	}
+	return reasons[sub]
+}
+
+// ShellQuote quotes s for a POSIX shell if it needs it.
+func ShellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@+,%^", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -2,27 +2,172 @@
 package git
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
-// IsValidRepo checks if the given path is a valid git repository.
+// niceness is the niceness commands run at, or 0 to run them at the
+// priority of bury-it itself.
+var niceness int
+
+// SetLowPriority makes commands run from now on at niceness n, from 1 to 19,
+// and on Linux in the lowest best-effort I/O class, so that long burials
+// leave shared machines to interactive work. 0 restores normal priority.
+func SetLowPriority(n int) {
+	niceness = n
+}
+
+// Command returns a command that runs name with args, under nice and ionice
+// where they are available if a low priority was set with SetLowPriority.
+// The command is explained first if SetExplain was called.
+func Command(name string, args ...string) *exec.Cmd {
+	explain(name, args)
+	if niceness == 0 {
+		return exec.Command(name, args...)
+	}
+	argv := append([]string{name}, args...)
+	if runtime.GOOS == "linux" {
+		if _, err := exec.LookPath("ionice"); err == nil {
+			argv = append([]string{"ionice", "-c", "2", "-n", "7"}, argv...)
+		}
+	}
+	if _, err := exec.LookPath("nice"); err == nil {
+		argv = append([]string{"nice", "-n", strconv.Itoa(niceness)}, argv...)
+	}
+	return exec.Command(argv[0], argv[1:]...)
+}
+
+// IsValidRepo checks if the given path is a valid git repository: a working
+// tree whose .git is a directory, or a file pointing at one, as in a linked
+// worktree or a submodule's checkout.
 func IsValidRepo(path string) bool {
+	_, ok := gitDirOf(path)
+	return ok
+}
+
+// gitDirPrefix starts the content of a .git file pointing at the git
+// directory of a linked worktree or a submodule's checkout.
+const gitDirPrefix = "gitdir: "
+
+// gitDirOf returns the git directory of the working tree at path, following
+// a .git file to it.
+func gitDirOf(path string) (string, bool) {
 	gitDir := filepath.Join(path, ".git")
-	info, err := os.Stat(gitDir)
+	if info, err := os.Stat(gitDir); err == nil && info.IsDir() {
+		return gitDir, true
+	}
+	target := LinkedGitDir(path)
+	if target == "" {
+		return "", false
+	}
+	if info, err := os.Stat(target); err != nil || !info.IsDir() {
+		return "", false
+	}
+	return target, true
+}
+
+// LinkedGitDir returns the git directory the .git file of the working tree
+// at path points at, whether or not it still exists, or "" if its .git is
+// not such a file.
+func LinkedGitDir(path string) string {
+	gitFile := filepath.Join(path, ".git")
+	if info, err := os.Stat(gitFile); err != nil || !info.Mode().IsRegular() {
+		return ""
+	}
+	data, err := os.ReadFile(gitFile)
+	if err != nil {
+		return ""
+	}
+	target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), gitDirPrefix)
+	if !ok {
+		return ""
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(path, target)
+	}
+	return target
+}
+
+// IsShallow reports whether the repository at path is a shallow clone,
+// missing the history before its shallow commits.
+func IsShallow(path string) bool {
+	out, err := output(path, "rev-parse", "--is-shallow-repository")
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+// HasCommits reports whether HEAD of the repository at path points to a
+// commit, which it does not before the first commit is made.
+func HasCommits(path string) bool {
+	_, err := output(path, "rev-parse", "--verify", "--quiet", "HEAD^{commit}")
+	return err == nil
+}
+
+// Unshallow fetches the history a shallow clone is missing from its
+// default remote.
+func Unshallow(repoPath string) error {
+	if _, err := output(repoPath, "fetch", "--quiet", "--unshallow"); err != nil {
+		return fmt.Errorf("git fetch --unshallow failed: %w", err)
+	}
+	return nil
+}
+
+// IsBareRepo reports whether path is a bare repository, with no working
+// tree, rather than a directory inside one.
+func IsBareRepo(path string) bool {
+	out, err := output(path, "rev-parse", "--is-bare-repository", "--absolute-git-dir")
 	if err != nil {
 		return false
 	}
-	return info.IsDir()
+	fields := strings.Split(strings.TrimSpace(out), "\n")
+	if len(fields) != 2 || fields[0] != "true" {
+		return false
+	}
+	dir, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	gitDir, err := filepath.EvalSymlinks(fields[1])
+	return err == nil && filepath.Clean(dir) == gitDir
+}
+
+// IsBundle reports whether path is a git bundle file, such as one written
+// by git bundle create.
+func IsBundle(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	cmd := Command("git", "bundle", "list-heads", path)
+	return cmd.Run() == nil
+}
+
+// Clone clones a remote repository to the destination path. env adds to
+// the environment git runs in, e.g. from ExtraHeaderEnv.
+func Clone(url, dest string, env ...string) error {
+	return CloneBranch(url, dest, "", env...)
 }
 
-// Clone clones a remote repository to the destination path.
-func Clone(url, dest string) error {
-	cmd := exec.Command("git", "clone", url, dest)
+// CloneBranch clones a repository to the destination path like Clone, with
+// branch checked out instead of the remote's default branch, if given.
+func CloneBranch(url, dest, branch string, env ...string) error {
+	args := []string{"clone"}
+	if branch != "" {
+		args = append(args, "--branch", branch)
+	}
+	cmd := Command("git", append(args, url, dest)...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
@@ -31,9 +176,194 @@ func Clone(url, dest string) error {
 	return nil
 }
 
+// Submodule is a submodule recorded in a repository.
+type Submodule struct {
+	// Path is the slash-separated path of the submodule in the repository.
+	Path string
+	// URL is the submodule's URL from .gitmodules, or "" if it has none.
+	URL string
+	// Commit is the commit of the submodule the repository records.
+	Commit string
+}
+
+// Submodules returns the submodules recorded at HEAD, with their URLs read
+// from .gitmodules as of HEAD.
+func Submodules(repoPath string) ([]Submodule, error) {
+	return SubmodulesIn(repoPath, "")
+}
+
+// SubmodulesIn returns the submodules recorded at HEAD under dir, with paths
+// relative to it and URLs read from its .gitmodules, as a repository added
+// there with git subtree records them. An empty dir is the repository root.
+func SubmodulesIn(repoPath, dir string) ([]Submodule, error) {
+	tree, gitmodules := "HEAD", "HEAD:.gitmodules"
+	if dir != "" {
+		tree, gitmodules = "HEAD:"+dir, "HEAD:"+dir+"/.gitmodules"
+	}
+	entries, err := lsTree(repoPath, tree)
+	if err != nil {
+		return nil, err
+	}
+	var submodules []Submodule
+	for _, e := range entries {
+		if e.Mode == ModeSubmodule {
+			submodules = append(submodules, Submodule{Path: e.Path, Commit: e.Object})
+		}
+	}
+	if len(submodules) == 0 {
+		return nil, nil
+	}
+
+	// A missing or unreadable .gitmodules only leaves the URLs out
+	out, _ := output(repoPath, "config", "--blob", gitmodules, "--get-regexp", `^submodule\..*\.(path|url)$`)
+	paths := map[string]string{}
+	urls := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(line, " ")
+		i := strings.LastIndex(key, ".")
+		if !ok || i < 0 {
+			continue
+		}
+		name := strings.TrimPrefix(key[:i], "submodule.")
+		switch key[i+1:] {
+		case "path":
+			paths[strings.TrimSuffix(value, "/")] = name
+		case "url":
+			urls[name] = value
+		}
+	}
+	for i := range submodules {
+		if name, ok := paths[submodules[i].Path]; ok {
+			submodules[i].URL = urls[name]
+		}
+	}
+	return submodules, nil
+}
+
+// UpdateSubmodules clones the submodules of repoPath, recursively, and
+// checks out the commits it records, with env set for git.
+func UpdateSubmodules(repoPath string, env ...string) error {
+	cmd := Command("git", "-C", repoPath, "submodule", "update", "--init", "--recursive")
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git submodule update failed: %s", strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// LFSFiles returns the files in the tree of HEAD that .gitattributes hands
+// to Git LFS, whether or not git-lfs is installed.
+func LFSFiles(repoPath string) ([]string, error) {
+	entries, err := TrackedTree(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, e := range entries {
+		if e.Mode != ModeSubmodule && e.Mode != ModeSymlink {
+			paths = append(paths, e.Path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	cmd := Command("git", "-C", repoPath, "check-attr", "--cached", "-z", "--stdin", "filter")
+	cmd.Stdin = strings.NewReader(strings.Join(paths, "\x00") + "\x00")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git check-attr failed: %s", strings.TrimSpace(stderr.String()))
+	}
+	// <path> NUL <attribute> NUL <value> NUL
+	fields := strings.Split(stdout.String(), "\x00")
+	var files []string
+	for i := 0; i+2 < len(fields); i += 3 {
+		if fields[i+2] == "lfs" {
+			files = append(files, fields[i])
+		}
+	}
+	return files, nil
+}
+
+// HasLFS reports whether git-lfs is installed.
+func HasLFS() bool {
+	return Command("git", "lfs", "version").Run() == nil
+}
+
+// LFSFetchAll fetches the Git LFS objects of every ref of repoPath from its
+// origin, with env set for git.
+func LFSFetchAll(repoPath string, env ...string) error {
+	cmd := Command("git", "-C", repoPath, "lfs", "fetch", "--all")
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Env = append(cmd.Env, env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git lfs fetch failed: %s", strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// GitPath returns the path of name within the git directory of repoPath,
+// such as "lfs/objects".
+func GitPath(repoPath, name string) (string, error) {
+	out, err := output(repoPath, "rev-parse", "--git-path", name)
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	p := strings.TrimSpace(out)
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(repoPath, p)
+	}
+	return p, nil
+}
+
+// ExtraHeaderEnv returns environment variables that make git send header
+// with its HTTP requests, without writing it to any configuration file.
+func ExtraHeaderEnv(header string) []string {
+	return ConfigEnv("http.extraHeader", header)
+}
+
+// ConfigEnv returns environment variables that set git configuration for a
+// single command, given as alternating keys and values, without writing it
+// to any configuration file.
+func ConfigEnv(keysAndValues ...string) []string {
+	n := len(keysAndValues) / 2
+	env := []string{"GIT_CONFIG_COUNT=" + strconv.Itoa(n)}
+	for i := 0; i < n; i++ {
+		env = append(env,
+			fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", i, keysAndValues[2*i]),
+			fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", i, keysAndValues[2*i+1]))
+	}
+	return env
+}
+
+// LsRemote checks that a remote repository can be reached by listing its
+// HEAD, without prompting for credentials.
+func LsRemote(url string) error {
+	cmd := Command("git", "ls-remote", "--quiet", url, "HEAD")
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return fmt.Errorf("git ls-remote failed: %s", msg)
+	}
+	return nil
+}
+
 // GetRemoteURL returns the origin remote URL for a repository.
 func GetRemoteURL(repoPath string) (string, error) {
-	cmd := exec.Command("git", "-C", repoPath, "remote", "get-url", "origin")
+	cmd := Command("git", "-C", repoPath, "remote", "get-url", "origin")
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -47,7 +377,7 @@ func GetRemoteURL(repoPath string) (string, error) {
 // GetDefaultBranch returns the default branch name for a repository.
 func GetDefaultBranch(repoPath string) (string, error) {
 	// Try to get the current branch first
-	cmd := exec.Command("git", "-C", repoPath, "rev-parse", "--abbrev-ref", "HEAD")
+	cmd := Command("git", "-C", repoPath, "rev-parse", "--abbrev-ref", "HEAD")
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -58,7 +388,7 @@ func GetDefaultBranch(repoPath string) (string, error) {
 	if branch == "" || branch == "HEAD" {
 		// Detached HEAD, try common branch names
 		for _, name := range []string{"main", "master"} {
-			cmd := exec.Command("git", "-C", repoPath, "rev-parse", "--verify", name)
+			cmd := Command("git", "-C", repoPath, "rev-parse", "--verify", name)
 			if cmd.Run() == nil {
 				return name, nil
 			}
@@ -70,10 +400,20 @@ func GetDefaultBranch(repoPath string) (string, error) {
 
 // SubtreeAdd adds a repository as a subtree with full history.
 func SubtreeAdd(graveyardPath, sourceRepoPath, prefix string) error {
+	return SubtreeAddRev(graveyardPath, sourceRepoPath, prefix, "")
+}
+
+// SubtreeAddRev adds a repository as a subtree with the history of rev, a
+// branch, tag, or full commit hash, or of its default branch if rev is "".
+func SubtreeAddRev(graveyardPath, sourceRepoPath, prefix, rev string) error {
 	// Get the default branch of the source repo
-	branch, err := GetDefaultBranch(sourceRepoPath)
-	if err != nil {
-		return fmt.Errorf("failed to get source branch: %w", err)
+	branch := rev
+	if branch == "" {
+		var err error
+		branch, err = GetDefaultBranch(sourceRepoPath)
+		if err != nil {
+			return fmt.Errorf("failed to get source branch: %w", err)
+		}
 	}
 
 	// Get absolute path to source repo
@@ -83,7 +423,7 @@ func SubtreeAdd(graveyardPath, sourceRepoPath, prefix string) error {
 	}
 
 	// Add as subtree
-	cmd := exec.Command("git", "-C", graveyardPath, "subtree", "add",
+	cmd := Command("git", "-C", graveyardPath, "subtree", "add",
 		"--prefix="+prefix, absSourcePath, branch)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
@@ -103,8 +443,8 @@ func CopyTrackedFiles(sourcePath, destPath string) error {
 
 	// Use git archive to create a tar of tracked files, then extract
 	// This automatically respects .gitignore since only tracked files are included
-	archiveCmd := exec.Command("git", "-C", sourcePath, "archive", "--format=tar", "HEAD")
-	extractCmd := exec.Command("tar", "-xf", "-", "-C", destPath)
+	archiveCmd := Command("git", "-C", sourcePath, "archive", "--format=tar", "HEAD")
+	extractCmd := Command("tar", "-xf", "-", "-C", destPath)
 
 	// Pipe archive output to tar extract
 	var archiveStderr, extractStderr bytes.Buffer
@@ -134,9 +474,121 @@ func CopyTrackedFiles(sourcePath, destPath string) error {
 	return nil
 }
 
-// StageAll stages all changes in the repository.
-func StageAll(repoPath string) error {
-	cmd := exec.Command("git", "-C", repoPath, "add", "-A")
+// TreeEntry is a file in the tree of a commit.
+type TreeEntry struct {
+	// Mode is the file's mode: 100644 for a file, 100755 for an executable,
+	// 120000 for a symbolic link, or 160000 for a submodule.
+	Mode string
+	// Object is the hash of the file's blob, or of the submodule's commit.
+	Object string
+	// Path is the slash-separated path of the file in the tree.
+	Path string
+}
+
+// Modes of tree entries that are not regular files.
+const (
+	ModeSymlink   = "120000"
+	ModeSubmodule = "160000"
+)
+
+// TrackedTree returns every file in the tree of HEAD.
+func TrackedTree(repoPath string) ([]TreeEntry, error) {
+	return lsTree(repoPath, "HEAD")
+}
+
+// lsTree returns every file in tree, with paths relative to it.
+func lsTree(repoPath, tree string) ([]TreeEntry, error) {
+	out, err := output(repoPath, "ls-tree", "-r", "-z", "--full-tree", tree)
+	if err != nil {
+		return nil, fmt.Errorf("git ls-tree failed: %w", err)
+	}
+	var entries []TreeEntry
+	for _, record := range strings.Split(out, "\x00") {
+		// <mode> SP <type> SP <object> TAB <path>
+		info, path, ok := strings.Cut(record, "\t")
+		fields := strings.Fields(info)
+		if !ok || len(fields) != 3 {
+			continue
+		}
+		entries = append(entries, TreeEntry{Mode: fields[0], Object: fields[2], Path: path})
+	}
+	return entries, nil
+}
+
+// ReadBlobs reads the content of each blob in objects, in order, passing it
+// to fn with the blob's index. fn must not keep r once it returns.
+func ReadBlobs(repoPath string, objects []string, fn func(i int, r io.Reader) error) error {
+	cmd := Command("git", "-C", repoPath, "cat-file", "--batch")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("git cat-file failed to start: %w", err)
+	}
+	go func() {
+		for _, object := range objects {
+			if _, err := fmt.Fprintln(stdin, object); err != nil {
+				break
+			}
+		}
+		_ = stdin.Close()
+	}()
+
+	readErr := readBatch(bufio.NewReader(stdout), len(objects), fn)
+	if readErr != nil {
+		// Stop git rather than wait for it to write what is left
+		_ = cmd.Process.Kill()
+	}
+	if err := cmd.Wait(); readErr == nil && err != nil {
+		return fmt.Errorf("git cat-file failed: %s", strings.TrimSpace(stderr.String()))
+	}
+	return readErr
+}
+
+// readBatch reads n objects written by git cat-file --batch, passing each to
+// fn.
+func readBatch(r *bufio.Reader, n int, fn func(i int, r io.Reader) error) error {
+	for i := 0; i < n; i++ {
+		// <object> SP <type> SP <size> LF <content> LF
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read object %d from git cat-file: %w", i, err)
+		}
+		fields := strings.Fields(header)
+		if len(fields) != 3 || fields[1] != "blob" {
+			return fmt.Errorf("git cat-file: unexpected object %s", strings.TrimSpace(header))
+		}
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("git cat-file: invalid size in %s", strings.TrimSpace(header))
+		}
+		content := io.LimitReader(r, size)
+		if err := fn(i, content); err != nil {
+			return err
+		}
+		if _, err := io.Copy(io.Discard, content); err != nil {
+			return fmt.Errorf("failed to read object %s: %w", fields[0], err)
+		}
+		if _, err := r.Discard(1); err != nil {
+			return fmt.Errorf("failed to read object %s: %w", fields[0], err)
+		}
+	}
+	return nil
+}
+
+// StageAll stages all changes in the repository, with env set for git.
+func StageAll(repoPath string, env ...string) error {
+	cmd := Command("git", "-C", repoPath, "add", "-A")
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
@@ -147,7 +599,7 @@ func StageAll(repoPath string) error {
 
 // StageFile stages a specific file in the repository.
 func StageFile(repoPath, filePath string) error {
-	cmd := exec.Command("git", "-C", repoPath, "add", filePath)
+	cmd := Command("git", "-C", repoPath, "add", filePath)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
@@ -158,7 +610,25 @@ func StageFile(repoPath, filePath string) error {
 
 // Commit creates a commit with the given message.
 func Commit(repoPath, message string) error {
-	cmd := exec.Command("git", "-C", repoPath, "commit", "-m", message)
+	cmd := Command("git", "-C", repoPath, "commit", "-m", message)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git commit failed: %s", strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// CommitAs commits what is staged in a repository bury-it made itself, as
+// the given author and committer, dated date, so that the same content
+// always gives the same commit.
+func CommitAs(repoPath, name, email, message string, date time.Time) error {
+	cmd := Command("git", "-C", repoPath, "-c", "commit.gpgsign=false", "commit", "-q", "--no-verify", "-m", message)
+	stamp := date.UTC().Format(time.RFC3339)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME="+name, "GIT_AUTHOR_EMAIL="+email, "GIT_AUTHOR_DATE="+stamp,
+		"GIT_COMMITTER_NAME="+name, "GIT_COMMITTER_EMAIL="+email, "GIT_COMMITTER_DATE="+stamp,
+	)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
@@ -166,3 +636,956 @@ func Commit(repoPath, message string) error {
 	}
 	return nil
 }
+
+// GrepMatch is a single line matched by Grep.
+type GrepMatch struct {
+	// Path is the file path relative to the repository root.
+	Path string
+	// Line is the 1-based line number of the match.
+	Line int
+	// Text is the content of the matching line.
+	Text string
+}
+
+// Grep searches tracked file contents for pattern, optionally limited to paths.
+func Grep(repoPath, pattern string, ignoreCase bool, paths ...string) ([]GrepMatch, error) {
+	args := []string{"-C", repoPath, "grep", "-n", "-I", "-z", "--full-name", "--no-color"}
+	if ignoreCase {
+		args = append(args, "-i")
+	}
+	args = append(args, "-e", pattern, "--")
+	args = append(args, paths...)
+
+	cmd := Command("git", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// Exit status 1 with no error output means nothing matched
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 && stderr.Len() == 0 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("git grep failed: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	var matches []GrepMatch
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if line == "" {
+			continue
+		}
+		// Each line is formatted as path\0line\0text when using -z
+		parts := strings.SplitN(line, "\x00", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		lineNum, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+		matches = append(matches, GrepMatch{Path: parts[0], Line: lineNum, Text: parts[2]})
+	}
+	return matches, nil
+}
+
+// LogEntry describes a single commit in a repository's history.
+type LogEntry struct {
+	// Hash is the full commit hash.
+	Hash string
+	// Parents are the full hashes of the commit's parents.
+	Parents []string
+	// Author is the name of the commit author.
+	Author string
+	// AuthorEmail is the email address of the commit author.
+	AuthorEmail string
+	// Date is the author date of the commit.
+	Date time.Time
+	// Subject is the first line of the commit message.
+	Subject string
+	// Body is the commit message after the subject line.
+	Body string
+}
+
+// logFormat separates commit fields with NUL and commits with RS.
+const logFormat = "%H%x00%P%x00%aN%x00%aE%x00%aI%x00%s%x00%b%x1e"
+
+// Log returns the commits reachable from revs, optionally limited to paths.
+func Log(repoPath string, revs []string, paths ...string) ([]LogEntry, error) {
+	args := []string{"log", "--format=" + logFormat}
+	args = append(args, revs...)
+	args = append(args, "--")
+	args = append(args, paths...)
+	out, err := output(repoPath, args...)
+	if err != nil {
+		return nil, fmt.Errorf("git log failed: %w", err)
+	}
+
+	var commits []LogEntry
+	for _, record := range strings.Split(out, "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+		fields := strings.SplitN(record, "\x00", 7)
+		if len(fields) != 7 {
+			continue
+		}
+		date, err := time.Parse(time.RFC3339, fields[4])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse commit date %q: %w", fields[4], err)
+		}
+		commits = append(commits, LogEntry{
+			Hash:        fields[0],
+			Parents:     strings.Fields(fields[1]),
+			Author:      fields[2],
+			AuthorEmail: fields[3],
+			Date:        date,
+			Subject:     fields[5],
+			Body:        strings.TrimSpace(fields[6]),
+		})
+	}
+	return commits, nil
+}
+
+// SubtreeSplit returns the commit that was added under prefix by the most
+// recent git subtree add, or an empty string if prefix was not added that way.
+func SubtreeSplit(repoPath, prefix string) (string, error) {
+	splits, err := SubtreeSplits(repoPath, prefix)
+	if err != nil || len(splits) == 0 {
+		return "", err
+	}
+	return splits[0], nil
+}
+
+// SubtreeSplits returns the commits added under prefix by every git subtree
+// add, most recent first.
+func SubtreeSplits(repoPath, prefix string) ([]string, error) {
+	// The prefix is matched as a fixed string, since project names can hold
+	// characters special to regular expressions, and then exactly below
+	commits, err := Log(repoPath, []string{"HEAD", "--fixed-strings", "--grep=git-subtree-dir: " + prefix})
+	if err != nil {
+		return nil, err
+	}
+	var splits []string
+	for _, c := range commits {
+		var dir, split string
+		for _, line := range strings.Split(c.Body, "\n") {
+			if v, ok := strings.CutPrefix(line, "git-subtree-dir: "); ok {
+				dir = strings.TrimSuffix(strings.TrimSpace(v), "/")
+			}
+			if v, ok := strings.CutPrefix(line, "git-subtree-split: "); ok {
+				split = strings.TrimSpace(v)
+			}
+		}
+		if dir == prefix && split != "" {
+			splits = append(splits, split)
+		}
+	}
+	return splits, nil
+}
+
+// Passthrough runs git in repoPath with its output and errors written to
+// stdout and stderr as git formats them, without git's pager.
+func Passthrough(repoPath string, stdout, stderr io.Writer, args ...string) error {
+	cmd := Command("git", append([]string{"-C", repoPath, "--no-pager"}, args...)...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return nil
+}
+
+// ListFiles returns the tracked files in the repository, optionally limited to paths.
+func ListFiles(repoPath string, paths ...string) ([]string, error) {
+	args := append([]string{"ls-files", "-z", "--"}, paths...)
+	out, err := output(repoPath, args...)
+	if err != nil {
+		return nil, fmt.Errorf("git ls-files failed: %w", err)
+	}
+	var files []string
+	for _, f := range strings.Split(out, "\x00") {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// Head returns the commit hash that HEAD points to.
+func Head(repoPath string) (string, error) {
+	out, err := output(repoPath, "rev-parse", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// ResolveCommit returns the full hash of the commit rev names, such as a
+// tag, branch, or abbreviated hash.
+func ResolveCommit(repoPath, rev string) (string, error) {
+	out, err := output(repoPath, "rev-parse", "--verify", "--quiet", "--end-of-options", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("%s does not name a commit in %s", rev, repoPath)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Detach checks out commit in repoPath with a detached HEAD.
+func Detach(repoPath, commit string) error {
+	if _, err := output(repoPath, "checkout", "-q", "--detach", commit); err != nil {
+		return fmt.Errorf("git checkout failed: %w", err)
+	}
+	return nil
+}
+
+// output runs a git command in repoPath and returns its standard output.
+func output(repoPath string, args ...string) (string, error) {
+	cmd := Command("git", append([]string{"-C", repoPath}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("%s", msg)
+	}
+	return stdout.String(), nil
+}
+
+// HasStagedChanges reports whether the repository index differs from HEAD.
+func HasStagedChanges(repoPath string) (bool, error) {
+	cmd := Command("git", "-C", repoPath, "diff", "--cached", "--quiet")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err == nil {
+		return false, nil
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return true, nil
+	}
+	return false, fmt.Errorf("git diff failed: %s", strings.TrimSpace(stderr.String()))
+}
+
+// Stashes returns the repository's stash entries, most recent first.
+func Stashes(repoPath string) ([]string, error) {
+	out, err := output(repoPath, "stash", "list", "--format=%gd: %gs")
+	if err != nil {
+		return nil, fmt.Errorf("git stash list failed: %w", err)
+	}
+	var stashes []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			stashes = append(stashes, line)
+		}
+	}
+	return stashes, nil
+}
+
+// UncommittedFiles returns tracked files with uncommitted changes and
+// untracked files that are not ignored.
+func UncommittedFiles(repoPath string) (modified, untracked []string, err error) {
+	out, err := output(repoPath, "status", "--porcelain", "-z", "--untracked-files=all")
+	if err != nil {
+		return nil, nil, fmt.Errorf("git status failed: %w", err)
+	}
+
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		code, path := entry[:2], entry[3:]
+		switch {
+		case code == "??":
+			untracked = append(untracked, path)
+		default:
+			modified = append(modified, path)
+			// Renames and copies are followed by the original path
+			if code[0] == 'R' || code[0] == 'C' {
+				i++
+			}
+		}
+	}
+	return modified, untracked, nil
+}
+
+// UncommittedPatch returns a patch of uncommitted changes to tracked files
+// followed by the changes recorded in each stash.
+func UncommittedPatch(repoPath string) (string, error) {
+	var b strings.Builder
+
+	diff, err := output(repoPath, "diff", "--binary", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("git diff failed: %w", err)
+	}
+	if diff != "" {
+		b.WriteString("# Uncommitted changes\n")
+		b.WriteString(diff)
+	}
+
+	stashes, err := Stashes(repoPath)
+	if err != nil {
+		return "", err
+	}
+	for i, stash := range stashes {
+		patch, err := output(repoPath, "stash", "show", "-p", "--binary", fmt.Sprintf("stash@{%d}", i))
+		if err != nil {
+			return "", fmt.Errorf("git stash show failed: %w", err)
+		}
+		fmt.Fprintf(&b, "# %s\n", stash)
+		b.WriteString(patch)
+	}
+	return b.String(), nil
+}
+
+// Ref is a branch or tag in a repository.
+type Ref struct {
+	// Name is the short branch or tag name.
+	Name string
+	// IsTag is true for tags and false for branches.
+	IsTag bool
+	// Hash is the commit the ref points to, with annotated tags peeled.
+	Hash string
+	// Date is the committer date of the commit, or the tagger date.
+	Date time.Time
+}
+
+// Refs returns the branches and tags of a repository. Remote-tracking branches
+// are included so that fresh clones report every branch on the remote.
+func Refs(repoPath string) ([]Ref, error) {
+	out, err := output(repoPath, "for-each-ref",
+		"--format=%(refname)%00%(objectname)%00%(*objectname)%00%(creatordate:iso-strict)",
+		"refs/heads", "refs/remotes", "refs/tags")
+	if err != nil {
+		return nil, fmt.Errorf("git for-each-ref failed: %w", err)
+	}
+
+	var refs []Ref
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 4 {
+			continue
+		}
+		ref := Ref{Hash: fields[1]}
+		if fields[2] != "" {
+			ref.Hash = fields[2]
+		}
+		if date, err := time.Parse(time.RFC3339, fields[3]); err == nil {
+			ref.Date = date
+		}
+
+		switch name := fields[0]; {
+		case strings.HasPrefix(name, "refs/heads/"):
+			ref.Name = strings.TrimPrefix(name, "refs/heads/")
+		case strings.HasPrefix(name, "refs/tags/"):
+			ref.Name = strings.TrimPrefix(name, "refs/tags/")
+			ref.IsTag = true
+		default:
+			// refs/remotes/<remote>/<branch>; skip symbolic HEAD refs
+			_, branch, ok := strings.Cut(strings.TrimPrefix(name, "refs/remotes/"), "/")
+			if !ok || branch == "HEAD" {
+				continue
+			}
+			ref.Name = branch
+		}
+
+		key := fmt.Sprintf("%t/%s", ref.IsTag, ref.Name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// Object is an object reachable from a set of revisions.
+type Object struct {
+	// Hash is the object hash.
+	Hash string
+	// Path is the path the object was reached by, empty for commits.
+	Path string
+}
+
+// ListObjects returns the objects reachable from revs, walking only commits
+// that touch paths when paths are given.
+func ListObjects(repoPath string, revs []string, paths ...string) ([]Object, error) {
+	args := append([]string{"rev-list", "--objects"}, revs...)
+	args = append(args, "--")
+	args = append(args, paths...)
+	out, err := output(repoPath, args...)
+	if err != nil {
+		return nil, fmt.Errorf("git rev-list failed: %w", err)
+	}
+
+	var objects []Object
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+		hash, path, _ := strings.Cut(line, " ")
+		objects = append(objects, Object{Hash: hash, Path: path})
+	}
+	return objects, nil
+}
+
+// ObjectInfo describes the type and size of an object.
+type ObjectInfo struct {
+	// Type is the object type: commit, tree, blob, or tag.
+	Type string
+	// Size is the uncompressed size of the object in bytes.
+	Size int64
+	// DiskSize is the size the object occupies on disk in bytes.
+	DiskSize int64
+}
+
+// ObjectInfos returns the type and sizes of the given objects.
+func ObjectInfos(repoPath string, hashes []string) (map[string]ObjectInfo, error) {
+	infos := make(map[string]ObjectInfo, len(hashes))
+	if len(hashes) == 0 {
+		return infos, nil
+	}
+
+	cmd := Command("git", "-C", repoPath, "cat-file",
+		"--batch-check=%(objectname) %(objecttype) %(objectsize) %(objectsize:disk)")
+	cmd.Stdin = strings.NewReader(strings.Join(hashes, "\n") + "\n")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git cat-file failed: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			// Missing objects are reported as "<hash> missing"
+			continue
+		}
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		diskSize, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			continue
+		}
+		infos[fields[0]] = ObjectInfo{Type: fields[1], Size: size, DiskSize: diskSize}
+	}
+	return infos, nil
+}
+
+// LooseObjects returns the bytes taken in the object store by loose objects
+// and by garbage, which git gc packs or deletes.
+func LooseObjects(repoPath string) (loose, garbage int64, err error) {
+	out, err := output(repoPath, "count-objects", "-v")
+	if err != nil {
+		return 0, 0, fmt.Errorf("git count-objects failed: %w", err)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		kib, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "size":
+			loose = kib * 1024
+		case "size-garbage":
+			garbage = kib * 1024
+		}
+	}
+	return loose, garbage, nil
+}
+
+// IsClean reports whether the working tree and index have no changes,
+// including untracked files.
+func IsClean(repoPath string) (bool, error) {
+	out, err := output(repoPath, "status", "--porcelain")
+	if err != nil {
+		return false, fmt.Errorf("git status failed: %w", err)
+	}
+	return strings.TrimSpace(out) == "", nil
+}
+
+// IsPushed reports whether a commit is contained in any remote-tracking branch.
+func IsPushed(repoPath, commit string) (bool, error) {
+	out, err := output(repoPath, "branch", "-r", "--contains", commit)
+	if err != nil {
+		return false, fmt.Errorf("git branch failed: %w", err)
+	}
+	return strings.TrimSpace(out) != "", nil
+}
+
+// ResetHard resets the current branch, index, and working tree to rev.
+func ResetHard(repoPath, rev string) error {
+	if _, err := output(repoPath, "reset", "--hard", rev); err != nil {
+		return fmt.Errorf("git reset failed: %w", err)
+	}
+	return nil
+}
+
+// Clean removes the untracked files and directories under path.
+func Clean(repoPath, path string) error {
+	if _, err := output(repoPath, "clean", "-f", "-d", "-q", "--", path); err != nil {
+		return fmt.Errorf("git clean failed: %w", err)
+	}
+	return nil
+}
+
+// Revert creates a commit reverting commit. For merge commits, mainline is
+// the 1-based parent to revert to; it is ignored for ordinary commits. The
+// paths in keep are left as they are at HEAD, such as a log that later
+// commits appended to, rather than reverted or left in conflict.
+func Revert(repoPath, commit string, mainline int, keep ...string) error {
+	args := []string{"revert", "--no-edit", "--no-commit"}
+	if mainline > 0 {
+		args = append(args, "-m", strconv.Itoa(mainline))
+	}
+	_, revertErr := output(repoPath, append(args, commit)...)
+	unmerged, err := output(repoPath, "diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		_, _ = output(repoPath, "revert", "--abort")
+		return fmt.Errorf("git revert failed: %w", err)
+	}
+	conflicts := strings.Fields(unmerged)
+	if revertErr != nil && (len(conflicts) == 0 || !allIn(conflicts, keep)) {
+		_, _ = output(repoPath, "revert", "--abort")
+		return fmt.Errorf("git revert failed: %w", revertErr)
+	}
+
+	for _, path := range keep {
+		if _, err := output(repoPath, "cat-file", "-e", "HEAD:"+path); err != nil {
+			continue
+		}
+		if _, err := output(repoPath, "checkout", "HEAD", "--", path); err != nil {
+			_, _ = output(repoPath, "revert", "--abort")
+			return fmt.Errorf("git checkout failed: %w", err)
+		}
+	}
+	if _, err := output(repoPath, "commit", "--quiet", "--no-edit", "--allow-empty", "--cleanup=strip"); err != nil {
+		_, _ = output(repoPath, "revert", "--abort")
+		return fmt.Errorf("git commit failed: %w", err)
+	}
+	return nil
+}
+
+// allIn reports whether every one of paths is among set.
+func allIn(paths, set []string) bool {
+	for _, p := range paths {
+		if !slices.Contains(set, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// LastCommitDate returns the committer date of the most recent commit on any
+// ref. It returns the zero time for a repository without commits.
+func LastCommitDate(repoPath string) (time.Time, error) {
+	return LastCommitDateBefore(repoPath, time.Time{})
+}
+
+// LastCommitDateBefore returns the committer date of the most recent commit on
+// any ref made before the given time, or on any date if before is zero. It
+// returns the zero time if there is no such commit.
+func LastCommitDateBefore(repoPath string, before time.Time) (time.Time, error) {
+	args := []string{"log", "-1", "--all", "--format=%cI"}
+	if !before.IsZero() {
+		args = append(args, "--before="+before.Format(time.RFC3339))
+	}
+	out, err := output(repoPath, args...)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("git log failed: %w", err)
+	}
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse(time.RFC3339, out)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse commit date %q: %w", out, err)
+	}
+	return date, nil
+}
+
+// CurrentBranch returns the name of the checked-out branch.
+func CurrentBranch(repoPath string) (string, error) {
+	out, err := output(repoPath, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to determine current branch: %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Checkout switches to branch, creating it from the current commit if create
+// is set.
+func Checkout(repoPath, branch string, create bool) error {
+	args := []string{"checkout", "-q"}
+	if create {
+		args = append(args, "-b")
+	}
+	if _, err := output(repoPath, append(args, branch)...); err != nil {
+		return fmt.Errorf("git checkout failed: %w", err)
+	}
+	return nil
+}
+
+// Push pushes branch to remote.
+func Push(repoPath, remote, branch string) error {
+	if _, err := output(repoPath, "push", "-q", remote, branch); err != nil {
+		return fmt.Errorf("git push failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteBranch deletes the local branch, merged or not.
+func DeleteBranch(repoPath, branch string) error {
+	if _, err := output(repoPath, "branch", "-q", "-D", branch); err != nil {
+		return fmt.Errorf("git branch -D failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteRemoteBranch deletes branch from remote.
+func DeleteRemoteBranch(repoPath, remote, branch string) error {
+	if _, err := output(repoPath, "push", "-q", remote, "--delete", branch); err != nil {
+		return fmt.Errorf("git push --delete failed: %w", err)
+	}
+	return nil
+}
+
+// IsInsideWorkTree reports whether path is inside the working tree of a git
+// repository, at any depth.
+func IsInsideWorkTree(path string) bool {
+	out, err := output(path, "rev-parse", "--is-inside-work-tree")
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+// TopLevel returns the root of the working tree that path is in.
+func TopLevel(path string) (string, error) {
+	out, err := output(path, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("%s is not in a git repository: %w", path, err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// LastCommitFor returns the hash of the most recent commit that changed path,
+// or an empty string if path has never been committed.
+func LastCommitFor(repoPath, path string) (string, error) {
+	out, err := output(repoPath, "log", "-1", "--format=%H", "--", path)
+	if err != nil {
+		return "", fmt.Errorf("git log failed: %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// HasChanges reports whether path has staged, unstaged, or untracked changes.
+func HasChanges(repoPath, path string) (bool, error) {
+	out, err := output(repoPath, "status", "--porcelain", "--", path)
+	if err != nil {
+		return false, fmt.Errorf("git status failed: %w", err)
+	}
+	return strings.TrimSpace(out) != "", nil
+}
+
+// ConfigValue returns the value of a git configuration key as seen from
+// repoPath, or an empty string if it is not set.
+func ConfigValue(repoPath, key string) string {
+	out, err := output(repoPath, "config", "--get", key)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+// Identity returns the git identity of the current user as seen from
+// repoPath: the configured email, or the name if no email is set.
+func Identity(repoPath string) (string, error) {
+	if email := ConfigValue(repoPath, "user.email"); email != "" {
+		return email, nil
+	}
+	if name := ConfigValue(repoPath, "user.name"); name != "" {
+		return name, nil
+	}
+	return "", fmt.Errorf("no git identity configured; set user.email")
+}
+
+// CatCommit returns the raw content of a commit object.
+func CatCommit(repoPath, commit string) (string, error) {
+	out, err := output(repoPath, "cat-file", "commit", commit)
+	if err != nil {
+		return "", fmt.Errorf("git cat-file failed: %w", err)
+	}
+	return out, nil
+}
+
+// WriteCommit stores raw commit content as a commit object and returns its
+// hash.
+func WriteCommit(repoPath, content string) (string, error) {
+	cmd := Command("git", "-C", repoPath, "hash-object", "-t", "commit", "-w", "--stdin")
+	cmd.Stdin = strings.NewReader(content)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git hash-object failed: %s", strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// UpdateRef points ref at newValue, provided it still points at oldValue.
+func UpdateRef(repoPath, ref, newValue, oldValue string) error {
+	if _, err := output(repoPath, "update-ref", ref, newValue, oldValue); err != nil {
+		return fmt.Errorf("git update-ref failed: %w", err)
+	}
+	return nil
+}
+
+// PruneUnreachable drops the entries of the reflogs of HEAD and the current
+// branch that are no longer reachable from them, and deletes the objects no
+// ref or remaining reflog entry reaches. Other reflogs are left alone, so the
+// recovery points they hold are kept.
+func PruneUnreachable(repoPath string) error {
+	args := []string{"reflog", "expire", "--expire-unreachable=now", "HEAD"}
+	if branch, err := CurrentBranch(repoPath); err == nil {
+		args = append(args, "refs/heads/"+branch)
+	}
+	if _, err := output(repoPath, args...); err != nil {
+		return fmt.Errorf("git reflog expire failed: %w", err)
+	}
+	if _, err := output(repoPath, "gc", "--prune=now", "--quiet"); err != nil {
+		return fmt.Errorf("git gc failed: %w", err)
+	}
+	return nil
+}
+
+// Fetch fetches ref from a repository URL or local path into repoPath, without
+// creating any local refs, and returns the hash of the fetched commit.
+func Fetch(repoPath, url, ref string) (string, error) {
+	// Local paths are relative to the caller, not repoPath; anything else,
+	// such as an scp-style URL, is passed to git as it is
+	if _, err := os.Stat(url); err == nil && !strings.Contains(url, "://") {
+		abs, err := filepath.Abs(url)
+		if err != nil {
+			return "", fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		url = abs
+	}
+	if _, err := output(repoPath, "fetch", "--quiet", "--no-tags", url, ref); err != nil {
+		return "", fmt.Errorf("git fetch failed: %w", err)
+	}
+	out, err := output(repoPath, "rev-parse", "FETCH_HEAD^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// CommitTree creates a commit of an existing tree with the given parents and
+// message, without moving any ref, and returns its hash.
+func CommitTree(repoPath, tree, message string, parents ...string) (string, error) {
+	args := []string{"commit-tree", tree, "-m", message}
+	for _, parent := range parents {
+		args = append(args, "-p", parent)
+	}
+	out, err := output(repoPath, args...)
+	if err != nil {
+		return "", fmt.Errorf("git commit-tree failed: %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// DiffNames returns the paths that differ between two commits or trees.
+func DiffNames(repoPath, from, to string) ([]string, error) {
+	out, err := output(repoPath, "diff", "-z", "--name-only", "--no-renames", from, to)
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %w", err)
+	}
+	var paths []string
+	for _, p := range strings.Split(out, "\x00") {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+// CreateTag creates a lightweight tag pointing at rev.
+func CreateTag(repoPath, name, rev string) error {
+	if _, err := output(repoPath, "tag", name, rev); err != nil {
+		return fmt.Errorf("git tag failed: %w", err)
+	}
+	return nil
+}
+
+// DeleteTag deletes a tag.
+func DeleteTag(repoPath, name string) error {
+	if _, err := output(repoPath, "tag", "-d", name); err != nil {
+		return fmt.Errorf("git tag failed: %w", err)
+	}
+	return nil
+}
+
+// Tags returns the names of the tags matching pattern, a glob such as
+// "v*". If pointsAt is non-empty only tags pointing at that commit are
+// returned.
+func Tags(repoPath, pattern, pointsAt string) ([]string, error) {
+	args := []string{"tag", "--list", pattern}
+	if pointsAt != "" {
+		args = append(args, "--points-at", pointsAt)
+	}
+	out, err := output(repoPath, args...)
+	if err != nil {
+		return nil, fmt.Errorf("git tag failed: %w", err)
+	}
+	return strings.Fields(out), nil
+}
+
+// ShowFile returns the content of a file as of rev.
+func ShowFile(repoPath, rev, path string) (string, error) {
+	out, err := output(repoPath, "show", rev+":"+path)
+	if err != nil {
+		return "", fmt.Errorf("git show failed: %w", err)
+	}
+	return out, nil
+}
+
+// RemoveTree removes a directory from the index and working tree.
+func RemoveTree(repoPath, path string) error {
+	if _, err := output(repoPath, "rm", "-r", "-q", "--", path); err != nil {
+		return fmt.Errorf("git rm failed: %w", err)
+	}
+	return nil
+}
+
+// Unstage removes path from the index, leaving the working tree alone.
+func Unstage(repoPath, path string) error {
+	if _, err := output(repoPath, "rm", "-r", "-q", "--cached", "--", path); err != nil {
+		return fmt.Errorf("git rm failed: %w", err)
+	}
+	return nil
+}
+
+// Init creates an empty repository in an existing directory.
+func Init(repoPath string) error {
+	if _, err := output(repoPath, "init", "--quiet"); err != nil {
+		return fmt.Errorf("git init failed: %w", err)
+	}
+	return nil
+}
+
+// OtherFiles returns the files in the working tree that git does not track,
+// ignored files included.
+func OtherFiles(repoPath string) ([]string, error) {
+	out, err := output(repoPath, "ls-files", "-z", "--others")
+	if err != nil {
+		return nil, fmt.Errorf("git ls-files failed: %w", err)
+	}
+	var files []string
+	for _, f := range strings.Split(out, "\x00") {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// UntrackedFiles returns the files in the working tree that git does not
+// track and does not ignore, also leaving out those matching the gitignore
+// patterns of excludeFile if it is not empty. Other repositories nested in
+// the working tree are left out.
+func UntrackedFiles(repoPath, excludeFile string) ([]string, error) {
+	args := []string{"ls-files", "-z", "--others", "--exclude-standard"}
+	if excludeFile != "" {
+		abs, err := filepath.Abs(excludeFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve path: %w", err)
+		}
+		args = append(args, "--exclude-from="+abs)
+	}
+	out, err := output(repoPath, args...)
+	if err != nil {
+		return nil, fmt.Errorf("git ls-files failed: %w", err)
+	}
+	var files []string
+	for _, f := range strings.Split(out, "\x00") {
+		if f != "" && !strings.HasSuffix(f, "/") {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// RefTips returns the objects the branches and tags of a repository point
+// to, keyed by full ref name.
+func RefTips(repoPath string) (map[string]string, error) {
+	out, err := output(repoPath, "for-each-ref", "--format=%(refname) %(objectname)", "refs/heads", "refs/tags")
+	if err != nil {
+		return nil, fmt.Errorf("git for-each-ref failed: %w", err)
+	}
+	tips := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		if ref, hash, ok := strings.Cut(line, " "); ok {
+			tips[ref] = hash
+		}
+	}
+	return tips, nil
+}
+
+// HasObject reports whether the repository holds an object.
+func HasObject(repoPath, hash string) bool {
+	_, err := output(repoPath, "cat-file", "-e", hash)
+	return err == nil
+}
+
+// HasNewObjects reports whether any branch or tag reaches objects that are
+// not reachable from the excluded objects.
+func HasNewObjects(repoPath string, exclude []string) (bool, error) {
+	args := append([]string{"rev-list", "--objects", "--all", "--not"}, exclude...)
+	out, err := output(repoPath, args...)
+	if err != nil {
+		return false, fmt.Errorf("git rev-list failed: %w", err)
+	}
+	return strings.TrimSpace(out) != "", nil
+}
+
+// CreateBundle writes every branch and tag to a bundle file, leaving out the
+// objects reachable from the excluded objects so that the bundle holds only
+// what is new since them.
+func CreateBundle(repoPath, file string, exclude []string) error {
+	args := []string{"bundle", "create", "--quiet", file, "--all"}
+	if len(exclude) > 0 {
+		args = append(append(args, "--not"), exclude...)
+	}
+	if _, err := output(repoPath, args...); err != nil {
+		return fmt.Errorf("git bundle failed: %w", err)
+	}
+	return nil
+}
+
+// Unbundle stores the objects of a bundle file in a repository without
+// updating any ref. The objects the bundle builds on must already be there.
+func Unbundle(repoPath, file string) error {
+	if _, err := output(repoPath, "bundle", "unbundle", file); err != nil {
+		return fmt.Errorf("git bundle failed: %w", err)
+	}
+	return nil
+}
+
+// SetHead points HEAD at a branch, given by full ref name.
+func SetHead(repoPath, ref string) error {
+	if _, err := output(repoPath, "symbolic-ref", "HEAD", ref); err != nil {
+		return fmt.Errorf("git symbolic-ref failed: %w", err)
+	}
+	return nil
+}
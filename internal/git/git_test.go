@@ -4,7 +4,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestIsValidRepo(t *testing.T) {
@@ -27,6 +30,28 @@ func TestIsValidRepo(t *testing.T) {
 		t.Fatalf("Failed to create invalid dir: %v", err)
 	}
 
+	// Create a linked worktree, whose .git is a file
+	work := initTestRepo(t, map[string]string{"main.go": "package main"})
+	worktree := filepath.Join(tempDir, "worktree")
+	if err := runGit(work, "worktree", "add", "-q", "-b", "feature", worktree); err != nil {
+		t.Fatalf("git worktree add failed: %v", err)
+	}
+
+	// Create a .git file pointing at a git directory that is gone
+	danglingDir := filepath.Join(tempDir, "dangling")
+	if err := os.MkdirAll(danglingDir, 0755); err != nil {
+		t.Fatalf("Failed to create dangling dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(danglingDir, ".git"), []byte("gitdir: ../gone/.git/worktrees/dangling\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .git file: %v", err)
+	}
+	if got, want := LinkedGitDir(danglingDir), filepath.Join(tempDir, "gone", ".git", "worktrees", "dangling"); got != want {
+		t.Errorf("LinkedGitDir(%q) = %q, want %q", danglingDir, got, want)
+	}
+	if got := LinkedGitDir(validRepo); got != "" {
+		t.Errorf("LinkedGitDir(%q) = %q, want \"\"", validRepo, got)
+	}
+
 	tests := []struct {
 		name string
 		path string
@@ -37,6 +62,16 @@ func TestIsValidRepo(t *testing.T) {
 			path: validRepo,
 			want: true,
 		},
+		{
+			name: "linked worktree with .git file",
+			path: worktree,
+			want: true,
+		},
+		{
+			name: ".git file pointing at a missing git directory",
+			path: danglingDir,
+			want: false,
+		},
 		{
 			name: "directory without .git",
 			path: invalidDir,
@@ -59,6 +94,159 @@ func TestIsValidRepo(t *testing.T) {
 	}
 }
 
+func TestIsShallowAndUnshallow(t *testing.T) {
+	origin := initTestRepo(t, map[string]string{"main.go": "package main"})
+	if err := runGit(origin, "commit", "-q", "--allow-empty", "-m", "second"); err != nil {
+		t.Fatalf("git commit failed: %v", err)
+	}
+	shallow := filepath.Join(t.TempDir(), "shallow")
+	if err := runGit(origin, "clone", "-q", "--depth", "1", "file://"+origin, shallow); err != nil {
+		t.Fatalf("git clone --depth 1 failed: %v", err)
+	}
+
+	if IsShallow(origin) {
+		t.Errorf("IsShallow(%q) = true, want false", origin)
+	}
+	if !IsShallow(shallow) {
+		t.Fatalf("IsShallow(%q) = false, want true", shallow)
+	}
+	if err := Unshallow(shallow); err != nil {
+		t.Fatalf("Unshallow() error = %v", err)
+	}
+	if IsShallow(shallow) {
+		t.Errorf("IsShallow() after Unshallow() = true, want false")
+	}
+}
+
+func TestHasCommits(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"main.go": "package main"})
+	empty := t.TempDir()
+	if err := runGit(empty, "init", "-q"); err != nil {
+		t.Fatalf("git init failed: %v", err)
+	}
+
+	if !HasCommits(repo) {
+		t.Errorf("HasCommits(%q) = false, want true", repo)
+	}
+	if HasCommits(empty) {
+		t.Errorf("HasCommits(%q) = true for a repository without commits, want false", empty)
+	}
+}
+
+func TestIsBareRepo(t *testing.T) {
+	work := initTestRepo(t, map[string]string{"main.go": "package main"})
+	bare := filepath.Join(t.TempDir(), "repo.git")
+	if err := runGit(work, "clone", "-q", "--bare", work, bare); err != nil {
+		t.Fatalf("git clone --bare failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{name: "bare repository", path: bare, want: true},
+		{name: "directory inside a bare repository", path: filepath.Join(bare, "refs"), want: false},
+		{name: "repository with a working tree", path: work, want: false},
+		{name: "non-existent path", path: filepath.Join(t.TempDir(), "does-not-exist"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsBareRepo(tt.path); got != tt.want {
+				t.Errorf("IsBareRepo(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsBundle(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"main.go": "package main"})
+	bundle := filepath.Join(t.TempDir(), "repo.bundle")
+	if err := runGit(repo, "bundle", "create", bundle, "--all"); err != nil {
+		t.Fatalf("git bundle create failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{name: "bundle", path: bundle, want: true},
+		{name: "other file", path: filepath.Join(repo, "main.go"), want: false},
+		{name: "repository", path: repo, want: false},
+		{name: "non-existent path", path: filepath.Join(t.TempDir(), "missing.bundle"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsBundle(tt.path); got != tt.want {
+				t.Errorf("IsBundle(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommand_LowPriority(t *testing.T) {
+	if got := Command("git", "version").Args; !reflect.DeepEqual(got, []string{"git", "version"}) {
+		t.Errorf("Command() args = %v, want git run directly", got)
+	}
+
+	SetLowPriority(15)
+	t.Cleanup(func() { SetLowPriority(0) })
+	cmd := Command("git", "version")
+	if _, err := exec.LookPath("nice"); err == nil {
+		if got := strings.Join(cmd.Args[:3], " "); got != "nice -n 15" {
+			t.Errorf("Command() args = %v, want git run under nice -n 15", cmd.Args)
+		}
+	}
+	out, err := cmd.Output()
+	if err != nil || !strings.HasPrefix(string(out), "git version") {
+		t.Errorf("low priority git version = %q, %v", out, err)
+	}
+}
+
+func TestCommand_Explain(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "subcommand",
+			args: []string{"git", "-C", "/srv/graveyard", "add", "-A"},
+			want: "# stage the files the burial changed\n$ git -C /srv/graveyard add -A\n",
+		},
+		{
+			name: "subcommand and action",
+			args: []string{"git", "-C", "/srv/graveyard", "subtree", "add", "--prefix=app", "/tmp/app", "main"},
+			want: "# graft the source's history into the graveyard under the project directory\n$ git -C /srv/graveyard subtree add --prefix=app /tmp/app main\n",
+		},
+		{
+			name: "quoted",
+			args: []string{"git", "-C", "/srv/my graveyard", "-c", "commit.gpgsign=false", "commit", "-m", "Bury app's code"},
+			want: "# record the change in the graveyard's history\n$ git -C '/srv/my graveyard' -c commit.gpgsign=false commit -m 'Bury app'\\''s code'\n",
+		},
+		{
+			name: "other tool",
+			args: []string{"rsync", "-a", "src/", "dest"},
+			want: "$ rsync -a src/ dest\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			SetExplain(&out)
+			t.Cleanup(func() { SetExplain(nil) })
+			Command(tt.args[0], tt.args[1:]...)
+			if out.String() != tt.want {
+				t.Errorf("Command() explained\n%s\nwant\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
 func TestCopyTrackedFiles(t *testing.T) {
 	// Create a real git repo to test with
 	sourceDir, err := os.MkdirTemp("", "git-copy-source-*")
@@ -149,9 +337,736 @@ func TestCopyTrackedFiles(t *testing.T) {
 	}
 }
 
+func TestGrep(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{
+		"alpha/main.go":  "package main\n\nfunc parseConfig() {}\n",
+		"beta/readme.md": "Nothing to see\nParseConfig is documented here\n",
+	})
+
+	tests := []struct {
+		name       string
+		pattern    string
+		ignoreCase bool
+		paths      []string
+		want       []GrepMatch
+	}{
+		{
+			name:    "case-sensitive match",
+			pattern: "parseConfig",
+			want:    []GrepMatch{{Path: "alpha/main.go", Line: 3, Text: "func parseConfig() {}"}},
+		},
+		{
+			name:       "case-insensitive match",
+			pattern:    "parseconfig",
+			ignoreCase: true,
+			want: []GrepMatch{
+				{Path: "alpha/main.go", Line: 3, Text: "func parseConfig() {}"},
+				{Path: "beta/readme.md", Line: 2, Text: "ParseConfig is documented here"},
+			},
+		},
+		{
+			name:       "limited to path",
+			pattern:    "parseconfig",
+			ignoreCase: true,
+			paths:      []string{"beta/"},
+			want:       []GrepMatch{{Path: "beta/readme.md", Line: 2, Text: "ParseConfig is documented here"}},
+		},
+		{
+			name:    "no matches",
+			pattern: "does-not-appear",
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Grep(repo, tt.pattern, tt.ignoreCase, tt.paths...)
+			if err != nil {
+				t.Fatalf("Grep() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Grep() returned %d matches, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Grep()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLog(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"a.txt": "a"})
+	if err := os.WriteFile(filepath.Join(repo, "b.txt"), []byte("b"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := runGit(repo, "add", "b.txt"); err != nil {
+		t.Fatalf("Failed to add file: %v", err)
+	}
+	if err := runGit(repo, "commit", "-m", "add b", "-m", "with a body"); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		paths        []string
+		wantSubjects []string
+	}{
+		{name: "all commits", wantSubjects: []string{"add b", "initial commit"}},
+		{name: "limited to path", paths: []string{"a.txt"}, wantSubjects: []string{"initial commit"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commits, err := Log(repo, []string{"HEAD"}, tt.paths...)
+			if err != nil {
+				t.Fatalf("Log() error = %v", err)
+			}
+			var subjects []string
+			for _, c := range commits {
+				subjects = append(subjects, c.Subject)
+				if c.Author != "Test" || c.AuthorEmail != "test@test.com" || c.Date.IsZero() {
+					t.Errorf("Log() commit %s has unexpected author or date: %+v", c.Hash, c)
+				}
+			}
+			if strings.Join(subjects, ",") != strings.Join(tt.wantSubjects, ",") {
+				t.Errorf("Log() subjects = %v, want %v", subjects, tt.wantSubjects)
+			}
+		})
+	}
+
+	commits, _ := Log(repo, []string{"HEAD"})
+	if commits[0].Body != "with a body" || len(commits[0].Parents) != 1 {
+		t.Errorf("Log() latest commit = %+v, want body and one parent", commits[0])
+	}
+}
+
+func TestSubtreeSplit(t *testing.T) {
+	source := initTestRepo(t, map[string]string{"main.go": "package main"})
+	graveyard := initTestRepo(t, map[string]string{"README.md": "graveyard"})
+
+	for _, prefix := range []string{"project", "lib[old]", "axb"} {
+		if err := SubtreeAdd(graveyard, source, prefix); err != nil {
+			t.Fatalf("SubtreeAdd(%q) error = %v", prefix, err)
+		}
+	}
+	sourceHead, err := Head(source)
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		prefix string
+		want   string
+	}{
+		{name: "subtree prefix", prefix: "project", want: sourceHead},
+		{name: "unknown prefix", prefix: "other", want: ""},
+		{name: "prefix special to regular expressions", prefix: "lib[old]", want: sourceHead},
+		{name: "prefix matching another as a regular expression", prefix: "a.b", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SubtreeSplit(graveyard, tt.prefix)
+			if err != nil {
+				t.Fatalf("SubtreeSplit() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("SubtreeSplit(%q) = %q, want %q", tt.prefix, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUncommittedInventory(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"tracked.txt": "one\n", "other.txt": "two\n"})
+
+	// Stash one change, then leave another change and an untracked file behind
+	if err := os.WriteFile(filepath.Join(repo, "other.txt"), []byte("stashed\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify file: %v", err)
+	}
+	if err := runGit(repo, "stash"); err != nil {
+		t.Fatalf("Failed to stash: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "tracked.txt"), []byte("changed\n"), 0644); err != nil {
+		t.Fatalf("Failed to modify file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "new.txt"), []byte("new\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	stashes, err := Stashes(repo)
+	if err != nil {
+		t.Fatalf("Stashes() error = %v", err)
+	}
+	if len(stashes) != 1 || !strings.HasPrefix(stashes[0], "stash@{0}: ") {
+		t.Errorf("Stashes() = %v, want one stash@{0} entry", stashes)
+	}
+
+	modified, untracked, err := UncommittedFiles(repo)
+	if err != nil {
+		t.Fatalf("UncommittedFiles() error = %v", err)
+	}
+	if strings.Join(modified, ",") != "tracked.txt" {
+		t.Errorf("UncommittedFiles() modified = %v, want [tracked.txt]", modified)
+	}
+	if strings.Join(untracked, ",") != "new.txt" {
+		t.Errorf("UncommittedFiles() untracked = %v, want [new.txt]", untracked)
+	}
+
+	patch, err := UncommittedPatch(repo)
+	if err != nil {
+		t.Fatalf("UncommittedPatch() error = %v", err)
+	}
+	for _, want := range []string{"+changed", "+stashed", "# stash@{0}: "} {
+		if !strings.Contains(patch, want) {
+			t.Errorf("UncommittedPatch() missing %q:\n%s", want, patch)
+		}
+	}
+}
+
+func TestUntrackedFiles(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{".gitignore": "node_modules/\n"})
+	for _, name := range []string{"node_modules/m.js", "data/big.csv", "src/new.go"} {
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ignoreFile := filepath.Join(t.TempDir(), ".buryignore")
+	if err := os.WriteFile(ignoreFile, []byte("/data/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		excludeFile string
+		want        string
+	}{
+		{"", "data/big.csv,src/new.go"},
+		{ignoreFile, "src/new.go"},
+	}
+	for _, tt := range tests {
+		got, err := UntrackedFiles(repo, tt.excludeFile)
+		if err != nil {
+			t.Fatalf("UntrackedFiles(%q) error = %v", tt.excludeFile, err)
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("UntrackedFiles(%q) = %v, want %s", tt.excludeFile, got, tt.want)
+		}
+	}
+}
+
+func TestCloneBranch(t *testing.T) {
+	source := initTestRepo(t, map[string]string{"a.txt": "a"})
+	if err := runGit(source, "branch", "legacy"); err != nil {
+		t.Fatal(err)
+	}
+
+	clone := filepath.Join(t.TempDir(), "clone")
+	if err := CloneBranch(source, clone, "legacy"); err != nil {
+		t.Fatalf("CloneBranch() error = %v", err)
+	}
+	if branch, err := CurrentBranch(clone); err != nil || branch != "legacy" {
+		t.Errorf("CurrentBranch() of clone = %q, %v, want legacy", branch, err)
+	}
+
+	err := CloneBranch(source, filepath.Join(t.TempDir(), "missing"), "missing")
+	if err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("CloneBranch() of a missing branch error = %v, want it named", err)
+	}
+}
+
+func TestSubmodules(t *testing.T) {
+	lib := initTestRepo(t, map[string]string{"lib.go": "package lib"})
+	libHead, err := Head(lib)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := initTestRepo(t, map[string]string{"main.go": "package main"})
+	if subs, err := Submodules(repo); err != nil || len(subs) != 0 {
+		t.Fatalf("Submodules() = %v, %v, want none", subs, err)
+	}
+	for _, args := range [][]string{
+		{"-c", "protocol.file.allow=always", "submodule", "add", "-q", lib, "vendor/lib"},
+		{"commit", "-q", "-m", "add lib"},
+	} {
+		if err := runGit(repo, args...); err != nil {
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+	}
+
+	subs, err := Submodules(repo)
+	if err != nil {
+		t.Fatalf("Submodules() error = %v", err)
+	}
+	want := []Submodule{{Path: "vendor/lib", URL: lib, Commit: libHead}}
+	if !reflect.DeepEqual(subs, want) {
+		t.Errorf("Submodules() = %+v, want %+v", subs, want)
+	}
+
+	// Once added to a graveyard, the submodules are found under the prefix
+	graveyard := initTestRepo(t, map[string]string{"README.md": "graveyard"})
+	if err := SubtreeAdd(graveyard, repo, "project"); err != nil {
+		t.Fatalf("SubtreeAdd() error = %v", err)
+	}
+	if subs, err := SubmodulesIn(graveyard, "project"); err != nil || !reflect.DeepEqual(subs, want) {
+		t.Errorf("SubmodulesIn() = %+v, %v, want %+v", subs, err, want)
+	}
+	if subs, err := SubmodulesIn(graveyard, ""); err != nil || len(subs) != 1 || subs[0].Path != "project/vendor/lib" || subs[0].URL != "" {
+		t.Errorf("SubmodulesIn() of the root = %+v, %v, want the link without a URL", subs, err)
+	}
+
+	clone := filepath.Join(t.TempDir(), "clone")
+	if err := Clone(repo, clone); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(clone, "vendor/lib/lib.go")); !os.IsNotExist(err) {
+		t.Fatalf("lib.go exists before UpdateSubmodules(): %v", err)
+	}
+	if err := UpdateSubmodules(clone, ConfigEnv("protocol.file.allow", "always")...); err != nil {
+		t.Fatalf("UpdateSubmodules() error = %v", err)
+	}
+	if got, err := os.ReadFile(filepath.Join(clone, "vendor/lib/lib.go")); err != nil || string(got) != "package lib" {
+		t.Errorf("vendor/lib/lib.go = %q, %v, want the submodule's content", got, err)
+	}
+}
+
+func TestLFSFiles(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{
+		".gitattributes":  "*.bin filter=lfs diff=lfs merge=lfs -text\ndocs/*.pdf filter=lfs\n",
+		"data/model.bin":  "pointer",
+		"docs/manual.pdf": "pointer",
+		"docs/notes.txt":  "text",
+		"main.go":         "package main",
+	})
+
+	files, err := LFSFiles(repo)
+	if err != nil {
+		t.Fatalf("LFSFiles() error = %v", err)
+	}
+	want := []string{"data/model.bin", "docs/manual.pdf"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("LFSFiles() = %v, want %v", files, want)
+	}
+
+	plain := initTestRepo(t, map[string]string{"main.go": "package main"})
+	if files, err := LFSFiles(plain); err != nil || len(files) != 0 {
+		t.Errorf("LFSFiles() without Git LFS = %v, %v, want none", files, err)
+	}
+}
+
+func TestResolveCommit(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"a.txt": "a"})
+	first, err := Head(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"tag", "-a", "v1", "-m", "release"},
+		{"commit", "--allow-empty", "-m", "second"},
+	} {
+		if err := runGit(repo, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, rev := range []string{"v1", first[:7], "HEAD~1"} {
+		if got, err := ResolveCommit(repo, rev); err != nil || got != first {
+			t.Errorf("ResolveCommit(%s) = %q, %v, want %s", rev, got, err, first)
+		}
+	}
+	if _, err := ResolveCommit(repo, "--all"); err == nil {
+		t.Errorf("ResolveCommit(--all) expected error")
+	}
+
+	if err := Detach(repo, first); err != nil {
+		t.Fatalf("Detach() error = %v", err)
+	}
+	if head, _ := Head(repo); head != first {
+		t.Errorf("Head() after Detach() = %s, want %s", head, first)
+	}
+}
+
+func TestRefs(t *testing.T) {
+	source := initTestRepo(t, map[string]string{"a.txt": "a"})
+	for _, args := range [][]string{
+		{"branch", "feature"},
+		{"tag", "light"},
+		{"tag", "-a", "annotated", "-m", "release"},
+	} {
+		if err := runGit(source, args...); err != nil {
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+	}
+	head, err := Head(source)
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+
+	// A clone only has remote-tracking refs for branches other than the default
+	clone := filepath.Join(t.TempDir(), "clone")
+	if err := Clone(source, clone); err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+
+	for _, repo := range []string{source, clone} {
+		refs, err := Refs(repo)
+		if err != nil {
+			t.Fatalf("Refs() error = %v", err)
+		}
+		got := make(map[string]Ref)
+		for _, r := range refs {
+			got[r.Name] = r
+		}
+		if len(got) != len(refs) {
+			t.Errorf("Refs(%s) returned duplicates: %+v", repo, refs)
+		}
+
+		tests := []struct {
+			name  string
+			isTag bool
+		}{
+			{name: "feature", isTag: false},
+			{name: "light", isTag: true},
+			{name: "annotated", isTag: true},
+		}
+		for _, tt := range tests {
+			r, ok := got[tt.name]
+			if !ok {
+				t.Errorf("Refs(%s) missing %s: %+v", repo, tt.name, refs)
+				continue
+			}
+			if r.IsTag != tt.isTag || r.Hash != head || r.Date.IsZero() {
+				t.Errorf("Refs(%s)[%s] = %+v, want isTag %v at %s", repo, tt.name, r, tt.isTag, head)
+			}
+		}
+	}
+}
+
+func TestListObjectsAndInfos(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"dir/file.txt": "hello world"})
+
+	objects, err := ListObjects(repo, []string{"HEAD"})
+	if err != nil {
+		t.Fatalf("ListObjects() error = %v", err)
+	}
+	byPath := make(map[string]string)
+	hashes := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		byPath[obj.Path] = obj.Hash
+		hashes = append(hashes, obj.Hash)
+	}
+	blob, ok := byPath["dir/file.txt"]
+	if !ok {
+		t.Fatalf("ListObjects() missing dir/file.txt: %+v", objects)
+	}
+
+	head, err := Head(repo)
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+
+	infos, err := ObjectInfos(repo, append(hashes, "0000000000000000000000000000000000000000"))
+	if err != nil {
+		t.Fatalf("ObjectInfos() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		hash     string
+		wantType string
+	}{
+		{name: "commit", hash: head, wantType: "commit"},
+		{name: "tree", hash: byPath["dir"], wantType: "tree"},
+		{name: "blob", hash: blob, wantType: "blob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, ok := infos[tt.hash]
+			if !ok {
+				t.Fatalf("ObjectInfos() missing %s", tt.hash)
+			}
+			if info.Type != tt.wantType || info.DiskSize <= 0 {
+				t.Errorf("ObjectInfos()[%s] = %+v, want type %s", tt.hash, info, tt.wantType)
+			}
+		})
+	}
+	if infos[blob].Size != int64(len("hello world")) {
+		t.Errorf("blob size = %d, want %d", infos[blob].Size, len("hello world"))
+	}
+	if len(infos) != len(hashes) {
+		t.Errorf("ObjectInfos() returned %d entries, want %d (missing objects skipped)", len(infos), len(hashes))
+	}
+}
+
+// initTestRepo creates a git repository containing files and commits them.
+func TestTagsAndShowFile(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"dir/a.txt": "first"})
+	first, err := Head(repo)
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+	if err := CreateTag(repo, "buried/a/v1", first); err != nil {
+		t.Fatalf("CreateTag() error = %v", err)
+	}
+	if err := RemoveTree(repo, "dir"); err != nil {
+		t.Fatalf("RemoveTree() error = %v", err)
+	}
+	if err := Commit(repo, "remove dir"); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if err := CreateTag(repo, "buried/a/v2", "HEAD"); err != nil {
+		t.Fatalf("CreateTag() error = %v", err)
+	}
+	if err := CreateTag(repo, "other", "HEAD"); err != nil {
+		t.Fatalf("CreateTag() error = %v", err)
+	}
+
+	tags, err := Tags(repo, "buried/a/*", "")
+	if err != nil || !reflect.DeepEqual(tags, []string{"buried/a/v1", "buried/a/v2"}) {
+		t.Errorf("Tags() = %v, %v, want both versions", tags, err)
+	}
+	tags, err = Tags(repo, "buried/*", first)
+	if err != nil || !reflect.DeepEqual(tags, []string{"buried/a/v1"}) {
+		t.Errorf("Tags() pointing at first = %v, %v, want [buried/a/v1]", tags, err)
+	}
+
+	content, err := ShowFile(repo, "buried/a/v1", "dir/a.txt")
+	if err != nil || content != "first" {
+		t.Errorf("ShowFile() = %q, %v, want first", content, err)
+	}
+	if _, err := ShowFile(repo, "buried/a/v2", "dir/a.txt"); err == nil {
+		t.Errorf("ShowFile() of a removed file expected error")
+	}
+
+	if err := DeleteTag(repo, "buried/a/v2"); err != nil {
+		t.Fatalf("DeleteTag() error = %v", err)
+	}
+	if tags, _ := Tags(repo, "buried/a/*", ""); len(tags) != 1 {
+		t.Errorf("Tags() after DeleteTag() = %v, want one tag", tags)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	src := initTestRepo(t, map[string]string{"a.txt": "a"})
+	want, err := Head(src)
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+	repo := initTestRepo(t, map[string]string{"b.txt": "b"})
+
+	// A relative path is resolved against the working directory
+	t.Chdir(filepath.Dir(src))
+	if got, err := Fetch(repo, filepath.Base(src), "HEAD"); err != nil || got != want {
+		t.Errorf("Fetch() of a relative path = %q, %v, want %q", got, err, want)
+	}
+
+	// An scp-style URL reaches git unchanged, here rewritten to src
+	if err := runGit(repo, "config", "url."+src+".insteadOf", "git@localhost:owner/src.git"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Fetch(repo, "git@localhost:owner/src.git", "HEAD"); err != nil || got != want {
+		t.Errorf("Fetch() of an scp-style URL = %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestBundles(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"a.txt": "first"})
+	first, err := Head(repo)
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+	bundles := t.TempDir()
+	full := filepath.Join(bundles, "full.bundle")
+	if err := CreateBundle(repo, full, nil); err != nil {
+		t.Fatalf("CreateBundle() error = %v", err)
+	}
+	if changed, err := HasNewObjects(repo, []string{first}); err != nil || changed {
+		t.Errorf("HasNewObjects() = %v, %v, want false", changed, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(repo, "b.txt"), []byte("second"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := StageAll(repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := Commit(repo, "second"); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := HasNewObjects(repo, []string{first}); err != nil || !changed {
+		t.Errorf("HasNewObjects() after a commit = %v, %v, want true", changed, err)
+	}
+	incremental := filepath.Join(bundles, "incremental.bundle")
+	if err := CreateBundle(repo, incremental, []string{first}); err != nil {
+		t.Fatalf("CreateBundle() error = %v", err)
+	}
+	tips, err := RefTips(repo)
+	if err != nil || len(tips) != 1 {
+		t.Fatalf("RefTips() = %v, %v, want one branch", tips, err)
+	}
+
+	restored := t.TempDir()
+	if err := Init(restored); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if err := Unbundle(restored, incremental); err == nil {
+		t.Errorf("Unbundle() without the objects it builds on expected error")
+	}
+	for _, bundle := range []string{full, incremental} {
+		if err := Unbundle(restored, bundle); err != nil {
+			t.Fatalf("Unbundle(%s) error = %v", bundle, err)
+		}
+	}
+	for ref, hash := range tips {
+		if !HasObject(restored, hash) {
+			t.Errorf("HasObject(%s) = false after unbundling", hash)
+		}
+		if err := UpdateRef(restored, ref, hash, ""); err != nil {
+			t.Fatal(err)
+		}
+		if err := SetHead(restored, ref); err != nil {
+			t.Fatalf("SetHead() error = %v", err)
+		}
+	}
+	if err := ResetHard(restored, "HEAD"); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := os.ReadFile(filepath.Join(restored, "b.txt")); err != nil || string(content) != "second" {
+		t.Errorf("restored b.txt = %q, %v, want second", content, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(restored, "notes.txt"), []byte("notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if files, err := OtherFiles(restored); err != nil || !reflect.DeepEqual(files, []string{"notes.txt"}) {
+		t.Errorf("OtherFiles() = %v, %v, want [notes.txt]", files, err)
+	}
+}
+
+func TestCommitAs(t *testing.T) {
+	date := time.Date(2019, 3, 4, 5, 6, 7, 0, time.UTC)
+	var heads []string
+	for range 2 {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := Init(dir); err != nil {
+			t.Fatal(err)
+		}
+		if err := StageAll(dir); err != nil {
+			t.Fatal(err)
+		}
+		if err := CommitAs(dir, "bury-it", "bury-it@localhost", "Snapshot", date); err != nil {
+			t.Fatalf("CommitAs() error = %v", err)
+		}
+		head, err := Head(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		heads = append(heads, head)
+	}
+	if heads[0] != heads[1] {
+		t.Errorf("CommitAs() made commits %s and %s of the same content, want the same", heads[0], heads[1])
+	}
+}
+
+func initTestRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init"},
+		{"config", "user.email", "test@test.com"},
+		{"config", "user.name", "Test"},
+	} {
+		if err := runGit(dir, args...); err != nil {
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	if err := runGit(dir, "add", "-A"); err != nil {
+		t.Fatalf("Failed to add files: %v", err)
+	}
+	if err := runGit(dir, "commit", "-m", "initial commit"); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	return dir
+}
+
 // runGit is a helper to run git commands in tests.
 func runGit(dir string, args ...string) error {
 	cmd := exec.Command("git", args...)
 	cmd.Dir = dir
 	return cmd.Run()
 }
+
+func TestPruneUnreachable(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"a.txt": "a"})
+	commit := func(message string) string {
+		t.Helper()
+		if err := runGit(repo, "commit", "-q", "--allow-empty", "-m", message); err != nil {
+			t.Fatalf("Failed to commit: %v", err)
+		}
+		head, err := Head(repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return head
+	}
+
+	// A commit dropped from another branch, recoverable from its reflog
+	if err := runGit(repo, "checkout", "-q", "-b", "side"); err != nil {
+		t.Fatal(err)
+	}
+	side := commit("side")
+	// A commit dropped from the current branch, as a compaction drops them
+	for _, args := range [][]string{{"reset", "-q", "--hard", "HEAD^"}, {"checkout", "-q", "-"}} {
+		if err := runGit(repo, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dropped := commit("dropped")
+	if err := runGit(repo, "reset", "-q", "--hard", "HEAD^"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := PruneUnreachable(repo); err != nil {
+		t.Fatalf("PruneUnreachable() error = %v", err)
+	}
+	if HasObject(repo, dropped) {
+		t.Errorf("PruneUnreachable() kept %s, dropped from the current branch", dropped)
+	}
+	if !HasObject(repo, side) {
+		t.Errorf("PruneUnreachable() deleted %s, still in the reflog of another branch", side)
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	repo := initTestRepo(t, map[string]string{"a.txt": "a"})
+	if got, err := Identity(repo); err != nil || got != "test@test.com" {
+		t.Errorf("Identity() = %q, %v, want test@test.com", got, err)
+	}
+	// Keep the machine's own git identity out of the way
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	if err := runGit(repo, "config", "--unset", "user.email"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Identity(repo); err != nil || got != "Test" {
+		t.Errorf("Identity() without an email = %q, %v, want Test", got, err)
+	}
+}
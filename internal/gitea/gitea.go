@@ -0,0 +1,140 @@
+// Package gitea provides a minimal client for the REST API of Gitea and
+// Forgejo instances.
+package gitea
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Kinds are the forge kinds whose API this package speaks. Forgejo is a fork
+// of Gitea and keeps its API.
+var Kinds = []string{"gitea", "forgejo"}
+
+// DefaultHosts are the public instances recognized without configuration,
+// keyed by host.
+var DefaultHosts = map[string]string{
+	"codeberg.org": "forgejo",
+	"gitea.com":    "gitea",
+}
+
+// ParseKind checks that name is a forge kind this package speaks to, and
+// returns it in lowercase.
+func ParseKind(name string) (string, error) {
+	kind := strings.ToLower(name)
+	for _, k := range Kinds {
+		if kind == k {
+			return kind, nil
+		}
+	}
+	return "", fmt.Errorf("unknown forge %q: must be %s", name, strings.Join(Kinds, " or "))
+}
+
+// Kind returns the forge kind of host, looked up in configured and then in
+// DefaultHosts, or "" if the host is not known to run Gitea or Forgejo.
+// Hosts configured with another kind of forge, such as github, give "".
+func Kind(host string, configured map[string]string) string {
+	host = strings.ToLower(host)
+	if kind, ok := configured[host]; ok {
+		if slices.Contains(Kinds, kind) {
+			return kind
+		}
+		return ""
+	}
+	return DefaultHosts[host]
+}
+
+// Client is a Gitea or Forgejo REST API client.
+type Client struct {
+	// BaseURL is the API base URL, without a trailing slash.
+	BaseURL string
+	// Token is an optional access token used for authentication.
+	Token string
+	// HTTPClient is the client used to make requests.
+	HTTPClient *http.Client
+}
+
+// NewClient creates a client for the instance at host.
+func NewClient(host, token string) *Client {
+	return &Client{
+		BaseURL:    "https://" + host + "/api/v1",
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// TokenFromEnv returns an access token from GITEA_TOKEN or FORGEJO_TOKEN.
+func TokenFromEnv() string {
+	if token := os.Getenv("GITEA_TOKEN"); token != "" {
+		return token
+	}
+	return os.Getenv("FORGEJO_TOKEN")
+}
+
+// Repository is a repository hosted on an instance.
+type Repository struct {
+	// Description is the repository's one-line description.
+	Description string `json:"description"`
+	// Website is the repository's website, if set.
+	Website string `json:"website"`
+	// Archived reports whether the repository is archived.
+	Archived bool `json:"archived"`
+	// Topics are the repository's topics.
+	Topics []string `json:"-"`
+}
+
+// Repo returns a repository's description and topics.
+func (c *Client) Repo(owner, repo string) (*Repository, error) {
+	var r Repository
+	path := fmt.Sprintf("/repos/%s/%s", owner, repo)
+	if err := c.get(path, &r); err != nil {
+		return nil, err
+	}
+	var topics struct {
+		Topics []string `json:"topics"`
+	}
+	if err := c.get(path+"/topics", &topics); err != nil {
+		return nil, err
+	}
+	r.Topics = topics.Topics
+	return &r, nil
+}
+
+// get performs a GET request and decodes the JSON response into v.
+func (c *Client) get(path string, v any) error {
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "token "+c.Token)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Gitea request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		msg := strings.TrimSpace(string(body))
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+			msg = apiErr.Message
+		}
+		return fmt.Errorf("Gitea API %s %s returned %d: %s", req.Method, req.URL.Path, resp.StatusCode, msg)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode Gitea response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package gitea
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestClient returns a client that talks to a test server running handler.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client := NewClient("example.com", "test-token")
+	client.BaseURL = server.URL
+	return client
+}
+
+func TestNewClient(t *testing.T) {
+	if got := NewClient("codeberg.org", "").BaseURL; got != "https://codeberg.org/api/v1" {
+		t.Errorf("BaseURL = %q, want https://codeberg.org/api/v1", got)
+	}
+}
+
+func TestKind(t *testing.T) {
+	configured := map[string]string{"git.example.com": "forgejo", "gitea.com": "forgejo", "codeberg.org": "github"}
+	tests := []struct {
+		host string
+		want string
+	}{
+		{host: "git.example.com", want: "forgejo"},
+		{host: "Codeberg.org", want: ""},
+		{host: "Git.Example.com", want: "forgejo"},
+		{host: "gitea.com", want: "forgejo"},
+		{host: "github.com", want: ""},
+	}
+	for _, tt := range tests {
+		if got := Kind(tt.host, configured); got != tt.want {
+			t.Errorf("Kind(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+
+	if kind, err := ParseKind("Forgejo"); err != nil || kind != "forgejo" {
+		t.Errorf("ParseKind(Forgejo) = %q, %v, want forgejo", kind, err)
+	}
+	if _, err := ParseKind("gogs"); err == nil {
+		t.Errorf("ParseKind(gogs) expected error")
+	}
+}
+
+func TestClient_Repo(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "token test-token" {
+			t.Errorf("Authorization = %q, want token", got)
+		}
+		switch r.URL.Path {
+		case "/repos/owner/repo":
+			_, _ = fmt.Fprint(w, `{"description": "A tool that did things", "archived": true}`)
+		case "/repos/owner/repo/topics":
+			_, _ = fmt.Fprint(w, `{"topics": ["cli", "go"]}`)
+		default:
+			t.Errorf("unexpected request for %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	})
+
+	got, err := client.Repo("owner", "repo")
+	if err != nil {
+		t.Fatalf("Repo() error = %v", err)
+	}
+	if got.Description != "A tool that did things" || !got.Archived {
+		t.Errorf("Repo() = %+v, want the description and archived", got)
+	}
+	if strings.Join(got.Topics, ",") != "cli,go" {
+		t.Errorf("Repo() topics = %v, want [cli go]", got.Topics)
+	}
+}
+
+func TestClient_RepoError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = fmt.Fprint(w, `{"message": "GetRepositoryByName"}`)
+	})
+
+	_, err := client.Repo("owner", "missing")
+	if err == nil || !strings.Contains(err.Error(), "404") || !strings.Contains(err.Error(), "GetRepositoryByName") {
+		t.Errorf("Repo() error = %v, want 404 with message", err)
+	}
+}
@@ -0,0 +1,636 @@
+// Package github provides a minimal client for the GitHub REST API.
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the base URL of the public GitHub API.
+const DefaultBaseURL = "https://api.github.com"
+
+// Client is a GitHub REST API client.
+type Client struct {
+	// BaseURL is the API base URL, without a trailing slash.
+	BaseURL string
+	// Token is an optional personal access token used for authentication.
+	Token string
+	// HTTPClient is the client used to make requests.
+	HTTPClient *http.Client
+	// GraphQLURL is the URL of the GraphQL API, or "" for BaseURL/graphql.
+	GraphQLURL string
+}
+
+// NewClient creates a client for the public GitHub API.
+func NewClient(token string) *Client {
+	return &Client{
+		BaseURL:    DefaultBaseURL,
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// NewEnterpriseClient creates a client for the GitHub Enterprise Server at
+// host.
+func NewEnterpriseClient(host, token string) *Client {
+	c := NewClient(token)
+	c.BaseURL = "https://" + host + "/api/v3"
+	c.GraphQLURL = "https://" + host + "/api/graphql"
+	return c
+}
+
+// EnterpriseTokenFromEnv returns a GitHub Enterprise Server token from
+// GH_ENTERPRISE_TOKEN or GITHUB_ENTERPRISE_TOKEN, as the GitHub CLI does.
+func EnterpriseTokenFromEnv() string {
+	if token := os.Getenv("GH_ENTERPRISE_TOKEN"); token != "" {
+		return token
+	}
+	return os.Getenv("GITHUB_ENTERPRISE_TOKEN")
+}
+
+// TokenFromEnv returns a GitHub token from GITHUB_TOKEN or GH_TOKEN.
+func TokenFromEnv() string {
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		return token
+	}
+	return os.Getenv("GH_TOKEN")
+}
+
+// Issue is a GitHub issue or pull request.
+type Issue struct {
+	// Number is the issue number.
+	Number int `json:"number"`
+	// Title is the issue title.
+	Title string `json:"title"`
+	// State is "open" or "closed".
+	State string `json:"state"`
+	// HTMLURL is the permalink to the issue.
+	HTMLURL string `json:"html_url"`
+	// NodeID is the GraphQL node ID of the issue.
+	NodeID string `json:"node_id"`
+	// Body is the issue description.
+	Body string `json:"body,omitempty"`
+	// User is the author of the issue.
+	User User `json:"user"`
+	// Labels are the labels attached to the issue.
+	Labels []Label `json:"labels,omitempty"`
+	// Comments is the number of comments on the issue.
+	Comments int `json:"comments,omitempty"`
+	// CreatedAt is when the issue was opened.
+	CreatedAt time.Time `json:"created_at"`
+	// ClosedAt is when the issue was closed, if it is closed.
+	ClosedAt *time.Time `json:"closed_at,omitempty"`
+	// PullRequest is non-nil when the issue is a pull request.
+	PullRequest *struct{} `json:"pull_request,omitempty"`
+}
+
+// User is a GitHub account.
+type User struct {
+	// Login is the account name.
+	Login string `json:"login"`
+}
+
+// Label is a label attached to an issue.
+type Label struct {
+	// Name is the label name.
+	Name string `json:"name"`
+}
+
+// RateLimitError is returned when a request was refused because the API rate
+// limit is exhausted.
+type RateLimitError struct {
+	// Reset is when the rate limit resets.
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("GitHub API rate limit exceeded until %s", e.Reset.Format(time.RFC3339))
+}
+
+// IsPullRequest reports whether the issue is a pull request.
+func (i *Issue) IsPullRequest() bool {
+	return i.PullRequest != nil
+}
+
+// CountIssues returns the number of issues or pull requests in a repository
+// with the given state ("open" or "closed").
+func (c *Client) CountIssues(owner, repo string, pullRequests bool, state string) (int, error) {
+	kind := "issue"
+	if pullRequests {
+		kind = "pr"
+	}
+	query := url.Values{}
+	query.Set("q", fmt.Sprintf("repo:%s/%s is:%s is:%s", owner, repo, kind, state))
+	query.Set("per_page", "1")
+
+	var result struct {
+		TotalCount int `json:"total_count"`
+	}
+	if _, err := c.get("/search/issues", query, &result); err != nil {
+		return 0, err
+	}
+	return result.TotalCount, nil
+}
+
+// ListOpenIssues returns every open issue and pull request in a repository.
+func (c *Client) ListOpenIssues(owner, repo string) ([]Issue, error) {
+	query := url.Values{}
+	query.Set("state", "open")
+	query.Set("per_page", "100")
+
+	var issues []Issue
+	path := fmt.Sprintf("/repos/%s/%s/issues", owner, repo)
+	for path != "" {
+		var page []Issue
+		next, err := c.get(path, query, &page)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, page...)
+		path, query = next, nil
+	}
+	return issues, nil
+}
+
+// ListIssuesPage returns one page of the issues and pull requests in a
+// repository, in any state and oldest first, and the URL of the next page.
+// An empty next starts at the first page; an empty returned URL means there
+// are no more pages.
+func (c *Client) ListIssuesPage(owner, repo, next string) ([]Issue, string, error) {
+	path, query := next, url.Values(nil)
+	if path == "" {
+		path = fmt.Sprintf("/repos/%s/%s/issues", owner, repo)
+		query = url.Values{}
+		query.Set("state", "all")
+		query.Set("sort", "created")
+		query.Set("direction", "asc")
+		query.Set("per_page", "100")
+	}
+
+	var page []Issue
+	next, err := c.get(path, query, &page)
+	if err != nil {
+		return nil, "", err
+	}
+	return page, next, nil
+}
+
+// DefaultBranch returns the name of a repository's default branch.
+func (c *Client) DefaultBranch(owner, repo string) (string, error) {
+	var result struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if _, err := c.get(fmt.Sprintf("/repos/%s/%s", owner, repo), nil, &result); err != nil {
+		return "", err
+	}
+	return result.DefaultBranch, nil
+}
+
+// Workflow is a GitHub Actions workflow.
+type Workflow struct {
+	// ID is the workflow ID.
+	ID int64 `json:"id"`
+	// Name is the workflow name.
+	Name string `json:"name"`
+	// Path is the workflow file, e.g. ".github/workflows/ci.yml".
+	Path string `json:"path"`
+	// State is "active" or a disabled state such as "disabled_manually".
+	State string `json:"state"`
+}
+
+// WorkflowRun is a run of a GitHub Actions workflow.
+type WorkflowRun struct {
+	// Status is the run status, e.g. "completed".
+	Status string `json:"status"`
+	// Conclusion is the result of a completed run, e.g. "success".
+	Conclusion string `json:"conclusion"`
+	// HeadBranch is the branch the run was for.
+	HeadBranch string `json:"head_branch"`
+	// CreatedAt is when the run started.
+	CreatedAt time.Time `json:"created_at"`
+	// HTMLURL is the permalink to the run.
+	HTMLURL string `json:"html_url"`
+}
+
+// ListWorkflows returns the GitHub Actions workflows of a repository.
+func (c *Client) ListWorkflows(owner, repo string) ([]Workflow, error) {
+	query := url.Values{}
+	query.Set("per_page", "100")
+
+	var workflows []Workflow
+	path := fmt.Sprintf("/repos/%s/%s/actions/workflows", owner, repo)
+	for path != "" {
+		var page struct {
+			Workflows []Workflow `json:"workflows"`
+		}
+		next, err := c.get(path, query, &page)
+		if err != nil {
+			return nil, err
+		}
+		workflows = append(workflows, page.Workflows...)
+		path, query = next, nil
+	}
+	return workflows, nil
+}
+
+// LatestWorkflowRun returns the most recent run of a workflow and the number
+// of runs, counting only runs with the given status (e.g. "success") and on
+// the given branch if they are not empty. The run is nil if there are none.
+func (c *Client) LatestWorkflowRun(owner, repo string, workflowID int64, status, branch string) (*WorkflowRun, int, error) {
+	query := url.Values{}
+	query.Set("per_page", "1")
+	if status != "" {
+		query.Set("status", status)
+	}
+	if branch != "" {
+		query.Set("branch", branch)
+	}
+
+	var result struct {
+		TotalCount   int           `json:"total_count"`
+		WorkflowRuns []WorkflowRun `json:"workflow_runs"`
+	}
+	if _, err := c.get(fmt.Sprintf("/repos/%s/%s/actions/workflows/%d/runs", owner, repo, workflowID), query, &result); err != nil {
+		return nil, 0, err
+	}
+	if len(result.WorkflowRuns) == 0 {
+		return nil, result.TotalCount, nil
+	}
+	return &result.WorkflowRuns[0], result.TotalCount, nil
+}
+
+// Repository is a GitHub repository.
+type Repository struct {
+	// Name is the repository name, without its owner.
+	Name string `json:"name"`
+	// FullName is the repository name with its owner, as owner/name.
+	FullName string `json:"full_name"`
+	// Description is the repository's description.
+	Description string `json:"description"`
+	// Archived is true if the repository is archived, and read-only.
+	Archived bool `json:"archived"`
+	// Fork is true if the repository is a fork of another.
+	Fork bool `json:"fork"`
+	// PushedAt is when a commit was last pushed to the repository.
+	PushedAt time.Time `json:"pushed_at"`
+}
+
+// ListOrgRepos returns every repository of an organization that the token,
+// if any, can see.
+func (c *Client) ListOrgRepos(org string) ([]Repository, error) {
+	query := url.Values{}
+	query.Set("type", "all")
+	query.Set("per_page", "100")
+
+	var repos []Repository
+	path := fmt.Sprintf("/orgs/%s/repos", url.PathEscape(org))
+	for path != "" {
+		var page []Repository
+		next, err := c.get(path, query, &page)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, page...)
+		path, query = next, nil
+	}
+	return repos, nil
+}
+
+// ListUserRepos returns every repository owned by the user the token
+// authenticates as, private ones included.
+func (c *Client) ListUserRepos() ([]Repository, error) {
+	query := url.Values{}
+	query.Set("affiliation", "owner")
+	query.Set("per_page", "100")
+
+	var repos []Repository
+	path := "/user/repos"
+	for path != "" {
+		var page []Repository
+		next, err := c.get(path, query, &page)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, page...)
+		path, query = next, nil
+	}
+	return repos, nil
+}
+
+// SearchRepos returns the repositories matching a GitHub search query, such as
+// "user:alice archived:true", that the token, if any, can see. GitHub returns
+// at most the first 1000 matches of a search.
+func (c *Client) SearchRepos(q string) ([]Repository, error) {
+	query := url.Values{}
+	query.Set("q", q)
+	query.Set("per_page", "100")
+
+	var repos []Repository
+	path := "/search/repositories"
+	for path != "" {
+		var page struct {
+			Items []Repository `json:"items"`
+		}
+		next, err := c.get(path, query, &page)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, page.Items...)
+		path, query = next, nil
+	}
+	return repos, nil
+}
+
+// Release is a published release of a repository.
+type Release struct {
+	// TagName is the tag the release was made from.
+	TagName string `json:"tag_name"`
+	// Name is the release's title, which may be empty.
+	Name string `json:"name"`
+	// Body is the release notes, as Markdown.
+	Body string `json:"body"`
+	// HTMLURL is the release's page.
+	HTMLURL string `json:"html_url"`
+	// PublishedAt is when the release was published.
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// ReleaseByTag returns the published release of a repository made from tag.
+func (c *Client) ReleaseByTag(owner, repo, tag string) (*Release, error) {
+	var release Release
+	if _, err := c.get(fmt.Sprintf("/repos/%s/%s/releases/tags/%s", owner, repo, url.PathEscape(tag)), nil, &release); err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+// Gist is a GitHub gist.
+type Gist struct {
+	// ID is the gist's ID, as in https://gist.github.com/<id>.
+	ID string `json:"id"`
+	// Description is the gist's description, its only title.
+	Description string `json:"description"`
+	// HTMLURL is the gist's page.
+	HTMLURL string `json:"html_url"`
+}
+
+// Gist returns the gist with the given ID.
+func (c *Client) Gist(id string) (*Gist, error) {
+	var gist Gist
+	if _, err := c.get("/gists/"+url.PathEscape(id), nil, &gist); err != nil {
+		return nil, err
+	}
+	return &gist, nil
+}
+
+// Topics returns the topics of a repository.
+func (c *Client) Topics(owner, repo string) ([]string, error) {
+	var result struct {
+		Names []string `json:"names"`
+	}
+	if _, err := c.get(fmt.Sprintf("/repos/%s/%s/topics", owner, repo), nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Names, nil
+}
+
+// CreateIssue opens a new issue in a repository.
+func (c *Client) CreateIssue(owner, repo, title, body string) (*Issue, error) {
+	var issue Issue
+	payload := map[string]string{"title": title, "body": body}
+	if err := c.send(http.MethodPost, fmt.Sprintf("/repos/%s/%s/issues", owner, repo), payload, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// UpdateIssueBody replaces the body of an existing issue.
+func (c *Client) UpdateIssueBody(owner, repo string, number int, body string) (*Issue, error) {
+	var issue Issue
+	payload := map[string]string{"body": body}
+	if err := c.send(http.MethodPatch, fmt.Sprintf("/repos/%s/%s/issues/%d", owner, repo, number), payload, &issue); err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// CloseIssue closes an issue.
+func (c *Client) CloseIssue(owner, repo string, number int) error {
+	var issue Issue
+	payload := map[string]string{"state": "closed"}
+	return c.send(http.MethodPatch, fmt.Sprintf("/repos/%s/%s/issues/%d", owner, repo, number), payload, &issue)
+}
+
+// Comment is a comment on an issue or pull request.
+type Comment struct {
+	// Body is the comment text.
+	Body string `json:"body"`
+	// User is the author of the comment.
+	User User `json:"user"`
+	// AuthorAssociation is the author's relationship to the repository,
+	// such as OWNER, MEMBER, COLLABORATOR, or NONE.
+	AuthorAssociation string `json:"author_association"`
+	// CreatedAt is when the comment was posted.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListComments returns every comment on an issue.
+func (c *Client) ListComments(owner, repo string, number int) ([]Comment, error) {
+	query := url.Values{}
+	query.Set("per_page", "100")
+
+	var comments []Comment
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d/comments", owner, repo, number)
+	for path != "" {
+		var page []Comment
+		next, err := c.get(path, query, &page)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, page...)
+		path, query = next, nil
+	}
+	return comments, nil
+}
+
+// CreateComment adds a comment to an issue.
+func (c *Client) CreateComment(owner, repo string, number int, body string) error {
+	var comment Comment
+	payload := map[string]string{"body": body}
+	return c.send(http.MethodPost, fmt.Sprintf("/repos/%s/%s/issues/%d/comments", owner, repo, number), payload, &comment)
+}
+
+// PinIssue pins an issue to its repository using the GraphQL API.
+func (c *Client) PinIssue(nodeID string) error {
+	payload := map[string]any{
+		"query":     "mutation($id: ID!) { pinIssue(input: {issueId: $id}) { issue { number } } }",
+		"variables": map[string]string{"id": nodeID},
+	}
+	var result struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	graphQL := c.GraphQLURL
+	if graphQL == "" {
+		graphQL = "/graphql"
+	}
+	if err := c.send(http.MethodPost, graphQL, payload, &result); err != nil {
+		return err
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("failed to pin issue: %s", result.Errors[0].Message)
+	}
+	return nil
+}
+
+// PullRequest is a GitHub pull request.
+type PullRequest struct {
+	// Number is the pull request number.
+	Number int `json:"number"`
+	// HTMLURL is the permalink to the pull request.
+	HTMLURL string `json:"html_url"`
+}
+
+// CreatePullRequest opens a pull request merging head into base.
+func (c *Client) CreatePullRequest(owner, repo, title, head, base, body string) (*PullRequest, error) {
+	var pr PullRequest
+	payload := map[string]string{"title": title, "head": head, "base": base, "body": body}
+	if err := c.send(http.MethodPost, fmt.Sprintf("/repos/%s/%s/pulls", owner, repo), payload, &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// repoURLPattern matches HTTPS and SSH GitHub remote URLs.
+var repoURLPattern = regexp.MustCompile(`^(?:https?://github\.com/|git@github\.com:|ssh://git@github\.com/)([^/]+)/([^/]+?)(?:\.git)?/?$`)
+
+// ParseRepoURL returns the owner and repository name of a GitHub remote URL.
+func ParseRepoURL(remoteURL string) (owner, repo string, ok bool) {
+	m := repoURLPattern.FindStringSubmatch(strings.TrimSpace(remoteURL))
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// send performs a request with a JSON body and decodes the JSON response into v.
+func (c *Client) send(method, path string, payload, v any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	reqURL := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		reqURL = c.BaseURL + path
+	}
+	req, err := http.NewRequest(method, reqURL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode GitHub response: %w", err)
+	}
+	return nil
+}
+
+// nextLinkPattern extracts the next page URL from a Link header.
+var nextLinkPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// get performs a GET request and decodes the JSON response into v. It returns
+// the URL of the next page of results, if any.
+func (c *Client) get(path string, query url.Values, v any) (string, error) {
+	reqURL := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		reqURL = c.BaseURL + path
+	}
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return "", fmt.Errorf("failed to decode GitHub response: %w", err)
+	}
+
+	var next string
+	if m := nextLinkPattern.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+		next = m[1]
+	}
+	return next, nil
+}
+
+// do sends an authenticated request and returns the response if it succeeded.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GitHub request failed: %w", err)
+	}
+	if err := rateLimitError(resp); err != nil {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		msg := strings.TrimSpace(string(body))
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+			msg = apiErr.Message
+		}
+		return nil, fmt.Errorf("GitHub API %s %s returned %d: %s", req.Method, req.URL.Path, resp.StatusCode, msg)
+	}
+	return resp, nil
+}
+
+// rateLimitError returns a RateLimitError if resp was refused because of the
+// primary or secondary rate limit.
+func rateLimitError(resp *http.Response) error {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return &RateLimitError{Reset: time.Now().Add(time.Duration(seconds) * time.Second)}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return nil
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return &RateLimitError{Reset: time.Now().Add(time.Minute)}
+	}
+	return &RateLimitError{Reset: time.Unix(reset, 0)}
+}
@@ -0,0 +1,476 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client that talks to a test server running handler.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client := NewClient("test-token")
+	client.BaseURL = server.URL
+	return client
+}
+
+func TestClient_CountIssues(t *testing.T) {
+	tests := []struct {
+		name         string
+		pullRequests bool
+		state        string
+		wantQuery    string
+	}{
+		{name: "open issues", state: "open", wantQuery: "repo:owner/repo is:issue is:open"},
+		{name: "closed pull requests", pullRequests: true, state: "closed", wantQuery: "repo:owner/repo is:pr is:closed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if got := r.URL.Query().Get("q"); got != tt.wantQuery {
+					t.Errorf("query = %q, want %q", got, tt.wantQuery)
+				}
+				if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+					t.Errorf("Authorization = %q, want bearer token", got)
+				}
+				_, _ = fmt.Fprint(w, `{"total_count": 7}`)
+			})
+
+			got, err := client.CountIssues("owner", "repo", tt.pullRequests, tt.state)
+			if err != nil {
+				t.Fatalf("CountIssues() error = %v", err)
+			}
+			if got != 7 {
+				t.Errorf("CountIssues() = %d, want 7", got)
+			}
+		})
+	}
+}
+
+func TestClient_ListOpenIssues(t *testing.T) {
+	var serverURL string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			_, _ = fmt.Fprint(w, `[{"number": 3, "title": "Third", "html_url": "https://github.com/owner/repo/pull/3", "pull_request": {}}]`)
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s/repos/owner/repo/issues?page=2>; rel="next"`, serverURL))
+		_, _ = fmt.Fprint(w, `[{"number": 1, "title": "First", "html_url": "https://github.com/owner/repo/issues/1"}]`)
+	})
+	serverURL = client.BaseURL
+
+	issues, err := client.ListOpenIssues("owner", "repo")
+	if err != nil {
+		t.Fatalf("ListOpenIssues() error = %v", err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("ListOpenIssues() returned %d issues, want 2", len(issues))
+	}
+	if issues[0].Number != 1 || issues[0].IsPullRequest() {
+		t.Errorf("issues[0] = %+v, want issue #1", issues[0])
+	}
+	if issues[1].Number != 3 || !issues[1].IsPullRequest() {
+		t.Errorf("issues[1] = %+v, want pull request #3", issues[1])
+	}
+}
+
+func TestClient_Topics(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/topics" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		_, _ = fmt.Fprint(w, `{"names": ["experiment", "ml"]}`)
+	})
+
+	got, err := client.Topics("owner", "repo")
+	if err != nil {
+		t.Fatalf("Topics() error = %v", err)
+	}
+	if want := []string{"experiment", "ml"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Topics() = %v, want %v", got, want)
+	}
+}
+
+func TestClient_ListOrgRepos(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/orgs/acme/repos" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("page") == "" {
+			if r.URL.Query().Get("type") != "all" {
+				t.Errorf("type = %q, want all", r.URL.Query().Get("type"))
+			}
+			w.Header().Set("Link", fmt.Sprintf(`<%s/orgs/acme/repos?page=2>; rel="next"`, server.URL))
+			_, _ = fmt.Fprint(w, `[{"name": "api", "full_name": "acme/api", "archived": false, "pushed_at": "2025-06-01T00:00:00Z"}]`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `[{"name": "old-app", "full_name": "acme/old-app", "archived": true, "fork": true, "pushed_at": "2021-03-04T05:06:07Z"}]`)
+	}))
+	t.Cleanup(server.Close)
+	client := NewClient("")
+	client.BaseURL = server.URL
+
+	repos, err := client.ListOrgRepos("acme")
+	if err != nil {
+		t.Fatalf("ListOrgRepos() error = %v", err)
+	}
+	if len(repos) != 2 {
+		t.Fatalf("ListOrgRepos() returned %d repositories, want 2 across both pages", len(repos))
+	}
+	if repos[0].FullName != "acme/api" || repos[0].Archived {
+		t.Errorf("repos[0] = %+v, want acme/api, not archived", repos[0])
+	}
+	old := repos[1]
+	if old.Name != "old-app" || !old.Archived || !old.Fork || !old.PushedAt.Equal(time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)) {
+		t.Errorf("repos[1] = %+v, want archived fork acme/old-app pushed 2021-03-04", old)
+	}
+}
+
+func TestClient_ListUserRepos(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user/repos" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("affiliation"); got != "owner" {
+			t.Errorf("affiliation = %q, want owner", got)
+		}
+		_, _ = fmt.Fprint(w, `[{"name": "notes", "full_name": "alice/notes", "archived": true}, {"name": "site", "full_name": "alice/site"}]`)
+	})
+
+	repos, err := client.ListUserRepos()
+	if err != nil {
+		t.Fatalf("ListUserRepos() error = %v", err)
+	}
+	if len(repos) != 2 || !repos[0].Archived || repos[1].Archived {
+		t.Errorf("ListUserRepos() = %+v, want archived alice/notes and alice/site", repos)
+	}
+}
+
+func TestClient_SearchRepos(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search/repositories" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.URL.Query().Get("page") == "" {
+			if q := r.URL.Query().Get("q"); q != "user:alice archived:true" {
+				t.Errorf("q = %q, want the query as given", q)
+			}
+			w.Header().Set("Link", fmt.Sprintf(`<%s/search/repositories?q=x&page=2>; rel="next"`, server.URL))
+			_, _ = fmt.Fprint(w, `{"total_count": 2, "items": [{"name": "notes", "full_name": "alice/notes", "archived": true}]}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"total_count": 2, "items": [{"name": "game", "full_name": "alice/game", "archived": true}]}`)
+	}))
+	t.Cleanup(server.Close)
+	client := NewClient("")
+	client.BaseURL = server.URL
+
+	repos, err := client.SearchRepos("user:alice archived:true")
+	if err != nil {
+		t.Fatalf("SearchRepos() error = %v", err)
+	}
+	if len(repos) != 2 || repos[0].FullName != "alice/notes" || repos[1].FullName != "alice/game" {
+		t.Errorf("SearchRepos() = %+v, want alice/notes and alice/game across both pages", repos)
+	}
+}
+
+func TestClient_ReleaseByTag(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/releases/tags/v1.2.0" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		_, _ = fmt.Fprint(w, `{"tag_name": "v1.2.0", "name": "Last light", "body": "Final release.", "html_url": "https://github.com/owner/repo/releases/tag/v1.2.0", "published_at": "2024-05-06T07:08:09Z"}`)
+	})
+
+	release, err := client.ReleaseByTag("owner", "repo", "v1.2.0")
+	if err != nil {
+		t.Fatalf("ReleaseByTag() error = %v", err)
+	}
+	if release.TagName != "v1.2.0" || release.Name != "Last light" || release.Body != "Final release." || release.PublishedAt.Year() != 2024 {
+		t.Errorf("ReleaseByTag() = %+v", release)
+	}
+}
+
+func TestClient_Gist(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/gists/aa5a315d61ae9438b18d" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		_, _ = fmt.Fprint(w, `{"id": "aa5a315d61ae9438b18d", "description": "Plot sensor data", "html_url": "https://gist.github.com/aa5a315d61ae9438b18d"}`)
+	})
+
+	gist, err := client.Gist("aa5a315d61ae9438b18d")
+	if err != nil {
+		t.Fatalf("Gist() error = %v", err)
+	}
+	if gist.ID != "aa5a315d61ae9438b18d" || gist.Description != "Plot sensor data" {
+		t.Errorf("Gist() = %+v", gist)
+	}
+}
+
+func TestClient_ListComments(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/issues/4/comments" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		_, _ = fmt.Fprint(w, `[{"body": "/keep", "user": {"login": "alice"}, "created_at": "2025-12-26T10:30:00Z"}]`)
+	})
+
+	got, err := client.ListComments("owner", "repo", 4)
+	if err != nil {
+		t.Fatalf("ListComments() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Body != "/keep" || got[0].User.Login != "alice" {
+		t.Errorf("ListComments() = %+v", got)
+	}
+}
+
+func TestClient_ErrorResponse(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = fmt.Fprint(w, `{"message": "Not Found"}`)
+	})
+
+	_, err := client.CountIssues("owner", "missing", false, "open")
+	if err == nil {
+		t.Fatalf("CountIssues() expected error, got nil")
+	}
+	if want := "returned 404: Not Found"; !strings.Contains(err.Error(), want) {
+		t.Errorf("CountIssues() error = %q, want it to contain %q", err, want)
+	}
+}
+
+func TestClient_ListIssuesPage(t *testing.T) {
+	var serverURL string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			_, _ = fmt.Fprint(w, `[{"number": 2, "title": "Second", "state": "closed", "closed_at": "2024-01-02T00:00:00Z"}]`)
+			return
+		}
+		if got := r.URL.Query().Get("state"); got != "all" {
+			t.Errorf("state = %q, want all", got)
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s/repos/owner/repo/issues?page=2>; rel="next"`, serverURL))
+		_, _ = fmt.Fprint(w, `[{"number": 1, "title": "First", "state": "open", "user": {"login": "alice"}, "labels": [{"name": "bug"}]}]`)
+	})
+	serverURL = client.BaseURL
+
+	page, next, err := client.ListIssuesPage("owner", "repo", "")
+	if err != nil {
+		t.Fatalf("ListIssuesPage() error = %v", err)
+	}
+	if len(page) != 1 || page[0].User.Login != "alice" || page[0].Labels[0].Name != "bug" || next == "" {
+		t.Fatalf("ListIssuesPage() = %+v, %q", page, next)
+	}
+
+	page, next, err = client.ListIssuesPage("owner", "repo", next)
+	if err != nil {
+		t.Fatalf("ListIssuesPage() error = %v", err)
+	}
+	if len(page) != 1 || page[0].Number != 2 || page[0].ClosedAt == nil || next != "" {
+		t.Errorf("ListIssuesPage() = %+v, %q", page, next)
+	}
+}
+
+func TestClient_Workflows(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/actions/workflows":
+			_, _ = fmt.Fprint(w, `{"total_count": 1, "workflows": [{"id": 42, "name": "CI", "path": ".github/workflows/ci.yml", "state": "active"}]}`)
+		case "/repos/owner/repo/actions/workflows/42/runs":
+			query := r.URL.Query()
+			if query.Get("status") != "failure" || query.Get("branch") != "main" || query.Get("per_page") != "1" {
+				t.Errorf("query = %v, want failure runs on main", query)
+			}
+			_, _ = fmt.Fprint(w, `{"total_count": 3, "workflow_runs": [{"status": "completed", "conclusion": "failure", "head_branch": "main", "created_at": "2024-05-01T10:00:00Z"}]}`)
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	})
+
+	workflows, err := client.ListWorkflows("owner", "repo")
+	if err != nil {
+		t.Fatalf("ListWorkflows() error = %v", err)
+	}
+	if len(workflows) != 1 || workflows[0].ID != 42 || workflows[0].Path != ".github/workflows/ci.yml" {
+		t.Errorf("ListWorkflows() = %+v", workflows)
+	}
+
+	run, total, err := client.LatestWorkflowRun("owner", "repo", 42, "failure", "main")
+	if err != nil {
+		t.Fatalf("LatestWorkflowRun() error = %v", err)
+	}
+	if total != 3 || run == nil || run.Conclusion != "failure" || !run.CreatedAt.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("LatestWorkflowRun() = %+v, %d", run, total)
+	}
+}
+
+func TestClient_RateLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		headers map[string]string
+		wantErr bool
+	}{
+		{name: "primary limit", status: http.StatusForbidden, headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1893456000"}, wantErr: true},
+		{name: "secondary limit", status: http.StatusTooManyRequests, headers: map[string]string{"Retry-After": "60"}, wantErr: true},
+		{name: "forbidden", status: http.StatusForbidden, headers: map[string]string{"X-RateLimit-Remaining": "42"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.headers {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = fmt.Fprint(w, `{"message": "limited"}`)
+			})
+
+			_, _, err := client.ListIssuesPage("owner", "repo", "")
+			var rateErr *RateLimitError
+			if got := errors.As(err, &rateErr); got != tt.wantErr {
+				t.Fatalf("ListIssuesPage() error = %v, want rate limit error %v", err, tt.wantErr)
+			}
+			if tt.name == "primary limit" && !rateErr.Reset.Equal(time.Unix(1893456000, 0)) {
+				t.Errorf("RateLimitError.Reset = %v", rateErr.Reset)
+			}
+		})
+	}
+}
+
+func TestClient_IssueMutations(t *testing.T) {
+	type request struct {
+		method string
+		path   string
+		body   map[string]any
+	}
+	var requests []request
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, request{method: r.Method, path: r.URL.Path, body: body})
+		if r.URL.Path == "/graphql" {
+			_, _ = fmt.Fprint(w, `{"data": {"pinIssue": {"issue": {"number": 9}}}}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"number": 9, "node_id": "I_9", "html_url": "https://github.com/owner/repo/issues/9"}`)
+	})
+
+	created, err := client.CreateIssue("owner", "repo", "Title", "Body")
+	if err != nil {
+		t.Fatalf("CreateIssue() error = %v", err)
+	}
+	if created.Number != 9 || created.NodeID != "I_9" {
+		t.Errorf("CreateIssue() = %+v, want issue #9", created)
+	}
+	if _, err := client.UpdateIssueBody("owner", "repo", 9, "New body"); err != nil {
+		t.Fatalf("UpdateIssueBody() error = %v", err)
+	}
+	if err := client.PinIssue("I_9"); err != nil {
+		t.Fatalf("PinIssue() error = %v", err)
+	}
+	if _, err := client.CreatePullRequest("owner", "repo", "Title", "feature", "main", "Body"); err != nil {
+		t.Fatalf("CreatePullRequest() error = %v", err)
+	}
+	if err := client.CreateComment("owner", "repo", 9, "Comment"); err != nil {
+		t.Fatalf("CreateComment() error = %v", err)
+	}
+	if err := client.CloseIssue("owner", "repo", 9); err != nil {
+		t.Fatalf("CloseIssue() error = %v", err)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		field  string
+		want   any
+	}{
+		{method: http.MethodPost, path: "/repos/owner/repo/issues", field: "title", want: "Title"},
+		{method: http.MethodPatch, path: "/repos/owner/repo/issues/9", field: "body", want: "New body"},
+		{method: http.MethodPost, path: "/graphql", field: "variables", want: map[string]any{"id": "I_9"}},
+		{method: http.MethodPost, path: "/repos/owner/repo/pulls", field: "head", want: "feature"},
+		{method: http.MethodPost, path: "/repos/owner/repo/issues/9/comments", field: "body", want: "Comment"},
+		{method: http.MethodPatch, path: "/repos/owner/repo/issues/9", field: "state", want: "closed"},
+	}
+	if len(requests) != len(tests) {
+		t.Fatalf("made %d requests, want %d", len(requests), len(tests))
+	}
+	for i, tt := range tests {
+		got := requests[i]
+		if got.method != tt.method || got.path != tt.path || fmt.Sprint(got.body[tt.field]) != fmt.Sprint(tt.want) {
+			t.Errorf("request %d = %s %s %v, want %s %s with %s=%v", i, got.method, got.path, got.body, tt.method, tt.path, tt.field, tt.want)
+		}
+	}
+}
+
+func TestClient_PinIssueGraphQLError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"errors": [{"message": "Resource not accessible"}]}`)
+	})
+	if err := client.PinIssue("I_1"); err == nil {
+		t.Errorf("PinIssue() expected error, got nil")
+	}
+}
+
+func TestEnterpriseClient(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		_, _ = fmt.Fprint(w, `{}`)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewEnterpriseClient("github.example.com", "test-token")
+	if client.BaseURL != "https://github.example.com/api/v3" || client.GraphQLURL != "https://github.example.com/api/graphql" {
+		t.Errorf("NewEnterpriseClient() URLs = %q, %q", client.BaseURL, client.GraphQLURL)
+	}
+
+	// The GraphQL API sits beside the REST API on GitHub Enterprise Server
+	client.BaseURL = server.URL + "/api/v3"
+	client.GraphQLURL = server.URL + "/api/graphql"
+	if err := client.PinIssue("I_1"); err != nil {
+		t.Fatalf("PinIssue() error = %v", err)
+	}
+	if _, err := client.DefaultBranch("owner", "repo"); err != nil {
+		t.Fatalf("DefaultBranch() error = %v", err)
+	}
+	if want := []string{"/api/graphql", "/api/v3/repos/owner/repo"}; strings.Join(paths, " ") != strings.Join(want, " ") {
+		t.Errorf("requested %v, want %v", paths, want)
+	}
+}
+
+func TestParseRepoURL(t *testing.T) {
+	tests := []struct {
+		url       string
+		wantOwner string
+		wantRepo  string
+		wantOK    bool
+	}{
+		{url: "https://github.com/owner/repo", wantOwner: "owner", wantRepo: "repo", wantOK: true},
+		{url: "https://github.com/owner/repo.git", wantOwner: "owner", wantRepo: "repo", wantOK: true},
+		{url: "git@github.com:owner/repo.git", wantOwner: "owner", wantRepo: "repo", wantOK: true},
+		{url: "ssh://git@github.com/owner/repo", wantOwner: "owner", wantRepo: "repo", wantOK: true},
+		{url: "https://gitlab.com/owner/repo", wantOK: false},
+		{url: "/path/to/repo", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			owner, repo, ok := ParseRepoURL(tt.url)
+			if owner != tt.wantOwner || repo != tt.wantRepo || ok != tt.wantOK {
+				t.Errorf("ParseRepoURL(%q) = %q, %q, %v, want %q, %q, %v", tt.url, owner, repo, ok, tt.wantOwner, tt.wantRepo, tt.wantOK)
+			}
+		})
+	}
+}
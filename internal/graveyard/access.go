@@ -0,0 +1,54 @@
+package graveyard
+
+import (
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/state"
+)
+
+// accessFile is the state file recording when the projects of the
+// graveyards used on this machine were read, keyed by graveyard path and
+// then by project.
+const accessFile = "access.json"
+
+// Access is how a buried project has been read on this machine.
+type Access struct {
+	// Last is when the project was last read.
+	Last time.Time `json:"last"`
+	// Count is the number of times it was read.
+	Count int `json:"count"`
+}
+
+// Accesses returns the recorded reads of the graveyard's projects, keyed by
+// project. Projects never read on this machine, or read before tracking was
+// turned on, are missing.
+func (g *Graveyard) Accesses() (map[string]Access, error) {
+	var all map[string]map[string]Access
+	if err := state.Load(accessFile, &all); err != nil {
+		return nil, err
+	}
+	if all[g.Path] == nil {
+		return map[string]Access{}, nil
+	}
+	return all[g.Path], nil
+}
+
+// RecordAccess records that project was read at the given time. The record
+// stays on this machine and is never committed to the graveyard.
+func (g *Graveyard) RecordAccess(project string, at time.Time) error {
+	var all map[string]map[string]Access
+	if err := state.Load(accessFile, &all); err != nil {
+		return err
+	}
+	if all == nil {
+		all = map[string]map[string]Access{}
+	}
+	if all[g.Path] == nil {
+		all[g.Path] = map[string]Access{}
+	}
+	access := all[g.Path][project]
+	access.Last = at
+	access.Count++
+	all[g.Path][project] = access
+	return state.Save(accessFile, all)
+}
@@ -0,0 +1,173 @@
+package graveyard
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/metadata"
+)
+
+// Compaction describes the history rewrite made by Compact.
+type Compaction struct {
+	// Project is the name of the compacted project.
+	Project string
+	// Rewritten is the number of graveyard commits that were rewritten.
+	Rewritten int
+	// Pushed is true if the rewritten commits had been pushed, so the
+	// graveyard must be force-pushed.
+	Pushed bool
+}
+
+// Compact drops the original history of a project buried with git subtree,
+// turning it into a snapshot-only burial to shrink the graveyard. The subtree
+// merge that brought in the history is rewritten as an ordinary commit with
+// the same content, every later commit on the branch is rewritten on top of
+// it, and the project's metadata is updated and staged. Version tags pointing
+// at rewritten commits are moved to their rewrites, so that they no longer
+// hold on to the old history. The project's old commits remain in the object
+// store until they are pruned.
+//
+// Commits that have already been pushed are only rewritten when force is set.
+func (g *Graveyard) Compact(name string, force bool) (*Compaction, error) {
+	if !g.ProjectExists(name) {
+		return nil, fmt.Errorf("project not found in graveyard: %s", name)
+	}
+	clean, err := git.IsClean(g.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !clean {
+		return nil, fmt.Errorf("graveyard has uncommitted changes; commit or stash them first")
+	}
+
+	merges, err := git.Log(g.Path, []string{"--first-parent", "--merges", "HEAD"})
+	if err != nil {
+		return nil, err
+	}
+	drop := make(map[string]bool)
+	var oldest git.LogEntry
+	for _, c := range merges {
+		if isSubtreeAdd(c, name) {
+			drop[c.Hash] = true
+			oldest = c
+		}
+	}
+	if len(drop) == 0 {
+		return nil, fmt.Errorf("%s was buried without history; there is nothing to compact", name)
+	}
+
+	result := &Compaction{Project: name}
+	if result.Pushed, err = git.IsPushed(g.Path, oldest.Hash); err != nil {
+		return nil, err
+	}
+	if result.Pushed && !force {
+		return nil, fmt.Errorf("the burial of %s has been pushed; compacting rewrites shared history and must be forced", name)
+	}
+
+	chain, err := git.Log(g.Path, []string{"--first-parent", "--reverse", "HEAD", "^" + oldest.Parents[0]})
+	if err != nil {
+		return nil, err
+	}
+	rewritten := make(map[string]string, len(chain))
+	for _, c := range chain {
+		parents := []string{c.Parents[0]}
+		if replacement, ok := rewritten[c.Parents[0]]; ok {
+			parents[0] = replacement
+		}
+		if !drop[c.Hash] {
+			parents = append(parents, c.Parents[1:]...)
+		}
+		raw, err := git.CatCommit(g.Path, c.Hash)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := git.WriteCommit(g.Path, rewriteCommit(raw, parents, drop[c.Hash]))
+		if err != nil {
+			return nil, err
+		}
+		rewritten[c.Hash] = hash
+	}
+	result.Rewritten = len(chain)
+
+	head := chain[len(chain)-1].Hash
+	if err := git.UpdateRef(g.Path, "HEAD", rewritten[head], head); err != nil {
+		return nil, err
+	}
+	if err := g.moveVersionTags(rewritten); err != nil {
+		return result, err
+	}
+
+	if err := metadata.UpdateField(g.ProjectPath(name), metadata.HistoryPreservedField, "No"); err != nil {
+		return result, fmt.Errorf("%s: %w", name, err)
+	}
+	if err := git.StageFile(g.Path, filepath.Join(name, metadata.FileName)); err != nil {
+		return result, fmt.Errorf("failed to stage metadata: %w", err)
+	}
+	return result, nil
+}
+
+// moveVersionTags points the version tags of any project that point at a
+// rewritten commit at its rewrite instead, given rewritten commits keyed by
+// their original hash.
+func (g *Graveyard) moveVersionTags(rewritten map[string]string) error {
+	refs, err := git.Refs(g.Path)
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		replacement, ok := rewritten[ref.Hash]
+		if !ref.IsTag || !strings.HasPrefix(ref.Name, versionTagPrefix) || !ok {
+			continue
+		}
+		if err := git.DeleteTag(g.Path, ref.Name); err != nil {
+			return err
+		}
+		if err := git.CreateTag(g.Path, ref.Name, replacement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteCommit returns raw commit content with its parents replaced and any
+// signature removed, since the signature no longer matches. With
+// dropSubtree, the git-subtree trailers are removed from the message so the
+// commit no longer points at the dropped history.
+func rewriteCommit(raw string, parents []string, dropSubtree bool) string {
+	header, message, _ := strings.Cut(raw, "\n\n")
+
+	var b strings.Builder
+	skipping := false
+	for _, line := range strings.Split(header, "\n") {
+		if strings.HasPrefix(line, " ") && skipping {
+			continue
+		}
+		skipping = false
+		switch {
+		case strings.HasPrefix(line, "parent "):
+			continue
+		case strings.HasPrefix(line, "gpgsig") || strings.HasPrefix(line, "mergetag "):
+			skipping = true
+			continue
+		}
+		b.WriteString(line + "\n")
+		if strings.HasPrefix(line, "tree ") {
+			for _, parent := range parents {
+				b.WriteString("parent " + parent + "\n")
+			}
+		}
+	}
+
+	if dropSubtree {
+		var kept []string
+		for _, line := range strings.Split(message, "\n") {
+			if !strings.HasPrefix(line, "git-subtree-") {
+				kept = append(kept, line)
+			}
+		}
+		message = strings.TrimRight(strings.Join(kept, "\n"), "\n") + "\n"
+	}
+	return b.String() + "\n" + message
+}
@@ -0,0 +1,146 @@
+package graveyard
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/metadata"
+)
+
+func TestGraveyard_Compact(t *testing.T) {
+	gy := &Graveyard{Path: initGraveyard(t, map[string]string{"README.md": "graveyard"})}
+	buryWithHistory(t, gy, "project", map[string]string{"main.go": "package main"})
+	meta := &metadata.Metadata{OriginalSource: "/src/project", BuriedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), HistoryPreserved: true}
+	if err := meta.Write(gy.ProjectPath("project")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	runGit(t, gy.Path, "commit", "-am", "docs: bury-it - tagged project")
+
+	split, err := git.SubtreeSplit(gy.Path, "project")
+	if err != nil || split == "" {
+		t.Fatalf("SubtreeSplit() = %q, %v, want the buried history", split, err)
+	}
+	oldHead, err := git.Head(gy.Path)
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+
+	result, err := gy.Compact("project", false)
+	if err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if result.Rewritten != 3 || result.Pushed {
+		t.Errorf("Compact() = %+v, want 3 unpushed commits rewritten", result)
+	}
+
+	// The content is unchanged, but the project's history is gone.
+	runGit(t, gy.Path, "diff", "--quiet", oldHead, "HEAD", "--", "project/main.go")
+	merges, err := git.Log(gy.Path, []string{"--merges", "HEAD"})
+	if err != nil || len(merges) != 0 {
+		t.Errorf("Log(--merges) = %+v, %v, want no merges after Compact()", merges, err)
+	}
+	if split, err := git.SubtreeSplit(gy.Path, "project"); err != nil || split != "" {
+		t.Errorf("SubtreeSplit() = %q, %v, want no history after Compact()", split, err)
+	}
+	got, err := gy.Metadata("project")
+	if err != nil || got.HistoryPreserved {
+		t.Errorf("Metadata() = %+v, %v, want history not preserved", got, err)
+	}
+	if staged, _ := git.HasStagedChanges(gy.Path); !staged {
+		t.Errorf("Compact() did not stage the metadata file")
+	}
+
+	runGit(t, gy.Path, "commit", "-m", "docs: bury-it - compacted project")
+	if _, err := gy.Compact("project", false); err == nil || !strings.Contains(err.Error(), "nothing to compact") {
+		t.Errorf("Compact() error = %v, want nothing to compact", err)
+	}
+}
+
+func TestGraveyard_Compact_VersionTags(t *testing.T) {
+	gy := &Graveyard{Path: initGraveyard(t, map[string]string{"README.md": "graveyard"})}
+	buryWithHistory(t, gy, "project", map[string]string{"main.go": "package main"})
+	if err := git.CreateTag(gy.Path, VersionTag("project", 1), "HEAD"); err != nil {
+		t.Fatalf("CreateTag() error = %v", err)
+	}
+	merges, err := git.Log(gy.Path, []string{"--merges", "HEAD"})
+	if err != nil || len(merges) != 1 {
+		t.Fatalf("Log(--merges) = %+v, %v, want the subtree merge", merges, err)
+	}
+	buried := merges[0].Parents[1]
+	oldTag, err := git.Head(gy.Path)
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+	meta := &metadata.Metadata{OriginalSource: "/src/project", HistoryPreserved: true}
+	if err := meta.Write(gy.ProjectPath("project")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	runGit(t, gy.Path, "commit", "-am", "docs: bury-it - tagged project")
+
+	if _, err := gy.Compact("project", false); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	runGit(t, gy.Path, "commit", "-m", "docs: bury-it - compacted project")
+
+	// The tag moved to the rewritten burial, which has the same content
+	tagged, err := git.Log(gy.Path, []string{"-1", VersionTag("project", 1)})
+	if err != nil || len(tagged) != 1 || len(tagged[0].Parents) != 1 {
+		t.Fatalf("Log(%s) = %+v, %v, want the rewritten burial", VersionTag("project", 1), tagged, err)
+	}
+	runGit(t, gy.Path, "diff", "--quiet", oldTag, VersionTag("project", 1))
+
+	// Nothing holds on to the old history, so pruning deletes it
+	if err := git.PruneUnreachable(gy.Path); err != nil {
+		t.Fatalf("PruneUnreachable() error = %v", err)
+	}
+	if err := exec.Command("git", "-C", gy.Path, "cat-file", "-e", buried).Run(); err == nil {
+		t.Errorf("buried commit %s still exists after pruning", buried)
+	}
+}
+
+func TestGraveyard_Compact_Pushed(t *testing.T) {
+	gy := &Graveyard{Path: initGraveyard(t, map[string]string{"README.md": "graveyard"})}
+	buryWithHistory(t, gy, "project", map[string]string{"main.go": "package main"})
+	meta := &metadata.Metadata{OriginalSource: "/src/project", HistoryPreserved: true}
+	if err := os.WriteFile(filepath.Join(gy.ProjectPath("project"), metadata.FileName), []byte(meta.Generate()), 0644); err != nil {
+		t.Fatalf("Failed to write metadata: %v", err)
+	}
+	runGit(t, gy.Path, "commit", "-am", "metadata")
+
+	remote := t.TempDir()
+	runGit(t, remote, "init", "--bare")
+	runGit(t, gy.Path, "remote", "add", "origin", remote)
+	runGit(t, gy.Path, "push", "origin", "HEAD")
+	runGit(t, gy.Path, "fetch", "origin")
+
+	if _, err := gy.Compact("project", false); err == nil {
+		t.Fatalf("Compact() expected error for pushed history without force")
+	}
+	result, err := gy.Compact("project", true)
+	if err != nil {
+		t.Fatalf("Compact(force) error = %v", err)
+	}
+	if !result.Pushed {
+		t.Errorf("Compact(force) = %+v, want Pushed", result)
+	}
+}
+
+func TestRewriteCommit(t *testing.T) {
+	raw := "tree abc\nparent p1\nparent p2\nauthor A <a@a> 1 +0000\ncommitter C <c@c> 1 +0000\ngpgsig -----BEGIN PGP SIGNATURE-----\n line\n -----END PGP SIGNATURE-----\n\nAdd 'x/' from commit 'p2'\n\ngit-subtree-dir: x\ngit-subtree-mainline: p1\ngit-subtree-split: p2\n"
+
+	got := rewriteCommit(raw, []string{"n1"}, true)
+	want := "tree abc\nparent n1\nauthor A <a@a> 1 +0000\ncommitter C <c@c> 1 +0000\n\nAdd 'x/' from commit 'p2'\n"
+	if got != want {
+		t.Errorf("rewriteCommit() = %q, want %q", got, want)
+	}
+
+	got = rewriteCommit("tree abc\nparent p1\nauthor A <a@a> 1 +0000\n\nmessage\n\ngit-subtree-dir: x\n", []string{"n1"}, false)
+	if !strings.Contains(got, "parent n1\n") || !strings.HasSuffix(got, "\n\nmessage\n\ngit-subtree-dir: x\n") {
+		t.Errorf("rewriteCommit() = %q, want the message kept", got)
+	}
+}
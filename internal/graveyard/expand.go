@@ -0,0 +1,88 @@
+package graveyard
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/metadata"
+)
+
+// Expansion describes the history attached by Expand.
+type Expansion struct {
+	// Project is the name of the expanded project.
+	Project string
+	// Commit is the source commit whose history was attached.
+	Commit string
+	// Merge is the graveyard commit that attached the history.
+	Merge string
+	// Changed lists the files that differ between the buried snapshot and
+	// the attached commit, if the source moved on after the burial.
+	Changed []string
+}
+
+// Expand attaches the git history of a project buried without it. The ref of
+// the source repository at url is fetched and joined to the graveyard with a
+// subtree merge that keeps the project's files as they were buried, so the
+// result looks like a burial made with history. The project's metadata is
+// updated and staged.
+func (g *Graveyard) Expand(name, url, ref string) (*Expansion, error) {
+	if !g.ProjectExists(name) {
+		return nil, fmt.Errorf("project not found in graveyard: %s", name)
+	}
+	meta, err := g.Metadata(name)
+	if err != nil {
+		return nil, err
+	}
+	if meta.HistoryPreserved {
+		return nil, fmt.Errorf("%s was buried with its history; there is nothing to expand", name)
+	}
+	clean, err := git.IsClean(g.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !clean {
+		return nil, fmt.Errorf("graveyard has uncommitted changes; commit or stash them first")
+	}
+
+	head, err := git.Head(g.Path)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := git.Fetch(g.Path, url, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+
+	result := &Expansion{Project: name, Commit: commit}
+	changed, err := git.DiffNames(g.Path, commit, head+":"+name)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range changed {
+		if !strings.HasPrefix(path, ".bury-it") {
+			result.Changed = append(result.Changed, path)
+		}
+	}
+
+	// Record the merge the way git subtree add does, so the project's
+	// history can be found, split, and compacted like any other burial.
+	message := fmt.Sprintf("Add '%s/' from commit '%s'\n\ngit-subtree-dir: %s\ngit-subtree-mainline: %s\ngit-subtree-split: %s\n",
+		name, commit, name, head, commit)
+	result.Merge, err = git.CommitTree(g.Path, head+"^{tree}", message, head, commit)
+	if err != nil {
+		return nil, err
+	}
+	if err := git.UpdateRef(g.Path, "HEAD", result.Merge, head); err != nil {
+		return nil, err
+	}
+
+	if err := metadata.UpdateField(g.ProjectPath(name), metadata.HistoryPreservedField, "Yes"); err != nil {
+		return result, fmt.Errorf("%s: %w", name, err)
+	}
+	if err := git.StageFile(g.Path, filepath.Join(name, metadata.FileName)); err != nil {
+		return result, fmt.Errorf("failed to stage metadata: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,77 @@
+package graveyard
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/metadata"
+)
+
+func TestGraveyard_Expand(t *testing.T) {
+	source := initGraveyard(t, map[string]string{"main.go": "package main", "README.md": "project"})
+	meta := &metadata.Metadata{OriginalSource: source}
+	gy := &Graveyard{Path: initGraveyard(t, map[string]string{
+		"README.md":                    "graveyard",
+		"project/main.go":              "package main",
+		"project/README.md":            "project",
+		"project/" + metadata.FileName: meta.Generate(),
+	})}
+
+	result, err := gy.Expand("project", source, "HEAD")
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	if len(result.Changed) != 0 {
+		t.Errorf("Expand() changed = %v, want the snapshot to match the source", result.Changed)
+	}
+	if split, err := git.SubtreeSplit(gy.Path, "project"); err != nil || split != result.Commit {
+		t.Errorf("SubtreeSplit() = %q, %v, want %s", split, err, result.Commit)
+	}
+	got, err := gy.Metadata("project")
+	if err != nil || !got.HistoryPreserved {
+		t.Errorf("Metadata() = %+v, %v, want history preserved", got, err)
+	}
+	if staged, _ := git.HasStagedChanges(gy.Path); !staged {
+		t.Errorf("Expand() did not stage the metadata file")
+	}
+	runGit(t, gy.Path, "commit", "-m", "docs: bury-it - expanded project")
+
+	if _, err := gy.Expand("project", source, "HEAD"); err == nil || !strings.Contains(err.Error(), "nothing to expand") {
+		t.Errorf("Expand() error = %v, want nothing to expand", err)
+	}
+
+	// The attached history can be dropped again.
+	if _, err := gy.Compact("project", false); err != nil {
+		t.Errorf("Compact() after Expand() error = %v", err)
+	}
+}
+
+func TestGraveyard_Expand_SourceMoved(t *testing.T) {
+	source := initGraveyard(t, map[string]string{"main.go": "package main"})
+	meta := &metadata.Metadata{OriginalSource: source}
+	gy := &Graveyard{Path: initGraveyard(t, map[string]string{
+		"project/main.go":              "package main",
+		"project/" + metadata.FileName: meta.Generate(),
+	})}
+	if err := os.WriteFile(filepath.Join(source, "extra.go"), []byte("package main"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, source, "add", "-A")
+	runGit(t, source, "commit", "-m", "after burial")
+
+	result, err := gy.Expand("project", source, "HEAD")
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	if !reflect.DeepEqual(result.Changed, []string{"extra.go"}) {
+		t.Errorf("Expand() changed = %v, want [extra.go]", result.Changed)
+	}
+	// The buried files are kept as they were.
+	if _, err := os.Stat(filepath.Join(gy.ProjectPath("project"), "extra.go")); !os.IsNotExist(err) {
+		t.Errorf("Expand() added extra.go to the project, err = %v", err)
+	}
+}
@@ -4,10 +4,16 @@ package graveyard
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/deanhigh/bury-it/internal/audit"
 	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/metadata"
 )
 
 // Graveyard represents a graveyard repository.
@@ -75,6 +81,20 @@ func (g *Graveyard) ProjectExists(name string) bool {
 
 // ValidateProjectName checks if a project name can be used.
 func (g *Graveyard) ValidateProjectName(name string) error {
+	if err := checkProjectName(name); err != nil {
+		return err
+	}
+
+	// Check if project already exists
+	if g.ProjectExists(name) {
+		return fmt.Errorf("project already exists in graveyard: %s (use --name to specify an alternative name, or --new-version to bury it again)", name)
+	}
+
+	return nil
+}
+
+// checkProjectName checks that name is usable as a project directory.
+func checkProjectName(name string) error {
 	if name == "" {
 		return fmt.Errorf("project name cannot be empty")
 	}
@@ -89,10 +109,645 @@ func (g *Graveyard) ValidateProjectName(name string) error {
 		return fmt.Errorf("project name cannot be '.' or '..'")
 	}
 
-	// Check if project already exists
-	if g.ProjectExists(name) {
-		return fmt.Errorf("project already exists in graveyard: %s (use --name to specify an alternative name)", name)
+	return nil
+}
+
+// Projects returns the names of all buried projects, sorted alphabetically.
+// A buried project is a top-level directory containing a metadata file.
+func (g *Graveyard) Projects() ([]string, error) {
+	entries, err := os.ReadDir(g.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read graveyard: %w", err)
+	}
+
+	var projects []string
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		metaPath := filepath.Join(g.Path, entry.Name(), metadata.FileName)
+		if _, err := os.Stat(metaPath); err == nil {
+			projects = append(projects, entry.Name())
+		}
+	}
+	return projects, nil
+}
+
+// Metadata reads the metadata of a buried project.
+func (g *Graveyard) Metadata(name string) (*metadata.Metadata, error) {
+	if !g.ProjectExists(name) {
+		return nil, fmt.Errorf("project not found in graveyard: %s", name)
+	}
+	meta, err := metadata.Read(g.ProjectPath(name))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
 	}
+	return meta, nil
+}
 
+// SetTags replaces the tags of a buried project and stages its metadata file.
+func (g *Graveyard) SetTags(name string, tags []string) error {
+	return g.SetField(name, metadata.TagsField, strings.Join(tags, ", "))
+}
+
+// SetField sets a row of a buried project's metadata table and stages the
+// metadata file. An empty value removes the row.
+func (g *Graveyard) SetField(name, key, value string) error {
+	if !g.ProjectExists(name) {
+		return fmt.Errorf("project not found in graveyard: %s", name)
+	}
+	if err := metadata.UpdateField(g.ProjectPath(name), key, value); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if err := git.StageFile(g.Path, filepath.Join(name, metadata.FileName)); err != nil {
+		return fmt.Errorf("failed to stage metadata: %w", err)
+	}
 	return nil
 }
+
+// Match is a line in a buried project that matched a search pattern.
+type Match struct {
+	// Project is the name of the buried project.
+	Project string `json:"project"`
+	// Path is the file path relative to the project directory.
+	Path string `json:"path"`
+	// Line is the 1-based line number of the match.
+	Line int `json:"line"`
+	// Text is the content of the matching line.
+	Text string `json:"text"`
+}
+
+// Grep searches the contents of buried projects for pattern.
+// If project is non-empty, the search is limited to that project.
+func (g *Graveyard) Grep(pattern, project string, ignoreCase bool) ([]Match, error) {
+	var projects []string
+	if project != "" {
+		if !g.ProjectExists(project) {
+			return nil, fmt.Errorf("project not found in graveyard: %s", project)
+		}
+		projects = []string{project}
+	} else {
+		var err error
+		projects, err = g.Projects()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(projects) == 0 {
+		return nil, nil
+	}
+
+	paths := make([]string, len(projects))
+	for i, name := range projects {
+		paths[i] = name + "/"
+	}
+
+	gitMatches, err := git.Grep(g.Path, pattern, ignoreCase, paths...)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]Match, 0, len(gitMatches))
+	for _, m := range gitMatches {
+		name, rel, ok := strings.Cut(m.Path, "/")
+		if !ok {
+			continue
+		}
+		matches = append(matches, Match{Project: name, Path: rel, Line: m.Line, Text: m.Text})
+	}
+	return matches, nil
+}
+
+// History returns the commits that make up a buried project's history, newest
+// first. This includes the project's original history when it was buried with
+// git subtree, followed by graveyard commits that touched the project.
+func (g *Graveyard) History(name string) ([]git.LogEntry, error) {
+	if !g.ProjectExists(name) {
+		return nil, fmt.Errorf("project not found in graveyard: %s", name)
+	}
+
+	commits, err := git.Log(g.Path, []string{"HEAD"}, name+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	split, err := git.SubtreeSplit(g.Path, name)
+	if err != nil {
+		return nil, err
+	}
+	if split == "" {
+		return commits, nil
+	}
+
+	original, err := git.Log(g.Path, []string{split})
+	if err != nil {
+		return nil, err
+	}
+	return appendNew(commits, original), nil
+}
+
+// Locate returns the buried project that path belongs to and the path within
+// the project, slash-separated and "" for the project directory itself. path
+// may be absolute, relative to the working directory, or relative to the
+// graveyard.
+func (g *Graveyard) Locate(path string) (project, rel string, err error) {
+	abs := path
+	if !filepath.IsAbs(path) {
+		abs = filepath.Join(g.Path, path)
+		if _, err := os.Lstat(path); err == nil {
+			if abs, err = filepath.Abs(path); err != nil {
+				return "", "", err
+			}
+		}
+	}
+	root := g.Path
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	// Resolve the directory only, so a symbolic link in a project is
+	// located rather than followed
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		abs = filepath.Join(dir, filepath.Base(abs))
+	}
+
+	inside, err := filepath.Rel(root, abs)
+	if err != nil || inside == "." || inside == ".." || strings.HasPrefix(inside, ".."+string(filepath.Separator)) {
+		return "", "", fmt.Errorf("%s is not inside the graveyard %s", path, g.Path)
+	}
+	project, rel, _ = strings.Cut(filepath.ToSlash(inside), "/")
+	if !g.ProjectExists(project) {
+		return "", "", fmt.Errorf("%s is not part of a buried project", path)
+	}
+	return project, rel, nil
+}
+
+// ProjectFile returns the path in the graveyard of a file in a buried
+// project, given its path relative to the project.
+func (g *Graveyard) ProjectFile(name, rel string) (string, error) {
+	if !g.ProjectExists(name) {
+		return "", fmt.Errorf("project not found in graveyard: %s", name)
+	}
+	rel = path.Clean(filepath.ToSlash(rel))
+	if path.IsAbs(rel) || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("%s is not a path within %s", rel, name)
+	}
+	return name + "/" + rel, nil
+}
+
+// FileHistory returns the commits that changed a path within a buried
+// project, newest first: the graveyard commits that touched it followed, for
+// projects buried with git subtree, by the original commits, following the
+// path across renames. An empty path gives the whole project's history.
+func (g *Graveyard) FileHistory(project, path string) ([]git.LogEntry, error) {
+	if path == "" {
+		return g.History(project)
+	}
+	if !g.ProjectExists(project) {
+		return nil, fmt.Errorf("project not found in graveyard: %s", project)
+	}
+
+	commits, err := git.Log(g.Path, []string{"HEAD"}, project+"/"+path)
+	if err != nil {
+		return nil, err
+	}
+
+	split, err := git.SubtreeSplit(g.Path, project)
+	if err != nil {
+		return nil, err
+	}
+	if split == "" {
+		return commits, nil
+	}
+
+	original, err := git.Log(g.Path, []string{"--follow", split}, path)
+	if err != nil {
+		return nil, err
+	}
+	return appendNew(commits, original), nil
+}
+
+// LogScope is a set of revisions and paths for git log to walk.
+type LogScope struct {
+	// Revs are the revisions to walk from.
+	Revs []string
+	// Paths limit the walk to commits that changed them, if any.
+	Paths []string
+}
+
+// HistoryScopes returns the git log scopes that together cover a buried
+// project's history, newest first. The first walks the graveyard commits that
+// touched the project, from HEAD and the tags of its earlier versions. For
+// projects buried with git subtree, the rest walk the original history of
+// each burial, where the project's files are at the root. paths, relative to
+// the project, narrow every scope.
+func (g *Graveyard) HistoryScopes(name string, paths []string) ([]LogScope, error) {
+	if !g.ProjectExists(name) {
+		return nil, fmt.Errorf("project not found in graveyard: %s", name)
+	}
+	tags, err := git.Tags(g.Path, versionTagPrefix+name+"/v*", "")
+	if err != nil {
+		return nil, err
+	}
+	graveyard := LogScope{Revs: append([]string{"HEAD"}, tags...), Paths: []string{name + "/"}}
+	if len(paths) > 0 {
+		graveyard.Paths = nil
+		for _, p := range paths {
+			graveyard.Paths = append(graveyard.Paths, path.Join(name, p))
+		}
+	}
+	scopes := []LogScope{graveyard}
+
+	splits, err := git.SubtreeSplits(g.Path, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, split := range splits {
+		scopes = append(scopes, LogScope{Revs: []string{split}, Paths: paths})
+	}
+	return scopes, nil
+}
+
+// appendNew appends the commits of more that are not already in commits.
+func appendNew(commits, more []git.LogEntry) []git.LogEntry {
+	seen := make(map[string]bool, len(commits))
+	for _, c := range commits {
+		seen[c.Hash] = true
+	}
+	for _, c := range more {
+		if !seen[c.Hash] {
+			commits = append(commits, c)
+		}
+	}
+	return commits
+}
+
+// ProjectObjects returns the git objects that belong to a buried project,
+// keyed by hash, with paths relative to the project directory. This covers
+// the project's files throughout the graveyard's history and, for projects
+// buried with history, every object of the original repository.
+func (g *Graveyard) ProjectObjects(name string) (map[string]string, error) {
+	objects := make(map[string]string)
+
+	prefix := name + "/"
+	inGraveyard, err := git.ListObjects(g.Path, []string{"--all"}, prefix)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range inGraveyard {
+		if rel, ok := strings.CutPrefix(obj.Path, prefix); ok {
+			objects[obj.Hash] = rel
+		} else if obj.Path == name {
+			objects[obj.Hash] = ""
+		}
+	}
+
+	split, err := git.SubtreeSplit(g.Path, name)
+	if err != nil {
+		return nil, err
+	}
+	if split != "" {
+		original, err := git.ListObjects(g.Path, []string{split})
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range original {
+			if _, ok := objects[obj.Hash]; !ok {
+				objects[obj.Hash] = obj.Path
+			}
+		}
+	}
+	return objects, nil
+}
+
+// Usage is the disk space used by a buried project.
+type Usage struct {
+	// Project is the name of the buried project.
+	Project string `json:"project"`
+	// WorkTree is the size of the project's checked-out files in bytes.
+	WorkTree int64 `json:"worktree_bytes"`
+	// Packed is the approximate on-disk size of the project's git objects.
+	Packed int64 `json:"packed_bytes"`
+}
+
+// DiskUsage reports the working-tree size and approximate object storage
+// used by a buried project.
+func (g *Graveyard) DiskUsage(name string) (*Usage, error) {
+	if !g.ProjectExists(name) {
+		return nil, fmt.Errorf("project not found in graveyard: %s", name)
+	}
+
+	usage := &Usage{Project: name}
+	err := filepath.WalkDir(g.ProjectPath(name), func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			usage.WorkTree += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure %s: %w", name, err)
+	}
+
+	objects, err := g.ProjectObjects(name)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, 0, len(objects))
+	for hash := range objects {
+		hashes = append(hashes, hash)
+	}
+	infos, err := git.ObjectInfos(g.Path, hashes)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		usage.Packed += info.DiskSize
+	}
+	return usage, nil
+}
+
+// Blob is a file version stored in the graveyard's history.
+type Blob struct {
+	// Project is the name of the buried project the blob belongs to.
+	Project string `json:"project"`
+	// Path is the path of the blob relative to the project directory.
+	Path string `json:"path"`
+	// Hash is the blob's object hash.
+	Hash string `json:"hash"`
+	// Size is the uncompressed size of the blob in bytes.
+	Size int64 `json:"size_bytes"`
+}
+
+// LargestBlobs returns the largest blobs across the history of buried
+// projects, biggest first. If project is non-empty only that project is
+// considered. At most limit blobs are returned; zero means no limit.
+func (g *Graveyard) LargestBlobs(project string, limit int) ([]Blob, error) {
+	projects := []string{project}
+	if project == "" {
+		var err error
+		projects, err = g.Projects()
+		if err != nil {
+			return nil, err
+		}
+	} else if !g.ProjectExists(project) {
+		return nil, fmt.Errorf("project not found in graveyard: %s", project)
+	}
+
+	var blobs []Blob
+	for _, name := range projects {
+		objects, err := g.ProjectObjects(name)
+		if err != nil {
+			return nil, err
+		}
+		hashes := make([]string, 0, len(objects))
+		for hash := range objects {
+			hashes = append(hashes, hash)
+		}
+		infos, err := git.ObjectInfos(g.Path, hashes)
+		if err != nil {
+			return nil, err
+		}
+		for hash, info := range infos {
+			if info.Type == "blob" {
+				blobs = append(blobs, Blob{Project: name, Path: objects[hash], Hash: hash, Size: info.Size})
+			}
+		}
+	}
+
+	sort.Slice(blobs, func(i, j int) bool {
+		if blobs[i].Size != blobs[j].Size {
+			return blobs[i].Size > blobs[j].Size
+		}
+		if blobs[i].Project != blobs[j].Project {
+			return blobs[i].Project < blobs[j].Project
+		}
+		return blobs[i].Path < blobs[j].Path
+	})
+	if limit > 0 && len(blobs) > limit {
+		blobs = blobs[:limit]
+	}
+	return blobs, nil
+}
+
+// burialSubjectPrefix starts the subject of every burial commit.
+const burialSubjectPrefix = "docs: bury-it - archived "
+
+// BurialMessage returns the commit message used when burying a project.
+func BurialMessage(name string) string {
+	return burialSubjectPrefix + name
+}
+
+// issueExportSubjectPrefix starts the subject of every commit of an issue
+// export.
+const issueExportSubjectPrefix = "docs: bury-it - exported issues of "
+
+// IssueExportMessage returns the commit message used when exporting the
+// issues of a buried project.
+func IssueExportMessage(name string) string {
+	return issueExportSubjectPrefix + name
+}
+
+// undoLogSubjectPrefix starts the subject of the commit logging an undo.
+const undoLogSubjectPrefix = "docs: bury-it - logged undo of "
+
+// UndoLogMessage returns the commit message used when logging the undo of a
+// burial.
+func UndoLogMessage(name string) string {
+	return undoLogSubjectPrefix + name
+}
+
+// keptOnUndo are the graveyard files an undo by revert leaves as they are:
+// the audit log, which is only ever appended to, and the attributes that
+// mark it for union merges.
+var keptOnUndo = []string{path.Join(audit.Dir, audit.FileName), ".gitattributes"}
+
+// revertPattern finds the commit a git revert commit reverts in its body.
+var revertPattern = regexp.MustCompile(`(?m)^This reverts commit ([0-9a-f]{40})`)
+
+// Burial is a burial recorded in the graveyard's history.
+type Burial struct {
+	// Project is the name of the buried project.
+	Project string
+	// Commits are the commits created by the burial, newest first. A burial
+	// with history consists of the metadata commit and the subtree merge,
+	// preceded by the commits of any issue export that followed it.
+	Commits []string
+	// Merge is the subtree merge commit, if history was preserved.
+	Merge string
+	// Parent is the commit the graveyard was at before the burial.
+	Parent string
+	// Retired is the version of the project the burial replaced, or zero if
+	// it was the project's first burial.
+	Retired int
+	// Later are the commits of earlier undos made since the burial, newest
+	// first: the reverts, the burials they reverted, and the commits logging
+	// them. Undo keeps them, so the burial is reverted rather than reset.
+	Later []string
+}
+
+// LastBurial returns the most recent burial still in place, together with
+// the issue export that followed it, if any. The commits of undos made since,
+// and the burials they reverted, are passed over, so that burials can be
+// undone one after another.
+func (g *Graveyard) LastBurial() (*Burial, error) {
+	var later []string
+	reverted := make(map[string]bool)
+	rev := "HEAD"
+	for {
+		commits, err := git.Log(g.Path, []string{"-1", rev})
+		if err != nil {
+			return nil, err
+		}
+		if len(commits) == 0 {
+			return nil, fmt.Errorf("graveyard has no commits")
+		}
+		c := commits[0]
+		undone := reverted[c.Hash] || strings.HasPrefix(c.Subject, undoLogSubjectPrefix)
+		for _, m := range revertPattern.FindAllStringSubmatch(c.Body, -1) {
+			reverted[m[1]] = true
+			undone = true
+		}
+		if !undone || len(c.Parents) == 0 {
+			break
+		}
+		later = append(later, c.Hash)
+		rev = c.Parents[0]
+	}
+
+	// The issues of a project are exported after its burial is committed
+	var exports []string
+	exported := ""
+	for {
+		commits, err := git.Log(g.Path, []string{"-1", rev})
+		if err != nil {
+			return nil, err
+		}
+		if len(commits) == 0 {
+			return nil, fmt.Errorf("graveyard has no commits")
+		}
+		name, ok := strings.CutPrefix(commits[0].Subject, issueExportSubjectPrefix)
+		if !ok || (exported != "" && name != exported) || len(commits[0].Parents) == 0 {
+			break
+		}
+		exports = append(exports, commits[0].Hash)
+		exported = name
+		rev = commits[0].Parents[0]
+	}
+
+	commits, err := git.Log(g.Path, []string{"-2", "--first-parent", rev})
+	if err != nil {
+		return nil, err
+	}
+	head := commits[0]
+	name, ok := strings.CutPrefix(head.Subject, burialSubjectPrefix)
+	if !ok || (exported != "" && name != exported) {
+		return nil, fmt.Errorf("the most recent commit is not a burial: %s", head.Subject)
+	}
+	if len(head.Parents) == 0 {
+		return nil, fmt.Errorf("burial of %s is the first commit and cannot be undone", name)
+	}
+
+	burial := &Burial{Project: name, Commits: append(exports, head.Hash), Parent: head.Parents[0], Later: later}
+	if len(commits) > 1 {
+		prev := commits[1]
+		if len(prev.Parents) == 2 && isSubtreeAdd(prev, name) {
+			burial.Commits = append(burial.Commits, prev.Hash)
+			burial.Merge = prev.Hash
+			burial.Parent = prev.Parents[0]
+		}
+	}
+
+	// A re-burial is preceded by the commit retiring the previous version
+	retirement, err := git.Log(g.Path, []string{"-1", burial.Parent})
+	if err != nil {
+		return nil, err
+	}
+	if len(retirement) > 0 && isRetirement(retirement[0], name) && len(retirement[0].Parents) > 0 {
+		n, err := strconv.Atoi(strings.Fields(strings.TrimPrefix(retirement[0].Subject, retiredSubjectPrefix))[0])
+		if err == nil {
+			burial.Commits = append(burial.Commits, retirement[0].Hash)
+			burial.Parent = retirement[0].Parents[0]
+			burial.Retired = n
+		}
+	}
+	return burial, nil
+}
+
+// isSubtreeAdd reports whether commit is a git subtree add of prefix.
+func isSubtreeAdd(commit git.LogEntry, prefix string) bool {
+	for _, line := range strings.Split(commit.Body, "\n") {
+		if dir, ok := strings.CutPrefix(line, "git-subtree-dir: "); ok {
+			return strings.TrimSuffix(strings.TrimSpace(dir), "/") == prefix
+		}
+	}
+	return false
+}
+
+// Undo removes a burial from the graveyard. Unpublished burials are removed by
+// resetting the branch; burials that have been pushed or that later undos
+// were made on top of, or all burials when revert is true, are removed with
+// revert commits instead.
+// It returns true if the burial was reverted rather than reset.
+func (g *Graveyard) Undo(b *Burial, revert bool) (bool, error) {
+	clean, err := git.IsClean(g.Path)
+	if err != nil {
+		return false, err
+	}
+	if !clean {
+		return false, fmt.Errorf("graveyard has uncommitted changes; commit or stash them first")
+	}
+
+	// Resetting would also drop the commits of the undos made since
+	if len(b.Later) > 0 {
+		revert = true
+	}
+	if !revert {
+		pushed, err := git.IsPushed(g.Path, b.Commits[0])
+		if err != nil {
+			return false, err
+		}
+		revert = pushed
+	}
+
+	if revert {
+		for _, commit := range b.Commits {
+			mainline := 0
+			if commit == b.Merge {
+				mainline = 1
+			}
+			if err := git.Revert(g.Path, commit, mainline, keptOnUndo...); err != nil {
+				return true, err
+			}
+		}
+	} else if err := git.ResetHard(g.Path, b.Parent); err != nil {
+		return false, err
+	}
+
+	// The version tag of a re-burial goes with it
+	for _, commit := range b.Commits {
+		tags, err := git.Tags(g.Path, versionTagPrefix+b.Project+"/v*", commit)
+		if err != nil {
+			return revert, err
+		}
+		for _, tag := range tags {
+			if err := git.DeleteTag(g.Path, tag); err != nil {
+				return revert, err
+			}
+		}
+	}
+
+	// Remove anything git left behind, such as empty directories, unless the
+	// previous version was restored
+	if b.Retired == 0 {
+		if err := os.RemoveAll(g.ProjectPath(b.Project)); err != nil {
+			return revert, fmt.Errorf("failed to remove project directory: %w", err)
+		}
+	}
+	return revert, nil
+}
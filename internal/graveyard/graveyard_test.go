@@ -1,9 +1,18 @@
 package graveyard
 
 import (
+	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/audit"
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/metadata"
 )
 
 func TestNew(t *testing.T) {
@@ -234,3 +243,575 @@ func TestGraveyard_ProjectExists(t *testing.T) {
 		})
 	}
 }
+
+func TestGraveyard_Projects(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// Buried projects have a metadata file; other directories are ignored
+	for _, dir := range []string{"zeta", "alpha", "not-buried", ".hidden"} {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	for _, dir := range []string{"zeta", "alpha", ".hidden"} {
+		if err := os.WriteFile(filepath.Join(tempDir, dir, ".bury-it.md"), []byte("# Archived Project\n"), 0644); err != nil {
+			t.Fatalf("Failed to write metadata: %v", err)
+		}
+	}
+
+	gy := &Graveyard{Path: tempDir}
+	got, err := gy.Projects()
+	if err != nil {
+		t.Fatalf("Projects() error = %v", err)
+	}
+	want := []string{"alpha", "zeta"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Projects() = %v, want %v", got, want)
+	}
+}
+
+func TestGraveyard_Grep(t *testing.T) {
+	gy := &Graveyard{Path: initGraveyard(t, map[string]string{
+		"README.md":           "needle in the graveyard root\n",
+		"first/.bury-it.md":   "# Archived Project\n",
+		"first/main.go":       "needle one\n",
+		"second/.bury-it.md":  "# Archived Project\n",
+		"second/lib/util.txt": "no match\nneedle two\n",
+	})}
+
+	tests := []struct {
+		name    string
+		project string
+		want    []Match
+		wantErr bool
+	}{
+		{
+			name: "all projects",
+			want: []Match{
+				{Project: "first", Path: "main.go", Line: 1, Text: "needle one"},
+				{Project: "second", Path: "lib/util.txt", Line: 2, Text: "needle two"},
+			},
+		},
+		{
+			name:    "single project",
+			project: "second",
+			want:    []Match{{Project: "second", Path: "lib/util.txt", Line: 2, Text: "needle two"}},
+		},
+		{
+			name:    "unknown project",
+			project: "missing",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := gy.Grep("needle", tt.project, false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Grep() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Grep() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraveyard_DiskUsage(t *testing.T) {
+	gy := &Graveyard{Path: initGraveyard(t, map[string]string{
+		"snapshot/.bury-it.md": "# Archived Project\n",
+		"snapshot/data.txt":    "0123456789",
+	})}
+	buryWithHistory(t, gy, "historic", map[string]string{"main.go": "package main\n"})
+
+	tests := []struct {
+		name         string
+		project      string
+		wantWorkTree int64
+		wantErr      bool
+	}{
+		{name: "snapshot burial", project: "snapshot", wantWorkTree: int64(len("# Archived Project\n") + 10)},
+		{name: "history burial", project: "historic", wantWorkTree: int64(len("package main\n") + len("# Archived Project\n"))},
+		{name: "unknown project", project: "missing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage, err := gy.DiskUsage(tt.project)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DiskUsage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if usage.WorkTree != tt.wantWorkTree {
+				t.Errorf("DiskUsage().WorkTree = %d, want %d", usage.WorkTree, tt.wantWorkTree)
+			}
+			if usage.Packed <= 0 {
+				t.Errorf("DiskUsage().Packed = %d, want > 0", usage.Packed)
+			}
+		})
+	}
+
+	// The original history of a subtree burial belongs to the project
+	objects, err := gy.ProjectObjects("historic")
+	if err != nil {
+		t.Fatalf("ProjectObjects() error = %v", err)
+	}
+	paths := make(map[string]bool)
+	for _, path := range objects {
+		paths[path] = true
+	}
+	for _, want := range []string{"main.go", ".bury-it.md"} {
+		if !paths[want] {
+			t.Errorf("ProjectObjects() missing %s: %v", want, objects)
+		}
+	}
+}
+
+func TestGraveyard_LargestBlobs(t *testing.T) {
+	gy := &Graveyard{Path: initGraveyard(t, map[string]string{
+		"small/.bury-it.md": "# Archived Project\n",
+		"small/tiny.txt":    "x",
+	})}
+	buryWithHistory(t, gy, "big", map[string]string{"huge.txt": string(make([]byte, 4096))})
+
+	tests := []struct {
+		name      string
+		project   string
+		limit     int
+		wantFirst Blob
+		wantLen   int
+		wantErr   bool
+	}{
+		{
+			name:      "all projects limited",
+			limit:     1,
+			wantFirst: Blob{Project: "big", Path: "huge.txt", Size: 4096},
+			wantLen:   1,
+		},
+		{
+			name:      "single project",
+			project:   "small",
+			wantFirst: Blob{Project: "small", Path: ".bury-it.md", Size: int64(len("# Archived Project\n"))},
+			wantLen:   2,
+		},
+		{
+			name:    "unknown project",
+			project: "missing",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blobs, err := gy.LargestBlobs(tt.project, tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LargestBlobs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(blobs) != tt.wantLen {
+				t.Fatalf("LargestBlobs() returned %d blobs, want %d: %+v", len(blobs), tt.wantLen, blobs)
+			}
+			got := blobs[0]
+			got.Hash = ""
+			if got != tt.wantFirst {
+				t.Errorf("LargestBlobs()[0] = %+v, want %+v", got, tt.wantFirst)
+			}
+		})
+	}
+}
+
+func TestGraveyard_LastBurialAndUndo(t *testing.T) {
+	tests := []struct {
+		name        string
+		history     bool
+		exports     int
+		revert      bool
+		wantCommits int
+	}{
+		{name: "snapshot burial reset", wantCommits: 1},
+		{name: "burial with issue export reset", exports: 2, wantCommits: 3},
+		{name: "history burial with issue export reverted", history: true, exports: 1, revert: true, wantCommits: 3},
+		{name: "history burial reset", history: true, wantCommits: 2},
+		{name: "history burial reverted", history: true, revert: true, wantCommits: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gy := &Graveyard{Path: initGraveyard(t, map[string]string{"README.md": "graveyard"})}
+			before, err := git.Head(gy.Path)
+			if err != nil {
+				t.Fatalf("Head() error = %v", err)
+			}
+
+			if tt.history {
+				buryWithHistory(t, gy, "project", map[string]string{"main.go": "package main"})
+			} else {
+				if err := os.MkdirAll(gy.ProjectPath("project"), 0755); err != nil {
+					t.Fatalf("Failed to create project: %v", err)
+				}
+				if err := os.WriteFile(filepath.Join(gy.ProjectPath("project"), ".bury-it.md"), []byte("meta"), 0644); err != nil {
+					t.Fatalf("Failed to write metadata: %v", err)
+				}
+				runGit(t, gy.Path, "add", "-A")
+				runGit(t, gy.Path, "commit", "-m", BurialMessage("project"))
+			}
+			for i := range tt.exports {
+				export := filepath.Join(gy.ProjectPath("project"), ".bury-it-issues.jsonl")
+				if err := os.WriteFile(export, []byte(strings.Repeat("{}\n", i+1)), 0644); err != nil {
+					t.Fatalf("Failed to write issue export: %v", err)
+				}
+				runGit(t, gy.Path, "add", "-A")
+				runGit(t, gy.Path, "commit", "-m", IssueExportMessage("project"))
+			}
+
+			burial, err := gy.LastBurial()
+			if err != nil {
+				t.Fatalf("LastBurial() error = %v", err)
+			}
+			if burial.Project != "project" || len(burial.Commits) != tt.wantCommits || burial.Parent != before {
+				t.Fatalf("LastBurial() = %+v, want project with %d commits after %s", burial, tt.wantCommits, before)
+			}
+
+			reverted, err := gy.Undo(burial, tt.revert)
+			if err != nil {
+				t.Fatalf("Undo() error = %v", err)
+			}
+			if reverted != tt.revert {
+				t.Errorf("Undo() reverted = %v, want %v", reverted, tt.revert)
+			}
+			if gy.ProjectExists("project") {
+				t.Errorf("project directory still exists after Undo()")
+			}
+
+			head, err := git.Head(gy.Path)
+			if err != nil {
+				t.Fatalf("Head() error = %v", err)
+			}
+			if !tt.revert && head != before {
+				t.Errorf("HEAD = %s after reset, want %s", head, before)
+			}
+			if tt.revert && head == before {
+				t.Errorf("HEAD = %s after revert, want new revert commits", head)
+			}
+		})
+	}
+}
+
+func TestGraveyard_UndoTwice(t *testing.T) {
+	for _, revert := range []bool{false, true} {
+		t.Run(fmt.Sprintf("first undo reverted %v", revert), func(t *testing.T) {
+			gy := &Graveyard{Path: initGraveyard(t, map[string]string{"README.md": "graveyard"})}
+			// Each burial and undo appends to the audit log, which the first
+			// burial creates
+			log := audit.Path(gy.Path)
+			logged := func(line string) {
+				t.Helper()
+				if err := os.MkdirAll(filepath.Dir(log), 0755); err != nil {
+					t.Fatal(err)
+				}
+				f, err := os.OpenFile(log, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, _ = f.WriteString(line + "\n")
+				_ = f.Close()
+				runGit(t, gy.Path, "add", "-A")
+			}
+			buryWithHistory(t, gy, "alpha", map[string]string{"main.go": "package alpha"})
+			logged("bury alpha")
+			runGit(t, gy.Path, "commit", "-q", "--amend", "--no-edit")
+			buryWithHistory(t, gy, "beta", map[string]string{"main.go": "package beta"})
+			logged("bury beta")
+			runGit(t, gy.Path, "commit", "-q", "--amend", "--no-edit")
+
+			burial, err := gy.LastBurial()
+			if err != nil || burial.Project != "beta" {
+				t.Fatalf("LastBurial() = %+v, %v, want beta", burial, err)
+			}
+			if _, err := gy.Undo(burial, revert); err != nil {
+				t.Fatalf("Undo() error = %v", err)
+			}
+			logged("undo beta")
+			runGit(t, gy.Path, "commit", "-q", "-m", UndoLogMessage("beta"))
+			undoLog, err := git.Head(gy.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before, err := os.ReadFile(log)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The undo's own commits do not hide the burial before it
+			burial, err = gy.LastBurial()
+			if err != nil || burial.Project != "alpha" {
+				t.Fatalf("LastBurial() after an undo = %+v, %v, want alpha", burial, err)
+			}
+			reverted, err := gy.Undo(burial, false)
+			if err != nil {
+				t.Fatalf("Undo() error = %v", err)
+			}
+			if !reverted {
+				t.Errorf("Undo() reset away the log of the earlier undo")
+			}
+			if gy.ProjectExists("alpha") || gy.ProjectExists("beta") {
+				t.Errorf("projects still exist after undoing both burials")
+			}
+			runGit(t, gy.Path, "merge-base", "--is-ancestor", undoLog, "HEAD")
+			if after, err := os.ReadFile(log); err != nil || string(after) != string(before) {
+				t.Errorf("audit log after undoing = %q, %v, want %q kept", after, err, before)
+			}
+		})
+	}
+}
+
+func TestGraveyard_LastBurial_NotABurial(t *testing.T) {
+	gy := &Graveyard{Path: initGraveyard(t, map[string]string{"README.md": "graveyard"})}
+	if _, err := gy.LastBurial(); err == nil {
+		t.Errorf("LastBurial() expected error when HEAD is not a burial, got nil")
+	}
+
+	// An issue export does not make the commit before it a burial
+	runGit(t, gy.Path, "commit", "-q", "--allow-empty", "-m", IssueExportMessage("project"))
+	if _, err := gy.LastBurial(); err == nil {
+		t.Errorf("LastBurial() expected error when HEAD is an issue export after no burial, got nil")
+	}
+}
+
+func TestGraveyard_Locate(t *testing.T) {
+	gy := &Graveyard{Path: initGraveyard(t, map[string]string{
+		"README.md":           "graveyard",
+		"project/.bury-it.md": "# Archived Project\n",
+		"project/src/main.go": "package main\n",
+	})}
+
+	tests := []struct {
+		name        string
+		path        string
+		wantProject string
+		wantRel     string
+		wantErr     bool
+	}{
+		{name: "relative to graveyard", path: "project/src/main.go", wantProject: "project", wantRel: "src/main.go"},
+		{name: "absolute", path: filepath.Join(gy.Path, "project", "src"), wantProject: "project", wantRel: "src"},
+		{name: "project directory", path: filepath.Join(gy.Path, "project"), wantProject: "project"},
+		{name: "graveyard root", path: gy.Path, wantErr: true},
+		{name: "outside graveyard", path: t.TempDir(), wantErr: true},
+		{name: "not a project", path: "README.md", wantErr: true},
+		{name: "escapes graveyard", path: "../project", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project, rel, err := gy.Locate(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Locate(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if project != tt.wantProject || rel != tt.wantRel {
+				t.Errorf("Locate(%q) = %q, %q, want %q, %q", tt.path, project, rel, tt.wantProject, tt.wantRel)
+			}
+		})
+	}
+}
+
+func TestGraveyard_ProjectFile(t *testing.T) {
+	gy := &Graveyard{Path: initGraveyard(t, map[string]string{"project/.bury-it.md": "# Archived Project\n"})}
+
+	tests := []struct {
+		name    string
+		project string
+		rel     string
+		want    string
+		wantErr bool
+	}{
+		{name: "file", project: "project", rel: "src/main.go", want: "project/src/main.go"},
+		{name: "cleaned", project: "project", rel: "./src/../main.go", want: "project/main.go"},
+		{name: "escapes project", project: "project", rel: "../other/main.go", wantErr: true},
+		{name: "absolute", project: "project", rel: "/etc/passwd", wantErr: true},
+		{name: "project itself", project: "project", rel: ".", wantErr: true},
+		{name: "unknown project", project: "missing", rel: "main.go", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := gy.ProjectFile(tt.project, tt.rel)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ProjectFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ProjectFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraveyard_FileHistory(t *testing.T) {
+	gy := &Graveyard{Path: initGraveyard(t, map[string]string{"README.md": "graveyard"})}
+	source := initGraveyard(t, map[string]string{"old.go": "package main\n", "other.go": "package other\n"})
+	runGit(t, source, "mv", "old.go", "new.go")
+	runGit(t, source, "commit", "-m", "rename old.go")
+	if err := os.WriteFile(filepath.Join(source, "other.go"), []byte("package changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, source, "commit", "-am", "change other.go")
+	if err := git.SubtreeAdd(gy.Path, source, "project"); err != nil {
+		t.Fatalf("SubtreeAdd() error = %v", err)
+	}
+
+	commits, err := gy.FileHistory("project", "new.go")
+	if err != nil {
+		t.Fatalf("FileHistory() error = %v", err)
+	}
+	var subjects []string
+	for _, c := range commits {
+		subjects = append(subjects, c.Subject)
+	}
+	got := strings.Join(subjects, "\n")
+	// The file's history is followed back across the rename
+	for _, want := range []string{"rename old.go", "initial commit"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FileHistory() = %q, want %q", subjects, want)
+		}
+	}
+	if strings.Contains(got, "change other.go") {
+		t.Errorf("FileHistory() = %q, want only commits that changed new.go", subjects)
+	}
+
+	if _, err := gy.FileHistory("missing", "new.go"); err == nil {
+		t.Errorf("FileHistory() expected error for an unknown project")
+	}
+}
+
+func TestGraveyard_HistoryScopes(t *testing.T) {
+	gy := &Graveyard{Path: initGraveyard(t, map[string]string{"snapshot/.bury-it.md": "# Archived Project\n"})}
+	buryWithHistory(t, gy, "historic", map[string]string{"main.go": "package main\n"})
+	runGit(t, gy.Path, "tag", VersionTag("historic", 1))
+	split, err := git.SubtreeSplit(gy.Path, "historic")
+	if err != nil {
+		t.Fatalf("SubtreeSplit() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		project string
+		paths   []string
+		want    []LogScope
+		wantErr bool
+	}{
+		{
+			name:    "snapshot burial",
+			project: "snapshot",
+			want:    []LogScope{{Revs: []string{"HEAD"}, Paths: []string{"snapshot/"}}},
+		},
+		{
+			name:    "history burial with a version",
+			project: "historic",
+			want: []LogScope{
+				{Revs: []string{"HEAD", "buried/historic/v1"}, Paths: []string{"historic/"}},
+				{Revs: []string{split}},
+			},
+		},
+		{
+			name:    "narrowed to paths",
+			project: "historic",
+			paths:   []string{"main.go", "docs"},
+			want: []LogScope{
+				{Revs: []string{"HEAD", "buried/historic/v1"}, Paths: []string{"historic/main.go", "historic/docs"}},
+				{Revs: []string{split}, Paths: []string{"main.go", "docs"}},
+			},
+		},
+		{name: "unknown project", project: "missing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := gy.HistoryScopes(tt.project, tt.paths)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HistoryScopes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("HistoryScopes() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// buryWithHistory creates a source repository with files and buries it in gy
+// using git subtree, as the archive package does.
+func buryWithHistory(t *testing.T, gy *Graveyard, name string, files map[string]string) {
+	t.Helper()
+	source := initGraveyard(t, files)
+	if err := git.SubtreeAdd(gy.Path, source, name); err != nil {
+		t.Fatalf("SubtreeAdd() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(gy.Path, name, ".bury-it.md"), []byte("# Archived Project\n"), 0644); err != nil {
+		t.Fatalf("Failed to write metadata: %v", err)
+	}
+	runGit(t, gy.Path, "add", "-A")
+	runGit(t, gy.Path, "commit", "-m", "docs: bury-it - archived "+name)
+}
+
+// initGraveyard creates a git repository containing files and commits them.
+func initGraveyard(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	runGit(t, dir, "init")
+	runGit(t, dir, "config", "user.email", "test@test.com")
+	runGit(t, dir, "config", "user.name", "Test")
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "-m", "initial commit")
+	return dir
+}
+
+// runGit runs a git command in dir and fails the test on error.
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
+
+func TestGraveyard_SetTagsAndMetadata(t *testing.T) {
+	meta := &metadata.Metadata{OriginalSource: "/src/project", BuriedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)}
+	gy := &Graveyard{Path: initGraveyard(t, map[string]string{
+		"project/" + metadata.FileName: meta.Generate(),
+	})}
+
+	if err := gy.SetTags("project", []string{"ml", "2023"}); err != nil {
+		t.Fatalf("SetTags() error = %v", err)
+	}
+	got, err := gy.Metadata("project")
+	if err != nil {
+		t.Fatalf("Metadata() error = %v", err)
+	}
+	if got.OriginalSource != "/src/project" || strings.Join(got.Tags, ",") != "ml,2023" {
+		t.Errorf("Metadata() = %+v, want tags ml,2023", got)
+	}
+
+	staged, err := git.HasStagedChanges(gy.Path)
+	if err != nil {
+		t.Fatalf("HasStagedChanges() error = %v", err)
+	}
+	if !staged {
+		t.Errorf("SetTags() did not stage the metadata file")
+	}
+
+	if err := gy.SetTags("missing", []string{"ml"}); err == nil {
+		t.Errorf("SetTags() expected error for a missing project")
+	}
+}
@@ -0,0 +1,147 @@
+package graveyard
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/state"
+)
+
+// knownFile is the state file listing the graveyards bury-it has used.
+const knownFile = "graveyards.json"
+
+// knownProjectsFile is the state file mapping the projects buried in the
+// graveyards bury-it has used to their graveyards.
+const knownProjectsFile = "projects.json"
+
+// KnownProject is a project buried in a graveyard used on this machine.
+type KnownProject struct {
+	// Name is the project's name in its graveyard.
+	Name string `json:"name"`
+	// Source is where the project was buried from.
+	Source string `json:"source"`
+	// Graveyard is the path of the graveyard holding the project.
+	Graveyard string `json:"graveyard"`
+	// BuriedAt is when the project was buried.
+	BuriedAt time.Time `json:"buried_at"`
+}
+
+// maxKnown is the number of graveyards remembered.
+const maxKnown = 20
+
+// Known returns the paths of the graveyards used on this machine, most
+// recently used first.
+func Known() ([]string, error) {
+	var paths []string
+	if err := state.Load(knownFile, &paths); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// Remember records the graveyard as the most recently used one.
+func (g *Graveyard) Remember() error {
+	known, err := Known()
+	if err != nil {
+		return err
+	}
+	if len(known) > 0 && known[0] == g.Path {
+		// Avoid rewriting the file on every command
+		return nil
+	}
+	paths := []string{g.Path}
+	for _, path := range known {
+		if path != g.Path && len(paths) < maxKnown {
+			paths = append(paths, path)
+		}
+	}
+	return state.Save(knownFile, paths)
+}
+
+// KnownProjects returns the projects of the graveyards used on this machine,
+// as last seen, sorted by name and graveyard.
+func KnownProjects() ([]KnownProject, error) {
+	var projects []KnownProject
+	if err := state.Load(knownProjectsFile, &projects); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// RememberProjects records the graveyard's current projects in the list of
+// known projects, replacing what was recorded for it before. Projects whose
+// metadata cannot be read are left out.
+func (g *Graveyard) RememberProjects() error {
+	known, err := KnownProjects()
+	if err != nil {
+		return err
+	}
+	names, err := g.Projects()
+	if err != nil {
+		return err
+	}
+
+	var projects []KnownProject
+	for _, p := range known {
+		if p.Graveyard != g.Path {
+			projects = append(projects, p)
+		}
+	}
+	for _, name := range names {
+		meta, err := g.Metadata(name)
+		if err != nil {
+			continue
+		}
+		projects = append(projects, KnownProject{Name: name, Source: meta.OriginalSource, Graveyard: g.Path, BuriedAt: meta.BuriedAt})
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].Name != projects[j].Name {
+			return projects[i].Name < projects[j].Name
+		}
+		return projects[i].Graveyard < projects[j].Graveyard
+	})
+	if sameKnownProjects(projects, known) {
+		// Avoid rewriting the file on every command
+		return nil
+	}
+	return state.Save(knownProjectsFile, projects)
+}
+
+func sameKnownProjects(a, b []KnownProject) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Source != b[i].Source ||
+			a[i].Graveyard != b[i].Graveyard || !a[i].BuriedAt.Equal(b[i].BuriedAt) {
+			return false
+		}
+	}
+	return true
+}
+
+// FindKnownProjects returns the known projects named name, or buried from
+// source if it is not empty.
+func FindKnownProjects(name, source string) ([]KnownProject, error) {
+	known, err := KnownProjects()
+	if err != nil {
+		return nil, err
+	}
+	var found []KnownProject
+	for _, p := range known {
+		if p.Name == name || (source != "" && sameSource(p.Source, source)) {
+			found = append(found, p)
+		}
+	}
+	return found, nil
+}
+
+// sameSource reports whether two sources name the same repository, ignoring
+// case, a trailing slash, and a .git suffix.
+func sameSource(a, b string) bool {
+	normalize := func(s string) string {
+		return strings.TrimSuffix(strings.TrimSuffix(s, "/"), ".git")
+	}
+	return strings.EqualFold(normalize(a), normalize(b))
+}
@@ -0,0 +1,139 @@
+package graveyard
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/metadata"
+	"github.com/deanhigh/bury-it/internal/state"
+)
+
+func TestRememberAndKnown(t *testing.T) {
+	t.Setenv(state.HomeEnv, t.TempDir())
+
+	if known, err := Known(); err != nil || len(known) != 0 {
+		t.Fatalf("Known() = %v, %v, want none", known, err)
+	}
+	for _, path := range []string{"/a", "/b", "/a", "/c", "/c"} {
+		if err := (&Graveyard{Path: path}).Remember(); err != nil {
+			t.Fatalf("Remember(%s) error = %v", path, err)
+		}
+	}
+	known, err := Known()
+	if err != nil {
+		t.Fatalf("Known() error = %v", err)
+	}
+	if want := []string{"/c", "/a", "/b"}; !reflect.DeepEqual(known, want) {
+		t.Errorf("Known() = %v, want %v", known, want)
+	}
+}
+
+func TestRememberProjectsAndFind(t *testing.T) {
+	t.Setenv(state.HomeEnv, t.TempDir())
+
+	buried := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	newGraveyard := func(projects map[string]string) *Graveyard {
+		files := map[string]string{}
+		for name, source := range projects {
+			meta := &metadata.Metadata{OriginalSource: source, BuriedAt: buried}
+			files[name+"/"+metadata.FileName] = meta.Generate()
+		}
+		return &Graveyard{Path: initGraveyard(t, files)}
+	}
+	first := newGraveyard(map[string]string{
+		"api":        "https://github.com/org/api",
+		"experiment": "https://github.com/org/experiment",
+	})
+	second := newGraveyard(map[string]string{
+		"api-v1": "https://github.com/org/api.git",
+	})
+	for _, gy := range []*Graveyard{first, second, first} {
+		if err := gy.RememberProjects(); err != nil {
+			t.Fatalf("RememberProjects() error = %v", err)
+		}
+	}
+	known, err := KnownProjects()
+	if err != nil || len(known) != 3 {
+		t.Fatalf("KnownProjects() = %v, %v, want 3 projects", known, err)
+	}
+
+	tests := []struct {
+		name      string
+		project   string
+		source    string
+		wantPaths []string
+	}{
+		{name: "by name", project: "experiment", wantPaths: []string{first.Path}},
+		{name: "by source ignoring .git", project: "new-api", source: "https://github.com/ORG/api", wantPaths: []string{first.Path, second.Path}},
+		{name: "unknown", project: "missing", source: "https://github.com/org/missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := FindKnownProjects(tt.project, tt.source)
+			if err != nil {
+				t.Fatalf("FindKnownProjects() error = %v", err)
+			}
+			var paths []string
+			for _, p := range found {
+				paths = append(paths, p.Graveyard)
+			}
+			sort.Strings(paths)
+			want := append([]string(nil), tt.wantPaths...)
+			sort.Strings(want)
+			if !reflect.DeepEqual(paths, want) {
+				t.Errorf("FindKnownProjects() graveyards = %v, want %v", paths, want)
+			}
+		})
+	}
+
+	// Projects removed from a graveyard are forgotten
+	if err := os.RemoveAll(first.ProjectPath("experiment")); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.RememberProjects(); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := FindKnownProjects("experiment", ""); len(found) != 0 {
+		t.Errorf("FindKnownProjects() after removal = %v, want none", found)
+	}
+}
+
+func TestRecordAccessAndAccesses(t *testing.T) {
+	t.Setenv(state.HomeEnv, t.TempDir())
+
+	first, second := &Graveyard{Path: "/a"}, &Graveyard{Path: "/b"}
+	if accesses, err := first.Accesses(); err != nil || len(accesses) != 0 {
+		t.Fatalf("Accesses() = %v, %v, want none", accesses, err)
+	}
+	early := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	late := early.AddDate(0, 1, 0)
+	for _, record := range []struct {
+		gy      *Graveyard
+		project string
+		at      time.Time
+	}{
+		{first, "api", early},
+		{first, "api", late},
+		{second, "api", early},
+		{first, "experiment", early},
+	} {
+		if err := record.gy.RecordAccess(record.project, record.at); err != nil {
+			t.Fatalf("RecordAccess(%s) error = %v", record.project, err)
+		}
+	}
+
+	accesses, err := first.Accesses()
+	if err != nil {
+		t.Fatalf("Accesses() error = %v", err)
+	}
+	want := map[string]Access{
+		"api":        {Last: late, Count: 2},
+		"experiment": {Last: early, Count: 1},
+	}
+	if !reflect.DeepEqual(accesses, want) {
+		t.Errorf("Accesses() = %v, want %v", accesses, want)
+	}
+}
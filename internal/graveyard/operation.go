@@ -0,0 +1,283 @@
+package graveyard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/atomicfile"
+	"github.com/deanhigh/bury-it/internal/git"
+)
+
+// operationFile is the name of the file, in the graveyard's git directory,
+// that records a burial while it changes the graveyard.
+const operationFile = "bury-it-operation.json"
+
+// Operation is a burial in progress, recorded in the graveyard's git
+// directory from its first change to the graveyard until it is committed, so
+// that one interrupted midway can be recovered.
+type Operation struct {
+	// Project is the name of the project being buried.
+	Project string `json:"project"`
+	// Head is the commit the graveyard was at before the burial changed it.
+	Head string `json:"head"`
+	// StartedAt is when the burial started changing the graveyard.
+	StartedAt time.Time `json:"started_at"`
+	// PID is the process ID of the bury-it process making the burial.
+	PID int `json:"pid"`
+	// Host is the name of the machine the process runs on.
+	Host string `json:"host,omitempty"`
+	// ProcessStart identifies when the process started, so that another
+	// process given the same ID later is not taken for it. It is empty
+	// where the start of a process cannot be looked up.
+	ProcessStart string `json:"process_start,omitempty"`
+	// TempDirs are the temporary directories the burial works in, which an
+	// interrupted burial leaves behind.
+	TempDirs []string `json:"temp_dirs,omitempty"`
+
+	path string
+}
+
+// Recovery describes how Recover dealt with an interrupted burial.
+type Recovery struct {
+	// Project is the name of the project whose burial was interrupted.
+	Project string
+	// Completed is true if the burial had been committed and was completed,
+	// and false if it was rolled back.
+	Completed bool
+	// Head is the commit the graveyard was reset to when rolled back.
+	Head string
+	// RemovedLock is true if git's index lock, left by a git process that
+	// was killed, was removed.
+	RemovedLock bool
+}
+
+// BeginBurial records that the burial of project is about to change the
+// graveyard, working in tempDirs. It fails if another burial is recorded,
+// whether still running or interrupted.
+func (g *Graveyard) BeginBurial(project string, tempDirs []string) (*Operation, error) {
+	if err := g.CheckNoOperation(); err != nil {
+		return nil, err
+	}
+	path, err := git.GitPath(g.Path, operationFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// A graveyard without commits has no state to roll back to
+	head, _ := git.Head(g.Path)
+	host, _ := os.Hostname()
+	op := &Operation{
+		Project:      project,
+		Head:         head,
+		StartedAt:    time.Now().UTC(),
+		PID:          os.Getpid(),
+		Host:         host,
+		ProcessStart: processStart(os.Getpid()),
+		TempDirs:     tempDirs,
+		path:         path,
+	}
+	data, err := json.MarshalIndent(op, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode operation: %w", err)
+	}
+	if err := atomicfile.Write(path, append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to record operation: %w", err)
+	}
+	return op, nil
+}
+
+// End records that the burial is complete.
+func (op *Operation) End() error {
+	if err := os.Remove(op.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove operation record: %w", err)
+	}
+	return nil
+}
+
+// Running reports whether the process making the burial is still running.
+// A process on another machine cannot be looked up, so it is taken to be
+// running.
+func (op *Operation) Running() bool {
+	if host, err := os.Hostname(); err == nil && op.Host != "" && op.Host != host {
+		return true
+	}
+	// The process ID may since have been given to another process, even
+	// this one
+	if op.ProcessStart != "" {
+		if start := processStart(op.PID); start != "" {
+			return start == op.ProcessStart
+		}
+	}
+	if op.PID == os.Getpid() {
+		return op.ProcessStart == ""
+	}
+	p, err := os.FindProcess(op.PID)
+	if err != nil {
+		return false
+	}
+	// FindProcess only fails for missing processes on Windows, which has no
+	// signal 0 to probe with
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	return p.Signal(syscall.Signal(0)) == nil
+}
+
+// processStart returns when the process with the given ID started, as the
+// boot it started in and its start time in clock ticks since then, or "" if
+// it is not running or this cannot be looked up, as outside Linux.
+func processStart(pid int) string {
+	if runtime.GOOS != "linux" {
+		return ""
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return ""
+	}
+	// The fields after the command, which may itself contain spaces, start
+	// at the third; the start time is the twenty-second
+	i := bytes.LastIndexByte(stat, ')')
+	if i < 0 {
+		return ""
+	}
+	fields := strings.Fields(string(stat[i+1:]))
+	if len(fields) < 20 {
+		return ""
+	}
+	bootID, err := os.ReadFile("/proc/sys/kernel/random/boot_id")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(bootID)) + "/" + fields[19]
+}
+
+// busyError returns the error reported when a burial is attempted while op
+// is recorded.
+func (op *Operation) busyError() error {
+	if op.Running() {
+		return fmt.Errorf("a burial of %s into this graveyard is in progress (process %d on %s); if it is not, run bury-it recover --force",
+			op.Project, op.PID, op.host())
+	}
+	return fmt.Errorf("a burial of %s into this graveyard was interrupted on %s; run bury-it recover first",
+		op.Project, op.StartedAt.Local().Format("2006-01-02 15:04"))
+}
+
+// host returns the name of the machine the burial runs on, for messages.
+func (op *Operation) host() string {
+	if op.Host == "" {
+		return "this machine"
+	}
+	return op.Host
+}
+
+// CheckNoOperation returns an error if a burial is recorded in the
+// graveyard, whether still running or interrupted.
+func (g *Graveyard) CheckNoOperation() error {
+	pending, err := g.PendingOperation()
+	if err != nil {
+		return err
+	}
+	if pending != nil {
+		return pending.busyError()
+	}
+	return nil
+}
+
+// PendingOperation returns the burial recorded in the graveyard, running or
+// interrupted, or nil if there is none.
+func (g *Graveyard) PendingOperation() (*Operation, error) {
+	path, err := git.GitPath(g.Path, operationFile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read operation record: %w", err)
+	}
+	op := &Operation{path: path}
+	if err := json.Unmarshal(data, op); err != nil {
+		return nil, fmt.Errorf("failed to parse operation record %s: %w", path, err)
+	}
+	return op, nil
+}
+
+// Recover finishes an interrupted burial. A burial that was committed is
+// completed: only its version tag, if any, can be missing. Any other is
+// rolled back by resetting the graveyard to the commit it started from,
+// discarding uncommitted changes, and removing what it left in the project
+// directory. Either way, its temporary directories and record are removed.
+// A burial that still seems to be running is only recovered with force, for
+// when it cannot be told apart from one that stopped, as on another machine.
+// With dryRun set, nothing is changed.
+func (g *Graveyard) Recover(op *Operation, force, dryRun bool) (*Recovery, error) {
+	if !force && op.Running() {
+		return nil, op.busyError()
+	}
+	recovery := &Recovery{Project: op.Project}
+
+	// The git process that was killed along with bury-it may have left its
+	// lock behind
+	lock, err := git.GitPath(g.Path, "index.lock")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(lock); err == nil {
+		recovery.RemovedLock = true
+		if !dryRun {
+			if err := os.Remove(lock); err != nil {
+				return nil, fmt.Errorf("failed to remove %s: %w", lock, err)
+			}
+		}
+	}
+
+	if b, err := g.LastBurial(); err == nil && b.Project == op.Project && b.Parent == op.Head {
+		recovery.Completed = true
+		if !dryRun && b.Retired > 0 {
+			tag := VersionTag(op.Project, b.Retired+1)
+			existing, err := git.Tags(g.Path, tag, "")
+			if err != nil {
+				return nil, err
+			}
+			if len(existing) == 0 {
+				if err := git.CreateTag(g.Path, tag, b.Commits[0]); err != nil {
+					return nil, err
+				}
+			}
+		}
+	} else {
+		if op.Head == "" {
+			return nil, fmt.Errorf("the graveyard had no commits when the burial of %s started, so it cannot be rolled back; remove %s from it by hand", op.Project, op.Project)
+		}
+		recovery.Head = op.Head
+		if !dryRun {
+			if err := git.ResetHard(g.Path, op.Head); err != nil {
+				return nil, err
+			}
+			if err := git.Clean(g.Path, op.Project); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if dryRun {
+		return recovery, nil
+	}
+	for _, dir := range op.TempDirs {
+		if err := os.RemoveAll(dir); err != nil {
+			return nil, fmt.Errorf("failed to remove %s: %w", dir, err)
+		}
+	}
+	if err := op.End(); err != nil {
+		return nil, err
+	}
+	return recovery, nil
+}
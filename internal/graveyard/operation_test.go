@@ -0,0 +1,202 @@
+package graveyard
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deanhigh/bury-it/internal/git"
+)
+
+// interrupt makes the recorded operation look like it was left by a process
+// that has since exited.
+func interrupt(t *testing.T, gy *Graveyard) *Operation {
+	t.Helper()
+	cmd := exec.Command("git", "--version")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	op, err := gy.PendingOperation()
+	if err != nil || op == nil {
+		t.Fatalf("PendingOperation() = %v, %v; want the burial", op, err)
+	}
+	op.PID = cmd.Process.Pid
+	data, err := json.Marshal(op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(op.path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return op
+}
+
+func TestGraveyard_BeginBurial(t *testing.T) {
+	gy := &Graveyard{Path: initGraveyard(t, map[string]string{"README.md": "graveyard"})}
+
+	op, err := gy.BeginBurial("project", []string{"/tmp/bury-it-1"})
+	if err != nil {
+		t.Fatalf("BeginBurial() error = %v", err)
+	}
+	if _, err := gy.BeginBurial("other", nil); err == nil {
+		t.Error("BeginBurial() while a burial is in progress error = nil, want error")
+	}
+	if _, err := gy.Recover(op, false, false); err == nil {
+		t.Error("Recover() of a running burial error = nil, want error")
+	}
+
+	if err := op.End(); err != nil {
+		t.Fatalf("End() error = %v", err)
+	}
+	pending, err := gy.PendingOperation()
+	if err != nil || pending != nil {
+		t.Errorf("PendingOperation() after End() = %+v, %v; want none", pending, err)
+	}
+}
+
+func TestOperation_Running(t *testing.T) {
+	host, err := os.Hostname()
+	if err != nil {
+		t.Skip(err)
+	}
+	start := processStart(os.Getpid())
+	tests := []struct {
+		name string
+		op   Operation
+		want bool
+		// linux is set for cases that need process start times
+		linux bool
+	}{
+		{name: "this process", op: Operation{PID: os.Getpid(), Host: host, ProcessStart: start}, want: true},
+		{name: "record without host or start", op: Operation{PID: os.Getpid()}, want: true},
+		{name: "process on another machine", op: Operation{PID: 1 << 30, Host: host + "-other"}, want: true},
+		{name: "exited process", op: Operation{PID: 1 << 30, Host: host, ProcessStart: start}, want: false},
+		{name: "process ID given to another process", op: Operation{PID: os.Getpid(), Host: host, ProcessStart: "other-boot/1"}, want: false, linux: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.linux && start == "" {
+				t.Skip("process start times cannot be looked up here")
+			}
+			if got := tt.op.Running(); got != tt.want {
+				t.Errorf("Running() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraveyard_Recover_Force(t *testing.T) {
+	gy := &Graveyard{Path: initGraveyard(t, map[string]string{"README.md": "graveyard"})}
+	op, err := gy.BeginBurial("project", nil)
+	if err != nil {
+		t.Fatalf("BeginBurial() error = %v", err)
+	}
+	// A burial recorded on another machine cannot be told apart from a
+	// running one
+	op.Host += "-other"
+	if _, err := gy.Recover(op, false, false); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("Recover() error = %v, want a burial in progress", err)
+	}
+	if _, err := gy.Recover(op, true, false); err != nil {
+		t.Fatalf("Recover(force) error = %v", err)
+	}
+	if pending, err := gy.PendingOperation(); err != nil || pending != nil {
+		t.Errorf("PendingOperation() after Recover(force) = %+v, %v; want none", pending, err)
+	}
+}
+
+func TestGraveyard_Recover(t *testing.T) {
+	tests := []struct {
+		name          string
+		commit        bool
+		wantCompleted bool
+	}{
+		{name: "rolled back", commit: false},
+		{name: "completed", commit: true, wantCompleted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gy := &Graveyard{Path: initGraveyard(t, map[string]string{"README.md": "graveyard"})}
+			before, err := git.Head(gy.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tempDir := t.TempDir()
+			if _, err := gy.BeginBurial("project", []string{tempDir}); err != nil {
+				t.Fatalf("BeginBurial() error = %v", err)
+			}
+
+			// The burial got as far as adding its files, or committing them
+			if err := os.MkdirAll(gy.ProjectPath("project"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range []string{".bury-it.md", "main.go"} {
+				if err := os.WriteFile(filepath.Join(gy.ProjectPath("project"), name), []byte(name), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			runGit(t, gy.Path, "add", "project/.bury-it.md")
+			if tt.commit {
+				runGit(t, gy.Path, "add", "-A")
+				runGit(t, gy.Path, "commit", "-m", BurialMessage("project"))
+			}
+			lock := filepath.Join(gy.Path, ".git", "index.lock")
+			if err := os.WriteFile(lock, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			op := interrupt(t, gy)
+
+			dry, err := gy.Recover(op, false, true)
+			if err != nil {
+				t.Fatalf("Recover(dry run) error = %v", err)
+			}
+			if dry.Completed != tt.wantCompleted || !dry.RemovedLock {
+				t.Errorf("Recover(dry run) = %+v, want completed %v with the lock removed", dry, tt.wantCompleted)
+			}
+			if _, err := os.Stat(lock); err != nil {
+				t.Errorf("Recover(dry run) removed the lock")
+			}
+
+			recovery, err := gy.Recover(op, false, false)
+			if err != nil {
+				t.Fatalf("Recover() error = %v", err)
+			}
+			if recovery.Completed != tt.wantCompleted {
+				t.Errorf("Recover() completed = %v, want %v", recovery.Completed, tt.wantCompleted)
+			}
+			if _, err := os.Stat(lock); !os.IsNotExist(err) {
+				t.Errorf("Recover() left the index lock")
+			}
+			if _, err := os.Stat(tempDir); !os.IsNotExist(err) {
+				t.Errorf("Recover() left the temporary directory")
+			}
+			if pending, err := gy.PendingOperation(); err != nil || pending != nil {
+				t.Errorf("PendingOperation() after Recover() = %+v, %v; want none", pending, err)
+			}
+
+			head, err := git.Head(gy.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantCompleted {
+				if head == before || !gy.ProjectExists("project") {
+					t.Errorf("Recover() undid a committed burial")
+				}
+				return
+			}
+			if head != before {
+				t.Errorf("HEAD = %s after Recover(), want %s", head, before)
+			}
+			if gy.ProjectExists("project") {
+				t.Errorf("project directory still exists after Recover()")
+			}
+			if clean, err := git.IsClean(gy.Path); err != nil || !clean {
+				t.Errorf("graveyard is not clean after Recover(): %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package graveyard
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/metadata"
+)
+
+// versionTagPrefix starts the tags marking each burial of a project that was
+// buried more than once.
+const versionTagPrefix = "buried/"
+
+// retiredSubjectPrefix starts the subject of the commit removing a project's
+// previous version before it is buried again.
+const retiredSubjectPrefix = "docs: bury-it - retired version "
+
+// VersionTag returns the name of the graveyard tag marking version n of a
+// project, e.g. buried/old-experiment/v2.
+func VersionTag(name string, n int) string {
+	return fmt.Sprintf("%s%s/v%d", versionTagPrefix, name, n)
+}
+
+// RetirementMessage returns the commit message used when removing version n
+// of a project to bury it again.
+func RetirementMessage(name string, n int) string {
+	return fmt.Sprintf("%s%d of %s", retiredSubjectPrefix, n, name)
+}
+
+// Version is one burial of a project that was buried more than once.
+type Version struct {
+	// Number is the version, starting at 1.
+	Number int `json:"version"`
+	// Tag is the graveyard tag marking the version.
+	Tag string `json:"tag"`
+	// Commit is the commit the tag points at.
+	Commit string `json:"commit"`
+	// Metadata is the project's metadata as of the version, or nil if it
+	// could not be read.
+	Metadata *metadata.Metadata `json:"-"`
+}
+
+// Versions returns the tagged versions of a project, oldest first. Projects
+// buried only once have none.
+func (g *Graveyard) Versions(name string) ([]Version, error) {
+	tags, err := git.Tags(g.Path, versionTagPrefix+name+"/v*", "")
+	if err != nil {
+		return nil, err
+	}
+	var versions []Version
+	for _, tag := range tags {
+		n, err := strconv.Atoi(strings.TrimPrefix(tag, versionTagPrefix+name+"/v"))
+		if err != nil || n < 1 {
+			continue
+		}
+		commits, err := git.Log(g.Path, []string{"-1", tag})
+		if err != nil || len(commits) == 0 {
+			return nil, fmt.Errorf("failed to resolve %s: %w", tag, err)
+		}
+		v := Version{Number: n, Tag: tag, Commit: commits[0].Hash}
+		if content, err := git.ShowFile(g.Path, tag, path.Join(name, metadata.FileName)); err == nil {
+			v.Metadata, _ = metadata.Parse(content)
+		}
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Number < versions[j].Number })
+	return versions, nil
+}
+
+// CurrentVersion returns the version of the project currently in the
+// graveyard: the latest tagged version, or 1 for a project buried once.
+func (g *Graveyard) CurrentVersion(name string) (int, error) {
+	if !g.ProjectExists(name) {
+		return 0, fmt.Errorf("project not found in graveyard: %s", name)
+	}
+	versions, err := g.Versions(name)
+	if err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 1, nil
+	}
+	return versions[len(versions)-1].Number, nil
+}
+
+// ValidateNewVersion checks that name can be buried again as a new version:
+// it must be a valid name of a project already in the graveyard.
+func (g *Graveyard) ValidateNewVersion(name string) error {
+	if err := checkProjectName(name); err != nil {
+		return err
+	}
+	if !g.ProjectExists(name) {
+		return fmt.Errorf("project not found in graveyard: %s (omit --new-version to bury it for the first time)", name)
+	}
+	return nil
+}
+
+// Retire removes the current version of a project from the working tree in
+// a commit of its own, so that the project can be buried again under the
+// same name. The version is tagged first, if it is not already, so that it
+// stays reachable. It returns the retired version's number.
+func (g *Graveyard) Retire(name string) (int, error) {
+	clean, err := git.IsClean(g.Path)
+	if err != nil {
+		return 0, err
+	}
+	if !clean {
+		return 0, fmt.Errorf("graveyard has uncommitted changes; commit or stash them first")
+	}
+	n, err := g.CurrentVersion(name)
+	if err != nil {
+		return 0, err
+	}
+	tags, err := git.Tags(g.Path, VersionTag(name, n), "")
+	if err != nil {
+		return 0, err
+	}
+	if len(tags) == 0 {
+		if err := git.CreateTag(g.Path, VersionTag(name, n), "HEAD"); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := git.RemoveTree(g.Path, name); err != nil {
+		return 0, err
+	}
+	// Remove ignored and untracked leftovers too, so the new version starts
+	// from an empty directory
+	if err := os.RemoveAll(g.ProjectPath(name)); err != nil {
+		return 0, fmt.Errorf("failed to remove project directory: %w", err)
+	}
+	if err := git.Commit(g.Path, RetirementMessage(name, n)); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// isRetirement reports whether commit retired a version of the project.
+func isRetirement(commit git.LogEntry, name string) bool {
+	rest, ok := strings.CutPrefix(commit.Subject, retiredSubjectPrefix)
+	return ok && strings.HasSuffix(rest, " of "+name)
+}
@@ -0,0 +1,105 @@
+package graveyard
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/metadata"
+)
+
+func TestGraveyard_RetireAndVersions(t *testing.T) {
+	gy := &Graveyard{Path: initGraveyard(t, map[string]string{"README.md": "graveyard"})}
+	buryWithHistory(t, gy, "project", map[string]string{"main.go": "package v1"})
+	first, err := git.Head(gy.Path)
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+
+	if versions, err := gy.Versions("project"); err != nil || len(versions) != 0 {
+		t.Errorf("Versions() before a re-burial = %v, %v, want none", versions, err)
+	}
+	if n, err := gy.CurrentVersion("project"); err != nil || n != 1 {
+		t.Errorf("CurrentVersion() = %d, %v, want 1", n, err)
+	}
+	if err := gy.ValidateNewVersion("missing"); err == nil {
+		t.Errorf("ValidateNewVersion() of a missing project expected error")
+	}
+	if err := gy.ValidateNewVersion("project"); err != nil {
+		t.Errorf("ValidateNewVersion() error = %v", err)
+	}
+
+	retired, err := gy.Retire("project")
+	if err != nil {
+		t.Fatalf("Retire() error = %v", err)
+	}
+	if retired != 1 {
+		t.Errorf("Retire() = %d, want 1", retired)
+	}
+	if gy.ProjectExists("project") {
+		t.Errorf("project directory still exists after Retire()")
+	}
+	retirement, err := git.Head(gy.Path)
+	if err != nil {
+		t.Fatalf("Head() error = %v", err)
+	}
+
+	// Bury the second version as the archive package does
+	meta := &metadata.Metadata{OriginalSource: "/src/project", Version: 2}
+	if err := os.MkdirAll(gy.ProjectPath("project"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(gy.ProjectPath("project"), "main.go"), []byte("package v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := meta.Write(gy.ProjectPath("project")); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, gy.Path, "add", "-A")
+	runGit(t, gy.Path, "commit", "-m", BurialMessage("project"))
+	runGit(t, gy.Path, "tag", VersionTag("project", 2))
+
+	versions, err := gy.Versions("project")
+	if err != nil {
+		t.Fatalf("Versions() error = %v", err)
+	}
+	if len(versions) != 2 || versions[0].Commit != first || versions[1].Number != 2 {
+		t.Fatalf("Versions() = %+v, want v1 at %s and v2", versions, first)
+	}
+	if versions[1].Metadata == nil || versions[1].Metadata.Version != 2 {
+		t.Errorf("Versions()[1].Metadata = %+v, want version 2 metadata", versions[1].Metadata)
+	}
+	if n, err := gy.CurrentVersion("project"); err != nil || n != 2 {
+		t.Errorf("CurrentVersion() = %d, %v, want 2", n, err)
+	}
+
+	burial, err := gy.LastBurial()
+	if err != nil {
+		t.Fatalf("LastBurial() error = %v", err)
+	}
+	if len(burial.Commits) != 2 || burial.Commits[1] != retirement || burial.Parent != first || burial.Retired != 1 {
+		t.Fatalf("LastBurial() = %+v, want the burial and retirement of version 1 after %s", burial, first)
+	}
+
+	if _, err := gy.Undo(burial, false); err != nil {
+		t.Fatalf("Undo() error = %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(gy.ProjectPath("project"), "main.go"))
+	if err != nil || string(content) != "package v1" {
+		t.Errorf("main.go after Undo() = %q, %v, want version 1 restored", content, err)
+	}
+	if tags, _ := git.Tags(gy.Path, "buried/project/*", ""); len(tags) != 1 || tags[0] != VersionTag("project", 1) {
+		t.Errorf("tags after Undo() = %v, want only version 1", tags)
+	}
+}
+
+func TestGraveyard_Retire_Dirty(t *testing.T) {
+	gy := &Graveyard{Path: initGraveyard(t, map[string]string{"README.md": "graveyard", "project/.bury-it.md": "meta"})}
+	if err := os.WriteFile(filepath.Join(gy.Path, "scratch.txt"), []byte("scratch"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gy.Retire("project"); err == nil {
+		t.Errorf("Retire() with uncommitted changes expected error")
+	}
+}
@@ -0,0 +1,221 @@
+// Package health checks a graveyard for problems that accumulate over time,
+// such as directories without metadata or uncommitted changes.
+package health
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/index"
+	"github.com/deanhigh/bury-it/internal/metadata"
+	"github.com/deanhigh/bury-it/internal/size"
+)
+
+// Check is the result of a single health check.
+type Check struct {
+	// Name describes what was checked.
+	Name string `json:"name"`
+	// Passed is true if no problems were found.
+	Passed bool `json:"passed"`
+	// Problems describes each problem found.
+	Problems []string `json:"problems"`
+}
+
+// Report is the result of every health check of a graveyard.
+type Report struct {
+	// Graveyard is the path of the checked graveyard.
+	Graveyard string `json:"graveyard"`
+	// Healthy is true if every check passed.
+	Healthy bool `json:"healthy"`
+	// Checks are the individual check results.
+	Checks []Check `json:"checks"`
+}
+
+// Options configures the health checks.
+type Options struct {
+	// MaxProjectSize is the working-tree or packed size in bytes above which
+	// a project is reported as oversized. Zero disables the check.
+	MaxProjectSize int64
+	// Ignore lists top-level directories that are not buried projects, such
+	// as a generated site, as path.Match patterns.
+	Ignore []string
+	// Now is the time overdue reviews are judged against.
+	Now time.Time
+}
+
+// maxListed is the number of uncommitted files reported individually.
+const maxListed = 10
+
+// Run checks the graveyard and returns the report.
+func Run(gy *graveyard.Graveyard, opts Options) (*Report, error) {
+	projects, err := gy.Projects()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Graveyard: gy.Path, Healthy: true}
+	for _, check := range []struct {
+		name string
+		run  func() ([]string, error)
+	}{
+		{"directories without metadata", func() ([]string, error) { return orphanedDirectories(gy, opts.Ignore) }},
+		{"metadata without directories", func() ([]string, error) { return missingDirectories(gy, projects) }},
+		{"unreadable metadata", func() ([]string, error) { return unreadableMetadata(gy, projects), nil }},
+		{"uncommitted changes", func() ([]string, error) { return uncommittedChanges(gy) }},
+		{"oversized projects", func() ([]string, error) { return oversizedProjects(gy, projects, opts.MaxProjectSize) }},
+		{"overdue reviews", func() ([]string, error) { return overdueReviews(gy, projects, opts.Now), nil }},
+	} {
+		problems, err := check.run()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", check.name, err)
+		}
+		if problems == nil {
+			problems = []string{}
+		}
+		passed := len(problems) == 0
+		report.Healthy = report.Healthy && passed
+		report.Checks = append(report.Checks, Check{Name: check.name, Passed: passed, Problems: problems})
+	}
+	return report, nil
+}
+
+// orphanedDirectories reports top-level directories that are not hidden,
+// not ignored, and have no metadata file.
+func orphanedDirectories(gy *graveyard.Graveyard, ignore []string) ([]string, error) {
+	entries, err := os.ReadDir(gy.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read graveyard: %w", err)
+	}
+	var problems []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || strings.HasPrefix(name, ".") || ignored(name, ignore) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(gy.Path, name, metadata.FileName)); os.IsNotExist(err) {
+			problems = append(problems, fmt.Sprintf("%s has no %s", name, metadata.FileName))
+		}
+	}
+	return problems, nil
+}
+
+func ignored(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.TrimSuffix(pattern, "/"), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// missingDirectories reports tracked metadata files and search index entries
+// of projects that are no longer in the working tree.
+func missingDirectories(gy *graveyard.Graveyard, projects []string) ([]string, error) {
+	files, err := git.ListFiles(gy.Path)
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	for _, file := range files {
+		dir, base := path.Split(file)
+		dir = strings.TrimSuffix(dir, "/")
+		if base != metadata.FileName || dir == "" || strings.Contains(dir, "/") {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(gy.Path, file)); os.IsNotExist(err) {
+			problems = append(problems, fmt.Sprintf("%s is tracked but %s is missing from the working tree", file, dir))
+		}
+	}
+
+	if _, err := os.Stat(index.Path(gy.Path)); err == nil {
+		idx, err := index.Load(gy.Path)
+		if err != nil {
+			return nil, err
+		}
+		present := make(map[string]bool, len(projects))
+		for _, name := range projects {
+			present[name] = true
+		}
+		reported := make(map[string]bool)
+		for _, doc := range idx.Documents {
+			if !present[doc.Project] && !reported[doc.Project] {
+				reported[doc.Project] = true
+				problems = append(problems, fmt.Sprintf("search index has entries for missing project %s; run bury-it index", doc.Project))
+			}
+		}
+	}
+	return problems, nil
+}
+
+// unreadableMetadata reports projects whose metadata cannot be parsed.
+func unreadableMetadata(gy *graveyard.Graveyard, projects []string) []string {
+	var problems []string
+	for _, name := range projects {
+		if _, err := gy.Metadata(name); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	return problems
+}
+
+// uncommittedChanges reports modified and untracked files in the graveyard.
+func uncommittedChanges(gy *graveyard.Graveyard) ([]string, error) {
+	modified, untracked, err := git.UncommittedFiles(gy.Path)
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	for _, file := range modified {
+		problems = append(problems, "modified: "+file)
+	}
+	for _, file := range untracked {
+		problems = append(problems, "untracked: "+file)
+	}
+	if len(problems) > maxListed {
+		more := len(problems) - maxListed
+		problems = append(problems[:maxListed], fmt.Sprintf("and %d more", more))
+	}
+	return problems, nil
+}
+
+// oversizedProjects reports projects whose working tree or packed objects
+// exceed limit.
+func oversizedProjects(gy *graveyard.Graveyard, projects []string, limit int64) ([]string, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	var problems []string
+	for _, name := range projects {
+		usage, err := gy.DiskUsage(name)
+		if err != nil {
+			return nil, err
+		}
+		if usage.WorkTree > limit || usage.Packed > limit {
+			problems = append(problems, fmt.Sprintf("%s uses %s of files and %s packed, over %s",
+				name, size.Format(usage.WorkTree), size.Format(usage.Packed), size.Format(limit)))
+		}
+	}
+	return problems, nil
+}
+
+// overdueReviews reports projects whose review date has passed.
+func overdueReviews(gy *graveyard.Graveyard, projects []string, now time.Time) []string {
+	var problems []string
+	for _, name := range projects {
+		meta, err := gy.Metadata(name)
+		if err != nil {
+			// Reported by the unreadable metadata check
+			continue
+		}
+		if meta.ReviewDue(now) {
+			problems = append(problems, fmt.Sprintf("%s was due for review on %s", name, meta.ReviewAfter.Format("2006-01-02")))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,111 @@
+package health
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/metadata"
+)
+
+func TestRun(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	healthy := &metadata.Metadata{OriginalSource: "/src/healthy", BuriedAt: now.AddDate(-1, 0, 0)}
+	overdue := &metadata.Metadata{OriginalSource: "/src/overdue", BuriedAt: now.AddDate(-2, 0, 0), ReviewAfter: now.AddDate(0, -1, 0)}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"healthy/" + metadata.FileName: healthy.Generate(),
+		"healthy/main.go":              strings.Repeat("x", 4096),
+		"overdue/" + metadata.FileName: overdue.Generate(),
+		"deleted/" + metadata.FileName: healthy.Generate(),
+		"broken/" + metadata.FileName:  "not metadata\n",
+		"docs/index.html":              "<html></html>",
+		"stray/notes.txt":              "notes",
+	}
+	for name, content := range files {
+		writeFile(t, filepath.Join(dir, name), content)
+	}
+	runGit(t, dir, "init", "-q")
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "-c", "user.email=t@t", "-c", "user.name=T", "commit", "-qm", "init")
+	if err := os.RemoveAll(filepath.Join(dir, "deleted")); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "scratch.txt"), "scratch")
+
+	report, err := Run(&graveyard.Graveyard{Path: dir}, Options{MaxProjectSize: 1024, Ignore: []string{"docs/"}, Now: now})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if report.Healthy {
+		t.Errorf("Run() healthy = true, want false")
+	}
+
+	want := map[string][]string{
+		"directories without metadata": {"stray has no .bury-it.md"},
+		"metadata without directories": {"deleted/.bury-it.md is tracked but deleted is missing from the working tree"},
+		"uncommitted changes":          {"modified: deleted/.bury-it.md", "untracked: scratch.txt"},
+		"overdue reviews":              {"overdue was due for review on 2026-02-01"},
+	}
+	for _, check := range report.Checks {
+		switch check.Name {
+		case "unreadable metadata":
+			if len(check.Problems) != 1 || !strings.HasPrefix(check.Problems[0], "broken:") {
+				t.Errorf("%s = %v, want broken", check.Name, check.Problems)
+			}
+		case "oversized projects":
+			if len(check.Problems) != 1 || !strings.HasPrefix(check.Problems[0], "healthy uses 4.") {
+				t.Errorf("%s = %v, want healthy", check.Name, check.Problems)
+			}
+		default:
+			if !reflect.DeepEqual(check.Problems, want[check.Name]) {
+				t.Errorf("%s = %v, want %v", check.Name, check.Problems, want[check.Name])
+			}
+		}
+		if check.Passed != (len(check.Problems) == 0) {
+			t.Errorf("%s passed = %v with %d problems", check.Name, check.Passed, len(check.Problems))
+		}
+	}
+}
+
+func TestRun_Healthy(t *testing.T) {
+	dir := t.TempDir()
+	meta := &metadata.Metadata{OriginalSource: "/src/project", BuriedAt: time.Now()}
+	writeFile(t, filepath.Join(dir, "project", metadata.FileName), meta.Generate())
+	runGit(t, dir, "init", "-q")
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "-c", "user.email=t@t", "-c", "user.name=T", "commit", "-qm", "init")
+
+	report, err := Run(&graveyard.Graveyard{Path: dir}, Options{Now: time.Now()})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !report.Healthy || len(report.Checks) != 6 {
+		t.Errorf("Run() = %+v, want 6 passing checks", report)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
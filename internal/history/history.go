@@ -0,0 +1,120 @@
+// Package history summarizes the commit history of a repository.
+package history
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/git"
+)
+
+// YearCount is the number of commits made in a calendar year.
+type YearCount struct {
+	// Year is the calendar year.
+	Year int
+	// Commits is the number of commits authored that year.
+	Commits int
+}
+
+// Summary is a compact description of how active a repository was.
+type Summary struct {
+	// FirstCommit is the author date of the earliest commit.
+	FirstCommit time.Time
+	// LastCommit is the author date of the latest commit.
+	LastCommit time.Time
+	// Commits is the total number of commits.
+	Commits int
+	// Merges is the number of merge commits.
+	Merges int
+	// Contributors is the number of distinct commit authors.
+	Contributors int
+	// PerYear is the number of commits per year, oldest first.
+	PerYear []YearCount
+	// monthly holds commit counts per month from first to last commit.
+	monthly []int
+}
+
+// Summarize summarizes the given commits. It returns nil if there are none.
+func Summarize(commits []git.LogEntry) *Summary {
+	if len(commits) == 0 {
+		return nil
+	}
+
+	s := &Summary{Commits: len(commits)}
+	authors := make(map[string]bool)
+	years := make(map[int]int)
+	for _, c := range commits {
+		if s.FirstCommit.IsZero() || c.Date.Before(s.FirstCommit) {
+			s.FirstCommit = c.Date
+		}
+		if c.Date.After(s.LastCommit) {
+			s.LastCommit = c.Date
+		}
+		if len(c.Parents) > 1 {
+			s.Merges++
+		}
+		author := strings.ToLower(c.AuthorEmail)
+		if author == "" {
+			author = c.Author
+		}
+		authors[author] = true
+		years[c.Date.UTC().Year()]++
+	}
+	s.Contributors = len(authors)
+
+	for year, count := range years {
+		s.PerYear = append(s.PerYear, YearCount{Year: year, Commits: count})
+	}
+	sort.Slice(s.PerYear, func(i, j int) bool { return s.PerYear[i].Year < s.PerYear[j].Year })
+
+	first := monthIndex(s.FirstCommit)
+	s.monthly = make([]int, monthIndex(s.LastCommit)-first+1)
+	for _, c := range commits {
+		s.monthly[monthIndex(c.Date)-first]++
+	}
+	return s
+}
+
+// monthIndex returns a monotonically increasing month number for t.
+func monthIndex(t time.Time) int {
+	t = t.UTC()
+	return t.Year()*12 + int(t.Month()) - 1
+}
+
+// sparklineWidth and sparklineHeight are the dimensions of the SVG sparkline.
+const (
+	sparklineWidth  = 240
+	sparklineHeight = 40
+)
+
+// Sparkline renders monthly commit activity as a small SVG image.
+func (s *Summary) Sparkline() string {
+	peak := 1
+	for _, n := range s.monthly {
+		peak = max(peak, n)
+	}
+
+	points := make([]string, len(s.monthly))
+	for i, n := range s.monthly {
+		x := float64(sparklineWidth)
+		if len(s.monthly) > 1 {
+			x = float64(i) * sparklineWidth / float64(len(s.monthly)-1)
+		}
+		y := sparklineHeight - 2 - float64(n)*(sparklineHeight-4)/float64(peak)
+		points[i] = fmt.Sprintf("%.1f,%.1f", x, y)
+	}
+	if len(points) == 1 {
+		// Draw a single month as a flat line across the image
+		points = append([]string{fmt.Sprintf("0,%s", strings.SplitN(points[0], ",", 2)[1])}, points[0])
+	}
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">
+<title>Commits per month, %s to %s (peak %d)</title>
+<polyline fill="none" stroke="#586069" stroke-width="1.5" points="%s"/>
+</svg>
+`, sparklineWidth, sparklineHeight, sparklineWidth, sparklineHeight,
+		s.FirstCommit.UTC().Format("2006-01"), s.LastCommit.UTC().Format("2006-01"), peak,
+		strings.Join(points, " "))
+}
@@ -0,0 +1,111 @@
+package history
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/git"
+)
+
+func TestSummarize(t *testing.T) {
+	date := func(y int, m time.Month) time.Time { return time.Date(y, m, 15, 12, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name             string
+		commits          []git.LogEntry
+		wantNil          bool
+		wantCommits      int
+		wantMerges       int
+		wantContributors int
+		wantYears        []YearCount
+		wantMonths       int
+	}{
+		{
+			name:    "no commits",
+			wantNil: true,
+		},
+		{
+			name: "multiple years with a merge",
+			commits: []git.LogEntry{
+				{AuthorEmail: "a@example.com", Date: date(2024, time.March), Parents: []string{"p1", "p2"}},
+				{AuthorEmail: "B@example.com", Date: date(2023, time.December), Parents: []string{"p1"}},
+				{AuthorEmail: "b@example.com", Date: date(2023, time.January), Parents: []string{"p1"}},
+				{AuthorEmail: "a@example.com", Date: date(2022, time.November)},
+			},
+			wantCommits:      4,
+			wantMerges:       1,
+			wantContributors: 2,
+			wantYears:        []YearCount{{2022, 1}, {2023, 2}, {2024, 1}},
+			wantMonths:       17,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Summarize(tt.commits)
+			if tt.wantNil {
+				if s != nil {
+					t.Errorf("Summarize() = %+v, want nil", s)
+				}
+				return
+			}
+			if s.Commits != tt.wantCommits || s.Merges != tt.wantMerges || s.Contributors != tt.wantContributors {
+				t.Errorf("Summarize() = %d commits, %d merges, %d contributors; want %d, %d, %d",
+					s.Commits, s.Merges, s.Contributors, tt.wantCommits, tt.wantMerges, tt.wantContributors)
+			}
+			if !s.FirstCommit.Equal(date(2022, time.November)) || !s.LastCommit.Equal(date(2024, time.March)) {
+				t.Errorf("Summarize() first/last = %v/%v", s.FirstCommit, s.LastCommit)
+			}
+			if len(s.PerYear) != len(tt.wantYears) {
+				t.Fatalf("Summarize().PerYear = %v, want %v", s.PerYear, tt.wantYears)
+			}
+			for i := range s.PerYear {
+				if s.PerYear[i] != tt.wantYears[i] {
+					t.Errorf("Summarize().PerYear[%d] = %v, want %v", i, s.PerYear[i], tt.wantYears[i])
+				}
+			}
+			if len(s.monthly) != tt.wantMonths {
+				t.Errorf("Summarize() tracked %d months, want %d", len(s.monthly), tt.wantMonths)
+			}
+		})
+	}
+}
+
+func TestSummary_Sparkline(t *testing.T) {
+	tests := []struct {
+		name       string
+		commits    []git.LogEntry
+		wantPoints int
+	}{
+		{
+			name: "single month",
+			commits: []git.LogEntry{
+				{Date: time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC)},
+			},
+			wantPoints: 2,
+		},
+		{
+			name: "three months",
+			commits: []git.LogEntry{
+				{Date: time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC)},
+				{Date: time.Date(2024, time.July, 1, 0, 0, 0, 0, time.UTC)},
+			},
+			wantPoints: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svg := Summarize(tt.commits).Sparkline()
+			if !strings.HasPrefix(svg, "<svg ") || !strings.Contains(svg, "</svg>") {
+				t.Fatalf("Sparkline() is not an SVG document:\n%s", svg)
+			}
+			_, points, _ := strings.Cut(svg, `points="`)
+			points, _, _ = strings.Cut(points, `"`)
+			if got := len(strings.Fields(points)); got != tt.wantPoints {
+				t.Errorf("Sparkline() has %d points, want %d: %s", got, tt.wantPoints, points)
+			}
+		})
+	}
+}
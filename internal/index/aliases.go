@@ -0,0 +1,156 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/deanhigh/bury-it/internal/atomicfile"
+	"github.com/deanhigh/bury-it/internal/git"
+)
+
+// AliasesDirName is the name of the directory, within Dir, that maps aliases
+// to the projects they stand for, with a file per alias holding its
+// project's name, so that clones adding different aliases change different
+// files and merge. It sits beside the index directory rather than in it, as
+// rebuilding the index replaces that directory's files.
+const AliasesDirName = "aliases"
+
+// legacyAliasesFileName is the single file, within Dir, that held every
+// alias before they were stored one per file. It is still read, and removed
+// on the next save.
+const legacyAliasesFileName = "aliases.json"
+
+// Aliases maps alternative names of projects, such as old names, codenames,
+// or ticket numbers, to the projects' names in the graveyard.
+type Aliases map[string]string
+
+// AliasesPath returns the location of the aliases directory in the given
+// graveyard.
+func AliasesPath(graveyardPath string) string {
+	return filepath.Join(graveyardPath, Dir, AliasesDirName)
+}
+
+// LoadAliases reads the aliases of the given graveyard. A graveyard without
+// any has an empty set.
+func LoadAliases(graveyardPath string) (Aliases, error) {
+	aliases := make(Aliases)
+	data, err := os.ReadFile(filepath.Join(graveyardPath, Dir, legacyAliasesFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read aliases: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &aliases); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", legacyAliasesFileName, err)
+		}
+	}
+
+	entries, err := os.ReadDir(AliasesPath(graveyardPath))
+	if os.IsNotExist(err) {
+		return aliases, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read aliases: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(AliasesPath(graveyardPath), entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read alias %s: %w", entry.Name(), err)
+		}
+		if project := strings.TrimSpace(string(data)); project != "" {
+			aliases[entry.Name()] = project
+		}
+	}
+	return aliases, nil
+}
+
+// Save writes the aliases to the given graveyard, one file per alias,
+// removes the files of aliases no longer in the set, and stages the changes.
+func (a Aliases) Save(graveyardPath string) error {
+	dir := AliasesPath(graveyardPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	for alias, project := range a {
+		if err := atomicfile.Write(filepath.Join(dir, alias), []byte(project+"\n"), 0644); err != nil {
+			return fmt.Errorf("failed to write alias %s: %w", alias, err)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read aliases: %w", err)
+	}
+	for _, entry := range entries {
+		if _, ok := a[entry.Name()]; ok {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			return fmt.Errorf("failed to remove alias %s: %w", entry.Name(), err)
+		}
+	}
+
+	if err := git.StageFile(graveyardPath, filepath.Join(Dir, AliasesDirName)); err != nil {
+		return fmt.Errorf("failed to stage aliases: %w", err)
+	}
+	legacy := filepath.Join(Dir, legacyAliasesFileName)
+	if err := os.Remove(filepath.Join(graveyardPath, legacy)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove old aliases: %w", err)
+	}
+	tracked, err := git.ListFiles(graveyardPath, legacy)
+	if err != nil {
+		return err
+	}
+	if len(tracked) > 0 {
+		if err := git.Unstage(graveyardPath, legacy); err != nil {
+			return fmt.Errorf("failed to stage aliases: %w", err)
+		}
+	}
+	return nil
+}
+
+// Add makes alias stand for project. An alias cannot be empty, contain
+// whitespace or a slash, be . or .., or already stand for another project.
+func (a Aliases) Add(project, alias string) error {
+	switch {
+	case alias == "":
+		return fmt.Errorf("alias cannot be empty")
+	case strings.ContainsAny(alias, "/\\ \t\n"):
+		return fmt.Errorf("alias cannot contain whitespace or slashes: %q", alias)
+	case alias == "." || alias == "..":
+		return fmt.Errorf("invalid alias: %q", alias)
+	case alias == project:
+		return fmt.Errorf("a project cannot be aliased to its own name")
+	}
+	if existing, ok := a[alias]; ok && existing != project {
+		return fmt.Errorf("%s is already an alias of %s", alias, existing)
+	}
+	a[alias] = project
+	return nil
+}
+
+// Of returns the aliases of project, sorted.
+func (a Aliases) Of(project string) []string {
+	var names []string
+	for alias, p := range a {
+		if p == project {
+			names = append(names, alias)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve returns the project name stands for: the project an alias was
+// given to, or name itself if it is not an alias.
+func (a Aliases) Resolve(name string) string {
+	if project, ok := a[name]; ok {
+		return project
+	}
+	return name
+}
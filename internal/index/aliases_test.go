@@ -0,0 +1,117 @@
+package index
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAliases_Add(t *testing.T) {
+	tests := []struct {
+		name    string
+		project string
+		alias   string
+		wantErr bool
+	}{
+		{name: "codename", project: "old-experiment", alias: "bluebird"},
+		{name: "same alias again", project: "old-experiment", alias: "PROJ-42"},
+		{name: "alias of another project", project: "other", alias: "PROJ-42", wantErr: true},
+		{name: "own name", project: "other", alias: "other", wantErr: true},
+		{name: "empty", project: "other", alias: "", wantErr: true},
+		{name: "slash", project: "other", alias: "team/other", wantErr: true},
+		{name: "whitespace", project: "other", alias: "old other", wantErr: true},
+		{name: "dot dot", project: "other", alias: "..", wantErr: true},
+	}
+	aliases := Aliases{"PROJ-42": "old-experiment"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := aliases.Add(tt.project, tt.alias)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Add(%q, %q) error = %v, wantErr %v", tt.project, tt.alias, err, tt.wantErr)
+			}
+			if err == nil && aliases[tt.alias] != tt.project {
+				t.Errorf("Add(%q, %q) left the alias standing for %q", tt.project, tt.alias, aliases[tt.alias])
+			}
+		})
+	}
+}
+
+func TestAliases_SaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q")
+
+	loaded, err := LoadAliases(dir)
+	if err != nil || len(loaded) != 0 {
+		t.Fatalf("LoadAliases() without a file = %v, %v, want no aliases", loaded, err)
+	}
+
+	aliases := Aliases{"bluebird": "old-experiment", "PROJ-42": "old-experiment", "legacy": "other"}
+	if err := aliases.Save(dir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err = LoadAliases(dir)
+	if err != nil {
+		t.Fatalf("LoadAliases() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, aliases) {
+		t.Errorf("LoadAliases() = %v, want %v", loaded, aliases)
+	}
+
+	if got, want := loaded.Of("old-experiment"), []string{"PROJ-42", "bluebird"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Of() = %v, want %v", got, want)
+	}
+	for name, want := range map[string]string{"bluebird": "old-experiment", "legacy": "other", "unknown": "unknown"} {
+		if got := loaded.Resolve(name); got != want {
+			t.Errorf("Resolve(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	// Each alias is a file of its own, removed with the alias
+	delete(loaded, "legacy")
+	if err := loaded.Save(dir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(AliasesPath(dir), "legacy")); !os.IsNotExist(err) {
+		t.Errorf("Save() kept the file of a removed alias, err = %v", err)
+	}
+	if content, err := os.ReadFile(filepath.Join(AliasesPath(dir), "bluebird")); err != nil || string(content) != "old-experiment\n" {
+		t.Errorf("alias file = %q, %v, want the project's name", content, err)
+	}
+}
+
+func TestAliases_Legacy(t *testing.T) {
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q")
+	legacy := filepath.Join(dir, Dir, legacyAliasesFileName)
+	if err := os.MkdirAll(filepath.Dir(legacy), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(legacy, []byte(`{"bluebird": "old-experiment"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "add", "-A")
+
+	aliases, err := LoadAliases(dir)
+	if err != nil {
+		t.Fatalf("LoadAliases() error = %v", err)
+	}
+	if err := aliases.Add("other", "PROJ-42"); err != nil {
+		t.Fatal(err)
+	}
+	if err := aliases.Save(dir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("Save() kept the old aliases file, err = %v", err)
+	}
+	loaded, err := LoadAliases(dir)
+	if want := (Aliases{"bluebird": "old-experiment", "PROJ-42": "other"}); err != nil || !reflect.DeepEqual(loaded, want) {
+		t.Errorf("LoadAliases() = %v, %v, want %v", loaded, err, want)
+	}
+	out, err := exec.Command("git", "-C", dir, "diff", "--cached", "--name-status").Output()
+	if want := "A\t.bury-it/aliases/PROJ-42\nA\t.bury-it/aliases/bluebird\n"; err != nil || string(out) != want {
+		t.Errorf("staged = %q, %v, want %q", out, err, want)
+	}
+}
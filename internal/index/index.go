@@ -0,0 +1,355 @@
+// Package index builds and queries a full-text search index over a graveyard.
+package index
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/deanhigh/bury-it/internal/atomicfile"
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/metadata"
+)
+
+// Dir is the graveyard directory that holds bury-it's own data files.
+const Dir = ".bury-it"
+
+// Name is the name of the index directory within Dir. It holds a file for
+// each project, named after it, so that burials of different projects made
+// on different machines change different files and merge without conflicts.
+const Name = "index"
+
+// legacyFileName is the name of the single index file, within Dir, that
+// version 1 of the index was stored in.
+const legacyFileName = "index.json"
+
+// version is the current on-disk index format version.
+const version = 2
+
+// maxFileSize is the largest file whose contents are indexed.
+const maxFileSize = 1 << 20
+
+// Kind identifies what an indexed document represents.
+type Kind string
+
+const (
+	// KindFile is the contents of a file in a buried project.
+	KindFile Kind = "file"
+	// KindMetadata is a buried project's metadata file.
+	KindMetadata Kind = "metadata"
+	// KindCommit is a commit message from a buried project's history.
+	KindCommit Kind = "commit"
+)
+
+// Document is a single searchable unit in the index.
+type Document struct {
+	// Project is the name of the buried project the document belongs to.
+	Project string `json:"project"`
+	// Kind is the kind of document.
+	Kind Kind `json:"kind"`
+	// Ref is the file path relative to the project, or the commit hash.
+	Ref string `json:"ref"`
+	// Title is a short human-readable description, such as a commit subject.
+	Title string `json:"title,omitempty"`
+	// Terms are the distinct normalized terms contained in the document.
+	Terms []string `json:"terms"`
+}
+
+// Index is a full-text search index over the projects in a graveyard.
+type Index struct {
+	// Documents are the indexed documents.
+	Documents []Document
+}
+
+// projectFile is the on-disk index of a single project. It holds nothing
+// that changes between builds, such as a timestamp, so that indexing the
+// same project on two machines gives identical files.
+type projectFile struct {
+	// Version is the on-disk format version.
+	Version int `json:"version"`
+	// Documents are the project's documents.
+	Documents []Document `json:"documents"`
+}
+
+// Path returns the location of the index directory in the given graveyard.
+func Path(graveyardPath string) string {
+	return filepath.Join(graveyardPath, Dir, Name)
+}
+
+// projectPath returns the location of a project's index file.
+func projectPath(graveyardPath, name string) string {
+	return filepath.Join(Path(graveyardPath), name+".json")
+}
+
+// Build indexes every project in the graveyard.
+func Build(gy *graveyard.Graveyard) (*Index, error) {
+	projects, err := gy.Projects()
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &Index{}
+	for _, name := range projects {
+		if err := idx.AddProject(gy, name); err != nil {
+			return nil, err
+		}
+	}
+	return idx, nil
+}
+
+// Refresh re-indexes a single project in the graveyard's existing index, saves
+// its index file, and stages it.
+func Refresh(gy *graveyard.Graveyard, name string) error {
+	idx := &Index{}
+	if err := idx.AddProject(gy, name); err != nil {
+		return fmt.Errorf("failed to index project: %w", err)
+	}
+	if err := writeProject(gy.Path, name, idx.Documents); err != nil {
+		return err
+	}
+	if err := git.StageFile(gy.Path, filepath.Join(Dir, Name, name+".json")); err != nil {
+		return fmt.Errorf("failed to stage index: %w", err)
+	}
+	return nil
+}
+
+// AddProject indexes a single project, replacing any existing documents for it.
+func (idx *Index) AddProject(gy *graveyard.Graveyard, name string) error {
+	idx.RemoveProject(name)
+
+	files, err := git.ListFiles(gy.Path, name+"/")
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		rel := strings.TrimPrefix(file, name+"/")
+		content, ok, err := readText(filepath.Join(gy.Path, file))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		kind := KindFile
+		if rel == metadata.FileName {
+			kind = KindMetadata
+		}
+		idx.Documents = append(idx.Documents, Document{
+			Project: name,
+			Kind:    kind,
+			Ref:     rel,
+			Terms:   Tokenize(rel + "\n" + content),
+		})
+	}
+
+	commits, err := gy.History(name)
+	if err != nil {
+		return err
+	}
+	for _, c := range commits {
+		idx.Documents = append(idx.Documents, Document{
+			Project: name,
+			Kind:    KindCommit,
+			Ref:     c.Hash,
+			Title:   c.Subject,
+			Terms:   Tokenize(c.Subject + "\n" + c.Body + "\n" + c.Author),
+		})
+	}
+	return nil
+}
+
+// RemoveProject removes all documents belonging to a project.
+func (idx *Index) RemoveProject(name string) {
+	docs := idx.Documents[:0]
+	for _, doc := range idx.Documents {
+		if doc.Project != name {
+			docs = append(docs, doc)
+		}
+	}
+	idx.Documents = docs
+}
+
+// Search returns the documents containing every term in query.
+func (idx *Index) Search(query string) []Document {
+	terms := Tokenize(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var results []Document
+	for _, doc := range idx.Documents {
+		if containsAll(doc.Terms, terms) {
+			results = append(results, doc)
+		}
+	}
+	return results
+}
+
+// Load reads the index from the given graveyard.
+func Load(graveyardPath string) (*Index, error) {
+	dir := Path(graveyardPath)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		if _, err := os.Stat(filepath.Join(graveyardPath, Dir, legacyFileName)); err == nil {
+			return nil, fmt.Errorf("search index is in an old format (run bury-it index to rebuild it)")
+		}
+		return nil, fmt.Errorf("search index not found (run bury-it index to build it)")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+
+	idx := &Index{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read index: %w", err)
+		}
+		var file projectFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse index file %s (run bury-it index to rebuild it): %w", entry.Name(), err)
+		}
+		if file.Version != version {
+			return nil, fmt.Errorf("unsupported index version %d in %s (run bury-it index to rebuild it)", file.Version, entry.Name())
+		}
+		idx.Documents = append(idx.Documents, file.Documents...)
+	}
+	return idx, nil
+}
+
+// Save writes the index to the given graveyard, a file for each project,
+// removing the files of projects no longer indexed and any index in the old
+// single-file format.
+func (idx *Index) Save(graveyardPath string) error {
+	dir := Path(graveyardPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+
+	byProject := make(map[string][]Document)
+	for _, doc := range idx.Documents {
+		byProject[doc.Project] = append(byProject[doc.Project], doc)
+	}
+	for name, docs := range byProject {
+		if err := writeProject(graveyardPath, name, docs); err != nil {
+			return err
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || byProject[name] != nil {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+			return fmt.Errorf("failed to remove index file: %w", err)
+		}
+	}
+	if err := os.Remove(filepath.Join(graveyardPath, Dir, legacyFileName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove old index: %w", err)
+	}
+	return nil
+}
+
+// Stage stages the index saved in the given graveyard, including the removal
+// of project files and of any index in the old single-file format.
+func Stage(graveyardPath string) error {
+	if err := git.StageFile(graveyardPath, filepath.Join(Dir, Name)); err != nil {
+		return fmt.Errorf("failed to stage index: %w", err)
+	}
+	legacy := filepath.Join(Dir, legacyFileName)
+	tracked, err := git.ListFiles(graveyardPath, legacy)
+	if err != nil {
+		return err
+	}
+	if len(tracked) > 0 {
+		if err := git.Unstage(graveyardPath, legacy); err != nil {
+			return fmt.Errorf("failed to stage index: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeProject writes the index file of a project.
+func writeProject(graveyardPath, name string, docs []Document) error {
+	if err := os.MkdirAll(Path(graveyardPath), 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	if docs == nil {
+		docs = []Document{}
+	}
+	data, err := json.Marshal(projectFile{Version: version, Documents: docs})
+	if err != nil {
+		return fmt.Errorf("failed to encode index: %w", err)
+	}
+	if err := atomicfile.Write(projectPath(graveyardPath, name), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	return nil
+}
+
+// Tokenize splits text into distinct, lowercased, sorted terms.
+func Tokenize(text string) []string {
+	seen := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) {
+		if len(word) >= 2 {
+			seen[word] = true
+		}
+	}
+
+	terms := make([]string, 0, len(seen))
+	for term := range seen {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// containsAll reports whether the sorted terms contain every wanted term.
+func containsAll(terms, wanted []string) bool {
+	for _, w := range wanted {
+		i := sort.SearchStrings(terms, w)
+		if i == len(terms) || terms[i] != w {
+			return false
+		}
+	}
+	return true
+}
+
+// readText reads a file if it is small enough and does not look binary.
+func readText(path string) (string, bool, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if !info.Mode().IsRegular() || info.Size() > maxFileSize {
+		return "", false, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if bytes.IndexByte(data, 0) != -1 {
+		return "", false, nil
+	}
+	return string(data), true, nil
+}
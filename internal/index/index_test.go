@@ -0,0 +1,204 @@
+package index
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deanhigh/bury-it/internal/graveyard"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "lowercases and sorts",
+			text: "Hello World hello",
+			want: []string{"hello", "world"},
+		},
+		{
+			name: "splits on punctuation but keeps underscores",
+			text: "parse_config(ctx) -> err",
+			want: []string{"ctx", "err", "parse_config"},
+		},
+		{
+			name: "drops single characters",
+			text: "a b cd",
+			want: []string{"cd"},
+		},
+		{
+			name: "empty text",
+			text: "",
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Tokenize(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndex_Search(t *testing.T) {
+	idx := &Index{Documents: []Document{
+		{Project: "alpha", Kind: KindFile, Ref: "main.go", Terms: Tokenize("websocket reconnect loop")},
+		{Project: "beta", Kind: KindCommit, Ref: "abc123", Terms: Tokenize("fix websocket timeout")},
+		{Project: "beta", Kind: KindMetadata, Ref: ".bury-it.md", Terms: Tokenize("archived project")},
+	}}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "single term", query: "websocket", want: []string{"main.go", "abc123"}},
+		{name: "all terms required", query: "websocket reconnect", want: []string{"main.go"}},
+		{name: "case-insensitive", query: "ARCHIVED", want: []string{".bury-it.md"}},
+		{name: "no match", query: "kubernetes", want: nil},
+		{name: "empty query", query: "", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, doc := range idx.Search(tt.query) {
+				got = append(got, doc.Ref)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"demo/.bury-it.md": "# Archived Project\n",
+		"demo/server.go":   "func serveWebsocket() {}\n",
+		"demo/blob.bin":    "binary\x00data",
+	}
+	runGit(t, dir, "init")
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "-c", "user.name=Test", "-c", "user.email=test@test.com", "commit", "-m", "bury demo project")
+
+	idx, err := Build(&graveyard.Graveyard{Path: dir})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if err := idx.Save(dir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	kinds := map[Kind][]string{}
+	for _, doc := range loaded.Documents {
+		kinds[doc.Kind] = append(kinds[doc.Kind], doc.Ref)
+	}
+	if want := []string{"server.go"}; !reflect.DeepEqual(kinds[KindFile], want) {
+		t.Errorf("file documents = %v, want %v", kinds[KindFile], want)
+	}
+	if want := []string{".bury-it.md"}; !reflect.DeepEqual(kinds[KindMetadata], want) {
+		t.Errorf("metadata documents = %v, want %v", kinds[KindMetadata], want)
+	}
+	if len(kinds[KindCommit]) != 1 {
+		t.Errorf("commit documents = %v, want 1", kinds[KindCommit])
+	}
+	if got := loaded.Search("bury demo"); len(got) != 1 || got[0].Kind != KindCommit {
+		t.Errorf("Search(commit message) = %+v, want the commit", got)
+	}
+}
+
+func TestSave_Projects(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, Dir, legacyFileName)
+	if err := os.MkdirAll(filepath.Dir(legacy), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(legacy, []byte(`{"version":1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir); err == nil || !strings.Contains(err.Error(), "old format") {
+		t.Errorf("Load() of old index error = %v, want old format", err)
+	}
+
+	idx := &Index{Documents: []Document{
+		{Project: "alpha", Kind: KindFile, Ref: "main.go", Terms: []string{"main"}},
+		{Project: "beta", Kind: KindFile, Ref: "app.py", Terms: []string{"app"}},
+		{Project: "alpha", Kind: KindCommit, Ref: "abc123", Title: "Initial", Terms: []string{"initial"}},
+	}}
+	if err := idx.Save(dir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	alpha, err := os.ReadFile(projectPath(dir, "alpha"))
+	if err != nil {
+		t.Fatalf("alpha index file: %v", err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("Save() kept the old index file")
+	}
+
+	// Saving the same documents again must give identical files, so that
+	// clones indexing the same project do not conflict
+	idx.Documents = idx.Documents[:1:1]
+	idx.Documents = append(idx.Documents, Document{Project: "alpha", Kind: KindCommit, Ref: "abc123", Title: "Initial", Terms: []string{"initial"}})
+	if err := idx.Save(dir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	again, err := os.ReadFile(projectPath(dir, "alpha"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(alpha) {
+		t.Errorf("Save() of the same documents changed the file:\n%s\n%s", alpha, again)
+	}
+	if _, err := os.Stat(projectPath(dir, "beta")); !os.IsNotExist(err) {
+		t.Errorf("Save() kept the index file of a project no longer indexed")
+	}
+
+	loaded, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(loaded.Documents) != 2 {
+		t.Errorf("Load() = %d documents, want 2", len(loaded.Documents))
+	}
+}
+
+func TestLoad_Missing(t *testing.T) {
+	if _, err := Load(t.TempDir()); err == nil {
+		t.Errorf("Load() expected error for missing index, got nil")
+	}
+}
+
+// runGit runs a git command in dir and fails the test on error.
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
@@ -0,0 +1,221 @@
+// Package lfs buries the content of files tracked with Git LFS, reading the
+// objects of a repository's LFS store directly so that git-lfs only needs to
+// be installed to fetch them.
+package lfs
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/deanhigh/bury-it/internal/git"
+)
+
+// pointerVersion is the first line of every Git LFS pointer file.
+const pointerVersion = "version https://git-lfs.github.com/spec/v1"
+
+// maxPointerSize is the size above which a file is never a pointer file.
+const maxPointerSize = 1024
+
+// Pointer is the content of a Git LFS pointer file, which git stores in place
+// of the file it stands for.
+type Pointer struct {
+	// OID is the SHA-256 of the file's content, in hex.
+	OID string
+	// Size is the size of the file's content in bytes.
+	Size int64
+}
+
+// ParsePointer parses a pointer file, reporting whether data is one.
+func ParsePointer(data []byte) (Pointer, bool) {
+	if len(data) > maxPointerSize {
+		return Pointer{}, false
+	}
+	var p Pointer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	first := true
+	sized := false
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok {
+			return Pointer{}, false
+		}
+		if first {
+			if key+" "+value != pointerVersion {
+				return Pointer{}, false
+			}
+			first = false
+			continue
+		}
+		switch key {
+		case "oid":
+			oid, ok := strings.CutPrefix(value, "sha256:")
+			if !ok || len(oid) != 64 || strings.Trim(oid, "0123456789abcdef") != "" {
+				return Pointer{}, false
+			}
+			p.OID = oid
+		case "size":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || size < 0 {
+				return Pointer{}, false
+			}
+			p.Size = size
+			sized = true
+		}
+	}
+	if first || p.OID == "" || !sized {
+		return Pointer{}, false
+	}
+	return p, true
+}
+
+// Store returns the directory holding the LFS objects of the repository at
+// repoPath, which need not exist.
+func Store(repoPath string) (string, error) {
+	return git.GitPath(repoPath, "lfs/objects")
+}
+
+// ObjectPath returns the path of the object oid in store.
+func ObjectPath(store, oid string) string {
+	return filepath.Join(store, oid[0:2], oid[2:4], oid)
+}
+
+// ReadPointers reads the pointer files of files at rev in the repository,
+// skipping any that are not pointer files, such as those committed before
+// they were tracked with Git LFS.
+func ReadPointers(repoPath, rev string, files []string) (map[string]Pointer, error) {
+	objects := make([]string, len(files))
+	for i, f := range files {
+		objects[i] = rev + ":" + f
+	}
+	pointers := make(map[string]Pointer)
+	err := git.ReadBlobs(repoPath, objects, func(i int, r io.Reader) error {
+		data, err := io.ReadAll(io.LimitReader(r, maxPointerSize+1))
+		if err != nil {
+			return err
+		}
+		if p, ok := ParsePointer(data); ok {
+			pointers[files[i]] = p
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pointers, nil
+}
+
+// Materialize replaces the pointer files in dir with the content of the
+// objects they point to in store, returning the sorted files whose objects
+// are not there.
+func Materialize(store, dir string, pointers map[string]Pointer) ([]string, error) {
+	var missing []string
+	for _, file := range sortedFiles(pointers) {
+		object, err := os.Open(ObjectPath(store, pointers[file].OID))
+		if errors.Is(err, fs.ErrNotExist) {
+			missing = append(missing, file)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read LFS object of %s: %w", file, err)
+		}
+		err = writeContent(filepath.Join(dir, filepath.FromSlash(file)), object)
+		_ = object.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", file, err)
+		}
+	}
+	return missing, nil
+}
+
+// Missing returns the sorted files whose objects are not in store.
+func Missing(store string, pointers map[string]Pointer) []string {
+	var missing []string
+	for _, file := range sortedFiles(pointers) {
+		if _, err := os.Stat(ObjectPath(store, pointers[file].OID)); err != nil {
+			missing = append(missing, file)
+		}
+	}
+	return missing
+}
+
+// CopyObjects copies every object in src that dest lacks into dest,
+// returning how many were copied. A missing src has no objects.
+func CopyObjects(src, dest string) (int, error) {
+	copied := 0
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == src {
+			return filepath.SkipDir
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+		if _, err := os.Stat(target); err == nil {
+			return nil
+		}
+		object, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = object.Close() }()
+		if err := writeContent(target, object); err != nil {
+			return err
+		}
+		copied++
+		return nil
+	})
+	if err != nil {
+		return copied, fmt.Errorf("failed to copy LFS objects: %w", err)
+	}
+	return copied, nil
+}
+
+// FilterlessEnv returns environment variables that turn off the Git LFS
+// filters for a single git command, so that files staged with it are stored
+// in git as they are, even where .gitattributes hands them to Git LFS.
+func FilterlessEnv() []string {
+	return git.ConfigEnv(
+		"filter.lfs.clean", "",
+		"filter.lfs.smudge", "",
+		"filter.lfs.process", "",
+		"filter.lfs.required", "false")
+}
+
+// writeContent writes r to path, keeping the mode of an existing file.
+func writeContent(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// sortedFiles returns the files of pointers in order.
+func sortedFiles(pointers map[string]Pointer) []string {
+	files := make([]string, 0, len(pointers))
+	for file := range pointers {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
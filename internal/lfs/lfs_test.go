@@ -0,0 +1,133 @@
+package lfs
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+const (
+	modelOID = "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+	dataOID  = "a3f1f1f3dd3ce1a8ba2a6c1d2e2f4a2b7b7a9d8c6b5a4f3e2d1c0b9a8f7e6d5c"
+)
+
+// pointerFile returns the pointer file of an object.
+func pointerFile(oid string, size int) string {
+	return "version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize " + strconv.Itoa(size) + "\n"
+}
+
+func TestParsePointer(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		want   Pointer
+		wantOK bool
+	}{
+		{name: "pointer", data: pointerFile(modelOID, 1234), want: Pointer{OID: modelOID, Size: 1234}, wantOK: true},
+		{name: "extension lines", data: "version https://git-lfs.github.com/spec/v1\next-0-foo sha256:" + dataOID + "\noid sha256:" + modelOID + "\nsize 5\n", want: Pointer{OID: modelOID, Size: 5}, wantOK: true},
+		{name: "plain file", data: "package main\n"},
+		{name: "empty", data: ""},
+		{name: "other version", data: "version https://example.com/v2\noid sha256:" + modelOID + "\nsize 5\n"},
+		{name: "no size", data: "version https://git-lfs.github.com/spec/v1\noid sha256:" + modelOID + "\n"},
+		{name: "bad oid", data: pointerFile("not-a-hash", 5)},
+		{name: "negative size", data: pointerFile(modelOID, -1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParsePointer([]byte(tt.data))
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("ParsePointer() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestMaterialize(t *testing.T) {
+	repo := t.TempDir()
+	files := map[string]string{
+		".gitattributes": "*.bin filter=lfs\n",
+		"model.bin":      pointerFile(modelOID, 5),
+		"data/more.bin":  pointerFile(dataOID, 4),
+		"old.bin":        "committed before Git LFS",
+	}
+	for name, content := range files {
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=Test", "-c", "user.email=test@test.com", "commit", "-q", "-m", "initial commit"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
+	pointers, err := ReadPointers(repo, "HEAD", []string{"data/more.bin", "model.bin", "old.bin"})
+	if err != nil {
+		t.Fatalf("ReadPointers() error = %v", err)
+	}
+	wantPointers := map[string]Pointer{
+		"model.bin":     {OID: modelOID, Size: 5},
+		"data/more.bin": {OID: dataOID, Size: 4},
+	}
+	if !reflect.DeepEqual(pointers, wantPointers) {
+		t.Fatalf("ReadPointers() = %+v, want %+v", pointers, wantPointers)
+	}
+
+	// Only the model's object was fetched
+	store, err := Store(repo)
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(ObjectPath(store, modelOID)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ObjectPath(store, modelOID), []byte("model"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := Missing(store, pointers); !reflect.DeepEqual(got, []string{"data/more.bin"}) {
+		t.Errorf("Missing() = %v, want [data/more.bin]", got)
+	}
+
+	missing, err := Materialize(store, repo, pointers)
+	if err != nil {
+		t.Fatalf("Materialize() error = %v", err)
+	}
+	if !reflect.DeepEqual(missing, []string{"data/more.bin"}) {
+		t.Errorf("Materialize() missing = %v, want [data/more.bin]", missing)
+	}
+	if got, _ := os.ReadFile(filepath.Join(repo, "model.bin")); string(got) != "model" {
+		t.Errorf("model.bin = %q, want the object's content", got)
+	}
+	if got, _ := os.ReadFile(filepath.Join(repo, "data/more.bin")); string(got) != files["data/more.bin"] {
+		t.Errorf("data/more.bin = %q, want the pointer file kept", got)
+	}
+
+	// Copying into another store skips what it already has
+	dest := filepath.Join(t.TempDir(), "lfs", "objects")
+	for _, want := range []int{1, 0} {
+		n, err := CopyObjects(store, dest)
+		if err != nil || n != want {
+			t.Errorf("CopyObjects() = %d, %v, want %d", n, err, want)
+		}
+	}
+	if got, _ := os.ReadFile(ObjectPath(dest, modelOID)); string(got) != "model" {
+		t.Errorf("copied object = %q, want %q", got, "model")
+	}
+	if n, err := CopyObjects(filepath.Join(t.TempDir(), "none"), dest); err != nil || n != 0 {
+		t.Errorf("CopyObjects() of a missing store = %d, %v, want 0, nil", n, err)
+	}
+}
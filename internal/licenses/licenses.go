@@ -0,0 +1,369 @@
+// Package licenses detects the licenses of buried projects and of the
+// dependencies vendored into them, and aggregates them across a graveyard
+// to show what obligations it still carries.
+package licenses
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+)
+
+// Unknown is the license of a license file whose text is not recognized.
+const Unknown = "Unknown"
+
+// Kind is the kind of obligations a license carries.
+type Kind string
+
+const (
+	// KindStrongCopyleft requires derived works to be distributed under the
+	// same license, in the case of the AGPL even when only offered over a
+	// network.
+	KindStrongCopyleft Kind = "strong copyleft"
+	// KindWeakCopyleft requires changes to the licensed files themselves to
+	// be shared under the same license.
+	KindWeakCopyleft Kind = "weak copyleft"
+	// KindPermissive requires little more than keeping the notice.
+	KindPermissive Kind = "permissive"
+	// KindPublicDomain waives all rights.
+	KindPublicDomain Kind = "public domain"
+	// KindUnknown is a license that was not recognized and needs reading.
+	KindUnknown Kind = "unknown"
+)
+
+// kindOrder ranks kinds by how much attention they need, most first.
+var kindOrder = map[Kind]int{
+	KindStrongCopyleft: 0,
+	KindUnknown:        1,
+	KindWeakCopyleft:   2,
+	KindPermissive:     3,
+	KindPublicDomain:   4,
+}
+
+// kinds maps the SPDX identifiers of the recognized licenses to their kinds.
+var kinds = map[string]Kind{
+	"AGPL-3.0":     KindStrongCopyleft,
+	"GPL-2.0":      KindStrongCopyleft,
+	"GPL-3.0":      KindStrongCopyleft,
+	"LGPL-2.1":     KindWeakCopyleft,
+	"LGPL-3.0":     KindWeakCopyleft,
+	"MPL-2.0":      KindWeakCopyleft,
+	"EPL-2.0":      KindWeakCopyleft,
+	"Apache-2.0":   KindPermissive,
+	"MIT":          KindPermissive,
+	"BSD-2-Clause": KindPermissive,
+	"BSD-3-Clause": KindPermissive,
+	"ISC":          KindPermissive,
+	"Unlicense":    KindPublicDomain,
+	"CC0-1.0":      KindPublicDomain,
+}
+
+// signatures recognize license texts: a text whose head contains every
+// phrase of a signature, ignoring case and line breaks, is under its license.
+// More specific signatures come first.
+var signatures = []struct {
+	license string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"gnu affero general public license", "version 3"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license", "version 3"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license", "version 2.1"}},
+	{"GPL-3.0", []string{"gnu general public license", "version 3"}},
+	{"GPL-2.0", []string{"gnu general public license", "version 2"}},
+	{"MPL-2.0", []string{"mozilla public license", "2.0"}},
+	{"EPL-2.0", []string{"eclipse public license", "2.0"}},
+	{"Apache-2.0", []string{"apache license", "version 2.0"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+	{"MIT", []string{"permission is hereby granted, free of charge"}},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"Unlicense", []string{"this is free and unencumbered software released into the public domain"}},
+	{"CC0-1.0", []string{"cc0 1.0 universal"}},
+}
+
+// maxLicenseSize is the largest license file that is read.
+const maxLicenseSize = 256 << 10
+
+// headSize is the number of characters at the start of a license text that
+// signatures are matched against. License texts name themselves at the top,
+// while their later terms can name other licenses, as the GPL does the LGPL.
+const headSize = 1500
+
+// Finding is a license found in a buried project.
+type Finding struct {
+	// Project is the buried project it was found in.
+	Project string `json:"project"`
+	// Dependency is the path, within the project, of the vendored
+	// dependency it applies to, or "" for the project's own license.
+	Dependency string `json:"dependency,omitempty"`
+	// License is the license's SPDX identifier, as declared or recognized
+	// from its text, or Unknown.
+	License string `json:"license"`
+	// Kind is the kind of obligations the license carries.
+	Kind Kind `json:"kind"`
+	// File is the path, within the project, of the file it was found in.
+	File string `json:"file"`
+}
+
+// Scan finds the licenses of the graveyard's projects and of the
+// dependencies vendored into them, under node_modules, vendor, or
+// third_party: license files such as LICENSE and COPYING, recognized from
+// their text, and the licenses package.json files declare. Each license is
+// reported once for a project or dependency, sorted by project and
+// dependency.
+func Scan(gy *graveyard.Graveyard) ([]Finding, error) {
+	projects, err := gy.Projects()
+	if err != nil {
+		return nil, err
+	}
+	buried := make(map[string]bool, len(projects))
+	for _, name := range projects {
+		buried[name] = true
+	}
+	files, err := git.ListFiles(gy.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	seen := make(map[Finding]bool)
+	for _, file := range files {
+		project, rel, ok := strings.Cut(file, "/")
+		if !ok || !buried[project] {
+			continue
+		}
+		base := path.Base(rel)
+		var licenses []string
+		switch {
+		case isLicenseFile(base):
+			license, ok := readLicense(filepath.Join(gy.Path, file))
+			if !ok {
+				continue
+			}
+			licenses = []string{license}
+		case base == "package.json":
+			licenses = declaredLicenses(filepath.Join(gy.Path, file))
+		default:
+			continue
+		}
+		for _, license := range licenses {
+			f := Finding{Project: project, Dependency: dependency(rel), License: license, Kind: KindOf(license)}
+			if seen[f] {
+				continue
+			}
+			seen[f] = true
+			f.File = rel
+			findings = append(findings, f)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Project != findings[j].Project {
+			return findings[i].Project < findings[j].Project
+		}
+		return findings[i].Dependency < findings[j].Dependency
+	})
+	return findings, nil
+}
+
+// KindOf returns the kind of obligations the license with the given SPDX
+// identifier carries. The -only and -or-later suffixes are ignored, and an
+// SPDX expression of several licenses is as demanding as its most demanding
+// license.
+func KindOf(license string) Kind {
+	best, found := KindPublicDomain, false
+	tokens := strings.FieldsFunc(license, func(r rune) bool { return r == ' ' || r == '(' || r == ')' })
+	for i := 0; i < len(tokens); i++ {
+		id := tokens[i]
+		switch id {
+		case "OR", "AND":
+			continue
+		case "WITH":
+			// Exceptions only relax the license they follow
+			i++
+			continue
+		}
+		id = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(id, "+"), "-only"), "-or-later")
+		kind, ok := kinds[id]
+		if !ok {
+			return KindUnknown
+		}
+		found = true
+		if kindOrder[kind] < kindOrder[best] {
+			best = kind
+		}
+	}
+	if !found {
+		return KindUnknown
+	}
+	return best
+}
+
+// Identify returns the SPDX identifier of the license in text: the one an
+// SPDX-License-Identifier line names, or the one whose text it matches, or
+// Unknown.
+func Identify(text string) string {
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		if _, id, ok := strings.Cut(scanner.Text(), "SPDX-License-Identifier:"); ok {
+			if id = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(id), "*/")); id != "" {
+				return id
+			}
+		}
+	}
+	normalized := strings.ToLower(strings.Join(strings.Fields(text), " "))
+	if len(normalized) > headSize {
+		normalized = normalized[:headSize]
+	}
+	for _, sig := range signatures {
+		matched := true
+		for _, phrase := range sig.phrases {
+			if !strings.Contains(normalized, phrase) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return sig.license
+		}
+	}
+	return Unknown
+}
+
+// isLicenseFile reports whether a file with the given base name holds a
+// license text.
+func isLicenseFile(base string) bool {
+	name := strings.ToUpper(strings.TrimSuffix(base, path.Ext(base)))
+	for _, prefix := range []string{"LICENSE", "LICENCE", "COPYING", "UNLICENSE"} {
+		if name == prefix || strings.HasPrefix(name, prefix+"-") || strings.HasPrefix(name, prefix+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+// readLicense identifies the license in the file at path. Files that cannot
+// be read or are too large to be a license are skipped.
+func readLicense(path string) (string, bool) {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxLicenseSize {
+		// Tracked files can be missing from a dirty working tree
+		return "", false
+	}
+	content, err := os.ReadFile(path)
+	if err != nil || bytes.IndexByte(content, 0) >= 0 {
+		return "", false
+	}
+	return Identify(string(content)), true
+}
+
+// declaredLicenses returns the licenses the package.json file at path
+// declares, in either the license field or the older licenses list.
+func declaredLicenses(path string) []string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var pkg struct {
+		License  json.RawMessage `json:"license"`
+		Licenses []struct {
+			Type string `json:"type"`
+		} `json:"licenses"`
+	}
+	if json.Unmarshal(content, &pkg) != nil {
+		return nil
+	}
+	var licenses []string
+	var license string
+	if json.Unmarshal(pkg.License, &license) == nil && license != "" && !strings.HasPrefix(license, "SEE LICENSE") {
+		licenses = append(licenses, license)
+	}
+	for _, l := range pkg.Licenses {
+		if l.Type != "" {
+			licenses = append(licenses, l.Type)
+		}
+	}
+	return licenses
+}
+
+// dependency returns the directory of the vendored dependency the file at
+// rel, relative to its project, belongs to, or "" if it is the project's
+// own: the file's directory, if it is inside a node_modules, vendor, or
+// third_party directory.
+func dependency(rel string) string {
+	dirs := strings.Split(path.Dir(rel), "/")
+	for i := len(dirs) - 1; i >= 0; i-- {
+		switch dirs[i] {
+		case "node_modules", "vendor", "third_party":
+			if i == len(dirs)-1 {
+				return ""
+			}
+			return path.Join(dirs...)
+		}
+	}
+	return ""
+}
+
+// Summary is the use of a license across the graveyard.
+type Summary struct {
+	// License is the license's SPDX identifier, or Unknown.
+	License string `json:"license"`
+	// Kind is the kind of obligations it carries.
+	Kind Kind `json:"kind"`
+	// Findings are where it was found, sorted by project and dependency.
+	Findings []Finding `json:"findings"`
+}
+
+// Report aggregates the licenses found in a graveyard.
+type Report struct {
+	// Licenses are the licenses found, those needing the most attention
+	// first: by kind, then by the number of places found.
+	Licenses []Summary `json:"licenses"`
+	// Unlicensed are the projects with no license of their own, which
+	// leaves all rights with their authors.
+	Unlicensed []string `json:"unlicensed"`
+}
+
+// Aggregate groups findings by license, and lists the projects of the
+// graveyard that have no license of their own.
+func Aggregate(projects []string, findings []Finding) *Report {
+	r := &Report{Licenses: []Summary{}, Unlicensed: []string{}}
+	byLicense := make(map[string]int)
+	licensed := make(map[string]bool)
+	for _, f := range findings {
+		if f.Dependency == "" {
+			licensed[f.Project] = true
+		}
+		i, ok := byLicense[f.License]
+		if !ok {
+			i = len(r.Licenses)
+			byLicense[f.License] = i
+			r.Licenses = append(r.Licenses, Summary{License: f.License, Kind: f.Kind})
+		}
+		r.Licenses[i].Findings = append(r.Licenses[i].Findings, f)
+	}
+	sort.SliceStable(r.Licenses, func(i, j int) bool {
+		a, b := r.Licenses[i], r.Licenses[j]
+		if a.Kind != b.Kind {
+			return kindOrder[a.Kind] < kindOrder[b.Kind]
+		}
+		if len(a.Findings) != len(b.Findings) {
+			return len(a.Findings) > len(b.Findings)
+		}
+		return a.License < b.License
+	})
+	for _, name := range projects {
+		if !licensed[name] {
+			r.Unlicensed = append(r.Unlicensed, name)
+		}
+	}
+	return r
+}
@@ -0,0 +1,153 @@
+package licenses
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/metadata"
+)
+
+const mitText = `MIT License
+
+Copyright (c) 2021 Someone
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction.
+`
+
+const gplText = `                    GNU GENERAL PUBLIC LICENSE
+                       Version 3, 29 June 2007
+
+ Copyright (C) 2007 Free Software Foundation, Inc. <https://fsf.org/>
+`
+
+func TestIdentify(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "MIT", text: mitText, want: "MIT"},
+		{name: "GPL naming the LGPL in its terms", text: gplText + strings.Repeat("terms ", 400) + "use the GNU Lesser General Public License instead", want: "GPL-3.0"},
+		{name: "LGPL", text: "GNU LESSER GENERAL PUBLIC LICENSE\nVersion 2.1, February 1999", want: "LGPL-2.1"},
+		{name: "Apache", text: "                                 Apache License\n                           Version 2.0, January 2004", want: "Apache-2.0"},
+		{name: "BSD 3-clause", text: "Redistribution and use in source and binary forms, with or without\nmodification, are permitted. Neither the name of the copyright holder", want: "BSD-3-Clause"},
+		{name: "BSD 2-clause", text: "Redistribution and use in source and binary forms, with or without\nmodification, are permitted.", want: "BSD-2-Clause"},
+		{name: "SPDX identifier", text: "// SPDX-License-Identifier: MPL-2.0\n", want: "MPL-2.0"},
+		{name: "unrecognized", text: "All rights reserved.\n", want: Unknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Identify(tt.text); got != tt.want {
+				t.Errorf("Identify() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		license string
+		want    Kind
+	}{
+		{"MIT", KindPermissive},
+		{"GPL-3.0-only", KindStrongCopyleft},
+		{"GPL-2.0-or-later", KindStrongCopyleft},
+		{"LGPL-2.1+", KindWeakCopyleft},
+		{"CC0-1.0", KindPublicDomain},
+		{"(MIT OR GPL-3.0)", KindStrongCopyleft},
+		{"Apache-2.0 AND MPL-2.0", KindWeakCopyleft},
+		{"GPL-2.0 WITH Classpath-exception-2.0", KindStrongCopyleft},
+		{"MIT AND Proprietary", KindUnknown},
+		{Unknown, KindUnknown},
+		{"", KindUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.license, func(t *testing.T) {
+			if got := KindOf(tt.license); got != tt.want {
+				t.Errorf("KindOf(%q) = %q, want %q", tt.license, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanAndAggregate(t *testing.T) {
+	dir := t.TempDir()
+	meta := (&metadata.Metadata{OriginalSource: "/src/project"}).Generate()
+	files := map[string]string{
+		"alpha/" + metadata.FileName:                    meta,
+		"alpha/LICENSE":                                 mitText,
+		"alpha/vendor/github.com/x/gpl/COPYING":         gplText,
+		"alpha/node_modules/left-pad/package.json":      `{"name": "left-pad", "license": "WTFPL"}`,
+		"alpha/node_modules/left-pad/LICENSE.md":        mitText,
+		"beta/" + metadata.FileName:                     meta,
+		"beta/package.json":                             `{"name": "beta", "license": "GPL-3.0-only"}`,
+		"beta/COPYING":                                  gplText,
+		"gamma/" + metadata.FileName:                    meta,
+		"gamma/main.go":                                 "package main\n",
+		"gamma/third_party/lib/LICENSE":                 mitText,
+		"not-a-project/LICENSE":                         mitText,
+		"alpha/docs/licenses-overview.md":               gplText,
+		"alpha/vendor/github.com/x/gpl/LICENSE-SUMMARY": gplText,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGit(t, dir, "init", "-q")
+	runGit(t, dir, "add", "-A")
+
+	gy := &graveyard.Graveyard{Path: dir}
+	findings, err := Scan(gy)
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	want := []Finding{
+		{Project: "alpha", License: "MIT", Kind: KindPermissive, File: "LICENSE"},
+		{Project: "alpha", Dependency: "node_modules/left-pad", License: "MIT", Kind: KindPermissive, File: "node_modules/left-pad/LICENSE.md"},
+		{Project: "alpha", Dependency: "node_modules/left-pad", License: "WTFPL", Kind: KindUnknown, File: "node_modules/left-pad/package.json"},
+		{Project: "alpha", Dependency: "vendor/github.com/x/gpl", License: "GPL-3.0", Kind: KindStrongCopyleft, File: "vendor/github.com/x/gpl/COPYING"},
+		{Project: "beta", License: "GPL-3.0", Kind: KindStrongCopyleft, File: "COPYING"},
+		{Project: "beta", License: "GPL-3.0-only", Kind: KindStrongCopyleft, File: "package.json"},
+		{Project: "gamma", Dependency: "third_party/lib", License: "MIT", Kind: KindPermissive, File: "third_party/lib/LICENSE"},
+	}
+	if !reflect.DeepEqual(findings, want) {
+		t.Fatalf("Scan() =\n%+v\nwant\n%+v", findings, want)
+	}
+
+	projects, err := gy.Projects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := Aggregate(projects, findings)
+	var order []string
+	for _, s := range r.Licenses {
+		order = append(order, s.License)
+	}
+	if want := []string{"GPL-3.0", "GPL-3.0-only", "WTFPL", "MIT"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("Aggregate() licenses = %v, want %v", order, want)
+	}
+	if want := []string{"gamma"}; !reflect.DeepEqual(r.Unlicensed, want) {
+		t.Errorf("Aggregate() unlicensed = %v, want %v", r.Unlicensed, want)
+	}
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
@@ -3,9 +3,16 @@ package metadata
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/deanhigh/bury-it/internal/atomicfile"
+	"github.com/deanhigh/bury-it/internal/history"
 )
 
 // Metadata contains information about an archived project.
@@ -16,11 +23,337 @@ type Metadata struct {
 	BuriedAt time.Time
 	// HistoryPreserved indicates whether git history was preserved.
 	HistoryPreserved bool
+	// ConvertedFrom is the version control system the history was converted
+	// to git from, such as Mercurial or Subversion, or "".
+	ConvertedFrom string
+	// Unversioned reports whether the source was a plain directory, never
+	// under version control, committed as a snapshot when it was buried.
+	Unversioned bool
+	// Branch is the branch that was buried when one other than the source's
+	// checked-out or default branch was chosen, or "".
+	Branch string
+	// Ref and RefCommit are the ref that was buried, when one was chosen,
+	// and the commit it named, or "".
+	Ref       string
+	RefCommit string
+	// Version is the number of the burial when the same project was buried
+	// more than once, or zero for a project buried once.
+	Version int
+	// Uncommitted lists work in the source that was not captured, if any.
+	Uncommitted *Uncommitted
+	// Refs are the branches and tags that existed in the source.
+	Refs []Ref
+	// History summarizes the source's commit activity, if recorded.
+	History *history.Summary
+	// ActivityImage is the name of an SVG sparkline of commit activity, if any.
+	ActivityImage string
+	// Issues summarizes the source's issues and pull requests, if recorded.
+	Issues *Issues
+	// Workflows summarizes the source's CI workflow runs, if recorded.
+	Workflows []Workflow
+	// Decommission lists infrastructure the source referred to, if a
+	// decommissioning checklist was written.
+	Decommission *Decommission
+	// Artifacts are the images and packages the source published, which
+	// registries may still host.
+	Artifacts []Artifact
+	// Submodules are the source's git submodules, at the commits it pinned.
+	Submodules []Submodule
+	// SubmodulesVendored reports whether the content of the submodules was
+	// copied into the project.
+	SubmodulesVendored bool
+	// LFS lists the source's files tracked with Git LFS, if any.
+	LFS *LFS
+	// Tags are labels attached to the project, e.g. "ml" or "2023".
+	Tags []string
+	// Owner is the team or person responsible for the project, if recorded.
+	Owner string
+	// ReviewAfter is the date after which the burial should be reviewed, or
+	// the zero time if no review was requested.
+	ReviewAfter time.Time
+	// InvalidReviewAfter is the review date as written in the metadata when
+	// it is not a date, such as after a hand edit, which leaves ReviewAfter
+	// the zero time.
+	InvalidReviewAfter string
+	// SupersededBy is the URL or project name of the project that replaced
+	// this one, if any.
+	SupersededBy string
+	// Supersedes is the URL or project name of the project this one
+	// replaced, if any.
+	Supersedes string
+	// MonthlyCostBefore is what the project cost to run each month before
+	// it was sunset, if recorded.
+	MonthlyCostBefore *float64
+	// MonthlyCostAfter is what the project still costs each month after it
+	// was sunset, if recorded.
+	MonthlyCostAfter *float64
+	// Description is the description the source's host gave the
+	// repository, if recorded.
+	Description string
+	// Topics are the topics the source's host gave the repository, if
+	// recorded.
+	Topics []string
+	// Release is the published release that was buried, if one was chosen.
+	Release *Release
+}
+
+// Release is a release published on the source repository's host, whose
+// tagged state was buried.
+type Release struct {
+	// Repository is the owner/name of the hosted repository.
+	Repository string
+	// Tag is the release's tag.
+	Tag string
+	// Name is the release's title, or "" if it has none.
+	Name string
+	// URL is the release's page.
+	URL string
+	// PublishedAt is when the release was published.
+	PublishedAt time.Time
+	// Notes are the release notes, as Markdown.
+	Notes string
+}
+
+// Issues is a cross-reference of the issues and pull requests that existed
+// on the source repository's host at burial time.
+type Issues struct {
+	// Repository is the owner/name of the hosted repository.
+	Repository string
+	// OpenIssues is the number of open issues.
+	OpenIssues int
+	// ClosedIssues is the number of closed issues.
+	ClosedIssues int
+	// OpenPullRequests is the number of open pull requests.
+	OpenPullRequests int
+	// ClosedPullRequests is the number of closed pull requests.
+	ClosedPullRequests int
+	// Open lists permalinks to the open issues and pull requests.
+	Open []IssueLink
+}
+
+// IssueLink is a permalink to a single issue or pull request.
+type IssueLink struct {
+	// Number is the issue or pull request number.
+	Number int
+	// Title is the issue or pull request title.
+	Title string
+	// URL is the permalink.
+	URL string
+	// PullRequest is true for pull requests.
+	PullRequest bool
+}
+
+// Workflow summarizes the runs of a CI workflow of the source repository.
+type Workflow struct {
+	// Name is the workflow name.
+	Name string
+	// Path is the workflow file.
+	Path string
+	// Disabled is true if the workflow was disabled at burial time.
+	Disabled bool
+	// Runs is the number of recorded runs.
+	Runs int
+	// LastSuccess is when the last successful run started, if any.
+	LastSuccess time.Time
+	// LastFailure is when the last failed run started, if any.
+	LastFailure time.Time
+	// Status is what the workflow's badge showed at burial time: the result
+	// of the latest completed run on the default branch, e.g. "passing".
+	Status string
+}
+
+// Decommission is the infrastructure referenced by the source repository,
+// which seeds the decommissioning checklist written with the burial.
+type Decommission struct {
+	// Endpoints are the hostnames, URLs, and resources found, grouped by kind.
+	Endpoints []Endpoint
+}
+
+// Endpoint is a hostname, URL, or cloud resource referenced by the source.
+type Endpoint struct {
+	// Kind is what the endpoint is, e.g. "Hostname" or "Terraform resource".
+	Kind string
+	// Value is the hostname, URL, resource address, or identifier.
+	Value string
+	// File is the source file that references the endpoint.
+	File string
+	// Line is the line number within File.
+	Line int
+}
+
+// Artifact is an image or package published by the source repository.
+type Artifact struct {
+	// Registry is the kind of registry, e.g. "Docker" or "npm".
+	Registry string
+	// Name is the image or package name, or empty for an unnamed image.
+	Name string
+	// File is the source file that references the artifact.
+	File string
+}
+
+// Submodule is a git submodule of the source repository.
+type Submodule struct {
+	// Path is the submodule's path in the project.
+	Path string
+	// URL is the URL the submodule was cloned from, if recorded.
+	URL string
+	// Commit is the commit of the submodule the source pinned.
+	Commit string
+}
+
+// LFS records what became of the source's files tracked with Git LFS, which
+// git itself only stores as pointer files.
+type LFS struct {
+	// Files are the files tracked with Git LFS.
+	Files []string
+	// Materialized reports whether the files' content replaced their pointer
+	// files in the project.
+	Materialized bool
+	// Objects is the number of LFS objects, of every version in the history,
+	// copied into the graveyard's LFS store.
+	Objects int
+	// Missing are the files whose content was not available to bury.
+	Missing []string
+}
+
+// Ref is a branch or tag that existed in the source repository.
+type Ref struct {
+	// Name is the branch or tag name.
+	Name string
+	// IsTag is true for tags and false for branches.
+	IsTag bool
+	// Commit is the commit hash the ref pointed to.
+	Commit string
+	// Date is the date of the commit or tag.
+	Date time.Time
+}
+
+// Uncommitted is an inventory of work in a source repository that existed
+// outside the committed snapshot at burial time.
+type Uncommitted struct {
+	// Stashes are the stash entries, e.g. "stash@{0}: WIP on main: fix".
+	Stashes []string
+	// Modified are tracked files with uncommitted changes.
+	Modified []string
+	// Untracked are files not tracked by git and not ignored.
+	Untracked []string
+	// Included are untracked files that were buried with the project.
+	Included []string
+	// PatchFile is the name of the file holding the captured changes, if any.
+	PatchFile string
+}
+
+// IsEmpty reports whether the inventory contains nothing.
+func (u *Uncommitted) IsEmpty() bool {
+	return u == nil || len(u.Stashes) == 0 && len(u.Modified) == 0 && len(u.Untracked) == 0 && len(u.Included) == 0
+}
+
+// LeftOut reports whether the inventory lists work that was not buried.
+func (u *Uncommitted) LeftOut() bool {
+	return u != nil && len(u.Stashes)+len(u.Modified)+len(u.Untracked) > 0
 }
 
 // FileName is the name of the metadata file.
 const FileName = ".bury-it.md"
 
+// ActivityImageFileName is the name of the commit activity sparkline image.
+const ActivityImageFileName = ".bury-it-activity.svg"
+
+// IssuesFileName is the name of the issue and pull request cross-reference file.
+const IssuesFileName = ".bury-it-issues.md"
+
+// ReleaseNotesFileName is the name of the file holding the notes of the
+// release that was buried.
+const ReleaseNotesFileName = ".bury-it-release.md"
+
+// IssueExportFileName is the name of the file holding every issue and pull
+// request of the source, one JSON object per line.
+const IssueExportFileName = ".bury-it-issues.jsonl"
+
+// IssueExportCheckpointFileName is the name of the file recording the progress
+// of an issue export, so that an interrupted export can resume.
+const IssueExportCheckpointFileName = ".bury-it-issues-export.json"
+
+// DecommissionFileName is the name of the decommissioning checklist.
+const DecommissionFileName = "DECOMMISSION.md"
+
+// UncommittedPatchFileName is the name of the file holding captured
+// uncommitted changes and stashes.
+const UncommittedPatchFileName = ".bury-it-uncommitted.patch"
+
+// HistoryPreservedField is the name of the main table row recording whether
+// the project's git history was preserved.
+const HistoryPreservedField = "History Preserved"
+
+// ConvertedFromField is the name of the main table row holding the version
+// control system the project was converted from.
+const ConvertedFromField = "Converted From"
+
+// VersionControlField is the name of the main table row recording that the
+// project was never under version control.
+const VersionControlField = "Version Control"
+
+// unversionedValue is the value of the VersionControlField row.
+const unversionedValue = "None; committed as a snapshot when buried"
+
+// BranchField is the name of the main table row holding the branch that was
+// buried, when one was chosen.
+const BranchField = "Branch"
+
+// RefField is the name of the main table row holding the ref that was
+// buried, when one was chosen, and the commit it named.
+const RefField = "Ref"
+
+// refPattern matches the ref row's value, a ref followed by the commit it
+// named in parentheses.
+var refPattern = regexp.MustCompile(`^(.+) \(([0-9a-f]{40,64})\)$`)
+
+// ReleaseField is the name of the main table row linking to the release
+// that was buried.
+const ReleaseField = "Release"
+
+// VersionField is the name of the main table row holding the number of a
+// re-burial.
+const VersionField = "Version"
+
+// TagsField is the name of the main table row holding the project's tags.
+const TagsField = "Tags"
+
+// OwnerField is the name of the main table row holding the team or person
+// responsible for the project.
+const OwnerField = "Owner"
+
+// ReviewAfterField is the name of the main table row holding the project's
+// review date.
+const ReviewAfterField = "Review After"
+
+// SupersededByField is the name of the main table row linking to the
+// project's replacement.
+const SupersededByField = "Superseded By"
+
+// SupersedesField is the name of the main table row linking to the project
+// this one replaced.
+const SupersedesField = "Supersedes"
+
+// MonthlyCostBeforeField is the name of the main table row holding the
+// project's monthly running cost before it was sunset.
+const MonthlyCostBeforeField = "Monthly Cost Before"
+
+// MonthlyCostAfterField is the name of the main table row holding the
+// project's monthly running cost after it was sunset.
+const MonthlyCostAfterField = "Monthly Cost After"
+
+// DescriptionField is the name of the main table row holding the
+// repository description recorded from the source's host.
+const DescriptionField = "Description"
+
+// TopicsField is the name of the main table row holding the repository
+// topics recorded from the source's host.
+const TopicsField = "Topics"
+
+// reviewDateFormat is the layout of the review date.
+const reviewDateFormat = "2006-01-02"
+
 // Generate generates the metadata content as a string.
 func (m *Metadata) Generate() string {
 	historyStr := "Yes"
@@ -28,25 +361,670 @@ func (m *Metadata) Generate() string {
 		historyStr = "No"
 	}
 
-	return fmt.Sprintf(`# Archived Project
+	var b strings.Builder
+	fmt.Fprintf(&b, `# Archived Project
 
 | Field | Value |
 |-------|-------|
 | **Original Source** | %s |
 | **Buried On** | %s |
 | **History Preserved** | %s |
+`, m.OriginalSource, m.BuriedAt.Format(time.RFC3339), historyStr)
+	if m.ConvertedFrom != "" {
+		fmt.Fprintf(&b, "| **%s** | %s |\n", ConvertedFromField, m.ConvertedFrom)
+	}
+	if m.Unversioned {
+		fmt.Fprintf(&b, "| **%s** | %s |\n", VersionControlField, unversionedValue)
+	}
+	if m.Branch != "" {
+		fmt.Fprintf(&b, "| **%s** | %s |\n", BranchField, tableCell(m.Branch))
+	}
+	if m.Ref != "" {
+		if m.RefCommit != "" && m.RefCommit != m.Ref {
+			fmt.Fprintf(&b, "| **%s** | %s (%s) |\n", RefField, tableCell(m.Ref), m.RefCommit)
+		} else {
+			fmt.Fprintf(&b, "| **%s** | %s |\n", RefField, tableCell(m.Ref))
+		}
+	}
+	if m.Version > 0 {
+		fmt.Fprintf(&b, "| **%s** | %d |\n", VersionField, m.Version)
+	}
+	if r := m.Release; r != nil {
+		fmt.Fprintf(&b, "| **%s** | [%s](%s), published %s ([notes](%s)) |\n",
+			ReleaseField, tableCell(r.Title()), r.URL, r.PublishedAt.Format(reviewDateFormat), ReleaseNotesFileName)
+	}
+	if m.Issues != nil {
+		fmt.Fprintf(&b, "| **Open Issues** | %d issues, %d pull requests ([details](%s)) |\n",
+			m.Issues.OpenIssues, m.Issues.OpenPullRequests, IssuesFileName)
+	}
+	if m.Decommission != nil {
+		if len(m.Decommission.Endpoints) > 0 {
+			fmt.Fprintf(&b, "| **Decommissioning** | %s ([checklist](%s)) |\n",
+				m.Decommission.Summary(), DecommissionFileName)
+		} else {
+			fmt.Fprintf(&b, "| **Decommissioning** | [checklist](%s) |\n", DecommissionFileName)
+		}
+	}
+	if m.Owner != "" {
+		fmt.Fprintf(&b, "| **%s** | %s |\n", OwnerField, m.Owner)
+	}
+	if len(m.Tags) > 0 {
+		fmt.Fprintf(&b, "| **%s** | %s |\n", TagsField, strings.Join(m.Tags, ", "))
+	}
+	if !m.ReviewAfter.IsZero() {
+		fmt.Fprintf(&b, "| **%s** | %s |\n", ReviewAfterField, m.ReviewAfter.Format(reviewDateFormat))
+	} else if m.InvalidReviewAfter != "" {
+		fmt.Fprintf(&b, "| **%s** | %s |\n", ReviewAfterField, m.InvalidReviewAfter)
+	}
+	if m.Supersedes != "" {
+		fmt.Fprintf(&b, "| **%s** | %s |\n", SupersedesField, m.Supersedes)
+	}
+	if m.SupersededBy != "" {
+		fmt.Fprintf(&b, "| **%s** | %s |\n", SupersededByField, m.SupersededBy)
+	}
+	if m.MonthlyCostBefore != nil {
+		fmt.Fprintf(&b, "| **%s** | %s |\n", MonthlyCostBeforeField, FormatCost(*m.MonthlyCostBefore))
+	}
+	if m.MonthlyCostAfter != nil {
+		fmt.Fprintf(&b, "| **%s** | %s |\n", MonthlyCostAfterField, FormatCost(*m.MonthlyCostAfter))
+	}
+	if m.Description != "" {
+		fmt.Fprintf(&b, "| **%s** | %s |\n", DescriptionField, tableCell(m.Description))
+	}
+	if len(m.Topics) > 0 {
+		fmt.Fprintf(&b, "| **%s** | %s |\n", TopicsField, strings.Join(m.Topics, ", "))
+	}
 
+	if !m.Uncommitted.IsEmpty() {
+		b.WriteString("\n## Uncommitted Work\n\n")
+		if m.Uncommitted.LeftOut() {
+			b.WriteString("The following existed in the source repository but was not part of the archived snapshot.\n")
+		} else {
+			b.WriteString("The untracked files of the source repository were buried with the project.\n")
+		}
+		writeList(&b, "Stashes", m.Uncommitted.Stashes)
+		writeList(&b, "Modified files", m.Uncommitted.Modified)
+		writeList(&b, "Untracked files", m.Uncommitted.Untracked)
+		writeList(&b, "Untracked files buried with the project", m.Uncommitted.Included)
+		if m.Uncommitted.PatchFile != "" {
+			fmt.Fprintf(&b, "\nChanges to tracked files and stashes were saved to `%s`.\n", m.Uncommitted.PatchFile)
+		}
+	}
+
+	if h := m.History; h != nil {
+		b.WriteString("\n## History Summary\n\n")
+		b.WriteString("| Metric | Value |\n")
+		b.WriteString("|--------|-------|\n")
+		fmt.Fprintf(&b, "| **First Commit** | %s |\n", h.FirstCommit.Format(time.RFC3339))
+		fmt.Fprintf(&b, "| **Last Commit** | %s |\n", h.LastCommit.Format(time.RFC3339))
+		fmt.Fprintf(&b, "| **Commits** | %d |\n", h.Commits)
+		fmt.Fprintf(&b, "| **Merges** | %d |\n", h.Merges)
+		fmt.Fprintf(&b, "| **Contributors** | %d |\n", h.Contributors)
+		b.WriteString("\n| Year | Commits |\n")
+		b.WriteString("|------|---------|\n")
+		for _, y := range h.PerYear {
+			fmt.Fprintf(&b, "| %d | %d |\n", y.Year, y.Commits)
+		}
+		if m.ActivityImage != "" {
+			fmt.Fprintf(&b, "\n![Commit activity](%s)\n", m.ActivityImage)
+		}
+	}
+
+	if len(m.Workflows) > 0 {
+		b.WriteString("\n## CI Workflows\n\n")
+		b.WriteString("| Workflow | File | Runs | Last Success | Last Failure | Status at Burial |\n")
+		b.WriteString("|----------|------|------|--------------|--------------|------------------|\n")
+		for _, w := range m.Workflows {
+			name := w.Name
+			if w.Disabled {
+				name += " (disabled)"
+			}
+			fmt.Fprintf(&b, "| %s | `%s` | %d | %s | %s | %s |\n",
+				name, w.Path, w.Runs, formatDate(w.LastSuccess), formatDate(w.LastFailure), w.Status)
+		}
+	}
+
+	if len(m.Artifacts) > 0 {
+		b.WriteString("\n## Published Artifacts\n\n")
+		b.WriteString("These registries may still host artifacts of this project.\n\n")
+		b.WriteString("| Registry | Artifact | Found In |\n")
+		b.WriteString("|----------|----------|----------|\n")
+		for _, a := range m.Artifacts {
+			name := "(unnamed image)"
+			if a.Name != "" {
+				name = "`" + a.Name + "`"
+			}
+			fmt.Fprintf(&b, "| %s | %s | `%s` |\n", a.Registry, name, a.File)
+		}
+	}
+
+	if len(m.Submodules) > 0 {
+		b.WriteString(submodulesSection(m.Submodules, m.SubmodulesVendored))
+	}
+
+	if m.LFS != nil {
+		b.WriteString("\n## Git LFS\n\n")
+		switch {
+		case m.LFS.Materialized:
+			b.WriteString("These files were tracked with Git LFS; their content replaced their pointer files in the project.\n")
+		case m.LFS.Objects > 0:
+			fmt.Fprintf(&b, "These files are tracked with Git LFS. %d objects from the source's history were copied into the graveyard's LFS store; install git-lfs in the graveyard to check them out, and push them with `git lfs push --all`.\n", m.LFS.Objects)
+		default:
+			b.WriteString("These files are tracked with Git LFS, whose objects were not buried; only their pointer files were.\n")
+		}
+		writeList(&b, "Files", m.LFS.Files)
+		writeList(&b, "Content not buried", m.LFS.Missing)
+	}
+
+	if len(m.Refs) > 0 {
+		b.WriteString("\n## Branches and Tags\n\n")
+		b.WriteString("| Type | Name | Commit | Date |\n")
+		b.WriteString("|------|------|--------|------|\n")
+		for _, ref := range m.Refs {
+			refType := "Branch"
+			if ref.IsTag {
+				refType = "Tag"
+			}
+			fmt.Fprintf(&b, "| %s | `%s` | `%s` | %s |\n", refType, ref.Name, ref.Commit, formatDate(ref.Date))
+		}
+	}
+
+	b.WriteString(`
 ---
 
 *This project was archived using [bury-it](https://github.com/deanhigh/bury-it).*
-`, m.OriginalSource, m.BuriedAt.Format(time.RFC3339), historyStr)
+`)
+	return b.String()
+}
+
+// Title returns the release's name, with its tag if that differs.
+func (r *Release) Title() string {
+	switch r.Name {
+	case "", r.Tag:
+		return r.Tag
+	}
+	return fmt.Sprintf("%s (%s)", r.Name, r.Tag)
+}
+
+// Generate generates the release notes content as a string.
+func (r *Release) Generate() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\nRelease %s of %s, published on %s: %s\n", r.Title(), r.Tag, r.Repository, r.PublishedAt.Format(time.RFC3339), r.URL)
+	if notes := strings.TrimSpace(strings.ReplaceAll(r.Notes, "\r\n", "\n")); notes != "" {
+		fmt.Fprintf(&b, "\n%s\n", notes)
+	} else {
+		b.WriteString("\nThe release was published without notes.\n")
+	}
+	return b.String()
+}
+
+// Generate generates the issue cross-reference content as a string.
+func (i *Issues) Generate(at time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `# Issues and Pull Requests
+
+Snapshot of %s taken on %s.
+
+| | Open | Closed |
+|-|------|--------|
+| **Issues** | %d | %d |
+| **Pull Requests** | %d | %d |
+`, i.Repository, at.Format(time.RFC3339), i.OpenIssues, i.ClosedIssues, i.OpenPullRequests, i.ClosedPullRequests)
+
+	for _, section := range []struct {
+		title       string
+		pullRequest bool
+	}{
+		{"Open Issues", false},
+		{"Open Pull Requests", true},
+	} {
+		var links []IssueLink
+		for _, link := range i.Open {
+			if link.PullRequest == section.pullRequest {
+				links = append(links, link)
+			}
+		}
+		if len(links) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", section.title)
+		for _, link := range links {
+			fmt.Fprintf(&b, "- [#%d %s](%s)\n", link.Number, link.Title, link.URL)
+		}
+	}
+	return b.String()
+}
+
+// Summary counts the endpoints of each kind, e.g. "2 Terraform resources,
+// 1 Hostname".
+func (d *Decommission) Summary() string {
+	var kinds []string
+	counts := make(map[string]int)
+	for _, e := range d.Endpoints {
+		if counts[e.Kind] == 0 {
+			kinds = append(kinds, e.Kind)
+		}
+		counts[e.Kind]++
+	}
+	parts := make([]string, len(kinds))
+	for i, kind := range kinds {
+		if counts[kind] == 1 {
+			parts[i] = "1 " + kind
+		} else {
+			parts[i] = fmt.Sprintf("%d %ss", counts[kind], kind)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// TombstoneTitle is the title of the issue opened on an archived repository.
+const TombstoneTitle = "This repository has been archived"
+
+// TombstoneBody generates the body of the issue that tells visitors of the
+// original repository where the project was buried.
+func (m *Metadata) TombstoneBody(project, graveyardLocation string) string {
+	historyStr := "Yes"
+	if !m.HistoryPreserved {
+		historyStr = "No"
+	}
+	return fmt.Sprintf(`This repository has been archived and is no longer maintained.
+
+Its contents were buried in a graveyard repository using [bury-it](https://github.com/deanhigh/bury-it).
+
+| Field | Value |
+|-------|-------|
+| **Graveyard** | %s |
+| **Project Directory** | %s |
+| **Buried On** | %s |
+| **History Preserved** | %s |
+`, graveyardLocation, project, m.BuriedAt.Format(time.RFC3339), historyStr)
+}
+
+// writeList writes a titled bullet list with a count, skipping empty lists.
+func writeList(b *strings.Builder, title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n**%s (%d)**\n\n", title, len(items))
+	for _, item := range items {
+		fmt.Fprintf(b, "- `%s`\n", item)
+	}
+}
+
+// formatDate formats t for a metadata table, leaving the zero time empty.
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// tableCell returns text as it can be written to a table cell: on one line,
+// with pipes escaped.
+func tableCell(text string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(text), " "), "|", `\|`)
+}
+
+// mainTableHeader is the header row of the main metadata table.
+const mainTableHeader = "| Field | Value |"
+
+// mainTable returns the line range [start, end) of the rows of the main
+// metadata table, excluding its header and separator, or ok=false if the
+// content has no main table.
+func mainTable(lines []string) (start, end int, ok bool) {
+	for i, line := range lines {
+		if strings.TrimSpace(line) != mainTableHeader {
+			continue
+		}
+		start = i + 2
+		if start > len(lines) {
+			return 0, 0, false
+		}
+		end = start
+		for end < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[end]), "|") {
+			end++
+		}
+		return start, end, true
+	}
+	return 0, 0, false
+}
+
+// rowKey returns the bolded key of a "| **Key** | Value |" row.
+func rowKey(line string) (key, value string, ok bool) {
+	cells := strings.SplitN(strings.Trim(strings.TrimSpace(line), "|"), "|", 2)
+	if len(cells) != 2 {
+		return "", "", false
+	}
+	key = strings.TrimSpace(cells[0])
+	if !strings.HasPrefix(key, "**") || !strings.HasSuffix(key, "**") || len(key) < 4 {
+		return "", "", false
+	}
+	return key[2 : len(key)-2], strings.TrimSpace(cells[1]), true
+}
+
+// Field returns the value of a row in the main table of metadata content.
+func Field(content, key string) (string, bool) {
+	lines := strings.Split(content, "\n")
+	start, end, ok := mainTable(lines)
+	if !ok {
+		return "", false
+	}
+	for _, line := range lines[start:end] {
+		if k, v, ok := rowKey(line); ok && k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// SetField returns content with the main table row for key set to value. A
+// missing row is appended to the end of the table, and an empty value removes
+// the row.
+func SetField(content, key, value string) string {
+	lines := strings.Split(content, "\n")
+	start, end, ok := mainTable(lines)
+	if !ok {
+		return content
+	}
+
+	row := fmt.Sprintf("| **%s** | %s |", key, value)
+	for i := start; i < end; i++ {
+		if k, _, ok := rowKey(lines[i]); ok && k == key {
+			if value == "" {
+				lines = append(lines[:i], lines[i+1:]...)
+			} else {
+				lines[i] = row
+			}
+			return strings.Join(lines, "\n")
+		}
+	}
+	if value == "" {
+		return content
+	}
+	lines = append(lines[:end], append([]string{row}, lines[end:]...)...)
+	return strings.Join(lines, "\n")
+}
+
+// Parse restores the fields of the main table from metadata content. Sections
+// below the main table are not parsed.
+func Parse(content string) (*Metadata, error) {
+	if _, _, ok := mainTable(strings.Split(content, "\n")); !ok {
+		return nil, fmt.Errorf("metadata has no field table")
+	}
+
+	m := &Metadata{}
+	m.OriginalSource, _ = Field(content, "Original Source")
+	if buriedOn, ok := Field(content, "Buried On"); ok {
+		t, err := time.Parse(time.RFC3339, buriedOn)
+		if err != nil {
+			return nil, fmt.Errorf("invalid burial date %q: %w", buriedOn, err)
+		}
+		m.BuriedAt = t
+	}
+	historyStr, _ := Field(content, HistoryPreservedField)
+	m.HistoryPreserved = historyStr == "Yes"
+	m.ConvertedFrom, _ = Field(content, ConvertedFromField)
+	versionControl, _ := Field(content, VersionControlField)
+	m.Unversioned = versionControl == unversionedValue
+	m.Branch, _ = Field(content, BranchField)
+	if ref, ok := Field(content, RefField); ok {
+		m.Ref = ref
+		if matches := refPattern.FindStringSubmatch(ref); matches != nil {
+			m.Ref, m.RefCommit = matches[1], matches[2]
+		}
+	}
+	if version, ok := Field(content, VersionField); ok {
+		n, err := strconv.Atoi(version)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid version %q", version)
+		}
+		m.Version = n
+	}
+	m.Owner, _ = Field(content, OwnerField)
+	if tags, ok := Field(content, TagsField); ok {
+		m.Tags = ParseTags(tags)
+	}
+	if reviewAfter, ok := Field(content, ReviewAfterField); ok {
+		// A review date that is not a date is kept for the caller to report,
+		// rather than making the whole project unreadable
+		if t, err := parseReviewDate(reviewAfter); err == nil {
+			m.ReviewAfter = t
+		} else {
+			m.InvalidReviewAfter = reviewAfter
+		}
+	}
+	m.Supersedes, _ = Field(content, SupersedesField)
+	m.SupersededBy, _ = Field(content, SupersededByField)
+	if description, ok := Field(content, DescriptionField); ok {
+		m.Description = strings.ReplaceAll(description, `\|`, "|")
+	}
+	if topics, ok := Field(content, TopicsField); ok {
+		m.Topics = ParseTags(topics)
+	}
+	for _, cost := range []struct {
+		key   string
+		value **float64
+	}{
+		{MonthlyCostBeforeField, &m.MonthlyCostBefore},
+		{MonthlyCostAfterField, &m.MonthlyCostAfter},
+	} {
+		if s, ok := Field(content, cost.key); ok {
+			v, err := ParseCost(s)
+			if err != nil {
+				return nil, err
+			}
+			*cost.value = &v
+		}
+	}
+	m.Submodules, m.SubmodulesVendored = parseSubmodules(content)
+	return m, nil
+}
+
+// parseReviewDate parses a review date, written as a date or, as hand edits
+// sometimes leave it, a full timestamp.
+func parseReviewDate(s string) (time.Time, error) {
+	if t, err := time.Parse(reviewDateFormat, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// Read reads and parses the metadata file in the specified directory.
+func Read(dir string) (*Metadata, error) {
+	content, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata file: %w", err)
+	}
+	return Parse(string(content))
+}
+
+// UpdateField sets a main table row in the metadata file in the specified
+// directory. An empty value removes the row.
+func UpdateField(dir, key, value string) error {
+	filePath := filepath.Join(dir, FileName)
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read metadata file: %w", err)
+	}
+	if _, _, ok := mainTable(strings.Split(string(content), "\n")); !ok {
+		return fmt.Errorf("metadata file %s has no field table", filePath)
+	}
+	updated := SetField(string(content), key, value)
+	if err := atomicfile.Write(filePath, []byte(updated), 0644); err != nil {
+		return fmt.Errorf("failed to write metadata file: %w", err)
+	}
+	return nil
+}
+
+// submodulesHeading starts the section listing the source's submodules.
+const submodulesHeading = "\n## Submodules\n"
+
+// submodulesSection returns the section listing submodules, saying whether
+// their content was copied into the project.
+func submodulesSection(submodules []Submodule, vendored bool) string {
+	var b strings.Builder
+	b.WriteString(submodulesHeading + "\n")
+	if vendored {
+		b.WriteString("The content of these submodules was copied into the project.\n\n")
+	} else {
+		b.WriteString("These submodules were not buried; their directories are empty.\n\n")
+	}
+	b.WriteString("| Path | URL | Commit |\n")
+	b.WriteString("|------|-----|--------|\n")
+	for _, sub := range submodules {
+		fmt.Fprintf(&b, "| `%s` | %s | `%s` |\n", sub.Path, tableCell(sub.URL), sub.Commit)
+	}
+	return b.String()
+}
+
+// submoduleRowPattern matches a row of the submodules section.
+var submoduleRowPattern = regexp.MustCompile("^\\| `([^`]+)` \\| (.*) \\| `([0-9a-f]+)` \\|$")
+
+// parseSubmodules reads the submodules section, reporting whether their
+// content was copied into the project.
+func parseSubmodules(content string) ([]Submodule, bool) {
+	start := strings.Index(content, submodulesHeading)
+	if start < 0 {
+		return nil, false
+	}
+	var submodules []Submodule
+	vendored := false
+	for _, line := range strings.Split(content[start+len(submodulesHeading):], "\n") {
+		if strings.HasPrefix(line, "## ") || line == "---" {
+			break
+		}
+		if strings.Contains(line, "copied into the project") {
+			vendored = true
+		}
+		if matches := submoduleRowPattern.FindStringSubmatch(line); matches != nil {
+			url := strings.ReplaceAll(strings.TrimSpace(matches[2]), `\|`, "|")
+			submodules = append(submodules, Submodule{Path: matches[1], URL: url, Commit: matches[3]})
+		}
+	}
+	return submodules, vendored
+}
+
+// SetSubmodulesVendored rewrites the submodules section of the metadata file
+// in dir to list submodules as copied into the project, adding the section
+// before the footer if the burial predates it.
+func SetSubmodulesVendored(dir string, submodules []Submodule) error {
+	filePath := filepath.Join(dir, FileName)
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read metadata file: %w", err)
+	}
+	text := string(content)
+	section := submodulesSection(submodules, true)
+	if start := strings.Index(text, submodulesHeading); start >= 0 {
+		end := len(text)
+		for _, next := range []string{"\n## ", "\n---\n"} {
+			if i := strings.Index(text[start+len(submodulesHeading):], next); i >= 0 {
+				end = min(end, start+len(submodulesHeading)+i)
+			}
+		}
+		text = text[:start] + section + text[end:]
+	} else if i := strings.LastIndex(text, "\n---\n"); i >= 0 {
+		text = text[:i] + section + text[i:]
+	} else {
+		text += section
+	}
+	if err := atomicfile.Write(filePath, []byte(text), 0644); err != nil {
+		return fmt.Errorf("failed to write metadata file: %w", err)
+	}
+	return nil
+}
+
+// CheckValue checks that value can be stored in a main table row.
+func CheckValue(value string) error {
+	if strings.ContainsAny(value, "|\r\n") {
+		return fmt.Errorf("invalid value %q: values cannot contain pipes or line breaks", value)
+	}
+	return nil
+}
+
+// ParseTags splits a comma-separated tag list.
+func ParseTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// NormalizeTag lowercases a tag and checks that it can be stored in the tag
+// list.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return "", fmt.Errorf("tag cannot be empty")
+	}
+	if strings.ContainsAny(tag, ",| \t") {
+		return "", fmt.Errorf("invalid tag %q: tags cannot contain commas, pipes, or whitespace", tag)
+	}
+	return tag, nil
+}
+
+// HasTags reports whether the project carries every one of tags.
+func (m *Metadata) HasTags(tags ...string) bool {
+	for _, want := range tags {
+		found := false
+		for _, tag := range m.Tags {
+			if strings.EqualFold(tag, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// ReviewDue reports whether the project's review date has been reached at now.
+func (m *Metadata) ReviewDue(now time.Time) bool {
+	return !m.ReviewAfter.IsZero() && !now.Before(m.ReviewAfter)
+}
+
+// MonthlySavings returns how much less the project costs each month since it
+// was sunset. It reports false if no cost before the sunset was recorded; a
+// missing cost after the sunset counts as zero.
+func (m *Metadata) MonthlySavings() (float64, bool) {
+	if m.MonthlyCostBefore == nil {
+		return 0, false
+	}
+	savings := *m.MonthlyCostBefore
+	if m.MonthlyCostAfter != nil {
+		savings -= *m.MonthlyCostAfter
+	}
+	return savings, true
+}
+
+// ParseCost parses a monthly cost, e.g. "420" or "99.50". Costs are plain
+// amounts in whatever currency the graveyard uses and cannot be negative.
+func ParseCost(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("invalid cost %q: must be a number", s)
+	}
+	if v < 0 {
+		return 0, fmt.Errorf("invalid cost %q: cannot be negative", s)
+	}
+	return v, nil
+}
+
+// FormatCost formats a monthly cost as it is stored in the main table.
+func FormatCost(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
 }
 
 // Write writes the metadata file to the specified directory.
 func (m *Metadata) Write(dir string) error {
 	filePath := filepath.Join(dir, FileName)
 	content := m.Generate()
-	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+	if err := atomicfile.Write(filePath, []byte(content), 0644); err != nil {
 		return fmt.Errorf("failed to write metadata file: %w", err)
 	}
 	return nil
@@ -6,6 +6,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/deanhigh/bury-it/internal/history"
 )
 
 func TestMetadata_Generate(t *testing.T) {
@@ -42,6 +44,197 @@ func TestMetadata_Generate(t *testing.T) {
 				"2025-12-26T10:30:00Z",
 				"**History Preserved** | No",
 			},
+			wantNotContains: []string{
+				"## Uncommitted Work",
+			},
+		},
+		{
+			name: "with uncommitted work",
+			meta: &Metadata{
+				OriginalSource:   "/path/to/local/repo",
+				BuriedAt:         fixedTime,
+				HistoryPreserved: true,
+				Uncommitted: &Uncommitted{
+					Stashes:   []string{"stash@{0}: WIP on main: abc123 fix"},
+					Untracked: []string{"notes.txt", "scratch/data.csv"},
+					PatchFile: UncommittedPatchFileName,
+				},
+			},
+			wantContains: []string{
+				"## Uncommitted Work",
+				"**Stashes (1)**",
+				"- `stash@{0}: WIP on main: abc123 fix`",
+				"**Untracked files (2)**",
+				"- `scratch/data.csv`",
+				"saved to `.bury-it-uncommitted.patch`",
+			},
+			wantNotContains: []string{
+				"**Modified files",
+			},
+		},
+		{
+			name: "with untracked files buried",
+			meta: &Metadata{
+				OriginalSource:   "/path/to/local/repo",
+				BuriedAt:         fixedTime,
+				HistoryPreserved: true,
+				Uncommitted: &Uncommitted{
+					Included: []string{"scratch/plot.py"},
+				},
+			},
+			wantContains: []string{
+				"## Uncommitted Work",
+				"were buried with the project",
+				"**Untracked files buried with the project (1)**",
+				"- `scratch/plot.py`",
+			},
+			wantNotContains: []string{
+				"was not part of the archived snapshot",
+			},
+		},
+		{
+			name: "with branch and tag inventory",
+			meta: &Metadata{
+				OriginalSource: "https://github.com/owner/repo",
+				BuriedAt:       fixedTime,
+				Refs: []Ref{
+					{Name: "main", Commit: "abc123", Date: fixedTime},
+					{Name: "v1.0.0", IsTag: true, Commit: "def456"},
+				},
+			},
+			wantContains: []string{
+				"## Branches and Tags",
+				"| Branch | `main` | `abc123` | 2025-12-26T10:30:00Z |",
+				"| Tag | `v1.0.0` | `def456` |  |",
+			},
+		},
+		{
+			name: "with CI workflows",
+			meta: &Metadata{
+				OriginalSource: "https://github.com/owner/repo",
+				BuriedAt:       fixedTime,
+				Workflows: []Workflow{
+					{Name: "CI", Path: ".github/workflows/ci.yml", Runs: 12, LastSuccess: fixedTime, Status: "passing"},
+					{Name: "Release", Path: ".github/workflows/release.yml", Disabled: true, Status: "no status"},
+				},
+			},
+			wantContains: []string{
+				"## CI Workflows",
+				"| CI | `.github/workflows/ci.yml` | 12 | 2025-12-26T10:30:00Z |  | passing |",
+				"| Release (disabled) | `.github/workflows/release.yml` | 0 |  |  | no status |",
+			},
+		},
+		{
+			name: "with published artifacts",
+			meta: &Metadata{
+				OriginalSource: "https://github.com/owner/repo",
+				BuriedAt:       fixedTime,
+				Artifacts: []Artifact{
+					{Registry: "Docker", Name: "ghcr.io/owner/repo", File: ".goreleaser.yml"},
+					{Registry: "Docker", File: "Dockerfile"},
+					{Registry: "npm", Name: "@owner/repo", File: "web/package.json"},
+				},
+			},
+			wantContains: []string{
+				"## Published Artifacts",
+				"| Docker | `ghcr.io/owner/repo` | `.goreleaser.yml` |",
+				"| Docker | (unnamed image) | `Dockerfile` |",
+				"| npm | `@owner/repo` | `web/package.json` |",
+			},
+		},
+		{
+			name: "with submodules",
+			meta: &Metadata{
+				OriginalSource: "https://github.com/owner/repo",
+				BuriedAt:       fixedTime,
+				Submodules: []Submodule{
+					{Path: "vendor/lib", URL: "https://github.com/owner/lib.git", Commit: "abc123"},
+					{Path: "docs", Commit: "def456"},
+				},
+			},
+			wantContains: []string{
+				"## Submodules",
+				"directories are empty",
+				"| `vendor/lib` | https://github.com/owner/lib.git | `abc123` |",
+				"| `docs` |  | `def456` |",
+			},
+		},
+		{
+			name: "with vendored submodules",
+			meta: &Metadata{
+				OriginalSource:     "https://github.com/owner/repo",
+				BuriedAt:           fixedTime,
+				Submodules:         []Submodule{{Path: "vendor/lib", URL: "../lib.git", Commit: "abc123"}},
+				SubmodulesVendored: true,
+			},
+			wantContains: []string{
+				"copied into the project",
+				"| `vendor/lib` | ../lib.git | `abc123` |",
+			},
+		},
+		{
+			name: "with materialized LFS files",
+			meta: &Metadata{
+				OriginalSource: "https://github.com/owner/repo",
+				BuriedAt:       fixedTime,
+				LFS:            &LFS{Files: []string{"assets/logo.psd", "model.bin"}, Materialized: true, Missing: []string{"model.bin"}},
+			},
+			wantContains: []string{
+				"## Git LFS",
+				"replaced their pointer files",
+				"**Files (2)**",
+				"- `assets/logo.psd`",
+				"**Content not buried (1)**",
+			},
+		},
+		{
+			name: "with migrated LFS objects",
+			meta: &Metadata{
+				OriginalSource:   "https://github.com/owner/repo",
+				BuriedAt:         fixedTime,
+				HistoryPreserved: true,
+				LFS:              &LFS{Files: []string{"model.bin"}, Objects: 3},
+			},
+			wantContains: []string{
+				"3 objects from the source's history were copied into the graveyard's LFS store",
+				"- `model.bin`",
+			},
+		},
+		{
+			name: "with LFS pointers only",
+			meta: &Metadata{
+				OriginalSource: "https://github.com/owner/repo",
+				BuriedAt:       fixedTime,
+				LFS:            &LFS{Files: []string{"model.bin"}},
+			},
+			wantContains: []string{
+				"only their pointer files were",
+			},
+		},
+		{
+			name: "with history summary",
+			meta: &Metadata{
+				OriginalSource: "https://github.com/owner/repo",
+				BuriedAt:       fixedTime,
+				History: &history.Summary{
+					FirstCommit:  fixedTime.AddDate(-2, 0, 0),
+					LastCommit:   fixedTime,
+					Commits:      42,
+					Merges:       3,
+					Contributors: 2,
+					PerYear:      []history.YearCount{{Year: 2023, Commits: 40}, {Year: 2025, Commits: 2}},
+				},
+				ActivityImage: ActivityImageFileName,
+			},
+			wantContains: []string{
+				"## History Summary",
+				"| **First Commit** | 2023-12-26T10:30:00Z |",
+				"| **Commits** | 42 |",
+				"| **Merges** | 3 |",
+				"| **Contributors** | 2 |",
+				"| 2023 | 40 |",
+				"![Commit activity](.bury-it-activity.svg)",
+			},
 		},
 	}
 
@@ -113,3 +306,472 @@ func TestMetadata_Write_NonExistentDir(t *testing.T) {
 		t.Errorf("Write() expected error for non-existent directory, got nil")
 	}
 }
+
+func TestDecommission_Summary(t *testing.T) {
+	d := &Decommission{Endpoints: []Endpoint{
+		{Kind: "Terraform resource", Value: "aws_s3_bucket.assets", File: "infra/main.tf", Line: 1},
+		{Kind: "Terraform resource", Value: "aws_sqs_queue.jobs", File: "infra/queue.tf", Line: 3},
+		{Kind: "Hostname", Value: "api.widget.io", File: "app.env", Line: 2},
+	}}
+
+	if got, want := d.Summary(), "2 Terraform resources, 1 Hostname"; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+
+	meta := &Metadata{OriginalSource: "/src/widget", Decommission: d}
+	if want := "| **Decommissioning** | 2 Terraform resources, 1 Hostname ([checklist](DECOMMISSION.md)) |"; !strings.Contains(meta.Generate(), want) {
+		t.Errorf("Generate() missing %q", want)
+	}
+	meta.Decommission = &Decommission{}
+	if want := "| **Decommissioning** | [checklist](DECOMMISSION.md) |"; !strings.Contains(meta.Generate(), want) {
+		t.Errorf("Generate() missing %q", want)
+	}
+}
+
+func TestIssues_Generate(t *testing.T) {
+	issues := &Issues{
+		Repository:         "owner/repo",
+		OpenIssues:         1,
+		ClosedIssues:       5,
+		OpenPullRequests:   1,
+		ClosedPullRequests: 9,
+		Open: []IssueLink{
+			{Number: 4, Title: "Crash on start", URL: "https://github.com/owner/repo/issues/4"},
+			{Number: 7, Title: "Add docs", URL: "https://github.com/owner/repo/pull/7", PullRequest: true},
+		},
+	}
+
+	got := issues.Generate(time.Date(2025, 12, 26, 10, 30, 0, 0, time.UTC))
+	for _, want := range []string{
+		"Snapshot of owner/repo taken on 2025-12-26T10:30:00Z.",
+		"| **Issues** | 1 | 5 |",
+		"| **Pull Requests** | 1 | 9 |",
+		"## Open Issues\n\n- [#4 Crash on start](https://github.com/owner/repo/issues/4)",
+		"## Open Pull Requests\n\n- [#7 Add docs](https://github.com/owner/repo/pull/7)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Generate() missing expected content: %q\n\nGot:\n%s", want, got)
+		}
+	}
+
+	meta := &Metadata{OriginalSource: "https://github.com/owner/repo", Issues: issues}
+	if want := "| **Open Issues** | 1 issues, 1 pull requests ([details](.bury-it-issues.md)) |"; !strings.Contains(meta.Generate(), want) {
+		t.Errorf("Metadata.Generate() missing %q", want)
+	}
+}
+
+func TestRelease_Generate(t *testing.T) {
+	release := &Release{
+		Repository:  "owner/repo",
+		Tag:         "v2.0.0",
+		Name:        "Final release",
+		URL:         "https://github.com/owner/repo/releases/tag/v2.0.0",
+		PublishedAt: time.Date(2025, 12, 26, 10, 30, 0, 0, time.UTC),
+		Notes:       "Last one.\r\n\r\n- Fix crash\r\n",
+	}
+
+	got := release.Generate()
+	for _, want := range []string{
+		"# Final release (v2.0.0)",
+		"Release v2.0.0 of owner/repo, published on 2025-12-26T10:30:00Z: https://github.com/owner/repo/releases/tag/v2.0.0",
+		"Last one.\n\n- Fix crash\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Generate() missing expected content: %q\n\nGot:\n%s", want, got)
+		}
+	}
+	if got := (&Release{Tag: "v1"}).Generate(); !strings.Contains(got, "# v1\n") || !strings.Contains(got, "published without notes") {
+		t.Errorf("Generate() without name or notes = %q", got)
+	}
+
+	meta := &Metadata{OriginalSource: "https://github.com/owner/repo", Release: release}
+	if want := "| **Release** | [Final release (v2.0.0)](https://github.com/owner/repo/releases/tag/v2.0.0), published 2025-12-26 ([notes](.bury-it-release.md)) |"; !strings.Contains(meta.Generate(), want) {
+		t.Errorf("Metadata.Generate() missing %q", want)
+	}
+}
+
+func TestMetadata_TombstoneBody(t *testing.T) {
+	meta := &Metadata{
+		OriginalSource:   "https://github.com/owner/repo",
+		BuriedAt:         time.Date(2025, 12, 26, 10, 30, 0, 0, time.UTC),
+		HistoryPreserved: false,
+	}
+
+	got := meta.TombstoneBody("repo", "git@github.com:owner/graveyard.git")
+	for _, want := range []string{
+		"| **Graveyard** | git@github.com:owner/graveyard.git |",
+		"| **Project Directory** | repo |",
+		"| **Buried On** | 2025-12-26T10:30:00Z |",
+		"| **History Preserved** | No |",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("TombstoneBody() missing %q\n\nGot:\n%s", want, got)
+		}
+	}
+}
+
+func TestSetFieldAndParse(t *testing.T) {
+	meta := &Metadata{
+		OriginalSource:   "https://github.com/owner/repo",
+		BuriedAt:         time.Date(2025, 12, 26, 10, 30, 0, 0, time.UTC),
+		HistoryPreserved: true,
+		Refs:             []Ref{{Name: "main", Commit: "abc123"}},
+	}
+	content := meta.Generate()
+
+	tests := []struct {
+		name     string
+		key      string
+		value    string
+		wantTags []string
+		wantRow  string
+	}{
+		{name: "add row", key: TagsField, value: "ml, 2023", wantTags: []string{"ml", "2023"}, wantRow: "| **History Preserved** | Yes |\n| **Tags** | ml, 2023 |\n\n## Branches and Tags"},
+		{name: "remove row", key: TagsField, value: "", wantTags: nil, wantRow: "| **History Preserved** | Yes |\n\n## Branches and Tags"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content = SetField(content, tt.key, tt.value)
+			if !strings.Contains(content, tt.wantRow) {
+				t.Errorf("SetField() content missing %q\n\nGot:\n%s", tt.wantRow, content)
+			}
+
+			got, err := Parse(content)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got.OriginalSource != meta.OriginalSource || !got.BuriedAt.Equal(meta.BuriedAt) || !got.HistoryPreserved {
+				t.Errorf("Parse() = %+v, want fields of %+v", got, meta)
+			}
+			if strings.Join(got.Tags, ",") != strings.Join(tt.wantTags, ",") {
+				t.Errorf("Parse() tags = %v, want %v", got.Tags, tt.wantTags)
+			}
+		})
+	}
+
+	if _, err := Parse("# Not metadata\n"); err == nil {
+		t.Errorf("Parse() expected error for content without a field table")
+	}
+}
+
+func TestNormalizeTagAndHasTags(t *testing.T) {
+	tests := []struct {
+		tag     string
+		want    string
+		wantErr bool
+	}{
+		{tag: " ML ", want: "ml"},
+		{tag: "2023", want: "2023"},
+		{tag: "", wantErr: true},
+		{tag: "a,b", wantErr: true},
+		{tag: "two words", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeTag(tt.tag)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("NormalizeTag(%q) = %q, %v, want %q, wantErr %v", tt.tag, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	meta := &Metadata{Tags: []string{"ml", "2023"}}
+	if !meta.HasTags("ML", "2023") || !meta.HasTags() || meta.HasTags("ml", "web") {
+		t.Errorf("HasTags() gave unexpected results for %v", meta.Tags)
+	}
+}
+
+func TestReviewAfter(t *testing.T) {
+	reviewAfter := time.Date(2027, 12, 26, 0, 0, 0, 0, time.UTC)
+	meta := &Metadata{
+		OriginalSource: "https://github.com/owner/repo",
+		BuriedAt:       time.Date(2025, 12, 26, 10, 30, 0, 0, time.UTC),
+		ReviewAfter:    reviewAfter,
+	}
+
+	content := meta.Generate()
+	if want := "| **Review After** | 2027-12-26 |"; !strings.Contains(content, want) {
+		t.Errorf("Generate() missing %q\n\nGot:\n%s", want, content)
+	}
+	got, err := Parse(content)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !got.ReviewAfter.Equal(reviewAfter) {
+		t.Errorf("Parse() ReviewAfter = %v, want %v", got.ReviewAfter, reviewAfter)
+	}
+
+	tests := []struct {
+		name string
+		meta *Metadata
+		now  time.Time
+		want bool
+	}{
+		{name: "before review date", meta: meta, now: reviewAfter.AddDate(0, 0, -1), want: false},
+		{name: "on review date", meta: meta, now: reviewAfter, want: true},
+		{name: "after review date", meta: meta, now: reviewAfter.AddDate(1, 0, 0), want: true},
+		{name: "no review date", meta: &Metadata{}, now: reviewAfter, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.meta.ReviewDue(tt.now); got != tt.want {
+				t.Errorf("ReviewDue(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+
+	lenient := []struct {
+		name        string
+		value       string
+		wantDate    time.Time
+		wantInvalid string
+	}{
+		{name: "timestamp", value: "2027-12-26T00:00:00Z", wantDate: reviewAfter},
+		{name: "not a date", value: "someday", wantInvalid: "someday"},
+	}
+	for _, tt := range lenient {
+		t.Run(tt.name, func(t *testing.T) {
+			edited := SetField(content, ReviewAfterField, tt.value)
+			got, err := Parse(edited)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !got.ReviewAfter.Equal(tt.wantDate) || got.InvalidReviewAfter != tt.wantInvalid {
+				t.Errorf("Parse() ReviewAfter = %v, InvalidReviewAfter = %q, want %v, %q", got.ReviewAfter, got.InvalidReviewAfter, tt.wantDate, tt.wantInvalid)
+			}
+			if tt.wantInvalid != "" && !strings.Contains(got.Generate(), "| **Review After** | "+tt.wantInvalid+" |") {
+				t.Errorf("Generate() dropped the invalid review date:\n%s", got.Generate())
+			}
+		})
+	}
+}
+
+func TestLinks(t *testing.T) {
+	meta := &Metadata{
+		OriginalSource: "https://github.com/owner/repo",
+		BuriedAt:       time.Date(2025, 12, 26, 10, 30, 0, 0, time.UTC),
+		Supersedes:     "prototype-v1",
+		SupersededBy:   "https://github.com/owner/new-service",
+		Owner:          "platform-team",
+		Version:        2,
+		ConvertedFrom:  "Mercurial",
+		Unversioned:    true,
+		Branch:         "legacy/v1",
+		Ref:            "v1.4.2",
+		RefCommit:      "0123456789abcdef0123456789abcdef01234567",
+	}
+
+	content := meta.Generate()
+	for _, want := range []string{
+		"| **Converted From** | Mercurial |",
+		"| **Version Control** | None; committed as a snapshot when buried |",
+		"| **Branch** | legacy/v1 |",
+		"| **Ref** | v1.4.2 (0123456789abcdef0123456789abcdef01234567) |",
+		"| **Version** | 2 |",
+		"| **Owner** | platform-team |",
+		"| **Supersedes** | prototype-v1 |",
+		"| **Superseded By** | https://github.com/owner/new-service |",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Generate() missing %q\n\nGot:\n%s", want, content)
+		}
+	}
+
+	got, err := Parse(SetField(content, SupersedesField, ""))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got.Supersedes != "" || got.SupersededBy != meta.SupersededBy {
+		t.Errorf("Parse() links = %q, %q, want \"\", %q", got.Supersedes, got.SupersededBy, meta.SupersededBy)
+	}
+	if got.Owner != meta.Owner {
+		t.Errorf("Parse() Owner = %q, want %q", got.Owner, meta.Owner)
+	}
+	if got.Version != meta.Version {
+		t.Errorf("Parse() Version = %d, want %d", got.Version, meta.Version)
+	}
+	if got.Branch != meta.Branch {
+		t.Errorf("Parse() Branch = %q, want %q", got.Branch, meta.Branch)
+	}
+	if got.ConvertedFrom != meta.ConvertedFrom {
+		t.Errorf("Parse() ConvertedFrom = %q, want %q", got.ConvertedFrom, meta.ConvertedFrom)
+	}
+	if !got.Unversioned {
+		t.Errorf("Parse() Unversioned = false, want true")
+	}
+	if got.Ref != meta.Ref || got.RefCommit != meta.RefCommit {
+		t.Errorf("Parse() Ref = %q at %q, want %q at %q", got.Ref, got.RefCommit, meta.Ref, meta.RefCommit)
+	}
+	if _, err := Parse(SetField(content, VersionField, "two")); err == nil {
+		t.Errorf("Parse() expected error for an invalid version")
+	}
+
+	for _, value := range []string{"a|b", "a\nb"} {
+		if err := CheckValue(value); err == nil {
+			t.Errorf("CheckValue(%q) expected error", value)
+		}
+	}
+	if err := CheckValue("https://example.com/a?b=c"); err != nil {
+		t.Errorf("CheckValue() error = %v", err)
+	}
+}
+
+func TestMonthlyCosts(t *testing.T) {
+	before, after := 420.0, 12.5
+	meta := &Metadata{
+		OriginalSource:    "https://github.com/owner/repo",
+		BuriedAt:          time.Date(2025, 12, 26, 10, 30, 0, 0, time.UTC),
+		MonthlyCostBefore: &before,
+		MonthlyCostAfter:  &after,
+	}
+
+	content := meta.Generate()
+	for _, want := range []string{
+		"| **Monthly Cost Before** | 420 |",
+		"| **Monthly Cost After** | 12.5 |",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Generate() missing %q\n\nGot:\n%s", want, content)
+		}
+	}
+	got, err := Parse(content)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got.MonthlyCostBefore == nil || *got.MonthlyCostBefore != before ||
+		got.MonthlyCostAfter == nil || *got.MonthlyCostAfter != after {
+		t.Errorf("Parse() costs = %v, %v, want %v, %v", got.MonthlyCostBefore, got.MonthlyCostAfter, before, after)
+	}
+
+	tests := []struct {
+		name        string
+		meta        *Metadata
+		wantSavings float64
+		wantOK      bool
+	}{
+		{name: "before and after", meta: meta, wantSavings: 407.5, wantOK: true},
+		{name: "before only", meta: &Metadata{MonthlyCostBefore: &before}, wantSavings: 420, wantOK: true},
+		{name: "after only", meta: &Metadata{MonthlyCostAfter: &after}, wantOK: false},
+		{name: "no costs", meta: &Metadata{}, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			savings, ok := tt.meta.MonthlySavings()
+			if savings != tt.wantSavings || ok != tt.wantOK {
+				t.Errorf("MonthlySavings() = %v, %v, want %v, %v", savings, ok, tt.wantSavings, tt.wantOK)
+			}
+		})
+	}
+
+	for _, value := range []string{"lots", "-5", "NaN", ""} {
+		if _, err := ParseCost(value); err == nil {
+			t.Errorf("ParseCost(%q) expected error", value)
+		}
+	}
+	if _, err := Parse(SetField(content, MonthlyCostBeforeField, "lots")); err == nil {
+		t.Errorf("Parse() expected error for an invalid cost")
+	}
+}
+
+func TestDescriptionAndTopics(t *testing.T) {
+	meta := &Metadata{
+		OriginalSource: "https://codeberg.org/owner/repo",
+		BuriedAt:       time.Date(2025, 12, 26, 10, 30, 0, 0, time.UTC),
+		Description:    "Converts A | B\nfiles",
+		Topics:         []string{"cli", "converter"},
+	}
+
+	content := meta.Generate()
+	for _, want := range []string{
+		`| **Description** | Converts A \| B files |`,
+		"| **Topics** | cli, converter |",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Generate() missing %q\n\nGot:\n%s", want, content)
+		}
+	}
+	got, err := Parse(content)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got.Description != "Converts A | B files" {
+		t.Errorf("Parse() Description = %q, want %q", got.Description, "Converts A | B files")
+	}
+	if strings.Join(got.Topics, ",") != "cli,converter" {
+		t.Errorf("Parse() Topics = %v, want [cli converter]", got.Topics)
+	}
+}
+
+func TestSetSubmodulesVendored(t *testing.T) {
+	subs := []Submodule{{Path: "vendor/lib", URL: "https://example.com/lib.git", Commit: "abc123"}}
+	tests := []struct {
+		name string
+		meta *Metadata
+	}{
+		{
+			name: "listed submodules",
+			meta: &Metadata{OriginalSource: "/src/app", BuriedAt: time.Now(), Submodules: subs, Refs: []Ref{{Name: "main", Commit: "def456"}}},
+		},
+		{
+			name: "burial before submodules were listed",
+			meta: &Metadata{OriginalSource: "/src/app", BuriedAt: time.Now()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := tt.meta.Write(dir); err != nil {
+				t.Fatal(err)
+			}
+			if err := SetSubmodulesVendored(dir, subs); err != nil {
+				t.Fatalf("SetSubmodulesVendored() error = %v", err)
+			}
+			content, err := os.ReadFile(filepath.Join(dir, FileName))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := string(content)
+			if strings.Count(got, "## Submodules") != 1 || !strings.Contains(got, "copied into the project") || strings.Contains(got, "directories are empty") {
+				t.Errorf("metadata = %q, want one vendored submodules section", got)
+			}
+			if !strings.Contains(got, "| `vendor/lib` | https://example.com/lib.git | `abc123` |") {
+				t.Errorf("metadata = %q, want the submodule listed", got)
+			}
+			// The sections around it and the footer are kept
+			if tt.meta.Refs != nil && !strings.Contains(got, "## Branches and Tags") {
+				t.Errorf("metadata = %q, want the following section kept", got)
+			}
+			if strings.Index(got, "## Submodules") > strings.Index(got, "archived using [bury-it]") {
+				t.Errorf("metadata = %q, want the section before the footer", got)
+			}
+
+			meta, err := Parse(got)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !meta.SubmodulesVendored || len(meta.Submodules) != 1 || meta.Submodules[0] != subs[0] {
+				t.Errorf("Parse() submodules = %+v, vendored %v, want %+v vendored", meta.Submodules, meta.SubmodulesVendored, subs)
+			}
+		})
+	}
+}
+
+func TestParseSubmodules(t *testing.T) {
+	subs := []Submodule{
+		{Path: "vendor/lib", URL: "https://example.com/lib.git", Commit: "abc123"},
+		{Path: "docs", Commit: "def456"},
+	}
+	meta := &Metadata{OriginalSource: "/src/app", BuriedAt: time.Now(), Submodules: subs, Refs: []Ref{{Name: "main", Commit: "0123abc"}}}
+	got, err := Parse(meta.Generate())
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got.SubmodulesVendored || len(got.Submodules) != len(subs) {
+		t.Fatalf("Parse() submodules = %+v, vendored %v, want %+v", got.Submodules, got.SubmodulesVendored, subs)
+	}
+	for i := range subs {
+		if got.Submodules[i] != subs[i] {
+			t.Errorf("Parse() submodule %d = %+v, want %+v", i, got.Submodules[i], subs[i])
+		}
+	}
+}
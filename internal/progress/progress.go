@@ -0,0 +1,140 @@
+// Package progress reports the phases of long operations such as a burial:
+// as the lines bury-it prints, and also, when requested, as newline-delimited
+// JSON events on stderr for wrappers that render their own progress.
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Formats of progress reporting.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Types of events.
+const (
+	// TypePhase starts a phase of an operation.
+	TypePhase = "phase"
+	// TypeCount reports how far a phase has got.
+	TypeCount = "count"
+	// TypeWarning reports a problem that did not stop the operation.
+	TypeWarning = "warning"
+	// TypeDone ends an operation that succeeded.
+	TypeDone = "done"
+	// TypeError ends an operation that failed.
+	TypeError = "error"
+)
+
+// Event is a JSON progress event.
+type Event struct {
+	Time time.Time `json:"time"`
+	Type string    `json:"type"`
+	// Phase names what the operation is doing, such as "clone" or
+	// "commit". Warnings carry the phase they happened in.
+	Phase string `json:"phase,omitempty"`
+	// Message is the line printed for the event.
+	Message string `json:"message"`
+	// Current and Total are how far a count has got, in Unit; Total is zero
+	// when unknown.
+	Current int64  `json:"current,omitempty"`
+	Total   int64  `json:"total,omitempty"`
+	Unit    string `json:"unit,omitempty"`
+}
+
+var (
+	mu sync.Mutex
+	// events receives JSON events, or is nil for text only.
+	events io.Writer
+	// phase is the current phase, which warnings are reported in.
+	phase string
+	// now is the clock, replaced in tests.
+	now = time.Now
+)
+
+// SetFormat selects how progress is reported: FormatText prints lines only,
+// and FormatJSON also writes JSON events to w.
+func SetFormat(format string, w io.Writer) error {
+	mu.Lock()
+	defer mu.Unlock()
+	switch format {
+	case FormatText:
+		events = nil
+	case FormatJSON:
+		events = w
+	default:
+		return fmt.Errorf("invalid progress format %q: must be %s or %s", format, FormatText, FormatJSON)
+	}
+	return nil
+}
+
+// Phase prints a line starting a phase, such as "Cloning ...", and reports
+// it.
+func Phase(name, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	fmt.Println(message)
+	mu.Lock()
+	defer mu.Unlock()
+	phase = name
+	emit(Event{Type: TypePhase, Phase: name, Message: message})
+}
+
+// Count prints a line saying how far the current phase has got, and reports
+// current of total units, or of an unknown total if total is zero.
+func Count(current, total int64, unit, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	fmt.Println(message)
+	mu.Lock()
+	defer mu.Unlock()
+	emit(Event{Type: TypeCount, Phase: phase, Message: strings.TrimSpace(message), Current: current, Total: total, Unit: unit})
+}
+
+// Warn prints a warning and reports it.
+func Warn(format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	fmt.Println("Warning: " + message)
+	mu.Lock()
+	defer mu.Unlock()
+	emit(Event{Type: TypeWarning, Phase: phase, Message: message})
+}
+
+// Done reports that the operation succeeded, without printing anything;
+// the caller prints its own summary.
+func Done(message string) {
+	mu.Lock()
+	defer mu.Unlock()
+	emit(Event{Type: TypeDone, Message: message})
+	phase = ""
+}
+
+// Fail reports that the operation failed, returning whether it was
+// reported as an event, in which case the caller need not print it.
+func Fail(err error) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	if events == nil {
+		return false
+	}
+	emit(Event{Type: TypeError, Phase: phase, Message: err.Error()})
+	return true
+}
+
+// emit writes e as a line of JSON if events are being written. The caller
+// holds mu.
+func emit(e Event) {
+	if events == nil {
+		return
+	}
+	e.Time = now().UTC()
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	_, _ = events.Write(append(data, '\n'))
+}
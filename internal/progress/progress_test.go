@@ -0,0 +1,76 @@
+package progress
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetFormat(t *testing.T) {
+	tests := []struct {
+		format  string
+		wantErr bool
+	}{
+		{format: FormatText},
+		{format: FormatJSON},
+		{format: "xml", wantErr: true},
+		{format: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			err := SetFormat(tt.format, &bytes.Buffer{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SetFormat(%q) error = %v, wantErr %v", tt.format, err, tt.wantErr)
+			}
+		})
+	}
+	_ = SetFormat(FormatText, nil)
+}
+
+func TestEvents(t *testing.T) {
+	fixed := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return fixed }
+	defer func() { now = time.Now }()
+
+	var buf bytes.Buffer
+	if err := SetFormat(FormatJSON, &buf); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = SetFormat(FormatText, nil) }()
+
+	Phase("clone", "Cloning %s...", "repo")
+	Warn("source has %d submodules", 2)
+	Count(40, 100, "issues", "  %d issues exported", 40)
+	Done("Buried repo")
+	if !Fail(errors.New("boom")) {
+		t.Error("Fail() = false, want the error reported as an event")
+	}
+
+	want := []string{
+		`{"time":"2024-06-15T12:00:00Z","type":"phase","phase":"clone","message":"Cloning repo..."}`,
+		`{"time":"2024-06-15T12:00:00Z","type":"warning","phase":"clone","message":"source has 2 submodules"}`,
+		`{"time":"2024-06-15T12:00:00Z","type":"count","phase":"clone","message":"40 issues exported","current":40,"total":100,"unit":"issues"}`,
+		`{"time":"2024-06-15T12:00:00Z","type":"done","message":"Buried repo"}`,
+		`{"time":"2024-06-15T12:00:00Z","type":"error","message":"boom"}`,
+	}
+	got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d:\n%s", len(got), len(want), buf.String())
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	// Text only writes no events, and leaves errors to the caller
+	if err := SetFormat(FormatText, nil); err != nil {
+		t.Fatal(err)
+	}
+	if Fail(errors.New("boom")) {
+		t.Error("Fail() = true in text mode, want false")
+	}
+}
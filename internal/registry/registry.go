@@ -0,0 +1,230 @@
+// Package registry records burials in a shared ledger repository.
+package registry
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/display"
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/github"
+)
+
+// DefaultFile is the ledger file used when none is configured.
+const DefaultFile = "burials.jsonl"
+
+// Format is the encoding of a ledger file.
+type Format string
+
+const (
+	// FormatJSONL writes one JSON object per line.
+	FormatJSONL Format = "jsonl"
+	// FormatCSV writes comma-separated values with a header row.
+	FormatCSV Format = "csv"
+)
+
+// csvHeader is the header row of CSV ledgers.
+var csvHeader = []string{"project", "graveyard", "commit", "original_source", "buried_at", "history_preserved"}
+
+// Entry is a single burial recorded in the ledger.
+type Entry struct {
+	// Project is the project's directory name in the graveyard.
+	Project string `json:"project"`
+	// Graveyard is the graveyard's remote URL, or its path if it has none.
+	Graveyard string `json:"graveyard"`
+	// Commit is the graveyard commit that completed the burial.
+	Commit string `json:"commit"`
+	// OriginalSource is where the project was buried from.
+	OriginalSource string `json:"original_source"`
+	// BuriedAt is when the project was buried.
+	BuriedAt time.Time `json:"buried_at"`
+	// HistoryPreserved indicates whether git history was preserved.
+	HistoryPreserved bool `json:"history_preserved"`
+}
+
+// Registry is a local clone of a ledger repository.
+type Registry struct {
+	// Path is the path to the registry repository.
+	Path string
+	// File is the ledger file, relative to Path.
+	File string
+	// Format is the ledger encoding, derived from the file extension.
+	Format Format
+}
+
+// New opens the registry repository at path. The ledger format is derived
+// from the extension of file: ".csv" for CSV, ".jsonl" or ".ndjson" for JSON
+// Lines. An empty file selects DefaultFile.
+func New(path, file string) (*Registry, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve registry path: %w", err)
+	}
+	if !git.IsValidRepo(absPath) {
+		return nil, fmt.Errorf("registry is not a git repository: %s", absPath)
+	}
+
+	if file == "" {
+		file = DefaultFile
+	}
+	if filepath.IsAbs(file) || strings.HasPrefix(filepath.Clean(file), "..") {
+		return nil, fmt.Errorf("registry file must be inside the registry: %s", file)
+	}
+
+	var format Format
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".csv":
+		format = FormatCSV
+	case ".jsonl", ".ndjson":
+		format = FormatJSONL
+	default:
+		return nil, fmt.Errorf("unsupported registry file %s (use .jsonl or .csv)", file)
+	}
+
+	return &Registry{Path: absPath, File: filepath.Clean(file), Format: format}, nil
+}
+
+// Append adds an entry to the end of the ledger file. Credentials in the
+// entry's URLs are left out, as the ledger is shared.
+func (r *Registry) Append(e Entry) error {
+	e = e.redacted()
+	path := filepath.Join(r.Path, r.File)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create registry directory: %w", err)
+	}
+
+	_, statErr := os.Stat(path)
+	isNew := os.IsNotExist(statErr)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open registry file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	switch r.Format {
+	case FormatCSV:
+		w := csv.NewWriter(f)
+		if isNew {
+			_ = w.Write(csvHeader)
+		}
+		_ = w.Write([]string{
+			e.Project,
+			e.Graveyard,
+			e.Commit,
+			e.OriginalSource,
+			e.BuriedAt.UTC().Format(time.RFC3339),
+			strconv.FormatBool(e.HistoryPreserved),
+		})
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return fmt.Errorf("failed to write registry entry: %w", err)
+		}
+	default:
+		data, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to encode registry entry: %w", err)
+		}
+		if _, err := f.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("failed to write registry entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// Commit appends e to the ledger and commits it on the current branch.
+func (r *Registry) Commit(e Entry) error {
+	if err := r.Append(e); err != nil {
+		return err
+	}
+	if err := git.StageFile(r.Path, r.File); err != nil {
+		return fmt.Errorf("failed to stage registry file: %w", err)
+	}
+	if err := git.Commit(r.Path, commitMessage(e)); err != nil {
+		return fmt.Errorf("failed to commit registry entry: %w", err)
+	}
+	return nil
+}
+
+// GitHubRepo returns the owner and name of the registry's origin on GitHub.
+func (r *Registry) GitHubRepo() (owner, repo string, ok bool) {
+	remote, err := git.GetRemoteURL(r.Path)
+	if err != nil {
+		return "", "", false
+	}
+	return github.ParseRepoURL(remote)
+}
+
+// redacted returns e with any credentials removed from its URLs.
+func (e Entry) redacted() Entry {
+	e.Graveyard = display.URL(e.Graveyard)
+	e.OriginalSource = display.URL(e.OriginalSource)
+	return e
+}
+
+// Propose commits e on a new branch, pushes the branch to origin, and opens a
+// pull request against the current branch. The branch is named after the
+// project and the time of the burial, so that burying a project again
+// proposes a new one, and is deleted, locally and from origin, if the
+// proposal fails. The registry is left on its original branch. It returns the
+// URL of the pull request.
+func (r *Registry) Propose(client *github.Client, e Entry) (url string, err error) {
+	owner, repo, ok := r.GitHubRepo()
+	if !ok {
+		return "", fmt.Errorf("registry origin is not a GitHub repository")
+	}
+	base, err := git.CurrentBranch(r.Path)
+	if err != nil {
+		return "", err
+	}
+
+	branch := branchName(e)
+	if err := git.Checkout(r.Path, branch, true); err != nil {
+		return "", err
+	}
+	pushed := false
+	defer func() {
+		if err == nil {
+			return
+		}
+		if pushed {
+			_ = git.DeleteRemoteBranch(r.Path, "origin", branch)
+		}
+		_ = git.DeleteBranch(r.Path, branch)
+	}()
+	err = r.Commit(e)
+	if err == nil {
+		err = git.Push(r.Path, "origin", branch)
+		pushed = err == nil
+	}
+	if checkoutErr := git.Checkout(r.Path, base, false); err == nil {
+		err = checkoutErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	public := e.redacted()
+	body := fmt.Sprintf("Registers the burial of `%s` from %s into %s.", public.Project, public.OriginalSource, public.Graveyard)
+	pr, err := client.CreatePullRequest(owner, repo, commitMessage(e), branch, base, body)
+	if err != nil {
+		return "", err
+	}
+	return pr.HTMLURL, nil
+}
+
+// branchName returns the name of the branch Propose proposes e on.
+func branchName(e Entry) string {
+	return "bury-it/register-" + e.Project + "-" + e.BuriedAt.UTC().Format("20060102-150405")
+}
+
+// commitMessage returns the registry commit message for an entry.
+func commitMessage(e Entry) string {
+	return fmt.Sprintf("docs: bury-it - registered %s", e.Project)
+}
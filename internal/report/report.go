@@ -0,0 +1,220 @@
+// Package report writes the annual report of a graveyard: the projects
+// buried in a year, how large they were, the most notable of them, and who
+// buried the most, for posting in an end-of-year retrospective.
+package report
+
+import (
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/audit"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/metadata"
+	"github.com/deanhigh/bury-it/internal/size"
+)
+
+// Formats are the formats a report can be written in.
+var Formats = []string{"md", "html"}
+
+// burialOperations are the audit log operations that bury a project.
+var burialOperations = map[string]bool{"bury": true, "apply": true, "sweep": true}
+
+// Report is the annual report of a graveyard.
+type Report struct {
+	// Year is the year reported on.
+	Year int `json:"year"`
+	// Graveyard is the graveyard's location.
+	Graveyard string `json:"graveyard"`
+	// Projects are the projects buried in the year, in the order they were
+	// buried.
+	Projects []Project `json:"projects"`
+	// HistoryPreserved is the number of projects buried with their history.
+	HistoryPreserved int `json:"history_preserved"`
+	// Bytes is the total size of the projects.
+	Bytes int64 `json:"bytes"`
+	// Notable picks out projects that stood out.
+	Notable []Notable `json:"notable"`
+	// Buriers are the people who buried the projects, most burials first.
+	Buriers []Burier `json:"buriers"`
+}
+
+// Project is a project buried in the reported year.
+type Project struct {
+	// Name is the project's name in the graveyard.
+	Name string `json:"name"`
+	// BuriedAt is when the project was buried.
+	BuriedAt time.Time `json:"buried_at"`
+	// Description is the project's one-line description, if recorded.
+	Description string `json:"description,omitempty"`
+	// Owner is the team or person responsible for the project, if recorded.
+	Owner string `json:"owner,omitempty"`
+	// Bytes is the size of the project's files and git objects.
+	Bytes int64 `json:"bytes"`
+	// Commits is the number of commits in the project's history, if kept.
+	Commits int `json:"commits"`
+	// Contributors is the number of commit authors, if the history was kept.
+	Contributors int `json:"contributors"`
+	// Lifetime is the time from the first commit to the last, if the
+	// history was kept.
+	Lifetime time.Duration `json:"-"`
+}
+
+// Notable is a project that stood out among the year's burials.
+type Notable struct {
+	// Title says what the project stood out for, e.g. "Largest".
+	Title string `json:"title"`
+	// Project is the project's name.
+	Project string `json:"project"`
+	// Detail is the figure it stood out with, e.g. "1.2 GiB".
+	Detail string `json:"detail"`
+}
+
+// Burier is someone who buried projects in the reported year.
+type Burier struct {
+	// User is the git identity the burials were made with.
+	User string `json:"user"`
+	// Burials is the number of burials they made.
+	Burials int `json:"burials"`
+}
+
+// Build reports on the projects buried in year in the graveyard at location,
+// taking their sizes from usages and who buried them from the graveyard's
+// audit log entries.
+func Build(year int, location string, projects map[string]*metadata.Metadata, usages map[string]*graveyard.Usage, entries []audit.Entry) *Report {
+	r := &Report{Year: year, Graveyard: location, Projects: []Project{}, Notable: []Notable{}, Buriers: []Burier{}}
+	buried := map[string]bool{}
+	for name, meta := range projects {
+		if meta.BuriedAt.Year() != year {
+			continue
+		}
+		p := Project{Name: name, BuriedAt: meta.BuriedAt, Description: meta.Description, Owner: meta.Owner}
+		if usage := usages[name]; usage != nil {
+			p.Bytes = usage.WorkTree + usage.Packed
+		}
+		if h := meta.History; h != nil {
+			p.Commits = h.Commits
+			p.Contributors = h.Contributors
+			p.Lifetime = h.LastCommit.Sub(h.FirstCommit)
+		}
+		if meta.HistoryPreserved {
+			r.HistoryPreserved++
+		}
+		r.Bytes += p.Bytes
+		r.Projects = append(r.Projects, p)
+		buried[name] = true
+	}
+	sort.Slice(r.Projects, func(i, j int) bool {
+		a, b := r.Projects[i], r.Projects[j]
+		if !a.BuriedAt.Equal(b.BuriedAt) {
+			return a.BuriedAt.Before(b.BuriedAt)
+		}
+		return a.Name < b.Name
+	})
+	r.Notable = notable(r.Projects)
+
+	counts := map[string]int{}
+	for _, e := range entries {
+		if e.Time.Year() == year && buried[e.Project] && burialOperations[e.Operation] {
+			counts[e.User]++
+		}
+	}
+	for user, n := range counts {
+		r.Buriers = append(r.Buriers, Burier{User: user, Burials: n})
+	}
+	sort.Slice(r.Buriers, func(i, j int) bool {
+		a, b := r.Buriers[i], r.Buriers[j]
+		if a.Burials != b.Burials {
+			return a.Burials > b.Burials
+		}
+		return a.User < b.User
+	})
+	return r
+}
+
+// notable picks the largest, longest-lived, busiest, and most collaborative
+// of projects. Figures no project has, such as commits when no history was
+// kept, are left out.
+func notable(projects []Project) []Notable {
+	picks := []struct {
+		title  string
+		value  func(p Project) int64
+		detail func(p Project) string
+	}{
+		{"Largest", func(p Project) int64 { return p.Bytes }, func(p Project) string { return size.Format(p.Bytes) }},
+		{"Longest lived", func(p Project) int64 { return int64(p.Lifetime) }, func(p Project) string { return formatLifetime(p.Lifetime) }},
+		{"Most commits", func(p Project) int64 { return int64(p.Commits) }, func(p Project) string { return fmt.Sprintf("%d commits", p.Commits) }},
+		{"Most contributors", func(p Project) int64 { return int64(p.Contributors) }, func(p Project) string { return fmt.Sprintf("%d contributors", p.Contributors) }},
+	}
+	found := []Notable{}
+	for _, pick := range picks {
+		best := -1
+		for i, p := range projects {
+			if pick.value(p) > 0 && (best < 0 || pick.value(p) > pick.value(projects[best])) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			found = append(found, Notable{Title: pick.title, Project: projects[best].Name, Detail: pick.detail(projects[best])})
+		}
+	}
+	return found
+}
+
+// formatLifetime formats a project's lifetime in years and months, to the
+// nearest month, or in days if it was shorter.
+func formatLifetime(d time.Duration) string {
+	days := int(d.Hours() / 24)
+	months := int(math.Round(float64(days) / (365.25 / 12)))
+	switch {
+	case months == 0:
+		return plural(days, "day")
+	case months < 12:
+		return plural(months, "month")
+	case months%12 == 0:
+		return plural(months/12, "year")
+	}
+	return plural(months/12, "year") + " " + plural(months%12, "month")
+}
+
+// plural formats a count of unit, adding an s unless there is one.
+func plural(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+//go:embed templates/*
+var templateFS embed.FS
+
+var funcs = map[string]any{
+	"size": size.Format,
+	"date": func(t time.Time) string { return t.Format("2006-01-02") },
+	// cell escapes a value for a Markdown table cell
+	"cell": func(s string) string {
+		return strings.ReplaceAll(strings.ReplaceAll(s, "|", `\|`), "\n", " ")
+	},
+}
+
+var (
+	markdownTemplate = texttemplate.Must(texttemplate.New("report.md").Funcs(funcs).ParseFS(templateFS, "templates/report.md"))
+	htmlTemplate     = htmltemplate.Must(htmltemplate.New("report.html").Funcs(funcs).ParseFS(templateFS, "templates/report.html"))
+)
+
+// Write writes the report to w in format, one of Formats.
+func Write(w io.Writer, r *Report, format string) error {
+	switch format {
+	case "md":
+		return markdownTemplate.Execute(w, r)
+	case "html":
+		return htmlTemplate.Execute(w, r)
+	}
+	return fmt.Errorf("unknown report format %q: must be %s", format, strings.Join(Formats, " or "))
+}
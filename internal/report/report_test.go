@@ -0,0 +1,129 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/audit"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/history"
+	"github.com/deanhigh/bury-it/internal/metadata"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func testReport() *Report {
+	projects := map[string]*metadata.Metadata{
+		"api": {
+			BuriedAt:         date(2025, 3, 1),
+			HistoryPreserved: true,
+			Owner:            "platform",
+			Description:      "Old | new API",
+			History:          &history.Summary{FirstCommit: date(2021, 1, 1), LastCommit: date(2024, 7, 1), Commits: 900, Contributors: 4},
+		},
+		"prototype": {
+			BuriedAt:         date(2025, 11, 2),
+			HistoryPreserved: true,
+			History:          &history.Summary{FirstCommit: date(2025, 1, 1), LastCommit: date(2025, 1, 20), Commits: 30, Contributors: 9},
+		},
+		"worker": {BuriedAt: date(2025, 1, 15)},
+		"legacy": {BuriedAt: date(2024, 6, 1), HistoryPreserved: true},
+	}
+	usages := map[string]*graveyard.Usage{
+		"api":       {WorkTree: 1000, Packed: 500},
+		"prototype": {WorkTree: 100},
+		"worker":    {WorkTree: 4000},
+		"legacy":    {WorkTree: 1 << 30},
+	}
+	entries := []audit.Entry{
+		{Time: date(2025, 3, 1), User: "ana@example.com", Operation: "bury", Project: "api"},
+		{Time: date(2025, 11, 2), User: "ana@example.com", Operation: "sweep", Project: "prototype"},
+		{Time: date(2025, 1, 15), User: "bo@example.com", Operation: "apply", Project: "worker"},
+		{Time: date(2025, 4, 1), User: "bo@example.com", Operation: "tag add", Project: "api"},
+		{Time: date(2024, 6, 1), User: "bo@example.com", Operation: "bury", Project: "legacy"},
+	}
+	return Build(2025, "/srv/graveyard", projects, usages, entries)
+}
+
+func TestBuild(t *testing.T) {
+	r := testReport()
+
+	var names []string
+	for _, p := range r.Projects {
+		names = append(names, p.Name)
+	}
+	if got := strings.Join(names, ","); got != "worker,api,prototype" {
+		t.Errorf("Projects = %s, want the 2025 burials in order", got)
+	}
+	if r.Bytes != 5600 || r.HistoryPreserved != 2 {
+		t.Errorf("Bytes, HistoryPreserved = %d, %d, want 5600, 2", r.Bytes, r.HistoryPreserved)
+	}
+
+	want := []Notable{
+		{Title: "Largest", Project: "worker", Detail: "3.9 KiB"},
+		{Title: "Longest lived", Project: "api", Detail: "3 years 6 months"},
+		{Title: "Most commits", Project: "api", Detail: "900 commits"},
+		{Title: "Most contributors", Project: "prototype", Detail: "9 contributors"},
+	}
+	if len(r.Notable) != len(want) {
+		t.Fatalf("Notable = %+v, want %+v", r.Notable, want)
+	}
+	for i := range want {
+		if r.Notable[i] != want[i] {
+			t.Errorf("Notable[%d] = %+v, want %+v", i, r.Notable[i], want[i])
+		}
+	}
+
+	wantBuriers := []Burier{{User: "ana@example.com", Burials: 2}, {User: "bo@example.com", Burials: 1}}
+	if len(r.Buriers) != 2 || r.Buriers[0] != wantBuriers[0] || r.Buriers[1] != wantBuriers[1] {
+		t.Errorf("Buriers = %+v, want %+v", r.Buriers, wantBuriers)
+	}
+}
+
+func TestBuild_Empty(t *testing.T) {
+	r := Build(2030, "/srv/graveyard", nil, nil, nil)
+	if len(r.Projects) != 0 || len(r.Notable) != 0 || len(r.Buriers) != 0 {
+		t.Errorf("Build() of no projects = %+v, want an empty report", r)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, r, "md"); err != nil || !strings.Contains(buf.String(), "No projects were buried in 2030.") {
+		t.Errorf("Write() = %q, %v, want no projects", buf.String(), err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	r := testReport()
+
+	var md bytes.Buffer
+	if err := Write(&md, r, "md"); err != nil {
+		t.Fatalf("Write(md) error = %v", err)
+	}
+	for _, want := range []string{
+		"# Graveyard report 2025",
+		"Projects buried in 2025: 3, 5.5 KiB in all, 2 with their full history.",
+		"- **Largest:** worker (3.9 KiB)",
+		"| ana@example.com | 2 |",
+		`| api | 2025-03-01 | 1.5 KiB | platform | Old \| new API |`,
+	} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("Markdown report missing %q:\n%s", want, md.String())
+		}
+	}
+
+	r.Projects[0].Description = "<script>"
+	var html bytes.Buffer
+	if err := Write(&html, r, "html"); err != nil {
+		t.Fatalf("Write(html) error = %v", err)
+	}
+	if !strings.Contains(html.String(), "<h1>Graveyard report 2025</h1>") || strings.Contains(html.String(), "<script>") {
+		t.Errorf("HTML report = %s, want a heading and escaped descriptions", html.String())
+	}
+
+	if err := Write(&bytes.Buffer{}, r, "pdf"); err == nil {
+		t.Errorf("Write(pdf) expected error")
+	}
+}
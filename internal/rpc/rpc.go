@@ -0,0 +1,220 @@
+// Package rpc serves JSON-RPC 2.0 over a pair of streams, one message per
+// line, so that editors and other tools can drive bury-it without parsing
+// the text it prints.
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// version is the JSON-RPC version of every message.
+const version = "2.0"
+
+// Error codes defined by JSON-RPC 2.0.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	// CodeServerError is the code of errors returned by handlers.
+	CodeServerError = -32000
+)
+
+// maxMessageSize is the size of the longest request line read.
+const maxMessageSize = 16 << 20
+
+// Error is a JSON-RPC error. Handlers may return one to choose its code;
+// any other error is reported with CodeServerError.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// InvalidParams returns the error for a request whose params could not be
+// used.
+func InvalidParams(err error) error {
+	return &Error{Code: CodeInvalidParams, Message: "invalid params: " + err.Error()}
+}
+
+// Handler handles the requests for a method, returning the result to send
+// back.
+type Handler func(params json.RawMessage) (any, error)
+
+// request is a request or, without an ID, a notification.
+type request struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is the reply to a request.
+type response struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// notification is a message sent without expecting a reply.
+type notification struct {
+	Version string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// Server handles requests one at a time, writing each response and
+// notification to its writer as a line of JSON.
+type Server struct {
+	handlers map[string]Handler
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewServer returns a server writing to w.
+func NewServer(w io.Writer) *Server {
+	return &Server{handlers: make(map[string]Handler), w: w}
+}
+
+// Handle registers the handler for method.
+func (s *Server) Handle(method string, h Handler) {
+	s.handlers[method] = h
+}
+
+// Notify sends a notification.
+func (s *Server) Notify(method string, params any) error {
+	return s.send(notification{Version: version, Method: method, Params: params})
+}
+
+// Notifier returns a writer that sends each line of JSON written to it as
+// the params of a notification of method, such as the events of
+// progress.SetFormat.
+func (s *Server) Notifier(method string) io.Writer {
+	return notifier{s: s, method: method}
+}
+
+// notifier sends the lines written to it as notifications.
+type notifier struct {
+	s      *Server
+	method string
+}
+
+func (n notifier) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(bytes.TrimSpace(p), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if err := n.s.Notify(n.method, json.RawMessage(line)); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Serve reads requests from r until it ends, handling each in turn.
+// Requests without an ID are notifications and get no response. Batches
+// are not supported.
+func (s *Server) Serve(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := s.serveLine(line); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read request: %w", err)
+	}
+	return nil
+}
+
+// serveLine handles a single request, returning an error only if the
+// response could not be written.
+func (s *Server) serveLine(line []byte) error {
+	if line[0] == '[' {
+		return s.reply(nil, nil, &Error{Code: CodeInvalidRequest, Message: "batches are not supported"})
+	}
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return s.reply(nil, nil, &Error{Code: CodeParseError, Message: "parse error: " + err.Error()})
+	}
+	if req.Version != version || req.Method == "" {
+		return s.reply(req.ID, nil, &Error{Code: CodeInvalidRequest, Message: "invalid request"})
+	}
+
+	h, ok := s.handlers[req.Method]
+	if !ok {
+		if req.ID == nil {
+			return nil
+		}
+		return s.reply(req.ID, nil, &Error{Code: CodeMethodNotFound, Message: "method not found: " + req.Method})
+	}
+	result, err := h(req.Params)
+	if req.ID == nil {
+		return nil
+	}
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &Error{Code: CodeServerError, Message: err.Error()}
+		}
+		return s.reply(req.ID, nil, rpcErr)
+	}
+	if result == nil {
+		// A successful response always has a result
+		result = struct{}{}
+	}
+	return s.reply(req.ID, result, nil)
+}
+
+// reply sends the response to the request with id, which is null if it
+// could not be read.
+func (s *Server) reply(id json.RawMessage, result any, rpcErr *Error) error {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return s.send(response{Version: version, ID: id, Result: result, Error: rpcErr})
+}
+
+// send writes a message as a line of JSON.
+func (s *Server) send(msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// DecodeParams decodes the params of a request into v, rejecting unknown
+// fields so that misspelled options are not silently ignored.
+func DecodeParams(params json.RawMessage, v any) error {
+	if len(params) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(params))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return InvalidParams(err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		request string
+		want    string
+	}{
+		{
+			name:    "result",
+			request: `{"jsonrpc":"2.0","id":1,"method":"echo","params":{"text":"hi"}}`,
+			want:    `{"jsonrpc":"2.0","id":1,"result":{"text":"hi"}}`,
+		},
+		{
+			name:    "string id",
+			request: `{"jsonrpc":"2.0","id":"a","method":"echo","params":{"text":"hi"}}`,
+			want:    `{"jsonrpc":"2.0","id":"a","result":{"text":"hi"}}`,
+		},
+		{
+			name:    "empty result",
+			request: `{"jsonrpc":"2.0","id":2,"method":"nothing"}`,
+			want:    `{"jsonrpc":"2.0","id":2,"result":{}}`,
+		},
+		{
+			name:    "handler error",
+			request: `{"jsonrpc":"2.0","id":3,"method":"fail"}`,
+			want:    `{"jsonrpc":"2.0","id":3,"error":{"code":-32000,"message":"boom"}}`,
+		},
+		{
+			name:    "unknown param",
+			request: `{"jsonrpc":"2.0","id":4,"method":"echo","params":{"txt":"hi"}}`,
+			want:    `{"jsonrpc":"2.0","id":4,"error":{"code":-32602,"message":"invalid params: json: unknown field \"txt\""}}`,
+		},
+		{
+			name:    "unknown method",
+			request: `{"jsonrpc":"2.0","id":5,"method":"missing"}`,
+			want:    `{"jsonrpc":"2.0","id":5,"error":{"code":-32601,"message":"method not found: missing"}}`,
+		},
+		{
+			name:    "notification",
+			request: `{"jsonrpc":"2.0","method":"echo","params":{"text":"hi"}}`,
+			want:    ``,
+		},
+		{
+			name:    "wrong version",
+			request: `{"jsonrpc":"1.0","id":6,"method":"echo"}`,
+			want:    `{"jsonrpc":"2.0","id":6,"error":{"code":-32600,"message":"invalid request"}}`,
+		},
+		{
+			name:    "parse error",
+			request: `{"jsonrpc":`,
+			want:    `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse error: unexpected end of JSON input"}}`,
+		},
+		{
+			name:    "batch",
+			request: `[{"jsonrpc":"2.0","id":7,"method":"echo"}]`,
+			want:    `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"batches are not supported"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			s := newTestServer(&out)
+			if err := s.Serve(strings.NewReader(tt.request + "\n")); err != nil {
+				t.Fatalf("Serve() error = %v", err)
+			}
+			if got := strings.TrimSpace(out.String()); got != tt.want {
+				t.Errorf("Serve() wrote %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_Notifier(t *testing.T) {
+	var out bytes.Buffer
+	s := newTestServer(&out)
+	s.Handle("work", func(params json.RawMessage) (any, error) {
+		_, err := fmt.Fprintf(s.Notifier("progress"), "{\"step\":1}\n{\"step\":2}\n")
+		return "done", err
+	})
+
+	input := "\n" + `{"jsonrpc":"2.0","id":1,"method":"work"}` + "\n"
+	if err := s.Serve(strings.NewReader(input)); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	want := strings.Join([]string{
+		`{"jsonrpc":"2.0","method":"progress","params":{"step":1}}`,
+		`{"jsonrpc":"2.0","method":"progress","params":{"step":2}}`,
+		`{"jsonrpc":"2.0","id":1,"result":"done"}`,
+	}, "\n")
+	if got := strings.TrimSpace(out.String()); got != want {
+		t.Errorf("Serve() wrote\n%s\nwant\n%s", got, want)
+	}
+}
+
+// newTestServer returns a server with methods that echo their params, return
+// nothing, and fail.
+func newTestServer(out *bytes.Buffer) *Server {
+	s := NewServer(out)
+	s.Handle("echo", func(params json.RawMessage) (any, error) {
+		var p struct {
+			Text string `json:"text"`
+		}
+		if err := DecodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return p, nil
+	})
+	s.Handle("nothing", func(params json.RawMessage) (any, error) {
+		return nil, nil
+	})
+	s.Handle("fail", func(params json.RawMessage) (any, error) {
+		return nil, errors.New("boom")
+	})
+	return s
+}
@@ -0,0 +1,128 @@
+// Package scan discovers git repositories on the local filesystem.
+package scan
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/progress"
+)
+
+// Repo is a git repository found by a scan.
+type Repo struct {
+	// Path is the absolute path to the repository's working tree.
+	Path string
+	// LastCommit is the date of the most recent commit on any ref, or the
+	// zero time if the repository has no commits.
+	LastCommit time.Time
+}
+
+// Repos returns the git repositories at or below root, sorted by path. The
+// scan does not descend into a repository once found, so nested repositories
+// and submodules are not reported separately. Directories that cannot be read
+// and repositories whose history cannot be read are skipped with a warning.
+func Repos(root string) ([]Repo, error) {
+	return ReposAsOf(root, time.Time{})
+}
+
+// ReposAsOf is like Repos, but reports each repository as it was at asOf:
+// LastCommit only considers commits made before then, and is the zero time for
+// repositories without any. A zero asOf considers every commit.
+func ReposAsOf(root string, asOf time.Time) ([]Repo, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	if info, err := os.Stat(root); err != nil {
+		return nil, fmt.Errorf("failed to access %s: %w", root, err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", root)
+	}
+
+	var repos []Repo
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			// One unreadable directory should not hide every other repository
+			progress.Warn("skipping %s: %v", path, err)
+			return filepath.SkipDir
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if _, err := os.Stat(filepath.Join(path, ".git")); err != nil {
+			return nil
+		}
+
+		lastCommit, err := git.LastCommitDateBefore(path, asOf)
+		if err != nil {
+			progress.Warn("skipping %s: failed to read its history: %v", path, err)
+			return filepath.SkipDir
+		}
+		repos = append(repos, Repo{Path: path, LastCommit: lastCommit})
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(repos, func(i, j int) bool {
+		return repos[i].Path < repos[j].Path
+	})
+	return repos, nil
+}
+
+// Select parses a selection of the repositories of a scan, numbered from 1
+// to n, such as "1,3-5" or "all", and returns their indexes in order. "none"
+// or an empty selection selects nothing.
+func Select(spec string, n int) ([]int, error) {
+	spec = strings.TrimSpace(spec)
+	switch strings.ToLower(spec) {
+	case "", "none":
+		return nil, nil
+	case "all":
+		indexes := make([]int, n)
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return indexes, nil
+	}
+
+	selected := make([]bool, n)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection %q: want numbers such as 1,3-5, all, or none", part)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(strings.TrimSpace(last)); err != nil || to < from {
+				return nil, fmt.Errorf("invalid selection %q: want numbers such as 1,3-5, all, or none", part)
+			}
+		}
+		if from < 1 || to > n {
+			return nil, fmt.Errorf("invalid selection %q: repositories are numbered from 1 to %d", part, n)
+		}
+		for i := from; i <= to; i++ {
+			selected[i-1] = true
+		}
+	}
+	var indexes []int
+	for i, ok := range selected {
+		if ok {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, nil
+}
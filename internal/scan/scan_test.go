@@ -0,0 +1,149 @@
+package scan
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRepos(t *testing.T) {
+	root := t.TempDir()
+	old := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	recent := time.Date(2025, 11, 5, 12, 0, 0, 0, time.UTC)
+
+	initRepo(t, filepath.Join(root, "old"), old)
+	initRepo(t, filepath.Join(root, "group", "recent"), recent)
+	initRepo(t, filepath.Join(root, "old", "vendor", "nested"), recent)
+	if err := os.MkdirAll(filepath.Join(root, "plain"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+
+	repos, err := Repos(root)
+	if err != nil {
+		t.Fatalf("Repos() error = %v", err)
+	}
+
+	want := []Repo{
+		{Path: filepath.Join(root, "group", "recent"), LastCommit: recent},
+		{Path: filepath.Join(root, "old"), LastCommit: old},
+	}
+	if len(repos) != len(want) {
+		t.Fatalf("Repos() = %+v, want %+v", repos, want)
+	}
+	for i := range want {
+		if repos[i].Path != want[i].Path || !repos[i].LastCommit.Equal(want[i].LastCommit) {
+			t.Errorf("Repos()[%d] = %+v, want %+v", i, repos[i], want[i])
+		}
+	}
+}
+
+func TestRepos_Errors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		root string
+	}{
+		{name: "missing root", root: "/path/that/does/not/exist"},
+		{name: "file root", root: file},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Repos(tt.root); err == nil {
+				t.Errorf("Repos(%q) expected error, got nil", tt.root)
+			}
+		})
+	}
+}
+
+func TestRepos_Unreadable(t *testing.T) {
+	root := t.TempDir()
+	at := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	initRepo(t, filepath.Join(root, "good"), at)
+	if err := os.MkdirAll(filepath.Join(root, "broken"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "broken", ".git"), []byte("not a repository\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if os.Geteuid() != 0 {
+		// Permissions do not stop root from reading a directory
+		locked := filepath.Join(root, "locked")
+		initRepo(t, filepath.Join(locked, "hidden"), at)
+		if err := os.Chmod(locked, 0); err != nil {
+			t.Fatalf("Failed to lock dir: %v", err)
+		}
+		t.Cleanup(func() { os.Chmod(locked, 0755) })
+	}
+
+	repos, err := Repos(root)
+	if err != nil {
+		t.Fatalf("Repos() error = %v", err)
+	}
+	if len(repos) != 1 || repos[0].Path != filepath.Join(root, "good") {
+		t.Errorf("Repos() = %+v, want only %s", repos, filepath.Join(root, "good"))
+	}
+}
+
+// initRepo creates a repository at dir with one commit dated at.
+func initRepo(t *testing.T, dir string, at time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# repo\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	date := at.Format(time.RFC3339)
+	for _, args := range [][]string{
+		{"init"},
+		{"add", "-A"},
+		{"-c", "user.name=Test", "-c", "user.email=test@test.com", "commit", "-m", "initial commit"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []int
+		wantErr bool
+	}{
+		{spec: "all", want: []int{0, 1, 2, 3, 4}},
+		{spec: "none", want: nil},
+		{spec: "", want: nil},
+		{spec: "2", want: []int{1}},
+		{spec: "1,3-5", want: []int{0, 2, 3, 4}},
+		{spec: " 4, 2 ,2-3", want: []int{1, 2, 3}},
+		{spec: "0", wantErr: true},
+		{spec: "6", wantErr: true},
+		{spec: "3-1", wantErr: true},
+		{spec: "two", wantErr: true},
+		{spec: "1,", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := Select(tt.spec, 5)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Select(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Select(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// Package size formats and parses human-readable byte sizes.
+package size
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// units are the binary size units in increasing order.
+var units = []string{"B", "KiB", "MiB", "GiB", "TiB"}
+
+// Format returns a human-readable representation of n bytes, such as "1.5 MiB".
+func Format(n int64) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	value := float64(n)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
+
+// Parse parses a size such as "500MB", "1.5GiB", or "2048" into bytes.
+// Decimal (KB, MB) and binary (KiB, MiB) suffixes are both treated as
+// powers of 1024.
+func Parse(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("size cannot be empty")
+	}
+
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	number, suffix := s, ""
+	if i >= 0 {
+		number, suffix = s[:i], strings.TrimSpace(s[i:])
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size: %s", s)
+	}
+
+	multiplier := float64(1)
+	unit := strings.ToUpper(suffix)
+	unit = strings.TrimSuffix(strings.TrimSuffix(unit, "IB"), "B")
+	switch unit {
+	case "":
+	case "K":
+		multiplier = 1 << 10
+	case "M":
+		multiplier = 1 << 20
+	case "G":
+		multiplier = 1 << 30
+	case "T":
+		multiplier = 1 << 40
+	default:
+		return 0, fmt.Errorf("invalid size unit: %s", suffix)
+	}
+	return int64(value * multiplier), nil
+}
@@ -0,0 +1,56 @@
+package size
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int64
+		want string
+	}{
+		{name: "bytes", n: 512, want: "512 B"},
+		{name: "kibibytes", n: 1536, want: "1.5 KiB"},
+		{name: "mebibytes", n: 5 << 20, want: "5.0 MiB"},
+		{name: "gibibytes", n: 3 << 30, want: "3.0 GiB"},
+		{name: "zero", n: 0, want: "0 B"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(tt.n); got != tt.want {
+				t.Errorf("Format(%d) = %q, want %q", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{name: "plain bytes", input: "2048", want: 2048},
+		{name: "decimal suffix", input: "500MB", want: 500 << 20},
+		{name: "binary suffix", input: "1GiB", want: 1 << 30},
+		{name: "fractional", input: "1.5K", want: 1536},
+		{name: "lowercase with space", input: "10 mb", want: 10 << 20},
+		{name: "bytes suffix", input: "12B", want: 12},
+		{name: "empty", input: "", wantErr: true},
+		{name: "unknown unit", input: "5PB", wantErr: true},
+		{name: "not a number", input: "lots", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
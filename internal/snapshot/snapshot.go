@@ -0,0 +1,373 @@
+// Package snapshot copies the files tracked at a repository's HEAD into a
+// directory, for burials that drop history. Several engines make the copy,
+// trading speed for what they preserve and need on each platform.
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/deanhigh/bury-it/internal/git"
+)
+
+// Engine copies the files tracked at HEAD of a repository into a directory.
+type Engine interface {
+	// Copy copies the files tracked at HEAD of the repository at src into
+	// dest, creating it if needed.
+	Copy(src, dest string) error
+}
+
+// AutoEngine is the name of the engine that picks another for each copy:
+// reflink when the source's tracked files are unchanged and the destination
+// can clone them, and archive otherwise.
+const AutoEngine = "auto"
+
+// DefaultEngine is the name of the engine used when none is chosen.
+const DefaultEngine = AutoEngine
+
+// engines are the available engines, by name.
+var engines = map[string]Engine{
+	AutoEngine: autoEngine{},
+	"archive":  archiveEngine{},
+	"tree":     treeEngine{},
+	"rsync":    rsyncEngine{},
+	"reflink":  reflinkEngine{},
+}
+
+// Names returns the names of the available engines in order.
+func Names() []string {
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the engine called name, or the default engine if name is
+// empty.
+func Get(name string) (Engine, error) {
+	if name == "" {
+		name = DefaultEngine
+	}
+	engine, ok := engines[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown copy engine %q: must be one of %s", name, strings.Join(Names(), ", "))
+	}
+	return engine, nil
+}
+
+// Choose returns the name of the engine AutoEngine picks to copy src into
+// dest. Copy-on-write clones are tried by cloning one tracked file next to
+// dest, so that support is known for the filesystems actually involved.
+func Choose(src, dest string) string {
+	modified, _, err := git.UncommittedFiles(src)
+	if err != nil || len(modified) > 0 {
+		return "archive"
+	}
+	entries, err := git.TrackedTree(src)
+	if err != nil {
+		return "archive"
+	}
+	for _, e := range entries {
+		if e.Mode == "100644" || e.Mode == "100755" {
+			if canClone(filepath.Join(src, filepath.FromSlash(e.Path)), filepath.Dir(dest)) {
+				return "reflink"
+			}
+			break
+		}
+	}
+	return "archive"
+}
+
+// canClone reports whether file can be cloned into dir without copying its
+// blocks.
+func canClone(file, dir string) bool {
+	var args []string
+	switch runtime.GOOS {
+	case "linux":
+		args = []string{"--reflink=always"}
+	case "darwin":
+		args = []string{"-c"}
+	default:
+		return false
+	}
+	probe, err := os.CreateTemp(dir, ".bury-it-reflink-*")
+	if err != nil {
+		return false
+	}
+	_ = probe.Close()
+	defer func() { _ = os.Remove(probe.Name()) }()
+	_, err = run(dir, nil, "cp", append(args, file, probe.Name())...)
+	return err == nil
+}
+
+// autoEngine copies with the engine Choose picks.
+type autoEngine struct{}
+
+func (autoEngine) Copy(src, dest string) error {
+	return engines[Choose(src, dest)].Copy(src, dest)
+}
+
+// archiveEngine pipes git archive into tar. It reads from the object
+// database, so uncommitted changes never leak into the copy.
+type archiveEngine struct{}
+
+func (archiveEngine) Copy(src, dest string) error {
+	return git.CopyTrackedFiles(src, dest)
+}
+
+// treeEngine walks the tree of HEAD and writes each blob itself, needing
+// neither tar nor any tool besides git.
+type treeEngine struct{}
+
+func (treeEngine) Copy(src, dest string) error {
+	entries, err := git.TrackedTree(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	var blobs []git.TreeEntry
+	for _, e := range entries {
+		if e.Mode == git.ModeSubmodule {
+			// Like git archive, leave an empty directory for a submodule
+			if err := os.MkdirAll(filepath.Join(dest, filepath.FromSlash(e.Path)), 0755); err != nil {
+				return fmt.Errorf("failed to create directory: %w", err)
+			}
+			continue
+		}
+		blobs = append(blobs, e)
+	}
+	objects := make([]string, len(blobs))
+	for i, e := range blobs {
+		objects[i] = e.Object
+	}
+	return git.ReadBlobs(src, objects, func(i int, r io.Reader) error {
+		return writeEntry(filepath.Join(dest, filepath.FromSlash(blobs[i].Path)), blobs[i].Mode, r)
+	})
+}
+
+// CopySubmodules copies the files of the checked-out submodules of the
+// repository at src, recursively, into the same paths under dest, as plain
+// files. Engines leave submodules as empty directories, which this fills.
+func CopySubmodules(src, dest string) error {
+	submodules, err := git.Submodules(src)
+	if err != nil {
+		return err
+	}
+	for _, sub := range submodules {
+		subSrc := filepath.Join(src, filepath.FromSlash(sub.Path))
+		subDest := filepath.Join(dest, filepath.FromSlash(sub.Path))
+		if err := os.RemoveAll(subDest); err != nil {
+			return err
+		}
+		if err := (treeEngine{}).Copy(subSrc, subDest); err != nil {
+			return fmt.Errorf("%s: %w", sub.Path, err)
+		}
+		if err := CopySubmodules(subSrc, subDest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CopyFiles copies the files at the given slash-separated paths under src
+// into the same paths under dest, keeping executables executable and
+// symbolic links as links.
+func CopyFiles(src, dest string, files []string) error {
+	for _, file := range files {
+		from := filepath.Join(src, filepath.FromSlash(file))
+		to := filepath.Join(dest, filepath.FromSlash(file))
+		info, err := os.Lstat(from)
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(from)
+			if err != nil {
+				return err
+			}
+			if err := writeEntry(to, git.ModeSymlink, strings.NewReader(target)); err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+			continue
+		}
+		mode := "100644"
+		if info.Mode()&0111 != 0 {
+			mode = "100755"
+		}
+		f, err := os.Open(from)
+		if err != nil {
+			return err
+		}
+		err = writeEntry(to, mode, f)
+		_ = f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// writeEntry writes a file, executable, or symbolic link read from a blob.
+func writeEntry(path, mode string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if mode == git.ModeSymlink {
+		target, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(string(target), path)
+	}
+	perm := os.FileMode(0644)
+	if mode == "100755" {
+		perm = 0755
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// rsyncEngine copies the tracked files from the working tree with rsync,
+// which keeps their modification times.
+type rsyncEngine struct{}
+
+func (rsyncEngine) Copy(src, dest string) error {
+	paths, err := workingTreeFiles(src, dest)
+	if err != nil {
+		return err
+	}
+	var list bytes.Buffer
+	for _, p := range paths {
+		list.WriteString(p)
+		list.WriteByte(0)
+	}
+	_, err = run(src, &list, "rsync", "--archive", "--from0", "--files-from=-", "./", dest+string(filepath.Separator))
+	return err
+}
+
+// reflinkEngine copies the tracked files from the working tree with cp,
+// sharing their blocks with the source on filesystems with copy-on-write
+// clones: with reflinks on Btrfs and XFS under Linux, and clonefile on APFS
+// under macOS. GNU cp falls back to copying elsewhere.
+type reflinkEngine struct{}
+
+// reflinkBatch is the most files passed to each cp.
+const reflinkBatch = 500
+
+func (reflinkEngine) Copy(src, dest string) error {
+	var flags []string
+	switch runtime.GOOS {
+	case "linux":
+		flags = []string{"--reflink=auto", "--no-dereference", "--preserve=mode,timestamps"}
+	case "darwin":
+		flags = []string{"-c", "-P", "-p"}
+	default:
+		return fmt.Errorf("the reflink copy engine is only available on Linux and macOS")
+	}
+	paths, err := workingTreeFiles(src, dest)
+	if err != nil {
+		return err
+	}
+	dest, err = filepath.Abs(dest)
+	if err != nil {
+		return err
+	}
+
+	// cp copies many files into one directory at a time, so copy each
+	// directory's files together
+	byDir := map[string][]string{}
+	var dirs []string
+	for _, p := range paths {
+		dir := filepath.Dir(p)
+		if _, ok := byDir[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		byDir[dir] = append(byDir[dir], p)
+	}
+	for _, dir := range dirs {
+		target := filepath.Join(dest, dir)
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		files := byDir[dir]
+		for start := 0; start < len(files); start += reflinkBatch {
+			end := min(start+reflinkBatch, len(files))
+			args := append(append(append([]string{}, flags...), "--"), files[start:end]...)
+			if _, err := run(src, nil, "cp", append(args, target)...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// workingTreeFiles returns the paths of the files tracked at HEAD of src for
+// engines that copy from the working tree, refusing if any were changed
+// since, and creates dest with the directories of its submodules.
+func workingTreeFiles(src, dest string) ([]string, error) {
+	modified, _, err := git.UncommittedFiles(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(modified) > 0 {
+		return nil, fmt.Errorf("%d tracked files differ from HEAD, which this copy engine would copy; commit them or use the archive engine", len(modified))
+	}
+	entries, err := git.TrackedTree(src)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create destination directory: %w", err)
+	}
+	var paths []string
+	for _, e := range entries {
+		if e.Mode == git.ModeSubmodule {
+			if err := os.MkdirAll(filepath.Join(dest, filepath.FromSlash(e.Path)), 0755); err != nil {
+				return nil, fmt.Errorf("failed to create directory: %w", err)
+			}
+			continue
+		}
+		paths = append(paths, filepath.FromSlash(e.Path))
+	}
+	return paths, nil
+}
+
+// run runs a copy tool in dir and returns its standard output.
+func run(dir string, stdin io.Reader, name string, args ...string) (string, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return "", fmt.Errorf("%s is required for this copy engine but was not found", name)
+	}
+	cmd := git.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Stdin = stdin
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("%s failed: %s", name, msg)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,204 @@
+package snapshot
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	if engine, err := Get(""); err != nil || engine != engines[DefaultEngine] {
+		t.Errorf("Get(\"\") = %v, %v, want the default engine", engine, err)
+	}
+	if _, err := Get("scp"); err == nil || !strings.Contains(err.Error(), "archive, auto, reflink, rsync, tree") {
+		t.Errorf("Get(scp) error = %v, want a list of engines", err)
+	}
+}
+
+func TestEngines(t *testing.T) {
+	src := newRepo(t)
+	for _, name := range Names() {
+		t.Run(name, func(t *testing.T) {
+			switch {
+			case name == "rsync" && !hasTool("rsync"):
+				t.Skip("rsync is not installed")
+			case name == "reflink" && runtime.GOOS != "linux" && runtime.GOOS != "darwin":
+				t.Skip("reflink needs Linux or macOS")
+			}
+			engine, err := Get(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dest := filepath.Join(t.TempDir(), "copy")
+			if err := engine.Copy(src, dest); err != nil {
+				t.Fatalf("Copy() error = %v", err)
+			}
+
+			for path, want := range map[string]string{
+				"README.md":           "# tool\n",
+				"cmd/tool/main.go":    "package main\n",
+				"scripts/run.sh":      "#!/bin/sh\n",
+				"notes with space.md": "spaces\n",
+			} {
+				got, err := os.ReadFile(filepath.Join(dest, path))
+				if err != nil || string(got) != want {
+					t.Errorf("%s = %q, %v, want %q", path, got, err, want)
+				}
+			}
+			for _, path := range []string{"ignored.log", "untracked.txt"} {
+				if _, err := os.Lstat(filepath.Join(dest, path)); !os.IsNotExist(err) {
+					t.Errorf("%s was copied, want only tracked files", path)
+				}
+			}
+			if info, err := os.Stat(filepath.Join(dest, "scripts/run.sh")); err != nil || info.Mode().Perm()&0100 == 0 {
+				t.Errorf("scripts/run.sh lost its executable bit: %v, %v", info, err)
+			}
+			if target, err := os.Readlink(filepath.Join(dest, "latest")); err != nil || target != "README.md" {
+				t.Errorf("latest = %q, %v, want a link to README.md", target, err)
+			}
+		})
+	}
+}
+
+func TestChoose(t *testing.T) {
+	src := newRepo(t)
+	parent := t.TempDir()
+	got := Choose(src, filepath.Join(parent, "copy"))
+	if got != "archive" && got != "reflink" {
+		t.Errorf("Choose() = %q, want archive or reflink", got)
+	}
+	if entries, _ := os.ReadDir(parent); len(entries) != 0 {
+		t.Errorf("Choose() left %d files behind", len(entries))
+	}
+}
+
+func TestEngines_ModifiedFiles(t *testing.T) {
+	src := newRepo(t)
+	writeFile(t, filepath.Join(src, "README.md"), "# changed\n", 0644)
+
+	// The archive and tree engines copy HEAD, so changes are left out
+	for _, name := range []string{"archive", "tree"} {
+		engine, _ := Get(name)
+		dest := filepath.Join(t.TempDir(), "copy")
+		if err := engine.Copy(src, dest); err != nil {
+			t.Fatalf("%s Copy() error = %v", name, err)
+		}
+		if got, _ := os.ReadFile(filepath.Join(dest, "README.md")); string(got) != "# tool\n" {
+			t.Errorf("%s copied README.md = %q, want the committed content", name, got)
+		}
+	}
+
+	// Choose never picks an engine that would copy the changes
+	if got := Choose(src, filepath.Join(t.TempDir(), "copy")); got != "archive" {
+		t.Errorf("Choose() = %q for a source with changes, want archive", got)
+	}
+
+	// The working tree engines refuse rather than copy something else
+	if runtime.GOOS == "linux" {
+		engine, _ := Get("reflink")
+		if err := engine.Copy(src, filepath.Join(t.TempDir(), "copy")); err == nil || !strings.Contains(err.Error(), "differ from HEAD") {
+			t.Errorf("reflink Copy() error = %v, want modified files error", err)
+		}
+	}
+}
+
+func TestCopySubmodules(t *testing.T) {
+	inner := newRepo(t)
+	lib := newRepo(t)
+	runGit(t, lib, "-c", "protocol.file.allow=always", "submodule", "add", "-q", inner, "deep")
+	runGit(t, lib, "-c", "user.email=t@t", "-c", "user.name=T", "commit", "-qm", "add deep")
+	app := newRepo(t)
+	runGit(t, app, "-c", "protocol.file.allow=always", "submodule", "add", "-q", lib, "vendor/lib")
+	runGit(t, app, "-c", "user.email=t@t", "-c", "user.name=T", "commit", "-qm", "add lib")
+	runGit(t, app, "-c", "protocol.file.allow=always", "submodule", "update", "-q", "--init", "--recursive")
+
+	engine, _ := Get("archive")
+	dest := filepath.Join(t.TempDir(), "copy")
+	if err := engine.Copy(app, dest); err != nil {
+		t.Fatal(err)
+	}
+	if err := CopySubmodules(app, dest); err != nil {
+		t.Fatalf("CopySubmodules() error = %v", err)
+	}
+	for _, path := range []string{"vendor/lib/README.md", "vendor/lib/deep/cmd/tool/main.go"} {
+		if got, err := os.ReadFile(filepath.Join(dest, path)); err != nil || len(got) == 0 {
+			t.Errorf("%s = %q, %v, want the submodule's file", path, got, err)
+		}
+	}
+	for _, path := range []string{"vendor/lib/.git", "vendor/lib/deep/.git", "vendor/lib/untracked.txt"} {
+		if _, err := os.Lstat(filepath.Join(dest, path)); !os.IsNotExist(err) {
+			t.Errorf("%s was copied, want only tracked files", path)
+		}
+	}
+}
+
+func TestCopyFiles(t *testing.T) {
+	src := t.TempDir()
+	writeFile(t, filepath.Join(src, "notes/todo.md"), "- ship\n", 0644)
+	writeFile(t, filepath.Join(src, "run.sh"), "#!/bin/sh\n", 0755)
+	if err := os.Symlink("notes/todo.md", filepath.Join(src, "todo")); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "copy")
+	if err := CopyFiles(src, dest, []string{"notes/todo.md", "run.sh", "todo"}); err != nil {
+		t.Fatalf("CopyFiles() error = %v", err)
+	}
+	if got, err := os.ReadFile(filepath.Join(dest, "notes/todo.md")); err != nil || string(got) != "- ship\n" {
+		t.Errorf("notes/todo.md = %q, %v", got, err)
+	}
+	if info, err := os.Stat(filepath.Join(dest, "run.sh")); err != nil || info.Mode()&0100 == 0 {
+		t.Errorf("run.sh is not executable: %v", err)
+	}
+	if target, err := os.Readlink(filepath.Join(dest, "todo")); err != nil || target != "notes/todo.md" {
+		t.Errorf("todo links to %q, %v, want notes/todo.md", target, err)
+	}
+}
+
+// newRepo returns a repository with a committed file, executable, and
+// symbolic link, and an ignored and an untracked file.
+func newRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q")
+	writeFile(t, filepath.Join(dir, "README.md"), "# tool\n", 0644)
+	writeFile(t, filepath.Join(dir, "cmd/tool/main.go"), "package main\n", 0644)
+	writeFile(t, filepath.Join(dir, "scripts/run.sh"), "#!/bin/sh\n", 0755)
+	writeFile(t, filepath.Join(dir, "notes with space.md"), "spaces\n", 0644)
+	writeFile(t, filepath.Join(dir, ".gitignore"), "*.log\n", 0644)
+	if err := os.Symlink("README.md", filepath.Join(dir, "latest")); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "-c", "user.email=t@t", "-c", "user.name=T", "commit", "-qm", "initial")
+	writeFile(t, filepath.Join(dir, "ignored.log"), "log\n", 0644)
+	writeFile(t, filepath.Join(dir, "untracked.txt"), "untracked\n", 0644)
+	return dir
+}
+
+func hasTool(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+func writeFile(t *testing.T, path, content string, perm os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), perm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
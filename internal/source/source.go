@@ -2,13 +2,20 @@
 package source
 
 import (
+	"bufio"
+	"encoding/base64"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
 
 	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/unpack"
 )
 
 // Type represents the type of source repository.
@@ -17,13 +24,35 @@ type Type int
 const (
 	// TypeLocal represents a local filesystem repository.
 	TypeLocal Type = iota
-	// TypeRemote represents a remote GitHub repository.
+	// TypeRemote represents a remote repository, on GitHub, GitLab,
+	// Bitbucket, or any other host git can fetch from.
 	TypeRemote
+	// TypeArchive represents a tar or zip archive of a project, a local file
+	// or a URL to download it from.
+	TypeArchive
+	// TypeMercurial represents a Mercurial repository, a local one or a URL
+	// given as hg::<url>, whose history is converted to git.
+	TypeMercurial
+	// TypeSubversion represents a Subversion repository, at an svn:// or
+	// svn+ssh:// URL, another URL given as svn::<url>, or a local path,
+	// whose history is converted to git.
+	TypeSubversion
+	// TypePlain represents a local directory that was never under version
+	// control, whose files are committed as a single snapshot.
+	TypePlain
+)
+
+// Prefixes marking a URL as that of a Mercurial or Subversion repository,
+// as in hg::https://hg.example.com/repo.
+const (
+	MercurialPrefix  = "hg::"
+	SubversionPrefix = "svn::"
 )
 
 // Source represents a parsed source repository.
 type Source struct {
-	// Type is the source type (local or remote).
+	// Type is the source type (local, remote, archive, Mercurial,
+	// Subversion, or plain).
 	Type Type
 	// Path is the local filesystem path (for local repos) or the URL (for remote repos).
 	Path string
@@ -31,44 +60,306 @@ type Source struct {
 	Name string
 	// OriginalInput is the original input string.
 	OriginalInput string
+
+	// host and project are the host and project path a shorthand source
+	// expands to, e.g. github.com and owner/repo.
+	host    string
+	project string
 }
 
-// gitHubURLPattern matches GitHub URLs.
-var gitHubURLPattern = regexp.MustCompile(`^https?://github\.com/([^/]+)/([^/]+?)(?:\.git)?/?$`)
+// Transport is the protocol a shorthand source is cloned over.
+type Transport string
+
+const (
+	// TransportHTTPS clones over HTTPS, e.g. https://github.com/owner/repo.
+	TransportHTTPS Transport = "https"
+	// TransportSSH clones over SSH, e.g. git@github.com:owner/repo.git.
+	TransportSSH Transport = "ssh"
+)
+
+// ParseTransport parses a transport name.
+func ParseTransport(name string) (Transport, error) {
+	switch t := Transport(strings.ToLower(name)); t {
+	case TransportHTTPS, TransportSSH:
+		return t, nil
+	}
+	return "", fmt.Errorf("unknown transport %q: must be ssh or https", name)
+}
+
+// gitHubURLPattern matches GitHub HTTPS and SSH URLs.
+var gitHubURLPattern = regexp.MustCompile(`^(?:https?://github\.com/|ssh://git@github\.com/|git@github\.com:)([^/]+)/([^/]+?)(?:\.git)?/?$`)
+
+// gitHubPageURLPattern matches links to pages of a GitHub repository, such
+// as https://github.com/owner/repo/tree/main, capturing the repository URL.
+var gitHubPageURLPattern = regexp.MustCompile(`^(https?://github\.com/[^/]+/[^/]+?)(?:\.git)?/[^/].*$`)
+
+// gistURLPattern matches the URL of a GitHub gist, with or without its
+// owner, capturing its ID.
+var gistURLPattern = regexp.MustCompile(`^(?:https?://gist\.github\.com/(?:[a-zA-Z0-9-]+/)?|git@gist\.github\.com:)([0-9a-f]{20}|[0-9a-f]{32})(?:\.git)?/?(?:#.*)?$`)
+
+// gistIDPattern matches the ID of a GitHub gist, on its own or given as
+// gist:<id>.
+var gistIDPattern = regexp.MustCompile(`^(gist:)?([0-9a-f]{20}|[0-9a-f]{32})$`)
 
 // ownerRepoPattern matches owner/repo shorthand.
 var ownerRepoPattern = regexp.MustCompile(`^([a-zA-Z0-9_.-]+)/([a-zA-Z0-9_.-]+)$`)
 
+// gitLabURLPattern matches gitlab.com HTTPS and SSH URLs. GitLab projects may
+// sit in nested groups, so the project path has two or more segments.
+var gitLabURLPattern = regexp.MustCompile(`^(?:https?://gitlab\.com/|ssh://git@gitlab\.com/|git@gitlab\.com:)((?:[a-zA-Z0-9_.-]+/)+([a-zA-Z0-9_.-]+?))(?:\.git)?/?$`)
+
+// prefixedShorthandPattern matches prefixed shorthand such as gl:group/repo,
+// capturing the prefix, the project path, which may sit in nested groups, and
+// the repository name.
+var prefixedShorthandPattern = regexp.MustCompile(`^([a-z][a-z0-9-]*):((?:[a-zA-Z0-9_.-]+/)+([a-zA-Z0-9_.-]+))$`)
+
+// DefaultPrefixes are the shorthand prefixes recognized without
+// configuration, and the hosts they expand to.
+var DefaultPrefixes = map[string]string{
+	"gh":        "github.com",
+	"github":    "github.com",
+	"gl":        "gitlab.com",
+	"gitlab":    "gitlab.com",
+	"bb":        "bitbucket.org",
+	"bitbucket": "bitbucket.org",
+}
+
+// gitLabPageSeparator separates a GitLab project URL from the path of one of
+// its pages, as in https://gitlab.com/group/repo/-/tree/main.
+const gitLabPageSeparator = "/-/"
+
+// bitbucketURLPattern matches bitbucket.org HTTPS and SSH URLs, capturing
+// the URL up to the workspace, the workspace, the repository, and the .git
+// suffix. HTTPS clone URLs may name the user, as in
+// https://user@bitbucket.org/workspace/repo.git, and links to pages of a
+// repository, such as /src/main/, are accepted too.
+var bitbucketURLPattern = regexp.MustCompile(`^((?:https?://(?:[^@/]+@)?|ssh://git@)bitbucket\.org/|git@bitbucket\.org:)([a-zA-Z0-9_.-]+)/([a-zA-Z0-9_.-]+?)(\.git)?(?:/.*)?$`)
+
+// azureDevOpsURLPatterns match Azure DevOps HTTPS URLs, including the
+// legacy org.visualstudio.com form and links to pages of a repository, and
+// SSH URLs. Each captures the clone URL, the organization, the project, and
+// the repository.
+var azureDevOpsURLPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^(https://(?:[^@/]+@)?dev\.azure\.com/([^/]+)/([^/]+)/_git/([^/?#]+))(?:[/?#].*)?$`),
+	regexp.MustCompile(`^(https://(?:[^@/]+@)?([a-zA-Z0-9-]+)\.visualstudio\.com/(?:DefaultCollection/)?([^/]+)/_git/([^/?#]+))(?:[/?#].*)?$`),
+	regexp.MustCompile(`^((?:ssh://git@ssh\.dev\.azure\.com/|git@ssh\.dev\.azure\.com:)v3/([^/]+)/([^/]+)/([^/]+?))/?$`),
+}
+
+// AzureDevOpsPATEnv is the environment variable holding the personal access
+// token used to clone Azure DevOps sources over HTTPS. It is the variable the
+// Azure CLI reads.
+const AzureDevOpsPATEnv = "AZURE_DEVOPS_EXT_PAT"
+
+// codeCommitURLPattern matches AWS CodeCommit HTTPS and SSH URLs, capturing
+// the region and the repository.
+var codeCommitURLPattern = regexp.MustCompile(`^(?:https|ssh)://(?:[^@/]+@)?git-codecommit(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?/v1/repos/([a-zA-Z0-9_.-]+)/?$`)
+
+// codeCommitGRCPattern matches the codecommit:: URLs of git-remote-codecommit,
+// such as codecommit::us-east-1://profile@repo, capturing the region and the
+// repository. The region and profile are optional.
+var codeCommitGRCPattern = regexp.MustCompile(`^codecommit(?:::([a-z0-9-]+))?://(?:[^@/]+@)?([a-zA-Z0-9_.-]+)$`)
+
+// codeCommitCredentialHelper is the git credential helper of the AWS CLI,
+// which signs CodeCommit HTTPS requests with the caller's AWS credentials.
+const codeCommitCredentialHelper = "!aws codecommit credential-helper $@"
+
+// remoteURLPattern matches URLs of any host in a scheme git can fetch from,
+// such as a self-hosted Gitea, cgit, or corporate forge, capturing the last
+// path segment as the repository name.
+var remoteURLPattern = regexp.MustCompile(`^(?:https?|ssh|git|ftps?|file)://(?:[^@/]+@)?[^/]*/(?:[^/]*/)*([^/]+?)(?:\.git)?/?$`)
+
+// SCPURLPattern matches scp-style URLs of any host, such as
+// git@example.com:team/repo.git, capturing the repository name. The user is
+// required so that local paths containing a colon are not mistaken for URLs.
+var SCPURLPattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]+@[a-zA-Z0-9_.-]+:(?:[^/]*/)*([^/]+?)(?:\.git)?/?$`)
+
+// hostedURLPattern matches URLs and scp-style URLs of a repository at
+// owner/repo on any host, capturing the host, owner, and repository name.
+var hostedURLPattern = regexp.MustCompile(`^(?:(?:https?|ssh|git)://(?:[^@/]+@)?([a-zA-Z0-9_.-]+)(?::\d+)?/|[a-zA-Z0-9_.-]+@([a-zA-Z0-9_.-]+):)([a-zA-Z0-9_.-]+)/([a-zA-Z0-9_.-]+?)(?:\.git)?/?$`)
+
+// ResolveSubmoduleURL resolves a submodule URL from .gitmodules against the
+// URL or path of the repository it belongs to, as git does for URLs starting
+// with ./ or ../. Other URLs are returned unchanged.
+func ResolveSubmoduleURL(base, submodule string) string {
+	if !strings.HasPrefix(submodule, "./") && !strings.HasPrefix(submodule, "../") {
+		return submodule
+	}
+	if u, err := url.Parse(base); err == nil && u.Scheme != "" && u.Host != "" {
+		u.Path = path.Join(u.Path, submodule)
+		return u.String()
+	}
+	if SCPURLPattern.MatchString(base) {
+		host, repoPath, _ := strings.Cut(base, ":")
+		return host + ":" + path.Join(repoPath, submodule)
+	}
+	return filepath.Join(base, filepath.FromSlash(submodule))
+}
+
+// ReadList reads a list of sources, one per line. Blank lines and lines
+// starting with # are skipped. Only the first tab-separated field of a line is
+// read, so the output of gh repo list can be used as is.
+func ReadList(r io.Reader) ([]string, error) {
+	var sources []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "\t")
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sources = append(sources, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read sources: %w", err)
+	}
+	return sources, nil
+}
+
+// CheckPrefix checks that prefix can be used as a shorthand prefix, as in
+// prefix:owner/repo.
+func CheckPrefix(prefix string) error {
+	if !prefixedShorthandPattern.MatchString(prefix + ":owner/repo") {
+		return fmt.Errorf("invalid prefix %q: want a lowercase word such as corp", prefix)
+	}
+	return nil
+}
+
 // Parse parses the input string and returns a Source.
 func Parse(input string) (*Source, error) {
+	return ParseWithPrefixes(input, nil)
+}
+
+// ParseWithPrefixes parses the input string like Parse, also expanding
+// shorthand with the given prefixes, keyed by prefix, which add to or
+// override DefaultPrefixes.
+func ParseWithPrefixes(input string, prefixes map[string]string) (*Source, error) {
 	input = strings.TrimSpace(input)
 	if input == "" {
 		return nil, fmt.Errorf("source cannot be empty")
 	}
 
-	// Check if it's a GitHub URL
-	if matches := gitHubURLPattern.FindStringSubmatch(input); matches != nil {
+	// Check if it's a Mercurial or Subversion repository to convert
+	if src, err := parseConverted(input); src != nil || err != nil {
+		return src, err
+	}
+
+	// Check if it's a URL of a tar or zip archive, such as a release
+	if u, err := url.Parse(input); err == nil && (u.Scheme == "http" || u.Scheme == "https") && unpack.Ext(u.Path) != "" {
 		return &Source{
-			Type:          TypeRemote,
+			Type:          TypeArchive,
 			Path:          input,
+			Name:          archiveName(u.Path),
+			OriginalInput: input,
+		}, nil
+	}
+
+	// Check if it's a gist, by URL or ID. A bare ID could also be the name
+	// of a local directory, which wins.
+	if matches := gistURLPattern.FindStringSubmatch(input); matches != nil {
+		return gist(matches[1], input), nil
+	}
+	if matches := gistIDPattern.FindStringSubmatch(input); matches != nil {
+		if _, err := os.Stat(input); matches[1] != "" || os.IsNotExist(err) {
+			return gist(matches[2], input), nil
+		}
+	}
+
+	// Check if it's a GitHub URL, or a link to a page of a GitHub repository
+	gitHubURL := input
+	if matches := gitHubPageURLPattern.FindStringSubmatch(input); matches != nil {
+		gitHubURL = matches[1]
+	}
+	if matches := gitHubURLPattern.FindStringSubmatch(gitHubURL); matches != nil {
+		return &Source{
+			Type:          TypeRemote,
+			Path:          gitHubURL,
 			Name:          matches[2],
 			OriginalInput: input,
 		}, nil
 	}
 
-	// Check if it's owner/repo shorthand (but not a local path like ./foo or /foo)
-	if !strings.HasPrefix(input, ".") && !strings.HasPrefix(input, "/") && !strings.HasPrefix(input, "~") {
-		if matches := ownerRepoPattern.FindStringSubmatch(input); matches != nil {
-			url := fmt.Sprintf("https://github.com/%s/%s", matches[1], matches[2])
+	// Check if it's a GitLab URL, or a link to a page of a GitLab project
+	gitLabURL := input
+	if i := strings.Index(gitLabURL, gitLabPageSeparator); i >= 0 {
+		gitLabURL = gitLabURL[:i]
+	}
+	if matches := gitLabURLPattern.FindStringSubmatch(gitLabURL); matches != nil {
+		return &Source{
+			Type:          TypeRemote,
+			Path:          gitLabURL,
+			Name:          matches[2],
+			OriginalInput: input,
+		}, nil
+	}
+
+	// Check if it's a Bitbucket URL, dropping the path of any page
+	if matches := bitbucketURLPattern.FindStringSubmatch(input); matches != nil {
+		return &Source{
+			Type:          TypeRemote,
+			Path:          matches[1] + matches[2] + "/" + matches[3] + matches[4],
+			Name:          matches[3],
+			OriginalInput: input,
+		}, nil
+	}
+
+	// Check if it's an Azure DevOps URL, dropping the path of any page
+	for _, pattern := range azureDevOpsURLPatterns {
+		if matches := pattern.FindStringSubmatch(input); matches != nil {
+			name, err := url.PathUnescape(matches[4])
+			if err != nil {
+				name = matches[4]
+			}
 			return &Source{
 				Type:          TypeRemote,
-				Path:          url,
+				Path:          matches[1],
+				Name:          name,
+				OriginalInput: input,
+			}, nil
+		}
+	}
+
+	// Check if it's an AWS CodeCommit URL
+	for _, pattern := range []*regexp.Regexp{codeCommitURLPattern, codeCommitGRCPattern} {
+		if matches := pattern.FindStringSubmatch(input); matches != nil {
+			return &Source{
+				Type:          TypeRemote,
+				Path:          input,
 				Name:          matches[2],
 				OriginalInput: input,
 			}, nil
 		}
 	}
 
+	// Check if it's prefixed shorthand such as gl:group/repo
+	if matches := prefixedShorthandPattern.FindStringSubmatch(input); matches != nil {
+		host, ok := prefixes[matches[1]]
+		if !ok {
+			host, ok = DefaultPrefixes[matches[1]]
+		}
+		if ok {
+			return shorthand(host, matches[2], matches[3], input), nil
+		}
+	}
+
+	// Check if it's a URL of any other host
+	for _, pattern := range []*regexp.Regexp{remoteURLPattern, SCPURLPattern} {
+		if matches := pattern.FindStringSubmatch(input); matches != nil {
+			return &Source{
+				Type:          TypeRemote,
+				Path:          input,
+				Name:          matches[1],
+				OriginalInput: input,
+			}, nil
+		}
+	}
+
+	// Check if it's owner/repo shorthand (but not a local path like ./foo or /foo)
+	if !strings.HasPrefix(input, ".") && !strings.HasPrefix(input, "/") && !strings.HasPrefix(input, "~") {
+		if matches := ownerRepoPattern.FindStringSubmatch(input); matches != nil {
+			return shorthand("github.com", matches[1]+"/"+matches[2], matches[2], input), nil
+		}
+	}
+
 	// Treat as local path
 	path := input
 
@@ -87,8 +378,44 @@ func Parse(input string) (*Source, error) {
 		return nil, fmt.Errorf("failed to resolve path: %w", err)
 	}
 
-	// Extract project name from path
+	// A local Mercurial repository is converted rather than buried as it is
+	if isDir(filepath.Join(absPath, ".hg")) && !isDir(filepath.Join(absPath, ".git")) {
+		return &Source{
+			Type:          TypeMercurial,
+			Path:          absPath,
+			Name:          convertedName(absPath),
+			OriginalInput: input,
+		}, nil
+	}
+	if isSubversionRepo(absPath) {
+		return &Source{
+			Type:          TypeSubversion,
+			Path:          absPath,
+			Name:          convertedName(absPath),
+			OriginalInput: input,
+		}, nil
+	}
+
+	// A tar or zip archive is named without its extension
+	if unpack.Ext(absPath) != "" {
+		if info, err := os.Stat(absPath); err != nil || !info.IsDir() {
+			return &Source{
+				Type:          TypeArchive,
+				Path:          absPath,
+				Name:          unpack.TrimExt(filepath.Base(absPath)),
+				OriginalInput: input,
+			}, nil
+		}
+	}
+
+	// Extract project name from path, without the extension of a bundle or
+	// the .git of a bare repository
 	name := filepath.Base(absPath)
+	for _, ext := range []string{".bundle", ".git"} {
+		if trimmed := strings.TrimSuffix(name, ext); trimmed != "" {
+			name = trimmed
+		}
+	}
 
 	return &Source{
 		Type:          TypeLocal,
@@ -98,11 +425,266 @@ func Parse(input string) (*Source, error) {
 	}, nil
 }
 
+// ParsePlain parses input as the path of a plain directory, one that is not
+// under version control, whatever it holds.
+func ParsePlain(input string) (*Source, error) {
+	path := strings.TrimSpace(input)
+	if path == "" {
+		return nil, fmt.Errorf("source cannot be empty")
+	}
+	if strings.HasPrefix(path, "~") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand home directory: %w", err)
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	return &Source{
+		Type:          TypePlain,
+		Path:          absPath,
+		Name:          filepath.Base(absPath),
+		OriginalInput: input,
+	}, nil
+}
+
+// parseConverted parses a Mercurial or Subversion source given as a URL,
+// returning nil if input is not one.
+func parseConverted(input string) (*Source, error) {
+	typ := TypeSubversion
+	location, ok := strings.CutPrefix(input, SubversionPrefix)
+	if !ok {
+		location, ok = strings.CutPrefix(input, MercurialPrefix)
+		typ = TypeMercurial
+	}
+	switch {
+	case ok:
+		if location == "" {
+			return nil, fmt.Errorf("source %s names no repository", input)
+		}
+		if !strings.Contains(location, "://") {
+			abs, err := filepath.Abs(location)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve path: %w", err)
+			}
+			location = abs
+		}
+	case strings.HasPrefix(input, "svn://"), strings.HasPrefix(input, "svn+ssh://"):
+		location, typ = input, TypeSubversion
+	default:
+		return nil, nil
+	}
+	return &Source{
+		Type:          typ,
+		Path:          location,
+		Name:          convertedName(location),
+		OriginalInput: input,
+	}, nil
+}
+
+// convertedName returns the project name of a Mercurial or Subversion
+// repository: the last segment of its path or URL, or, for the trunk of a
+// Subversion repository, the one before it.
+func convertedName(location string) string {
+	segments := strings.Split(strings.TrimRight(location, "/"), "/")
+	name := segments[len(segments)-1]
+	if name == "trunk" && len(segments) > 1 {
+		name = segments[len(segments)-2]
+	}
+	return strings.TrimSuffix(name, ".hg")
+}
+
+// isDir reports whether path is a directory.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// isSubversionRepo reports whether path is a Subversion repository, as
+// created by svnadmin, rather than a working copy.
+func isSubversionRepo(path string) bool {
+	data, err := os.ReadFile(filepath.Join(path, "README.txt"))
+	return err == nil && strings.Contains(string(data), "Subversion repository") && isDir(filepath.Join(path, "db"))
+}
+
+// archiveName returns the project name of an archive downloaded from
+// urlPath: the repository it was made from if it is the archive of a GitHub
+// or GitLab repository, as in /owner/repo/archive/refs/tags/v1.0.tar.gz or
+// /group/repo/-/archive/main/repo-main.zip, or else its file name without
+// the extension.
+func archiveName(urlPath string) string {
+	segments := strings.Split(strings.Trim(urlPath, "/"), "/")
+	for i := len(segments) - 1; i > 0; i-- {
+		if segments[i] != "archive" {
+			continue
+		}
+		if segments[i-1] == "-" && i > 1 {
+			return segments[i-2]
+		}
+		return segments[i-1]
+	}
+	return unpack.TrimExt(path.Base(urlPath))
+}
+
+// shorthand returns the remote source a shorthand input expands to, cloned
+// over HTTPS.
+func shorthand(host, project, name, input string) *Source {
+	s := &Source{
+		Type:          TypeRemote,
+		Name:          name,
+		OriginalInput: input,
+		host:          host,
+		project:       project,
+	}
+	s.Path = s.ShorthandURL(TransportHTTPS)
+	return s
+}
+
+// gist returns a source for the GitHub gist with the given ID, named after
+// it until its description is known.
+func gist(id, input string) *Source {
+	return &Source{
+		Type:          TypeRemote,
+		Path:          "https://gist.github.com/" + id + ".git",
+		Name:          "gist-" + id,
+		OriginalInput: input,
+	}
+}
+
+// GistID returns the ID of a GitHub gist source.
+func (s *Source) GistID() (string, bool) {
+	if s.Type != TypeRemote {
+		return "", false
+	}
+	matches := gistURLPattern.FindStringSubmatch(s.Path)
+	if matches == nil {
+		return "", false
+	}
+	return matches[1], true
+}
+
+// ShorthandHost returns the host a source given as shorthand expands to, such
+// as github.com for owner/repo, or "" if the source was given as a URL or
+// path.
+func (s *Source) ShorthandHost() string {
+	return s.host
+}
+
+// OnHost returns the source as it would be if its shorthand expanded to
+// host, such as a GitHub Enterprise Server host for owner/repo. Sources given
+// as a URL or path are returned as they are.
+func (s *Source) OnHost(host string) *Source {
+	if s.host == "" {
+		return s
+	}
+	moved := *s
+	moved.host = host
+	moved.Path = moved.ShorthandURL(TransportHTTPS)
+	return &moved
+}
+
+// ShorthandURL returns the URL to clone a source given as shorthand over
+// transport. Sources given as a URL or path are returned as they are.
+func (s *Source) ShorthandURL(transport Transport) string {
+	switch {
+	case s.host == "":
+		return s.Path
+	case transport == TransportSSH:
+		return fmt.Sprintf("git@%s:%s.git", s.host, s.project)
+	}
+	return fmt.Sprintf("https://%s/%s", s.host, s.project)
+}
+
+// FilePath returns the local path a file:// URL source names, such as a
+// mirror on a network drive, which is cloned without any network access.
+// It reports false for any other source.
+func (s *Source) FilePath() (string, bool) {
+	if s.Type != TypeRemote || !strings.HasPrefix(s.Path, "file://") {
+		return "", false
+	}
+	u, err := url.Parse(s.Path)
+	if err != nil || (u.Host != "" && u.Host != "localhost") || u.Path == "" {
+		return "", false
+	}
+	return filepath.FromSlash(u.Path), true
+}
+
+// IsURL reports whether an archive, Mercurial, or Subversion source is
+// given as a URL rather than a local path.
+func (s *Source) IsURL() bool {
+	return s.Type != TypeLocal && s.Type != TypeRemote && strings.Contains(s.Path, "://")
+}
+
+// IsDownload reports whether the source is an archive to download.
+func (s *Source) IsDownload() bool {
+	return s.Type == TypeArchive && s.IsURL()
+}
+
+// ConvertedFrom returns the version control system the history of a
+// Mercurial or Subversion source is converted from, or "" for sources
+// buried as they are.
+func (s *Source) ConvertedFrom() string {
+	switch s.Type {
+	case TypeMercurial:
+		return "Mercurial"
+	case TypeSubversion:
+		return "Subversion"
+	}
+	return ""
+}
+
+// IsBare reports whether the source is a local bare repository, which has
+// no working tree to bury files from.
+func (s *Source) IsBare() bool {
+	return s.Type == TypeLocal && git.IsBareRepo(s.Path)
+}
+
+// IsBundle reports whether the source is a git bundle file, which is cloned
+// to be buried.
+func (s *Source) IsBundle() bool {
+	return s.Type == TypeLocal && git.IsBundle(s.Path)
+}
+
 // Validate validates that the source is a valid git repository.
 func (s *Source) Validate() error {
 	switch s.Type {
 	case TypeLocal:
 		// Check if path exists
+		info, err := os.Stat(s.Path)
+		if os.IsNotExist(err) {
+			return fmt.Errorf("source path does not exist: %s", s.Path)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to access source path: %w", err)
+		}
+		if !info.IsDir() {
+			if git.IsBundle(s.Path) {
+				return nil
+			}
+			return fmt.Errorf("source path is neither a directory nor a git bundle: %s", s.Path)
+		}
+		// Check if it's a git repository, with or without a working tree
+		if isDir(filepath.Join(s.Path, ".svn")) && !git.IsValidRepo(s.Path) {
+			return fmt.Errorf("source is a Subversion working copy: %s; give the URL of its repository, as svn::<url> if it is not an svn:// URL", s.Path)
+		}
+		if target := git.LinkedGitDir(s.Path); target != "" && !git.IsValidRepo(s.Path) {
+			return fmt.Errorf("source is a linked worktree or submodule checkout whose git directory is missing: %s points at %s", s.Path, target)
+		}
+		if !git.IsValidRepo(s.Path) && !git.IsBareRepo(s.Path) {
+			return fmt.Errorf("source is not a git repository: %s; bury a directory never under version control with --source-type plain", s.Path)
+		}
+	case TypeMercurial, TypeSubversion:
+		// Repositories at a URL are checked as they are converted
+		if s.IsURL() {
+			return nil
+		}
+		if _, err := os.Stat(s.Path); err != nil {
+			return fmt.Errorf("source path does not exist: %s", s.Path)
+		}
+	case TypePlain:
 		info, err := os.Stat(s.Path)
 		if os.IsNotExist(err) {
 			return fmt.Errorf("source path does not exist: %s", s.Path)
@@ -113,21 +695,102 @@ func (s *Source) Validate() error {
 		if !info.IsDir() {
 			return fmt.Errorf("source path is not a directory: %s", s.Path)
 		}
-		// Check if it's a git repository
-		if !git.IsValidRepo(s.Path) {
-			return fmt.Errorf("source is not a git repository: %s", s.Path)
+		if git.IsValidRepo(s.Path) || git.IsBareRepo(s.Path) {
+			return fmt.Errorf("source is a git repository: %s; bury it without --source-type plain to keep its history", s.Path)
+		}
+	case TypeArchive:
+		// Archives to download are checked as they are downloaded
+		if s.IsDownload() {
+			return nil
+		}
+		info, err := os.Stat(s.Path)
+		if os.IsNotExist(err) {
+			return fmt.Errorf("source path does not exist: %s", s.Path)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to access source path: %w", err)
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("source archive is not a file: %s", s.Path)
 		}
 	case TypeRemote:
-		// Remote repos will be validated during clone
-		// We could add a lightweight check here (e.g., git ls-remote) but that
-		// would add latency for valid repos. We'll let clone fail with a clear error.
+		// Repositories on the well-known forges will be validated during
+		// clone, saving a round trip for valid repos. Other hosts are checked
+		// up front, since a URL of any host is accepted.
+		if codeCommitGRCPattern.MatchString(s.Path) {
+			if _, err := exec.LookPath("git-remote-codecommit"); err != nil {
+				return fmt.Errorf("git-remote-codecommit is required to clone %s; install it with pip install git-remote-codecommit", s.Path)
+			}
+		}
+		if s.isForge() {
+			return nil
+		}
+		if path, ok := s.FilePath(); ok {
+			if !git.IsValidRepo(path) && !git.IsBareRepo(path) {
+				return fmt.Errorf("no git repository at %s, named by source %s", path, s.Path)
+			}
+			return nil
+		}
+		if err := git.LsRemote(s.Path); err != nil {
+			return fmt.Errorf("cannot reach remote source %s: %w", s.Path, err)
+		}
+	}
+	return nil
+}
+
+// isForge reports whether the source is on GitHub, as a repository or a gist,
+// GitLab, Bitbucket, Azure DevOps, or AWS CodeCommit.
+func (s *Source) isForge() bool {
+	if _, ok := s.GistID(); ok {
+		return true
+	}
+	if _, _, ok := s.GitHubRepo(); ok {
+		return true
+	}
+	if _, ok := s.GitLabProject(); ok {
+		return true
+	}
+	if _, _, _, ok := s.AzureDevOpsRepo(); ok {
+		return true
+	}
+	if _, _, ok := s.CodeCommitRepo(); ok {
+		return true
+	}
+	_, _, ok := s.BitbucketRepo()
+	return ok
+}
+
+// CloneEnv returns the environment variables git needs to clone the source
+// over HTTPS: for an Azure DevOps source, a header authenticating with the
+// personal access token in AZURE_DEVOPS_EXT_PAT, if it is set; for an AWS
+// CodeCommit source, the AWS CLI's credential helper, if the CLI is
+// installed. Both are passed through git's environment, so they are neither
+// shown in the process list nor saved in the clone's configuration.
+func (s *Source) CloneEnv() []string {
+	if !strings.HasPrefix(s.Path, "https://") {
+		return nil
+	}
+	if _, _, _, ok := s.AzureDevOpsRepo(); ok {
+		token := os.Getenv(AzureDevOpsPATEnv)
+		if token == "" {
+			return nil
+		}
+		credentials := base64.StdEncoding.EncodeToString([]byte(":" + token))
+		return git.ExtraHeaderEnv("Authorization: Basic " + credentials)
+	}
+	if _, _, ok := s.CodeCommitRepo(); ok {
+		if _, err := exec.LookPath("aws"); err != nil {
+			return nil
+		}
+		// CodeCommit credentials are scoped to the repository's path
+		return git.ConfigEnv("credential.helper", codeCommitCredentialHelper, "credential.UseHttpPath", "true")
 	}
 	return nil
 }
 
 // DisplayPath returns a human-readable path for display purposes.
 func (s *Source) DisplayPath() string {
-	if s.Type == TypeRemote {
+	if s.Type != TypeLocal {
 		return s.Path
 	}
 	// For local repos, try to get remote URL, otherwise use path
@@ -136,3 +799,74 @@ func (s *Source) DisplayPath() string {
 	}
 	return s.Path
 }
+
+// GitHubRepo returns the owner and repository name if the source is hosted on
+// GitHub. Local repositories are matched using their origin remote.
+func (s *Source) GitHubRepo() (owner, repo string, ok bool) {
+	matches := gitHubURLPattern.FindStringSubmatch(s.DisplayPath())
+	if matches == nil {
+		return "", "", false
+	}
+	return matches[1], matches[2], true
+}
+
+// GitLabProject returns the full path of the project, groups included, if the
+// source is hosted on gitlab.com. Local repositories are matched using their
+// origin remote.
+func (s *Source) GitLabProject() (string, bool) {
+	matches := gitLabURLPattern.FindStringSubmatch(s.DisplayPath())
+	if matches == nil {
+		return "", false
+	}
+	return matches[1], true
+}
+
+// BitbucketRepo returns the workspace and repository name if the source is
+// hosted on bitbucket.org. Local repositories are matched using their origin
+// remote.
+func (s *Source) BitbucketRepo() (workspace, repo string, ok bool) {
+	matches := bitbucketURLPattern.FindStringSubmatch(s.DisplayPath())
+	if matches == nil {
+		return "", "", false
+	}
+	return matches[2], matches[3], true
+}
+
+// HostedRepo returns the host, owner, and repository name if the source is a
+// repository at owner/repo on some host, as on Gitea and Forgejo instances.
+// Local repositories are matched using their origin remote.
+func (s *Source) HostedRepo() (host, owner, repo string, ok bool) {
+	matches := hostedURLPattern.FindStringSubmatch(s.DisplayPath())
+	if matches == nil {
+		return "", "", "", false
+	}
+	host = matches[1] + matches[2]
+	return strings.ToLower(host), matches[3], matches[4], true
+}
+
+// AzureDevOpsRepo returns the organization, project, and repository name if
+// the source is hosted on Azure DevOps. Local repositories are matched using
+// their origin remote.
+func (s *Source) AzureDevOpsRepo() (org, project, repo string, ok bool) {
+	path := s.DisplayPath()
+	for _, pattern := range azureDevOpsURLPatterns {
+		if matches := pattern.FindStringSubmatch(path); matches != nil {
+			return matches[2], matches[3], matches[4], true
+		}
+	}
+	return "", "", "", false
+}
+
+// CodeCommitRepo returns the region and repository name if the source is an
+// AWS CodeCommit repository. The region is empty for a codecommit:: URL that
+// leaves it to the AWS configuration. Local repositories are matched using
+// their origin remote.
+func (s *Source) CodeCommitRepo() (region, repo string, ok bool) {
+	path := s.DisplayPath()
+	for _, pattern := range []*regexp.Regexp{codeCommitURLPattern, codeCommitGRCPattern} {
+		if matches := pattern.FindStringSubmatch(path); matches != nil {
+			return matches[1], matches[2], true
+		}
+	}
+	return "", "", false
+}
@@ -2,7 +2,10 @@ package source
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -36,6 +39,34 @@ func TestParse(t *testing.T) {
 			wantName:    "repo",
 			wantPathSfx: "https://github.com/owner/repo/",
 		},
+		{
+			name:        "gist url with owner",
+			input:       "https://gist.github.com/alice/aa5a315d61ae9438b18d",
+			wantType:    TypeRemote,
+			wantName:    "gist-aa5a315d61ae9438b18d",
+			wantPathSfx: "https://gist.github.com/aa5a315d61ae9438b18d.git",
+		},
+		{
+			name:        "gist clone url",
+			input:       "https://gist.github.com/0123456789abcdef0123456789abcdef.git",
+			wantType:    TypeRemote,
+			wantName:    "gist-0123456789abcdef0123456789abcdef",
+			wantPathSfx: "https://gist.github.com/0123456789abcdef0123456789abcdef.git",
+		},
+		{
+			name:        "gist id",
+			input:       "aa5a315d61ae9438b18d",
+			wantType:    TypeRemote,
+			wantName:    "gist-aa5a315d61ae9438b18d",
+			wantPathSfx: "https://gist.github.com/aa5a315d61ae9438b18d.git",
+		},
+		{
+			name:        "gist id with prefix",
+			input:       "gist:aa5a315d61ae9438b18d",
+			wantType:    TypeRemote,
+			wantName:    "gist-aa5a315d61ae9438b18d",
+			wantPathSfx: "https://gist.github.com/aa5a315d61ae9438b18d.git",
+		},
 		{
 			name:        "owner/repo shorthand",
 			input:       "deanhigh/bury-it",
@@ -50,6 +81,215 @@ func TestParse(t *testing.T) {
 			wantName:    "my.project-name",
 			wantPathSfx: "https://github.com/some-org/my.project-name",
 		},
+		{
+			name:        "github tree url",
+			input:       "https://github.com/owner/repo/tree/main",
+			wantType:    TypeRemote,
+			wantName:    "repo",
+			wantPathSfx: "https://github.com/owner/repo",
+		},
+		{
+			name:        "github blob url",
+			input:       "https://github.com/owner/repo/blob/main/docs/README.md",
+			wantType:    TypeRemote,
+			wantName:    "repo",
+			wantPathSfx: "https://github.com/owner/repo",
+		},
+		{
+			name:        "gitlab url",
+			input:       "https://gitlab.com/group/repo",
+			wantType:    TypeRemote,
+			wantName:    "repo",
+			wantPathSfx: "https://gitlab.com/group/repo",
+		},
+		{
+			name:        "gitlab url with nested groups and .git suffix",
+			input:       "https://gitlab.com/group/subgroup/team/repo.git",
+			wantType:    TypeRemote,
+			wantName:    "repo",
+			wantPathSfx: "https://gitlab.com/group/subgroup/team/repo.git",
+		},
+		{
+			name:        "gitlab project page url",
+			input:       "https://gitlab.com/group/subgroup/repo/-/tree/main",
+			wantType:    TypeRemote,
+			wantName:    "repo",
+			wantPathSfx: "https://gitlab.com/group/subgroup/repo",
+		},
+		{
+			name:        "gitlab ssh url",
+			input:       "git@gitlab.com:group/subgroup/repo.git",
+			wantType:    TypeRemote,
+			wantName:    "repo",
+			wantPathSfx: "git@gitlab.com:group/subgroup/repo.git",
+		},
+		{
+			name:        "gitlab ssh url with scheme",
+			input:       "ssh://git@gitlab.com/group/repo.git",
+			wantType:    TypeRemote,
+			wantName:    "repo",
+			wantPathSfx: "ssh://git@gitlab.com/group/repo.git",
+		},
+		{
+			name:        "gitlab shorthand with nested groups",
+			input:       "gitlab:group/subgroup/repo",
+			wantType:    TypeRemote,
+			wantName:    "repo",
+			wantPathSfx: "https://gitlab.com/group/subgroup/repo",
+		},
+		{
+			name:        "bitbucket url",
+			input:       "https://bitbucket.org/workspace/repo",
+			wantType:    TypeRemote,
+			wantName:    "repo",
+			wantPathSfx: "https://bitbucket.org/workspace/repo",
+		},
+		{
+			name:        "bitbucket clone url with user",
+			input:       "https://someone@bitbucket.org/workspace/repo.git",
+			wantType:    TypeRemote,
+			wantName:    "repo",
+			wantPathSfx: "https://someone@bitbucket.org/workspace/repo.git",
+		},
+		{
+			name:        "bitbucket page url",
+			input:       "https://bitbucket.org/workspace/repo/src/main/README.md",
+			wantType:    TypeRemote,
+			wantName:    "repo",
+			wantPathSfx: "https://bitbucket.org/workspace/repo",
+		},
+		{
+			name:        "bitbucket ssh url",
+			input:       "git@bitbucket.org:workspace/repo.git",
+			wantType:    TypeRemote,
+			wantName:    "repo",
+			wantPathSfx: "git@bitbucket.org:workspace/repo.git",
+		},
+		{
+			name:        "bitbucket ssh url with scheme",
+			input:       "ssh://git@bitbucket.org/workspace/repo.git",
+			wantType:    TypeRemote,
+			wantName:    "repo",
+			wantPathSfx: "ssh://git@bitbucket.org/workspace/repo.git",
+		},
+		{
+			name:        "azure devops url",
+			input:       "https://dev.azure.com/org/project/_git/repo",
+			wantType:    TypeRemote,
+			wantName:    "repo",
+			wantPathSfx: "https://dev.azure.com/org/project/_git/repo",
+		},
+		{
+			name:        "azure devops clone url with user",
+			input:       "https://org@dev.azure.com/org/My%20Project/_git/my%20repo",
+			wantType:    TypeRemote,
+			wantName:    "my repo",
+			wantPathSfx: "https://org@dev.azure.com/org/My%20Project/_git/my%20repo",
+		},
+		{
+			name:        "azure devops page url",
+			input:       "https://dev.azure.com/org/project/_git/repo?path=/README.md&version=GBmain",
+			wantType:    TypeRemote,
+			wantName:    "repo",
+			wantPathSfx: "https://dev.azure.com/org/project/_git/repo",
+		},
+		{
+			name:        "azure devops legacy url",
+			input:       "https://org.visualstudio.com/DefaultCollection/project/_git/repo/pullrequest/12",
+			wantType:    TypeRemote,
+			wantName:    "repo",
+			wantPathSfx: "https://org.visualstudio.com/DefaultCollection/project/_git/repo",
+		},
+		{
+			name:        "azure devops ssh url",
+			input:       "git@ssh.dev.azure.com:v3/org/project/repo",
+			wantType:    TypeRemote,
+			wantName:    "repo",
+			wantPathSfx: "git@ssh.dev.azure.com:v3/org/project/repo",
+		},
+		{
+			name:        "codecommit https url",
+			input:       "https://git-codecommit.eu-west-1.amazonaws.com/v1/repos/old-service",
+			wantType:    TypeRemote,
+			wantName:    "old-service",
+			wantPathSfx: "https://git-codecommit.eu-west-1.amazonaws.com/v1/repos/old-service",
+		},
+		{
+			name:        "codecommit grc url",
+			input:       "codecommit::eu-west-1://ops@old-service",
+			wantType:    TypeRemote,
+			wantName:    "old-service",
+			wantPathSfx: "codecommit::eu-west-1://ops@old-service",
+		},
+		{
+			name:        "github ssh url",
+			input:       "git@github.com:owner/repo.git",
+			wantType:    TypeRemote,
+			wantName:    "repo",
+			wantPathSfx: "git@github.com:owner/repo.git",
+		},
+		{
+			name:        "github ssh url with scheme",
+			input:       "ssh://git@github.com/owner/repo",
+			wantType:    TypeRemote,
+			wantName:    "repo",
+			wantPathSfx: "ssh://git@github.com/owner/repo",
+		},
+		{
+			name:        "scp-style url of another host",
+			input:       "deploy@git.example.com:teams/infra/old-tool.git",
+			wantType:    TypeRemote,
+			wantName:    "old-tool",
+			wantPathSfx: "deploy@git.example.com:teams/infra/old-tool.git",
+		},
+		{
+			name:        "scp-style url with absolute path",
+			input:       "git@git.example.com:/srv/git/old-tool.git",
+			wantType:    TypeRemote,
+			wantName:    "old-tool",
+			wantPathSfx: "git@git.example.com:/srv/git/old-tool.git",
+		},
+		{
+			name:        "ssh url with port",
+			input:       "ssh://git@git.example.com:2222/teams/old-tool.git/",
+			wantType:    TypeRemote,
+			wantName:    "old-tool",
+			wantPathSfx: "ssh://git@git.example.com:2222/teams/old-tool.git/",
+		},
+		{
+			name:        "self-hosted forge url",
+			input:       "https://gitea.example.com/team/old-service.git",
+			wantType:    TypeRemote,
+			wantName:    "old-service",
+			wantPathSfx: "https://gitea.example.com/team/old-service.git",
+		},
+		{
+			name:        "cgit url with trailing slash",
+			input:       "https://git.example.com/cgit/old-tool/",
+			wantType:    TypeRemote,
+			wantName:    "old-tool",
+			wantPathSfx: "https://git.example.com/cgit/old-tool/",
+		},
+		{
+			name:        "git protocol url",
+			input:       "git://git.example.com/old-tool.git",
+			wantType:    TypeRemote,
+			wantName:    "old-tool",
+			wantPathSfx: "git://git.example.com/old-tool.git",
+		},
+		{
+			name:        "file url",
+			input:       "file:///srv/git/old-tool.git",
+			wantType:    TypeRemote,
+			wantName:    "old-tool",
+			wantPathSfx: "file:///srv/git/old-tool.git",
+		},
+		{
+			name:     "nested relative path is local, not gitlab",
+			input:    "group/subgroup/repo",
+			wantType: TypeLocal,
+			wantName: "repo",
+		},
 		{
 			name:     "relative path with dot",
 			input:    "./my-project",
@@ -62,6 +302,65 @@ func TestParse(t *testing.T) {
 			wantType: TypeLocal,
 			wantName: "my-project",
 		},
+		{
+			name:     "bare repository path",
+			input:    "/srv/git/my-project.git",
+			wantType: TypeLocal,
+			wantName: "my-project",
+		},
+		{
+			name:     "bundle path",
+			input:    "/tmp/handover/my-project.bundle",
+			wantType: TypeLocal,
+			wantName: "my-project",
+		},
+		{
+			name:     "tarball path",
+			input:    "/tmp/handover/my-project-1.0.tar.gz",
+			wantType: TypeArchive,
+			wantName: "my-project-1.0",
+		},
+		{
+			name:     "zip url",
+			input:    "https://example.com/downloads/my-project.zip",
+			wantType: TypeArchive,
+			wantName: "my-project",
+		},
+		{
+			name:     "github archive url",
+			input:    "https://github.com/owner/my-project/archive/refs/tags/v1.0.tar.gz",
+			wantType: TypeArchive,
+			wantName: "my-project",
+		},
+		{
+			name:     "gitlab archive url",
+			input:    "https://gitlab.com/group/my-project/-/archive/main/my-project-main.zip",
+			wantType: TypeArchive,
+			wantName: "my-project",
+		},
+		{
+			name:     "mercurial url",
+			input:    "hg::https://hg.example.com/projects/old-tool",
+			wantType: TypeMercurial,
+			wantName: "old-tool",
+		},
+		{
+			name:     "subversion url",
+			input:    "svn://svn.example.com/repos/old-tool/trunk",
+			wantType: TypeSubversion,
+			wantName: "old-tool",
+		},
+		{
+			name:     "prefixed subversion url",
+			input:    "svn::https://svn.example.com/repos/old-tool/",
+			wantType: TypeSubversion,
+			wantName: "old-tool",
+		},
+		{
+			name:    "prefix without repository",
+			input:   "hg::",
+			wantErr: true,
+		},
 		{
 			name:    "empty input",
 			input:   "",
@@ -119,6 +418,29 @@ func TestSource_Validate(t *testing.T) {
 		t.Fatalf("Failed to create valid repo: %v", err)
 	}
 
+	// Create a repository that git can reach by URL
+	realRepo := filepath.Join(tempDir, "real-repo")
+	if out, err := exec.Command("git", "init", "-q", realRepo).CombinedOutput(); err != nil {
+		t.Fatalf("Failed to create real repo: %v\n%s", err, out)
+	}
+
+	// Create a bare repository, with no working tree
+	bareRepo := filepath.Join(tempDir, "bare-repo.git")
+	if out, err := exec.Command("git", "init", "-q", "--bare", bareRepo).CombinedOutput(); err != nil {
+		t.Fatalf("Failed to create bare repo: %v\n%s", err, out)
+	}
+
+	// Create a bundle of a repository with a commit
+	bundle := filepath.Join(tempDir, "real-repo.bundle")
+	for _, args := range [][]string{
+		{"-C", realRepo, "-c", "user.name=Test", "-c", "user.email=test@test.com", "commit", "-q", "--allow-empty", "-m", "initial"},
+		{"-C", realRepo, "bundle", "create", "-q", bundle, "HEAD"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+
 	// Create a non-git directory
 	nonGitDir := filepath.Join(tempDir, "non-git")
 	if err := os.MkdirAll(nonGitDir, 0755); err != nil {
@@ -144,6 +466,14 @@ func TestSource_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "bare local git repo",
+			source: &Source{
+				Type: TypeLocal,
+				Path: bareRepo,
+			},
+			wantErr: false,
+		},
 		{
 			name: "non-existent path",
 			source: &Source{
@@ -152,6 +482,38 @@ func TestSource_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "git bundle file",
+			source: &Source{
+				Type: TypeLocal,
+				Path: bundle,
+			},
+			wantErr: false,
+		},
+		{
+			name: "archive file",
+			source: &Source{
+				Type: TypeArchive,
+				Path: filePath,
+			},
+			wantErr: false,
+		},
+		{
+			name: "missing archive file",
+			source: &Source{
+				Type: TypeArchive,
+				Path: filepath.Join(tempDir, "missing.tar.gz"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "archive to download",
+			source: &Source{
+				Type: TypeArchive,
+				Path: "https://example.com/my-project.tar.gz",
+			},
+			wantErr: false,
+		},
 		{
 			name: "path is a file not directory",
 			source: &Source{
@@ -168,6 +530,22 @@ func TestSource_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "reachable url of another host",
+			source: &Source{
+				Type: TypeRemote,
+				Path: "file://" + realRepo,
+			},
+			wantErr: false,
+		},
+		{
+			name: "unreachable url of another host",
+			source: &Source{
+				Type: TypeRemote,
+				Path: "file://" + nonGitDir,
+			},
+			wantErr: true,
+		},
 		{
 			name: "remote type skips local validation",
 			source: &Source{
@@ -187,3 +565,510 @@ func TestSource_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestParse_LocalConverted(t *testing.T) {
+	tempDir := t.TempDir()
+	hgRepo := filepath.Join(tempDir, "hg-project")
+	svnRepo := filepath.Join(tempDir, "svn-project")
+	workingCopy := filepath.Join(tempDir, "checkout")
+	for _, dir := range []string{filepath.Join(hgRepo, ".hg"), filepath.Join(svnRepo, "db"), filepath.Join(workingCopy, ".svn")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(svnRepo, "README.txt"), []byte("This is a Subversion repository; use the 'svnadmin' and 'svnlook'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]Type{hgRepo: TypeMercurial, svnRepo: TypeSubversion} {
+		src, err := Parse(path)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", path, err)
+		}
+		if src.Type != want {
+			t.Errorf("Parse(%q) Type = %v, want %v", path, src.Type, want)
+		}
+		if err := src.Validate(); err != nil {
+			t.Errorf("Validate() of %s error = %v", path, err)
+		}
+	}
+
+	src, err := Parse(workingCopy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := src.Validate(); err == nil || !strings.Contains(err.Error(), "Subversion working copy") {
+		t.Errorf("Validate() of a working copy error = %v, want one naming it", err)
+	}
+}
+
+func TestParsePlain(t *testing.T) {
+	tempDir := t.TempDir()
+	notes := filepath.Join(tempDir, "notes")
+	repo := filepath.Join(tempDir, "repo")
+	for _, dir := range []string{notes, filepath.Join(repo, ".git")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file := filepath.Join(tempDir, "notes.txt")
+	if err := os.WriteFile(file, []byte("x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := ParsePlain(notes)
+	if err != nil {
+		t.Fatalf("ParsePlain() error = %v", err)
+	}
+	if src.Type != TypePlain || src.Name != "notes" || src.Path != notes {
+		t.Errorf("ParsePlain() = %+v, want plain source notes at %s", src, notes)
+	}
+	if err := src.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{filepath.Join(tempDir, "missing"), "does not exist"},
+		{file, "not a directory"},
+		{repo, "is a git repository"},
+	}
+	for _, tt := range tests {
+		src, err := ParsePlain(tt.path)
+		if err != nil {
+			t.Fatalf("ParsePlain(%q) error = %v", tt.path, err)
+		}
+		if err := src.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Validate() of %s error = %v, want one containing %q", tt.path, err, tt.want)
+		}
+	}
+}
+
+func TestSource_GitHubRepo(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantOwner string
+		wantRepo  string
+		wantOK    bool
+	}{
+		{name: "shorthand", input: "owner/repo", wantOwner: "owner", wantRepo: "repo", wantOK: true},
+		{name: "url with .git", input: "https://github.com/owner/repo.git", wantOwner: "owner", wantRepo: "repo", wantOK: true},
+		{name: "ssh url", input: "git@github.com:owner/repo.git", wantOwner: "owner", wantRepo: "repo", wantOK: true},
+		{name: "ssh url of another host", input: "git@git.example.com:owner/repo.git", wantOK: false},
+		{name: "local path without remote", input: t.TempDir(), wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+			owner, repo, ok := src.GitHubRepo()
+			if ok != tt.wantOK || owner != tt.wantOwner || repo != tt.wantRepo {
+				t.Errorf("GitHubRepo() = %q, %q, %v; want %q, %q, %v", owner, repo, ok, tt.wantOwner, tt.wantRepo, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSource_GistID(t *testing.T) {
+	tests := []struct {
+		input  string
+		wantID string
+		wantOK bool
+	}{
+		{"https://gist.github.com/alice/aa5a315d61ae9438b18d#file-plot-py", "aa5a315d61ae9438b18d", true},
+		{"git@gist.github.com:aa5a315d61ae9438b18d.git", "aa5a315d61ae9438b18d", true},
+		{"https://github.com/alice/aa5a315d61ae9438b18d", "", false},
+	}
+	for _, tt := range tests {
+		src, err := Parse(tt.input)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.input, err)
+		}
+		id, ok := src.GistID()
+		if id != tt.wantID || ok != tt.wantOK {
+			t.Errorf("GistID() of %s = %q, %v, want %q, %v", tt.input, id, ok, tt.wantID, tt.wantOK)
+		}
+	}
+
+	// A directory named like a gist ID is buried as the directory
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.Mkdir("aa5a315d61ae9438b18d", 0755); err != nil {
+		t.Fatal(err)
+	}
+	src, err := Parse("aa5a315d61ae9438b18d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.Type != TypeLocal {
+		t.Errorf("Parse() of a directory named like a gist ID = type %v, want local", src.Type)
+	}
+}
+
+func TestSource_FilePath(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantPath string
+		wantOK   bool
+	}{
+		{"file:///mnt/mirrors/old-tool.git", "/mnt/mirrors/old-tool.git", true},
+		{"file://localhost/mnt/mirrors/old-tool.git", "/mnt/mirrors/old-tool.git", true},
+		{"file:///mnt/my%20mirrors/old-tool.git", "/mnt/my mirrors/old-tool.git", true},
+		{"file://fileserver/mirrors/old-tool.git", "", false},
+		{"https://git.example.com/old-tool.git", "", false},
+	}
+	for _, tt := range tests {
+		src, err := Parse(tt.input)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.input, err)
+		}
+		path, ok := src.FilePath()
+		if path != tt.wantPath || ok != tt.wantOK {
+			t.Errorf("FilePath() of %s = %q, %v, want %q, %v", tt.input, path, ok, tt.wantPath, tt.wantOK)
+		}
+	}
+}
+
+func TestSource_GitLabProject(t *testing.T) {
+	tests := []struct {
+		input  string
+		want   string
+		wantOK bool
+	}{
+		{input: "https://gitlab.com/group/subgroup/repo.git", want: "group/subgroup/repo", wantOK: true},
+		{input: "git@gitlab.com:group/repo.git", want: "group/repo", wantOK: true},
+		{input: "gitlab:group/repo", want: "group/repo", wantOK: true},
+		{input: "https://github.com/owner/repo", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			src, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+			got, ok := src.GitLabProject()
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("GitLabProject() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSource_BitbucketRepo(t *testing.T) {
+	tests := []struct {
+		input         string
+		wantWorkspace string
+		wantRepo      string
+		wantOK        bool
+	}{
+		{input: "https://someone@bitbucket.org/team-space/repo.git", wantWorkspace: "team-space", wantRepo: "repo", wantOK: true},
+		{input: "git@bitbucket.org:team-space/repo.git", wantWorkspace: "team-space", wantRepo: "repo", wantOK: true},
+		{input: "https://bitbucket.org/team-space/repo/pull-requests/4", wantWorkspace: "team-space", wantRepo: "repo", wantOK: true},
+		{input: "https://gitlab.com/group/repo", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			src, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+			workspace, repo, ok := src.BitbucketRepo()
+			if workspace != tt.wantWorkspace || repo != tt.wantRepo || ok != tt.wantOK {
+				t.Errorf("BitbucketRepo() = %q, %q, %v, want %q, %q, %v", workspace, repo, ok, tt.wantWorkspace, tt.wantRepo, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSource_AzureDevOpsRepo(t *testing.T) {
+	tests := []struct {
+		input       string
+		wantOrg     string
+		wantProject string
+		wantRepo    string
+		wantOK      bool
+	}{
+		{input: "https://dev.azure.com/org/project/_git/repo", wantOrg: "org", wantProject: "project", wantRepo: "repo", wantOK: true},
+		{input: "https://org.visualstudio.com/project/_git/repo", wantOrg: "org", wantProject: "project", wantRepo: "repo", wantOK: true},
+		{input: "ssh://git@ssh.dev.azure.com/v3/org/project/repo", wantOrg: "org", wantProject: "project", wantRepo: "repo", wantOK: true},
+		{input: "https://github.com/owner/repo", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			src, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+			org, project, repo, ok := src.AzureDevOpsRepo()
+			if org != tt.wantOrg || project != tt.wantProject || repo != tt.wantRepo || ok != tt.wantOK {
+				t.Errorf("AzureDevOpsRepo() = %q, %q, %q, %v, want %q, %q, %q, %v",
+					org, project, repo, ok, tt.wantOrg, tt.wantProject, tt.wantRepo, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSource_CloneEnv(t *testing.T) {
+	tests := []struct {
+		input string
+		token string
+		want  string
+	}{
+		// base64(":secret")
+		{input: "https://dev.azure.com/org/project/_git/repo", token: "secret", want: "GIT_CONFIG_VALUE_0=Authorization: Basic OnNlY3JldA=="},
+		{input: "https://dev.azure.com/org/project/_git/repo"},
+		{input: "git@ssh.dev.azure.com:v3/org/project/repo", token: "secret"},
+		{input: "https://github.com/owner/repo", token: "secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			t.Setenv(AzureDevOpsPATEnv, tt.token)
+			src, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+			env := src.CloneEnv()
+			got := ""
+			if len(env) > 0 {
+				got = env[len(env)-1]
+			}
+			if got != tt.want {
+				t.Errorf("CloneEnv() = %q, want last variable %q", env, tt.want)
+			}
+		})
+	}
+}
+
+func TestSource_CodeCommitRepo(t *testing.T) {
+	tests := []struct {
+		input      string
+		wantRegion string
+		wantRepo   string
+		wantOK     bool
+	}{
+		{input: "https://git-codecommit.us-east-2.amazonaws.com/v1/repos/repo", wantRegion: "us-east-2", wantRepo: "repo", wantOK: true},
+		{input: "ssh://APKAEIBAERJR2EXAMPLE@git-codecommit.us-east-2.amazonaws.com/v1/repos/repo", wantRegion: "us-east-2", wantRepo: "repo", wantOK: true},
+		{input: "https://git-codecommit-fips.us-gov-west-1.amazonaws.com/v1/repos/repo", wantRegion: "us-gov-west-1", wantRepo: "repo", wantOK: true},
+		{input: "codecommit://repo", wantRepo: "repo", wantOK: true},
+		{input: "https://github.com/owner/repo", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			src, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+			region, repo, ok := src.CodeCommitRepo()
+			if region != tt.wantRegion || repo != tt.wantRepo || ok != tt.wantOK {
+				t.Errorf("CodeCommitRepo() = %q, %q, %v, want %q, %q, %v", region, repo, ok, tt.wantRegion, tt.wantRepo, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSource_CloneEnv_CodeCommit(t *testing.T) {
+	src, err := Parse("https://git-codecommit.us-east-2.amazonaws.com/v1/repos/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	if env := src.CloneEnv(); env != nil {
+		t.Errorf("CloneEnv() without the AWS CLI = %q, want nil", env)
+	}
+
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "aws"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	env := strings.Join(src.CloneEnv(), "\n")
+	for _, want := range []string{"GIT_CONFIG_VALUE_0=!aws codecommit credential-helper $@", "GIT_CONFIG_KEY_1=credential.UseHttpPath"} {
+		if !strings.Contains(env, want) {
+			t.Errorf("CloneEnv() = %q, want %q", env, want)
+		}
+	}
+}
+
+func TestSource_HostedRepo(t *testing.T) {
+	tests := []struct {
+		input     string
+		wantHost  string
+		wantOwner string
+		wantRepo  string
+		wantOK    bool
+	}{
+		{input: "https://codeberg.org/owner/repo", wantHost: "codeberg.org", wantOwner: "owner", wantRepo: "repo", wantOK: true},
+		{input: "https://Git.Example.com:3000/team/tool.git", wantHost: "git.example.com", wantOwner: "team", wantRepo: "tool", wantOK: true},
+		{input: "ssh://git@git.example.com:2222/team/tool.git", wantHost: "git.example.com", wantOwner: "team", wantRepo: "tool", wantOK: true},
+		{input: "git@codeberg.org:owner/repo.git", wantHost: "codeberg.org", wantOwner: "owner", wantRepo: "repo", wantOK: true},
+		{input: "https://git.example.com/a/b/c", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			src, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+			host, owner, repo, ok := src.HostedRepo()
+			if host != tt.wantHost || owner != tt.wantOwner || repo != tt.wantRepo || ok != tt.wantOK {
+				t.Errorf("HostedRepo() = %q, %q, %q, %v, want %q, %q, %q, %v",
+					host, owner, repo, ok, tt.wantHost, tt.wantOwner, tt.wantRepo, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSource_ShorthandURL(t *testing.T) {
+	tests := []struct {
+		input     string
+		wantHost  string
+		wantHTTPS string
+		wantSSH   string
+	}{
+		{input: "owner/repo", wantHost: "github.com", wantHTTPS: "https://github.com/owner/repo", wantSSH: "git@github.com:owner/repo.git"},
+		{input: "gitlab:group/sub/repo", wantHost: "gitlab.com", wantHTTPS: "https://gitlab.com/group/sub/repo", wantSSH: "git@gitlab.com:group/sub/repo.git"},
+		{input: "gl:group/repo", wantHost: "gitlab.com", wantHTTPS: "https://gitlab.com/group/repo", wantSSH: "git@gitlab.com:group/repo.git"},
+		{input: "gh:owner/repo", wantHost: "github.com", wantHTTPS: "https://github.com/owner/repo", wantSSH: "git@github.com:owner/repo.git"},
+		{input: "bb:workspace/repo", wantHost: "bitbucket.org", wantHTTPS: "https://bitbucket.org/workspace/repo", wantSSH: "git@bitbucket.org:workspace/repo.git"},
+		{input: "https://github.com/owner/repo", wantHTTPS: "https://github.com/owner/repo", wantSSH: "https://github.com/owner/repo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			src, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+			if got := src.ShorthandHost(); got != tt.wantHost {
+				t.Errorf("ShorthandHost() = %q, want %q", got, tt.wantHost)
+			}
+			if got := src.ShorthandURL(TransportHTTPS); got != tt.wantHTTPS {
+				t.Errorf("ShorthandURL(https) = %q, want %q", got, tt.wantHTTPS)
+			}
+			if got := src.ShorthandURL(TransportSSH); got != tt.wantSSH {
+				t.Errorf("ShorthandURL(ssh) = %q, want %q", got, tt.wantSSH)
+			}
+		})
+	}
+}
+
+func TestParseWithPrefixes(t *testing.T) {
+	prefixes := map[string]string{"corp": "git.example.com", "gl": "gitlab.example.com"}
+	tests := []struct {
+		input    string
+		wantType Type
+		wantPath string
+		wantName string
+	}{
+		{input: "corp:team/tools/repo", wantType: TypeRemote, wantPath: "https://git.example.com/team/tools/repo", wantName: "repo"},
+		{input: "gl:group/repo", wantType: TypeRemote, wantPath: "https://gitlab.example.com/group/repo", wantName: "repo"},
+		{input: "bb:workspace/repo", wantType: TypeRemote, wantPath: "https://bitbucket.org/workspace/repo", wantName: "repo"},
+		{input: "git@corp:team/repo.git", wantType: TypeRemote, wantPath: "git@corp:team/repo.git", wantName: "repo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			src, err := ParseWithPrefixes(tt.input, prefixes)
+			if err != nil {
+				t.Fatalf("ParseWithPrefixes(%q) error = %v", tt.input, err)
+			}
+			if src.Type != tt.wantType || src.Path != tt.wantPath || src.Name != tt.wantName {
+				t.Errorf("ParseWithPrefixes(%q) = %v %q %q, want %v %q %q",
+					tt.input, src.Type, src.Path, src.Name, tt.wantType, tt.wantPath, tt.wantName)
+			}
+		})
+	}
+
+	// Without the prefix configured, corp: is not shorthand
+	if src, err := Parse("corp:team/repo"); err != nil || src.Type != TypeLocal {
+		t.Errorf("Parse(corp:team/repo) = %+v, %v, want a local path", src, err)
+	}
+}
+
+func TestSource_OnHost(t *testing.T) {
+	src, err := Parse("owner/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved := src.OnHost("github.example.com")
+	if moved.Path != "https://github.example.com/owner/repo" || moved.ShorthandHost() != "github.example.com" {
+		t.Errorf("OnHost() = %q on %q, want https://github.example.com/owner/repo", moved.Path, moved.ShorthandHost())
+	}
+	if got := moved.ShorthandURL(TransportSSH); got != "git@github.example.com:owner/repo.git" {
+		t.Errorf("OnHost().ShorthandURL(ssh) = %q", got)
+	}
+	if src.Path != "https://github.com/owner/repo" {
+		t.Errorf("OnHost() changed the original source to %q", src.Path)
+	}
+
+	url, _ := Parse("https://gitlab.com/group/repo")
+	if got := url.OnHost("github.example.com"); got.Path != url.Path {
+		t.Errorf("OnHost() of a URL = %q, want it unchanged", got.Path)
+	}
+}
+
+func TestParseTransport(t *testing.T) {
+	for input, want := range map[string]Transport{"ssh": TransportSSH, "HTTPS": TransportHTTPS} {
+		if got, err := ParseTransport(input); err != nil || got != want {
+			t.Errorf("ParseTransport(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParseTransport("ftp"); err == nil {
+		t.Errorf("ParseTransport(ftp) expected error")
+	}
+}
+
+func TestResolveSubmoduleURL(t *testing.T) {
+	tests := []struct {
+		base      string
+		submodule string
+		want      string
+	}{
+		{base: "https://github.com/owner/app", submodule: "../lib.git", want: "https://github.com/owner/lib.git"},
+		{base: "https://github.com/owner/app.git", submodule: "./docs", want: "https://github.com/owner/app.git/docs"},
+		{base: "git@gitlab.com:group/app.git", submodule: "../lib.git", want: "git@gitlab.com:group/lib.git"},
+		{base: "/src/app", submodule: "../lib", want: "/src/lib"},
+		{base: "/src/app", submodule: "https://example.com/lib.git", want: "https://example.com/lib.git"},
+		{base: "https://github.com/owner/app", submodule: "git@github.com:other/lib.git", want: "git@github.com:other/lib.git"},
+	}
+	for _, tt := range tests {
+		if got := ResolveSubmoduleURL(tt.base, tt.submodule); got != tt.want {
+			t.Errorf("ResolveSubmoduleURL(%q, %q) = %q, want %q", tt.base, tt.submodule, got, tt.want)
+		}
+	}
+}
+
+func TestReadList(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{name: "empty", input: "", want: nil},
+		{name: "one per line", input: "owner/app\n./spike\n", want: []string{"owner/app", "./spike"}},
+		{name: "no trailing newline", input: "owner/app\n./spike", want: []string{"owner/app", "./spike"}},
+		{name: "blank lines and comments", input: "# retired in 2024\n\nowner/app\n  \n  ./spike  \r\n", want: []string{"owner/app", "./spike"}},
+		{
+			name:  "gh repo list",
+			input: "owner/app\tAn old app\tpublic, archived\t2024-01-02T03:04:05Z\nowner/lib\t\tprivate\t2023-05-06T07:08:09Z\n",
+			want:  []string{"owner/app", "owner/lib"},
+		},
+		{name: "path with spaces", input: "/src/old project\n", want: []string{"/src/old project"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadList(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("ReadList() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadList() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+// Package state stores bury-it's local state between runs, such as pending
+// sweep notices, outside of any repository.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/deanhigh/bury-it/internal/atomicfile"
+)
+
+// HomeEnv overrides the state directory when set.
+const HomeEnv = "BURY_IT_HOME"
+
+// ErrReadOnly is returned by Save in read-only mode.
+var ErrReadOnly = errors.New("local state cannot be changed in read-only mode")
+
+// readOnly is set by SetReadOnly.
+var readOnly bool
+
+// SetReadOnly makes Save refuse to write any state, for bury-it's
+// --read-only mode.
+func SetReadOnly(on bool) {
+	readOnly = on
+}
+
+// Dir returns the directory holding local state: $BURY_IT_HOME if set,
+// otherwise bury-it in the user's configuration directory.
+func Dir() (string, error) {
+	if dir := os.Getenv(HomeEnv); dir != "" {
+		return dir, nil
+	}
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate state directory (set %s): %w", HomeEnv, err)
+	}
+	return filepath.Join(config, "bury-it"), nil
+}
+
+// Load decodes the named JSON state file into v. A missing file leaves v
+// unchanged.
+func Load(name string, v any) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read state: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse state file %s: %w", name, err)
+	}
+	return nil
+}
+
+// Save encodes v as JSON into the named state file, or returns ErrReadOnly
+// in read-only mode.
+func Save(name string, v any) error {
+	if readOnly {
+		return ErrReadOnly
+	}
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	if err := atomicfile.Write(filepath.Join(dir, name), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
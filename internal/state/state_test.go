@@ -0,0 +1,76 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDir(t *testing.T) {
+	t.Setenv(HomeEnv, "/custom/state")
+	dir, err := Dir()
+	if err != nil || dir != "/custom/state" {
+		t.Errorf("Dir() = %q, %v, want /custom/state", dir, err)
+	}
+
+	t.Setenv(HomeEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	t.Setenv("HOME", "/home/test")
+	dir, err = Dir()
+	if err != nil {
+		t.Fatalf("Dir() error = %v", err)
+	}
+	if filepath.Base(dir) != "bury-it" {
+		t.Errorf("Dir() = %q, want a bury-it directory", dir)
+	}
+}
+
+func TestLoadSave(t *testing.T) {
+	home := filepath.Join(t.TempDir(), "state")
+	t.Setenv(HomeEnv, home)
+
+	type record struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+
+	got := record{Name: "unchanged"}
+	if err := Load("records.json", &got); err != nil {
+		t.Fatalf("Load() of missing file error = %v", err)
+	}
+	if got.Name != "unchanged" {
+		t.Errorf("Load() of missing file changed value to %+v", got)
+	}
+
+	if err := Save("records.json", record{Name: "saved", Count: 2}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := Load("records.json", &got); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got != (record{Name: "saved", Count: 2}) {
+		t.Errorf("Load() = %+v, want saved record", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(home, "bad.json"), []byte("{"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := Load("bad.json", &got); err == nil {
+		t.Errorf("Load() expected error for invalid JSON")
+	}
+}
+
+func TestSave_ReadOnly(t *testing.T) {
+	home := filepath.Join(t.TempDir(), "state")
+	t.Setenv(HomeEnv, home)
+	SetReadOnly(true)
+	defer SetReadOnly(false)
+
+	if err := Save("records.json", []string{"a"}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Save() error = %v, want ErrReadOnly", err)
+	}
+	if _, err := os.Stat(home); !os.IsNotExist(err) {
+		t.Errorf("Save() created %s in read-only mode", home)
+	}
+}
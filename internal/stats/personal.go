@@ -0,0 +1,100 @@
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/state"
+)
+
+// personalFile is the state file holding the burials made on this machine.
+// It is only ever read and written locally, and never sent anywhere.
+const personalFile = "personal-stats.json"
+
+// ManualBurial is a rough estimate of how long a burial takes by hand:
+// cloning the source, copying it into the graveyard, writing up what it was,
+// and committing. The time a burial saves is this less how long bury-it took.
+const ManualBurial = 30 * time.Minute
+
+// Burial is a burial made on this machine.
+type Burial struct {
+	// Time is when the burial finished.
+	Time time.Time `json:"time"`
+	// Project is the name of the buried project.
+	Project string `json:"project"`
+	// Bytes is the size of the buried project's files and git objects.
+	Bytes int64 `json:"bytes"`
+	// Seconds is how long the burial took.
+	Seconds float64 `json:"seconds"`
+}
+
+// RecordBurial adds a burial to the personal statistics kept on this
+// machine.
+func RecordBurial(b Burial) error {
+	burials, err := LoadBurials()
+	if err != nil {
+		return err
+	}
+	return state.Save(personalFile, append(burials, b))
+}
+
+// LoadBurials returns the burials made on this machine, oldest first.
+func LoadBurials() ([]Burial, error) {
+	var burials []Burial
+	if err := state.Load(personalFile, &burials); err != nil {
+		return nil, err
+	}
+	return burials, nil
+}
+
+// Personal are the figures of the burials made on this machine.
+type Personal struct {
+	// Burials is the number of burials.
+	Burials int `json:"burials"`
+	// Bytes is the total size of the buried projects.
+	Bytes int64 `json:"bytes"`
+	// MinutesSaved is the estimated time the burials saved over making them
+	// by hand.
+	MinutesSaved float64 `json:"minutes_saved"`
+	// Years breaks the burials down by the year they were made in, oldest
+	// first.
+	Years []PersonalYear `json:"years"`
+}
+
+// PersonalYear is the rollup of the burials made on this machine in a year.
+type PersonalYear struct {
+	// Year is the year the burials were made in.
+	Year int `json:"year"`
+	// Burials is the number of burials made in the year.
+	Burials int `json:"burials"`
+	// Bytes is the total size of the projects buried in the year.
+	Bytes int64 `json:"bytes"`
+	// MinutesSaved is the estimated time the year's burials saved.
+	MinutesSaved float64 `json:"minutes_saved"`
+}
+
+// ComputePersonal totals burials, overall and per year.
+func ComputePersonal(burials []Burial) *Personal {
+	p := &Personal{Years: []PersonalYear{}}
+	years := map[int]*PersonalYear{}
+	for _, b := range burials {
+		saved := max(ManualBurial.Minutes()-b.Seconds/60, 0)
+		p.Burials++
+		p.Bytes += b.Bytes
+		p.MinutesSaved += saved
+
+		y, ok := years[b.Time.Year()]
+		if !ok {
+			y = &PersonalYear{Year: b.Time.Year()}
+			years[b.Time.Year()] = y
+		}
+		y.Burials++
+		y.Bytes += b.Bytes
+		y.MinutesSaved += saved
+	}
+	for _, y := range years {
+		p.Years = append(p.Years, *y)
+	}
+	sort.Slice(p.Years, func(i, j int) bool { return p.Years[i].Year < p.Years[j].Year })
+	return p
+}
@@ -0,0 +1,51 @@
+package stats
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/state"
+)
+
+func TestRecordBurial(t *testing.T) {
+	t.Setenv(state.HomeEnv, filepath.Join(t.TempDir(), "state"))
+
+	burials, err := LoadBurials()
+	if err != nil || len(burials) != 0 {
+		t.Fatalf("LoadBurials() of missing file = %v, %v, want none", burials, err)
+	}
+	for _, project := range []string{"api", "worker"} {
+		if err := RecordBurial(Burial{Time: time.Now(), Project: project, Bytes: 100, Seconds: 2}); err != nil {
+			t.Fatalf("RecordBurial(%s) error = %v", project, err)
+		}
+	}
+	burials, err = LoadBurials()
+	if err != nil || len(burials) != 2 || burials[0].Project != "api" || burials[1].Project != "worker" {
+		t.Errorf("LoadBurials() = %v, %v, want api and worker", burials, err)
+	}
+}
+
+func TestComputePersonal(t *testing.T) {
+	burials := []Burial{
+		{Time: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), Project: "prototype", Bytes: 1000, Seconds: 60},
+		{Time: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), Project: "api", Bytes: 5000, Seconds: 120},
+		// A burial slower than by hand saves nothing, rather than costing time
+		{Time: time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), Project: "monorepo", Bytes: 1 << 30, Seconds: 3600},
+	}
+
+	p := ComputePersonal(burials)
+	if p.Burials != 3 || p.Bytes != 6000+1<<30 || p.MinutesSaved != 29+28 {
+		t.Errorf("ComputePersonal() = %d burials, %d bytes, %v minutes, want 3, %d, 57", p.Burials, p.Bytes, p.MinutesSaved, 6000+1<<30)
+	}
+	if len(p.Years) != 2 || p.Years[0].Year != 2025 || p.Years[1].Year != 2026 {
+		t.Fatalf("ComputePersonal() years = %+v, want 2025 and 2026", p.Years)
+	}
+	if y := p.Years[1]; y.Burials != 2 || y.MinutesSaved != 28 {
+		t.Errorf("2026 = %+v, want 2 burials saving 28 minutes", y)
+	}
+
+	if empty := ComputePersonal(nil); empty.Burials != 0 || empty.Years == nil {
+		t.Errorf("ComputePersonal(nil) = %+v, want no burials and an empty list of years", empty)
+	}
+}
@@ -0,0 +1,188 @@
+// Package stats aggregates figures about the projects buried in a graveyard,
+// such as how many there are and how much their sunset saved.
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/metadata"
+)
+
+// daysPerMonth is the average length of a month, used to turn the time since
+// a burial into months of savings.
+const daysPerMonth = 365.25 / 12
+
+// Stats are the aggregated figures of a set of buried projects.
+type Stats struct {
+	// Projects is the number of projects.
+	Projects int `json:"projects"`
+	// HistoryPreserved is the number of projects buried with their history.
+	HistoryPreserved int `json:"history_preserved"`
+	// ReviewsDue is the number of projects past their review date.
+	ReviewsDue int `json:"reviews_due"`
+	// BuriedByYear counts the projects buried in each year.
+	BuriedByYear map[int]int `json:"buried_by_year"`
+	// Costs aggregates the projects' recorded running costs.
+	Costs Costs `json:"costs"`
+	// ByOwner breaks the projects down by owner, if requested.
+	ByOwner []Owner `json:"by_owner,omitempty"`
+	// Consulted counts the projects still being read, if reads are tracked.
+	Consulted *Consulted `json:"consulted,omitempty"`
+}
+
+// Consulted counts buried projects by how recently they were read on this
+// machine, as recorded with --track-access.
+type Consulted struct {
+	// LastYear is the number of projects read in the year before now.
+	LastYear int `json:"last_year"`
+	// Never is the number of projects never read since tracking began.
+	Never int `json:"never"`
+}
+
+// Owner is the rollup of the projects of a single owner.
+type Owner struct {
+	// Owner is the team or person, or empty for projects without an owner.
+	Owner string `json:"owner"`
+	// Projects is the number of projects the owner buried.
+	Projects int `json:"projects"`
+	// WorkTree is the total size of the projects' files in bytes.
+	WorkTree int64 `json:"worktree_bytes"`
+	// Packed is the approximate total size of the projects' git objects.
+	Packed int64 `json:"packed_bytes"`
+	// MonthlySavings is the estimated total the owner's sunsets save each
+	// month.
+	MonthlySavings float64 `json:"monthly_savings"`
+}
+
+// Costs aggregates the monthly running costs recorded for buried projects.
+// Only projects with a cost before their sunset are counted.
+type Costs struct {
+	// Projects is the number of projects with a recorded cost.
+	Projects int `json:"projects"`
+	// MonthlyBefore is the total monthly cost before the sunsets.
+	MonthlyBefore float64 `json:"monthly_before"`
+	// MonthlyAfter is the total monthly cost after the sunsets.
+	MonthlyAfter float64 `json:"monthly_after"`
+	// MonthlySavings is the estimated total saved each month.
+	MonthlySavings float64 `json:"monthly_savings"`
+	// AnnualSavings is the estimated total saved each year.
+	AnnualSavings float64 `json:"annual_savings"`
+	// SavedToDate is the estimated total saved since each project was buried.
+	SavedToDate float64 `json:"saved_to_date"`
+	// Savings lists each project's savings, largest first.
+	Savings []Saving `json:"savings"`
+}
+
+// Saving is the estimated saving of a single project.
+type Saving struct {
+	// Project is the name of the buried project.
+	Project string `json:"project"`
+	// Monthly is the estimated amount saved each month.
+	Monthly float64 `json:"monthly"`
+	// ToDate is the estimated amount saved since the project was buried.
+	ToDate float64 `json:"to_date"`
+}
+
+// Compute aggregates the metadata of the named projects as of now.
+func Compute(projects map[string]*metadata.Metadata, now time.Time) *Stats {
+	s := &Stats{
+		BuriedByYear: map[int]int{},
+		Costs:        Costs{Savings: []Saving{}},
+	}
+	for name, meta := range projects {
+		s.Projects++
+		if meta.HistoryPreserved {
+			s.HistoryPreserved++
+		}
+		if meta.ReviewDue(now) {
+			s.ReviewsDue++
+		}
+		if !meta.BuriedAt.IsZero() {
+			s.BuriedByYear[meta.BuriedAt.Year()]++
+		}
+
+		monthly, ok := meta.MonthlySavings()
+		if !ok {
+			continue
+		}
+		saving := Saving{Project: name, Monthly: monthly}
+		if !meta.BuriedAt.IsZero() && now.After(meta.BuriedAt) {
+			saving.ToDate = monthly * now.Sub(meta.BuriedAt).Hours() / 24 / daysPerMonth
+		}
+		s.Costs.Projects++
+		s.Costs.MonthlyBefore += *meta.MonthlyCostBefore
+		if meta.MonthlyCostAfter != nil {
+			s.Costs.MonthlyAfter += *meta.MonthlyCostAfter
+		}
+		s.Costs.MonthlySavings += saving.Monthly
+		s.Costs.SavedToDate += saving.ToDate
+		s.Costs.Savings = append(s.Costs.Savings, saving)
+	}
+	s.Costs.AnnualSavings = s.Costs.MonthlySavings * 12
+
+	sort.Slice(s.Costs.Savings, func(i, j int) bool {
+		a, b := s.Costs.Savings[i], s.Costs.Savings[j]
+		if a.Monthly != b.Monthly {
+			return a.Monthly > b.Monthly
+		}
+		return a.Project < b.Project
+	})
+	return s
+}
+
+// CountConsulted sets s.Consulted from the recorded reads of the named
+// projects, keyed by project.
+func (s *Stats) CountConsulted(projects map[string]*metadata.Metadata, accesses map[string]graveyard.Access, now time.Time) {
+	c := &Consulted{}
+	since := now.AddDate(-1, 0, 0)
+	for name := range projects {
+		access, ok := accesses[name]
+		switch {
+		case !ok:
+			c.Never++
+		case access.Last.After(since):
+			c.LastYear++
+		}
+	}
+	s.Consulted = c
+}
+
+// ByOwner rolls the named projects up by owner, with the disk usage of each
+// project taken from usages. Owners with the most projects come first and
+// projects without an owner last.
+func ByOwner(projects map[string]*metadata.Metadata, usages map[string]*graveyard.Usage) []Owner {
+	owners := map[string]*Owner{}
+	for name, meta := range projects {
+		o, ok := owners[meta.Owner]
+		if !ok {
+			o = &Owner{Owner: meta.Owner}
+			owners[meta.Owner] = o
+		}
+		o.Projects++
+		if usage := usages[name]; usage != nil {
+			o.WorkTree += usage.WorkTree
+			o.Packed += usage.Packed
+		}
+		if monthly, ok := meta.MonthlySavings(); ok {
+			o.MonthlySavings += monthly
+		}
+	}
+
+	rollup := make([]Owner, 0, len(owners))
+	for _, o := range owners {
+		rollup = append(rollup, *o)
+	}
+	sort.Slice(rollup, func(i, j int) bool {
+		a, b := rollup[i], rollup[j]
+		if (a.Owner == "") != (b.Owner == "") {
+			return b.Owner == ""
+		}
+		if a.Projects != b.Projects {
+			return a.Projects > b.Projects
+		}
+		return a.Owner < b.Owner
+	})
+	return rollup
+}
@@ -0,0 +1,116 @@
+package stats
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/metadata"
+)
+
+func cost(v float64) *float64 {
+	return &v
+}
+
+func TestCompute(t *testing.T) {
+	now := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+	projects := map[string]*metadata.Metadata{
+		"api": {
+			BuriedAt:          time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			HistoryPreserved:  true,
+			MonthlyCostBefore: cost(420),
+			MonthlyCostAfter:  cost(20),
+		},
+		"worker": {
+			BuriedAt:          time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+			MonthlyCostBefore: cost(100),
+			ReviewAfter:       time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"prototype": {
+			BuriedAt:         time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+			HistoryPreserved: true,
+			MonthlyCostAfter: cost(5),
+		},
+	}
+
+	s := Compute(projects, now)
+	if s.Projects != 3 || s.HistoryPreserved != 2 || s.ReviewsDue != 1 {
+		t.Errorf("Compute() counts = %d, %d, %d, want 3, 2, 1", s.Projects, s.HistoryPreserved, s.ReviewsDue)
+	}
+	if s.BuriedByYear[2025] != 2 || s.BuriedByYear[2026] != 1 {
+		t.Errorf("Compute() BuriedByYear = %v", s.BuriedByYear)
+	}
+
+	c := s.Costs
+	if c.Projects != 2 {
+		t.Errorf("Costs.Projects = %d, want 2", c.Projects)
+	}
+	if c.MonthlyBefore != 520 || c.MonthlyAfter != 20 || c.MonthlySavings != 500 || c.AnnualSavings != 6000 {
+		t.Errorf("Costs = %+v, want 520 before, 20 after, 500 monthly, 6000 annual", c)
+	}
+	if len(c.Savings) != 2 || c.Savings[0].Project != "api" || c.Savings[1].Project != "worker" {
+		t.Fatalf("Costs.Savings = %+v, want api then worker", c.Savings)
+	}
+	// worker has been buried for a year, api for about six months
+	if got := c.Savings[1].ToDate; math.Abs(got-1200) > 1 {
+		t.Errorf("worker ToDate = %v, want about 1200", got)
+	}
+	if got := c.Savings[0].ToDate; math.Abs(got-400*181/daysPerMonth) > 0.01 {
+		t.Errorf("api ToDate = %v, want about %v", got, 400*181/daysPerMonth)
+	}
+	if math.Abs(c.SavedToDate-c.Savings[0].ToDate-c.Savings[1].ToDate) > 0.001 {
+		t.Errorf("SavedToDate = %v, want the sum of the projects", c.SavedToDate)
+	}
+}
+
+func TestCompute_Empty(t *testing.T) {
+	s := Compute(nil, time.Now())
+	if s.Projects != 0 || s.Costs.Projects != 0 || s.Costs.Savings == nil || s.BuriedByYear == nil {
+		t.Errorf("Compute(nil) = %+v, want zero counts and empty collections", s)
+	}
+}
+
+func TestCountConsulted(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	projects := map[string]*metadata.Metadata{"api": {}, "web": {}, "cli": {}}
+	accesses := map[string]graveyard.Access{
+		"api":     {Last: now.AddDate(0, -2, 0), Count: 3},
+		"web":     {Last: now.AddDate(-2, 0, 0), Count: 1},
+		"removed": {Last: now, Count: 1},
+	}
+	s := Compute(projects, now)
+	s.CountConsulted(projects, accesses, now)
+	if want := (Consulted{LastYear: 1, Never: 1}); s.Consulted == nil || *s.Consulted != want {
+		t.Errorf("CountConsulted() = %+v, want %+v", s.Consulted, want)
+	}
+}
+
+func TestByOwner(t *testing.T) {
+	projects := map[string]*metadata.Metadata{
+		"api":       {Owner: "payments", MonthlyCostBefore: cost(300), MonthlyCostAfter: cost(50)},
+		"worker":    {Owner: "payments", MonthlyCostBefore: cost(100)},
+		"dashboard": {Owner: "data"},
+		"prototype": {},
+	}
+	usages := map[string]*graveyard.Usage{
+		"api":       {Project: "api", WorkTree: 1000, Packed: 400},
+		"worker":    {Project: "worker", WorkTree: 500, Packed: 200},
+		"prototype": {Project: "prototype", WorkTree: 10, Packed: 5},
+	}
+
+	got := ByOwner(projects, usages)
+	want := []Owner{
+		{Owner: "payments", Projects: 2, WorkTree: 1500, Packed: 600, MonthlySavings: 350},
+		{Owner: "data", Projects: 1},
+		{Owner: "", Projects: 1, WorkTree: 10, Packed: 5},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ByOwner() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ByOwner()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package sweep
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/age"
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/github"
+	"github.com/deanhigh/bury-it/internal/state"
+)
+
+// noticesFile is the state file holding pending notices.
+const noticesFile = "notices.json"
+
+// NoticeTitle is the title of the issue that warns owners of a burial.
+const NoticeTitle = "This repository is scheduled for archival"
+
+// ObjectionCommand is the comment that objects to a scheduled burial.
+const ObjectionCommand = "/keep"
+
+// ErrCannotNotify is returned by a Notifier that has no way to reach the
+// owners of a repository.
+var ErrCannotNotify = errors.New("no way to notify owners")
+
+// Notice records that the owners of a repository were told about its burial.
+type Notice struct {
+	// Repo is the path of the local repository.
+	Repo string `json:"repo"`
+	// URL is the link to the notice, such as an issue.
+	URL string `json:"url"`
+	// Owner and Name identify the GitHub repository the notice was posted on.
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+	// Number is the issue number of the notice.
+	Number int `json:"number"`
+	// NotifiedAt is when the notice was posted.
+	NotifiedAt time.Time `json:"notified_at"`
+	// Deadline is when the grace period ends.
+	Deadline time.Time `json:"deadline"`
+	// Owners are the owners of the rule that scheduled the burial, who may
+	// object to it along with the repository's collaborators.
+	Owners []string `json:"owners,omitempty"`
+	// Objected is set once an owner objects; the repository is then kept.
+	Objected bool `json:"objected"`
+}
+
+// Notices are the notices posted by earlier sweeps, keyed by repository path.
+type Notices map[string]*Notice
+
+// LoadNotices reads the notices from local state.
+func LoadNotices() (Notices, error) {
+	notices := Notices{}
+	if err := state.Load(noticesFile, &notices); err != nil {
+		return nil, err
+	}
+	return notices, nil
+}
+
+// Save writes the notices to local state.
+func (n Notices) Save() error {
+	return state.Save(noticesFile, n)
+}
+
+// Notifier tells the owners of a repository about a scheduled burial and
+// collects their response.
+type Notifier interface {
+	// Notify posts a notice with the given deadline. It returns
+	// ErrCannotNotify if the owners cannot be reached.
+	Notify(c Candidate, deadline time.Time) (*Notice, error)
+	// Objected reports whether an owner has objected to the notice.
+	Objected(n *Notice) (bool, error)
+	// Resolve follows up on a notice once the repository has been buried.
+	Resolve(n *Notice, location string) error
+}
+
+// Decision is the outcome of reviewing a candidate's notice.
+type Decision string
+
+const (
+	// DecisionNotify means the owners were notified by this run.
+	DecisionNotify Decision = "notified"
+	// DecisionWait means the grace period has not ended.
+	DecisionWait Decision = "waiting"
+	// DecisionKeep means an owner objected.
+	DecisionKeep Decision = "kept"
+	// DecisionSkip means the owners could not be notified.
+	DecisionSkip Decision = "skipped"
+	// DecisionBury means the grace period ended without objection.
+	DecisionBury Decision = "bury"
+)
+
+// Review decides whether a candidate may be buried now. Candidates without a
+// notice are notified, unless dryRun is set, and buried on a later run once
+// grace has passed without objection.
+func (n Notices) Review(c Candidate, now time.Time, grace age.Span, notifier Notifier, dryRun bool) (Decision, error) {
+	notice, ok := n[c.Repo.Path]
+	if !ok {
+		if dryRun {
+			return DecisionNotify, nil
+		}
+		notice, err := notifier.Notify(c, grace.After(now))
+		if errors.Is(err, ErrCannotNotify) {
+			return DecisionSkip, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		n[c.Repo.Path] = notice
+		return DecisionNotify, nil
+	}
+
+	if notice.Objected {
+		return DecisionKeep, nil
+	}
+	objected, err := notifier.Objected(notice)
+	if err != nil {
+		return "", err
+	}
+	if objected {
+		notice.Objected = true
+		return DecisionKeep, nil
+	}
+	if now.Before(notice.Deadline) {
+		return DecisionWait, nil
+	}
+	return DecisionBury, nil
+}
+
+// IsObjection reports whether a comment objects to a scheduled burial.
+func IsObjection(comment string) bool {
+	for _, line := range strings.Split(comment, "\n") {
+		if strings.EqualFold(strings.TrimSpace(line), ObjectionCommand) {
+			return true
+		}
+	}
+	return false
+}
+
+// GitHubNotifier notifies owners with an issue on the repository's GitHub
+// origin.
+type GitHubNotifier struct {
+	// Client is the GitHub API client.
+	Client *github.Client
+}
+
+// Notify opens an issue mentioning the rule's owners.
+func (g *GitHubNotifier) Notify(c Candidate, deadline time.Time) (*Notice, error) {
+	remote, err := git.GetRemoteURL(c.Repo.Path)
+	if err != nil {
+		return nil, err
+	}
+	owner, name, ok := github.ParseRepoURL(remote)
+	if !ok {
+		return nil, ErrCannotNotify
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "This repository has had no commits since %s and matches the sunset rule \"%s\".\n\n",
+		c.Repo.LastCommit.Format("2006-01-02"), c.Rule.Name)
+	fmt.Fprintf(&b, "It will be archived into a graveyard repository with [bury-it](https://github.com/deanhigh/bury-it) after **%s**.\n\n",
+		deadline.Format("2006-01-02"))
+	fmt.Fprintf(&b, "To keep it, comment `%s` on this issue before then.\n", ObjectionCommand)
+	if len(c.Rule.Owners) > 0 {
+		fmt.Fprintf(&b, "\ncc %s\n", strings.Join(c.Rule.Owners, " "))
+	}
+
+	issue, err := g.Client.CreateIssue(owner, name, NoticeTitle, b.String())
+	if err != nil {
+		return nil, err
+	}
+	return &Notice{
+		Repo:       c.Repo.Path,
+		URL:        issue.HTMLURL,
+		Owner:      owner,
+		Name:       name,
+		Number:     issue.Number,
+		NotifiedAt: time.Now(),
+		Deadline:   deadline,
+		Owners:     c.Rule.Owners,
+	}, nil
+}
+
+// Objected reports whether any comment on the notice issue is an objection
+// by one of the notice's owners or a collaborator on the repository. Anyone
+// can comment on an issue, so objections from others are ignored.
+func (g *GitHubNotifier) Objected(n *Notice) (bool, error) {
+	comments, err := g.Client.ListComments(n.Owner, n.Name, n.Number)
+	if err != nil {
+		return false, err
+	}
+	for _, comment := range comments {
+		if IsObjection(comment.Body) && mayObject(comment, n.Owners) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// mayObject reports whether the author of a comment may object to a burial:
+// they are named in owners, or GitHub reports them as the repository's
+// owner, a member of its organization, or a collaborator.
+func mayObject(comment github.Comment, owners []string) bool {
+	switch comment.AuthorAssociation {
+	case "OWNER", "MEMBER", "COLLABORATOR":
+		return true
+	}
+	for _, owner := range owners {
+		if strings.EqualFold(strings.TrimPrefix(owner, "@"), comment.User.Login) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve comments on the notice issue with the graveyard location and closes
+// it.
+func (g *GitHubNotifier) Resolve(n *Notice, location string) error {
+	body := fmt.Sprintf("The grace period ended without objection. This repository has been buried in %s.", location)
+	if err := g.Client.CreateComment(n.Owner, n.Name, n.Number, body); err != nil {
+		return err
+	}
+	return g.Client.CloseIssue(n.Owner, n.Name, n.Number)
+}
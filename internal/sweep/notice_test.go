@@ -0,0 +1,158 @@
+package sweep
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/age"
+	"github.com/deanhigh/bury-it/internal/github"
+	"github.com/deanhigh/bury-it/internal/scan"
+)
+
+// fakeNotifier records notifications and reports configured objections.
+type fakeNotifier struct {
+	notified  []string
+	objecting map[string]bool
+	reachable bool
+}
+
+func (f *fakeNotifier) Notify(c Candidate, deadline time.Time) (*Notice, error) {
+	if !f.reachable {
+		return nil, ErrCannotNotify
+	}
+	f.notified = append(f.notified, c.Repo.Path)
+	return &Notice{Repo: c.Repo.Path, Deadline: deadline}, nil
+}
+
+func (f *fakeNotifier) Objected(n *Notice) (bool, error) {
+	return f.objecting[n.Repo], nil
+}
+
+func (f *fakeNotifier) Resolve(n *Notice, location string) error {
+	return nil
+}
+
+func TestNotices_Review(t *testing.T) {
+	now := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	grace := age.Span{Days: 14}
+	candidate := func(path string) Candidate {
+		return Candidate{Rule: &Rule{Name: "rule"}, Repo: scan.Repo{Path: path}}
+	}
+
+	tests := []struct {
+		name         string
+		notice       *Notice
+		objecting    bool
+		unreachable  bool
+		dryRun       bool
+		want         Decision
+		wantNotified bool
+		wantObjected bool
+	}{
+		{name: "first sighting notifies", want: DecisionNotify, wantNotified: true},
+		{name: "dry run does not notify", dryRun: true, want: DecisionNotify},
+		{name: "unreachable owners are skipped", unreachable: true, want: DecisionSkip},
+		{name: "grace period running", notice: &Notice{Deadline: now.AddDate(0, 0, 3)}, want: DecisionWait},
+		{name: "grace period over", notice: &Notice{Deadline: now.AddDate(0, 0, -1)}, want: DecisionBury},
+		{name: "objection", notice: &Notice{Deadline: now.AddDate(0, 0, -1)}, objecting: true, want: DecisionKeep, wantObjected: true},
+		{name: "earlier objection", notice: &Notice{Deadline: now.AddDate(0, 0, -1), Objected: true}, want: DecisionKeep, wantObjected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/src/repo"
+			notices := Notices{}
+			if tt.notice != nil {
+				tt.notice.Repo = path
+				notices[path] = tt.notice
+			}
+			notifier := &fakeNotifier{objecting: map[string]bool{path: tt.objecting}, reachable: !tt.unreachable}
+
+			got, err := notices.Review(candidate(path), now, grace, notifier, tt.dryRun)
+			if err != nil {
+				t.Fatalf("Review() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Review() = %q, want %q", got, tt.want)
+			}
+			if notified := len(notifier.notified) > 0; notified != tt.wantNotified {
+				t.Errorf("notified = %v, want %v", notified, tt.wantNotified)
+			}
+			if tt.wantNotified && !notices[path].Deadline.Equal(now.AddDate(0, 0, 14)) {
+				t.Errorf("notice deadline = %v, want grace period after now", notices[path].Deadline)
+			}
+			if notice := notices[path]; tt.wantObjected && (notice == nil || !notice.Objected) {
+				t.Errorf("notice not marked as objected: %+v", notice)
+			}
+		})
+	}
+}
+
+func TestIsObjection(t *testing.T) {
+	tests := []struct {
+		comment string
+		want    bool
+	}{
+		{comment: "/keep", want: true},
+		{comment: "Still in use!\n\n  /KEEP  ", want: true},
+		{comment: "please /keep this", want: false},
+		{comment: "fine to archive", want: false},
+	}
+	for _, tt := range tests {
+		if got := IsObjection(tt.comment); got != tt.want {
+			t.Errorf("IsObjection(%q) = %v, want %v", tt.comment, got, tt.want)
+		}
+	}
+}
+
+func TestMayObject(t *testing.T) {
+	owners := []string{"@alice", "@acme/platform"}
+	tests := []struct {
+		name        string
+		login       string
+		association string
+		want        bool
+	}{
+		{name: "rule owner", login: "Alice", association: "NONE", want: true},
+		{name: "collaborator", login: "bob", association: "COLLABORATOR", want: true},
+		{name: "organization member", login: "carol", association: "MEMBER", want: true},
+		{name: "repository owner", login: "acme", association: "OWNER", want: true},
+		{name: "drive-by commenter", login: "mallory", association: "NONE", want: false},
+		{name: "earlier contributor", login: "dave", association: "CONTRIBUTOR", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comment := github.Comment{Body: ObjectionCommand, User: github.User{Login: tt.login}, AuthorAssociation: tt.association}
+			if got := mayObject(comment, owners); got != tt.want {
+				t.Errorf("mayObject() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotices_LoadSave(t *testing.T) {
+	t.Setenv("BURY_IT_HOME", filepath.Join(t.TempDir(), "state"))
+
+	notices, err := LoadNotices()
+	if err != nil {
+		t.Fatalf("LoadNotices() error = %v", err)
+	}
+	if len(notices) != 0 {
+		t.Errorf("LoadNotices() = %v, want empty", notices)
+	}
+
+	deadline := time.Date(2025, 12, 15, 0, 0, 0, 0, time.UTC)
+	notices["/src/repo"] = &Notice{Repo: "/src/repo", Number: 4, Deadline: deadline}
+	if err := notices.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := LoadNotices()
+	if err != nil {
+		t.Fatalf("LoadNotices() error = %v", err)
+	}
+	if got := loaded["/src/repo"]; got == nil || got.Number != 4 || !got.Deadline.Equal(deadline) {
+		t.Errorf("LoadNotices() = %+v", loaded)
+	}
+}
@@ -0,0 +1,131 @@
+package sweep
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/deanhigh/bury-it/internal/age"
+	"github.com/deanhigh/bury-it/internal/git"
+	"go.yaml.in/yaml/v3"
+)
+
+// PolicyFileName is the name of the sunset policy file in a governance
+// repository.
+const PolicyFileName = "sunset-policy.yaml"
+
+// Policy is an organization's sunset policy: the criteria a sweep applies,
+// kept under version control so that changes to it can be reviewed.
+type Policy struct {
+	// Staleness is how long ago the last commit must be for a repository to
+	// be buried.
+	Staleness *age.Span `yaml:"staleness"`
+	// Topics restricts the policy to repositories carrying one of these
+	// GitHub topics.
+	Topics []string `yaml:"topics"`
+	// Owners are the people or teams to notify about matched repositories.
+	Owners []string `yaml:"owners"`
+	// Exclude lists glob patterns for repositories that are never buried.
+	Exclude []string `yaml:"exclude"`
+	// DropHistory buries matching repositories without their git history.
+	DropHistory bool `yaml:"drop_history"`
+
+	// Path is the absolute path of the policy file.
+	Path string `yaml:"-"`
+	// Version is the commit that last changed the policy file, or empty if
+	// the file is not committed. A "-dirty" suffix marks uncommitted edits.
+	Version string `yaml:"-"`
+}
+
+// LoadPolicy reads a sunset policy from a file, or from PolicyFileName in a
+// directory such as a clone of the governance repository.
+func LoadPolicy(path string) (*Policy, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve policy path: %w", err)
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, PolicyFileName)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	var policy Policy
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&policy); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse policy file %s: %w", path, err)
+	}
+	if policy.Staleness == nil {
+		return nil, fmt.Errorf("policy file %s: staleness is required", path)
+	}
+	for _, pattern := range policy.Exclude {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("policy file %s: invalid exclude pattern %q", path, pattern)
+		}
+	}
+
+	policy.Path = path
+	policy.Version, err = fileVersion(path)
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Rules applies the policy to repositories under each of paths.
+func (p *Policy) Rules(paths []string) (*Rules, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("policy needs at least one path to sweep")
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	rules := &Rules{}
+	for _, path := range paths {
+		resolved, err := resolvePath(cwd, path)
+		if err != nil {
+			return nil, err
+		}
+		rules.Rules = append(rules.Rules, Rule{
+			Name:          "sunset policy",
+			Path:          resolved,
+			NotTouchedFor: p.Staleness,
+			DropHistory:   p.DropHistory,
+			Exclude:       p.Exclude,
+			Topics:        p.Topics,
+			Owners:        p.Owners,
+		})
+	}
+	return rules, nil
+}
+
+// fileVersion returns the commit that last changed a file, suffixed with
+// "-dirty" if it has uncommitted changes. Files outside a git repository have
+// no version.
+func fileVersion(path string) (string, error) {
+	dir := filepath.Dir(path)
+	if !git.IsInsideWorkTree(dir) {
+		return "", nil
+	}
+	commit, err := git.LastCommitFor(dir, filepath.Base(path))
+	if err != nil || commit == "" {
+		return "", err
+	}
+	changed, err := git.HasChanges(dir, filepath.Base(path))
+	if err != nil {
+		return "", err
+	}
+	if changed {
+		commit += "-dirty"
+	}
+	return commit, nil
+}
@@ -0,0 +1,113 @@
+package sweep
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/deanhigh/bury-it/internal/age"
+)
+
+const testPolicy = `staleness: 18mo
+topics: [experiment]
+owners: ["@org/platform"]
+exclude: ["infra-*"]
+drop_history: true
+`
+
+func TestLoadPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		versioned   bool
+		edit        bool
+		wantErr     string
+		wantVersion string
+	}{
+		{name: "unversioned file", content: testPolicy},
+		{name: "committed in governance repo", content: testPolicy, versioned: true, wantVersion: "commit"},
+		{name: "uncommitted edits", content: testPolicy, versioned: true, edit: true, wantVersion: "dirty"},
+		{name: "missing staleness", content: "topics: [experiment]\n", wantErr: "staleness is required"},
+		{name: "unknown field", content: testPolicy + "stale: 1y\n", wantErr: "stale"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, PolicyFileName)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write policy: %v", err)
+			}
+			if tt.versioned {
+				for _, args := range [][]string{
+					{"init"},
+					{"add", "-A"},
+					{"-c", "user.name=Test", "-c", "user.email=test@test.com", "commit", "-m", "add policy"},
+				} {
+					if err := runGit(dir, args...); err != nil {
+						t.Fatalf("git %v failed: %v", args, err)
+					}
+				}
+			}
+			if tt.edit {
+				if err := os.WriteFile(path, []byte(tt.content+"# edited\n"), 0644); err != nil {
+					t.Fatalf("Failed to edit policy: %v", err)
+				}
+			}
+
+			// Loading from the directory finds the policy file
+			policy, err := LoadPolicy(dir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadPolicy() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadPolicy() error = %v", err)
+			}
+
+			if *policy.Staleness != (age.Span{Months: 18}) || !policy.DropHistory || policy.Path != path {
+				t.Errorf("LoadPolicy() = %+v", policy)
+			}
+			switch tt.wantVersion {
+			case "":
+				if policy.Version != "" {
+					t.Errorf("Version = %q, want empty", policy.Version)
+				}
+			case "commit":
+				if len(policy.Version) != 40 {
+					t.Errorf("Version = %q, want a commit hash", policy.Version)
+				}
+			case "dirty":
+				if !strings.HasSuffix(policy.Version, "-dirty") {
+					t.Errorf("Version = %q, want -dirty suffix", policy.Version)
+				}
+			}
+		})
+	}
+}
+
+func TestPolicy_Rules(t *testing.T) {
+	staleness := age.Span{Years: 1}
+	policy := &Policy{Staleness: &staleness, Topics: []string{"experiment"}, Owners: []string{"alice"}, Exclude: []string{"keep-*"}}
+
+	rules, err := policy.Rules([]string{"/src/a", "/src/b"})
+	if err != nil {
+		t.Fatalf("Rules() error = %v", err)
+	}
+	if len(rules.Rules) != 2 {
+		t.Fatalf("Rules() returned %d rules, want 2", len(rules.Rules))
+	}
+	for i, path := range []string{"/src/a", "/src/b"} {
+		r := rules.Rules[i]
+		if r.Path != path || *r.NotTouchedFor != staleness || r.Topics[0] != "experiment" || r.Owners[0] != "alice" || r.Exclude[0] != "keep-*" {
+			t.Errorf("Rules()[%d] = %+v", i, r)
+		}
+	}
+
+	if _, err := policy.Rules(nil); err == nil {
+		t.Errorf("Rules() expected error without paths")
+	}
+}
@@ -0,0 +1,216 @@
+// Package sweep selects repositories to bury using a declarative rules file.
+package sweep
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/age"
+	"github.com/deanhigh/bury-it/internal/scan"
+	"go.yaml.in/yaml/v3"
+)
+
+// Rules is the contents of a sweep rules file.
+type Rules struct {
+	// Graveyard is the path to the graveyard repository. It may be
+	// overridden on the command line.
+	Graveyard string `yaml:"graveyard"`
+	// Rules are applied in order; a repository matched by an earlier rule is
+	// not considered by later ones.
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule selects repositories under a directory that have gone untouched.
+type Rule struct {
+	// Name describes the rule in reports.
+	Name string `yaml:"name"`
+	// Path is the directory scanned for repositories.
+	Path string `yaml:"path"`
+	// NotTouchedFor is how long ago the last commit must be for a repository
+	// to match.
+	NotTouchedFor *age.Span `yaml:"not_touched_for"`
+	// DropHistory buries matching repositories without their git history.
+	DropHistory bool `yaml:"drop_history"`
+	// Exclude lists glob patterns matched against the repository's directory
+	// name and its path relative to Path.
+	Exclude []string `yaml:"exclude"`
+	// Topics restricts the rule to repositories whose GitHub origin carries
+	// at least one of these topics.
+	Topics []string `yaml:"topics"`
+	// Owners are the people or teams to notify about repositories matched
+	// by the rule.
+	Owners []string `yaml:"owners"`
+}
+
+// Candidate is a repository selected for burial.
+type Candidate struct {
+	// Rule is the rule that matched the repository.
+	Rule *Rule
+	// Repo is the matched repository.
+	Repo scan.Repo
+}
+
+// Load reads and validates a rules file. Relative paths in the file are
+// resolved against the file's directory.
+func Load(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	var rules Rules
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&rules); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse rules file %s: %w", path, err)
+	}
+
+	base, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	if rules.Graveyard != "" {
+		if rules.Graveyard, err = resolvePath(base, rules.Graveyard); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(rules.Rules) == 0 {
+		return nil, fmt.Errorf("rules file %s defines no rules", path)
+	}
+	for i := range rules.Rules {
+		rule := &rules.Rules[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if rule.Path == "" {
+			return nil, fmt.Errorf("%s: path is required", rule.Name)
+		}
+		if rule.NotTouchedFor == nil {
+			return nil, fmt.Errorf("%s: not_touched_for is required", rule.Name)
+		}
+		for _, pattern := range rule.Exclude {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("%s: invalid exclude pattern %q", rule.Name, pattern)
+			}
+		}
+		if rule.Path, err = resolvePath(base, rule.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	return &rules, nil
+}
+
+// Candidates scans the directory of each rule and returns the repositories
+// whose last commit is older than the rule allows, as of now. Repositories
+// without commits are never selected.
+func (r *Rules) Candidates(now time.Time) ([]Candidate, error) {
+	return r.candidates(now, scan.Repos)
+}
+
+// CandidatesAsOf returns the repositories the rules would have selected at
+// asOf, judging each by the commits it had then. Repositories that had no
+// commits yet are never selected.
+func (r *Rules) CandidatesAsOf(asOf time.Time) ([]Candidate, error) {
+	return r.candidates(asOf, func(root string) ([]scan.Repo, error) {
+		return scan.ReposAsOf(root, asOf)
+	})
+}
+
+// candidates applies the rules at now to the repositories found by scanRepos.
+func (r *Rules) candidates(now time.Time, scanRepos func(root string) ([]scan.Repo, error)) ([]Candidate, error) {
+	var candidates []Candidate
+	seen := make(map[string]bool)
+	for i := range r.Rules {
+		rule := &r.Rules[i]
+		repos, err := scanRepos(rule.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", rule.Name, err)
+		}
+
+		cutoff := rule.NotTouchedFor.Before(now)
+		for _, repo := range repos {
+			if seen[repo.Path] || repo.LastCommit.IsZero() || !repo.LastCommit.Before(cutoff) {
+				continue
+			}
+			if rule.excludes(repo.Path) {
+				continue
+			}
+			seen[repo.Path] = true
+			candidates = append(candidates, Candidate{Rule: rule, Repo: repo})
+		}
+	}
+	return candidates, nil
+}
+
+// FilterTopics keeps the candidates whose rule has no topic restriction or
+// whose repository carries one of the rule's topics. topics returns the topics
+// of a repository given its path.
+func FilterTopics(candidates []Candidate, topics func(repoPath string) ([]string, error)) ([]Candidate, error) {
+	var kept []Candidate
+	for _, c := range candidates {
+		if len(c.Rule.Topics) == 0 {
+			kept = append(kept, c)
+			continue
+		}
+		repoTopics, err := topics(c.Repo.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read topics of %s: %w", c.Repo.Path, err)
+		}
+		if hasAny(repoTopics, c.Rule.Topics) {
+			kept = append(kept, c)
+		}
+	}
+	return kept, nil
+}
+
+// hasAny reports whether have and want share an element, ignoring case.
+func hasAny(have, want []string) bool {
+	for _, h := range have {
+		for _, w := range want {
+			if strings.EqualFold(h, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// excludes reports whether repoPath matches one of the rule's exclude patterns.
+func (r *Rule) excludes(repoPath string) bool {
+	rel, err := filepath.Rel(r.Path, repoPath)
+	if err != nil {
+		rel = repoPath
+	}
+	for _, pattern := range r.Exclude {
+		if ok, _ := filepath.Match(pattern, filepath.Base(repoPath)); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath expands a leading ~ and makes path absolute relative to base.
+func resolvePath(base, path string) (string, error) {
+	if strings.HasPrefix(path, "~") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to expand home directory: %w", err)
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+	return filepath.Clean(path), nil
+}
@@ -0,0 +1,216 @@
+package sweep
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/age"
+	"github.com/deanhigh/bury-it/internal/scan"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+		check   func(t *testing.T, dir string, rules *Rules)
+	}{
+		{
+			name: "valid rules",
+			content: `graveyard: graveyard
+rules:
+  - name: experiments
+    path: src/experiments
+    not_touched_for: 18mo
+    drop_history: true
+    exclude: ["keep-*"]
+  - path: /abs/path
+    not_touched_for: 2y
+`,
+			check: func(t *testing.T, dir string, rules *Rules) {
+				if rules.Graveyard != filepath.Join(dir, "graveyard") {
+					t.Errorf("Graveyard = %q", rules.Graveyard)
+				}
+				first := rules.Rules[0]
+				if first.Path != filepath.Join(dir, "src", "experiments") {
+					t.Errorf("Rules[0].Path = %q", first.Path)
+				}
+				if *first.NotTouchedFor != (age.Span{Months: 18}) || !first.DropHistory {
+					t.Errorf("Rules[0] = %+v", first)
+				}
+				second := rules.Rules[1]
+				if second.Name != "rule 2" || second.Path != "/abs/path" {
+					t.Errorf("Rules[1] = %+v", second)
+				}
+			},
+		},
+		{name: "empty file", content: "", wantErr: "defines no rules"},
+		{name: "missing path", content: "rules:\n  - not_touched_for: 1y\n", wantErr: "path is required"},
+		{name: "missing age", content: "rules:\n  - path: src\n", wantErr: "not_touched_for is required"},
+		{name: "invalid age", content: "rules:\n  - path: src\n    not_touched_for: 18m\n", wantErr: "invalid span"},
+		{name: "unknown field", content: "rules:\n  - path: src\n    not_touched_for: 1y\n    olderThan: 1y\n", wantErr: "olderThan"},
+		{name: "invalid exclude", content: "rules:\n  - path: src\n    not_touched_for: 1y\n    exclude: [\"[\"]\n", wantErr: "invalid exclude pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "rules.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write rules: %v", err)
+			}
+
+			rules, err := Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			tt.check(t, dir, rules)
+		})
+	}
+}
+
+func TestRules_Candidates(t *testing.T) {
+	root := t.TempDir()
+	now := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	old := now.AddDate(-2, 0, 0)
+
+	initRepo(t, filepath.Join(root, "experiments", "stale"), old)
+	initRepo(t, filepath.Join(root, "experiments", "keep-me"), old)
+	initRepo(t, filepath.Join(root, "experiments", "fresh"), now.AddDate(0, -1, 0))
+	initRepo(t, filepath.Join(root, "work", "legacy"), old)
+	if err := runGit(filepath.Join(root, "experiments"), "init", "empty"); err != nil {
+		t.Fatalf("git init failed: %v", err)
+	}
+
+	yearAndHalf := age.Span{Months: 18}
+	rules := &Rules{Rules: []Rule{
+		{Name: "experiments", Path: filepath.Join(root, "experiments"), NotTouchedFor: &yearAndHalf, Exclude: []string{"keep-*"}},
+		{Name: "everything", Path: root, NotTouchedFor: &yearAndHalf, DropHistory: true},
+	}}
+
+	got, err := rules.Candidates(now)
+	if err != nil {
+		t.Fatalf("Candidates() error = %v", err)
+	}
+
+	want := []struct {
+		rule string
+		path string
+	}{
+		{"experiments", filepath.Join(root, "experiments", "stale")},
+		{"everything", filepath.Join(root, "experiments", "keep-me")},
+		{"everything", filepath.Join(root, "work", "legacy")},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Candidates() returned %d candidates, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Rule.Name != w.rule || got[i].Repo.Path != w.path {
+			t.Errorf("Candidates()[%d] = %s %s, want %s %s", i, got[i].Rule.Name, got[i].Repo.Path, w.rule, w.path)
+		}
+	}
+}
+
+func TestRules_CandidatesAsOf(t *testing.T) {
+	root := t.TempDir()
+	asOf := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	old := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	initRepo(t, filepath.Join(root, "stale"), old)
+	initRepo(t, filepath.Join(root, "revived"), old)
+	initRepo(t, filepath.Join(root, "young"), time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	// revived was worked on again after asOf.
+	revived := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC).Format(time.RFC3339)
+	t.Setenv("GIT_AUTHOR_DATE", revived)
+	t.Setenv("GIT_COMMITTER_DATE", revived)
+	if err := runGit(filepath.Join(root, "revived"), "-c", "user.name=Test", "-c", "user.email=test@test.com", "commit", "--allow-empty", "-m", "revive"); err != nil {
+		t.Fatalf("git commit failed: %v", err)
+	}
+
+	yearAndHalf := age.Span{Months: 18}
+	rules := &Rules{Rules: []Rule{{Name: "all", Path: root, NotTouchedFor: &yearAndHalf}}}
+
+	got, err := rules.CandidatesAsOf(asOf)
+	if err != nil {
+		t.Fatalf("CandidatesAsOf() error = %v", err)
+	}
+	if len(got) != 2 || got[0].Repo.Path != filepath.Join(root, "revived") || got[1].Repo.Path != filepath.Join(root, "stale") {
+		t.Fatalf("CandidatesAsOf() = %+v, want revived and stale", got)
+	}
+	if !got[0].Repo.LastCommit.Equal(old) {
+		t.Errorf("CandidatesAsOf() last commit of revived = %v, want %v", got[0].Repo.LastCommit, old)
+	}
+
+	got, err = rules.Candidates(time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Candidates() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Repo.Path != filepath.Join(root, "stale") {
+		t.Errorf("Candidates() = %+v, want only stale", got)
+	}
+}
+
+// initRepo creates a repository at dir with one commit dated at.
+func initRepo(t *testing.T, dir string, at time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# repo\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	date := at.Format(time.RFC3339)
+	t.Setenv("GIT_AUTHOR_DATE", date)
+	t.Setenv("GIT_COMMITTER_DATE", date)
+	for _, args := range [][]string{
+		{"init"},
+		{"add", "-A"},
+		{"-c", "user.name=Test", "-c", "user.email=test@test.com", "commit", "-m", "initial commit"},
+	} {
+		if err := runGit(dir, args...); err != nil {
+			t.Fatalf("git %v failed: %v", args, err)
+		}
+	}
+}
+
+// runGit is a helper to run git commands in tests.
+func runGit(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	return cmd.Run()
+}
+
+func TestFilterTopics(t *testing.T) {
+	anyTopic := &Rule{Name: "any"}
+	experiments := &Rule{Name: "experiments", Topics: []string{"Experiment"}}
+	candidates := []Candidate{
+		{Rule: anyTopic, Repo: scan.Repo{Path: "/src/untagged"}},
+		{Rule: experiments, Repo: scan.Repo{Path: "/src/tagged"}},
+		{Rule: experiments, Repo: scan.Repo{Path: "/src/other"}},
+	}
+	topics := map[string][]string{
+		"/src/tagged": {"experiment", "ml"},
+		"/src/other":  {"production"},
+	}
+
+	got, err := FilterTopics(candidates, func(repoPath string) ([]string, error) {
+		return topics[repoPath], nil
+	})
+	if err != nil {
+		t.Fatalf("FilterTopics() error = %v", err)
+	}
+	if len(got) != 2 || got[0].Repo.Path != "/src/untagged" || got[1].Repo.Path != "/src/tagged" {
+		t.Errorf("FilterTopics() = %+v", got)
+	}
+}
@@ -0,0 +1,301 @@
+// Package unpack extracts tar and zip archives, from a file or a URL, so that
+// code that only survives as an archive can be buried.
+package unpack
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Extensions are the archive extensions recognized, longest first.
+var Extensions = []string{".tar.gz", ".tar.bz2", ".tgz", ".tbz2", ".tar", ".zip"}
+
+// Ext returns the archive extension of name, or "" if it has none.
+func Ext(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range Extensions {
+		if strings.HasSuffix(lower, ext) && len(lower) > len(ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// TrimExt returns name without its archive extension.
+func TrimExt(name string) string {
+	return name[:len(name)-len(Ext(name))]
+}
+
+// Download fetches the archive at rawURL into dir, returning the path of the
+// file, which is named after the last segment of the URL's path.
+func Download(rawURL, dir string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %s: %w", rawURL, err)
+	}
+	resp, err := http.Get(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download %s: %s", rawURL, resp.Status)
+	}
+
+	// A redirect may lead to a better name, such as the one GitHub gives
+	// the archive of a tag
+	name := path.Base(resp.Request.URL.Path)
+	if Ext(name) == "" {
+		name = path.Base(u.Path)
+	}
+	archivePath := filepath.Join(dir, name)
+	f, err := os.Create(archivePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", archivePath, err)
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		_ = f.Close()
+		return "", fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", archivePath, err)
+	}
+	return archivePath, nil
+}
+
+// Extract extracts the archive at archivePath into dir, which must exist,
+// and returns the latest modification time of its entries. Entries that
+// would land outside dir, even by way of links extracted before them, are
+// refused.
+func Extract(archivePath, dir string) (time.Time, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer func() { _ = root.Close() }()
+
+	var latest time.Time
+	if Ext(archivePath) == ".zip" {
+		latest, err = extractZip(archivePath, root)
+	} else {
+		latest, err = extractTar(archivePath, root)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to extract %s: %w", filepath.Base(archivePath), err)
+	}
+	return latest, nil
+}
+
+// extractTar extracts a tar archive, compressed according to its
+// extension, into root.
+func extractTar(archivePath string, root *os.Root) (time.Time, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var r io.Reader = f
+	switch Ext(archivePath) {
+	case ".tar.gz", ".tgz":
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return time.Time{}, err
+		}
+		defer func() { _ = gz.Close() }()
+		r = gz
+	case ".tar.bz2", ".tbz2":
+		r = bzip2.NewReader(f)
+	}
+
+	var latest time.Time
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return latest, nil
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+		target, err := entryPath(hdr.Name)
+		if err != nil {
+			return time.Time{}, err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = root.MkdirAll(target, 0755)
+		case tar.TypeReg:
+			err = writeFile(root, target, tr, hdr.FileInfo().Mode())
+		case tar.TypeSymlink:
+			err = symlink(root, hdr.Name, hdr.Linkname, target)
+		case tar.TypeLink:
+			var source string
+			if source, err = entryPath(hdr.Linkname); err == nil {
+				err = root.Link(source, target)
+			}
+		default:
+			// Devices, FIFOs, and the like are not source code
+			continue
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+		if hdr.Typeflag != tar.TypeDir && hdr.ModTime.After(latest) {
+			latest = hdr.ModTime
+		}
+	}
+}
+
+// extractZip extracts a zip archive into root.
+func extractZip(archivePath string, root *os.Root) (time.Time, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer func() { _ = zr.Close() }()
+
+	var latest time.Time
+	for _, f := range zr.File {
+		target, err := entryPath(f.Name)
+		if err != nil {
+			return time.Time{}, err
+		}
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			err = root.MkdirAll(target, 0755)
+		case mode&os.ModeSymlink != 0:
+			var link []byte
+			if link, err = readZipFile(f); err == nil {
+				err = symlink(root, f.Name, string(link), target)
+			}
+		case mode.IsRegular():
+			var rc io.ReadCloser
+			if rc, err = f.Open(); err == nil {
+				err = writeFile(root, target, rc, mode)
+				_ = rc.Close()
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+		if !mode.IsDir() && f.Modified.After(latest) {
+			latest = f.Modified
+		}
+	}
+	return latest, nil
+}
+
+// readZipFile returns the content of a zip entry.
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+	return io.ReadAll(rc)
+}
+
+// entryPath returns where the entry name is extracted to, relative to the
+// extraction directory, refusing names that would land outside it.
+func entryPath(name string) (string, error) {
+	clean := filepath.FromSlash(strings.TrimSuffix(name, "/"))
+	if !filepath.IsLocal(clean) {
+		return "", fmt.Errorf("entry %s is outside the archive", name)
+	}
+	return clean, nil
+}
+
+// writeFile writes a regular file at target in root, keeping whether it is
+// executable.
+func writeFile(root *os.Root, target string, r io.Reader, mode os.FileMode) error {
+	if err := root.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	perm := os.FileMode(0644)
+	if mode&0111 != 0 {
+		perm = 0755
+	}
+	f, err := root.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// symlink creates the symbolic link entry name at target in root, pointing
+// to link, refusing links that point outside root, so that nothing reading
+// the extracted files is led out of it. The link is followed component by
+// component, as the links extracted before it can make a path that looks
+// local, such as x/.. with x linking to ., lead elsewhere; a link made in or
+// pointing through another link is refused. Files are written through root,
+// which refuses to follow links out of it.
+func symlink(root *os.Root, name, link, target string) error {
+	if filepath.IsAbs(link) {
+		return fmt.Errorf("entry %s links outside the archive", name)
+	}
+	// The link's own directory is checked too, as a link made through
+	// another link is resolved from where that one leads
+	sep := string(filepath.Separator)
+	parts := strings.Split(filepath.FromSlash(link), sep)
+	if dir := filepath.Dir(target); dir != "." {
+		parts = append(strings.Split(dir, sep), parts...)
+	}
+	var resolved []string
+	for i, part := range parts {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				return fmt.Errorf("entry %s links outside the archive", name)
+			}
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+		resolved = append(resolved, part)
+		if i == len(parts)-1 {
+			// Linking to a link, itself checked when it was made, is fine
+			break
+		}
+		if info, err := root.Lstat(filepath.Join(resolved...)); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("entry %s links through another link", name)
+		}
+	}
+	if err := root.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return root.Symlink(link, target)
+}
+
+// Root returns the directory the content of an archive extracted into dir
+// lies in: the single directory the archive holds, as in the
+// project-1.0/ of project-1.0.tar.gz, or else dir itself.
+func Root(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return filepath.Join(dir, entries[0].Name()), nil
+	}
+	return dir, nil
+}
@@ -0,0 +1,243 @@
+package unpack
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExt(t *testing.T) {
+	tests := []struct {
+		name     string
+		wantExt  string
+		wantTrim string
+	}{
+		{name: "project.tar.gz", wantExt: ".tar.gz", wantTrim: "project"},
+		{name: "project-1.0.TGZ", wantExt: ".tgz", wantTrim: "project-1.0"},
+		{name: "project.tar.bz2", wantExt: ".tar.bz2", wantTrim: "project"},
+		{name: "project.tar", wantExt: ".tar", wantTrim: "project"},
+		{name: "project.zip", wantExt: ".zip", wantTrim: "project"},
+		{name: "project.gz", wantExt: "", wantTrim: "project.gz"},
+		{name: "project", wantExt: "", wantTrim: "project"},
+		{name: ".zip", wantExt: "", wantTrim: ".zip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Ext(tt.name); got != tt.wantExt {
+				t.Errorf("Ext(%q) = %q, want %q", tt.name, got, tt.wantExt)
+			}
+			if got := TrimExt(tt.name); got != tt.wantTrim {
+				t.Errorf("TrimExt(%q) = %q, want %q", tt.name, got, tt.wantTrim)
+			}
+		})
+	}
+}
+
+// entry is a file of a test archive; a link is a symbolic link to it.
+type entry struct {
+	name    string
+	content string
+	link    string
+	exec    bool
+}
+
+var modTime = time.Date(2019, 3, 4, 5, 6, 7, 0, time.UTC)
+
+func writeTarGz(t *testing.T, path string, entries []entry) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for i, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content)), ModTime: modTime.Add(-time.Duration(i) * time.Hour), Typeflag: tar.TypeReg}
+		if e.exec {
+			hdr.Mode = 0755
+		}
+		if e.link != "" {
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, e.link, 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []interface{ Close() error }{tw, gz, f} {
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func writeZip(t *testing.T, path string, entries []entry) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for i, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Modified: modTime.Add(-time.Duration(i) * time.Hour)}
+		hdr.SetMode(0644)
+		if e.exec {
+			hdr.SetMode(0755)
+		}
+		content := e.content
+		if e.link != "" {
+			hdr.SetMode(os.ModeSymlink | 0777)
+			content = e.link
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	entries := []entry{
+		{name: "project-1.0/README.md", content: "# Project\n"},
+		{name: "project-1.0/bin/run.sh", content: "#!/bin/sh\n", exec: true},
+		{name: "project-1.0/docs", link: "README.md"},
+	}
+
+	for _, ext := range []string{".tar.gz", ".zip"} {
+		t.Run(ext, func(t *testing.T) {
+			dir := t.TempDir()
+			archivePath := filepath.Join(dir, "project-1.0"+ext)
+			if ext == ".zip" {
+				writeZip(t, archivePath, entries)
+			} else {
+				writeTarGz(t, archivePath, entries)
+			}
+			dest := filepath.Join(dir, "out")
+			if err := os.Mkdir(dest, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			latest, err := Extract(archivePath, dest)
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if !latest.Equal(modTime) {
+				t.Errorf("Extract() latest = %v, want %v", latest, modTime)
+			}
+			root, err := Root(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.Join(dest, "project-1.0"); root != want {
+				t.Errorf("Root() = %s, want %s", root, want)
+			}
+
+			data, err := os.ReadFile(filepath.Join(root, "README.md"))
+			if err != nil || string(data) != "# Project\n" {
+				t.Errorf("README.md = %q, %v", data, err)
+			}
+			info, err := os.Stat(filepath.Join(root, "bin", "run.sh"))
+			if err != nil || info.Mode()&0100 == 0 {
+				t.Errorf("run.sh is not executable: %v, %v", info, err)
+			}
+			if link, err := os.Readlink(filepath.Join(root, "docs")); err != nil || link != "README.md" {
+				t.Errorf("docs links to %q, %v; want README.md", link, err)
+			}
+		})
+	}
+}
+
+func TestExtract_Outside(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []entry
+	}{
+		{name: "parent", entries: []entry{{name: "../evil", content: "x"}}},
+		{name: "absolute", entries: []entry{{name: "/tmp/evil", content: "x"}}},
+		{name: "absolute link", entries: []entry{{name: "link", link: "/etc"}}},
+		{name: "escaping link", entries: []entry{{name: "a/link", link: "../../etc"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			archivePath := filepath.Join(dir, "evil.tar.gz")
+			writeTarGz(t, archivePath, tt.entries)
+			dest := filepath.Join(dir, "out")
+			if err := os.Mkdir(dest, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Extract(archivePath, dest); err == nil {
+				t.Error("Extract() error = nil, want entries outside the archive refused")
+			}
+		})
+	}
+}
+
+func TestExtract_ThroughLinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []entry
+	}{
+		{name: "link resolving outside through another", entries: []entry{
+			{name: "x", link: "."},
+			{name: "x/y", link: ".."},
+			{name: "y/escaped.txt", content: "x"},
+		}},
+		{name: "file written through a link", entries: []entry{
+			{name: "x", link: "."},
+			{name: "y", link: "x/.."},
+			{name: "y/escaped.txt", content: "x"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			archivePath := filepath.Join(dir, "evil.tar.gz")
+			writeTarGz(t, archivePath, tt.entries)
+			dest := filepath.Join(dir, "out")
+			if err := os.Mkdir(dest, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Extract(archivePath, dest); err == nil {
+				t.Error("Extract() error = nil, want the escaping entry refused")
+			}
+			if _, err := os.Lstat(filepath.Join(dir, "escaped.txt")); err == nil {
+				t.Error("Extract() wrote escaped.txt outside the extraction directory")
+			}
+		})
+	}
+}
+
+func TestRoot(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := Root(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != dir {
+		t.Errorf("Root() of several directories = %s, want %s", got, dir)
+	}
+}
@@ -0,0 +1,76 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/deanhigh/bury-it/internal/display"
+	"github.com/deanhigh/bury-it/internal/git"
+	"github.com/deanhigh/bury-it/internal/graveyard"
+)
+
+// WriteSite generates a static HTML catalog of the graveyard in outDir: an
+// index page listing every project and a page per project with its metadata
+// and README. It returns the number of project pages written. Pages use
+// relative links, so the site can be published from any path, such as a
+// GitHub Pages docs/ directory.
+func WriteSite(gy *graveyard.Graveyard, outDir string) (int, error) {
+	data, err := indexData(gy, "")
+	if err != nil {
+		return 0, err
+	}
+	// Point readers at the published graveyard rather than a local path,
+	// without the credentials a remote URL can carry
+	if remote, err := git.GetRemoteURL(gy.Path); err == nil && remote != "" {
+		data["Graveyard"] = display.URL(remote)
+	}
+
+	// Project pages are regenerated from scratch so removed projects disappear
+	projectsDir := filepath.Join(outDir, "p")
+	if err := os.RemoveAll(projectsDir); err != nil {
+		return 0, fmt.Errorf("failed to clear %s: %w", projectsDir, err)
+	}
+
+	data["Root"] = "./"
+	data["Static"] = true
+	if err := writePage(filepath.Join(outDir, "index.html"), "index.html", data); err != nil {
+		return 0, err
+	}
+
+	rows := data["Projects"].([]projectSummary)
+	for _, row := range rows {
+		// Links in a project are not followed out of it
+		root, err := os.OpenRoot(gy.ProjectPath(row.Name))
+		if err != nil {
+			return 0, err
+		}
+		page, err := projectData(row.Name, root.FS())
+		_ = root.Close()
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", row.Name, err)
+		}
+		page["Root"] = "../../"
+		page["Static"] = true
+		if err := writePage(filepath.Join(projectsDir, row.Name, "index.html"), "project.html", page); err != nil {
+			return 0, err
+		}
+	}
+	return len(rows), nil
+}
+
+// writePage renders a template to a file, creating its directory.
+func writePage(path, name string, data map[string]any) error {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return fmt.Errorf("failed to render %s: %w", path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
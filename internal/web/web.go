@@ -0,0 +1,313 @@
+// Package web renders a read-only browser view of a graveyard, either served
+// live or written out as a static site.
+package web
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/metadata"
+	"github.com/deanhigh/bury-it/internal/size"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// templates are the parsed page templates, keyed by file name.
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"date": func(t time.Time) string { return t.Format("2006-01-02") },
+	"size": size.Format,
+	"join": path.Join,
+	"base": path.Base,
+}).ParseFS(templateFS, "templates/*.html"))
+
+// maxPreviewSize is the largest file shown inline; larger files are only
+// offered for download.
+const maxPreviewSize = 1 << 20
+
+// readmeNames are the file names shown as a project's README preview.
+var readmeNames = []string{"README.md", "README", "README.txt", "readme.md"}
+
+// Server serves the pages of a graveyard.
+type Server struct {
+	gy  *graveyard.Graveyard
+	mux *http.ServeMux
+}
+
+// New creates a server for the graveyard.
+func New(gy *graveyard.Graveyard) *Server {
+	s := &Server{gy: gy, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /{$}", s.handleIndex)
+	s.mux.HandleFunc("GET /p/{project}/{$}", s.handleProject)
+	s.mux.HandleFunc("GET /p/{project}/tree/{path...}", s.handleTree)
+	s.mux.HandleFunc("GET /p/{project}/raw/{path...}", s.handleRaw)
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// projectSummary is a row of the project list.
+type projectSummary struct {
+	Name string
+	Meta *metadata.Metadata
+}
+
+// entry is a file or directory in a tree listing.
+type entry struct {
+	Name  string
+	IsDir bool
+	Size  int64
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	data, err := indexData(s.gy, r.URL.Query().Get("tag"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data["Root"] = "/"
+	s.render(w, "index.html", data)
+}
+
+func (s *Server) handleProject(w http.ResponseWriter, r *http.Request) {
+	project, root, ok := s.projectRoot(w, r)
+	if !ok {
+		return
+	}
+	defer func() { _ = root.Close() }()
+	fsys := root.FS()
+
+	data, err := projectData(project, fsys)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	entries, err := listDir(fsys, ".")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data["Root"] = "/"
+	data["Entries"] = entries
+	s.render(w, "project.html", data)
+}
+
+// indexData returns the template data of the project list, keeping only
+// projects with tag if it is not empty.
+func indexData(gy *graveyard.Graveyard, tag string) (map[string]any, error) {
+	projects, err := gy.Projects()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []projectSummary
+	for _, name := range projects {
+		meta, err := gy.Metadata(name)
+		if err != nil {
+			return nil, err
+		}
+		if tag != "" && !meta.HasTags(tag) {
+			continue
+		}
+		rows = append(rows, projectSummary{Name: name, Meta: meta})
+	}
+	return map[string]any{"Graveyard": gy.Path, "Projects": rows, "Tag": tag}, nil
+}
+
+// projectData returns the template data of a project page: its metadata and
+// README preview.
+func projectData(project string, fsys fs.FS) (map[string]any, error) {
+	meta, err := fs.ReadFile(fsys, metadata.FileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var readmeName, readme string
+	for _, name := range readmeNames {
+		if content, err := fs.ReadFile(fsys, name); err == nil {
+			readmeName, readme = name, string(content)
+			break
+		}
+	}
+
+	return map[string]any{
+		"Project":    project,
+		"Metadata":   string(meta),
+		"Dir":        "",
+		"ReadmeName": readmeName,
+		"Readme":     readme,
+	}, nil
+}
+
+func (s *Server) handleTree(w http.ResponseWriter, r *http.Request) {
+	project, root, ok := s.projectRoot(w, r)
+	if !ok {
+		return
+	}
+	defer func() { _ = root.Close() }()
+	fsys := root.FS()
+	name, ok := cleanPath(r.PathValue("path"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	data := map[string]any{"Root": "/", "Project": project, "Dir": name, "Parents": parents(name)}
+	if info.IsDir() {
+		entries, err := listDir(fsys, name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data["Entries"] = entries
+		s.render(w, "tree.html", data)
+		return
+	}
+
+	data["Size"] = info.Size()
+	if info.Size() <= maxPreviewSize {
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if isText(content) {
+			data["Text"] = true
+			data["Content"] = string(content)
+		}
+	}
+	s.render(w, "file.html", data)
+}
+
+func (s *Server) handleRaw(w http.ResponseWriter, r *http.Request) {
+	_, root, ok := s.projectRoot(w, r)
+	if !ok {
+		return
+	}
+	defer func() { _ = root.Close() }()
+	fsys := root.FS()
+	name, ok := cleanPath(r.PathValue("path"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if info, err := fs.Stat(fsys, name); err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeFileFS(w, r, fsys, name)
+}
+
+// projectRoot opens the directory of the project named in the request, or
+// writes a not found response. Files are read through the returned root,
+// which the caller closes, so that links in a project cannot serve files
+// from elsewhere on the host.
+func (s *Server) projectRoot(w http.ResponseWriter, r *http.Request) (string, *os.Root, bool) {
+	project := r.PathValue("project")
+	projects, err := s.gy.Projects()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return "", nil, false
+	}
+	for _, name := range projects {
+		if name == project {
+			root, err := os.OpenRoot(s.gy.ProjectPath(project))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return "", nil, false
+			}
+			return project, root, true
+		}
+	}
+	http.NotFound(w, r)
+	return "", nil, false
+}
+
+// render executes a page template, reporting failures as server errors.
+func (s *Server) render(w http.ResponseWriter, name string, data map[string]any) {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = buf.WriteTo(w)
+}
+
+// listDir lists a directory with subdirectories first, skipping .git.
+func listDir(fsys fs.FS, dir string) ([]entry, error) {
+	dirEntries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var entries []entry
+	for _, d := range dirEntries {
+		if d.Name() == ".git" {
+			continue
+		}
+		e := entry{Name: d.Name(), IsDir: d.IsDir()}
+		if !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				e.Size = info.Size()
+			}
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].IsDir && !entries[j].IsDir
+	})
+	return entries, nil
+}
+
+// cleanPath validates a slash-separated path within a project.
+func cleanPath(p string) (string, bool) {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return ".", true
+	}
+	return p, fs.ValidPath(p)
+}
+
+// crumb is a link to an ancestor directory.
+type crumb struct {
+	Name string
+	Path string
+}
+
+// parents returns the breadcrumb trail for a path, excluding the path itself.
+func parents(p string) []crumb {
+	if p == "." {
+		return nil
+	}
+	parts := strings.Split(p, "/")
+	crumbs := make([]crumb, 0, len(parts)-1)
+	for i := range parts[:len(parts)-1] {
+		crumbs = append(crumbs, crumb{Name: parts[i], Path: strings.Join(parts[:i+1], "/")})
+	}
+	return crumbs
+}
+
+// isText reports whether content looks like UTF-8 text.
+func isText(content []byte) bool {
+	return utf8.Valid(content) && !bytes.ContainsRune(content, 0)
+}
@@ -0,0 +1,123 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deanhigh/bury-it/internal/graveyard"
+	"github.com/deanhigh/bury-it/internal/metadata"
+)
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	meta := &metadata.Metadata{
+		OriginalSource: "https://github.com/owner/old-app",
+		BuriedAt:       time.Date(2025, 12, 26, 10, 30, 0, 0, time.UTC),
+		Tags:           []string{"ml"},
+	}
+	files := map[string]string{
+		"old-app/" + metadata.FileName: meta.Generate(),
+		"old-app/README.md":            "# Old App\n\nDid <things>.\n",
+		"old-app/src/main.go":          "package main\n",
+		"old-app/logo.bin":             "\x00\x01\x02",
+		"other/" + metadata.FileName:   (&metadata.Metadata{OriginalSource: "/src/other"}).Generate(),
+		"not-a-project/file.txt":       "ignored",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	secret := filepath.Join(t.TempDir(), "id_rsa")
+	if err := os.WriteFile(secret, []byte("PRIVATE KEY"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secret, filepath.Join(dir, "old-app", "leak")); err != nil {
+		t.Fatal(err)
+	}
+	server := New(&graveyard.Graveyard{Path: dir})
+
+	tests := []struct {
+		name            string
+		path            string
+		wantStatus      int
+		wantContains    []string
+		wantNotContains []string
+	}{
+		{
+			name:            "project list",
+			path:            "/",
+			wantStatus:      http.StatusOK,
+			wantContains:    []string{`href="/p/old-app/"`, "2025-12-26", "https://github.com/owner/old-app", `href="/?tag=ml"`, `href="/p/other/"`},
+			wantNotContains: []string{"not-a-project"},
+		},
+		{
+			name:            "project list filtered by tag",
+			path:            "/?tag=ml",
+			wantStatus:      http.StatusOK,
+			wantContains:    []string{`href="/p/old-app/"`},
+			wantNotContains: []string{`href="/p/other/"`},
+		},
+		{
+			name:         "project page",
+			path:         "/p/old-app/",
+			wantStatus:   http.StatusOK,
+			wantContains: []string{"| **Tags** | ml |", "<h2>README.md</h2>", "Did &lt;things&gt;.", `href="/p/old-app/tree/src/"`},
+		},
+		{
+			name:         "directory",
+			path:         "/p/old-app/tree/src/",
+			wantStatus:   http.StatusOK,
+			wantContains: []string{`href="/p/old-app/tree/src/main.go"`},
+		},
+		{
+			name:         "text file",
+			path:         "/p/old-app/tree/src/main.go",
+			wantStatus:   http.StatusOK,
+			wantContains: []string{"package main", `href="/p/old-app/raw/src/main.go"`},
+		},
+		{
+			name:         "binary file",
+			path:         "/p/old-app/tree/logo.bin",
+			wantStatus:   http.StatusOK,
+			wantContains: []string{"Binary or large file not shown."},
+		},
+		{name: "raw file", path: "/p/old-app/raw/src/main.go", wantStatus: http.StatusOK, wantContains: []string{"package main"}},
+		{name: "unknown project", path: "/p/not-a-project/", wantStatus: http.StatusNotFound},
+		{name: "missing file", path: "/p/old-app/tree/nope.txt", wantStatus: http.StatusNotFound},
+		{name: "path traversal", path: "/p/old-app/raw/..%2F..%2Fother/.bury-it.md", wantStatus: http.StatusNotFound},
+		{name: "link outside the project", path: "/p/old-app/raw/leak", wantStatus: http.StatusNotFound, wantNotContains: []string{"PRIVATE KEY"}},
+		{name: "link outside the project shown", path: "/p/old-app/tree/leak", wantStatus: http.StatusNotFound, wantNotContains: []string{"PRIVATE KEY"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("GET %s status = %d, want %d\n%s", tt.path, rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			body := rec.Body.String()
+			for _, want := range tt.wantContains {
+				if !strings.Contains(body, want) {
+					t.Errorf("GET %s missing %q\n\nGot:\n%s", tt.path, want, body)
+				}
+			}
+			for _, notWant := range tt.wantNotContains {
+				if strings.Contains(body, notWant) {
+					t.Errorf("GET %s contains unexpected %q", tt.path, notWant)
+				}
+			}
+		})
+	}
+}